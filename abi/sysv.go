@@ -0,0 +1,387 @@
+// Package abi implements classification of LLVM IR types according to C
+// calling conventions, to generate function signatures compatible with the C
+// ABI of a target (e.g. for foreign function interfaces).
+package abi
+
+import (
+	"github.com/llir/l/datalayout"
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// === [ System V x86-64 ABI ] =================================================
+
+// Class is an argument class of an eightbyte, as defined by the System V
+// x86-64 ABI (section 3.2.3).
+type Class uint8
+
+// Argument classes.
+const (
+	NoClass    Class = iota // NO_CLASS
+	Integer                 // INTEGER
+	SSE                     // SSE
+	SSEUp                   // SSEUP
+	X87                     // X87
+	X87Up                   // X87UP
+	ComplexX87              // COMPLEX_X87
+	Memory                  // MEMORY
+)
+
+// String returns the string representation of the argument class.
+func (c Class) String() string {
+	switch c {
+	case NoClass:
+		return "NO_CLASS"
+	case Integer:
+		return "INTEGER"
+	case SSE:
+		return "SSE"
+	case SSEUp:
+		return "SSEUP"
+	case X87:
+		return "X87"
+	case X87Up:
+		return "X87UP"
+	case ComplexX87:
+		return "COMPLEX_X87"
+	case Memory:
+		return "MEMORY"
+	}
+	panic(errors.Errorf("support for argument class %d not yet implemented", uint8(c)))
+}
+
+// Number of registers available for passing arguments.
+const (
+	// rdi, rsi, rdx, rcx, r8 and r9.
+	numIntRegs = 6
+	// xmm0 through xmm7.
+	numSSERegs = 8
+)
+
+// ArgInfo specifies how an argument or return value is passed.
+type ArgInfo struct {
+	// Type of the argument or return value.
+	Type types.Type
+	// Classes of the eightbytes of the type; or a single Memory class if the
+	// type is passed in memory.
+	Classes []Class
+	// The aggregate is passed in memory; as a byval pointer parameter for
+	// arguments, and as a leading sret pointer parameter for return values.
+	Indirect bool
+	// Types of the registers used to pass the type, in order; or nil if passed
+	// indirectly or empty. Non-aggregate types are passed directly, as the
+	// type itself; and aggregates are split into one register per eightbyte
+	// (e.g. {i64, double} for struct { long a; double b; }).
+	Regs []types.Type
+}
+
+// FuncInfo specifies how the return value and arguments of a function are
+// passed.
+type FuncInfo struct {
+	// Function signature.
+	Sig *types.FuncType
+	// Return value; nil if void.
+	Ret *ArgInfo
+	// Parameters.
+	Params []*ArgInfo
+}
+
+// Classify returns the classes of the eightbytes of the given type, according
+// to the System V x86-64 ABI; or a single Memory class if the type is passed
+// in memory. It panics if the type is unsized.
+//
+// Aggregates of more than 16 bytes, and aggregates with unaligned fields, are
+// passed in memory. 256-bit and larger vectors are passed in memory, as for
+// targets without AVX.
+func Classify(dl *datalayout.DataLayout, t types.Type) []Class {
+	size := dl.TypeAllocSize(t)
+	if size > 16 {
+		return []Class{Memory}
+	}
+	classes := make([]Class, (size+7)/8)
+	for _, leaf := range leaves(dl, t, 0) {
+		if leaf.offset%dl.ABIAlign(leaf.typ) != 0 {
+			return []Class{Memory}
+		}
+		for i, c := range leafClasses(dl, leaf.typ) {
+			j := leaf.offset/8 + int64(i)
+			if j >= int64(len(classes)) {
+				return []Class{Memory}
+			}
+			classes[j] = merge(classes[j], c)
+		}
+	}
+	// Post merger cleanup.
+	for i, c := range classes {
+		switch {
+		case c == Memory:
+			return []Class{Memory}
+		case c == X87Up && (i == 0 || classes[i-1] != X87):
+			return []Class{Memory}
+		case c == SSEUp && (i == 0 || (classes[i-1] != SSE && classes[i-1] != SSEUp)):
+			classes[i] = SSE
+		}
+	}
+	return classes
+}
+
+// ClassifyReturn returns how a return value of the given type is passed.
+func ClassifyReturn(dl *datalayout.DataLayout, t types.Type) *ArgInfo {
+	info := &ArgInfo{Type: t, Classes: Classify(dl, t)}
+	if isAggregate(t) && hasClass(info.Classes, Memory) {
+		info.Indirect = true
+		return info
+	}
+	info.Regs = regs(dl, t, info.Classes)
+	return info
+}
+
+// ClassifyArg returns how an argument of the given type is passed, assuming
+// sufficient available registers.
+func ClassifyArg(dl *datalayout.DataLayout, t types.Type) *ArgInfo {
+	info := &ArgInfo{Type: t, Classes: Classify(dl, t)}
+	if isAggregate(t) && (hasClass(info.Classes, Memory) || hasClass(info.Classes, X87)) {
+		info.Classes = []Class{Memory}
+		info.Indirect = true
+		return info
+	}
+	info.Regs = regs(dl, t, info.Classes)
+	return info
+}
+
+// ClassifyFunc returns how the return value and arguments of a function of
+// the given signature are passed. Aggregate arguments are passed in memory if
+// the remaining integer or SSE registers are insufficient to pass the entire
+// aggregate in registers.
+func ClassifyFunc(dl *datalayout.DataLayout, sig *types.FuncType) *FuncInfo {
+	info := &FuncInfo{Sig: sig}
+	intRegs, sseRegs := numIntRegs, numSSERegs
+	if !sig.RetType.Equal(types.Void) {
+		info.Ret = ClassifyReturn(dl, sig.RetType)
+		if info.Ret.Indirect {
+			// The address of the return value is passed in rdi.
+			intRegs--
+		}
+	}
+	for _, param := range sig.Params {
+		arg := ClassifyArg(dl, param)
+		if !arg.Indirect {
+			nint, nsse := numRegs(arg.Classes)
+			if isAggregate(param) && (nint > intRegs || nsse > sseRegs) {
+				arg = &ArgInfo{Type: param, Classes: []Class{Memory}, Indirect: true}
+			} else {
+				intRegs -= nint
+				sseRegs -= nsse
+			}
+		}
+		info.Params = append(info.Params, arg)
+	}
+	return info
+}
+
+// Signature returns the LLVM IR function signature of the C function, with
+// aggregate parameters and return values lowered to registers and pointers.
+//
+// Indirect return values are returned through a leading pointer parameter, to
+// be marked sret; and indirect arguments are passed as pointer parameters, to
+// be marked byval. Aggregate return values passed in two registers are
+// returned as a literal struct of the register types.
+func (info *FuncInfo) Signature() *types.FuncType {
+	var retType types.Type = types.Void
+	var params []types.Type
+	if ret := info.Ret; ret != nil {
+		switch {
+		case ret.Indirect:
+			params = append(params, types.NewPointer(ret.Type))
+		case len(ret.Regs) == 1:
+			retType = ret.Regs[0]
+		case len(ret.Regs) > 1:
+			retType = types.NewStruct(ret.Regs...)
+		}
+	}
+	for _, param := range info.Params {
+		if param.Indirect {
+			params = append(params, types.NewPointer(param.Type))
+			continue
+		}
+		params = append(params, param.Regs...)
+	}
+	sig := types.NewFunc(retType, params...)
+	sig.Variadic = info.Sig.Variadic
+	return sig
+}
+
+// ### [ Helper functions ] ####################################################
+
+// leaf is a scalar field of a type.
+type leaf struct {
+	// Offset in bytes from the start of the outermost type.
+	offset int64
+	// Type of the scalar field.
+	typ types.Type
+}
+
+// leaves returns the scalar fields of the given type at the given offset, in
+// order of offset.
+func leaves(dl *datalayout.DataLayout, t types.Type, offset int64) []leaf {
+	switch t := t.(type) {
+	case *types.StructType:
+		var ls []leaf
+		layout := dl.StructLayout(t)
+		for i, field := range t.Fields {
+			ls = append(ls, leaves(dl, field, offset+layout.Offsets[i])...)
+		}
+		return ls
+	case *types.ArrayType:
+		var ls []leaf
+		size := dl.TypeAllocSize(t.ElemType)
+		for i := int64(0); i < t.Len; i++ {
+			ls = append(ls, leaves(dl, t.ElemType, offset+i*size)...)
+		}
+		return ls
+	}
+	return []leaf{{offset: offset, typ: t}}
+}
+
+// leafClasses returns the classes of the eightbytes of the given scalar type.
+func leafClasses(dl *datalayout.DataLayout, t types.Type) []Class {
+	switch t := t.(type) {
+	case *types.IntType:
+		switch {
+		case t.BitSize <= 64:
+			return []Class{Integer}
+		case t.BitSize <= 128:
+			return []Class{Integer, Integer}
+		}
+	case *types.PointerType:
+		return []Class{Integer}
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindHalf, types.FloatKindFloat, types.FloatKindDouble:
+			return []Class{SSE}
+		case types.FloatKindX86FP80:
+			return []Class{X87, X87Up}
+		case types.FloatKindFP128:
+			return []Class{SSE, SSEUp}
+		}
+	case *types.MMXType:
+		return []Class{SSE}
+	case *types.VectorType:
+		switch size := dl.TypeAllocSize(t); {
+		case size <= 8:
+			return []Class{SSE}
+		case size == 16:
+			return []Class{SSE, SSEUp}
+		}
+	}
+	return []Class{Memory}
+}
+
+// merge returns the class of an eightbyte containing fields of the given
+// classes.
+func merge(a, b Class) Class {
+	switch {
+	case a == b:
+		return a
+	case a == NoClass:
+		return b
+	case b == NoClass:
+		return a
+	case a == Memory || b == Memory:
+		return Memory
+	case a == Integer || b == Integer:
+		return Integer
+	case a == X87 || a == X87Up || a == ComplexX87 || b == X87 || b == X87Up || b == ComplexX87:
+		return Memory
+	}
+	return SSE
+}
+
+// regs returns the types of the registers used to pass the given type of the
+// given eightbyte classes.
+func regs(dl *datalayout.DataLayout, t types.Type, classes []Class) []types.Type {
+	if !isAggregate(t) {
+		return []types.Type{t}
+	}
+	ls := leaves(dl, t, 0)
+	size := dl.TypeAllocSize(t)
+	var rs []types.Type
+	for i := 0; i < len(classes); i++ {
+		start := int64(i) * 8
+		n := size - start
+		if n > 8 {
+			n = 8
+		}
+		var in []leaf
+		for _, l := range ls {
+			if l.offset >= start && l.offset < start+8 {
+				in = append(in, l)
+			}
+		}
+		switch classes[i] {
+		case Integer:
+			if len(in) == 1 && in[0].offset == start && dl.TypeAllocSize(in[0].typ) == 8 {
+				rs = append(rs, in[0].typ)
+			} else {
+				rs = append(rs, types.NewInt(n*8))
+			}
+		case SSE:
+			rs = append(rs, sseReg(dl, in, start))
+			if i+1 < len(classes) && classes[i+1] == SSEUp {
+				// The register spans the following eightbyte.
+				i++
+			}
+		case X87:
+			rs = append(rs, types.X86FP80)
+			// Skip X87UP.
+			i++
+		}
+	}
+	return rs
+}
+
+// sseReg returns the type of the SSE register used to pass the given scalar
+// fields of the eightbyte at the given offset.
+func sseReg(dl *datalayout.DataLayout, in []leaf, start int64) types.Type {
+	switch {
+	case len(in) == 1 && in[0].offset == start:
+		// Single scalar field; e.g. double, float, fp128 or <4 x float>.
+		return in[0].typ
+	case len(in) == 2 && in[0].typ.Equal(types.Float) && in[1].typ.Equal(types.Float):
+		return types.NewVector(2, types.Float)
+	}
+	return types.Double
+}
+
+// numRegs returns the number of integer and SSE registers needed to pass an
+// argument of the given eightbyte classes.
+func numRegs(classes []Class) (nint, nsse int) {
+	for _, c := range classes {
+		switch c {
+		case Integer:
+			nint++
+		case SSE:
+			nsse++
+		}
+	}
+	return nint, nsse
+}
+
+// hasClass reports whether the given eightbyte classes contain c.
+func hasClass(classes []Class, c Class) bool {
+	for _, class := range classes {
+		if class == c {
+			return true
+		}
+	}
+	return false
+}
+
+// isAggregate reports whether the given type is an aggregate type.
+func isAggregate(t types.Type) bool {
+	switch t.(type) {
+	case *types.StructType, *types.ArrayType:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package abi
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/llir/l/datalayout"
+	"github.com/llir/l/ir/types"
+)
+
+func TestClassify(t *testing.T) {
+	dl, err := datalayout.Parse("e-m:e-i64:64-f80:128-n8:16:32:64-S128")
+	if err != nil {
+		t.Fatalf("unable to parse data layout; %+v", err)
+	}
+	packed := types.NewStruct(types.I8, types.I32)
+	packed.Packed = true
+	golden := []struct {
+		typ      types.Type
+		classes  string
+		indirect bool
+		regs     string
+	}{
+		// i=0
+		{typ: types.I32, classes: "[INTEGER]", regs: "[i32]"},
+		// i=1
+		{typ: types.NewInt(128), classes: "[INTEGER INTEGER]", regs: "[i128]"},
+		// i=2
+		{typ: types.X86FP80, classes: "[X87 X87UP]", regs: "[x86_fp80]"},
+		// i=3
+		{typ: types.NewVector(4, types.Float), classes: "[SSE SSEUP]", regs: "[<4 x float>]"},
+		// i=4: int and float share an eightbyte.
+		{typ: types.NewStruct(types.I32, types.Float), classes: "[INTEGER]", regs: "[i64]"},
+		// i=5
+		{typ: types.NewStruct(types.Double, types.I64), classes: "[SSE INTEGER]", regs: "[double i64]"},
+		// i=6
+		{typ: types.NewStruct(types.Float, types.Float, types.Float), classes: "[SSE SSE]", regs: "[<2 x float> float]"},
+		// i=7
+		{typ: types.NewStruct(types.NewArray(2, types.Float), types.I32), classes: "[SSE INTEGER]", regs: "[<2 x float> i32]"},
+		// i=8
+		{typ: types.NewStruct(types.I8Ptr), classes: "[INTEGER]", regs: "[i8*]"},
+		// i=9
+		{typ: types.NewStruct(types.I8, types.I8, types.I8), classes: "[INTEGER]", regs: "[i24]"},
+		// i=10: more than 16 bytes.
+		{typ: types.NewStruct(types.I64, types.I64, types.I64), classes: "[MEMORY]", indirect: true, regs: "[]"},
+		// i=11: unaligned field.
+		{typ: packed, classes: "[MEMORY]", indirect: true, regs: "[]"},
+		// i=12: x87 arguments are passed in memory.
+		{typ: types.NewStruct(types.X86FP80), classes: "[MEMORY]", indirect: true, regs: "[]"},
+	}
+	for i, g := range golden {
+		info := ClassifyArg(dl, g.typ)
+		if got := fmt.Sprint(info.Classes); g.classes != got {
+			t.Errorf("i=%d: classes mismatch of %v; expected %v, got %v", i, g.typ, g.classes, got)
+		}
+		if g.indirect != info.Indirect {
+			t.Errorf("i=%d: indirect mismatch of %v; expected %v, got %v", i, g.typ, g.indirect, info.Indirect)
+		}
+		if got := fmt.Sprint(info.Regs); g.regs != got {
+			t.Errorf("i=%d: registers mismatch of %v; expected %v, got %v", i, g.typ, g.regs, got)
+		}
+	}
+	// x87 aggregates are returned in x87 registers.
+	if got := fmt.Sprint(ClassifyReturn(dl, types.NewStruct(types.X86FP80)).Regs); got != "[x86_fp80]" {
+		t.Errorf("registers mismatch of x87 return value; expected [x86_fp80], got %v", got)
+	}
+}
+
+func TestClassifyFunc(t *testing.T) {
+	dl, err := datalayout.Parse("e-m:e-i64:64-f80:128-n8:16:32:64-S128")
+	if err != nil {
+		t.Fatalf("unable to parse data layout; %+v", err)
+	}
+	big := types.NewStruct(types.I64, types.I64, types.I64)
+	pair := types.NewStruct(types.Double, types.I64)
+	ints := types.NewStruct(types.I64, types.I64)
+	// The sret pointer, the mixed pair and the first two pairs of integers use
+	// all six integer registers, so the last pair of integers is passed in
+	// memory.
+	sig := types.NewFunc(big, pair, ints, ints, types.I32, ints)
+	info := ClassifyFunc(dl, sig)
+	if !info.Ret.Indirect {
+		t.Errorf("expected return value to be passed indirectly")
+	}
+	for i, param := range info.Params {
+		if want := i == 4; want != param.Indirect {
+			t.Errorf("parameter %d: indirect mismatch; expected %v, got %v", i, want, param.Indirect)
+		}
+	}
+	want := "void ({ i64, i64, i64 }*, double, i64, i64, i64, i64, i64, i32, { i64, i64 }*)"
+	if got := info.Signature().String(); want != got {
+		t.Errorf("signature mismatch; expected %q, got %q", want, got)
+	}
+	// Aggregates returned in two registers.
+	want = "{ double, i64 } ()"
+	if got := ClassifyFunc(dl, types.NewFunc(pair)).Signature().String(); want != got {
+		t.Errorf("signature mismatch; expected %q, got %q", want, got)
+	}
+}
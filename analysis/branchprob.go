@@ -0,0 +1,380 @@
+package analysis
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+// --- [ Branch probabilities ] ------------------------------------------------
+
+// Weights of the static branch heuristics, as used by LLVM; the taken weight
+// applies to the successors predicted to be taken, and the not-taken weight to
+// the other successors.
+const (
+	// Unreachable heuristic; successors which always reach unreachable are not
+	// taken.
+	unreachableTakenWeight    = 1<<20 - 1
+	unreachableNotTakenWeight = 1
+	// Cold call heuristic; successors which always reach a call to a cold
+	// function are not taken.
+	coldTakenWeight    = 124
+	coldNotTakenWeight = 4
+	// Loop branch heuristic; edges which stay within a loop are taken, and loop
+	// exits are not taken.
+	loopTakenWeight    = 124
+	loopNotTakenWeight = 4
+	// Pointer heuristic; pointers are predicted to be non-equal (e.g. to null).
+	pointerTakenWeight    = 20
+	pointerNotTakenWeight = 12
+	// Zero heuristic; integers are predicted to be non-zero and non-negative.
+	zeroTakenWeight    = 20
+	zeroNotTakenWeight = 12
+	// Float heuristic; floating-point values are predicted to be non-equal and
+	// ordered.
+	floatTakenWeight    = 20
+	floatNotTakenWeight = 12
+	// Invoke heuristic; invokes are predicted not to unwind.
+	invokeTakenWeight    = 1<<20 - 1
+	invokeNotTakenWeight = 1
+)
+
+// BranchProbs is the branch probability analysis of a function, which records
+// the probability of each control flow edge and the estimated execution
+// frequency of each basic block.
+//
+// Edge probabilities are derived from `!prof !{!"branch_weights", ...}`
+// metadata if present, and otherwise from static heuristics (in order of
+// precedence: unreachable, cold call, loop branch, pointer, zero, float and
+// invoke heuristics), falling back to an even split between successors.
+type BranchProbs struct {
+	// Probability of each successor edge of the terminator of each basic block,
+	// in the order of the successors of the terminator.
+	succProbs map[*ir.BasicBlock][]float64
+	// Estimated execution frequency of each basic block, relative to the entry
+	// basic block (which has frequency 1).
+	freqs map[*ir.BasicBlock]float64
+}
+
+// BranchProbabilities returns the branch probability analysis of the given
+// function.
+func BranchProbabilities(f *ir.Function) *BranchProbs {
+	h := newHeuristics(f)
+	bp := &BranchProbs{succProbs: make(map[*ir.BasicBlock][]float64)}
+	for _, block := range f.Blocks {
+		if block.Term != nil {
+			bp.succProbs[block] = h.probs(block)
+		}
+	}
+	bp.freqs = blockFreqs(f, func(term ir.Terminator) []float64 {
+		return bp.succProbs[h.blockOf[term]]
+	})
+	return bp
+}
+
+// Prob returns the probability of the control flow edge from the given basic
+// block to succ, given that the basic block is executed. Probabilities of
+// repeated edges between the same basic blocks are summed.
+func (bp *BranchProbs) Prob(block, succ *ir.BasicBlock) float64 {
+	if block.Term == nil {
+		return 0
+	}
+	probs := bp.succProbs[block]
+	prob := 0.0
+	for i, s := range block.Term.Succs() {
+		if s == succ {
+			prob += probs[i]
+		}
+	}
+	return prob
+}
+
+// SuccProbs returns the probability of each successor edge of the terminator
+// of the given basic block, in the order of the successors of the terminator.
+func (bp *BranchProbs) SuccProbs(block *ir.BasicBlock) []float64 {
+	return bp.succProbs[block]
+}
+
+// BlockFreq returns the estimated execution frequency of the given basic
+// block, relative to the entry basic block of the function.
+func (bp *BranchProbs) BlockFreq(block *ir.BasicBlock) float64 {
+	return bp.freqs[block]
+}
+
+// EdgeFreq returns the estimated execution frequency of the control flow edge
+// from the given basic block to succ, relative to the entry basic block of the
+// function.
+func (bp *BranchProbs) EdgeFreq(block, succ *ir.BasicBlock) float64 {
+	return bp.BlockFreq(block) * bp.Prob(block, succ)
+}
+
+// IsLikely reports whether the control flow edge from the given basic block to
+// succ is likely taken; i.e. with a probability of at least 80%.
+func (bp *BranchProbs) IsLikely(block, succ *ir.BasicBlock) bool {
+	return bp.Prob(block, succ) >= 0.8
+}
+
+// IsUnlikely reports whether the control flow edge from the given basic block
+// to succ is unlikely taken; i.e. with a probability of at most 20%.
+func (bp *BranchProbs) IsUnlikely(block, succ *ir.BasicBlock) bool {
+	return bp.Prob(block, succ) <= 0.2
+}
+
+// ### [ Helper functions ] ####################################################
+
+// heuristics holds the function-wide information used by the static branch
+// heuristics.
+type heuristics struct {
+	// Loops of the function.
+	loops *LoopInfo
+	// Basic blocks which always reach unreachable.
+	unreachable map[*ir.BasicBlock]bool
+	// Basic blocks which always reach a call to a cold function.
+	cold map[*ir.BasicBlock]bool
+	// Basic block of each terminator.
+	blockOf map[ir.Terminator]*ir.BasicBlock
+}
+
+// newHeuristics returns the static branch heuristics of the given function.
+func newHeuristics(f *ir.Function) *heuristics {
+	h := &heuristics{
+		loops:   FindLoops(f),
+		blockOf: make(map[ir.Terminator]*ir.BasicBlock),
+	}
+	for _, block := range f.Blocks {
+		if block.Term != nil {
+			h.blockOf[block.Term] = block
+		}
+	}
+	h.unreachable = alwaysReaches(f, func(block *ir.BasicBlock) bool {
+		_, ok := block.Term.(*ir.TermUnreachable)
+		return ok
+	})
+	h.cold = alwaysReaches(f, func(block *ir.BasicBlock) bool {
+		for _, inst := range block.Insts {
+			if call, ok := inst.(*ir.InstCall); ok && isColdCall(call) {
+				return true
+			}
+		}
+		return false
+	})
+	return h
+}
+
+// probs returns the probability of each successor edge of the terminator of
+// the given basic block.
+func (h *heuristics) probs(block *ir.BasicBlock) []float64 {
+	term := block.Term
+	if weights, ok := BranchWeights(term); ok && len(weights) == len(term.Succs()) {
+		return edgeProbs(term)
+	}
+	succs := term.Succs()
+	for _, heuristic := range []func(block *ir.BasicBlock) ([]bool, uint64, uint64){
+		h.unreachableHeuristic,
+		h.coldHeuristic,
+		h.loopHeuristic,
+		compareHeuristic,
+		invokeHeuristic,
+	} {
+		taken, takenWeight, notTakenWeight := heuristic(block)
+		if taken == nil {
+			continue
+		}
+		// Split the taken and not-taken weights evenly between the successors
+		// predicted to be taken and not taken, respectively.
+		var numTaken int
+		for _, t := range taken {
+			if t {
+				numTaken++
+			}
+		}
+		if numTaken == 0 || numTaken == len(succs) {
+			continue
+		}
+		probs := make([]float64, len(succs))
+		total := float64(takenWeight + notTakenWeight)
+		for i, t := range taken {
+			if t {
+				probs[i] = float64(takenWeight) / total / float64(numTaken)
+			} else {
+				probs[i] = float64(notTakenWeight) / total / float64(len(succs)-numTaken)
+			}
+		}
+		return probs
+	}
+	return edgeProbs(term)
+}
+
+// unreachableHeuristic predicts successors which always reach unreachable not
+// to be taken.
+func (h *heuristics) unreachableHeuristic(block *ir.BasicBlock) ([]bool, uint64, uint64) {
+	return predictNot(block, h.unreachable), unreachableTakenWeight, unreachableNotTakenWeight
+}
+
+// coldHeuristic predicts successors which always reach a call to a cold
+// function not to be taken.
+func (h *heuristics) coldHeuristic(block *ir.BasicBlock) ([]bool, uint64, uint64) {
+	return predictNot(block, h.cold), coldTakenWeight, coldNotTakenWeight
+}
+
+// loopHeuristic predicts edges which exit the innermost loop of the basic block
+// not to be taken.
+func (h *heuristics) loopHeuristic(block *ir.BasicBlock) ([]bool, uint64, uint64) {
+	l := h.loops.LoopFor(block)
+	if l == nil {
+		return nil, 0, 0
+	}
+	succs := block.Term.Succs()
+	taken := make([]bool, len(succs))
+	for i, succ := range succs {
+		taken[i] = l.Contains(succ)
+	}
+	return taken, loopTakenWeight, loopNotTakenWeight
+}
+
+// compareHeuristic predicts the outcome of conditional branches on integer,
+// pointer and floating-point comparisons; comparisons for equality and against
+// zero are predicted to be false.
+func compareHeuristic(block *ir.BasicBlock) ([]bool, uint64, uint64) {
+	term, ok := block.Term.(*ir.TermCondBr)
+	if !ok {
+		return nil, 0, 0
+	}
+	// Predicted outcome of the condition.
+	var outcome bool
+	var takenWeight, notTakenWeight uint64
+	switch cond := term.Cond.(type) {
+	case *ir.InstICmp:
+		if _, ok := cond.X.Type().(*types.PointerType); ok {
+			// Pointer heuristic.
+			switch cond.Pred {
+			case enum.IPredEQ:
+				outcome = false
+			case enum.IPredNE:
+				outcome = true
+			default:
+				return nil, 0, 0
+			}
+			takenWeight, notTakenWeight = pointerTakenWeight, pointerNotTakenWeight
+		} else {
+			// Zero heuristic.
+			c, ok := cond.Y.(*ir.ConstInt)
+			if !ok {
+				return nil, 0, 0
+			}
+			switch {
+			case c.X.Sign() == 0 && cond.Pred == enum.IPredEQ:
+				outcome = false // x == 0
+			case c.X.Sign() == 0 && cond.Pred == enum.IPredNE:
+				outcome = true // x != 0
+			case c.X.Sign() == 0 && cond.Pred == enum.IPredSLT:
+				outcome = false // x < 0
+			case c.X.Sign() == 0 && cond.Pred == enum.IPredSGT:
+				outcome = true // x > 0
+			case c.X.Sign() < 0 && cond.Pred == enum.IPredEQ:
+				outcome = false // x == -1
+			case c.X.Sign() < 0 && cond.Pred == enum.IPredNE:
+				outcome = true // x != -1
+			default:
+				return nil, 0, 0
+			}
+			takenWeight, notTakenWeight = zeroTakenWeight, zeroNotTakenWeight
+		}
+	case *ir.InstFCmp:
+		// Float heuristic.
+		switch cond.Pred {
+		case enum.FPredOEQ, enum.FPredUEQ, enum.FPredUNO:
+			outcome = false
+		case enum.FPredONE, enum.FPredUNE, enum.FPredORD:
+			outcome = true
+		default:
+			return nil, 0, 0
+		}
+		takenWeight, notTakenWeight = floatTakenWeight, floatNotTakenWeight
+	default:
+		return nil, 0, 0
+	}
+	if term.TargetTrue == term.TargetFalse {
+		return nil, 0, 0
+	}
+	return []bool{outcome, !outcome}, takenWeight, notTakenWeight
+}
+
+// invokeHeuristic predicts invokes not to unwind.
+func invokeHeuristic(block *ir.BasicBlock) ([]bool, uint64, uint64) {
+	term, ok := block.Term.(*ir.TermInvoke)
+	if !ok {
+		return nil, 0, 0
+	}
+	succs := term.Succs()
+	taken := make([]bool, len(succs))
+	for i, succ := range succs {
+		taken[i] = succ == term.Normal
+	}
+	return taken, invokeTakenWeight, invokeNotTakenWeight
+}
+
+// predictNot returns the successors of the given basic block predicted to be
+// taken; those not in the given set of basic blocks.
+func predictNot(block *ir.BasicBlock, notTaken map[*ir.BasicBlock]bool) []bool {
+	succs := block.Term.Succs()
+	taken := make([]bool, len(succs))
+	for i, succ := range succs {
+		taken[i] = !notTaken[succ]
+	}
+	return taken
+}
+
+// alwaysReaches returns the set of basic blocks of the given function from
+// which every path reaches a basic block satisfying pred.
+func alwaysReaches(f *ir.Function, pred func(block *ir.BasicBlock) bool) map[*ir.BasicBlock]bool {
+	reaches := make(map[*ir.BasicBlock]bool)
+	for _, block := range f.Blocks {
+		if block.Term != nil && pred(block) {
+			reaches[block] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, block := range f.Blocks {
+			if reaches[block] || block.Term == nil {
+				continue
+			}
+			succs := block.Term.Succs()
+			if len(succs) == 0 {
+				continue
+			}
+			all := true
+			for _, succ := range succs {
+				if !reaches[succ] {
+					all = false
+					break
+				}
+			}
+			if all {
+				reaches[block] = true
+				changed = true
+			}
+		}
+	}
+	return reaches
+}
+
+// isColdCall reports whether the given call instruction calls a cold function.
+func isColdCall(call *ir.InstCall) bool {
+	for _, attr := range call.FuncAttrs {
+		if attr == enum.FuncAttrCold {
+			return true
+		}
+	}
+	callee, ok := call.Callee.(*ir.Function)
+	if !ok {
+		return false
+	}
+	for _, attr := range callee.FuncAttrs {
+		if attr == enum.FuncAttrCold {
+			return true
+		}
+	}
+	return false
+}
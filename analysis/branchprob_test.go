@@ -0,0 +1,82 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestBranchProbabilities(t *testing.T) {
+	m := &ir.Module{}
+	abort := m.NewFunc("abort", types.Void)
+	abort.FuncAttrs = append(abort.FuncAttrs, enum.FuncAttrCold)
+	p := ir.NewParam(types.I32Ptr, "p")
+	n := ir.NewParam(types.I32, "n")
+	f := m.NewFunc("f", types.Void, p, n)
+	entry := f.NewBlock("entry")
+	fail := f.NewBlock("fail")
+	check := f.NewBlock("check")
+	loop := f.NewBlock("loop")
+	exit := f.NewBlock("exit")
+	// entry: pointer heuristic.
+	isNull := entry.NewICmp(enum.IPredEQ, p, ir.NewNull(types.I32Ptr))
+	entry.NewCondBr(isNull, fail, check)
+	// fail: cold call.
+	fail.NewCall(abort)
+	fail.NewUnreachable()
+	// check: zero heuristic.
+	isZero := check.NewICmp(enum.IPredEQ, n, ir.NewInt(types.I32, 0))
+	check.NewCondBr(isZero, exit, loop)
+	// loop: loop branch heuristic, and branch weights.
+	i := loop.NewPhi(ir.NewIncoming(ir.NewInt(types.I32, 0), check))
+	next := loop.NewAdd(i, ir.NewInt(types.I32, 1))
+	i.Incs = append(i.Incs, ir.NewIncoming(next, loop))
+	done := loop.NewICmp(enum.IPredEQ, next, n)
+	loop.NewCondBr(done, exit, loop)
+	exit.NewRet(nil)
+	bp := BranchProbabilities(f)
+	golden := []struct {
+		from, to *ir.BasicBlock
+		want     float64
+	}{
+		// i=0: unreachable heuristic.
+		{from: entry, to: fail, want: 1.0 / (1 << 20)},
+		// i=1
+		{from: entry, to: check, want: (1<<20 - 1.0) / (1 << 20)},
+		// i=2: zero heuristic.
+		{from: check, to: exit, want: 12.0 / 32},
+		// i=3
+		{from: check, to: loop, want: 20.0 / 32},
+		// i=4: loop branch heuristic.
+		{from: loop, to: loop, want: 124.0 / 128},
+		// i=5
+		{from: loop, to: exit, want: 4.0 / 128},
+		// i=6: unconditional branch.
+		{from: fail, to: exit, want: 0},
+	}
+	for i, g := range golden {
+		if got := bp.Prob(g.from, g.to); math.Abs(g.want-got) > 1e-9 {
+			t.Errorf("i=%d: probability mismatch of edge %v -> %v; expected %v, got %v", i, g.from.Ident(), g.to.Ident(), g.want, got)
+		}
+	}
+	// The loop is executed 32 times per execution of the loop header, and the
+	// exit is always reached except through the unreachable fail block.
+	if want, got := (1<<20-1.0)/(1<<20)*20/32*32, bp.BlockFreq(loop); math.Abs(want-got) > 1e-6 {
+		t.Errorf("frequency mismatch of loop; expected %v, got %v", want, got)
+	}
+	if want, got := (1<<20-1.0)/(1<<20), bp.BlockFreq(exit); math.Abs(want-got) > 1e-6 {
+		t.Errorf("frequency mismatch of exit; expected %v, got %v", want, got)
+	}
+	// Branch weights take precedence over heuristics.
+	loop.Term.(*ir.TermCondBr).Metadata = append(loop.Term.(*ir.TermCondBr).Metadata, ir.NewMetadataAttachment(ir.MDKindProf, ir.NewTuple(ir.NewMDString("branch_weights"), ir.NewMDValue(ir.NewInt(types.I32, 1)), ir.NewMDValue(ir.NewInt(types.I32, 3)))))
+	bp = BranchProbabilities(f)
+	if want, got := 0.75, bp.Prob(loop, loop); want != got {
+		t.Errorf("probability mismatch of back edge; expected %v, got %v", want, got)
+	}
+	if !bp.IsUnlikely(entry, fail) || !bp.IsLikely(entry, check) {
+		t.Errorf("expected likely edge entry -> check")
+	}
+}
@@ -0,0 +1,111 @@
+// Package analysis implements analyses of LLVM IR functions and modules.
+package analysis
+
+import "github.com/llir/l/ir"
+
+// Edges specifies the control flow edges considered by the control flow graph
+// analyses (e.g. Preds, ReversePostorder and Dominators).
+type Edges uint8
+
+// Control flow edges.
+const (
+	// All control flow edges, including the unwind edges of invoke, catchswitch
+	// and cleanupret terminators to exception handling basic blocks.
+	AllEdges Edges = iota
+	// Normal control flow edges only; unwind edges are ignored, and exception
+	// handling basic blocks only reached through unwind edges are thus
+	// unreachable.
+	NormalEdges
+)
+
+// Preds returns the predecessor basic blocks of each basic block of the given
+// function, including predecessors through unwind edges. The predecessors are
+// listed in the order of the basic blocks of the function.
+func Preds(f *ir.Function) map[*ir.BasicBlock][]*ir.BasicBlock {
+	return AllEdges.Preds(f)
+}
+
+// ReversePostorder returns the basic blocks of the given function reachable
+// from the entry basic block, including through unwind edges, in reverse
+// postorder of a depth-first traversal. In reverse postorder, each basic block
+// is listed before its successors, except along back edges.
+func ReversePostorder(f *ir.Function) []*ir.BasicBlock {
+	return AllEdges.ReversePostorder(f)
+}
+
+// Succs returns the successor basic blocks of the given basic block along the
+// control flow edges, with duplicate successors (e.g. of switch terminators)
+// removed.
+func (edges Edges) Succs(block *ir.BasicBlock) []*ir.BasicBlock {
+	if block.Term == nil {
+		return nil
+	}
+	var succs []*ir.BasicBlock
+	seen := make(map[*ir.BasicBlock]bool)
+	for _, succ := range edges.termSuccs(block.Term) {
+		if succ == nil || seen[succ] {
+			continue
+		}
+		seen[succ] = true
+		succs = append(succs, succ)
+	}
+	return succs
+}
+
+// Preds returns the predecessor basic blocks of each basic block of the given
+// function along the control flow edges. The predecessors are listed in the
+// order of the basic blocks of the function.
+func (edges Edges) Preds(f *ir.Function) map[*ir.BasicBlock][]*ir.BasicBlock {
+	preds := make(map[*ir.BasicBlock][]*ir.BasicBlock)
+	for _, block := range f.Blocks {
+		for _, succ := range edges.Succs(block) {
+			preds[succ] = append(preds[succ], block)
+		}
+	}
+	return preds
+}
+
+// ReversePostorder returns the basic blocks of the given function reachable
+// from the entry basic block along the control flow edges, in reverse
+// postorder of a depth-first traversal.
+func (edges Edges) ReversePostorder(f *ir.Function) []*ir.BasicBlock {
+	if len(f.Blocks) == 0 {
+		return nil
+	}
+	var post []*ir.BasicBlock
+	visited := make(map[*ir.BasicBlock]bool)
+	var visit func(block *ir.BasicBlock)
+	visit = func(block *ir.BasicBlock) {
+		visited[block] = true
+		for _, succ := range edges.Succs(block) {
+			if !visited[succ] {
+				visit(succ)
+			}
+		}
+		post = append(post, block)
+	}
+	visit(f.Blocks[0])
+	for i, j := 0, len(post)-1; i < j; i, j = i+1, j-1 {
+		post[i], post[j] = post[j], post[i]
+	}
+	return post
+}
+
+// ### [ Helper functions ] ####################################################
+
+// termSuccs returns the successor basic blocks of the given terminator along
+// the control flow edges, possibly with duplicates.
+func (edges Edges) termSuccs(term ir.Terminator) []*ir.BasicBlock {
+	if edges == NormalEdges {
+		// Omit unwind edges.
+		switch term := term.(type) {
+		case *ir.TermInvoke:
+			return []*ir.BasicBlock{term.Normal}
+		case *ir.TermCatchSwitch:
+			return term.Handlers
+		case *ir.TermCleanupRet:
+			return nil
+		}
+	}
+	return term.Succs()
+}
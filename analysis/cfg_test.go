@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestUnwindEdges(t *testing.T) {
+	m := &ir.Module{}
+	g := m.NewFunc("g", types.Void)
+	f := m.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	normal := f.NewBlock("normal")
+	dispatch := f.NewBlock("dispatch")
+	handler := f.NewBlock("handler")
+	cleanup := f.NewBlock("cleanup")
+	exit := f.NewBlock("exit")
+	entry.NewInvoke(g, nil, normal, dispatch)
+	normal.NewBr(exit)
+	dispatch.NewCatchSwitch(nil, []*ir.BasicBlock{handler}, cleanup)
+	handler.NewBr(exit)
+	cleanup.NewCleanupRet(nil, exit)
+	exit.NewRet(nil)
+	golden := []struct {
+		edges Edges
+		// Number of reachable basic blocks.
+		reachable int
+		// Immediate dominator of exit.
+		idom *ir.BasicBlock
+	}{
+		// i=0
+		{edges: AllEdges, reachable: 6, idom: entry},
+		// i=1
+		{edges: NormalEdges, reachable: 3, idom: normal},
+	}
+	for i, g := range golden {
+		if got := len(g.edges.ReversePostorder(f)); got != g.reachable {
+			t.Errorf("i=%d: reachable basic blocks mismatch; expected %d, got %d", i, g.reachable, got)
+		}
+		dom := g.edges.Dominators(f)
+		if got := dom.Idom(exit); got != g.idom {
+			t.Errorf("i=%d: immediate dominator mismatch; expected %v, got %v", i, g.idom.Ident(), got)
+		}
+		if got := dom.Dominates(dispatch, handler); got != (g.edges == AllEdges) {
+			t.Errorf("i=%d: dominance of handler mismatch; got %v", i, got)
+		}
+	}
+	if got, want := len(Preds(f)[exit]), 3; got != want {
+		t.Errorf("predecessors of exit mismatch; expected %d, got %d", want, got)
+	}
+	if got, want := len(NormalEdges.Preds(f)[exit]), 2; got != want {
+		t.Errorf("normal predecessors of exit mismatch; expected %d, got %d", want, got)
+	}
+}
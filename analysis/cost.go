@@ -0,0 +1,277 @@
+package analysis
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+// --- [ Cost model ] ----------------------------------------------------------
+
+// Target is a configurable cost model of a target architecture, as used by
+// inlining, unrolling and code size heuristics. Costs are abstract units
+// relative to the cost of a basic integer operation.
+//
+// The cost of an operation is scaled by the number of registers needed to hold
+// its type once legalized for the target; e.g. an i128 addition on a 64-bit
+// target costs twice as much as an i64 addition.
+type Target struct {
+	// Width in bits of the widest legal integer type.
+	IntWidth int64
+	// Size in bits of pointers.
+	PointerSize int64
+	// Width in bits of vector registers; or 0 if vector operations are
+	// scalarized.
+	VectorWidth int64
+
+	// Cost of basic integer and bitwise operations, comparisons and selects.
+	Basic int
+	// Cost of integer multiplication.
+	Mul int
+	// Cost of integer division and remainder.
+	Div int
+	// Cost of floating-point addition, subtraction, multiplication and
+	// comparison.
+	FloatOp int
+	// Cost of floating-point division and remainder.
+	FloatDiv int
+	// Cost of conversions which are not no-ops (e.g. sext and sitofp).
+	Cast int
+	// Cost of memory loads.
+	Load int
+	// Cost of memory stores.
+	Store int
+	// Cost of atomic memory operations and fences.
+	Atomic int
+	// Cost of function calls, excluding arguments.
+	Call int
+	// Cost of each function call argument.
+	CallArg int
+	// Cost of conditional and indirect branches, and of each switch case.
+	Branch int
+}
+
+// DefaultTarget is a cost model of a generic 64-bit target with 128-bit vector
+// registers.
+var DefaultTarget = &Target{
+	IntWidth:    64,
+	PointerSize: 64,
+	VectorWidth: 128,
+	Basic:       1,
+	Mul:         3,
+	Div:         20,
+	FloatOp:     3,
+	FloatDiv:    15,
+	Cast:        1,
+	Load:        4,
+	Store:       4,
+	Atomic:      20,
+	Call:        5,
+	CallArg:     1,
+	Branch:      1,
+}
+
+// Cost returns the estimated cost of the given instruction on the given target.
+// The default target is used if target is nil.
+//
+// No-op conversions (e.g. bitcast and trunc), phi instructions, aggregate
+// value operations, static allocas and getelementptr instructions with
+// constant indices are free.
+func Cost(inst ir.Instruction, target *Target) int {
+	if target == nil {
+		target = DefaultTarget
+	}
+	switch inst := inst.(type) {
+	// Unary instructions.
+	case *ir.InstFNeg:
+		return target.FloatOp * target.parts(inst.Type())
+	// Binary instructions.
+	case *ir.InstAdd, *ir.InstSub:
+		return target.Basic * target.parts(inst.(value.Value).Type())
+	case *ir.InstMul:
+		return target.Mul * target.parts(inst.Type())
+	case *ir.InstUDiv, *ir.InstSDiv, *ir.InstURem, *ir.InstSRem:
+		return target.Div * target.parts(inst.(value.Value).Type())
+	case *ir.InstFAdd, *ir.InstFSub, *ir.InstFMul:
+		return target.FloatOp * target.parts(inst.(value.Value).Type())
+	case *ir.InstFDiv, *ir.InstFRem:
+		return target.FloatDiv * target.parts(inst.(value.Value).Type())
+	// Bitwise instructions.
+	case *ir.InstShl, *ir.InstLShr, *ir.InstAShr, *ir.InstAnd, *ir.InstOr, *ir.InstXor:
+		return target.Basic * target.parts(inst.(value.Value).Type())
+	// Vector instructions.
+	case *ir.InstExtractElement, *ir.InstInsertElement:
+		return target.Basic
+	case *ir.InstShuffleVector:
+		return target.Basic * target.parts(inst.Type())
+	// Aggregate instructions.
+	case *ir.InstExtractValue, *ir.InstInsertValue:
+		return 0
+	// Memory instructions.
+	case *ir.InstAlloca:
+		if inst.NElems == nil || isConst(inst.NElems) {
+			return 0
+		}
+		return target.Basic
+	case *ir.InstLoad:
+		if inst.Atomic {
+			return target.Atomic
+		}
+		return target.Load * target.parts(inst.Type())
+	case *ir.InstStore:
+		if inst.Atomic {
+			return target.Atomic
+		}
+		return target.Store * target.parts(inst.Src.Type())
+	case *ir.InstFence, *ir.InstCmpXchg, *ir.InstAtomicRMW:
+		return target.Atomic
+	case *ir.InstGetElementPtr:
+		cost := 0
+		for _, index := range inst.Indices {
+			if !isConst(index) {
+				cost += target.Basic
+			}
+		}
+		return cost
+	// Conversion instructions.
+	case *ir.InstTrunc, *ir.InstBitCast, *ir.InstPtrToInt, *ir.InstIntToPtr, *ir.InstAddrSpaceCast:
+		return 0
+	case *ir.InstZExt, *ir.InstSExt, *ir.InstFPTrunc, *ir.InstFPExt, *ir.InstFPToUI, *ir.InstFPToSI, *ir.InstUIToFP, *ir.InstSIToFP:
+		return target.Cast * target.parts(inst.(value.Value).Type())
+	// Other instructions.
+	case *ir.InstICmp:
+		return target.Basic * target.parts(inst.X.Type())
+	case *ir.InstFCmp:
+		return target.FloatOp * target.parts(inst.X.Type())
+	case *ir.InstSelect:
+		return target.Basic * target.parts(inst.Type())
+	case *ir.InstPhi, *ir.InstFreeze:
+		return 0
+	case *ir.InstCall:
+		return target.Call + target.CallArg*len(inst.Args)
+	case *ir.InstVAArg:
+		return target.Load + target.Basic
+	default:
+		// Exception handling instructions and unknown instructions.
+		return target.Basic
+	}
+}
+
+// TermCost returns the estimated cost of the given terminator on the given
+// target. The default target is used if target is nil.
+func TermCost(term ir.Terminator, target *Target) int {
+	if target == nil {
+		target = DefaultTarget
+	}
+	switch term := term.(type) {
+	case *ir.TermBr, *ir.TermUnreachable:
+		return 0
+	case *ir.TermSwitch:
+		return target.Branch * (1 + len(term.Cases))
+	case *ir.TermInvoke:
+		return target.Call + target.CallArg*len(term.Args)
+	default:
+		return target.Branch
+	}
+}
+
+// BlockCost returns the estimated cost of the instructions and terminator of
+// the given basic block on the given target. The default target is used if
+// target is nil.
+func BlockCost(block *ir.BasicBlock, target *Target) int {
+	cost := 0
+	for _, inst := range block.Insts {
+		cost += Cost(inst, target)
+	}
+	if block.Term != nil {
+		cost += TermCost(block.Term, target)
+	}
+	return cost
+}
+
+// FuncCost returns the estimated cost of the basic blocks of the given
+// function on the given target; i.e. an estimate of its code size. The default
+// target is used if target is nil.
+func FuncCost(f *ir.Function, target *Target) int {
+	cost := 0
+	for _, block := range f.Blocks {
+		cost += BlockCost(block, target)
+	}
+	return cost
+}
+
+// ### [ Helper functions ] ####################################################
+
+// parts returns the number of registers needed to hold a value of the given
+// type once legalized for the target; at least 1.
+func (target *Target) parts(t types.Type) int {
+	if n := target.typeParts(t); n > 1 {
+		return int(n)
+	}
+	return 1
+}
+
+// typeParts returns the number of registers needed to hold a value of the
+// given type once legalized for the target.
+func (target *Target) typeParts(t types.Type) int64 {
+	switch t := t.(type) {
+	case *types.IntType:
+		return ceilDiv(t.BitSize, target.IntWidth)
+	case *types.FloatType, *types.PointerType:
+		return 1
+	case *types.VectorType:
+		if target.VectorWidth == 0 {
+			return t.Len * target.typeParts(t.ElemType)
+		}
+		return ceilDiv(t.Len*target.bitSize(t.ElemType), target.VectorWidth)
+	case *types.ArrayType:
+		return t.Len * target.typeParts(t.ElemType)
+	case *types.StructType:
+		var n int64
+		for _, field := range t.Fields {
+			n += target.typeParts(field)
+		}
+		return n
+	default:
+		return 0
+	}
+}
+
+// bitSize returns the size in bits of the given scalar type.
+func (target *Target) bitSize(t types.Type) int64 {
+	switch t := t.(type) {
+	case *types.IntType:
+		return t.BitSize
+	case *types.PointerType:
+		return target.PointerSize
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindHalf:
+			return 16
+		case types.FloatKindFloat:
+			return 32
+		case types.FloatKindDouble:
+			return 64
+		case types.FloatKindX86FP80:
+			return 80
+		default:
+			return 128
+		}
+	default:
+		return target.IntWidth
+	}
+}
+
+// ceilDiv returns x/y rounded up; or 1 if y is 0.
+func ceilDiv(x, y int64) int64 {
+	if y <= 0 {
+		return 1
+	}
+	return (x + y - 1) / y
+}
+
+// isConst reports whether the given value is a constant.
+func isConst(v value.Value) bool {
+	_, ok := v.(ir.Constant)
+	return ok
+}
@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestCost(t *testing.T) {
+	x := ir.NewParam(types.I32, "x")
+	w := ir.NewParam(types.NewInt(128), "w")
+	v := ir.NewParam(types.NewVector(8, types.I32), "v")
+	p := ir.NewParam(types.NewPointer(types.I32), "p")
+	g := ir.NewFunc("g", types.Void, ir.NewParam(types.I32, "a"), ir.NewParam(types.I32, "b"))
+	// Scalarized target without vector registers.
+	scalar := *DefaultTarget
+	scalar.VectorWidth = 0
+	golden := []struct {
+		in     ir.Instruction
+		target *Target
+		want   int
+	}{
+		// i=0: basic integer operation.
+		{in: ir.NewAdd(x, x), want: 1},
+		// i=1: integer operation legalized into two registers.
+		{in: ir.NewAdd(w, w), want: 2},
+		// i=2: vector operation legalized into two vector registers.
+		{in: ir.NewMul(v, v), want: 6},
+		// i=3: scalarized vector operation.
+		{in: ir.NewMul(v, v), target: &scalar, want: 24},
+		// i=4: division.
+		{in: ir.NewSDiv(x, x), want: 20},
+		// i=5: no-op conversion.
+		{in: ir.NewTrunc(w, types.I32), want: 0},
+		// i=6: conversion.
+		{in: ir.NewSExt(x, types.I64), want: 1},
+		// i=7: memory load and store.
+		{in: ir.NewLoad(p), want: 4},
+		{in: ir.NewStore(x, p), want: 4},
+		// i=9: static alloca.
+		{in: ir.NewAlloca(types.I32), want: 0},
+		// i=10: getelementptr with constant and variable indices.
+		{in: ir.NewGetElementPtr(types.I32, p, constI32(1)), want: 0},
+		{in: ir.NewGetElementPtr(types.I32, p, x), want: 1},
+		// i=12: function call.
+		{in: ir.NewCall(g, x, x), want: 7},
+		// i=13: phi instruction.
+		{in: ir.NewPhi(ir.NewIncoming(x, ir.NewBlock("entry"))), want: 0},
+	}
+	for i, gold := range golden {
+		if got := Cost(gold.in, gold.target); gold.want != got {
+			t.Errorf("i=%d: cost mismatch of `%v`; expected %d, got %d", i, gold.in.Def(), gold.want, got)
+		}
+	}
+}
+
+func TestFuncCost(t *testing.T) {
+	// The condbr and ret terminators cost one branch each, and unconditional
+	// branches are free.
+	if got, want := FuncCost(newDiamond(), nil), 2; want != got {
+		t.Errorf("function cost mismatch; expected %d, got %d", want, got)
+	}
+}
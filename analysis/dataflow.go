@@ -0,0 +1,127 @@
+package analysis
+
+import "github.com/llir/l/ir"
+
+// --- [ Dataflow analysis ] ---------------------------------------------------
+
+// Direction is the direction of a dataflow analysis.
+type Direction uint8
+
+// Dataflow directions.
+const (
+	// Forward dataflow; facts flow from predecessors to successors.
+	Forward Direction = iota
+	// Backward dataflow; facts flow from successors to predecessors.
+	Backward
+)
+
+// Fact is an element of the lattice of a dataflow problem.
+type Fact interface{}
+
+// Problem is a dataflow problem.
+type Problem struct {
+	// Direction of the dataflow problem.
+	Direction Direction
+	// Boundary fact; the input fact of the entry basic block of forward
+	// problems, and of the exit basic blocks (without successors) of backward
+	// problems.
+	Boundary Fact
+	// Initial fact of every other basic block.
+	Init Fact
+	// Transfer returns the output fact of the given basic block, based on its
+	// input fact.
+	Transfer func(block *ir.BasicBlock, in Fact) Fact
+	// Merge returns the merge (or meet) of the two given facts.
+	Merge func(a, b Fact) Fact
+	// Equal reports whether the two given facts are equal.
+	Equal func(a, b Fact) bool
+}
+
+// DataflowResult is the fixed point solution of a dataflow problem.
+//
+// For forward problems, In holds the fact at the start of each basic block and
+// Out the fact at its end. For backward problems, In holds the fact at the end
+// of each basic block and Out the fact at its start.
+type DataflowResult struct {
+	// Input fact of each basic block.
+	In map[*ir.BasicBlock]Fact
+	// Output fact of each basic block.
+	Out map[*ir.BasicBlock]Fact
+}
+
+// Dataflow solves the given dataflow problem for the function, using a
+// worklist algorithm iterating until a fixed point is reached.
+func Dataflow(f *ir.Function, p *Problem) *DataflowResult {
+	res := &DataflowResult{
+		In:  make(map[*ir.BasicBlock]Fact),
+		Out: make(map[*ir.BasicBlock]Fact),
+	}
+	if len(f.Blocks) == 0 {
+		return res
+	}
+	// Edges in the direction of dataflow; from sources to targets.
+	preds := Preds(f)
+	sources := preds
+	targets := make(map[*ir.BasicBlock][]*ir.BasicBlock)
+	for _, block := range f.Blocks {
+		targets[block] = AllEdges.Succs(block)
+	}
+	if p.Direction == Backward {
+		sources, targets = targets, sources
+	}
+	isBoundary := func(block *ir.BasicBlock) bool {
+		if p.Direction == Forward {
+			return block == f.Blocks[0]
+		}
+		return len(sources[block]) == 0
+	}
+	// Initialize facts and worklist; backward problems visit the basic blocks in
+	// reverse order to reduce the number of iterations.
+	var worklist []*ir.BasicBlock
+	inWorklist := make(map[*ir.BasicBlock]bool)
+	for i := range f.Blocks {
+		block := f.Blocks[i]
+		if p.Direction == Backward {
+			block = f.Blocks[len(f.Blocks)-1-i]
+		}
+		res.Out[block] = p.Init
+		worklist = append(worklist, block)
+		inWorklist[block] = true
+	}
+	for len(worklist) > 0 {
+		block := worklist[0]
+		worklist = worklist[1:]
+		inWorklist[block] = false
+		// Merge the output facts of the sources.
+		var in Fact
+		srcs := sources[block]
+		switch {
+		case isBoundary(block):
+			in = p.Boundary
+			for _, src := range srcs {
+				in = p.Merge(in, res.Out[src])
+			}
+		case len(srcs) == 0:
+			// Unreachable basic block.
+			in = p.Init
+		default:
+			in = res.Out[srcs[0]]
+			for _, src := range srcs[1:] {
+				in = p.Merge(in, res.Out[src])
+			}
+		}
+		res.In[block] = in
+		out := p.Transfer(block, in)
+		if p.Equal(out, res.Out[block]) {
+			continue
+		}
+		res.Out[block] = out
+		for _, target := range targets[block] {
+			if !inWorklist[target] {
+				worklist = append(worklist, target)
+				inWorklist[target] = true
+			}
+		}
+	}
+	return res
+}
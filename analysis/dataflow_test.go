@@ -0,0 +1,108 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+// blockSet is a set of basic block names.
+type blockSet map[string]bool
+
+func TestDataflowForward(t *testing.T) {
+	// Compute dominators as a forward must problem.
+	f := newDiamond()
+	all := blockSet{}
+	for _, block := range f.Blocks {
+		all[block.Name()] = true
+	}
+	p := &Problem{
+		Direction: Forward,
+		Boundary:  blockSet{},
+		Init:      all,
+		Transfer: func(block *ir.BasicBlock, in Fact) Fact {
+			out := blockSet{block.Name(): true}
+			for name := range in.(blockSet) {
+				out[name] = true
+			}
+			return out
+		},
+		Merge: func(a, b Fact) Fact {
+			out := blockSet{}
+			for name := range a.(blockSet) {
+				if b.(blockSet)[name] {
+					out[name] = true
+				}
+			}
+			return out
+		},
+		Equal: func(a, b Fact) bool {
+			return reflect.DeepEqual(a, b)
+		},
+	}
+	res := Dataflow(f, p)
+	want := blockSet{"entry": true, "exit": true}
+	if got := res.Out[f.Blocks[3]]; !reflect.DeepEqual(want, got) {
+		t.Errorf("dominators mismatch; expected %v, got %v", want, got)
+	}
+}
+
+func TestDataflowBackward(t *testing.T) {
+	// Compute the set of basic blocks reachable from each basic block as a
+	// backward may problem.
+	f := newDiamond()
+	p := &Problem{
+		Direction: Backward,
+		Boundary:  blockSet{},
+		Init:      blockSet{},
+		Transfer: func(block *ir.BasicBlock, in Fact) Fact {
+			out := blockSet{block.Name(): true}
+			for name := range in.(blockSet) {
+				out[name] = true
+			}
+			return out
+		},
+		Merge: func(a, b Fact) Fact {
+			out := blockSet{}
+			for name := range a.(blockSet) {
+				out[name] = true
+			}
+			for name := range b.(blockSet) {
+				out[name] = true
+			}
+			return out
+		},
+		Equal: func(a, b Fact) bool {
+			return reflect.DeepEqual(a, b)
+		},
+	}
+	res := Dataflow(f, p)
+	want := blockSet{"left": true, "exit": true}
+	if got := res.Out[f.Blocks[1]]; !reflect.DeepEqual(want, got) {
+		t.Errorf("reachable blocks mismatch; expected %v, got %v", want, got)
+	}
+}
+
+// newDiamond returns a function with a diamond-shaped control flow graph.
+//
+//      entry
+//      /   \
+//    left right
+//      \   /
+//      exit
+func newDiamond() *ir.Function {
+	cond := ir.NewParam(types.I1, "cond")
+	f := ir.NewFunc("f", types.Void, cond)
+	entry := ir.NewBlock("entry")
+	left := ir.NewBlock("left")
+	right := ir.NewBlock("right")
+	exit := ir.NewBlock("exit")
+	entry.NewCondBr(cond, left, right)
+	left.NewBr(exit)
+	right.NewBr(exit)
+	exit.NewRet(nil)
+	f.Blocks = append(f.Blocks, entry, left, right, exit)
+	return f
+}
@@ -0,0 +1,478 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/value"
+)
+
+// --- [ Loop dependence analysis ] --------------------------------------------
+
+// DepDirection is the direction of a dependence with regards to a loop.
+type DepDirection uint8
+
+// Dependence directions.
+const (
+	// DirAll denotes an unknown direction.
+	DirAll DepDirection = iota // *
+	// DirLT denotes a dependence carried forward by the loop; the source
+	// access happens in an earlier iteration than the destination access.
+	DirLT // <
+	// DirEQ denotes a loop-independent dependence; both accesses happen in
+	// the same iteration.
+	DirEQ // =
+	// DirGT denotes a dependence carried backward by the loop.
+	DirGT // >
+)
+
+// String returns the string representation of the dependence direction.
+func (dir DepDirection) String() string {
+	switch dir {
+	case DirLT:
+		return "<"
+	case DirEQ:
+		return "="
+	case DirGT:
+		return ">"
+	}
+	return "*"
+}
+
+// Dependence is a memory dependence between two accesses of a loop nest, at
+// least one of which is a store. Dependences are normalized so that the source
+// access executes first; i.e. the first direction other than DirEQ is never
+// DirGT, except for confused dependences.
+type Dependence struct {
+	// Source access (load or store).
+	Src ir.Instruction
+	// Destination access (load or store).
+	Dst ir.Instruction
+	// Loops enclosing both accesses, outermost first.
+	Loops []*Loop
+	// Dependence distance per loop, in number of iterations; only valid if
+	// the direction of the loop is not DirAll.
+	Distance []int64
+	// Dependence direction per loop.
+	Direction []DepDirection
+	// Confused specifies whether the accesses could not be analyzed precisely,
+	// in which case the dependence is conservatively assumed in all
+	// directions.
+	Confused bool
+}
+
+// String returns the string representation of the dependence, as a direction
+// vector (e.g. "[< =]").
+func (dep *Dependence) String() string {
+	dirs := make([]string, len(dep.Direction))
+	for i, dir := range dep.Direction {
+		dirs[i] = dir.String()
+	}
+	return fmt.Sprintf("[%s]", strings.Join(dirs, " "))
+}
+
+// LoopDependences returns the memory dependences between the load and store
+// instructions of the given loop (including nested loops). Pairs of accesses
+// proven independent are omitted.
+//
+// Array accesses are analyzed by their getelementptr subscripts, which must be
+// affine functions of the induction variables of the enclosing loops (e.g.
+// a[i+1][2*j]). Accesses of distinct base pointers are assumed not to alias if
+// both bases are distinct allocas or global variables.
+func LoopDependences(info *LoopInfo, loop *Loop) []*Dependence {
+	var accesses []*access
+	for _, block := range loop.Blocks {
+		for _, inst := range block.Insts {
+			var ptr value.Value
+			store := false
+			switch inst := inst.(type) {
+			case *ir.InstLoad:
+				ptr = inst.Src
+			case *ir.InstStore:
+				ptr = inst.Dst
+				store = true
+			default:
+				continue
+			}
+			accesses = append(accesses, newAccess(info, inst, ptr, store, info.LoopFor(block)))
+		}
+	}
+	var deps []*Dependence
+	for i, a := range accesses {
+		for _, b := range accesses[i:] {
+			if !a.store && !b.store {
+				continue
+			}
+			if a == b && !a.store {
+				continue
+			}
+			dep := depend(a, b)
+			if dep == nil {
+				continue
+			}
+			if a == b && dep.isLoopIndependent() {
+				// An access does not depend on itself within an iteration.
+				continue
+			}
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// ___ [ Memory access ] _______________________________________________________
+
+// access is a memory access of a loop.
+type access struct {
+	// Load or store instruction.
+	inst ir.Instruction
+	// Store access.
+	store bool
+	// Innermost loop containing the access.
+	loop *Loop
+	// Base pointer.
+	base value.Value
+	// Affine subscripts; nil if not affine.
+	subscripts []*affine
+}
+
+// newAccess returns a new memory access of the given instruction and pointer
+// operand.
+func newAccess(info *LoopInfo, inst ir.Instruction, ptr value.Value, store bool, loop *Loop) *access {
+	a := &access{inst: inst, store: store, loop: loop, base: ptr}
+	gep, ok := ptr.(*ir.InstGetElementPtr)
+	if !ok {
+		a.subscripts = []*affine{}
+		return a
+	}
+	a.base = gep.Src
+	for _, index := range gep.Indices {
+		sub, ok := affineOf(info, index)
+		if !ok {
+			a.subscripts = nil
+			return a
+		}
+		a.subscripts = append(a.subscripts, sub)
+	}
+	return a
+}
+
+// depend returns the dependence between the given memory accesses, or nil if
+// proven independent.
+func depend(a, b *access) *Dependence {
+	loops := commonLoops(a.loop, b.loop)
+	dep := &Dependence{
+		Src:       a.inst,
+		Dst:       b.inst,
+		Loops:     loops,
+		Distance:  make([]int64, len(loops)),
+		Direction: make([]DepDirection, len(loops)),
+	}
+	if a.base != b.base {
+		if isDistinctObject(a.base) && isDistinctObject(b.base) {
+			return nil
+		}
+		dep.Confused = true
+		return dep
+	}
+	if a.subscripts == nil || b.subscripts == nil || len(a.subscripts) != len(b.subscripts) {
+		dep.Confused = true
+		return dep
+	}
+	known := make([]bool, len(loops))
+	for i := range a.subscripts {
+		x, y := a.subscripts[i], b.subscripts[i]
+		if !x.sameSymbols(y) {
+			dep.Confused = true
+			continue
+		}
+		// Solve x(i) = y(i + d) for the distance d of each loop.
+		diff := x.c - y.c
+		ziv := true
+		for j, l := range loops {
+			cx, cy := x.coeffs[l], y.coeffs[l]
+			if cx == 0 && cy == 0 {
+				continue
+			}
+			ziv = false
+			if cx != cy {
+				// Not a strong SIV subscript; unknown direction.
+				dep.Confused = true
+				continue
+			}
+			if diff%cx != 0 {
+				// No integer solution; independent.
+				return nil
+			}
+			d := diff / cx
+			if known[j] && dep.Distance[j] != d {
+				// Inconsistent distances across subscripts; independent.
+				return nil
+			}
+			known[j] = true
+			dep.Distance[j] = d
+		}
+		// Coefficients of loops not common to both accesses.
+		if x.hasOtherLoops(loops) || y.hasOtherLoops(loops) {
+			dep.Confused = true
+			continue
+		}
+		if ziv && diff != 0 {
+			// Distinct constant subscripts; independent.
+			return nil
+		}
+	}
+	for j := range loops {
+		switch {
+		case !known[j]:
+			// Distance not constrained by any subscript.
+			dep.Direction[j] = DirAll
+		case dep.Distance[j] > 0:
+			dep.Direction[j] = DirLT
+		case dep.Distance[j] < 0:
+			dep.Direction[j] = DirGT
+		default:
+			dep.Direction[j] = DirEQ
+		}
+	}
+	// Normalize the dependence so that the source access executes first.
+	for _, dir := range dep.Direction {
+		if dir == DirEQ {
+			continue
+		}
+		if dir == DirGT {
+			dep.Src, dep.Dst = dep.Dst, dep.Src
+			for j := range dep.Distance {
+				dep.Distance[j] = -dep.Distance[j]
+				switch dep.Direction[j] {
+				case DirLT:
+					dep.Direction[j] = DirGT
+				case DirGT:
+					dep.Direction[j] = DirLT
+				}
+			}
+		}
+		break
+	}
+	return dep
+}
+
+// isLoopIndependent reports whether the dependence holds only within the same
+// iteration of every loop.
+func (dep *Dependence) isLoopIndependent() bool {
+	if dep.Confused {
+		return false
+	}
+	for _, dir := range dep.Direction {
+		if dir != DirEQ {
+			return false
+		}
+	}
+	return true
+}
+
+// commonLoops returns the loops enclosing both of the given loops, outermost
+// first.
+func commonLoops(a, b *Loop) []*Loop {
+	outer := make(map[*Loop]bool)
+	for l := a; l != nil; l = l.Parent {
+		outer[l] = true
+	}
+	var loops []*Loop
+	for l := b; l != nil; l = l.Parent {
+		if outer[l] {
+			loops = append([]*Loop{l}, loops...)
+		}
+	}
+	return loops
+}
+
+// isDistinctObject reports whether the given pointer refers to a distinct
+// memory object, which may not alias other distinct memory objects.
+func isDistinctObject(ptr value.Value) bool {
+	switch ptr.(type) {
+	case *ir.InstAlloca, *ir.Global:
+		return true
+	}
+	return false
+}
+
+// ___ [ Affine expressions ] __________________________________________________
+
+// affine is an affine expression over the iteration counters of loops and
+// loop-invariant symbolic values; e.g. 2*i + n + 3.
+type affine struct {
+	// Coefficient of the iteration counter of each loop.
+	coeffs map[*Loop]int64
+	// Coefficient of each loop-invariant symbolic value.
+	syms map[value.Value]int64
+	// Constant term.
+	c int64
+}
+
+// newAffine returns a new constant affine expression.
+func newAffine(c int64) *affine {
+	return &affine{coeffs: make(map[*Loop]int64), syms: make(map[value.Value]int64), c: c}
+}
+
+// add returns the sum of a and b scaled by the factor k; i.e. a + k*b.
+func (a *affine) add(b *affine, k int64) *affine {
+	sum := newAffine(a.c + k*b.c)
+	for l, c := range a.coeffs {
+		sum.coeffs[l] += c
+	}
+	for l, c := range b.coeffs {
+		sum.coeffs[l] += k * c
+	}
+	for v, c := range a.syms {
+		sum.syms[v] += c
+	}
+	for v, c := range b.syms {
+		sum.syms[v] += k * c
+	}
+	return sum
+}
+
+// scale returns a scaled by the factor k.
+func (a *affine) scale(k int64) *affine {
+	return newAffine(0).add(a, k)
+}
+
+// isConst reports whether the affine expression is a constant.
+func (a *affine) isConst() bool {
+	for _, c := range a.coeffs {
+		if c != 0 {
+			return false
+		}
+	}
+	for _, c := range a.syms {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sameSymbols reports whether a and b have the same symbolic terms.
+func (a *affine) sameSymbols(b *affine) bool {
+	for v, c := range a.syms {
+		if b.syms[v] != c {
+			return false
+		}
+	}
+	for v, c := range b.syms {
+		if a.syms[v] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// hasOtherLoops reports whether a has non-zero coefficients for loops not
+// among the given loops.
+func (a *affine) hasOtherLoops(loops []*Loop) bool {
+outer:
+	for l, c := range a.coeffs {
+		if c == 0 {
+			continue
+		}
+		for _, m := range loops {
+			if l == m {
+				continue outer
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// affineOf returns the affine expression of the given integer value, in terms
+// of the iteration counters of the loops of info. The boolean return value
+// indicates success.
+func affineOf(info *LoopInfo, v value.Value) (*affine, bool) {
+	switch v := v.(type) {
+	case *ir.ConstInt:
+		if !v.X.IsInt64() {
+			return nil, false
+		}
+		return newAffine(v.X.Int64()), true
+	case *ir.InstPhi:
+		iv, ok := BasicInductionVar(info, v)
+		if !ok {
+			break
+		}
+		start, ok := affineOf(info, iv.Start)
+		if !ok {
+			return nil, false
+		}
+		a := start.add(newAffine(0), 0)
+		a.coeffs[iv.Loop] += iv.Step
+		return a, true
+	case *ir.InstAdd:
+		return affineBinary(info, v.X, v.Y, 1)
+	case *ir.InstSub:
+		return affineBinary(info, v.X, v.Y, -1)
+	case *ir.InstMul:
+		x, ok := affineOf(info, v.X)
+		if !ok {
+			return nil, false
+		}
+		y, ok := affineOf(info, v.Y)
+		if !ok {
+			return nil, false
+		}
+		switch {
+		case x.isConst():
+			return y.scale(x.c), true
+		case y.isConst():
+			return x.scale(y.c), true
+		}
+		return nil, false
+	case *ir.InstSExt:
+		return affineOf(info, v.From)
+	case *ir.InstZExt:
+		return affineOf(info, v.From)
+	}
+	if isLoopInvariant(info, v) {
+		a := newAffine(0)
+		a.syms[v] = 1
+		return a, true
+	}
+	return nil, false
+}
+
+// affineBinary returns the affine expression x + k*y.
+func affineBinary(info *LoopInfo, x, y value.Value, k int64) (*affine, bool) {
+	ax, ok := affineOf(info, x)
+	if !ok {
+		return nil, false
+	}
+	ay, ok := affineOf(info, y)
+	if !ok {
+		return nil, false
+	}
+	return ax.add(ay, k), true
+}
+
+// isLoopInvariant reports whether the given value is defined outside of every
+// loop; e.g. constants, parameters and instructions outside of loops.
+func isLoopInvariant(info *LoopInfo, v value.Value) bool {
+	switch v := v.(type) {
+	case ir.Constant, *ir.Param:
+		return true
+	case ir.Instruction:
+		for block, l := range info.loopOf {
+			if l == nil {
+				continue
+			}
+			for _, inst := range block.Insts {
+				if inst == v {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
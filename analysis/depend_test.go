@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestLoopDependences(t *testing.T) {
+	// for (i = 0; i < n; i++) {
+	//    a[i+1] = a[i] + 1;
+	//    b[2*i] = b[2*i+1];
+	// }
+	n := ir.NewParam(types.I32, "n")
+	f := ir.NewFunc("f", types.Void, n)
+	entry := ir.NewBlock("entry")
+	arrayType := types.NewArray(100, types.I32)
+	a := entry.NewAlloca(arrayType)
+	b := entry.NewAlloca(arrayType)
+	header := ir.NewBlock("header")
+	body := ir.NewBlock("body")
+	exit := ir.NewBlock("exit")
+	f.Blocks = append(f.Blocks, entry, header, body, exit)
+	entry.NewBr(header)
+	i := header.NewPhi()
+	i.Typ = types.I32
+	cond := header.NewICmp(enum.IPredSLT, i, n)
+	header.NewCondBr(cond, body, exit)
+	one := ir.NewInt(types.I32, 1)
+	two := ir.NewInt(types.I32, 2)
+	zero := ir.NewInt(types.I32, 0)
+	// a[i+1] = a[i] + 1
+	x := body.NewLoad(body.NewGetElementPtr(arrayType, a, zero, i))
+	inc := body.NewAdd(i, one)
+	store := body.NewStore(body.NewAdd(x, one), body.NewGetElementPtr(arrayType, a, zero, inc))
+	// b[2*i] = b[2*i+1]
+	i2 := body.NewMul(two, i)
+	y := body.NewLoad(body.NewGetElementPtr(arrayType, b, zero, body.NewAdd(i2, one)))
+	body.NewStore(y, body.NewGetElementPtr(arrayType, b, zero, i2))
+	body.NewBr(header)
+	i.Incs = append(i.Incs, ir.NewIncoming(zero, entry), ir.NewIncoming(inc, body))
+	exit.NewRet(nil)
+
+	info := FindLoops(f)
+	if len(info.Loops) != 1 {
+		t.Fatalf("invalid number of loops; expected 1, got %d", len(info.Loops))
+	}
+	loop := info.Loops[0]
+	if loop.Header != header || loop.Preheader != entry {
+		t.Fatalf("invalid loop; header %v, preheader %v", loop.Header, loop.Preheader)
+	}
+	deps := LoopDependences(info, loop)
+	if len(deps) != 1 {
+		t.Fatalf("invalid number of dependences; expected 1, got %d (%v)", len(deps), deps)
+	}
+	dep := deps[0]
+	if dep.Src != store || dep.Dst != x {
+		t.Errorf("invalid dependence; expected flow dependence from store to load, got %v to %v", dep.Src, dep.Dst)
+	}
+	if got, want := dep.String(), "[<]"; want != got {
+		t.Errorf("direction vector mismatch; expected %v, got %v", want, got)
+	}
+	if got, want := dep.Distance[0], int64(1); want != got {
+		t.Errorf("distance mismatch; expected %d, got %d", want, got)
+	}
+}
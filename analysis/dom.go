@@ -0,0 +1,107 @@
+package analysis
+
+import "github.com/llir/l/ir"
+
+// --- [ Dominator tree ] ------------------------------------------------------
+
+// DomTree is the dominator tree of a function.
+type DomTree struct {
+	// Entry basic block.
+	entry *ir.BasicBlock
+	// Immediate dominator of each reachable basic block; the entry basic block
+	// is its own immediate dominator.
+	idom map[*ir.BasicBlock]*ir.BasicBlock
+	// Reverse postorder number of each reachable basic block.
+	order map[*ir.BasicBlock]int
+}
+
+// Dominators returns the dominator tree of the given function, including
+// unwind edges to exception handling basic blocks.
+func Dominators(f *ir.Function) *DomTree {
+	return AllEdges.Dominators(f)
+}
+
+// Dominators returns the dominator tree of the given function along the
+// control flow edges.
+//
+// References:
+//    A Simple, Fast Dominance Algorithm (Cooper, Harvey and Kennedy, 2001)
+//    https://www.cs.rice.edu/~keith/EMBED/dom.pdf
+func (edges Edges) Dominators(f *ir.Function) *DomTree {
+	d := &DomTree{
+		idom:  make(map[*ir.BasicBlock]*ir.BasicBlock),
+		order: make(map[*ir.BasicBlock]int),
+	}
+	rpo := edges.ReversePostorder(f)
+	if len(rpo) == 0 {
+		return d
+	}
+	for i, block := range rpo {
+		d.order[block] = i
+	}
+	d.entry = rpo[0]
+	d.idom[d.entry] = d.entry
+	preds := edges.Preds(f)
+	for changed := true; changed; {
+		changed = false
+		for _, block := range rpo[1:] {
+			var newIdom *ir.BasicBlock
+			for _, pred := range preds[block] {
+				if _, ok := d.idom[pred]; !ok {
+					// Skip unprocessed and unreachable predecessors.
+					continue
+				}
+				if newIdom == nil {
+					newIdom = pred
+				} else {
+					newIdom = d.intersect(pred, newIdom)
+				}
+			}
+			if d.idom[block] != newIdom {
+				d.idom[block] = newIdom
+				changed = true
+			}
+		}
+	}
+	return d
+}
+
+// Idom returns the immediate dominator of the given basic block, or nil if the
+// basic block is the entry basic block or unreachable.
+func (d *DomTree) Idom(block *ir.BasicBlock) *ir.BasicBlock {
+	if block == d.entry {
+		return nil
+	}
+	return d.idom[block]
+}
+
+// Dominates reports whether the basic block a dominates the basic block b.
+// Every basic block dominates itself.
+func (d *DomTree) Dominates(a, b *ir.BasicBlock) bool {
+	if _, ok := d.idom[b]; !ok {
+		// Unreachable basic block.
+		return false
+	}
+	for {
+		if a == b {
+			return true
+		}
+		if b == d.entry {
+			return false
+		}
+		b = d.idom[b]
+	}
+}
+
+// intersect returns the nearest common dominator of the given basic blocks.
+func (d *DomTree) intersect(a, b *ir.BasicBlock) *ir.BasicBlock {
+	for a != b {
+		for d.order[a] > d.order[b] {
+			a = d.idom[a]
+		}
+		for d.order[b] > d.order[a] {
+			b = d.idom[b]
+		}
+	}
+	return a
+}
@@ -0,0 +1,194 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/value"
+)
+
+// --- [ Address-taken functions ] ---------------------------------------------
+
+// AddressTaken returns the functions of the given module which have their
+// address taken; i.e. are used other than as the callee of a direct call or
+// invoke (e.g. stored, passed as an argument, or referenced by the initializer
+// of a global variable).
+//
+// Only uses within the module are considered; functions with external linkage
+// may have their address taken by other modules.
+func AddressTaken(m *ir.Module) map[*ir.Function]bool {
+	// Number of uses and direct callee uses of each function.
+	uses := make(map[*ir.Function]int)
+	calls := make(map[*ir.Function]int)
+	visit := func(root interface{}) {
+		ir.Walk(root, func(n interface{}) bool {
+			switch n := n.(type) {
+			case *ir.Function:
+				if n != root {
+					uses[n]++
+				}
+			case *ir.InstCall:
+				if callee, ok := n.Callee.(*ir.Function); ok {
+					calls[callee]++
+				}
+			case *ir.TermInvoke:
+				if invokee, ok := n.Invokee.(*ir.Function); ok {
+					calls[invokee]++
+				}
+			}
+			return true
+		})
+	}
+	for _, g := range m.Globals {
+		visit(g)
+	}
+	for _, f := range m.Funcs {
+		visit(f)
+	}
+	taken := make(map[*ir.Function]bool)
+	for f, n := range uses {
+		if n > calls[f] {
+			taken[f] = true
+		}
+	}
+	return taken
+}
+
+// --- [ Escaping allocas ] ----------------------------------------------------
+
+// EscapingAllocas returns the alloca instructions of the given function the
+// address of which escapes; i.e. which may be accessed other than through
+// loads and stores of the function. The address of an alloca escapes if it
+// (or a pointer derived from it by getelementptr, bitcast, addrspacecast,
+// select or phi) is stored to memory, returned, converted to an integer, or
+// passed to a callee (except to parameters marked nocapture, and to the
+// lifetime and debug intrinsics).
+//
+// Allocas which do not escape are candidates for promotion to registers, as
+// all their accesses are known.
+func EscapingAllocas(f *ir.Function) map[*ir.InstAlloca]bool {
+	users := make(map[value.Value][]interface{})
+	addUses := func(inst interface{}) {
+		ir.Walk(inst, func(n interface{}) bool {
+			if x, ok := n.(value.Value); ok && n != inst {
+				users[x] = append(users[x], inst)
+			}
+			return true
+		})
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			addUses(inst)
+		}
+		if block.Term != nil {
+			addUses(block.Term)
+		}
+	}
+	escaping := make(map[*ir.InstAlloca]bool)
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if alloca, ok := inst.(*ir.InstAlloca); ok && escapes(alloca, users) {
+				escaping[alloca] = true
+			}
+		}
+	}
+	return escaping
+}
+
+// ### [ Helper functions ] ####################################################
+
+// escapes reports whether the address of the given alloca escapes, based on
+// the given users of each value.
+func escapes(alloca *ir.InstAlloca, users map[value.Value][]interface{}) bool {
+	seen := map[value.Value]bool{alloca: true}
+	queue := []value.Value{alloca}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, user := range users[p] {
+			var derived value.Value
+			switch user := user.(type) {
+			case *ir.InstLoad, *ir.InstICmp:
+				// Loads from and comparisons of the address do not capture it.
+				continue
+			case *ir.InstStore:
+				if user.Src == p {
+					return true
+				}
+				continue
+			case *ir.InstCmpXchg:
+				if user.Cmp == p || user.New == p {
+					return true
+				}
+				continue
+			case *ir.InstAtomicRMW:
+				if user.X == p {
+					return true
+				}
+				continue
+			case *ir.InstCall:
+				if capturesArg(user.Callee, user.Args, p) {
+					return true
+				}
+				continue
+			case *ir.TermInvoke:
+				if capturesArg(user.Invokee, user.Args, p) {
+					return true
+				}
+				continue
+			case *ir.InstGetElementPtr, *ir.InstBitCast, *ir.InstAddrSpaceCast, *ir.InstSelect, *ir.InstPhi:
+				derived = user.(value.Value)
+			default:
+				// Conservatively, any other use (e.g. ret or ptrtoint) escapes.
+				return true
+			}
+			if !seen[derived] {
+				seen[derived] = true
+				queue = append(queue, derived)
+			}
+		}
+	}
+	return false
+}
+
+// capturesArg reports whether a call of the given callee with the given
+// arguments may capture the pointer p; i.e. if p is the callee, or is passed
+// to a parameter not marked nocapture of a callee other than the lifetime and
+// debug intrinsics.
+func capturesArg(callee value.Value, args []value.Value, p value.Value) bool {
+	if callee == p {
+		return true
+	}
+	f, _ := callee.(*ir.Function)
+	if f != nil && (strings.HasPrefix(f.Name(), "llvm.lifetime.") || strings.HasPrefix(f.Name(), "llvm.dbg.")) {
+		return false
+	}
+	for i, arg := range args {
+		x, attrs := arg, []ir.ParamAttribute(nil)
+		if a, ok := arg.(*ir.Arg); ok {
+			x, attrs = a.Value, a.Attrs
+		}
+		if x != p {
+			continue
+		}
+		if f != nil && i < len(f.Params) {
+			attrs = append(attrs, f.Params[i].Attrs...)
+		}
+		if !hasParamAttr(attrs, enum.ParamAttrNoCapture) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasParamAttr reports whether the given parameter attribute is present among
+// attrs.
+func hasParamAttr(attrs []ir.ParamAttribute, attr enum.ParamAttr) bool {
+	for _, a := range attrs {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestAddressTaken(t *testing.T) {
+	m := &ir.Module{}
+	called := m.NewFunc("called", types.Void)
+	stored := m.NewFunc("stored", types.Void)
+	global := m.NewFunc("global", types.Void)
+	m.NewGlobalDef("fp", global)
+	f := m.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	entry.NewCall(called)
+	slot := entry.NewAlloca(stored.Type())
+	entry.NewStore(stored, slot)
+	entry.NewCall(stored)
+	entry.NewRet(nil)
+	taken := AddressTaken(m)
+	for _, g := range []*ir.Function{called, f} {
+		if taken[g] {
+			t.Errorf("unexpected address taken of %v", g.Ident())
+		}
+	}
+	for _, g := range []*ir.Function{stored, global} {
+		if !taken[g] {
+			t.Errorf("expected address taken of %v", g.Ident())
+		}
+	}
+}
+
+func TestEscapingAllocas(t *testing.T) {
+	m := &ir.Module{}
+	unknown := m.NewFunc("unknown", types.Void, ir.NewParam(types.I8Ptr, "p"))
+	lifetime := m.NewFunc("llvm.lifetime.start.p0i8", types.Void, ir.NewParam(types.I64, "size"), ir.NewParam(types.I8Ptr, "p"))
+	f := m.NewFunc("f", types.I32Ptr)
+	entry := f.NewBlock("entry")
+	// Only loaded and stored.
+	local := entry.NewAlloca(types.I32)
+	// Stored to memory through a derived pointer.
+	stored := entry.NewAlloca(types.I32)
+	// Passed to an unknown callee.
+	passed := entry.NewAlloca(types.I8)
+	// Passed to a nocapture parameter and the lifetime intrinsic.
+	nocapture := entry.NewAlloca(types.I8)
+	// Returned.
+	returned := entry.NewAlloca(types.I32)
+	slot := entry.NewAlloca(types.I32Ptr)
+	entry.NewStore(ir.NewInt(types.I32, 1), local)
+	entry.NewLoad(local)
+	gep := entry.NewGetElementPtr(types.I32, stored, ir.NewInt(types.I64, 0))
+	entry.NewStore(gep, slot)
+	entry.NewCall(unknown, passed)
+	entry.NewCall(unknown, ir.NewArg(nocapture, enum.ParamAttrNoCapture))
+	entry.NewCall(lifetime, ir.NewInt(types.I64, 1), nocapture)
+	entry.NewRet(returned)
+	escaping := EscapingAllocas(f)
+	golden := []struct {
+		alloca *ir.InstAlloca
+		want   bool
+	}{
+		// i=0
+		{alloca: local, want: false},
+		// i=1
+		{alloca: stored, want: true},
+		// i=2
+		{alloca: passed, want: true},
+		// i=3
+		{alloca: nocapture, want: false},
+		// i=4
+		{alloca: returned, want: true},
+		// i=5
+		{alloca: slot, want: false},
+	}
+	for i, g := range golden {
+		if got := escaping[g.alloca]; got != g.want {
+			t.Errorf("i=%d: escape mismatch; expected %v, got %v", i, g.want, got)
+		}
+	}
+}
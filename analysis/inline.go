@@ -0,0 +1,358 @@
+package analysis
+
+import (
+	"math/big"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/value"
+)
+
+// --- [ Inline cost ] ---------------------------------------------------------
+
+// InlineParams are the parameters of the inline cost analysis.
+type InlineParams struct {
+	// Cost model of the target; or nil for the default target.
+	Target *Target
+	// Maximum cost of inlining a call site; call sites with a higher cost are
+	// not inlined.
+	Threshold int
+	// Cost saved by removing the call, in addition to the cost of the call
+	// instruction itself (e.g. for the prologue and epilogue of the callee).
+	CallPenalty int
+	// Bonus for each constant argument of the call site, in addition to the
+	// cost of the callee instructions and basic blocks which fold away given
+	// the constant arguments.
+	ConstArgBonus int
+	// Maximum size of callees (as estimated by FuncCost), regardless of
+	// bonuses; or 0 for no size cap. Callees marked alwaysinline are exempt.
+	MaxSize int
+}
+
+// DefaultInlineParams are the default parameters of the inline cost analysis.
+var DefaultInlineParams = &InlineParams{
+	Threshold:     45,
+	CallPenalty:   5,
+	ConstArgBonus: 2,
+	MaxSize:       1000,
+}
+
+// InlineCost is the result of the inline cost analysis of a call site.
+type InlineCost struct {
+	// Estimated cost of inlining the call site; the cost of the callee
+	// instructions reachable given the constant arguments, minus the cost of
+	// the call and bonuses.
+	Cost int
+	// Threshold of the call site.
+	Threshold int
+	// The call site must be inlined (e.g. alwaysinline callee).
+	Always bool
+	// The call site may not be inlined (e.g. recursive call or declaration).
+	Never bool
+	// Reason of Always and Never; or empty if neither.
+	Reason string
+}
+
+// ShouldInline reports whether the call site should be inlined; i.e. if it
+// must be inlined, or if it may be inlined and its cost is within the
+// threshold.
+func (c *InlineCost) ShouldInline() bool {
+	if c.Always {
+		return true
+	}
+	return !c.Never && c.Cost <= c.Threshold
+}
+
+// AnalyzeInline returns the estimated cost of inlining the given call of the
+// caller function. The default parameters are used if params is nil.
+//
+// The body of the callee is simulated with the constant arguments of the call
+// site bound to the parameters. Instructions with constant operands are
+// assumed to fold away, and conditional branches and switches on integer
+// constants (including integer comparisons of constants) are assumed to fold
+// to unconditional branches, the dead successors of which are not counted.
+func AnalyzeInline(caller *ir.Function, call *ir.InstCall, params *InlineParams) *InlineCost {
+	if params == nil {
+		params = DefaultInlineParams
+	}
+	c := &InlineCost{Threshold: params.Threshold}
+	callee, ok := call.Callee.(*ir.Function)
+	switch {
+	case !ok:
+		return c.never("indirect call")
+	case len(callee.Blocks) == 0:
+		return c.never("callee is a declaration")
+	case callee == caller || calls(callee, callee):
+		return c.never("recursive call")
+	case hasFuncAttr(call.FuncAttrs, enum.FuncAttrNoInline) || hasFuncAttr(callee.FuncAttrs, enum.FuncAttrNoInline):
+		return c.never("noinline")
+	case callee.Sig.Variadic:
+		return c.never("variadic callee")
+	case hasIndirectBr(callee):
+		return c.never("callee contains indirectbr")
+	case hasFuncAttr(callee.FuncAttrs, enum.FuncAttrAlwaysInline):
+		c.Always = true
+		c.Reason = "alwaysinline"
+	case params.MaxSize > 0 && FuncCost(callee, params.Target) > params.MaxSize:
+		return c.never("callee too large")
+	}
+	s := &inlineSim{
+		target: params.Target,
+		folded: make(map[value.Value]bool),
+		consts: make(map[value.Value]*ir.ConstInt),
+	}
+	numConstArgs := 0
+	for i, arg := range call.Args {
+		if i >= len(callee.Params) {
+			break
+		}
+		if _, ok := arg.(ir.Constant); ok {
+			numConstArgs++
+			s.folded[callee.Params[i]] = true
+			if x, ok := arg.(*ir.ConstInt); ok {
+				s.consts[callee.Params[i]] = x
+			}
+		}
+	}
+	c.Cost = s.cost(callee) - Cost(call, params.Target) - params.CallPenalty - params.ConstArgBonus*numConstArgs
+	return c
+}
+
+// ### [ Helper functions ] ####################################################
+
+// never marks the call site as never inlined for the given reason.
+func (c *InlineCost) never(reason string) *InlineCost {
+	c.Never = true
+	c.Reason = reason
+	return c
+}
+
+// inlineSim is a simulation of the body of a callee, given the constant
+// arguments of a call site.
+type inlineSim struct {
+	// Cost model of the target.
+	target *Target
+	// Values which fold to constants.
+	folded map[value.Value]bool
+	// Values which fold to known integer constants.
+	consts map[value.Value]*ir.ConstInt
+}
+
+// cost returns the cost of the basic blocks of the given callee reachable
+// given the constant arguments.
+func (s *inlineSim) cost(f *ir.Function) int {
+	live := map[*ir.BasicBlock]bool{f.Blocks[0]: true}
+	cost := 0
+	for _, block := range ReversePostorder(f) {
+		if !live[block] {
+			continue
+		}
+		for _, inst := range block.Insts {
+			if s.fold(inst) {
+				continue
+			}
+			cost += Cost(inst, s.target)
+		}
+		if block.Term == nil {
+			continue
+		}
+		if target, ok := s.foldTerm(block.Term); ok {
+			live[target] = true
+			continue
+		}
+		for _, succ := range block.Term.Succs() {
+			live[succ] = true
+		}
+		cost += TermCost(block.Term, s.target)
+	}
+	return cost
+}
+
+// fold reports whether the given instruction folds to a constant, given the
+// values which fold to constants, and records its known integer value if
+// present.
+func (s *inlineSim) fold(inst ir.Instruction) bool {
+	v, ok := inst.(value.Value)
+	if !ok {
+		return false
+	}
+	switch inst := inst.(type) {
+	case *ir.InstICmp:
+		x, xok := s.constOf(inst.X)
+		y, yok := s.constOf(inst.Y)
+		if xok && yok {
+			if icmp(inst.Pred, x, y) {
+				s.consts[v] = ir.True
+			} else {
+				s.consts[v] = ir.False
+			}
+		}
+	case *ir.InstSelect:
+		if cond, ok := s.constOf(inst.Cond); ok {
+			x := inst.Y
+			if cond.X.Sign() != 0 {
+				x = inst.X
+			}
+			if c, ok := s.constOf(x); ok {
+				s.consts[v] = c
+			}
+			if s.isFolded(x) {
+				s.folded[v] = true
+				return true
+			}
+		}
+	case *ir.InstAdd, *ir.InstSub, *ir.InstMul, *ir.InstShl, *ir.InstLShr, *ir.InstAShr, *ir.InstAnd, *ir.InstOr, *ir.InstXor,
+		*ir.InstFAdd, *ir.InstFSub, *ir.InstFMul, *ir.InstFCmp,
+		*ir.InstTrunc, *ir.InstZExt, *ir.InstSExt, *ir.InstBitCast, *ir.InstPtrToInt, *ir.InstIntToPtr,
+		*ir.InstExtractValue, *ir.InstExtractElement, *ir.InstGetElementPtr:
+		// Pure instructions which fold if all operands are constant.
+	default:
+		return false
+	}
+	for _, x := range operands(inst) {
+		if !s.isFolded(x) {
+			return false
+		}
+	}
+	s.folded[v] = true
+	return true
+}
+
+// foldTerm returns the single live successor of the given terminator if its
+// condition folds to a known integer constant.
+func (s *inlineSim) foldTerm(term ir.Terminator) (*ir.BasicBlock, bool) {
+	switch term := term.(type) {
+	case *ir.TermCondBr:
+		cond, ok := s.constOf(term.Cond)
+		if !ok {
+			return nil, false
+		}
+		if cond.X.Sign() != 0 {
+			return term.TargetTrue, true
+		}
+		return term.TargetFalse, true
+	case *ir.TermSwitch:
+		x, ok := s.constOf(term.X)
+		if !ok {
+			return nil, false
+		}
+		for _, c := range term.Cases {
+			if y, ok := c.X.(*ir.ConstInt); ok && icmp(enum.IPredEQ, x, y) {
+				return c.Target, true
+			}
+		}
+		return term.TargetDefault, true
+	}
+	return nil, false
+}
+
+// isFolded reports whether the given value folds to a constant.
+func (s *inlineSim) isFolded(v value.Value) bool {
+	if _, ok := v.(ir.Constant); ok {
+		return true
+	}
+	return s.folded[v]
+}
+
+// constOf returns the known integer value of the given value.
+func (s *inlineSim) constOf(v value.Value) (*ir.ConstInt, bool) {
+	if c, ok := v.(*ir.ConstInt); ok {
+		return c, true
+	}
+	c, ok := s.consts[v]
+	return c, ok
+}
+
+// operands returns the operands of the given instruction.
+func operands(inst ir.Instruction) []value.Value {
+	var xs []value.Value
+	ir.Walk(inst, func(n interface{}) bool {
+		if n == inst {
+			return true
+		}
+		if x, ok := n.(value.Value); ok {
+			xs = append(xs, x)
+			return false
+		}
+		return true
+	})
+	return xs
+}
+
+// icmp reports whether the integer comparison of the given constants holds.
+func icmp(pred enum.IPred, x, y *ir.ConstInt) bool {
+	n := x.Typ.BitSize
+	us := unsigned(x.X, n).Cmp(unsigned(y.X, n))
+	ss := signed(x.X, n).Cmp(signed(y.X, n))
+	switch pred {
+	case enum.IPredEQ:
+		return us == 0
+	case enum.IPredNE:
+		return us != 0
+	case enum.IPredSGE:
+		return ss >= 0
+	case enum.IPredSGT:
+		return ss > 0
+	case enum.IPredSLE:
+		return ss <= 0
+	case enum.IPredSLT:
+		return ss < 0
+	case enum.IPredUGE:
+		return us >= 0
+	case enum.IPredUGT:
+		return us > 0
+	case enum.IPredULE:
+		return us <= 0
+	default:
+		// enum.IPredULT
+		return us < 0
+	}
+}
+
+// unsigned returns x wrapped to an unsigned integer of bit size n.
+func unsigned(x *big.Int, n int64) *big.Int {
+	mod := new(big.Int).Lsh(big.NewInt(1), uint(n))
+	return new(big.Int).Mod(x, mod)
+}
+
+// signed returns x wrapped to a signed integer of bit size n.
+func signed(x *big.Int, n int64) *big.Int {
+	z := unsigned(x, n)
+	if z.Bit(int(n-1)) == 1 {
+		z.Sub(z, new(big.Int).Lsh(big.NewInt(1), uint(n)))
+	}
+	return z
+}
+
+// hasFuncAttr reports whether the given function attributes contain attr.
+func hasFuncAttr(attrs []ir.FuncAttribute, attr enum.FuncAttr) bool {
+	for _, a := range attrs {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIndirectBr reports whether the given function contains an indirectbr
+// terminator.
+func hasIndirectBr(f *ir.Function) bool {
+	for _, block := range f.Blocks {
+		if _, ok := block.Term.(*ir.TermIndirectBr); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// calls reports whether the given function contains a direct call to callee.
+func calls(f, callee *ir.Function) bool {
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if call, ok := inst.(*ir.InstCall); ok && call.Callee == callee {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+func TestAnalyzeInline(t *testing.T) {
+	m := &ir.Module{}
+	decl := m.NewFunc("decl", types.I32, ir.NewParam(types.I32, "x"))
+	// g has a cheap path if mode is 0, and an expensive path otherwise.
+	mode := ir.NewParam(types.I32, "mode")
+	x := ir.NewParam(types.I32, "x")
+	g := m.NewFunc("g", types.I32, mode, x)
+	entry := g.NewBlock("entry")
+	fast := g.NewBlock("fast")
+	slow := g.NewBlock("slow")
+	isFast := entry.NewICmp(enum.IPredEQ, mode, ir.NewInt(types.I32, 0))
+	entry.NewCondBr(isFast, fast, slow)
+	fast.NewRet(x)
+	var v value.Value = slow.NewSDiv(x, mode)
+	for i := 0; i < 10; i++ {
+		v = slow.NewMul(v, x)
+	}
+	slow.NewRet(slow.NewCall(decl, v))
+	// rec is recursive.
+	n := ir.NewParam(types.I32, "n")
+	rec := m.NewFunc("rec", types.I32, n)
+	rec.NewBlock("entry").NewRet(rec.Blocks[0].NewCall(rec, n))
+	f := m.NewFunc("f", types.I32, ir.NewParam(types.I32, "y"))
+	y := f.Params[0]
+	fentry := f.NewBlock("entry")
+	constCall := fentry.NewCall(g, ir.NewInt(types.I32, 0), y)
+	varCall := fentry.NewCall(g, y, y)
+	declCall := fentry.NewCall(decl, y)
+	recCall := fentry.NewCall(rec, y)
+	noinlineCall := fentry.NewCall(g, ir.NewInt(types.I32, 0), y)
+	noinlineCall.FuncAttrs = append(noinlineCall.FuncAttrs, enum.FuncAttrNoInline)
+	fentry.NewRet(constCall)
+
+	// The constant mode folds the comparison and branch, so only the fast path
+	// is counted.
+	c := AnalyzeInline(f, constCall, nil)
+	if !c.ShouldInline() || c.Never || c.Always {
+		t.Errorf("expected call with constant mode to be inlined; got cost %d, threshold %d, reason %q", c.Cost, c.Threshold, c.Reason)
+	}
+	vc := AnalyzeInline(f, varCall, nil)
+	if vc.ShouldInline() {
+		t.Errorf("expected call with variable mode not to be inlined; got cost %d, threshold %d", vc.Cost, vc.Threshold)
+	}
+	if c.Cost >= vc.Cost {
+		t.Errorf("expected cost of call with constant mode (%d) to be less than with variable mode (%d)", c.Cost, vc.Cost)
+	}
+	golden := []struct {
+		call   *ir.InstCall
+		reason string
+	}{
+		// i=0
+		{call: declCall, reason: "callee is a declaration"},
+		// i=1
+		{call: recCall, reason: "recursive call"},
+		// i=2
+		{call: noinlineCall, reason: "noinline"},
+	}
+	for i, gold := range golden {
+		c := AnalyzeInline(f, gold.call, nil)
+		if !c.Never || c.ShouldInline() {
+			t.Errorf("i=%d: expected call never to be inlined", i)
+		}
+		if c.Reason != gold.reason {
+			t.Errorf("i=%d: reason mismatch; expected %q, got %q", i, gold.reason, c.Reason)
+		}
+	}
+	// alwaysinline overrides the threshold.
+	g.FuncAttrs = append(g.FuncAttrs, enum.FuncAttrAlwaysInline)
+	if c := AnalyzeInline(f, varCall, nil); !c.Always || !c.ShouldInline() {
+		t.Errorf("expected call of alwaysinline function to be inlined")
+	}
+	// Size cap.
+	g.FuncAttrs = nil
+	params := *DefaultInlineParams
+	params.MaxSize = 5
+	if c := AnalyzeInline(f, constCall, &params); !c.Never || c.Reason != "callee too large" {
+		t.Errorf("expected callee exceeding size cap never to be inlined; got reason %q", c.Reason)
+	}
+}
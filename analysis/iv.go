@@ -0,0 +1,427 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/value"
+)
+
+// --- [ Induction variables ] -------------------------------------------------
+
+// InductionVar is an affine induction variable of a loop; i.e. a value which
+// is incremented by a constant step each iteration of the loop.
+//
+// The value of a basic induction variable at iteration k (starting at 0) is
+// Start + k*Step. The value of a derived induction variable is
+// Scale*b + Offset, where b is the value of its basic induction variable.
+type InductionVar struct {
+	// Value of the induction variable; a phi instruction in the loop header for
+	// basic induction variables, or an instruction of the loop for derived
+	// induction variables.
+	Value value.Value
+	// Loop of the induction variable.
+	Loop *Loop
+	// Initial value of basic induction variables, incoming from outside the
+	// loop; nil for derived induction variables.
+	Start value.Value
+	// Constant step per iteration.
+	Step int64
+
+	// Basic induction variable of derived induction variables; nil for basic
+	// induction variables.
+	Basic *InductionVar
+	// Scale and offset of derived induction variables, relative to their basic
+	// induction variable.
+	Scale, Offset int64
+}
+
+// InductionVars returns the basic and derived induction variables of the
+// given loop. Derived induction variables are affine functions of a basic
+// induction variable (e.g. 4*i + 8), computed by add, sub, mul and shl
+// instructions with constant operands, or by sext and zext instructions.
+func InductionVars(info *LoopInfo, loop *Loop) []*InductionVar {
+	var ivs []*InductionVar
+	ivOf := make(map[value.Value]*InductionVar)
+	for _, inst := range loop.Header.Insts {
+		phi, ok := inst.(*ir.InstPhi)
+		if !ok {
+			continue
+		}
+		if iv, ok := BasicInductionVar(info, phi); ok && iv.Loop == loop {
+			ivs = append(ivs, iv)
+			ivOf[phi] = iv
+		}
+	}
+	// Derive induction variables until a fixed point is reached, as operands
+	// may be defined in later basic blocks.
+	for changed := true; changed; {
+		changed = false
+		for _, block := range loop.Blocks {
+			for _, inst := range block.Insts {
+				v, ok := inst.(value.Value)
+				if !ok || ivOf[v] != nil {
+					continue
+				}
+				if iv, ok := derivedIV(ivOf, v); ok {
+					ivs = append(ivs, iv)
+					ivOf[v] = iv
+					changed = true
+				}
+			}
+		}
+	}
+	return ivs
+}
+
+// BasicInductionVar returns the basic induction variable of the given phi
+// instruction; a phi instruction in a loop header which is initialized from
+// outside the loop and updated by a constant step within the loop (e.g.
+// i = phi [0, %entry], [%i.next, %latch], with %i.next = add i, 1). The boolean
+// return value indicates success.
+func BasicInductionVar(info *LoopInfo, phi *ir.InstPhi) (*InductionVar, bool) {
+	var loop *Loop
+	for block, l := range info.loopOf {
+		if l.Header != block {
+			continue
+		}
+		for _, inst := range block.Insts {
+			if inst == phi {
+				loop = l
+			}
+		}
+	}
+	if loop == nil {
+		return nil, false
+	}
+	iv := &InductionVar{Value: phi, Loop: loop, Scale: 1}
+	stepSet := false
+	for _, inc := range phi.Incs {
+		if !loop.Contains(inc.Pred) {
+			if iv.Start != nil && iv.Start != inc.X {
+				return nil, false
+			}
+			iv.Start = inc.X
+			continue
+		}
+		step, ok := stepOf(phi, inc.X)
+		if !ok || (stepSet && step != iv.Step) {
+			return nil, false
+		}
+		iv.Step = step
+		stepSet = true
+	}
+	if iv.Start == nil || !stepSet {
+		return nil, false
+	}
+	return iv, true
+}
+
+// ~~~ [ Trip count ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// TripCount is the number of iterations of a loop; i.e. the number of times
+// the loop latch branches back to the header, plus one if the loop exits from
+// the latch.
+type TripCount struct {
+	// Induction variable controlling the loop exit condition.
+	IV *InductionVar
+	// Loop-invariant limit of the exit condition.
+	Limit value.Value
+	// Predicate of the exit condition, normalized so that the loop continues
+	// while `IV Pred Limit` holds.
+	Pred enum.IPred
+	// Constant trip count; only valid if IsConst is set.
+	Const int64
+	// IsConst specifies whether the trip count is a compile-time constant.
+	IsConst bool
+
+	// Symbolic trip count expression.
+	expr string
+}
+
+// String returns the string representation of the trip count; e.g. "42" or
+// "(%n - (0) + 0) / 1".
+func (tc *TripCount) String() string {
+	if tc.IsConst {
+		return fmt.Sprint(tc.Const)
+	}
+	return tc.expr
+}
+
+// LoopTripCount returns the trip count of the given loop. Trip counts are
+// computed for loops with a single exit, from the header or the latch, which
+// is controlled by an integer comparison of an induction variable against a
+// loop-invariant limit. The boolean return value indicates success.
+func LoopTripCount(info *LoopInfo, loop *Loop) (*TripCount, bool) {
+	if len(loop.Latches) != 1 {
+		return nil, false
+	}
+	latch := loop.Latches[0]
+	// Locate the single exiting basic block.
+	var exiting *ir.BasicBlock
+	for _, block := range loop.Blocks {
+		for _, succ := range AllEdges.Succs(block) {
+			if loop.Contains(succ) {
+				continue
+			}
+			if exiting != nil && exiting != block {
+				return nil, false
+			}
+			exiting = block
+		}
+	}
+	if exiting != loop.Header && exiting != latch {
+		return nil, false
+	}
+	term, ok := exiting.Term.(*ir.TermCondBr)
+	if !ok {
+		return nil, false
+	}
+	cmp, ok := term.Cond.(*ir.InstICmp)
+	if !ok {
+		return nil, false
+	}
+	// Normalize the condition to `x pred y`, where the loop continues while the
+	// condition holds.
+	pred, x, y := cmp.Pred, cmp.X, cmp.Y
+	if !loop.Contains(term.TargetTrue) {
+		pred = invertPred(pred)
+	}
+	ivs := make(map[value.Value]*InductionVar)
+	for _, iv := range InductionVars(info, loop) {
+		ivs[iv.Value] = iv
+	}
+	iv, limit := ivs[x], y
+	if iv == nil {
+		iv, limit = ivs[y], x
+		pred = swapPred(pred)
+	}
+	if iv == nil || !isLoopInvariant(info, limit) {
+		return nil, false
+	}
+	basic := iv
+	if iv.Basic != nil {
+		basic = iv.Basic
+	}
+	tc := &TripCount{IV: iv, Limit: limit, Pred: pred}
+	// The compared value at the first evaluation of the exit condition is
+	// start, and is incremented by step each iteration.
+	step := iv.Step
+	if step == 0 {
+		return nil, false
+	}
+	var adjust int64 // added to (limit - start) before division by step
+	switch pred {
+	case enum.IPredSLT, enum.IPredULT:
+		if step < 0 {
+			return nil, false
+		}
+		adjust = step - 1
+	case enum.IPredSLE, enum.IPredULE:
+		if step < 0 {
+			return nil, false
+		}
+		adjust = step
+	case enum.IPredSGT, enum.IPredUGT:
+		if step > 0 {
+			return nil, false
+		}
+		adjust = step + 1
+	case enum.IPredSGE, enum.IPredUGE:
+		if step > 0 {
+			return nil, false
+		}
+		adjust = step
+	case enum.IPredNE:
+		adjust = 0
+	default:
+		return nil, false
+	}
+	// Latch exits evaluate the condition at the end of each iteration, so the
+	// first iteration executes unconditionally.
+	extra := int64(0)
+	if exiting == latch {
+		extra = 1
+	}
+	startConst, startOK := constInt(basic.Start)
+	limitConst, limitOK := constInt(limit)
+	if startOK && limitOK {
+		start := iv.Scale*startConst + iv.Offset
+		n := (limitConst - start + adjust) / step
+		if pred == enum.IPredNE && (limitConst-start)%step != 0 {
+			// Never reaches the limit exactly.
+			return nil, false
+		}
+		if n < 0 {
+			n = 0
+		}
+		tc.Const = n + extra
+		tc.IsConst = true
+		return tc, true
+	}
+	start := basic.Start.Ident()
+	if iv.Basic != nil {
+		start = fmt.Sprintf("%d*%s + %d", iv.Scale, start, iv.Offset)
+	}
+	tc.expr = fmt.Sprintf("(%s - (%s) + %d) / %d", limit.Ident(), start, adjust, step)
+	if extra == 1 {
+		tc.expr = fmt.Sprintf("1 + %s", tc.expr)
+	}
+	return tc, true
+}
+
+// ### [ Helper functions ] ####################################################
+
+// derivedIV returns the derived induction variable of the given value, based
+// on the known induction variables of ivOf. The boolean return value indicates
+// success.
+func derivedIV(ivOf map[value.Value]*InductionVar, v value.Value) (*InductionVar, bool) {
+	var (
+		x, y       value.Value
+		scale, off int64
+	)
+	switch v := v.(type) {
+	case *ir.InstAdd:
+		x, y = v.X, v.Y
+		if ivOf[x] == nil {
+			x, y = y, x
+		}
+		c, ok := constInt(y)
+		if !ok {
+			return nil, false
+		}
+		scale, off = 1, c
+	case *ir.InstSub:
+		x, y = v.X, v.Y
+		c, ok := constInt(y)
+		if !ok {
+			return nil, false
+		}
+		scale, off = 1, -c
+	case *ir.InstMul:
+		x, y = v.X, v.Y
+		if ivOf[x] == nil {
+			x, y = y, x
+		}
+		c, ok := constInt(y)
+		if !ok {
+			return nil, false
+		}
+		scale, off = c, 0
+	case *ir.InstShl:
+		x, y = v.X, v.Y
+		c, ok := constInt(y)
+		if !ok || c < 0 || c > 62 {
+			return nil, false
+		}
+		scale, off = 1<<uint(c), 0
+	case *ir.InstSExt:
+		x, scale = v.From, 1
+	case *ir.InstZExt:
+		x, scale = v.From, 1
+	default:
+		return nil, false
+	}
+	src := ivOf[x]
+	if src == nil {
+		return nil, false
+	}
+	basic, srcScale, srcOff := src, int64(1), int64(0)
+	if src.Basic != nil {
+		basic, srcScale, srcOff = src.Basic, src.Scale, src.Offset
+	}
+	iv := &InductionVar{
+		Value:  v,
+		Loop:   src.Loop,
+		Basic:  basic,
+		Scale:  scale * srcScale,
+		Offset: scale*srcOff + off,
+	}
+	iv.Step = iv.Scale * basic.Step
+	return iv, true
+}
+
+// stepOf returns the constant step of the given update of a phi instruction
+// (e.g. phi + 1 or phi - 2). The boolean return value indicates success.
+func stepOf(phi *ir.InstPhi, update value.Value) (int64, bool) {
+	switch update := update.(type) {
+	case *ir.InstAdd:
+		if update.X == phi {
+			return constInt(update.Y)
+		}
+		if update.Y == phi {
+			return constInt(update.X)
+		}
+	case *ir.InstSub:
+		if update.X == phi {
+			step, ok := constInt(update.Y)
+			return -step, ok
+		}
+	}
+	return 0, false
+}
+
+// constInt returns the value of the given integer constant. The boolean return
+// value indicates success.
+func constInt(v value.Value) (int64, bool) {
+	c, ok := v.(*ir.ConstInt)
+	if !ok || !c.X.IsInt64() {
+		return 0, false
+	}
+	return c.X.Int64(), true
+}
+
+// invertPred returns the inverse of the given integer predicate; i.e. the
+// predicate which holds exactly when pred does not.
+func invertPred(pred enum.IPred) enum.IPred {
+	switch pred {
+	case enum.IPredEQ:
+		return enum.IPredNE
+	case enum.IPredNE:
+		return enum.IPredEQ
+	case enum.IPredSGE:
+		return enum.IPredSLT
+	case enum.IPredSGT:
+		return enum.IPredSLE
+	case enum.IPredSLE:
+		return enum.IPredSGT
+	case enum.IPredSLT:
+		return enum.IPredSGE
+	case enum.IPredUGE:
+		return enum.IPredULT
+	case enum.IPredUGT:
+		return enum.IPredULE
+	case enum.IPredULE:
+		return enum.IPredUGT
+	case enum.IPredULT:
+		return enum.IPredUGE
+	}
+	panic(fmt.Errorf("support for integer predicate %v not yet implemented", pred))
+}
+
+// swapPred returns the predicate of the given integer predicate with swapped
+// operands; i.e. x pred y holds exactly when y swapPred(pred) x holds.
+func swapPred(pred enum.IPred) enum.IPred {
+	switch pred {
+	case enum.IPredEQ, enum.IPredNE:
+		return pred
+	case enum.IPredSGE:
+		return enum.IPredSLE
+	case enum.IPredSGT:
+		return enum.IPredSLT
+	case enum.IPredSLE:
+		return enum.IPredSGE
+	case enum.IPredSLT:
+		return enum.IPredSGT
+	case enum.IPredUGE:
+		return enum.IPredULE
+	case enum.IPredUGT:
+		return enum.IPredULT
+	case enum.IPredULE:
+		return enum.IPredUGE
+	case enum.IPredULT:
+		return enum.IPredUGT
+	}
+	panic(fmt.Errorf("support for integer predicate %v not yet implemented", pred))
+}
@@ -0,0 +1,111 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+func TestLoopTripCount(t *testing.T) {
+	golden := []struct {
+		start, limit value.Value
+		step         int64
+		pred         enum.IPred
+		latch        bool
+		want         string
+	}{
+		// i=0: for (i = 0; i < 10; i++)
+		{start: constI32(0), limit: constI32(10), step: 1, pred: enum.IPredSLT, want: "10"},
+		// i=1: for (i = 0; i <= 10; i += 3)
+		{start: constI32(0), limit: constI32(10), step: 3, pred: enum.IPredSLE, want: "4"},
+		// i=2: for (i = 10; i > 0; i--)
+		{start: constI32(10), limit: constI32(0), step: -1, pred: enum.IPredSGT, want: "10"},
+		// i=3: for (i = 5; i < 3; i++)
+		{start: constI32(5), limit: constI32(3), step: 1, pred: enum.IPredSLT, want: "0"},
+		// i=4: for (i = 0; i < n; i++)
+		{start: constI32(0), limit: ir.NewParam(types.I32, "n"), step: 1, pred: enum.IPredSLT, want: "(%n - (0) + 0) / 1"},
+		// i=5: i = 0; do { i++ } while (i < 10)
+		{start: constI32(0), limit: constI32(10), step: 1, pred: enum.IPredSLT, latch: true, want: "10"},
+		// i=6: i = 0; do { i++ } while (i < 0)
+		{start: constI32(0), limit: constI32(0), step: 1, pred: enum.IPredSLT, latch: true, want: "1"},
+	}
+	for i, g := range golden {
+		f, loop := newCountedLoop(g.start, g.limit, g.step, g.pred, g.latch)
+		info := FindLoops(f)
+		l := info.LoopFor(loop)
+		if l == nil {
+			t.Errorf("i=%d: unable to locate loop", i)
+			continue
+		}
+		tc, ok := LoopTripCount(info, l)
+		if !ok {
+			t.Errorf("i=%d: unable to compute trip count", i)
+			continue
+		}
+		if got := tc.String(); g.want != got {
+			t.Errorf("i=%d: trip count mismatch; expected %v, got %v", i, g.want, got)
+		}
+	}
+}
+
+func TestInductionVars(t *testing.T) {
+	f, loop := newCountedLoop(constI32(0), constI32(10), 2, enum.IPredSLT, false)
+	// Add derived induction variable 4*i + 8.
+	i := loop.Insts[0].(*ir.InstPhi)
+	j := ir.NewAdd(ir.NewMul(i, constI32(4)), constI32(8))
+	loop.Insts = append(loop.Insts[:1], append([]ir.Instruction{j.X.(*ir.InstMul), j}, loop.Insts[1:]...)...)
+	info := FindLoops(f)
+	ivs := InductionVars(info, info.LoopFor(loop))
+	var got *InductionVar
+	for _, iv := range ivs {
+		if iv.Value == j {
+			got = iv
+		}
+	}
+	if got == nil {
+		t.Fatalf("unable to locate derived induction variable")
+	}
+	if got.Basic == nil || got.Basic.Value != i || got.Scale != 4 || got.Offset != 8 || got.Step != 8 {
+		t.Errorf("derived induction variable mismatch; expected 4*i + 8 with step 8, got %d*i + %d with step %d", got.Scale, got.Offset, got.Step)
+	}
+}
+
+// newCountedLoop returns a function with a single loop, controlled by an
+// induction variable starting at start and incremented by step while
+// `i pred limit` holds. If latch is set, the exit condition is evaluated on the
+// incremented value at the end of the loop. The loop header is returned.
+func newCountedLoop(start, limit value.Value, step int64, pred enum.IPred, latch bool) (*ir.Function, *ir.BasicBlock) {
+	f := ir.NewFunc("f", types.Void)
+	entry := ir.NewBlock("entry")
+	header := ir.NewBlock("header")
+	exit := ir.NewBlock("exit")
+	entry.NewBr(header)
+	i := header.NewPhi()
+	i.Typ = types.I32
+	next := ir.NewAdd(i, constI32(step))
+	if latch {
+		header.Insts = append(header.Insts, next)
+		cond := header.NewICmp(pred, next, limit)
+		header.NewCondBr(cond, header, exit)
+		i.Incs = append(i.Incs, ir.NewIncoming(start, entry), ir.NewIncoming(next, header))
+		f.Blocks = append(f.Blocks, entry, header, exit)
+	} else {
+		body := ir.NewBlock("body")
+		cond := header.NewICmp(pred, i, limit)
+		header.NewCondBr(cond, body, exit)
+		body.Insts = append(body.Insts, next)
+		body.NewBr(header)
+		i.Incs = append(i.Incs, ir.NewIncoming(start, entry), ir.NewIncoming(next, body))
+		f.Blocks = append(f.Blocks, entry, header, body, exit)
+	}
+	exit.NewRet(nil)
+	return f, header
+}
+
+// constI32 returns a new i32 integer constant.
+func constI32(x int64) *ir.ConstInt {
+	return ir.NewInt(types.I32, x)
+}
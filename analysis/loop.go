@@ -0,0 +1,142 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/llir/l/ir"
+)
+
+// --- [ Natural loops ] -------------------------------------------------------
+
+// Loop is a natural loop.
+type Loop struct {
+	// Loop header; the single entry of the loop, which dominates every basic
+	// block of the loop.
+	Header *ir.BasicBlock
+	// Basic blocks of the loop, including the header and the basic blocks of
+	// nested loops, in the order of the basic blocks of the function.
+	Blocks []*ir.BasicBlock
+	// Latches of the loop; the basic blocks with back edges to the header.
+	Latches []*ir.BasicBlock
+	// Parent loop; or nil if outermost loop.
+	Parent *Loop
+	// Nested loops.
+	Children []*Loop
+	// Preheader of the loop; the single predecessor of the header outside the
+	// loop, or nil if not present.
+	Preheader *ir.BasicBlock
+
+	// Set of basic blocks of the loop.
+	blocks map[*ir.BasicBlock]bool
+}
+
+// Contains reports whether the given basic block is part of the loop.
+func (l *Loop) Contains(block *ir.BasicBlock) bool {
+	return l.blocks[block]
+}
+
+// Depth returns the nesting depth of the loop, where outermost loops have a
+// depth of 1.
+func (l *Loop) Depth() int {
+	depth := 1
+	for p := l.Parent; p != nil; p = p.Parent {
+		depth++
+	}
+	return depth
+}
+
+// LoopInfo is the loop nesting forest of a function.
+type LoopInfo struct {
+	// Outermost loops, in the order of their headers.
+	Loops []*Loop
+	// Innermost loop of each basic block.
+	loopOf map[*ir.BasicBlock]*Loop
+}
+
+// FindLoops returns the natural loops of the given function, identified by the
+// back edges of the control flow graph (edges to a dominating basic block).
+// Natural loops sharing a header are merged.
+func FindLoops(f *ir.Function) *LoopInfo {
+	info := &LoopInfo{loopOf: make(map[*ir.BasicBlock]*Loop)}
+	dom := Dominators(f)
+	preds := Preds(f)
+	index := make(map[*ir.BasicBlock]int)
+	for i, block := range f.Blocks {
+		index[block] = i
+	}
+	// Identify loops by their headers.
+	var loops []*Loop
+	for _, header := range ReversePostorder(f) {
+		var l *Loop
+		for _, pred := range preds[header] {
+			if !dom.Dominates(header, pred) {
+				continue
+			}
+			if l == nil {
+				l = &Loop{Header: header, blocks: map[*ir.BasicBlock]bool{header: true}}
+				loops = append(loops, l)
+			}
+			l.Latches = append(l.Latches, pred)
+			// Collect the basic blocks reaching the latch without passing
+			// through the header.
+			worklist := []*ir.BasicBlock{pred}
+			for len(worklist) > 0 {
+				block := worklist[len(worklist)-1]
+				worklist = worklist[:len(worklist)-1]
+				if l.blocks[block] {
+					continue
+				}
+				l.blocks[block] = true
+				worklist = append(worklist, preds[block]...)
+			}
+		}
+		if l == nil {
+			continue
+		}
+		for block := range l.blocks {
+			l.Blocks = append(l.Blocks, block)
+		}
+		sort.Slice(l.Blocks, func(i, j int) bool {
+			return index[l.Blocks[i]] < index[l.Blocks[j]]
+		})
+		for _, pred := range preds[header] {
+			if l.blocks[pred] {
+				continue
+			}
+			if l.Preheader != nil {
+				// Multiple predecessors outside the loop.
+				l.Preheader = nil
+				break
+			}
+			l.Preheader = pred
+		}
+	}
+	// Determine loop nesting. Loops are in reverse postorder of their headers,
+	// so outer loops precede the loops nested within.
+	for i, l := range loops {
+		for j := i - 1; j >= 0; j-- {
+			if loops[j].Contains(l.Header) && loops[j] != l {
+				if l.Parent == nil || len(loops[j].Blocks) < len(l.Parent.Blocks) {
+					l.Parent = loops[j]
+				}
+			}
+		}
+		if l.Parent == nil {
+			info.Loops = append(info.Loops, l)
+		} else {
+			l.Parent.Children = append(l.Parent.Children, l)
+		}
+		for _, block := range l.Blocks {
+			if prev, ok := info.loopOf[block]; !ok || len(l.Blocks) < len(prev.Blocks) {
+				info.loopOf[block] = l
+			}
+		}
+	}
+	return info
+}
+
+// LoopFor returns the innermost loop containing the given basic block, or nil
+// if the basic block is not part of a loop.
+func (info *LoopInfo) LoopFor(block *ir.BasicBlock) *Loop {
+	return info.loopOf[block]
+}
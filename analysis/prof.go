@@ -0,0 +1,367 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+
+	"github.com/llir/l/ir"
+)
+
+// --- [ Profile summary ] -----------------------------------------------------
+
+// Hot and cold count cutoffs of the profile summary, as fractions of the total
+// count of the module. A count is hot if the counts greater than or equal to it
+// account for hotCutoff of the total count, and cold if the counts greater than
+// it account for coldCutoff of the total count.
+const (
+	hotCutoff  = 0.99
+	coldCutoff = 0.999999
+)
+
+// ProfileSummary is a summary of the profile metadata of a module; i.e. the
+// function entry counts recorded by `!prof !{!"function_entry_count", i64 N}`
+// attachments of functions, and the block frequencies derived from
+// `!prof !{!"branch_weights", i32 W1, ...}` attachments of terminators.
+type ProfileSummary struct {
+	// Entry count of each function with profile metadata.
+	EntryCounts map[*ir.Function]uint64
+	// Estimated execution frequency of each basic block, relative to the entry
+	// basic block of its function (which has frequency 1).
+	BlockFreqs map[*ir.BasicBlock]float64
+	// Minimum count of hot functions and basic blocks; or 0 if the module has
+	// no profile metadata.
+	HotThreshold uint64
+	// Maximum count of cold functions and basic blocks; or 0 if the module has
+	// no profile metadata.
+	ColdThreshold uint64
+
+	// Function of each basic block.
+	parent map[*ir.BasicBlock]*ir.Function
+	// Maximum count of each function with profile metadata; i.e. the maximum
+	// of its entry count and the counts of its basic blocks.
+	maxCounts map[*ir.Function]uint64
+}
+
+// SummarizeProfile returns the profile summary of the given module. Block
+// frequencies are estimated for every function definition, and default to an
+// even split between successors for terminators without branch weights.
+func SummarizeProfile(m *ir.Module) *ProfileSummary {
+	s := &ProfileSummary{
+		EntryCounts: make(map[*ir.Function]uint64),
+		BlockFreqs:  make(map[*ir.BasicBlock]float64),
+		parent:      make(map[*ir.BasicBlock]*ir.Function),
+		maxCounts:   make(map[*ir.Function]uint64),
+	}
+	var counts []uint64
+	for _, f := range m.Funcs {
+		for block, freq := range blockFreqs(f, edgeProbs) {
+			s.BlockFreqs[block] = freq
+			s.parent[block] = f
+		}
+		entryCount, ok := FuncEntryCount(f)
+		if !ok {
+			continue
+		}
+		s.EntryCounts[f] = entryCount
+		max := entryCount
+		counts = append(counts, entryCount)
+		for _, block := range f.Blocks {
+			count, _ := s.BlockCount(block)
+			counts = append(counts, count)
+			if count > max {
+				max = count
+			}
+		}
+		s.maxCounts[f] = max
+	}
+	s.HotThreshold = countThreshold(counts, hotCutoff)
+	s.ColdThreshold = countThreshold(counts, coldCutoff)
+	return s
+}
+
+// HasProfile reports whether the module of the profile summary has profile
+// metadata.
+func (s *ProfileSummary) HasProfile() bool {
+	return len(s.EntryCounts) > 0
+}
+
+// BlockFreq returns the estimated execution frequency of the given basic
+// block, relative to the entry basic block of its function. Unreachable basic
+// blocks have frequency 0.
+func (s *ProfileSummary) BlockFreq(block *ir.BasicBlock) float64 {
+	return s.BlockFreqs[block]
+}
+
+// BlockCount returns the estimated execution count of the given basic block,
+// derived from the entry count of its function. The boolean return value
+// indicates whether the function of the basic block has profile metadata.
+func (s *ProfileSummary) BlockCount(block *ir.BasicBlock) (uint64, bool) {
+	entryCount, ok := s.EntryCounts[s.parent[block]]
+	if !ok {
+		return 0, false
+	}
+	return uint64(math.Round(s.BlockFreqs[block] * float64(entryCount))), true
+}
+
+// IsHotCount reports whether the given count is hot.
+func (s *ProfileSummary) IsHotCount(count uint64) bool {
+	return s.HasProfile() && count >= s.HotThreshold
+}
+
+// IsColdCount reports whether the given count is cold. Hot counts are never
+// cold.
+func (s *ProfileSummary) IsColdCount(count uint64) bool {
+	return s.HasProfile() && count <= s.ColdThreshold && count < s.HotThreshold
+}
+
+// IsHotFunc reports whether the given function is hot; i.e. whether its entry
+// count or the count of any of its basic blocks is hot.
+func (s *ProfileSummary) IsHotFunc(f *ir.Function) bool {
+	max, ok := s.maxCounts[f]
+	return ok && s.IsHotCount(max)
+}
+
+// IsColdFunc reports whether the given function is cold; i.e. whether its
+// entry count and the counts of all of its basic blocks are cold.
+func (s *ProfileSummary) IsColdFunc(f *ir.Function) bool {
+	max, ok := s.maxCounts[f]
+	return ok && s.IsColdCount(max)
+}
+
+// IsHotBlock reports whether the given basic block is hot.
+func (s *ProfileSummary) IsHotBlock(block *ir.BasicBlock) bool {
+	count, ok := s.BlockCount(block)
+	return ok && s.IsHotCount(count)
+}
+
+// IsColdBlock reports whether the given basic block is cold.
+func (s *ProfileSummary) IsColdBlock(block *ir.BasicBlock) bool {
+	count, ok := s.BlockCount(block)
+	return ok && s.IsColdCount(count)
+}
+
+// --- [ Profile metadata ] ----------------------------------------------------
+
+// FuncEntryCount returns the entry count of the given function, as recorded
+// by its `!prof !{!"function_entry_count", i64 N}` attachment. The boolean
+// return value indicates success.
+func FuncEntryCount(f *ir.Function) (uint64, bool) {
+	vals, ok := profValues(f.Metadata, "function_entry_count")
+	if !ok || len(vals) != 1 {
+		return 0, false
+	}
+	return vals[0], true
+}
+
+// BranchWeights returns the branch weights of the given terminator, as
+// recorded by its `!prof !{!"branch_weights", i32 W1, ...}` attachment. The
+// branch weights are listed in the order of the successors of the terminator.
+// The boolean return value indicates success.
+func BranchWeights(term ir.Terminator) ([]uint64, bool) {
+	var mds ir.MDAttachments
+	switch term := term.(type) {
+	case *ir.TermCondBr:
+		mds = term.Metadata
+	case *ir.TermSwitch:
+		mds = term.Metadata
+	case *ir.TermIndirectBr:
+		mds = term.Metadata
+	case *ir.TermInvoke:
+		mds = term.Metadata
+	default:
+		return nil, false
+	}
+	weights, ok := profValues(mds, "branch_weights")
+	if !ok || len(weights) != len(term.Succs()) {
+		return nil, false
+	}
+	return weights, true
+}
+
+// ### [ Helper functions ] ####################################################
+
+// profValues returns the integer values of the `!prof` attachment of the given
+// kind (e.g. "branch_weights") among the given metadata attachments. The
+// boolean return value indicates success.
+func profValues(mds ir.MDAttachments, kind string) ([]uint64, bool) {
+	tuple := mds.Prof()
+	if tuple == nil || len(tuple.Fields) < 1 {
+		return nil, false
+	}
+	if name, ok := tuple.Fields[0].(*ir.MDString); !ok || name.Value != kind {
+		return nil, false
+	}
+	var vals []uint64
+	for _, field := range tuple.Fields[1:] {
+		v, ok := field.(*ir.MDValue)
+		if !ok {
+			return nil, false
+		}
+		c, ok := v.Value.(*ir.ConstInt)
+		if !ok || !c.X.IsUint64() {
+			return nil, false
+		}
+		vals = append(vals, c.X.Uint64())
+	}
+	return vals, true
+}
+
+// edge is a weighted control flow edge into a basic block.
+type edge struct {
+	// Source basic block.
+	pred *ir.BasicBlock
+	// Probability of the edge, given that the source basic block is executed.
+	prob float64
+}
+
+// blockFreqs returns the estimated execution frequency of each basic block of
+// the given function, relative to its entry basic block, based on the given
+// probabilities of the successor edges of terminators.
+//
+// The frequencies are computed by propagating edge probabilities in reverse
+// postorder, ignoring back edges. The frequency of each loop header is scaled
+// by the expected number of iterations of its loop, 1/(1-p), where p is the
+// probability of taking a back edge to the loop header given that the loop
+// header is executed. Loop scales are computed for the innermost loops first.
+// Retreating edges of irreducible control flow are ignored.
+func blockFreqs(f *ir.Function, succProbs func(term ir.Terminator) []float64) map[*ir.BasicBlock]float64 {
+	freqs := make(map[*ir.BasicBlock]float64)
+	if len(f.Blocks) == 0 {
+		return freqs
+	}
+	for _, block := range f.Blocks {
+		freqs[block] = 0
+	}
+	order := ReversePostorder(f)
+	index := make(map[*ir.BasicBlock]int)
+	for i, block := range order {
+		index[block] = i
+	}
+	// Incoming forward edges and back edges of each basic block.
+	in := make(map[*ir.BasicBlock][]edge)
+	back := make(map[*ir.BasicBlock][]edge)
+	for _, block := range order {
+		if block.Term == nil {
+			continue
+		}
+		succs := block.Term.Succs()
+		probs := succProbs(block.Term)
+		for i, succ := range succs {
+			if succ == nil {
+				continue
+			}
+			e := edge{pred: block, prob: probs[i]}
+			if index[block] < index[succ] {
+				in[succ] = append(in[succ], e)
+			} else {
+				back[succ] = append(back[succ], e)
+			}
+		}
+	}
+	// Compute loop scales, innermost loops first.
+	scales := make(map[*ir.BasicBlock]float64)
+	var loops []*Loop
+	var addLoops func(ls []*Loop)
+	addLoops = func(ls []*Loop) {
+		for _, l := range ls {
+			addLoops(l.Children)
+			loops = append(loops, l)
+		}
+	}
+	addLoops(FindLoops(f).Loops)
+	for _, l := range loops {
+		var body []*ir.BasicBlock
+		for _, block := range order {
+			if l.Contains(block) {
+				body = append(body, block)
+			}
+		}
+		rel := propagateFreqs(body, in, scales)
+		p := 0.0
+		for _, e := range back[l.Header] {
+			p += rel[e.pred] * e.prob
+		}
+		scale := float64(maxLoopScale)
+		if p < 1-1/maxLoopScale {
+			scale = 1 / (1 - p)
+		}
+		scales[l.Header] = scale
+	}
+	for block, freq := range propagateFreqs(order, in, scales) {
+		freqs[block] = freq
+	}
+	return freqs
+}
+
+// maxLoopScale is the maximum expected number of iterations of a loop, as
+// used for loops which are never (or almost never) exited.
+const maxLoopScale = 4096
+
+// propagateFreqs returns the frequency of each of the given basic blocks,
+// listed in reverse postorder, relative to the first. Only forward edges
+// between the given basic blocks are considered. The frequency of each loop
+// header except the first is scaled by the given loop scale.
+func propagateFreqs(blocks []*ir.BasicBlock, in map[*ir.BasicBlock][]edge, scales map[*ir.BasicBlock]float64) map[*ir.BasicBlock]float64 {
+	freqs := make(map[*ir.BasicBlock]float64)
+	for i, block := range blocks {
+		freq := 0.0
+		if i == 0 {
+			freq = 1
+		}
+		for _, e := range in[block] {
+			// Predecessors outside of blocks have no frequency.
+			freq += freqs[e.pred] * e.prob
+		}
+		if scale, ok := scales[block]; ok && i != 0 {
+			freq *= scale
+		}
+		freqs[block] = freq
+	}
+	return freqs
+}
+
+// edgeProbs returns the probability of each successor edge of the given
+// terminator, as derived from its branch weights; or evenly split between
+// successors if not present.
+func edgeProbs(term ir.Terminator) []float64 {
+	succs := term.Succs()
+	probs := make([]float64, len(succs))
+	if weights, ok := BranchWeights(term); ok {
+		var total float64
+		for _, w := range weights {
+			total += float64(w)
+		}
+		if total > 0 {
+			for i, w := range weights {
+				probs[i] = float64(w) / total
+			}
+			return probs
+		}
+	}
+	for i := range probs {
+		probs[i] = 1 / float64(len(succs))
+	}
+	return probs
+}
+
+// countThreshold returns the minimum count of the largest counts which
+// together account for the given fraction of the total count.
+func countThreshold(counts []uint64, cutoff float64) uint64 {
+	if len(counts) == 0 {
+		return 0
+	}
+	sorted := append([]uint64(nil), counts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+	var total float64
+	for _, count := range sorted {
+		total += float64(count)
+	}
+	var sum float64
+	for _, count := range sorted {
+		sum += float64(count)
+		if sum >= cutoff*total {
+			return count
+		}
+	}
+	return sorted[len(sorted)-1]
+}
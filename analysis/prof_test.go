@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestSummarizeProfile(t *testing.T) {
+	m := &ir.Module{}
+	// Hot function with a loop taken 99 times out of 100.
+	hot := m.NewFunc("hot", types.Void)
+	hot.Metadata = append(hot.Metadata, entryCount(1000))
+	entry := ir.NewBlock("entry")
+	loop := ir.NewBlock("loop")
+	exit := ir.NewBlock("exit")
+	entry.NewBr(loop)
+	cond := loop.NewICmp(enum.IPredSLT, constI32(0), constI32(1))
+	br := loop.NewCondBr(cond, loop, exit)
+	br.Metadata = append(br.Metadata, branchWeights(99, 1))
+	exit.NewRet(nil)
+	hot.Blocks = append(hot.Blocks, entry, loop, exit)
+	// Cold function.
+	cold := m.NewFunc("cold", types.Void)
+	cold.Metadata = append(cold.Metadata, entryCount(1))
+	coldEntry := ir.NewBlock("entry")
+	coldEntry.NewRet(nil)
+	cold.Blocks = append(cold.Blocks, coldEntry)
+	// Function without profile metadata.
+	diamond := newDiamond()
+	m.Funcs = append(m.Funcs, diamond)
+
+	s := SummarizeProfile(m)
+	freqs := []struct {
+		block *ir.BasicBlock
+		want  float64
+	}{
+		{block: entry, want: 1},
+		{block: loop, want: 100},
+		{block: exit, want: 1},
+		{block: coldEntry, want: 1},
+		{block: diamond.Blocks[1], want: 0.5},
+		{block: diamond.Blocks[3], want: 1},
+	}
+	for _, g := range freqs {
+		if got := s.BlockFreq(g.block); math.Abs(got-g.want) > 1e-6 {
+			t.Errorf("block frequency mismatch of %v; expected %v, got %v", g.block.Ident(), g.want, got)
+		}
+	}
+	if count, ok := s.BlockCount(loop); !ok || count != 100000 {
+		t.Errorf("block count mismatch; expected 100000, got %v", count)
+	}
+	if _, ok := s.BlockCount(diamond.Blocks[0]); ok {
+		t.Errorf("unexpected block count of function without profile metadata")
+	}
+	hotness := []struct {
+		name      string
+		got, want bool
+	}{
+		{name: "IsHotFunc(hot)", got: s.IsHotFunc(hot), want: true},
+		{name: "IsColdFunc(hot)", got: s.IsColdFunc(hot), want: false},
+		{name: "IsHotFunc(cold)", got: s.IsHotFunc(cold), want: false},
+		{name: "IsColdFunc(cold)", got: s.IsColdFunc(cold), want: true},
+		{name: "IsHotFunc(diamond)", got: s.IsHotFunc(diamond), want: false},
+		{name: "IsColdFunc(diamond)", got: s.IsColdFunc(diamond), want: false},
+		{name: "IsHotBlock(loop)", got: s.IsHotBlock(loop), want: true},
+		{name: "IsColdBlock(coldEntry)", got: s.IsColdBlock(coldEntry), want: true},
+	}
+	for _, g := range hotness {
+		if g.got != g.want {
+			t.Errorf("%s mismatch; expected %v, got %v", g.name, g.want, g.got)
+		}
+	}
+}
+
+// entryCount returns a function entry count profile attachment.
+func entryCount(count int64) ir.MetadataAttachment {
+	prof := ir.NewTuple(ir.NewMDString("function_entry_count"), ir.NewMDValue(ir.NewInt(types.I64, count)))
+	return ir.NewMetadataAttachment(ir.MDKindProf, prof)
+}
+
+// branchWeights returns a branch weights profile attachment.
+func branchWeights(weights ...int64) ir.MetadataAttachment {
+	fields := []ir.Metadata{ir.NewMDString("branch_weights")}
+	for _, w := range weights {
+		fields = append(fields, ir.NewMDValue(constI32(w)))
+	}
+	return ir.NewMetadataAttachment(ir.MDKindProf, ir.NewTuple(fields...))
+}
@@ -0,0 +1,199 @@
+package analysis
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+// --- [ Value numbering ] -----------------------------------------------------
+
+// ValueNumbering is a hash-based value numbering of the values of a function.
+// Values with the same value number are congruent; i.e. they are guaranteed to
+// compute the same value.
+//
+// Value numbers are assigned in the following order, starting at 0, and are
+// independent of local variable names.
+//
+//    1. function parameters, in order of declaration;
+//    2. values used and defined by the instructions of the basic blocks, with
+//       basic blocks in reverse postorder and instructions in order; each
+//       operand is numbered before the instruction using it.
+//
+// Two pure instructions (e.g. add, icmp, getelementptr, select) are congruent
+// if they have the same opcode, result type, flags and congruent operands; the
+// operands of commutative instructions are compared irrespective of order.
+// Constants are congruent if they have the same LLVM syntax representation.
+// Every other value (e.g. parameters, phi, load and call instructions) has a
+// unique value number.
+type ValueNumbering struct {
+	// Value number of each value.
+	nums map[value.Value]int
+	// Value number of each hash key.
+	keys map[string]int
+	// Leader of each value number; the first value assigned the value number.
+	leaders []value.Value
+}
+
+// NumberValues returns the value numbering of the given function.
+func NumberValues(f *ir.Function) *ValueNumbering {
+	vn := &ValueNumbering{
+		nums: make(map[value.Value]int),
+		keys: make(map[string]int),
+	}
+	for _, param := range f.Params {
+		vn.unique(param)
+	}
+	for _, block := range ReversePostorder(f) {
+		for _, inst := range block.Insts {
+			if v, ok := inst.(value.Value); ok {
+				vn.number(v)
+			}
+		}
+	}
+	return vn
+}
+
+// Number returns the value number of the given value. The boolean return value
+// indicates success.
+func (vn *ValueNumbering) Number(v value.Value) (int, bool) {
+	num, ok := vn.nums[v]
+	return num, ok
+}
+
+// Leader returns the first value assigned the given value number, or nil if
+// not present.
+func (vn *ValueNumbering) Leader(num int) value.Value {
+	if num < 0 || num >= len(vn.leaders) {
+		return nil
+	}
+	return vn.leaders[num]
+}
+
+// Congruent reports whether the two given values have the same value number.
+func (vn *ValueNumbering) Congruent(a, b value.Value) bool {
+	x, ok := vn.nums[a]
+	if !ok {
+		return false
+	}
+	y, ok := vn.nums[b]
+	return ok && x == y
+}
+
+// ### [ Helper functions ] ####################################################
+
+// number returns the value number of the given value, assigning one if not
+// already present.
+func (vn *ValueNumbering) number(v value.Value) int {
+	if num, ok := vn.nums[v]; ok {
+		return num
+	}
+	switch x := v.(type) {
+	case ir.Constant:
+		// Constants are congruent if structurally equal.
+		return vn.keyed(v, "const "+x.String())
+	case *ir.Global, *ir.Function:
+		return vn.keyed(v, "global "+v.Ident())
+	case ir.Instruction:
+		if isPure(x) {
+			return vn.keyed(v, vn.instKey(x))
+		}
+	}
+	return vn.unique(v)
+}
+
+// keyed assigns the value number associated with the given hash key to v.
+func (vn *ValueNumbering) keyed(v value.Value, key string) int {
+	if num, ok := vn.keys[key]; ok {
+		vn.nums[v] = num
+		return num
+	}
+	num := vn.unique(v)
+	vn.keys[key] = num
+	return num
+}
+
+// unique assigns a new value number to v.
+func (vn *ValueNumbering) unique(v value.Value) int {
+	num := len(vn.leaders)
+	vn.leaders = append(vn.leaders, v)
+	vn.nums[v] = num
+	return num
+}
+
+// instKey returns the hash key of the given pure instruction, based on its
+// opcode, result type, flags and the value numbers of its operands.
+func (vn *ValueNumbering) instKey(inst ir.Instruction) string {
+	var operands []string
+	var attrs []string
+	v := reflect.ValueOf(inst).Elem()
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		if len(t.Field(i).PkgPath) > 0 {
+			// Skip unexported fields.
+			continue
+		}
+		switch t.Field(i).Name {
+		case "LocalName", "Typ", "Metadata", "Comments":
+			// Skip local names, cached types, metadata and comments.
+			continue
+		}
+		field := v.Field(i).Interface()
+		switch field := field.(type) {
+		case value.Value:
+			operands = append(operands, fmt.Sprintf("v%d", vn.number(field)))
+		case []value.Value:
+			for _, x := range field {
+				operands = append(operands, fmt.Sprintf("v%d", vn.number(x)))
+			}
+		case types.Type:
+			attrs = append(attrs, field.String())
+		default:
+			attrs = append(attrs, fmt.Sprintf("%v", field))
+		}
+	}
+	if isCommutative(inst) {
+		sort.Strings(operands)
+	}
+	return fmt.Sprintf("%T %v [%s] (%s)", inst, inst.(value.Value).Type(), strings.Join(attrs, " "), strings.Join(operands, ", "))
+}
+
+// isPure reports whether the given instruction is free of side effects and
+// computes its result solely from its operands.
+func isPure(inst ir.Instruction) bool {
+	switch inst.(type) {
+	case *ir.InstAlloca, *ir.InstLoad, *ir.InstStore, *ir.InstFence, *ir.InstCmpXchg, *ir.InstAtomicRMW:
+		// Memory instructions.
+		return false
+	case *ir.InstPhi, *ir.InstCall, *ir.InstVAArg, *ir.InstLandingPad, *ir.InstCatchPad, *ir.InstCleanupPad:
+		// Other instructions dependent on control flow or side effects.
+		return false
+	case *ir.InstFreeze:
+		// Each freeze of a poison or undef operand may yield a different value.
+		return false
+	case *ir.RawInst, ir.CustomInst:
+		// Raw and custom instructions are treated conservatively.
+		return false
+	}
+	return true
+}
+
+// isCommutative reports whether the operands of the given instruction may be
+// swapped without changing its result.
+func isCommutative(inst ir.Instruction) bool {
+	switch inst := inst.(type) {
+	case *ir.InstAdd, *ir.InstFAdd, *ir.InstMul, *ir.InstFMul, *ir.InstAnd, *ir.InstOr, *ir.InstXor:
+		return true
+	case *ir.InstICmp:
+		return inst.Pred == enum.IPredEQ || inst.Pred == enum.IPredNE
+	case *ir.InstFCmp:
+		return inst.Pred == enum.FPredOEQ || inst.Pred == enum.FPredONE || inst.Pred == enum.FPredUEQ || inst.Pred == enum.FPredUNE
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+func TestNumberValues(t *testing.T) {
+	x := ir.NewParam(types.I32, "x")
+	y := ir.NewParam(types.I32, "y")
+	f := ir.NewFunc("f", types.I32, x, y)
+	entry := ir.NewBlock("entry")
+	a := entry.NewAdd(x, y)
+	b := entry.NewAdd(y, x)
+	c := entry.NewSub(x, y)
+	d := entry.NewSub(y, x)
+	e := entry.NewMul(a, ir.NewInt(types.I32, 2))
+	g := entry.NewMul(b, ir.NewInt(types.I32, 2))
+	h := entry.NewAdd(e, g)
+	entry.NewRet(h)
+	f.Blocks = append(f.Blocks, entry)
+	vn := NumberValues(f)
+	golden := []struct {
+		a, b value.Value
+		want bool
+	}{
+		// i=0: commutative operands.
+		{a: a, b: b, want: true},
+		// i=1: non-commutative operands.
+		{a: c, b: d, want: false},
+		// i=2: congruent operands.
+		{a: e, b: g, want: true},
+		// i=3: different opcodes.
+		{a: a, b: c, want: false},
+		// i=4: parameters.
+		{a: x, b: y, want: false},
+	}
+	for i, gold := range golden {
+		if got := vn.Congruent(gold.a, gold.b); gold.want != got {
+			t.Errorf("i=%d: congruence mismatch of %v and %v; expected %v, got %v", i, gold.a, gold.b, gold.want, got)
+		}
+	}
+	// Value numbers are stable and independent of local names.
+	want := []int{0, 1, 2, 2, 3, 4}
+	for i, v := range []value.Value{x, y, a, b, c, d} {
+		if got, _ := vn.Number(v); want[i] != got {
+			t.Errorf("value number mismatch of %v; expected %d, got %d", v, want[i], got)
+		}
+	}
+	if leader := vn.Leader(2); leader != a {
+		t.Errorf("leader mismatch; expected %v, got %v", a, leader)
+	}
+}
@@ -0,0 +1,322 @@
+package datalayout
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// === [ Data layouts ] ========================================================
+
+// DataLayout is a parsed target data layout. Sizes and alignments are in bits.
+type DataLayout struct {
+	// Big-endian; false if little-endian.
+	BigEndian bool
+	// Natural stack alignment; or 0 if not specified.
+	StackAlign int
+	// Address space of program memory, global variables and allocas.
+	ProgramAddrSpace, GlobalAddrSpace, AllocaAddrSpace int
+	// Name mangling style (e.g. "e" for ELF and "o" for Mach-O); or empty if not
+	// specified.
+	Mangling string
+	// Function pointer alignment specification (e.g. "i8"); or empty if not
+	// specified.
+	FuncPtrAlign string
+	// Pointer specifications, sorted by address space.
+	Pointers []PointerSpec
+	// Integer, floating-point and vector alignment specifications, sorted by
+	// size.
+	Ints, Floats, Vectors []AlignSpec
+	// Alignment of aggregate types; the Size of the specification is unused.
+	Aggregate AlignSpec
+	// Native integer widths of the target, sorted by size.
+	LegalInts []int
+	// Address spaces with non-integral pointer types.
+	NonIntegral []int
+}
+
+// PointerSpec specifies the size, alignment and index size of pointers in an
+// address space.
+type PointerSpec struct {
+	// Address space.
+	AddrSpace int
+	// Pointer size.
+	Size int
+	// ABI and preferred alignment.
+	ABIAlign, PrefAlign int
+	// Size of indices used in address calculations (e.g. getelementptr).
+	IndexSize int
+}
+
+// AlignSpec specifies the alignment of a type of a given size.
+type AlignSpec struct {
+	// Type size.
+	Size int
+	// ABI and preferred alignment.
+	ABIAlign, PrefAlign int
+}
+
+// Default returns the default data layout, as used for specifications not
+// present in a data layout string.
+func Default() *DataLayout {
+	return &DataLayout{
+		Pointers: []PointerSpec{{AddrSpace: 0, Size: 64, ABIAlign: 64, PrefAlign: 64, IndexSize: 64}},
+		Ints: []AlignSpec{
+			{Size: 1, ABIAlign: 8, PrefAlign: 8},
+			{Size: 8, ABIAlign: 8, PrefAlign: 8},
+			{Size: 16, ABIAlign: 16, PrefAlign: 16},
+			{Size: 32, ABIAlign: 32, PrefAlign: 32},
+			{Size: 64, ABIAlign: 32, PrefAlign: 64},
+		},
+		Floats: []AlignSpec{
+			{Size: 16, ABIAlign: 16, PrefAlign: 16},
+			{Size: 32, ABIAlign: 32, PrefAlign: 32},
+			{Size: 64, ABIAlign: 64, PrefAlign: 64},
+			{Size: 128, ABIAlign: 128, PrefAlign: 128},
+		},
+		Vectors: []AlignSpec{
+			{Size: 64, ABIAlign: 64, PrefAlign: 64},
+			{Size: 128, ABIAlign: 128, PrefAlign: 128},
+		},
+		Aggregate: AlignSpec{ABIAlign: 0, PrefAlign: 64},
+	}
+}
+
+// Parse parses the given data layout string (e.g. "e-m:e-i64:64-n8:16:32:64"),
+// with specifications not present in the data layout string set to their
+// defaults.
+func Parse(s string) (*DataLayout, error) {
+	dl := Default()
+	if len(s) == 0 {
+		return dl, nil
+	}
+	for _, spec := range strings.Split(s, "-") {
+		if err := dl.parseSpec(spec); err != nil {
+			return nil, errors.Wrapf(err, "invalid data layout specification %q", spec)
+		}
+	}
+	return dl, nil
+}
+
+// IsBigEndian reports whether the target is big-endian.
+func (dl *DataLayout) IsBigEndian() bool {
+	return dl.BigEndian
+}
+
+// PointerSizeInBits returns the size of pointers in the given address space.
+func (dl *DataLayout) PointerSizeInBits(addrSpace int) int {
+	return dl.pointerSpec(addrSpace).Size
+}
+
+// IndexSizeInBits returns the size of indices used in address calculations of
+// pointers in the given address space.
+func (dl *DataLayout) IndexSizeInBits(addrSpace int) int {
+	return dl.pointerSpec(addrSpace).IndexSize
+}
+
+// IsNonIntegral reports whether pointers in the given address space are
+// non-integral.
+func (dl *DataLayout) IsNonIntegral(addrSpace int) bool {
+	for _, as := range dl.NonIntegral {
+		if as == addrSpace {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLegalInteger reports whether integers of the given width are native to the
+// target.
+func (dl *DataLayout) IsLegalInteger(width int) bool {
+	for _, w := range dl.LegalInts {
+		if w == width {
+			return true
+		}
+	}
+	return false
+}
+
+// LargestLegalIntWidth returns the width of the largest integer type native to
+// the target; or 0 if not specified.
+func (dl *DataLayout) LargestLegalIntWidth() int {
+	if len(dl.LegalInts) == 0 {
+		return 0
+	}
+	return dl.LegalInts[len(dl.LegalInts)-1]
+}
+
+// SmallestLegalIntWidth returns the width of the smallest integer type native
+// to the target which is at least as wide as the given width; or 0 if not
+// present.
+func (dl *DataLayout) SmallestLegalIntWidth(width int) int {
+	for _, w := range dl.LegalInts {
+		if w >= width {
+			return w
+		}
+	}
+	return 0
+}
+
+// ### [ Helper functions ] ####################################################
+
+// pointerSpec returns the pointer specification of the given address space, or
+// the specification of address space 0 if not present.
+func (dl *DataLayout) pointerSpec(addrSpace int) PointerSpec {
+	var def PointerSpec
+	for _, spec := range dl.Pointers {
+		if spec.AddrSpace == addrSpace {
+			return spec
+		}
+		if spec.AddrSpace == 0 {
+			def = spec
+		}
+	}
+	return def
+}
+
+// parseSpec parses the given data layout specification into dl.
+func (dl *DataLayout) parseSpec(spec string) error {
+	if len(spec) == 0 {
+		return errors.New("empty specification")
+	}
+	switch {
+	case spec == "e":
+		dl.BigEndian = false
+		return nil
+	case spec == "E":
+		dl.BigEndian = true
+		return nil
+	case strings.HasPrefix(spec, "m:"):
+		dl.Mangling = spec[len("m:"):]
+		return nil
+	case strings.HasPrefix(spec, "ni:"):
+		as, err := parseInts(strings.Split(spec[len("ni:"):], ":"))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		dl.NonIntegral = as
+		return nil
+	}
+	fields := strings.Split(spec[1:], ":")
+	switch spec[0] {
+	case 'S', 'P', 'G', 'A':
+		n, err := strconv.Atoi(spec[1:])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch spec[0] {
+		case 'S':
+			dl.StackAlign = n
+		case 'P':
+			dl.ProgramAddrSpace = n
+		case 'G':
+			dl.GlobalAddrSpace = n
+		case 'A':
+			dl.AllocaAddrSpace = n
+		}
+		return nil
+	case 'F':
+		dl.FuncPtrAlign = spec[1:]
+		return nil
+	case 'n':
+		widths, err := parseInts(fields)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		sort.Ints(widths)
+		dl.LegalInts = widths
+		return nil
+	case 'p':
+		// p[n]:<size>:<abi>[:<pref>][:<idx>]
+		vals, err := parseInts(fields)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if len(vals) < 3 || len(vals) > 5 {
+			return errors.Errorf("invalid number of pointer specification fields; expected 3-5, got %d", len(vals))
+		}
+		p := PointerSpec{AddrSpace: vals[0], Size: vals[1], ABIAlign: vals[2], PrefAlign: vals[2], IndexSize: vals[1]}
+		if len(vals) > 3 {
+			p.PrefAlign = vals[3]
+		}
+		if len(vals) > 4 {
+			p.IndexSize = vals[4]
+		}
+		dl.Pointers = setPointerSpec(dl.Pointers, p)
+		return nil
+	case 'i', 'f', 'v', 'a':
+		// i<size>:<abi>[:<pref>]
+		vals, err := parseInts(fields)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if len(vals) < 2 || len(vals) > 3 {
+			return errors.Errorf("invalid number of alignment specification fields; expected 2-3, got %d", len(vals))
+		}
+		a := AlignSpec{Size: vals[0], ABIAlign: vals[1], PrefAlign: vals[1]}
+		if len(vals) > 2 {
+			a.PrefAlign = vals[2]
+		}
+		switch spec[0] {
+		case 'i':
+			dl.Ints = setAlignSpec(dl.Ints, a)
+		case 'f':
+			dl.Floats = setAlignSpec(dl.Floats, a)
+		case 'v':
+			dl.Vectors = setAlignSpec(dl.Vectors, a)
+		case 'a':
+			a.Size = 0
+			dl.Aggregate = a
+		}
+		return nil
+	}
+	return errors.Errorf("unknown specification kind %q", spec[0])
+}
+
+// parseInts parses the given decimal integers. Empty strings are parsed as 0
+// (e.g. the omitted address space of "p:64:64").
+func parseInts(ss []string) ([]int, error) {
+	var vals []int
+	for _, s := range ss {
+		if len(s) == 0 {
+			vals = append(vals, 0)
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		vals = append(vals, n)
+	}
+	return vals, nil
+}
+
+// setPointerSpec adds or replaces the pointer specification of the address
+// space of p, keeping specs sorted by address space.
+func setPointerSpec(specs []PointerSpec, p PointerSpec) []PointerSpec {
+	for i, spec := range specs {
+		if spec.AddrSpace == p.AddrSpace {
+			specs[i] = p
+			return specs
+		}
+	}
+	specs = append(specs, p)
+	sort.Slice(specs, func(i, j int) bool { return specs[i].AddrSpace < specs[j].AddrSpace })
+	return specs
+}
+
+// setAlignSpec adds or replaces the alignment specification of the size of a,
+// keeping specs sorted by size.
+func setAlignSpec(specs []AlignSpec, a AlignSpec) []AlignSpec {
+	for i, spec := range specs {
+		if spec.Size == a.Size {
+			specs[i] = a
+			return specs
+		}
+	}
+	specs = append(specs, a)
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Size < specs[j].Size })
+	return specs
+}
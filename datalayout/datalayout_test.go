@@ -0,0 +1,79 @@
+package datalayout
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	golden := []struct {
+		in         string
+		bigEndian  bool
+		ptrSize    [2]int // address spaces 0 and 270
+		indexSize  [2]int // address spaces 0 and 270
+		largestInt int
+		legal      []int
+		illegal    []int
+	}{
+		// i=0
+		{
+			in:         "",
+			ptrSize:    [2]int{64, 64},
+			indexSize:  [2]int{64, 64},
+			largestInt: 0,
+			illegal:    []int{8, 32},
+		},
+		// i=1
+		{
+			in:         "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128",
+			ptrSize:    [2]int{64, 32},
+			indexSize:  [2]int{64, 32},
+			largestInt: 64,
+			legal:      []int{8, 16, 32, 64},
+			illegal:    []int{1, 128},
+		},
+		// i=2
+		{
+			in:         "E-m:e-p:32:32:32:16-i64:64-n32",
+			bigEndian:  true,
+			ptrSize:    [2]int{32, 32},
+			indexSize:  [2]int{16, 16},
+			largestInt: 32,
+			legal:      []int{32},
+			illegal:    []int{8, 64},
+		},
+	}
+	for i, g := range golden {
+		dl, err := Parse(g.in)
+		if err != nil {
+			t.Errorf("i=%d: unable to parse data layout %q; %v", i, g.in, err)
+			continue
+		}
+		if got := dl.IsBigEndian(); g.bigEndian != got {
+			t.Errorf("i=%d: endianness mismatch; expected big-endian %v, got %v", i, g.bigEndian, got)
+		}
+		for j, addrSpace := range []int{0, 270} {
+			if got := dl.PointerSizeInBits(addrSpace); g.ptrSize[j] != got {
+				t.Errorf("i=%d: pointer size mismatch of address space %d; expected %d, got %d", i, addrSpace, g.ptrSize[j], got)
+			}
+			if got := dl.IndexSizeInBits(addrSpace); g.indexSize[j] != got {
+				t.Errorf("i=%d: index size mismatch of address space %d; expected %d, got %d", i, addrSpace, g.indexSize[j], got)
+			}
+		}
+		if got := dl.LargestLegalIntWidth(); g.largestInt != got {
+			t.Errorf("i=%d: largest legal integer width mismatch; expected %d, got %d", i, g.largestInt, got)
+		}
+		for _, width := range g.legal {
+			if !dl.IsLegalInteger(width) {
+				t.Errorf("i=%d: expected i%d to be legal", i, width)
+			}
+		}
+		for _, width := range g.illegal {
+			if dl.IsLegalInteger(width) {
+				t.Errorf("i=%d: expected i%d to be illegal", i, width)
+			}
+		}
+	}
+	for _, in := range []string{"e-x", "p:64", "i64:a", "e--n8"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("expected error for invalid data layout %q", in)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package datalayout
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// === [ Address calculations ] ================================================
+
+// IndexedOffset returns the byte offset computed by a getelementptr with the
+// given source element type and constant indices. The first index steps over
+// values of the source element type, and each successive index selects an
+// element of an array or vector type or a field of a struct type.
+func (dl *DataLayout) IndexedOffset(elemType types.Type, indices []int64) (int64, error) {
+	if len(indices) == 0 {
+		return 0, nil
+	}
+	if !dl.IsSized(elemType) {
+		return 0, errors.Errorf("invalid getelementptr source element type; unsized type %v", elemType)
+	}
+	offset := indices[0] * dl.TypeAllocSize(elemType)
+	t := elemType
+	for _, index := range indices[1:] {
+		switch tt := t.(type) {
+		case *types.ArrayType:
+			t = tt.ElemType
+			offset += index * dl.TypeAllocSize(t)
+		case *types.VectorType:
+			t = tt.ElemType
+			offset += index * dl.TypeAllocSize(t)
+		case *types.StructType:
+			if index < 0 || index >= int64(len(tt.Fields)) {
+				return 0, errors.Errorf("invalid struct field index %d of %v; expected index in range [0, %d)", index, tt, len(tt.Fields))
+			}
+			offset += dl.StructLayout(tt).Offsets[index]
+			t = tt.Fields[index]
+		default:
+			return 0, errors.Errorf("invalid getelementptr index into non-aggregate type %v", t)
+		}
+	}
+	return offset, nil
+}
+
+// GEPOffset returns the byte offset from the source address of the given
+// constant getelementptr expression. The offset is wrapped to the index size
+// of the address space of the source address.
+func (dl *DataLayout) GEPOffset(e *ir.ExprGetElementPtr) (int64, error) {
+	indices := make([]int64, len(e.Indices))
+	for i, index := range e.Indices {
+		c, ok := index.Index.(*ir.ConstInt)
+		if !ok {
+			return 0, errors.Errorf("unable to fold getelementptr expression; non-integer constant index %v", index.Index)
+		}
+		if !c.X.IsInt64() {
+			return 0, errors.Errorf("unable to fold getelementptr expression; index %v out of range", c.X)
+		}
+		indices[i] = c.X.Int64()
+	}
+	offset, err := dl.IndexedOffset(e.ElemType, indices)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return wrapIndex(offset, dl.IndexSizeInBits(addrSpaceOf(e.Src.Type()))), nil
+}
+
+// OffsetIndices returns the getelementptr indices which address the given byte
+// offset from a value of the given source element type, the element type
+// addressed by the indices, and the remaining byte offset not reachable by
+// indices. Indices descend into arrays and structs until the remaining offset
+// is 0, or the offset falls within the padding of a struct.
+//
+// OffsetIndices is the inverse of IndexedOffset; for a remaining offset of 0,
+// IndexedOffset(elemType, indices) returns offset.
+func (dl *DataLayout) OffsetIndices(elemType types.Type, offset int64) (indices []int64, indexedType types.Type, rem int64) {
+	size := dl.TypeAllocSize(elemType)
+	if size == 0 {
+		return []int64{0}, elemType, offset
+	}
+	index := floorDiv(offset, size)
+	indices = append(indices, index)
+	rem = offset - index*size
+	t := elemType
+	for rem != 0 {
+		switch tt := t.(type) {
+		case *types.ArrayType:
+			elemSize := dl.TypeAllocSize(tt.ElemType)
+			if elemSize == 0 {
+				return indices, t, rem
+			}
+			index := rem / elemSize
+			indices = append(indices, index)
+			rem -= index * elemSize
+			t = tt.ElemType
+		case *types.StructType:
+			layout := dl.StructLayout(tt)
+			index := layout.FieldAt(rem)
+			if index == -1 || rem-layout.Offsets[index] >= dl.TypeAllocSize(tt.Fields[index]) {
+				// Offset within padding.
+				return indices, t, rem
+			}
+			indices = append(indices, int64(index))
+			rem -= layout.Offsets[index]
+			t = tt.Fields[index]
+		default:
+			return indices, t, rem
+		}
+	}
+	return indices, t, rem
+}
+
+// NewGEPExpr returns a new constant getelementptr expression which addresses
+// the given byte offset from the source address, based on the given source
+// element type. Struct field indices are of type i32 and other indices are
+// integers of the index size of the address space of the source address. An
+// error is returned if the offset is not at the start of an element.
+func (dl *DataLayout) NewGEPExpr(elemType types.Type, src ir.Constant, offset int64) (*ir.ExprGetElementPtr, error) {
+	indices, _, rem := dl.OffsetIndices(elemType, offset)
+	if rem != 0 {
+		return nil, errors.Errorf("unable to address byte offset %d of %v; %d bytes past the start of an element", offset, elemType, rem)
+	}
+	indexType := types.NewInt(int64(dl.IndexSizeInBits(addrSpaceOf(src.Type()))))
+	e := ir.NewGetElementPtrExpr(elemType, src)
+	t := elemType
+	for i, index := range indices {
+		typ := indexType
+		if i > 0 {
+			switch tt := t.(type) {
+			case *types.ArrayType:
+				t = tt.ElemType
+			case *types.StructType:
+				typ = types.I32
+				t = tt.Fields[index]
+			}
+		}
+		e.Indices = append(e.Indices, ir.NewIndex(ir.NewInt(typ, index)))
+	}
+	return e, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// addrSpaceOf returns the address space of the given pointer type; or 0 if not
+// a pointer type.
+func addrSpaceOf(t types.Type) int {
+	if t, ok := t.(*types.PointerType); ok {
+		return int(t.AddrSpace)
+	}
+	return 0
+}
+
+// wrapIndex returns the given offset sign-extended from the given index size in
+// bits.
+func wrapIndex(offset int64, size int) int64 {
+	if size <= 0 || size >= 64 {
+		return offset
+	}
+	shift := uint(64 - size)
+	return offset << shift >> shift
+}
+
+// floorDiv returns x/y rounded towards negative infinity.
+func floorDiv(x, y int64) int64 {
+	q := x / y
+	if x%y != 0 && (x < 0) != (y < 0) {
+		q--
+	}
+	return q
+}
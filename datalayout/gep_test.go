@@ -0,0 +1,75 @@
+package datalayout
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestGEPOffset(t *testing.T) {
+	dl, err := Parse("e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// %T = type { i8, [4 x { i16, i64 }], i32 }
+	inner := types.NewStruct(types.I16, types.I64)
+	outer := types.NewStruct(types.I8, types.NewArray(4, inner), types.I32)
+	g := ir.NewGlobalDecl("x", outer)
+	golden := []struct {
+		indices []int64
+		want    int64
+		// Expected remaining offset of OffsetIndices.
+		rem int64
+	}{
+		// i=0
+		{indices: []int64{0}, want: 0},
+		// i=1
+		{indices: []int64{1}, want: 80},
+		// i=2
+		{indices: []int64{0, 1, 2, 1}, want: 8 + 2*16 + 8},
+		// i=3
+		{indices: []int64{-1, 2}, want: -80 + 72},
+		// i=4
+		{indices: []int64{0, 1, 3}, want: 8 + 3*16},
+	}
+	for i, gold := range golden {
+		var indices []*ir.Index
+		for j, index := range gold.indices {
+			typ := types.I64
+			if j == 1 || j == 3 {
+				typ = types.I32
+			}
+			indices = append(indices, ir.NewIndex(ir.NewInt(typ, index)))
+		}
+		e := ir.NewGetElementPtrExpr(outer, g, indices...)
+		got, err := dl.GEPOffset(e)
+		if err != nil {
+			t.Errorf("i=%d: unable to fold %v; %v", i, e.Ident(), err)
+			continue
+		}
+		if gold.want != got {
+			t.Errorf("i=%d: offset mismatch of %v; expected %d, got %d", i, e.Ident(), gold.want, got)
+		}
+		back, _, rem := dl.OffsetIndices(outer, got)
+		if rem != 0 || !reflect.DeepEqual(gold.indices, back) {
+			t.Errorf("i=%d: indices mismatch of offset %d; expected %v, got %v (remaining offset %d)", i, got, gold.indices, back, rem)
+		}
+	}
+	// Offset within padding.
+	if _, _, rem := dl.OffsetIndices(outer, 3); rem != 3 {
+		t.Errorf("remaining offset mismatch; expected 3, got %d", rem)
+	}
+	if _, err := dl.NewGEPExpr(outer, g, 3); err == nil {
+		t.Errorf("expected error for offset within padding")
+	}
+	e, err := dl.NewGEPExpr(outer, g, 8+16+8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "getelementptr ({ i8, [4 x { i16, i64 }], i32 }, { i8, [4 x { i16, i64 }], i32 }* @x, i64 0, i32 1, i64 1, i32 1)"
+	if got := e.Ident(); want != got {
+		t.Errorf("getelementptr expression mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
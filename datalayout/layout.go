@@ -0,0 +1,276 @@
+package datalayout
+
+import (
+	"sort"
+
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// === [ Type layouts ] ========================================================
+
+// IsSized reports whether values of the given type have a size in memory;
+// i.e. whether the type is not void, function, token, metadata, an opaque struct
+// type or an aggregate type containing an unsized type.
+func (dl *DataLayout) IsSized(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.IntType, *types.FloatType, *types.PointerType, *types.MMXType, *types.LabelType:
+		return true
+	case *types.VectorType:
+		return dl.IsSized(t.ElemType)
+	case *types.ArrayType:
+		return dl.IsSized(t.ElemType)
+	case *types.StructType:
+		if t.Opaque {
+			return false
+		}
+		for _, field := range t.Fields {
+			if !dl.IsSized(field) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// TypeSizeInBits returns the number of bits needed to hold a value of the given
+// type, excluding padding. It panics if the type is unsized.
+func (dl *DataLayout) TypeSizeInBits(t types.Type) int64 {
+	switch t := t.(type) {
+	case *types.IntType:
+		return t.BitSize
+	case *types.FloatType:
+		return floatSize(t.Kind)
+	case *types.PointerType:
+		return int64(dl.PointerSizeInBits(int(t.AddrSpace)))
+	case *types.LabelType:
+		return int64(dl.PointerSizeInBits(0))
+	case *types.MMXType:
+		return 64
+	case *types.VectorType:
+		return t.Len * dl.TypeSizeInBits(t.ElemType)
+	case *types.ArrayType:
+		return t.Len * dl.TypeAllocSize(t.ElemType) * 8
+	case *types.StructType:
+		return dl.StructLayout(t).Size * 8
+	default:
+		panic(errors.Errorf("unable to compute size of unsized type %v", t))
+	}
+}
+
+// TypeStoreSize returns the maximum number of bytes written when storing a
+// value of the given type. It panics if the type is unsized.
+func (dl *DataLayout) TypeStoreSize(t types.Type) int64 {
+	return (dl.TypeSizeInBits(t) + 7) / 8
+}
+
+// TypeAllocSize returns the offset in bytes between successive values of the
+// given type (e.g. elements of an array), including alignment padding. It
+// panics if the type is unsized.
+func (dl *DataLayout) TypeAllocSize(t types.Type) int64 {
+	return alignTo(dl.TypeStoreSize(t), dl.ABIAlign(t))
+}
+
+// ABIAlign returns the minimum alignment in bytes required by the ABI for
+// values of the given type. It panics if the type is unsized.
+func (dl *DataLayout) ABIAlign(t types.Type) int64 {
+	return dl.align(t, true)
+}
+
+// PrefAlign returns the preferred alignment in bytes of values of the given
+// type (e.g. as used for global variables). It panics if the type is unsized.
+func (dl *DataLayout) PrefAlign(t types.Type) int64 {
+	return dl.align(t, false)
+}
+
+// --- [ Struct layouts ] ------------------------------------------------------
+
+// StructLayout is the memory layout of a struct type.
+type StructLayout struct {
+	// Byte offset of each field from the start of the struct.
+	Offsets []int64
+	// Size in bytes of the struct, including padding between fields and tail
+	// padding.
+	Size int64
+	// ABI alignment in bytes of the struct.
+	Align int64
+	// Struct contains padding between fields or tail padding.
+	Padded bool
+}
+
+// StructLayout returns the memory layout of the given struct type. Each field
+// is placed at the next offset which is a multiple of its ABI alignment, except
+// in packed structs where fields are laid out without padding. The size of the
+// struct is rounded up to a multiple of its alignment. It panics if the struct
+// type is unsized.
+func (dl *DataLayout) StructLayout(t *types.StructType) *StructLayout {
+	if t.Opaque {
+		panic(errors.Errorf("unable to compute layout of opaque struct type %v", t))
+	}
+	l := &StructLayout{
+		Offsets: make([]int64, len(t.Fields)),
+		Align:   1,
+	}
+	for i, field := range t.Fields {
+		align := int64(1)
+		if !t.Packed {
+			align = dl.ABIAlign(field)
+		}
+		if l.Size%align != 0 {
+			l.Size = alignTo(l.Size, align)
+			l.Padded = true
+		}
+		if align > l.Align {
+			l.Align = align
+		}
+		l.Offsets[i] = l.Size
+		l.Size += dl.TypeAllocSize(field)
+	}
+	if l.Size%l.Align != 0 {
+		l.Size = alignTo(l.Size, l.Align)
+		l.Padded = true
+	}
+	return l
+}
+
+// FieldAt returns the index of the field containing the given byte offset; or
+// -1 if the offset is outside of the struct. Offsets within padding belong to
+// the preceding field, and empty fields are skipped.
+func (l *StructLayout) FieldAt(offset int64) int {
+	if offset < 0 || offset >= l.Size || len(l.Offsets) == 0 {
+		return -1
+	}
+	// Index of the first field starting after offset.
+	i := sort.Search(len(l.Offsets), func(i int) bool {
+		return l.Offsets[i] > offset
+	})
+	return i - 1
+}
+
+// ### [ Helper functions ] ####################################################
+
+// align returns the ABI or preferred alignment in bytes of the given type.
+func (dl *DataLayout) align(t types.Type, abi bool) int64 {
+	switch t := t.(type) {
+	case *types.IntType:
+		return specAlign(intSpec(dl.Ints, t.BitSize), abi)
+	case *types.FloatType:
+		size := floatSize(t.Kind)
+		if spec, ok := exactSpec(dl.Floats, size); ok {
+			return specAlign(spec, abi)
+		}
+		return naturalAlign(size)
+	case *types.VectorType:
+		size := dl.TypeSizeInBits(t)
+		if spec, ok := exactSpec(dl.Vectors, size); ok {
+			return specAlign(spec, abi)
+		}
+		return naturalAlign(size)
+	case *types.PointerType:
+		spec := dl.pointerSpec(int(t.AddrSpace))
+		if abi {
+			return bitsToBytes(int64(spec.ABIAlign))
+		}
+		return bitsToBytes(int64(spec.PrefAlign))
+	case *types.LabelType:
+		return dl.align(types.NewPointer(types.I8), abi)
+	case *types.MMXType:
+		return 8
+	case *types.ArrayType:
+		return dl.align(t.ElemType, abi)
+	case *types.StructType:
+		if t.Packed && abi {
+			return 1
+		}
+		layout := dl.StructLayout(t)
+		align := specAlign(dl.Aggregate, abi)
+		if layout.Align > align {
+			return layout.Align
+		}
+		return align
+	default:
+		panic(errors.Errorf("unable to compute alignment of unsized type %v", t))
+	}
+}
+
+// intSpec returns the alignment specification of integers of the given size;
+// i.e. the specification of the smallest integer type at least as large, or
+// the largest integer type if none is large enough.
+func intSpec(specs []AlignSpec, size int64) AlignSpec {
+	if len(specs) == 0 {
+		return AlignSpec{ABIAlign: 8, PrefAlign: 8}
+	}
+	for _, spec := range specs {
+		if int64(spec.Size) >= size {
+			return spec
+		}
+	}
+	return specs[len(specs)-1]
+}
+
+// exactSpec returns the alignment specification of the given size. The boolean
+// return value indicates success.
+func exactSpec(specs []AlignSpec, size int64) (AlignSpec, bool) {
+	for _, spec := range specs {
+		if int64(spec.Size) == size {
+			return spec, true
+		}
+	}
+	return AlignSpec{}, false
+}
+
+// specAlign returns the ABI or preferred alignment in bytes of the given
+// alignment specification; at least 1.
+func specAlign(spec AlignSpec, abi bool) int64 {
+	if abi {
+		return bitsToBytes(int64(spec.ABIAlign))
+	}
+	return bitsToBytes(int64(spec.PrefAlign))
+}
+
+// naturalAlign returns the natural alignment in bytes of a type of the given
+// size in bits; i.e. its store size rounded up to a power of two.
+func naturalAlign(size int64) int64 {
+	storeSize := (size + 7) / 8
+	align := int64(1)
+	for align < storeSize {
+		align *= 2
+	}
+	return align
+}
+
+// bitsToBytes converts the given alignment in bits to bytes; at least 1.
+func bitsToBytes(bits int64) int64 {
+	if bits < 8 {
+		return 1
+	}
+	return bits / 8
+}
+
+// alignTo returns x rounded up to a multiple of align.
+func alignTo(x, align int64) int64 {
+	if align <= 1 {
+		return x
+	}
+	return (x + align - 1) / align * align
+}
+
+// floatSize returns the size in bits of the given floating-point kind.
+func floatSize(kind types.FloatKind) int64 {
+	switch kind {
+	case types.FloatKindHalf:
+		return 16
+	case types.FloatKindFloat:
+		return 32
+	case types.FloatKindDouble:
+		return 64
+	case types.FloatKindX86FP80:
+		return 80
+	case types.FloatKindFP128, types.FloatKindPPCFP128:
+		return 128
+	default:
+		panic(errors.Errorf("support for floating-point kind %v not yet implemented", kind))
+	}
+}
@@ -0,0 +1,86 @@
+package datalayout
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/llir/l/ir/types"
+)
+
+func TestStructLayout(t *testing.T) {
+	const x86_64 = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+	const i686 = "e-m:e-p:32:32-p270:32:32-p271:32:32-p272:64:64-f64:32:64-f80:32-n8:16:32-S128"
+	golden := []struct {
+		layout string
+		in     *types.StructType
+		want   *StructLayout
+	}{
+		// i=0
+		{
+			layout: x86_64,
+			in:     types.NewStruct(types.I8, types.I32, types.I8),
+			want:   &StructLayout{Offsets: []int64{0, 4, 8}, Size: 12, Align: 4, Padded: true},
+		},
+		// i=1
+		{
+			layout: x86_64,
+			in:     &types.StructType{Packed: true, Fields: []types.Type{types.I8, types.I32, types.I8}},
+			want:   &StructLayout{Offsets: []int64{0, 1, 5}, Size: 6, Align: 1},
+		},
+		// i=2
+		{
+			layout: x86_64,
+			in:     types.NewStruct(types.I8, types.X86FP80),
+			want:   &StructLayout{Offsets: []int64{0, 16}, Size: 32, Align: 16, Padded: true},
+		},
+		// i=3
+		{
+			layout: i686,
+			in:     types.NewStruct(types.I8, types.Double, types.NewPointer(types.I8)),
+			want:   &StructLayout{Offsets: []int64{0, 4, 12}, Size: 16, Align: 4, Padded: true},
+		},
+		// i=4
+		{
+			layout: x86_64,
+			in:     types.NewStruct(types.I16, types.NewArray(3, types.I16), types.NewStruct(types.I64)),
+			want:   &StructLayout{Offsets: []int64{0, 2, 8}, Size: 16, Align: 8},
+		},
+		// i=5
+		{
+			layout: x86_64,
+			in:     types.NewStruct(types.I1, types.NewVector(4, types.Float)),
+			want:   &StructLayout{Offsets: []int64{0, 16}, Size: 32, Align: 16, Padded: true},
+		},
+	}
+	for i, g := range golden {
+		dl, err := Parse(g.layout)
+		if err != nil {
+			t.Errorf("i=%d: unable to parse data layout %q; %v", i, g.layout, err)
+			continue
+		}
+		got := dl.StructLayout(g.in)
+		if !reflect.DeepEqual(g.want, got) {
+			t.Errorf("i=%d: struct layout mismatch of %v; expected %+v, got %+v", i, g.in, g.want, got)
+		}
+	}
+}
+
+func TestFieldAt(t *testing.T) {
+	l := &StructLayout{Offsets: []int64{0, 4, 4, 8}, Size: 12, Align: 4}
+	golden := []struct {
+		offset int64
+		want   int
+	}{
+		{offset: -1, want: -1},
+		{offset: 0, want: 0},
+		{offset: 3, want: 0},
+		{offset: 4, want: 2},
+		{offset: 11, want: 3},
+		{offset: 12, want: -1},
+	}
+	for _, g := range golden {
+		if got := l.FieldAt(g.offset); g.want != got {
+			t.Errorf("field index mismatch of offset %d; expected %d, got %d", g.offset, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Package datalayout implements target data layouts of LLVM IR modules.
+//
+// A data layout specifies how data is laid out in memory for a target; e.g.
+// endianness, pointer sizes and the alignment of types.
+//
+// References:
+//    https://llvm.org/docs/LangRef.html#data-layout
+package datalayout
+
+import (
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/pkg/errors"
+)
+
+// --- [ Target triples ] ------------------------------------------------------
+
+// ForTriple returns the canonical data layout string used by Clang for the
+// given target triple (e.g. "x86_64-unknown-linux-gnu").
+//
+// The supported architectures are x86 (i386, i486, i586, i686), x86_64, ARM
+// (arm, armv7, thumbv7), AArch64 (aarch64, arm64), RISC-V (riscv32, riscv64),
+// PowerPC (powerpc64, powerpc64le), SystemZ (s390x), WebAssembly (wasm32,
+// wasm64) and NVPTX (nvptx, nvptx64). The object file format (ELF, Mach-O or
+// COFF), which determines the name mangling of some architectures, is derived
+// from the operating system and environment of the triple.
+func ForTriple(triple string) (string, error) {
+	parts := strings.Split(triple, "-")
+	arch := parts[0]
+	format := objectFormat(parts[1:])
+	switch arch {
+	case "x86_64", "amd64":
+		return "e-" + mangling(format, "m:w") + "-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128", nil
+	case "i386", "i486", "i586", "i686":
+		switch format {
+		case formatMachO:
+			return "e-m:o-p:32:32-p270:32:32-p271:32:32-p272:64:64-f64:32:64-f80:128-n8:16:32-S128", nil
+		case formatCOFF:
+			return "e-m:x-p:32:32-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:32-n8:16:32-a:0:32-S32", nil
+		}
+		return "e-m:e-p:32:32-p270:32:32-p271:32:32-p272:64:64-f64:32:64-f80:32-n8:16:32-S128", nil
+	case "aarch64", "arm64":
+		switch format {
+		case formatMachO:
+			return "e-m:o-i64:64-i128:128-n32:64-S128", nil
+		case formatCOFF:
+			return "e-m:w-p:64:64-i32:32-i64:64-i128:128-n32:64-S128", nil
+		}
+		return "e-m:e-i8:8:32-i16:16:32-i64:64-i128:128-n32:64-S128", nil
+	case "arm", "armv7", "armv7a", "thumbv7", "thumbv7a":
+		return "e-" + mangling(format, "m:w") + "-p:32:32-Fi8-i64:64-v128:64:128-a:0:32-n32-S64", nil
+	case "riscv32":
+		return "e-m:e-p:32:32-i64:64-n32-S128", nil
+	case "riscv64":
+		return "e-m:e-p:64:64-i64:64-i128:128-n64-S128", nil
+	case "powerpc64":
+		return "E-m:e-i64:64-n32:64-S128-v256:256:256-v512:512:512", nil
+	case "powerpc64le":
+		return "e-m:e-i64:64-n32:64-S128-v256:256:256-v512:512:512", nil
+	case "s390x", "systemz":
+		return "E-m:e-i1:8:16-i8:8:16-i64:64-f128:64-v128:64-a:8:16-n32:64", nil
+	case "wasm32":
+		return "e-m:e-p:32:32-p10:8:8-p20:8:8-i64:64-n32:64-S128-ni:1:10:20", nil
+	case "wasm64":
+		return "e-m:e-p:64:64-p10:8:8-p20:8:8-i64:64-n32:64-S128-ni:1:10:20", nil
+	case "nvptx":
+		return "e-p:32:32-i64:64-i128:128-v16:16-v32:32-n16:32:64", nil
+	case "nvptx64":
+		return "e-i64:64-i128:128-v16:16-v32:32-n16:32:64", nil
+	}
+	return "", errors.Errorf("unsupported architecture %q of target triple %q", arch, triple)
+}
+
+// SetTarget sets the target triple and corresponding data layout of the given
+// module.
+func SetTarget(m *ir.Module, triple string) error {
+	layout, err := ForTriple(triple)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	m.TargetTriple = triple
+	m.DataLayout = layout
+	return nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// Object file formats.
+const (
+	formatELF = iota
+	formatMachO
+	formatCOFF
+)
+
+// objectFormat returns the object file format of the given vendor, operating
+// system and environment components of a target triple.
+func objectFormat(components []string) int {
+	for _, c := range components {
+		switch {
+		case c == "apple", strings.HasPrefix(c, "darwin"), strings.HasPrefix(c, "macos"), strings.HasPrefix(c, "ios"), strings.HasPrefix(c, "tvos"), strings.HasPrefix(c, "watchos"):
+			return formatMachO
+		case c == "msvc", c == "cygnus", strings.HasPrefix(c, "windows"), strings.HasPrefix(c, "win32"), strings.HasPrefix(c, "mingw"):
+			return formatCOFF
+		}
+	}
+	return formatELF
+}
+
+// mangling returns the name mangling specification of the given object file
+// format, using coff as the specification for COFF.
+func mangling(format int, coff string) string {
+	switch format {
+	case formatMachO:
+		return "m:o"
+	case formatCOFF:
+		return coff
+	}
+	return "m:e"
+}
@@ -0,0 +1,53 @@
+package datalayout
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+)
+
+func TestForTriple(t *testing.T) {
+	golden := []struct {
+		triple string
+		want   string
+	}{
+		// i=0
+		{triple: "x86_64-unknown-linux-gnu", want: "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"},
+		// i=1
+		{triple: "x86_64-apple-macosx10.15.0", want: "e-m:o-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"},
+		// i=2
+		{triple: "x86_64-pc-windows-msvc", want: "e-m:w-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"},
+		// i=3
+		{triple: "i686-pc-windows-msvc", want: "e-m:x-p:32:32-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:32-n8:16:32-a:0:32-S32"},
+		// i=4
+		{triple: "arm64-apple-ios14.0.0", want: "e-m:o-i64:64-i128:128-n32:64-S128"},
+		// i=5
+		{triple: "aarch64-unknown-linux-gnu", want: "e-m:e-i8:8:32-i16:16:32-i64:64-i128:128-n32:64-S128"},
+		// i=6
+		{triple: "wasm32-unknown-unknown", want: "e-m:e-p:32:32-p10:8:8-p20:8:8-i64:64-n32:64-S128-ni:1:10:20"},
+	}
+	for i, g := range golden {
+		got, err := ForTriple(g.triple)
+		if err != nil {
+			t.Errorf("i=%d: unable to get data layout of %q; %v", i, g.triple, err)
+			continue
+		}
+		if g.want != got {
+			t.Errorf("i=%d: data layout mismatch of %q; expected %q, got %q", i, g.triple, g.want, got)
+		}
+	}
+	if _, err := ForTriple("m68k-unknown-linux-gnu"); err == nil {
+		t.Errorf("expected error for unsupported architecture")
+	}
+}
+
+func TestSetTarget(t *testing.T) {
+	m := &ir.Module{}
+	if err := SetTarget(m, "riscv64-unknown-linux-gnu"); err != nil {
+		t.Fatal(err)
+	}
+	want := "target datalayout = \"e-m:e-p:64:64-i64:64-i128:128-n64-S128\"\ntarget triple = \"riscv64-unknown-linux-gnu\"\n"
+	if got := m.Def(); want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
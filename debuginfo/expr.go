@@ -0,0 +1,265 @@
+package debuginfo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/l/internal/enc"
+)
+
+// === [ DWARF expressions ] ===================================================
+
+// Op is a DWARF expression operation of a DIExpression.
+type Op uint64
+
+// DWARF expression operations, with their encodings of the DWARF standard; and
+// LLVM specific operations in the range reserved by LLVM.
+const (
+	OpAddr              Op = 0x03   // DW_OP_addr
+	OpDeref             Op = 0x06   // DW_OP_deref
+	OpConstu            Op = 0x10   // DW_OP_constu
+	OpConsts            Op = 0x11   // DW_OP_consts
+	OpDup               Op = 0x12   // DW_OP_dup
+	OpDrop              Op = 0x13   // DW_OP_drop
+	OpOver              Op = 0x14   // DW_OP_over
+	OpPick              Op = 0x15   // DW_OP_pick
+	OpSwap              Op = 0x16   // DW_OP_swap
+	OpXDeref            Op = 0x18   // DW_OP_xderef
+	OpAnd               Op = 0x1A   // DW_OP_and
+	OpDiv               Op = 0x1B   // DW_OP_div
+	OpMinus             Op = 0x1C   // DW_OP_minus
+	OpMod               Op = 0x1D   // DW_OP_mod
+	OpMul               Op = 0x1E   // DW_OP_mul
+	OpNeg               Op = 0x1F   // DW_OP_neg
+	OpNot               Op = 0x20   // DW_OP_not
+	OpOr                Op = 0x21   // DW_OP_or
+	OpPlus              Op = 0x22   // DW_OP_plus
+	OpPlusUConst        Op = 0x23   // DW_OP_plus_uconst
+	OpShl               Op = 0x24   // DW_OP_shl
+	OpShr               Op = 0x25   // DW_OP_shr
+	OpShra              Op = 0x26   // DW_OP_shra
+	OpXor               Op = 0x27   // DW_OP_xor
+	OpEq                Op = 0x29   // DW_OP_eq
+	OpGe                Op = 0x2A   // DW_OP_ge
+	OpGt                Op = 0x2B   // DW_OP_gt
+	OpLe                Op = 0x2C   // DW_OP_le
+	OpLt                Op = 0x2D   // DW_OP_lt
+	OpNe                Op = 0x2E   // DW_OP_ne
+	OpLit0              Op = 0x30   // DW_OP_lit0
+	OpReg0              Op = 0x50   // DW_OP_reg0
+	OpBreg0             Op = 0x70   // DW_OP_breg0
+	OpRegx              Op = 0x90   // DW_OP_regx
+	OpBregx             Op = 0x92   // DW_OP_bregx
+	OpPiece             Op = 0x93   // DW_OP_piece
+	OpDerefSize         Op = 0x94   // DW_OP_deref_size
+	OpPushObjectAddress Op = 0x97   // DW_OP_push_object_address
+	OpStackValue        Op = 0x9F   // DW_OP_stack_value
+	OpEntryValue        Op = 0xA3   // DW_OP_entry_value
+	OpConvert           Op = 0xA8   // DW_OP_convert
+	OpLLVMFragment      Op = 0x1000 // DW_OP_LLVM_fragment
+	OpLLVMConvert       Op = 0x1001 // DW_OP_LLVM_convert
+	OpLLVMTagOffset     Op = 0x1002 // DW_OP_LLVM_tag_offset
+	OpLLVMEntryValue    Op = 0x1003 // DW_OP_LLVM_entry_value
+)
+
+// opNames maps from DWARF expression operations to their names.
+var opNames = map[Op]string{
+	OpAddr:              "DW_OP_addr",
+	OpDeref:             "DW_OP_deref",
+	OpConstu:            "DW_OP_constu",
+	OpConsts:            "DW_OP_consts",
+	OpDup:               "DW_OP_dup",
+	OpDrop:              "DW_OP_drop",
+	OpOver:              "DW_OP_over",
+	OpPick:              "DW_OP_pick",
+	OpSwap:              "DW_OP_swap",
+	OpXDeref:            "DW_OP_xderef",
+	OpAnd:               "DW_OP_and",
+	OpDiv:               "DW_OP_div",
+	OpMinus:             "DW_OP_minus",
+	OpMod:               "DW_OP_mod",
+	OpMul:               "DW_OP_mul",
+	OpNeg:               "DW_OP_neg",
+	OpNot:               "DW_OP_not",
+	OpOr:                "DW_OP_or",
+	OpPlus:              "DW_OP_plus",
+	OpPlusUConst:        "DW_OP_plus_uconst",
+	OpShl:               "DW_OP_shl",
+	OpShr:               "DW_OP_shr",
+	OpShra:              "DW_OP_shra",
+	OpXor:               "DW_OP_xor",
+	OpEq:                "DW_OP_eq",
+	OpGe:                "DW_OP_ge",
+	OpGt:                "DW_OP_gt",
+	OpLe:                "DW_OP_le",
+	OpLt:                "DW_OP_lt",
+	OpNe:                "DW_OP_ne",
+	OpRegx:              "DW_OP_regx",
+	OpBregx:             "DW_OP_bregx",
+	OpPiece:             "DW_OP_piece",
+	OpDerefSize:         "DW_OP_deref_size",
+	OpPushObjectAddress: "DW_OP_push_object_address",
+	OpStackValue:        "DW_OP_stack_value",
+	OpEntryValue:        "DW_OP_entry_value",
+	OpConvert:           "DW_OP_convert",
+	OpLLVMFragment:      "DW_OP_LLVM_fragment",
+	OpLLVMConvert:       "DW_OP_LLVM_convert",
+	OpLLVMTagOffset:     "DW_OP_LLVM_tag_offset",
+	OpLLVMEntryValue:    "DW_OP_LLVM_entry_value",
+}
+
+// String returns the name of the DWARF expression operation (e.g.
+// "DW_OP_deref").
+func (op Op) String() string {
+	switch {
+	case OpLit0 <= op && op <= OpLit0+31:
+		return fmt.Sprintf("DW_OP_lit%d", op-OpLit0)
+	case OpReg0 <= op && op <= OpReg0+31:
+		return fmt.Sprintf("DW_OP_reg%d", op-OpReg0)
+	case OpBreg0 <= op && op <= OpBreg0+31:
+		return fmt.Sprintf("DW_OP_breg%d", op-OpBreg0)
+	}
+	if name, ok := opNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%X", uint64(op))
+}
+
+// ExprOp is an operation of a DWARF expression with its operands (e.g.
+// `DW_OP_plus_uconst, 8`).
+type ExprOp struct {
+	// DWARF expression operation.
+	Op Op
+	// Operands of the operation.
+	Args []uint64
+}
+
+// --- [ DIExpression ] --------------------------------------------------------
+
+// DIExpression is a DWARF expression metadata node (e.g.
+// `!DIExpression(DW_OP_deref, DW_OP_plus_uconst, 8)`), describing how to
+// compute the value of a source variable from the location of an
+// llvm.dbg.declare or llvm.dbg.value intrinsic call.
+//
+// DWARF expressions are built by chaining the methods of the operations to
+// append; e.g.
+//
+//    NewDIExpression().Deref().PlusUConst(8).Fragment(0, 32)
+type DIExpression struct {
+	// Metadata ID (without '!' prefix); or -1 if the node is printed inline.
+	MetadataID int64
+	// Operations of the DWARF expression, in order of evaluation.
+	Ops []ExprOp
+}
+
+// NewDIExpression returns a new inline DWARF expression based on the given
+// operations.
+func NewDIExpression(ops ...ExprOp) *DIExpression {
+	return &DIExpression{MetadataID: -1, Ops: ops}
+}
+
+// Op appends the given DWARF expression operation with operands to the
+// expression, and returns the expression.
+func (e *DIExpression) Op(op Op, args ...uint64) *DIExpression {
+	e.Ops = append(e.Ops, ExprOp{Op: op, Args: args})
+	return e
+}
+
+// Deref appends a DW_OP_deref operation, which dereferences the address on
+// top of the stack.
+func (e *DIExpression) Deref() *DIExpression {
+	return e.Op(OpDeref)
+}
+
+// PlusUConst appends a DW_OP_plus_uconst operation, which adds the given
+// unsigned constant to the value on top of the stack.
+func (e *DIExpression) PlusUConst(x uint64) *DIExpression {
+	return e.Op(OpPlusUConst, x)
+}
+
+// Constu appends a DW_OP_constu operation, which pushes the given unsigned
+// constant.
+func (e *DIExpression) Constu(x uint64) *DIExpression {
+	return e.Op(OpConstu, x)
+}
+
+// Plus appends a DW_OP_plus operation.
+func (e *DIExpression) Plus() *DIExpression {
+	return e.Op(OpPlus)
+}
+
+// Minus appends a DW_OP_minus operation.
+func (e *DIExpression) Minus() *DIExpression {
+	return e.Op(OpMinus)
+}
+
+// StackValue appends a DW_OP_stack_value operation, which marks the value on
+// top of the stack as the value of the variable rather than its location.
+func (e *DIExpression) StackValue() *DIExpression {
+	return e.Op(OpStackValue)
+}
+
+// Fragment appends a DW_OP_LLVM_fragment operation, which marks the
+// expression as describing the fragment of the variable at the given offset
+// and size in bits. A fragment is the last operation of an expression.
+func (e *DIExpression) Fragment(offset, size uint64) *DIExpression {
+	return e.Op(OpLLVMFragment, offset, size)
+}
+
+// String returns the LLVM syntax representation of the DWARF expression, as
+// used when referenced; the metadata ID if present, and the inline node
+// otherwise.
+func (e *DIExpression) String() string {
+	if e.MetadataID >= 0 {
+		return enc.MetadataID(e.MetadataID)
+	}
+	return e.Def()
+}
+
+// Def returns the LLVM syntax representation of the DWARF expression
+// definition.
+func (e *DIExpression) Def() string {
+	// "!DIExpression" "(" DIExpressionFields ")"
+	buf := &strings.Builder{}
+	buf.WriteString("!DIExpression(")
+	for i, op := range e.Ops {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(op.Op.String())
+		for _, arg := range op.Args {
+			fmt.Fprintf(buf, ", %d", arg)
+		}
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
+// IsMetadata ensures that only metadata can be assigned to the ir.Metadata
+// interface.
+func (*DIExpression) IsMetadata() {}
+
+// ID returns the metadata ID of the DWARF expression; or -1 if printed inline.
+func (e *DIExpression) ID() int64 {
+	return e.MetadataID
+}
+
+// SetID sets the metadata ID of the DWARF expression.
+func (e *DIExpression) SetID(id int64) {
+	e.MetadataID = id
+}
+
+// FragmentInfo returns the bit offset and size of the variable fragment described
+// by the DWARF expression, as recorded by its trailing DW_OP_LLVM_fragment
+// operation. The boolean return value indicates whether the expression
+// describes a fragment.
+func (e *DIExpression) FragmentInfo() (offset, size uint64, ok bool) {
+	if len(e.Ops) == 0 {
+		return 0, 0, false
+	}
+	last := e.Ops[len(e.Ops)-1]
+	if last.Op != OpLLVMFragment || len(last.Args) != 2 {
+		return 0, 0, false
+	}
+	return last.Args[0], last.Args[1], true
+}
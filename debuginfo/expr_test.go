@@ -0,0 +1,57 @@
+package debuginfo
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestDIExpression(t *testing.T) {
+	golden := []struct {
+		in   *DIExpression
+		want string
+	}{
+		// i=0
+		{in: NewDIExpression(), want: "!DIExpression()"},
+		// i=1
+		{
+			in:   NewDIExpression().Deref().PlusUConst(8).Fragment(0, 32),
+			want: "!DIExpression(DW_OP_deref, DW_OP_plus_uconst, 8, DW_OP_LLVM_fragment, 0, 32)",
+		},
+		// i=2
+		{
+			in:   NewDIExpression().Constu(3).Op(OpLit0 + 2).Op(OpMul).StackValue(),
+			want: "!DIExpression(DW_OP_constu, 3, DW_OP_lit2, DW_OP_mul, DW_OP_stack_value)",
+		},
+	}
+	for i, g := range golden {
+		if got := g.in.String(); got != g.want {
+			t.Errorf("i=%d: expression mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+	}
+	e := NewDIExpression().Deref().Fragment(32, 16)
+	if offset, size, ok := e.FragmentInfo(); !ok || offset != 32 || size != 16 {
+		t.Errorf("fragment mismatch; expected (32, 16, true), got (%d, %d, %v)", offset, size, ok)
+	}
+	if _, _, ok := NewDIExpression().Deref().FragmentInfo(); ok {
+		t.Errorf("unexpected fragment of expression without DW_OP_LLVM_fragment")
+	}
+}
+
+func TestDIExpressionDef(t *testing.T) {
+	m := &ir.Module{}
+	f := m.NewFunc("f", types.Void)
+	f.NewBlock("").NewRet(nil)
+	e := NewDIExpression().Deref()
+	m.NewMetadataDef(e)
+	ir.SetMetadata(f, ir.NewMetadataAttachment(ir.RegisterMDKind("expr"), e))
+	want := `define void @f() !expr !0 {
+	ret void
+}
+!0 = !DIExpression(DW_OP_deref)
+`
+	if got := m.Def(); want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
@@ -0,0 +1,172 @@
+// Package debuginfo implements generation of DWARF debug information metadata
+// for LLVM IR modules.
+package debuginfo
+
+import (
+	"go/token"
+	"path/filepath"
+
+	"github.com/llir/l/ir"
+)
+
+// Version of the debug information metadata format, as recorded by the "Debug
+// Info Version" module flag.
+const Version = 3
+
+// === [ Line tables ] =========================================================
+
+// LineTable generates line-table debug information for the functions of a
+// module, mapping instructions to source positions.
+//
+// Source positions are given as go/token.Position values; for languages other
+// than Go, a token.Position may be constructed from any file, line and column
+// triple. Positions with a zero line are treated as unknown.
+//
+// Instructions may be mapped to source positions explicitly, using SetPos, or
+// implicitly, by setting the current source position of a function using At
+// before emitting the instructions of the source construct at that position.
+type LineTable struct {
+	// Module of the line table.
+	m *ir.Module
+	// Compile unit of the line table (DICompileUnit).
+	cu *ir.MDSpecialized
+	// File metadata nodes (DIFile); filename -> node.
+	files map[string]*ir.MDSpecialized
+	// Subprogram metadata nodes (DISubprogram); function -> node.
+	subprograms map[*ir.Function]*ir.MDSpecialized
+	// Location metadata nodes (DILocation); location -> node.
+	locs map[location]*ir.MDSpecialized
+	// Current source position of functions, as set by At.
+	cur map[*ir.Function]token.Position
+	// Instructions and terminators which have been mapped to source positions.
+	done map[interface{}]bool
+	// Functions in order of their first current source position.
+	funcs []*ir.Function
+}
+
+// location is a source location within a scope.
+type location struct {
+	line, column int
+	scope        *ir.MDSpecialized
+}
+
+// NewLineTable returns a new line table for the given module, with a compile
+// unit of the given source language (e.g. "DW_LANG_Go" or "DW_LANG_C99"),
+// primary source file and producer (e.g. name and version of the compiler).
+//
+// The compile unit is added to the !llvm.dbg.cu named metadata of the module,
+// and the "Debug Info Version" module flag is set.
+func NewLineTable(m *ir.Module, lang ir.MDEnum, filename, producer string) *LineTable {
+	lt := &LineTable{
+		m:           m,
+		files:       make(map[string]*ir.MDSpecialized),
+		subprograms: make(map[*ir.Function]*ir.MDSpecialized),
+		locs:        make(map[location]*ir.MDSpecialized),
+		cur:         make(map[*ir.Function]token.Position),
+		done:        make(map[interface{}]bool),
+	}
+	lt.cu = NewCompileUnit(m, lang, lt.File(filename), producer, EmissionLineTablesOnly)
+	return lt
+}
+
+// CompileUnit returns the compile unit of the line table (DICompileUnit).
+func (lt *LineTable) CompileUnit() *ir.MDSpecialized {
+	return lt.cu
+}
+
+// File returns the file metadata node (DIFile) of the given source file,
+// creating it if not yet present.
+func (lt *LineTable) File(filename string) *ir.MDSpecialized {
+	if file, ok := lt.files[filename]; ok {
+		return file
+	}
+	dir, base := filepath.Split(filename)
+	dir = filepath.Clean(dir)
+	if len(base) == 0 || dir == "." {
+		dir, base = "", filename
+	}
+	file := ir.NewSpecialized("DIFile",
+		ir.NewMDField("filename", base),
+		ir.NewMDField("directory", dir),
+	)
+	lt.m.NewMetadataDef(file)
+	lt.files[filename] = file
+	return file
+}
+
+// Func returns the subprogram metadata node (DISubprogram) of the given
+// function definition, creating it if not yet present, with the source position
+// of the function. The subprogram is attached to the function as !dbg.
+func (lt *LineTable) Func(f *ir.Function, pos token.Position) *ir.MDSpecialized {
+	if sp, ok := lt.subprograms[f]; ok {
+		return sp
+	}
+	file := lt.File(pos.Filename)
+	sp := NewSubprogram(f.Name(), file, file, int64(pos.Line), ir.NewSpecialized("DISubroutineType", ir.NewMDField("types", ir.NewTuple())), lt.cu)
+	AttachSubprogram(lt.m, f, sp)
+	lt.subprograms[f] = sp
+	return sp
+}
+
+// SetPos maps the given instruction or terminator of the function to the given
+// source position, by attaching a location metadata node (DILocation) as !dbg.
+// The subprogram of the function is created with the given source position if
+// not yet present. Unknown positions are ignored.
+func (lt *LineTable) SetPos(f *ir.Function, inst interface{}, pos token.Position) {
+	lt.done[inst] = true
+	if pos.Line == 0 {
+		return
+	}
+	sp := lt.Func(f, pos)
+	key := location{line: pos.Line, column: pos.Column, scope: sp}
+	loc, ok := lt.locs[key]
+	if !ok {
+		loc = ir.NewSpecialized("DILocation",
+			ir.NewMDField("line", int64(pos.Line)),
+			ir.NewMDField("column", int64(pos.Column)),
+			ir.NewMDField("scope", sp),
+		)
+		lt.m.NewMetadataDef(loc)
+		lt.locs[key] = loc
+	}
+	ir.SetMetadata(inst, ir.NewMetadataAttachment(ir.MDKindDbg, loc))
+}
+
+// At sets the current source position of the given function. Instructions and
+// terminators emitted to the function since the previous call to At are mapped
+// to the previous current source position, unless already mapped by SetPos.
+func (lt *LineTable) At(f *ir.Function, pos token.Position) {
+	if _, ok := lt.cur[f]; ok {
+		lt.flush(f)
+	} else {
+		lt.funcs = append(lt.funcs, f)
+	}
+	lt.cur[f] = pos
+}
+
+// Finish maps the instructions and terminators emitted to functions since the
+// last call to At to the current source position of their function.
+func (lt *LineTable) Finish() {
+	for _, f := range lt.funcs {
+		lt.flush(f)
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// flush maps the unmapped instructions and terminators of the given function
+// to the current source position of the function. Instructions without support
+// for metadata attachments (e.g. custom instructions) are skipped.
+func (lt *LineTable) flush(f *ir.Function) {
+	pos := lt.cur[f]
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if _, ok := ir.MetadataAttachments(inst); ok && !lt.done[inst] {
+				lt.SetPos(f, inst, pos)
+			}
+		}
+		if block.Term != nil && !lt.done[block.Term] {
+			lt.SetPos(f, block.Term, pos)
+		}
+	}
+}
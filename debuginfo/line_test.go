@@ -0,0 +1,46 @@
+package debuginfo
+
+import (
+	"go/token"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestLineTable(t *testing.T) {
+	m := &ir.Module{}
+	x := ir.NewParam(types.I32, "x")
+	f := m.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	lt := NewLineTable(m, "DW_LANG_Go", "/src/foo.go", "llir")
+	lt.At(f, token.Position{Filename: "/src/foo.go", Line: 3, Column: 1})
+	y := entry.NewAdd(x, x)
+	lt.At(f, token.Position{Filename: "/src/foo.go", Line: 4, Column: 9})
+	z := entry.NewMul(y, y)
+	ret := entry.NewRet(z)
+	// Explicit source positions take precedence over the current position.
+	lt.SetPos(f, ret, token.Position{Filename: "/src/foo.go", Line: 3, Column: 1})
+	lt.Finish()
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %+v", err)
+	}
+	want := `define i32 @f(i32 %x) !dbg !3 {
+entry:
+	%0 = add i32 %x, %x, !dbg !4
+	%1 = mul i32 %0, %0, !dbg !5
+	ret i32 %1, !dbg !4
+}
+!llvm.dbg.cu = !{!1}
+!llvm.module.flags = !{!2}
+!0 = !DIFile(filename: "foo.go", directory: "/src")
+!1 = distinct !DICompileUnit(language: DW_LANG_Go, file: !0, producer: "llir", isOptimized: false, runtimeVersion: 0, emissionKind: LineTablesOnly)
+!2 = !{i32 2, !"Debug Info Version", i32 3}
+!3 = distinct !DISubprogram(name: "f", scope: !0, file: !0, line: 3, type: !DISubroutineType(types: !{}), scopeLine: 3, spFlags: DISPFlagDefinition, unit: !1)
+!4 = !DILocation(line: 3, column: 1, scope: !3)
+!5 = !DILocation(line: 4, column: 9, scope: !3)
+`
+	if got := m.Def(); want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
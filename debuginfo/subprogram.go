@@ -0,0 +1,154 @@
+package debuginfo
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+// === [ Compile units and subprograms ] =======================================
+
+// Emission kinds of compile units.
+const (
+	EmissionFullDebug      ir.MDEnum = "FullDebug"
+	EmissionLineTablesOnly ir.MDEnum = "LineTablesOnly"
+	EmissionNoDebug        ir.MDEnum = "NoDebug"
+)
+
+// --- [ Compile units ] -------------------------------------------------------
+
+// NewCompileUnit returns a new distinct compile unit metadata node
+// (DICompileUnit) of the given module, based on the given source language
+// (e.g. "DW_LANG_C99"), primary source file (DIFile), producer (e.g. name and
+// version of the compiler) and emission kind (e.g. EmissionFullDebug).
+//
+// The compile unit is defined in the module and added to the !llvm.dbg.cu
+// named metadata of the module, and the "Debug Info Version" module flag is
+// set if not yet present.
+func NewCompileUnit(m *ir.Module, lang ir.MDEnum, file ir.Metadata, producer string, emissionKind ir.MDEnum) *ir.MDSpecialized {
+	cu := ir.NewSpecialized("DICompileUnit",
+		ir.NewMDField("language", lang),
+		ir.NewMDField("file", file),
+		ir.NewMDField("producer", producer),
+		ir.NewMDField("isOptimized", false),
+		ir.NewMDField("runtimeVersion", int64(0)),
+		ir.NewMDField("emissionKind", emissionKind),
+	)
+	cu.Distinct = true
+	m.NewMetadataDef(cu)
+	registerCompileUnit(m, cu)
+	if !hasVersionFlag(m) {
+		// Module flag behaviour 2 (Warning) of LLVM.
+		flag := ir.NewTuple(ir.NewMDValue(ir.NewInt(types.I32, 2)), ir.NewMDString("Debug Info Version"), ir.NewMDValue(ir.NewInt(types.I32, Version)))
+		m.NewMetadataDef(flag)
+		flags := m.NamedMetadata("llvm.module.flags")
+		flags.Nodes = append(flags.Nodes, flag)
+	}
+	return cu
+}
+
+// --- [ Subprograms ] ---------------------------------------------------------
+
+// NewSubprogram returns a new distinct subprogram definition metadata node
+// (DISubprogram) based on the given name, scope (e.g. DIFile), file, line of
+// definition, subroutine type (DISubroutineType) and compile unit.
+//
+// Use AttachSubprogram to attach the subprogram to its function definition,
+// and AddRetainedNode to add retained nodes (e.g. local variables).
+func NewSubprogram(name string, scope, file ir.Metadata, line int64, typ ir.Metadata, unit *ir.MDSpecialized) *ir.MDSpecialized {
+	sp := ir.NewSpecialized("DISubprogram",
+		ir.NewMDField("name", name),
+		ir.NewMDField("scope", scope),
+		ir.NewMDField("file", file),
+		ir.NewMDField("line", line),
+		ir.NewMDField("type", typ),
+		ir.NewMDField("scopeLine", line),
+		ir.NewMDField("spFlags", ir.MDEnum("DISPFlagDefinition")),
+		ir.NewMDField("unit", unit),
+	)
+	sp.Distinct = true
+	return sp
+}
+
+// AttachSubprogram attaches the given subprogram definition to the given
+// function definition of the module, as !dbg on the define line. The
+// subprogram is defined in the module if not yet defined, and its compile unit
+// (as referenced by the unit field) is added to the !llvm.dbg.cu named
+// metadata of the module if not yet present.
+func AttachSubprogram(m *ir.Module, f *ir.Function, sp *ir.MDSpecialized) {
+	if sp.ID() < 0 {
+		m.NewMetadataDef(sp)
+	}
+	if field := sp.Field("unit"); field != nil {
+		if cu, ok := field.Value.(*ir.MDSpecialized); ok {
+			if cu.ID() < 0 {
+				m.NewMetadataDef(cu)
+			}
+			registerCompileUnit(m, cu)
+		}
+	}
+	ir.SetMetadata(f, ir.NewMetadataAttachment(ir.MDKindDbg, sp))
+}
+
+// Subprogram returns the subprogram attached to the given function; or nil if
+// not present.
+func Subprogram(f *ir.Function) *ir.MDSpecialized {
+	sp := f.Metadata.Dbg()
+	if sp == nil || sp.Kind != "DISubprogram" {
+		return nil
+	}
+	return sp
+}
+
+// AddRetainedNode appends the given node (e.g. a local variable) to the
+// retainedNodes of the given subprogram, adding the field if not yet present.
+func AddRetainedNode(sp *ir.MDSpecialized, node ir.Metadata) {
+	if field := sp.Field("retainedNodes"); field != nil {
+		if nodes, ok := field.Value.(*ir.MDTuple); ok {
+			nodes.Fields = append(nodes.Fields, node)
+			return
+		}
+	}
+	// retainedNodes follows the unit field, as printed by LLVM.
+	field := ir.NewMDField("retainedNodes", ir.NewTuple(node))
+	for i, f := range sp.Fields {
+		if f.Name == "unit" {
+			sp.Fields = append(sp.Fields[:i+1], append([]*ir.MDField{field}, sp.Fields[i+1:]...)...)
+			return
+		}
+	}
+	sp.Fields = append(sp.Fields, field)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// registerCompileUnit adds the given compile unit to the !llvm.dbg.cu named
+// metadata of the module if not yet present.
+func registerCompileUnit(m *ir.Module, cu *ir.MDSpecialized) {
+	dbgCU := m.NamedMetadata("llvm.dbg.cu")
+	for _, node := range dbgCU.Nodes {
+		if node == cu {
+			return
+		}
+	}
+	dbgCU.Nodes = append(dbgCU.Nodes, cu)
+}
+
+// hasVersionFlag reports whether the "Debug Info Version" module flag of the
+// module is present.
+func hasVersionFlag(m *ir.Module) bool {
+	for _, md := range m.NamedMetadataDefs {
+		if md.Name != "llvm.module.flags" {
+			continue
+		}
+		for _, node := range md.Nodes {
+			flag, ok := node.(*ir.MDTuple)
+			if !ok || len(flag.Fields) != 3 {
+				continue
+			}
+			if name, ok := flag.Fields[1].(*ir.MDString); ok && name.Value == "Debug Info Version" {
+				return true
+			}
+		}
+	}
+	return false
+}
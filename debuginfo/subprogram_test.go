@@ -0,0 +1,51 @@
+package debuginfo
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestAttachSubprogram(t *testing.T) {
+	m := &ir.Module{}
+	f := m.NewFunc("f", types.Void)
+	f.NewBlock("").NewRet(nil)
+	file := ir.NewSpecialized("DIFile", ir.NewMDField("filename", "foo.c"), ir.NewMDField("directory", "/src"))
+	m.NewMetadataDef(file)
+	cu := ir.NewSpecialized("DICompileUnit", ir.NewMDField("language", ir.MDEnum("DW_LANG_C99")), ir.NewMDField("file", file))
+	cu.Distinct = true
+	sp := NewSubprogram("f", file, file, 1, NewSubroutineType(nil), cu)
+	AttachSubprogram(m, f, sp)
+	// Attaching again does not register the compile unit twice.
+	AttachSubprogram(m, f, sp)
+	i32 := NewBasicType("int", 32, 0, EncodingSigned)
+	AddRetainedNode(sp, ir.NewSpecialized("DILocalVariable", ir.NewMDField("name", "x"), ir.NewMDField("scope", sp), ir.NewMDField("type", i32)))
+	if got := Subprogram(f); got != sp {
+		t.Errorf("subprogram mismatch; expected %v, got %v", sp, got)
+	}
+	want := `define void @f() !dbg !1 {
+	ret void
+}
+!llvm.dbg.cu = !{!2}
+!0 = !DIFile(filename: "foo.c", directory: "/src")
+!1 = distinct !DISubprogram(name: "f", scope: !0, file: !0, line: 1, type: !DISubroutineType(types: !{null}), scopeLine: 1, spFlags: DISPFlagDefinition, unit: !2, retainedNodes: !{!DILocalVariable(name: "x", scope: !1, type: !DIBasicType(name: "int", size: 32, encoding: DW_ATE_signed))})
+!2 = distinct !DICompileUnit(language: DW_LANG_C99, file: !0)
+`
+	if got := m.Def(); want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
+
+func TestNewCompileUnit(t *testing.T) {
+	m := &ir.Module{}
+	file := ir.NewSpecialized("DIFile", ir.NewMDField("filename", "foo.c"), ir.NewMDField("directory", "/src"))
+	NewCompileUnit(m, "DW_LANG_C99", file, "llir", EmissionFullDebug)
+	NewCompileUnit(m, "DW_LANG_C99", file, "llir", EmissionFullDebug)
+	if got := len(m.NamedMetadata("llvm.dbg.cu").Nodes); got != 2 {
+		t.Errorf("number of compile units mismatch; expected 2, got %d", got)
+	}
+	if got := len(m.NamedMetadata("llvm.module.flags").Nodes); got != 1 {
+		t.Errorf("number of module flags mismatch; expected 1, got %d", got)
+	}
+}
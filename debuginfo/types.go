@@ -0,0 +1,204 @@
+package debuginfo
+
+import (
+	"github.com/llir/l/ir"
+)
+
+// === [ Type metadata ] =======================================================
+
+// DWARF tags of derived and composite types.
+const (
+	TagPointerType     ir.MDEnum = "DW_TAG_pointer_type"
+	TagReferenceType   ir.MDEnum = "DW_TAG_reference_type"
+	TagConstType       ir.MDEnum = "DW_TAG_const_type"
+	TagVolatileType    ir.MDEnum = "DW_TAG_volatile_type"
+	TagTypedef         ir.MDEnum = "DW_TAG_typedef"
+	TagMember          ir.MDEnum = "DW_TAG_member"
+	TagStructureType   ir.MDEnum = "DW_TAG_structure_type"
+	TagUnionType       ir.MDEnum = "DW_TAG_union_type"
+	TagArrayType       ir.MDEnum = "DW_TAG_array_type"
+	TagEnumerationType ir.MDEnum = "DW_TAG_enumeration_type"
+)
+
+// DWARF encodings of basic types.
+const (
+	EncodingAddress      ir.MDEnum = "DW_ATE_address"
+	EncodingBoolean      ir.MDEnum = "DW_ATE_boolean"
+	EncodingFloat        ir.MDEnum = "DW_ATE_float"
+	EncodingSigned       ir.MDEnum = "DW_ATE_signed"
+	EncodingSignedChar   ir.MDEnum = "DW_ATE_signed_char"
+	EncodingUnsigned     ir.MDEnum = "DW_ATE_unsigned"
+	EncodingUnsignedChar ir.MDEnum = "DW_ATE_unsigned_char"
+	EncodingUTF          ir.MDEnum = "DW_ATE_UTF"
+)
+
+// Fields with zero values (e.g. an empty name or a zero alignment) are omitted
+// by the constructors of type metadata nodes, as they are by LLVM. Sizes,
+// alignments and offsets are in bits.
+
+// --- [ Basic types ] ---------------------------------------------------------
+
+// NewBasicType returns a new basic type metadata node (DIBasicType) based on
+// the given name, size, alignment and encoding (e.g. EncodingSigned).
+func NewBasicType(name string, size, align uint64, encoding ir.MDEnum) *ir.MDSpecialized {
+	return newType("DIBasicType",
+		ir.NewMDField("name", name),
+		ir.NewMDField("size", size),
+		ir.NewMDField("align", align),
+		ir.NewMDField("encoding", encoding),
+	)
+}
+
+// --- [ Derived types ] -------------------------------------------------------
+
+// NewDerivedType returns a new derived type metadata node (DIDerivedType) based
+// on the given tag (e.g. TagPointerType), name, base type, size, alignment and
+// offset. A nil base type denotes void.
+func NewDerivedType(tag ir.MDEnum, name string, baseType ir.Metadata, size, align, offset uint64) *ir.MDSpecialized {
+	return newType("DIDerivedType",
+		ir.NewMDField("tag", tag),
+		ir.NewMDField("name", name),
+		ir.NewMDField("baseType", baseType),
+		ir.NewMDField("size", size),
+		ir.NewMDField("align", align),
+		ir.NewMDField("offset", offset),
+	)
+}
+
+// NewPointerType returns a new pointer type metadata node of the given base
+// type and pointer size. A nil base type denotes void.
+func NewPointerType(baseType ir.Metadata, size uint64) *ir.MDSpecialized {
+	return NewDerivedType(TagPointerType, "", baseType, size, 0, 0)
+}
+
+// NewTypedef returns a new typedef metadata node of the given name and base
+// type.
+func NewTypedef(name string, baseType ir.Metadata) *ir.MDSpecialized {
+	return NewDerivedType(TagTypedef, name, baseType, 0, 0, 0)
+}
+
+// NewMember returns a new member metadata node of a structure or union type,
+// based on the given name, base type, size, alignment and offset.
+func NewMember(name string, baseType ir.Metadata, size, align, offset uint64) *ir.MDSpecialized {
+	return NewDerivedType(TagMember, name, baseType, size, align, offset)
+}
+
+// --- [ Composite types ] -----------------------------------------------------
+
+// NewCompositeType returns a new composite type metadata node
+// (DICompositeType) based on the given tag (e.g. TagStructureType), name, base
+// type, size, alignment and elements; e.g. the members of a structure type.
+func NewCompositeType(tag ir.MDEnum, name string, baseType ir.Metadata, size, align uint64, elems ...ir.Metadata) *ir.MDSpecialized {
+	return newType("DICompositeType",
+		ir.NewMDField("tag", tag),
+		ir.NewMDField("name", name),
+		ir.NewMDField("baseType", baseType),
+		ir.NewMDField("size", size),
+		ir.NewMDField("align", align),
+		ir.NewMDField("elements", ir.NewTuple(elems...)),
+	)
+}
+
+// NewStructType returns a new structure type metadata node based on the given
+// name, size, alignment and members (e.g. by NewMember).
+func NewStructType(name string, size, align uint64, members ...ir.Metadata) *ir.MDSpecialized {
+	return NewCompositeType(TagStructureType, name, nil, size, align, members...)
+}
+
+// NewArrayType returns a new array type metadata node based on the given
+// element type, size and alignment, with a subrange per dimension of the given
+// element counts.
+func NewArrayType(elemType ir.Metadata, size, align uint64, counts ...int64) *ir.MDSpecialized {
+	var subranges []ir.Metadata
+	for _, count := range counts {
+		subranges = append(subranges, NewSubrange(count))
+	}
+	return NewCompositeType(TagArrayType, "", elemType, size, align, subranges...)
+}
+
+// NewEnumType returns a new enumeration type metadata node based on the given
+// name, underlying base type, size, alignment and enumerators (e.g. by
+// NewEnumerator).
+func NewEnumType(name string, baseType ir.Metadata, size, align uint64, enumerators ...ir.Metadata) *ir.MDSpecialized {
+	return NewCompositeType(TagEnumerationType, name, baseType, size, align, enumerators...)
+}
+
+// NewSubrange returns a new subrange metadata node (DISubrange) of an array
+// dimension with the given element count.
+func NewSubrange(count int64) *ir.MDSpecialized {
+	return ir.NewSpecialized("DISubrange", ir.NewMDField("count", count))
+}
+
+// NewEnumerator returns a new enumerator metadata node (DIEnumerator) based on
+// the given name and value.
+func NewEnumerator(name string, value int64) *ir.MDSpecialized {
+	return ir.NewSpecialized("DIEnumerator",
+		ir.NewMDField("name", name),
+		ir.NewMDField("value", value),
+	)
+}
+
+// --- [ Subroutine types ] ----------------------------------------------------
+
+// NewSubroutineType returns a new subroutine type metadata node
+// (DISubroutineType) based on the given return type and parameter types. A nil
+// return type denotes void.
+func NewSubroutineType(retType ir.Metadata, params ...ir.Metadata) *ir.MDSpecialized {
+	types := append([]ir.Metadata{retType}, params...)
+	return ir.NewSpecialized("DISubroutineType", ir.NewMDField("types", ir.NewTuple(types...)))
+}
+
+// ~~~ [ Source locations ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SetDecl sets the scope (e.g. a DIFile or enclosing composite type), file and
+// line of declaration of the given derived or composite type metadata node;
+// nil scopes and files are omitted. The fields are placed after the name of the type, as printed by LLVM.
+func SetDecl(node *ir.MDSpecialized, scope, file ir.Metadata, line int64) {
+	var fields []*ir.MDField
+	if scope != nil {
+		fields = append(fields, ir.NewMDField("scope", scope))
+	}
+	if file != nil {
+		fields = append(fields, ir.NewMDField("file", file))
+	}
+	fields = append(fields, ir.NewMDField("line", line))
+	// Insert after tag and name fields.
+	i := 0
+	for i < len(node.Fields) && (node.Fields[i].Name == "tag" || node.Fields[i].Name == "name") {
+		i++
+	}
+	var rest []*ir.MDField
+	for _, field := range node.Fields[i:] {
+		switch field.Name {
+		case "scope", "file", "line":
+			// Replace existing declaration fields.
+		default:
+			rest = append(rest, field)
+		}
+	}
+	node.Fields = append(append(node.Fields[:i:i], fields...), rest...)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// newType returns a new specialized metadata node of the given kind, with the
+// given fields omitting zero values.
+func newType(kind string, fields ...*ir.MDField) *ir.MDSpecialized {
+	node := ir.NewSpecialized(kind)
+	for _, field := range fields {
+		switch v := field.Value.(type) {
+		case nil:
+			continue
+		case string:
+			if len(v) == 0 {
+				continue
+			}
+		case uint64:
+			if v == 0 {
+				continue
+			}
+		}
+		node.Fields = append(node.Fields, field)
+	}
+	return node
+}
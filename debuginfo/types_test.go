@@ -0,0 +1,52 @@
+package debuginfo
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+)
+
+func TestTypes(t *testing.T) {
+	file := ir.NewSpecialized("DIFile", ir.NewMDField("filename", "foo.c"), ir.NewMDField("directory", "/src"))
+	i32 := NewBasicType("int", 32, 0, EncodingSigned)
+	char := NewBasicType("char", 8, 8, EncodingSignedChar)
+	x := NewMember("x", i32, 32, 0, 0)
+	SetDecl(x, nil, file, 2)
+	s := NewStructType("S", 64, 32, x, NewMember("c", char, 8, 0, 32))
+	SetDecl(s, file, file, 1)
+	golden := []struct {
+		in   ir.Metadata
+		want string
+	}{
+		// i=0
+		{in: i32, want: `!DIBasicType(name: "int", size: 32, encoding: DW_ATE_signed)`},
+		// i=1
+		{in: char, want: `!DIBasicType(name: "char", size: 8, align: 8, encoding: DW_ATE_signed_char)`},
+		// i=2
+		{in: NewPointerType(nil, 64), want: `!DIDerivedType(tag: DW_TAG_pointer_type, size: 64)`},
+		// i=3
+		{in: NewTypedef("myint", i32), want: `!DIDerivedType(tag: DW_TAG_typedef, name: "myint", baseType: !DIBasicType(name: "int", size: 32, encoding: DW_ATE_signed))`},
+		// i=4
+		{
+			in:   s,
+			want: `!DICompositeType(tag: DW_TAG_structure_type, name: "S", scope: !DIFile(filename: "foo.c", directory: "/src"), file: !DIFile(filename: "foo.c", directory: "/src"), line: 1, size: 64, align: 32, elements: !{!DIDerivedType(tag: DW_TAG_member, name: "x", file: !DIFile(filename: "foo.c", directory: "/src"), line: 2, baseType: !DIBasicType(name: "int", size: 32, encoding: DW_ATE_signed), size: 32), !DIDerivedType(tag: DW_TAG_member, name: "c", baseType: !DIBasicType(name: "char", size: 8, align: 8, encoding: DW_ATE_signed_char), size: 8, offset: 32)})`,
+		},
+		// i=5
+		{
+			in:   NewArrayType(i32, 192, 0, 2, 3),
+			want: `!DICompositeType(tag: DW_TAG_array_type, baseType: !DIBasicType(name: "int", size: 32, encoding: DW_ATE_signed), size: 192, elements: !{!DISubrange(count: 2), !DISubrange(count: 3)})`,
+		},
+		// i=6
+		{
+			in:   NewEnumType("E", i32, 32, 0, NewEnumerator("A", 0), NewEnumerator("B", -1)),
+			want: `!DICompositeType(tag: DW_TAG_enumeration_type, name: "E", baseType: !DIBasicType(name: "int", size: 32, encoding: DW_ATE_signed), size: 32, elements: !{!DIEnumerator(name: "A", value: 0), !DIEnumerator(name: "B", value: -1)})`,
+		},
+		// i=7
+		{in: NewSubroutineType(nil, NewPointerType(char, 64)), want: `!DISubroutineType(types: !{null, !DIDerivedType(tag: DW_TAG_pointer_type, baseType: !DIBasicType(name: "char", size: 8, align: 8, encoding: DW_ATE_signed_char), size: 64)})`},
+	}
+	for i, g := range golden {
+		if got := g.in.String(); got != g.want {
+			t.Errorf("i=%d: metadata mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+// Package equiv implements checking of LLVM IR functions for behavioural
+// equivalence; e.g. to validate transformation passes by comparing functions
+// before and after transformation.
+package equiv
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/llir/l/analysis"
+	"github.com/llir/l/interp"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/llir/l/transform"
+	"github.com/pkg/errors"
+)
+
+// === [ Equivalence checking ] ================================================
+
+// Config is the configuration of equivalence checking.
+type Config struct {
+	// Number of sampled inputs on which functions are co-interpreted.
+	Samples int
+	// Seed of the random inputs.
+	Seed int64
+	// Maximum number of instructions and terminators executed by each call;
+	// or 0 for no limit. Samples exceeding the limit in either function are
+	// skipped.
+	MaxSteps int
+}
+
+// DefaultConfig is the default configuration of equivalence checking.
+var DefaultConfig = &Config{
+	Samples:  100,
+	Seed:     1,
+	MaxSteps: 100000,
+}
+
+// Method is a method of equivalence checking.
+type Method uint8
+
+// Methods of equivalence checking, in order of application.
+const (
+	// Equal LLVM syntax representations after canonicalization.
+	MethodCanonical Method = iota
+	// Congruent return values by value numbering.
+	MethodValueNumbering
+	// Equal results on sampled inputs by co-interpretation.
+	MethodInterpretation
+)
+
+// String returns the string representation of the method.
+func (method Method) String() string {
+	switch method {
+	case MethodCanonical:
+		return "canonicalization"
+	case MethodValueNumbering:
+		return "value numbering"
+	case MethodInterpretation:
+		return "interpretation"
+	}
+	panic(errors.Errorf("support for method %d not yet implemented", uint8(method)))
+}
+
+// Result is the result of equivalence checking.
+type Result struct {
+	// The functions are equivalent; proven by canonicalization or value
+	// numbering, or without divergence on the sampled inputs if checked by
+	// interpretation.
+	Equivalent bool
+	// Method which established the result.
+	Method Method
+	// First divergence of the functions; or nil if equivalent.
+	Divergence *Divergence
+}
+
+// Divergence is a divergence of the behaviour of two functions on an input.
+type Divergence struct {
+	// Arguments of the divergent calls.
+	Args []interp.Value
+	// Return values of the first and second function.
+	A, B interp.Value
+	// Errors of the first and second function (e.g. undefined behaviour); or
+	// nil if returned.
+	ErrA, ErrB error
+}
+
+// String returns a string representation of the divergence.
+func (d *Divergence) String() string {
+	var args []string
+	for _, arg := range d.Args {
+		args = append(args, format(arg))
+	}
+	return fmt.Sprintf("args (%s): %s != %s", strings.Join(args, ", "), result(d.A, d.ErrA), result(d.B, d.ErrB))
+}
+
+// Funcs checks the function a of module ma and the function b of module mb for
+// behavioural equivalence. Global variables and functions referenced by the
+// functions are assumed equivalent if they have the same name.
+//
+// The functions are first compared for equal LLVM syntax representations after
+// canonicalization, and then (for functions of a single basic block without
+// side effects) for congruent return values by value numbering. As a fallback,
+// the functions are co-interpreted on sampled inputs (a mix of edge cases and
+// random values) in an interpreter of each module, reporting the first
+// divergence in return value or error; which requires integer and
+// floating-point parameters. Functions co-interpreted without divergence are
+// reported equivalent, although equivalence is only established for the
+// sampled inputs.
+//
+// The default configuration is used if cfg is nil. An error is returned if
+// the functions have different signatures, or may not be interpreted.
+func Funcs(ma *ir.Module, a *ir.Function, mb *ir.Module, b *ir.Function, cfg *Config) (*Result, error) {
+	if cfg == nil {
+		cfg = DefaultConfig
+	}
+	if !a.Sig.Equal(b.Sig) {
+		return nil, errors.Errorf("signature mismatch of %v and %v; %v != %v", a.Ident(), b.Ident(), a.Sig, b.Sig)
+	}
+	eq, err := canonicalEqual(a, b)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if eq {
+		return &Result{Equivalent: true, Method: MethodCanonical}, nil
+	}
+	if congruent(a, b) {
+		return &Result{Equivalent: true, Method: MethodValueNumbering}, nil
+	}
+	d, err := coInterpret(ma, a, mb, b, cfg)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Result{Equivalent: d == nil, Method: MethodInterpretation, Divergence: d}, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// canonicalEqual reports whether the given functions have equal LLVM syntax
+// representations after canonicalization, disregarding function names.
+func canonicalEqual(a, b *ir.Function) (bool, error) {
+	if len(a.Blocks) == 0 || len(b.Blocks) == 0 {
+		return false, nil
+	}
+	var defs []string
+	for _, f := range []*ir.Function{a, b} {
+		c := ir.CloneFunc(f)
+		c.GlobalName = a.GlobalName
+		if err := transform.CanonicalizeFunc(c); err != nil {
+			return false, errors.WithStack(err)
+		}
+		defs = append(defs, c.Def())
+	}
+	return defs[0] == defs[1], nil
+}
+
+// congruent reports whether the given functions of a single basic block
+// without side effects have congruent return values, by value numbering of a
+// function combining the instructions of both with shared parameters.
+func congruent(a, b *ir.Function) bool {
+	if len(a.Blocks) != 1 || len(b.Blocks) != 1 {
+		return false
+	}
+	var params []*ir.Param
+	for _, param := range a.Params {
+		params = append(params, ir.NewParam(param.Type(), ""))
+	}
+	f := ir.NewFunc("", a.Sig.RetType, params...)
+	block := f.NewBlock("")
+	var xs []value.Value
+	for _, g := range []*ir.Function{a, b} {
+		ret, ok := g.Blocks[0].Term.(*ir.TermRet)
+		if !ok {
+			return false
+		}
+		remap := make(map[value.Value]value.Value)
+		for i, param := range g.Params {
+			remap[param] = params[i]
+		}
+		for _, inst := range g.Blocks[0].Insts {
+			if !isPure(inst) {
+				return false
+			}
+			c := ir.CloneInst(inst, remap)
+			remap[inst.(value.Value)] = c.(value.Value)
+			block.Insts = append(block.Insts, c)
+		}
+		x := ret.X
+		if r, ok := remap[x]; ok {
+			x = r
+		}
+		xs = append(xs, x)
+	}
+	if xs[0] == nil || xs[1] == nil {
+		// Functions without side effects returning void.
+		return xs[0] == nil && xs[1] == nil
+	}
+	// Number the return values, as operands of terminators are not numbered.
+	block.NewSelect(ir.True, xs[0], xs[1])
+	block.NewRet(nil)
+	return analysis.NumberValues(f).Congruent(xs[0], xs[1])
+}
+
+// isPure reports whether the given instruction is free of side effects and
+// computes its result solely from its operands.
+func isPure(inst ir.Instruction) bool {
+	switch inst.(type) {
+	case *ir.InstAlloca, *ir.InstLoad, *ir.InstStore, *ir.InstFence, *ir.InstCmpXchg, *ir.InstAtomicRMW:
+		return false
+	case *ir.InstPhi, *ir.InstCall, *ir.InstVAArg, *ir.InstLandingPad, *ir.InstCatchPad, *ir.InstCleanupPad:
+		return false
+	case *ir.InstFreeze:
+		return false
+	case *ir.RawInst, ir.CustomInst:
+		return false
+	}
+	_, ok := inst.(value.Value)
+	return ok
+}
+
+// coInterpret interprets the given functions on sampled inputs, and returns
+// the first divergence; or nil if none.
+func coInterpret(ma *ir.Module, a *ir.Function, mb *ir.Module, b *ir.Function, cfg *Config) (*Divergence, error) {
+	ina, err := interp.New(ma)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	inb, err := interp.New(mb)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ina.MaxSteps = cfg.MaxSteps
+	inb.MaxSteps = cfg.MaxSteps
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	for i := 0; i < cfg.Samples; i++ {
+		var args []interp.Value
+		for _, param := range a.Sig.Params {
+			arg, err := sample(rnd, param, i)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			args = append(args, arg)
+		}
+		// Arguments are normalized in place (e.g. truncated to the parameter
+		// types) by the interpreter.
+		x, errA := ina.CallFunc(a, args...)
+		y, errB := inb.CallFunc(b, append([]interp.Value(nil), args...)...)
+		if errors.Cause(errA) == interp.ErrStepLimit || errors.Cause(errB) == interp.ErrStepLimit {
+			continue
+		}
+		if (errA != nil) != (errB != nil) || (errA == nil && !equal(x, y)) {
+			return &Divergence{Args: args, A: x, B: y, ErrA: errA, ErrB: errB}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Edge case values of integer and floating-point inputs, sampled before random
+// values.
+var (
+	intEdges   = []uint64{0, 1, math.MaxUint64, 2, math.MaxInt64, 1 << 63}
+	floatEdges = []float64{0, 1, -1, math.Copysign(0, -1), math.Inf(1), math.NaN()}
+)
+
+// sample returns the i:th sampled input of the given parameter type.
+func sample(rnd *rand.Rand, t types.Type, i int) (interp.Value, error) {
+	switch t.(type) {
+	case *types.IntType:
+		if i < len(intEdges) {
+			return interp.Value{Int: intEdges[i]}, nil
+		}
+		return interp.Value{Int: rnd.Uint64() >> uint(rnd.Intn(64))}, nil
+	case *types.FloatType:
+		if i < len(floatEdges) {
+			return interp.Value{Float: floatEdges[i]}, nil
+		}
+		return interp.Value{Float: rnd.NormFloat64() * 1000}, nil
+	}
+	return interp.Value{}, errors.Errorf("support for sampling inputs of type %v not yet implemented", t)
+}
+
+// equal reports whether the given runtime values are equal. NaN values are
+// equal to each other.
+func equal(x, y interp.Value) bool {
+	if x.Int != y.Int || len(x.Elems) != len(y.Elems) {
+		return false
+	}
+	if x.Float != y.Float || math.Signbit(x.Float) != math.Signbit(y.Float) {
+		if !math.IsNaN(x.Float) || !math.IsNaN(y.Float) {
+			return false
+		}
+	}
+	for i := range x.Elems {
+		if !equal(x.Elems[i], y.Elems[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// result returns a string representation of the given return value or error.
+func result(v interp.Value, err error) string {
+	if err != nil {
+		return fmt.Sprintf("error %q", err)
+	}
+	return format(v)
+}
+
+// format returns a string representation of the given runtime value.
+func format(v interp.Value) string {
+	switch {
+	case len(v.Elems) > 0:
+		var elems []string
+		for _, elem := range v.Elems {
+			elems = append(elems, format(elem))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(elems, ", "))
+	case v.Float != 0 || math.Signbit(v.Float) || math.IsNaN(v.Float):
+		return fmt.Sprintf("%g", v.Float)
+	}
+	return fmt.Sprintf("%d", v.Int)
+}
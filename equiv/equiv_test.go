@@ -0,0 +1,94 @@
+package equiv
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+func TestFuncs(t *testing.T) {
+	golden := []struct {
+		a, b       func(block *ir.BasicBlock, x, y value.Value) value.Value
+		equivalent bool
+		method     Method
+		args       []uint64
+	}{
+		// i=0
+		{
+			a: func(block *ir.BasicBlock, x, y value.Value) value.Value {
+				return block.NewAdd(x, y)
+			},
+			b: func(block *ir.BasicBlock, x, y value.Value) value.Value {
+				return block.NewAdd(y, x)
+			},
+			equivalent: true,
+			method:     MethodCanonical,
+		},
+		// i=1
+		{
+			a: func(block *ir.BasicBlock, x, y value.Value) value.Value {
+				return block.NewMul(block.NewAdd(x, y), ir.NewInt(types.I32, 2))
+			},
+			b: func(block *ir.BasicBlock, x, y value.Value) value.Value {
+				block.NewAdd(x, ir.NewInt(types.I32, 1))
+				return block.NewMul(block.NewAdd(y, x), ir.NewInt(types.I32, 2))
+			},
+			equivalent: true,
+			method:     MethodValueNumbering,
+		},
+		// i=2
+		{
+			a: func(block *ir.BasicBlock, x, y value.Value) value.Value {
+				return block.NewMul(x, ir.NewInt(types.I32, 2))
+			},
+			b: func(block *ir.BasicBlock, x, y value.Value) value.Value {
+				return block.NewShl(x, ir.NewInt(types.I32, 1))
+			},
+			equivalent: true,
+			method:     MethodInterpretation,
+		},
+		// i=3
+		{
+			a: func(block *ir.BasicBlock, x, y value.Value) value.Value {
+				return block.NewUDiv(x, ir.NewInt(types.I32, 2))
+			},
+			b: func(block *ir.BasicBlock, x, y value.Value) value.Value {
+				return block.NewLShr(x, ir.NewInt(types.I32, 2))
+			},
+			equivalent: false,
+			method:     MethodInterpretation,
+			args:       []uint64{0xFFFFFFFF, 0xFFFFFFFF},
+		},
+	}
+	for i, g := range golden {
+		m := &ir.Module{}
+		var funcs []*ir.Function
+		for j, body := range []func(block *ir.BasicBlock, x, y value.Value) value.Value{g.a, g.b} {
+			x, y := ir.NewParam(types.I32, "x"), ir.NewParam(types.I32, "y")
+			f := m.NewFunc(string(rune('a'+j)), types.I32, x, y)
+			entry := f.NewBlock("entry")
+			entry.NewRet(body(entry, x, y))
+			funcs = append(funcs, f)
+		}
+		res, err := Funcs(m, funcs[0], m, funcs[1], nil)
+		if err != nil {
+			t.Errorf("i=%d: unable to check equivalence; %+v", i, err)
+			continue
+		}
+		if res.Equivalent != g.equivalent || res.Method != g.method {
+			t.Errorf("i=%d: result mismatch; expected equivalent=%v by %v, got equivalent=%v by %v", i, g.equivalent, g.method, res.Equivalent, res.Method)
+			continue
+		}
+		if g.equivalent {
+			continue
+		}
+		d := res.Divergence
+		for j, arg := range g.args {
+			if d.Args[j].Int != arg {
+				t.Errorf("i=%d: argument %d mismatch of divergence %v; expected %d, got %d", i, j, d, arg, d.Args[j].Int)
+			}
+		}
+	}
+}
@@ -0,0 +1,318 @@
+// Package instrument implements instrumentation of LLVM IR modules, which
+// inserts calls to user-specified callbacks around memory accesses, function
+// entries and exits, and atomic operations; as the basis for custom sanitizers
+// and tracers.
+package instrument
+
+import (
+	"github.com/llir/l/datalayout"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/llir/l/sanitize"
+	"github.com/pkg/errors"
+)
+
+// === [ Instrumentation ] =====================================================
+
+// Callbacks specifies the callback functions of an instrumentation. Calls to
+// nil callbacks are not inserted.
+type Callbacks struct {
+	// Load is called before each non-atomic load, with the address and size in
+	// bytes of the loaded value.
+	//
+	//    void (i8* addr, i64 size)
+	Load *ir.Function
+	// Store is called before each non-atomic store, with the address and size
+	// in bytes of the stored value, and the stored value converted to i64. The
+	// value of stores of types other than integers of at most 64 bits, pointers,
+	// float and double is 0.
+	//
+	//    void (i8* addr, i64 size, i64 value)
+	Store *ir.Function
+	// Atomic is called before each atomic load, atomic store, atomicrmw and
+	// cmpxchg instruction, with the address and size in bytes of the accessed
+	// value, and the C11 memory order (memory_order_relaxed = 0, ...,
+	// memory_order_seq_cst = 5) of the atomic operation.
+	//
+	//    void (i8* addr, i64 size, i32 order)
+	Atomic *ir.Function
+	// FuncEntry is called at the entry of each function, with a pointer to the
+	// function.
+	//
+	//    void (i8* fn)
+	FuncEntry *ir.Function
+	// FuncExit is called before each return of each function, with a pointer to
+	// the function.
+	//
+	//    void (i8* fn)
+	FuncExit *ir.Function
+}
+
+// NewCallbacks returns callbacks of all kinds, which are declared in the module
+// as external functions with the given name prefix (e.g. "__trace_") followed
+// by "load", "store", "atomic", "func_entry" and "func_exit" respectively.
+// Existing functions of the same name are reused.
+func NewCallbacks(m *ir.Module, prefix string) (*Callbacks, error) {
+	cb := &Callbacks{}
+	for _, c := range []struct {
+		f    **ir.Function
+		name string
+		sig  *types.FuncType
+	}{
+		{f: &cb.Load, name: "load", sig: loadSig},
+		{f: &cb.Store, name: "store", sig: storeSig},
+		{f: &cb.Atomic, name: "atomic", sig: atomicSig},
+		{f: &cb.FuncEntry, name: "func_entry", sig: funcSig},
+		{f: &cb.FuncExit, name: "func_exit", sig: funcSig},
+	} {
+		name := prefix + c.name
+		if v, ok := m.Lookup(name); ok {
+			f, ok := v.(*ir.Function)
+			if !ok {
+				return nil, errors.Errorf("invalid callback %v; expected function, got %T", v.Ident(), v)
+			}
+			*c.f = f
+			continue
+		}
+		*c.f = m.NewFunc(name, c.sig.RetType, paramsOf(c.sig)...)
+	}
+	return cb, nil
+}
+
+// Instrument inserts calls to the given callbacks into the function
+// definitions of the module, using the data layout of the module to determine
+// the size of accessed values. The callback functions themselves are not
+// instrumented.
+//
+// Instructions marked with !nosanitize metadata are not instrumented, and the
+// inserted calls are marked with !nosanitize metadata, so that they are not
+// instrumented by subsequent instrumentations (e.g. with other callbacks).
+func Instrument(m *ir.Module, cb *Callbacks) error {
+	if err := cb.check(); err != nil {
+		return errors.WithStack(err)
+	}
+	dl, err := datalayout.Parse(m.DataLayout)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, f := range m.Funcs {
+		instrumentFunc(dl, f, cb)
+	}
+	return nil
+}
+
+// InstrumentFunc inserts calls to the given callbacks into the given function
+// definition of the module, as described by Instrument.
+func InstrumentFunc(m *ir.Module, f *ir.Function, cb *Callbacks) error {
+	if err := cb.check(); err != nil {
+		return errors.WithStack(err)
+	}
+	dl, err := datalayout.Parse(m.DataLayout)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	instrumentFunc(dl, f, cb)
+	return nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// Signatures of callback functions.
+var (
+	loadSig   = types.NewFunc(types.Void, types.I8Ptr, types.I64)
+	storeSig  = types.NewFunc(types.Void, types.I8Ptr, types.I64, types.I64)
+	atomicSig = types.NewFunc(types.Void, types.I8Ptr, types.I64, types.I32)
+	funcSig   = types.NewFunc(types.Void, types.I8Ptr)
+)
+
+// check checks the signatures of the callbacks.
+func (cb *Callbacks) check() error {
+	for _, c := range []struct {
+		f   *ir.Function
+		sig *types.FuncType
+	}{
+		{f: cb.Load, sig: loadSig},
+		{f: cb.Store, sig: storeSig},
+		{f: cb.Atomic, sig: atomicSig},
+		{f: cb.FuncEntry, sig: funcSig},
+		{f: cb.FuncExit, sig: funcSig},
+	} {
+		if c.f != nil && !c.f.Sig.Equal(c.sig) {
+			return errors.Errorf("invalid signature of callback %v; expected %v, got %v", c.f.Ident(), c.sig, c.f.Sig)
+		}
+	}
+	return nil
+}
+
+// isCallback reports whether the given function is one of the callbacks.
+func (cb *Callbacks) isCallback(f *ir.Function) bool {
+	return f == cb.Load || f == cb.Store || f == cb.Atomic || f == cb.FuncEntry || f == cb.FuncExit
+}
+
+// instrumentFunc inserts calls to the given callbacks into the given function
+// definition, unless a callback function.
+func instrumentFunc(dl *datalayout.DataLayout, f *ir.Function, cb *Callbacks) {
+	if len(f.Blocks) == 0 || cb.isCallback(f) {
+		return
+	}
+	fn := ir.NewBitCastExpr(f, types.I8Ptr)
+	for i, block := range f.Blocks {
+		insts := append([]ir.Instruction(nil), block.Insts...)
+		if i == 0 && cb.FuncEntry != nil {
+			block.InsertInst(insertionPoint(block), newCall(cb.FuncEntry, fn))
+		}
+		for _, inst := range insts {
+			if !sanitize.IsNoSanitize(inst) {
+				block.InsertBefore(inst, cb.access(dl, inst)...)
+			}
+		}
+		if _, ok := block.Term.(*ir.TermRet); ok && cb.FuncExit != nil {
+			block.InsertInst(len(block.Insts), newCall(cb.FuncExit, fn))
+		}
+	}
+}
+
+// access returns the instructions calling the callback of the given memory
+// access instruction; or nil if not a memory access.
+func (cb *Callbacks) access(dl *datalayout.DataLayout, inst ir.Instruction) []ir.Instruction {
+	switch inst := inst.(type) {
+	case *ir.InstLoad:
+		if inst.Atomic {
+			return cb.atomic(dl, inst.Src, inst.Typ, inst.Ordering)
+		}
+		if cb.Load == nil {
+			return nil
+		}
+		addr, insts := toI8Ptr(inst.Src)
+		return append(insts, newCall(cb.Load, addr, size(dl, inst.Typ)))
+	case *ir.InstStore:
+		if inst.Atomic {
+			return cb.atomic(dl, inst.Dst, inst.Src.Type(), inst.Ordering)
+		}
+		if cb.Store == nil {
+			return nil
+		}
+		addr, insts := toI8Ptr(inst.Dst)
+		x, xInsts := toI64(inst.Src)
+		insts = append(insts, xInsts...)
+		return append(insts, newCall(cb.Store, addr, size(dl, inst.Src.Type()), x))
+	case *ir.InstAtomicRMW:
+		return cb.atomic(dl, inst.Dst, inst.X.Type(), inst.Ordering)
+	case *ir.InstCmpXchg:
+		return cb.atomic(dl, inst.Ptr, inst.Cmp.Type(), inst.Success)
+	}
+	return nil
+}
+
+// atomic returns the instructions calling the atomic callback of an atomic
+// operation on a value of the given type at addr.
+func (cb *Callbacks) atomic(dl *datalayout.DataLayout, addr value.Value, typ types.Type, ordering enum.AtomicOrdering) []ir.Instruction {
+	if cb.Atomic == nil {
+		return nil
+	}
+	addr, insts := toI8Ptr(addr)
+	order := ir.NewInt(types.I32, memoryOrder(ordering))
+	return append(insts, newCall(cb.Atomic, addr, size(dl, typ), order))
+}
+
+// newCall returns a new call to the given callback, marked with !nosanitize
+// metadata.
+func newCall(callback *ir.Function, args ...value.Value) *ir.InstCall {
+	inst := ir.NewCall(callback, args...)
+	sanitize.NoSanitize(inst)
+	return inst
+}
+
+// size returns the store size in bytes of the given type as an i64 constant.
+func size(dl *datalayout.DataLayout, t types.Type) *ir.ConstInt {
+	return ir.NewInt(types.I64, dl.TypeStoreSize(t))
+}
+
+// toI8Ptr converts the given pointer to i8*, and returns the converted value
+// and the conversion instructions.
+func toI8Ptr(ptr value.Value) (value.Value, []ir.Instruction) {
+	if ptr.Type().Equal(types.I8Ptr) {
+		return ptr, nil
+	}
+	if c, ok := ptr.(ir.Constant); ok {
+		return ir.NewBitCastExpr(c, types.I8Ptr), nil
+	}
+	inst := ir.NewBitCast(ptr, types.I8Ptr)
+	return inst, []ir.Instruction{inst}
+}
+
+// toI64 converts the given value to i64, and returns the converted value and
+// the conversion instructions. Values of types other than integers of at most
+// 64 bits, pointers, float and double are converted to 0.
+func toI64(x value.Value) (value.Value, []ir.Instruction) {
+	var insts []ir.Instruction
+	switch t := x.Type().(type) {
+	case *types.IntType:
+		if t.BitSize > 64 {
+			return ir.NewInt(types.I64, 0), nil
+		}
+	case *types.PointerType:
+		inst := ir.NewPtrToInt(x, types.I64)
+		return inst, []ir.Instruction{inst}
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindFloat:
+			inst := ir.NewBitCast(x, types.I32)
+			insts = append(insts, inst)
+			x = inst
+		case types.FloatKindDouble:
+			inst := ir.NewBitCast(x, types.I64)
+			return inst, []ir.Instruction{inst}
+		default:
+			return ir.NewInt(types.I64, 0), nil
+		}
+	default:
+		return ir.NewInt(types.I64, 0), nil
+	}
+	if x.Type().Equal(types.I64) {
+		return x, insts
+	}
+	inst := ir.NewZExt(x, types.I64)
+	return inst, append(insts, inst)
+}
+
+// memoryOrder returns the C11 memory order of the given atomic ordering.
+func memoryOrder(ordering enum.AtomicOrdering) int64 {
+	switch ordering {
+	case enum.AtomicOrderingAcquire:
+		return 2 // memory_order_acquire
+	case enum.AtomicOrderingRelease:
+		return 3 // memory_order_release
+	case enum.AtomicOrderingAcqRel:
+		return 4 // memory_order_acq_rel
+	case enum.AtomicOrderingSeqCst:
+		return 5 // memory_order_seq_cst
+	}
+	// unordered and monotonic.
+	return 0 // memory_order_relaxed
+}
+
+// insertionPoint returns the index of the first instruction of the given basic
+// block which is not a phi instruction or exception handling pad.
+func insertionPoint(block *ir.BasicBlock) int {
+	for i, inst := range block.Insts {
+		switch inst.(type) {
+		case *ir.InstPhi, *ir.InstLandingPad, *ir.InstCatchPad, *ir.InstCleanupPad:
+			// skip.
+		default:
+			return i
+		}
+	}
+	return len(block.Insts)
+}
+
+// paramsOf returns unnamed parameters of the given function signature.
+func paramsOf(sig *types.FuncType) []*ir.Param {
+	var params []*ir.Param
+	for _, param := range sig.Params {
+		params = append(params, ir.NewParam(param, ""))
+	}
+	return params
+}
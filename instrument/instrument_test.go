@@ -0,0 +1,74 @@
+package instrument
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestInstrument(t *testing.T) {
+	m := &ir.Module{}
+	g := m.NewGlobalDef("g", ir.NewInt(types.I32, 0))
+	p := ir.NewParam(types.NewPointer(types.Double), "p")
+	f := m.NewFunc("f", types.Void, p)
+	entry := f.NewBlock("entry")
+	x := entry.NewLoad(g)
+	entry.NewStore(x, g)
+	d := entry.NewLoad(p)
+	entry.NewStore(d, p)
+	atomic := entry.NewStore(x, g)
+	atomic.Atomic = true
+	atomic.Ordering = enum.AtomicOrderingSeqCst
+	atomic.Alignment = 4
+	entry.NewRet(nil)
+	cb, err := NewCallbacks(m, "__trace_")
+	if err != nil {
+		t.Fatalf("unable to create callbacks; %+v", err)
+	}
+	cb.Load = nil
+	if err := Instrument(m, cb); err != nil {
+		t.Fatalf("unable to instrument module; %+v", err)
+	}
+	// Calls inserted by the first instrumentation are not instrumented.
+	if err := Instrument(m, &Callbacks{Load: loadCallback(m)}); err != nil {
+		t.Fatalf("unable to instrument module; %+v", err)
+	}
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %+v", err)
+	}
+	want := `define void @f(double* %p) {
+entry:
+	call void @__trace_func_entry(i8* bitcast (void (double*)* @f to i8*)), !nosanitize !{}
+	call void @__load(i8* bitcast (i32* @g to i8*), i64 4), !nosanitize !{}
+	%0 = load i32, i32* @g
+	%1 = zext i32 %0 to i64
+	call void @__trace_store(i8* bitcast (i32* @g to i8*), i64 4, i64 %1), !nosanitize !{}
+	store i32 %0, i32* @g
+	%2 = bitcast double* %p to i8*
+	call void @__load(i8* %2, i64 8), !nosanitize !{}
+	%3 = load double, double* %p
+	%4 = bitcast double* %p to i8*
+	%5 = bitcast double %3 to i64
+	call void @__trace_store(i8* %4, i64 8, i64 %5), !nosanitize !{}
+	store double %3, double* %p
+	call void @__trace_atomic(i8* bitcast (i32* @g to i8*), i64 4, i32 5), !nosanitize !{}
+	store atomic i32 %0, i32* @g seq_cst, align 4
+	call void @__trace_func_exit(i8* bitcast (void (double*)* @f to i8*)), !nosanitize !{}
+	ret void
+}`
+	if got := f.Def(); want != got {
+		t.Errorf("function mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// Invalid callback signature.
+	cb.Load = f
+	if err := Instrument(m, cb); err == nil {
+		t.Errorf("expected error for invalid callback signature")
+	}
+}
+
+// loadCallback returns a load callback declared in the module.
+func loadCallback(m *ir.Module) *ir.Function {
+	return m.NewFunc("__load", types.Void, ir.NewParam(types.I8Ptr, "addr"), ir.NewParam(types.I64, "size"))
+}
@@ -1,8 +1,17 @@
 // Package enc implements encoding of identifiers for LLVM IR assembly.
+//
+// Non-ASCII characters are treated as raw bytes; each byte of the UTF-8
+// encoding of a non-ASCII character is escaped as a hexadecimal escape sequence
+// (\XX) in quoted identifiers, metadata names, string literals and metadata
+// strings alike. Decoding restores the original bytes, so names and strings
+// survive round trips unchanged, even if they contain invalid UTF-8.
+//
+//    "世" -> `\E4\B8\96`
 package enc
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -10,7 +19,9 @@ import (
 //
 // Examples:
 //    "foo" -> "@foo"
-//    "a b" -> `@"a\20b"`
+//    "a b" -> `@"a b"`
+//    "2"   -> "@2"
+//    "2a"  -> `@"2a"`
 //    "世" -> `@"\E4\B8\96"`
 //
 // References:
@@ -23,7 +34,9 @@ func Global(name string) string {
 //
 // Examples:
 //    "foo" -> "%foo"
-//    "a b" -> `%"a\20b"`
+//    "a b" -> `%"a b"`
+//    "42"  -> "%42"
+//    "4a"  -> `%"4a"`
 //    "世" -> `%"\E4\B8\96"`
 //
 // References:
@@ -32,11 +45,33 @@ func Local(name string) string {
 	return "%" + EscapeIdent(name)
 }
 
+// GlobalID encodes a global ID to its LLVM IR assembly representation.
+//
+// Examples:
+//    0 -> "@0"
+//
+// References:
+//    http://www.llvm.org/docs/LangRef.html#identifiers
+func GlobalID(id int64) string {
+	return "@" + strconv.FormatInt(id, 10)
+}
+
+// LocalID encodes a local ID to its LLVM IR assembly representation.
+//
+// Examples:
+//    42 -> "%42"
+//
+// References:
+//    http://www.llvm.org/docs/LangRef.html#identifiers
+func LocalID(id int64) string {
+	return "%" + strconv.FormatInt(id, 10)
+}
+
 // Label encodes a label name to its LLVM IR assembly representation.
 //
 // Examples:
 //    "foo" -> "foo:"
-//    "a b" -> `"a\20b":`
+//    "a b" -> `"a b":`
 //    "世" -> `"\E4\B8\96":`
 //
 // References:
@@ -61,7 +96,7 @@ func AttrGroupID(id string) string {
 //
 // Examples:
 //    "foo" -> $%foo"
-//    "a b" -> `$"a\20b"`
+//    "a b" -> `$"a b"`
 //    "世" -> `$"\E4\B8\96"`
 //
 // References:
@@ -70,21 +105,120 @@ func Comdat(name string) string {
 	return "$" + EscapeIdent(name)
 }
 
-// Metadata encodes a metadata name to its LLVM IR assembly representation.
+// MetadataName encodes a metadata name to its LLVM IR assembly
+// representation. Metadata names are never quoted; instead, characters which
+// are not valid in metadata names are escaped, as is a leading decimal digit
+// (to distinguish metadata names from metadata IDs).
 //
 // Examples:
-//    "foo" -> "!foo"
-//    "a b" -> `!a\20b`
-//    "世" -> `!\E4\B8\96`
+//    "foo"          -> "!foo"
+//    "llvm.dbg.cu"  -> "!llvm.dbg.cu"
+//    "a b"          -> `!a\20b`
+//    "2"            -> `!\32`
+//    "世"           -> `!\E4\B8\96`
 //
 // References:
 //    http://www.llvm.org/docs/LangRef.html#identifiers
-func Metadata(name string) string {
+func MetadataName(name string) string {
+	if len(name) == 0 {
+		return "!"
+	}
+	// Escape leading decimal digit.
+	first := string(Escape([]byte(name[:1]), func(b byte) bool {
+		return strings.IndexByte(head, b) != -1
+	}))
 	valid := func(b byte) bool {
-		const metadataCharset = tail + `\`
-		return strings.IndexByte(metadataCharset, b) != -1
+		return strings.IndexByte(tail, b) != -1
+	}
+	return "!" + first + string(Escape([]byte(name[1:]), valid))
+}
+
+// MetadataID encodes a metadata ID to its LLVM IR assembly representation.
+//
+// Examples:
+//    42 -> "!42"
+//
+// References:
+//    http://www.llvm.org/docs/LangRef.html#identifiers
+func MetadataID(id int64) string {
+	return "!" + strconv.FormatInt(id, 10)
+}
+
+// UnescapeGlobal decodes the given global identifier to its name; the inverse
+// of Global.
+//
+// Examples:
+//    "@foo"     -> "foo"
+//    `@"a b"`   -> "a b"
+//    "@2"       -> "2"
+//    `@"\E4\B8\96"` -> "世"
+func UnescapeGlobal(ident string) string {
+	return unescapeIdent(ident, "@")
+}
+
+// UnescapeLocal decodes the given local identifier to its name; the inverse
+// of Local.
+//
+// Examples:
+//    "%foo"     -> "foo"
+//    `%"a b"`   -> "a b"
+//    "%42"      -> "42"
+//    `%"\E4\B8\96"` -> "世"
+func UnescapeLocal(ident string) string {
+	return unescapeIdent(ident, "%")
+}
+
+// UnescapeLabel decodes the given label to its name; the inverse of Label.
+//
+// Examples:
+//    "foo:"     -> "foo"
+//    `"a b":`   -> "a b"
+//    "2:"       -> "2"
+//    `"\E4\B8\96":` -> "世"
+func UnescapeLabel(label string) string {
+	if !strings.HasSuffix(label, ":") {
+		panic(fmt.Errorf("invalid label `%s`; missing ':' suffix", label))
 	}
-	return "!" + string(Escape([]byte(name), valid))
+	return unescapeIdent(label[:len(label)-1], "")
+}
+
+// UnescapeMetadata decodes the given metadata name or ID to its name; the
+// inverse of MetadataName.
+//
+// Examples:
+//    "!foo"     -> "foo"
+//    `!a\20b`   -> "a b"
+//    `!\32`     -> "2"
+//    "!42"      -> "42"
+func UnescapeMetadata(ident string) string {
+	if !strings.HasPrefix(ident, "!") {
+		panic(fmt.Errorf("invalid metadata identifier `%s`; missing '!' prefix", ident))
+	}
+	return string(Unescape(ident[1:]))
+}
+
+// IsID reports whether the given name is a numeric ID (e.g. "42" as used in
+// "%42" or "@42"), as opposed to a regular name.
+func IsID(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if strings.IndexByte(decimal, name[i]) == -1 {
+			return false
+		}
+	}
+	return len(name) > 0
+}
+
+// unescapeIdent decodes the given identifier to its name, after stripping the
+// prefix of the identifier.
+func unescapeIdent(ident, prefix string) string {
+	if !strings.HasPrefix(ident, prefix) {
+		panic(fmt.Errorf("invalid identifier `%s`; missing %q prefix", ident, prefix))
+	}
+	s := ident[len(prefix):]
+	if strings.HasPrefix(s, `"`) {
+		return string(Unquote(s))
+	}
+	return s
 }
 
 const (
@@ -119,31 +253,55 @@ const (
 // EscapeIdent replaces any characters which are not valid in identifiers with
 // corresponding hexadecimal escape sequence (\XX).
 func EscapeIdent(s string) string {
-	valid := func(b byte) bool {
-		const identCharset = tail
-		return strings.IndexByte(identCharset, b) != -1
-	}
-	if new := Escape(s, valid); len(new) > len(s) {
-		return `"` + new + `"`
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(tail, s[i]) == -1 {
+			return `"` + Escape([]byte(s), isQuotable) + `"`
+		}
 	}
 	return s
 }
 */
 
 // EscapeIdent replaces any characters which are not valid in identifiers with
-// corresponding hexadecimal escape sequence (\XX).
+// corresponding hexadecimal escape sequence (\XX). Identifiers containing
+// characters outside of the bare identifier character set are quoted, in which
+// case only non-printable characters, '"' and '\' are escaped.
+//
+// Examples:
+//    "foo"         -> "foo"
+//    "a b"         -> `"a b"`
+//    "weird name!" -> `"weird name!"`
+//    `a"b`         -> `"a\22b"`
+//    "42"          -> "42"
+//    "4a"          -> `"4a"`
+//    "世"           -> `"\E4\B8\96"`
 func EscapeIdent(s string) string {
+	// Numeric IDs are never quoted.
+	if IsID(s) {
+		return s
+	}
 	// Check if a replacement is required.
+	//
+	// Names starting with a decimal digit must be quoted, so they are not
+	// confused with numeric IDs.
+	quote := len(s) > 0 && strings.IndexByte(head, s[0]) == -1
 	extra := 0
 	for i := 0; i < len(s); i++ {
-		if strings.IndexByte(tail, s[i]) == -1 {
+		b := s[i]
+		if strings.IndexByte(tail, b) == -1 {
+			// Note, there are characters which are not valid in identifiers
+			// (e.g. '#') but are valid in quoted identifiers, and therefore
+			// require quotes but no escape sequence.
+			quote = true
+		}
+		if !isQuotable(b) {
 			// Two extra bytes are required for each invalid byte; e.g.
-			//    "#" -> `\23`
+			//    "\n" -> `\0A`
 			//    "世" -> `\E4\B8\96`
 			extra += 2
 		}
 	}
-	if extra == 0 {
+	if !quote {
 		return s
 	}
 
@@ -153,7 +311,7 @@ func EscapeIdent(s string) string {
 	j := 0
 	for i := 0; i < len(s); i++ {
 		b := s[i]
-		if strings.IndexByte(tail, b) != -1 {
+		if isQuotable(b) {
 			buf[j] = b
 			j++
 			continue
@@ -170,10 +328,7 @@ func EscapeIdent(s string) string {
 // EscapeString replaces any characters in s categorized as invalid in string
 // literals with corresponding hexadecimal escape sequence (\XX).
 func EscapeString(s []byte) string {
-	valid := func(b byte) bool {
-		return ' ' <= b && b <= '~' && b != '"' && b != '\\'
-	}
-	return string(Escape(s, valid))
+	return string(Escape(s, isQuotable))
 }
 
 // Escape replaces any characters in s categorized as invalid by the valid
@@ -222,11 +377,11 @@ func Unescape(s string) []byte {
 	buf := []byte(s)
 	for i := 0; i < len(s); i++ {
 		b := s[i]
-		if b == '\\' && i+2 < len(s) {
+		if b == '\\' && i+1 < len(s) {
 			if s[i+1] == '\\' {
 				b = '\\'
 				i++
-			} else {
+			} else if i+2 < len(s) {
 				x1, ok := unhex(s[i+1])
 				if ok {
 					x2, ok := unhex(s[i+2])
@@ -250,6 +405,15 @@ func Quote(s []byte) string {
 	return `"` + string(EscapeString(s)) + `"`
 }
 
+// MetadataString returns s as a metadata string literal.
+//
+// Examples:
+//    "foo" -> `!"foo"`
+//    "世" -> `!"\E4\B8\96"`
+func MetadataString(s string) string {
+	return "!" + Quote([]byte(s))
+}
+
 // Unquote interprets s as a double-quoted string literal, returning the string
 // value that s quotes.
 func Unquote(s string) []byte {
@@ -267,6 +431,13 @@ func Unquote(s string) []byte {
 	return Unescape(s)
 }
 
+// isQuotable reports whether the given byte may be used as is within a quoted
+// identifier or string literal; i.e. printable ASCII characters except '"' and
+// '\\'.
+func isQuotable(b byte) bool {
+	return ' ' <= b && b <= '~' && b != '"' && b != '\\'
+}
+
 // unhex returns the numeric value represented by the hexadecimal digit b. It
 // returns false if b is not a hexadecimal digit.
 func unhex(b byte) (v byte, ok bool) {
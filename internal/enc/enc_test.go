@@ -13,7 +13,7 @@ func TestGlobal(t *testing.T) {
 		// i=0
 		{s: "foo", want: "@foo"},
 		// i=1
-		{s: "a b", want: `@"a\20b"`},
+		{s: "a b", want: `@"a b"`},
 		// i=2
 		{s: "$a", want: "@$a"},
 		// i=3
@@ -23,13 +23,21 @@ func TestGlobal(t *testing.T) {
 		// i=5
 		{s: "_a", want: "@_a"},
 		// i=6
-		{s: "#a", want: `@"\23a"`},
+		{s: "#a", want: `@"#a"`},
 		// i=7
-		{s: "a b#c", want: `@"a\20b\23c"`},
+		{s: "a b#c", want: `@"a b#c"`},
 		// i=8
 		{s: "2", want: "@2"},
 		// i=9
 		{s: "foo世bar", want: `@"foo\E4\B8\96bar"`},
+		// i=10
+		{s: "weird name!", want: `@"weird name!"`},
+		// i=11
+		{s: `a"b`, want: `@"a\22b"`},
+		// i=12
+		{s: `a\b`, want: `@"a\5Cb"`},
+		// i=13
+		{s: "2a", want: `@"2a"`},
 	}
 	for i, g := range golden {
 		got := Global(g.s)
@@ -47,7 +55,7 @@ func TestLocal(t *testing.T) {
 		// i=0
 		{s: "foo", want: "%foo"},
 		// i=1
-		{s: "a b", want: `%"a\20b"`},
+		{s: "a b", want: `%"a b"`},
 		// i=2
 		{s: "$a", want: "%$a"},
 		// i=3
@@ -57,13 +65,21 @@ func TestLocal(t *testing.T) {
 		// i=5
 		{s: "_a", want: "%_a"},
 		// i=6
-		{s: "#a", want: `%"\23a"`},
+		{s: "#a", want: `%"#a"`},
 		// i=7
-		{s: "a b#c", want: `%"a\20b\23c"`},
+		{s: "a b#c", want: `%"a b#c"`},
 		// i=8
 		{s: "2", want: "%2"},
 		// i=9
 		{s: "foo世bar", want: `%"foo\E4\B8\96bar"`},
+		// i=10
+		{s: "weird name!", want: `%"weird name!"`},
+		// i=11
+		{s: `a"b`, want: `%"a\22b"`},
+		// i=12
+		{s: `a\b`, want: `%"a\5Cb"`},
+		// i=13
+		{s: "2a", want: `%"2a"`},
 	}
 	for i, g := range golden {
 		got := Local(g.s)
@@ -81,7 +97,7 @@ func TestLabel(t *testing.T) {
 		// i=0
 		{s: "foo", want: "foo:"},
 		// i=1
-		{s: "a b", want: `"a\20b":`},
+		{s: "a b", want: `"a b":`},
 		// i=2
 		{s: "$a", want: "$a:"},
 		// i=3
@@ -91,13 +107,21 @@ func TestLabel(t *testing.T) {
 		// i=5
 		{s: "_a", want: "_a:"},
 		// i=6
-		{s: "#a", want: `"\23a":`},
+		{s: "#a", want: `"#a":`},
 		// i=7
-		{s: "a b#c", want: `"a\20b\23c":`},
+		{s: "a b#c", want: `"a b#c":`},
 		// i=8
 		{s: "2", want: "2:"},
 		// i=9
 		{s: "foo世bar", want: `"foo\E4\B8\96bar":`},
+		// i=10
+		{s: "weird name!", want: `"weird name!":`},
+		// i=11
+		{s: `a"b`, want: `"a\22b":`},
+		// i=12
+		{s: `a\b`, want: `"a\5Cb":`},
+		// i=13
+		{s: "2a", want: `"2a":`},
 	}
 	for i, g := range golden {
 		got := Label(g.s)
@@ -107,6 +131,128 @@ func TestLabel(t *testing.T) {
 	}
 }
 
+func TestGlobalID(t *testing.T) {
+	golden := []struct {
+		id   int64
+		want string
+	}{
+		// i=0
+		{id: 0, want: "@0"},
+		// i=1
+		{id: 42, want: "@42"},
+	}
+	for i, g := range golden {
+		got := GlobalID(g.id)
+		if g.want != got {
+			t.Errorf("i=%d: name mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
+
+func TestLocalID(t *testing.T) {
+	golden := []struct {
+		id   int64
+		want string
+	}{
+		// i=0
+		{id: 0, want: "%0"},
+		// i=1
+		{id: 42, want: "%42"},
+	}
+	for i, g := range golden {
+		got := LocalID(g.id)
+		if g.want != got {
+			t.Errorf("i=%d: name mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
+
+func TestUnescapeGlobal(t *testing.T) {
+	golden := []struct {
+		s    string
+		want string
+	}{
+		// i=0
+		{s: "@foo", want: "foo"},
+		// i=1
+		{s: `@"a b"`, want: "a b"},
+		// i=2
+		{s: "@42", want: "42"},
+		// i=3
+		{s: `@"2a"`, want: "2a"},
+		// i=4
+		{s: `@"foo\E4\B8\96bar"`, want: "foo世bar"},
+		// i=5
+		{s: `@"a\22b"`, want: `a"b`},
+	}
+	for i, g := range golden {
+		got := UnescapeGlobal(g.s)
+		if g.want != got {
+			t.Errorf("i=%d: name mismatch; expected %q, got %q", i, g.want, got)
+		}
+		// Round-trip.
+		if ident := Global(got); g.s != ident {
+			t.Errorf("i=%d: identifier mismatch; expected %q, got %q", i, g.s, ident)
+		}
+	}
+}
+
+func TestUnescapeLocal(t *testing.T) {
+	golden := []struct {
+		s    string
+		want string
+	}{
+		// i=0
+		{s: "%foo", want: "foo"},
+		// i=1
+		{s: `%"a b"`, want: "a b"},
+		// i=2
+		{s: "%42", want: "42"},
+		// i=3
+		{s: `%"2a"`, want: "2a"},
+		// i=4
+		{s: `%"foo\E4\B8\96bar"`, want: "foo世bar"},
+		// i=5
+		{s: `%"a\5Cb"`, want: `a\b`},
+	}
+	for i, g := range golden {
+		got := UnescapeLocal(g.s)
+		if g.want != got {
+			t.Errorf("i=%d: name mismatch; expected %q, got %q", i, g.want, got)
+		}
+		// Round-trip.
+		if ident := Local(got); g.s != ident {
+			t.Errorf("i=%d: identifier mismatch; expected %q, got %q", i, g.s, ident)
+		}
+	}
+}
+
+func TestUnescapeLabel(t *testing.T) {
+	golden := []struct {
+		s    string
+		want string
+	}{
+		// i=0
+		{s: "foo:", want: "foo"},
+		// i=1
+		{s: `"a b":`, want: "a b"},
+		// i=2
+		{s: "2:", want: "2"},
+		// i=3
+		{s: `"foo\E4\B8\96bar":`, want: "foo世bar"},
+	}
+	for i, g := range golden {
+		got := UnescapeLabel(g.s)
+		if g.want != got {
+			t.Errorf("i=%d: name mismatch; expected %q, got %q", i, g.want, got)
+		}
+		// Round-trip.
+		if label := Label(got); g.s != label {
+			t.Errorf("i=%d: label mismatch; expected %q, got %q", i, g.s, label)
+		}
+	}
+}
+
 func TestAttrGroupID(t *testing.T) {
 	golden := []struct {
 		s    string
@@ -131,7 +277,7 @@ func TestComdat(t *testing.T) {
 		// i=0
 		{s: "foo", want: "$foo"},
 		// i=1
-		{s: "a b", want: `$"a\20b"`},
+		{s: "a b", want: `$"a b"`},
 		// i=2
 		{s: "$a", want: "$$a"},
 		// i=3
@@ -141,13 +287,21 @@ func TestComdat(t *testing.T) {
 		// i=5
 		{s: "_a", want: "$_a"},
 		// i=6
-		{s: "#a", want: `$"\23a"`},
+		{s: "#a", want: `$"#a"`},
 		// i=7
-		{s: "a b#c", want: `$"a\20b\23c"`},
+		{s: "a b#c", want: `$"a b#c"`},
 		// i=8
 		{s: "2", want: "$2"},
 		// i=9
 		{s: "foo世bar", want: `$"foo\E4\B8\96bar"`},
+		// i=10
+		{s: "weird name!", want: `$"weird name!"`},
+		// i=11
+		{s: `a"b`, want: `$"a\22b"`},
+		// i=12
+		{s: `a\b`, want: `$"a\5Cb"`},
+		// i=13
+		{s: "2a", want: `$"2a"`},
 	}
 	for i, g := range golden {
 		got := Comdat(g.s)
@@ -157,7 +311,7 @@ func TestComdat(t *testing.T) {
 	}
 }
 
-func TestMetadata(t *testing.T) {
+func TestMetadataName(t *testing.T) {
 	golden := []struct {
 		s    string
 		want string
@@ -179,15 +333,69 @@ func TestMetadata(t *testing.T) {
 		// i=7
 		{s: "a b#c", want: `!a\20b\23c`},
 		// i=8
-		{s: "2", want: "!2"},
+		{s: "2", want: `!\32`},
 		// i=9
 		{s: "foo世bar", want: `!foo\E4\B8\96bar`},
+		// i=10
+		{s: "llvm.dbg.cu", want: "!llvm.dbg.cu"},
+		// i=11
+		{s: "llvm-ident", want: "!llvm-ident"},
+		// i=12
+		{s: "a2", want: "!a2"},
+		// i=13
+		{s: `a\b`, want: `!a\5Cb`},
+	}
+	for i, g := range golden {
+		got := MetadataName(g.s)
+		if g.want != got {
+			t.Errorf("i=%d: name mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
+
+func TestMetadataID(t *testing.T) {
+	golden := []struct {
+		id   int64
+		want string
+	}{
+		// i=0
+		{id: 0, want: "!0"},
+		// i=1
+		{id: 42, want: "!42"},
+	}
+	for i, g := range golden {
+		got := MetadataID(g.id)
+		if g.want != got {
+			t.Errorf("i=%d: name mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
+
+func TestUnescapeMetadata(t *testing.T) {
+	golden := []struct {
+		s    string
+		want string
+	}{
+		// i=0
+		{s: "!foo", want: "foo"},
+		// i=1
+		{s: "!llvm.dbg.cu", want: "llvm.dbg.cu"},
+		// i=2
+		{s: `!a\20b`, want: "a b"},
+		// i=3
+		{s: `!\32`, want: "2"},
+		// i=4
+		{s: `!foo\E4\B8\96bar`, want: "foo世bar"},
 	}
 	for i, g := range golden {
-		got := Metadata(g.s)
+		got := UnescapeMetadata(g.s)
 		if g.want != got {
 			t.Errorf("i=%d: name mismatch; expected %q, got %q", i, g.want, got)
 		}
+		// Round-trip.
+		if ident := MetadataName(got); g.s != ident {
+			t.Errorf("i=%d: identifier mismatch; expected %q, got %q", i, g.s, ident)
+		}
 	}
 }
 
@@ -380,6 +588,8 @@ func TestUnquote(t *testing.T) {
 		{s: `"foo \\ bar"`, want: []byte(`foo \ bar`)},
 		// i=12 (arbitrary data, invalid UTF-8)
 		{s: `"foo\81\82bar"`, want: []byte{'f', 'o', 'o', 0x81, 0x82, 'b', 'a', 'r'}},
+		// i=13
+		{s: `"foo\\"`, want: []byte(`foo\`)},
 	}
 	for i, g := range golden {
 		got := Unquote(g.s)
@@ -389,6 +599,68 @@ func TestUnquote(t *testing.T) {
 	}
 }
 
+func TestMetadataString(t *testing.T) {
+	golden := []struct {
+		s    string
+		want string
+	}{
+		// i=0
+		{s: "foo", want: `!"foo"`},
+		// i=1
+		{s: "a b", want: `!"a b"`},
+		// i=2
+		{s: "foo世bar", want: `!"foo\E4\B8\96bar"`},
+		// i=3
+		{s: `a"b`, want: `!"a\22b"`},
+	}
+	for i, g := range golden {
+		got := MetadataString(g.s)
+		if g.want != got {
+			t.Errorf("i=%d: string mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	golden := []string{
+		// i=0
+		"foo",
+		// i=1
+		"a b",
+		// i=2
+		"foo世bar",
+		// i=3
+		"日本語",
+		// i=4 (arbitrary data, invalid UTF-8)
+		string([]byte{'f', 'o', 'o', 0x81, 0x82, 'b', 'a', 'r'}),
+		// i=5
+		`a"b\c`,
+		// i=6
+		`trailing\`,
+		// i=7
+		"tab\tnewline\n",
+		// i=8
+		"42",
+	}
+	for i, g := range golden {
+		if got := UnescapeGlobal(Global(g)); g != got {
+			t.Errorf("i=%d: global name mismatch; expected %q, got %q", i, g, got)
+		}
+		if got := UnescapeLocal(Local(g)); g != got {
+			t.Errorf("i=%d: local name mismatch; expected %q, got %q", i, g, got)
+		}
+		if got := UnescapeLabel(Label(g)); g != got {
+			t.Errorf("i=%d: label name mismatch; expected %q, got %q", i, g, got)
+		}
+		if got := UnescapeMetadata(MetadataName(g)); g != got {
+			t.Errorf("i=%d: metadata name mismatch; expected %q, got %q", i, g, got)
+		}
+		if got := string(Unquote(Quote([]byte(g)))); g != got {
+			t.Errorf("i=%d: string literal mismatch; expected %q, got %q", i, g, got)
+		}
+	}
+}
+
 func BenchmarkGlobalNoReplace(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		Global("$foo_bar_baz")
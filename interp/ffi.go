@@ -0,0 +1,192 @@
+package interp
+
+import (
+	"reflect"
+
+	"github.com/llir/l/internal/enc"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// --- [ Foreign function interface ] ------------------------------------------
+
+// Pointer is an address in the memory of an interpreter, as passed to and
+// returned from Go functions bound to function declarations.
+type Pointer uint64
+
+// Bind binds the given Go function to the function declarations of the given
+// name (without '@' prefix), which are called in place of the declared
+// functions.
+//
+// Arguments and return values are marshalled between LLVM IR and Go values
+// based on their types, as follows.
+//
+//    iN       int8, int16, int32, int64, uint8, ..., uint64 (of size N)
+//    iN       int, uint, uintptr (of size at least N)
+//    i1       bool
+//    float    float32
+//    double   float64
+//    T*       interp.Pointer
+//
+// Variadic arguments are passed to the variadic parameter of a variadic Go
+// function; if its element type is interface{}, integers are passed as int64
+// (sign-extended), floating-point values as float64 and pointers as Pointer.
+//
+// The Go function may return an error as its last result, which aborts
+// execution of the interpreter.
+func (in *Interpreter) Bind(name string, fn interface{}) error {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func {
+		return errors.Errorf("invalid Go function bound to %v; expected function, got %T", enc.Global(name), fn)
+	}
+	ft := rv.Type()
+	nout := ft.NumOut()
+	if nout > 0 && ft.Out(nout-1) == errorType {
+		nout--
+	}
+	if nout > 1 {
+		return errors.Errorf("invalid Go function bound to %v; expected at most one result (and an error), got %d", enc.Global(name), ft.NumOut())
+	}
+	in.bindings[name] = rv
+	return nil
+}
+
+// callGo calls the Go function bound to the given function declaration with
+// the given arguments of the given types.
+func (in *Interpreter) callGo(f *ir.Function, args []Value, argTypes []types.Type) (Value, error) {
+	fn, ok := in.bindings[f.GlobalName]
+	if !ok {
+		return Value{}, errors.Errorf("call to undefined function %v; no Go function bound", f.Ident())
+	}
+	ft := fn.Type()
+	fixed := ft.NumIn()
+	if ft.IsVariadic() {
+		fixed--
+	}
+	if len(args) < fixed || (!ft.IsVariadic() && len(args) != fixed) {
+		return Value{}, errors.Errorf("invalid number of arguments in call to Go function bound to %v; expected %d, got %d", f.Ident(), fixed, len(args))
+	}
+	goArgs := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var goType reflect.Type
+		if i < fixed {
+			goType = ft.In(i)
+		} else {
+			goType = ft.In(fixed).Elem()
+		}
+		v, err := toGo(argTypes[i], arg, goType)
+		if err != nil {
+			return Value{}, errors.Wrapf(err, "invalid argument %d in call to Go function bound to %v", i, f.Ident())
+		}
+		goArgs[i] = v
+	}
+	results := fn.Call(goArgs)
+	if n := len(results); n > 0 && ft.Out(n-1) == errorType {
+		if err, _ := results[n-1].Interface().(error); err != nil {
+			return Value{}, errors.Wrapf(err, "Go function bound to %v", f.Ident())
+		}
+		results = results[:n-1]
+	}
+	retType := f.Sig.RetType
+	if retType.Equal(types.Void) {
+		if len(results) != 0 {
+			return Value{}, errors.Errorf("invalid result of Go function bound to %v; expected no result for void return type", f.Ident())
+		}
+		return Value{}, nil
+	}
+	if len(results) != 1 {
+		return Value{}, errors.Errorf("invalid result of Go function bound to %v; expected result of return type %v", f.Ident(), retType)
+	}
+	v, err := fromGo(retType, results[0])
+	if err != nil {
+		return Value{}, errors.Wrapf(err, "invalid result of Go function bound to %v", f.Ident())
+	}
+	return v, nil
+}
+
+// toGo returns the given value of the given type as a Go value of the given Go
+// type.
+func toGo(t types.Type, v Value, goType reflect.Type) (reflect.Value, error) {
+	if goType.Kind() == reflect.Interface && goType.NumMethod() == 0 {
+		switch t := t.(type) {
+		case *types.IntType:
+			return reflect.ValueOf(sext(v.Int, t.BitSize)), nil
+		case *types.FloatType:
+			return reflect.ValueOf(v.Float), nil
+		case *types.PointerType:
+			return reflect.ValueOf(Pointer(v.Int)), nil
+		}
+	}
+	if !compatible(t, goType) {
+		return reflect.Value{}, errors.Errorf("unable to marshal %v as Go type %v", t, goType)
+	}
+	rv := reflect.New(goType).Elem()
+	switch goType.Kind() {
+	case reflect.Bool:
+		rv.SetBool(v.Int != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		rv.SetInt(sext(v.Int, t.(*types.IntType).BitSize))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		rv.SetUint(v.Int)
+	case reflect.Float32, reflect.Float64:
+		rv.SetFloat(v.Float)
+	}
+	return rv, nil
+}
+
+// fromGo returns the given Go value as a value of the given type.
+func fromGo(t types.Type, rv reflect.Value) (Value, error) {
+	if !compatible(t, rv.Type()) {
+		return Value{}, errors.Errorf("unable to marshal Go type %v as %v", rv.Type(), t)
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return boolValue(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Value{Int: mask(uint64(rv.Int()), t.(*types.IntType).BitSize)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if t, ok := t.(*types.IntType); ok {
+			return Value{Int: mask(rv.Uint(), t.BitSize)}, nil
+		}
+		// Pointer.
+		return Value{Int: rv.Uint()}, nil
+	default:
+		return Value{Float: roundFloat(t, rv.Float())}, nil
+	}
+}
+
+// compatible reports whether values of the given type may be marshalled to and
+// from Go values of the given Go type.
+func compatible(t types.Type, goType reflect.Type) bool {
+	switch t := t.(type) {
+	case *types.IntType:
+		if goType == pointerType {
+			return false
+		}
+		switch goType.Kind() {
+		case reflect.Bool:
+			return t.BitSize == 1
+		case reflect.Int, reflect.Uint, reflect.Uintptr:
+			return t.BitSize <= int64(goType.Bits())
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return t.BitSize == int64(goType.Bits())
+		}
+	case *types.FloatType:
+		switch goType.Kind() {
+		case reflect.Float32:
+			return t.Kind == types.FloatKindFloat
+		case reflect.Float64:
+			return t.Kind == types.FloatKindDouble
+		}
+	case *types.PointerType:
+		return goType == pointerType
+	}
+	return false
+}
+
+// Go types used by the foreign function interface.
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	pointerType = reflect.TypeOf(Pointer(0))
+)
@@ -0,0 +1,91 @@
+package interp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestBind(t *testing.T) {
+	// @msg = global [6 x i8] c"hello\00"
+	// @format = global [9 x i8] c"%d %.1f\0A\00"
+	//
+	// declare i32 @puts(i8*)
+	// declare i32 @printf(i8*, ...)
+	//
+	// define i32 @main() {
+	// entry:
+	//    %0 = getelementptr [6 x i8], [6 x i8]* @msg, i64 0, i64 0
+	//    %1 = call i32 @puts(i8* %0)
+	//    %2 = getelementptr [9 x i8], [9 x i8]* @format, i64 0, i64 0
+	//    %3 = call i32 (i8*, ...) @printf(i8* %2, i32 -42, double 2.5)
+	//    %4 = add i32 %1, %3
+	//    ret i32 %4
+	// }
+	m := &ir.Module{}
+	msg := m.NewGlobalDef("msg", ir.NewCharArrayFromString("hello\x00"))
+	format := m.NewGlobalDef("format", ir.NewCharArrayFromString("%d %.1f\n\x00"))
+	i8ptr := types.NewPointer(types.I8)
+	puts := m.NewFunc("puts", types.I32, ir.NewParam(i8ptr, "s"))
+	printf := m.NewFunc("printf", types.I32, ir.NewParam(i8ptr, "format"))
+	printf.Sig.Variadic = true
+	main := m.NewFunc("main", types.I32)
+	entry := ir.NewBlock("entry")
+	zero := ir.NewInt(types.I64, 0)
+	n := entry.NewCall(puts, entry.NewGetElementPtr(msg.ContentType, msg, zero, zero))
+	k := entry.NewCall(printf, entry.NewGetElementPtr(format.ContentType, format, zero, zero), ir.NewInt(types.I32, -42), ir.NewFloat(types.Double, 2.5))
+	entry.NewRet(entry.NewAdd(n, k))
+	main.Blocks = append(main.Blocks, entry)
+
+	in, err := New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &strings.Builder{}
+	if err := in.Bind("puts", func(s Pointer) (int32, error) {
+		str, err := in.ReadString(s)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := fmt.Fprintln(out, str)
+		return int32(n), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Bind("printf", func(format Pointer, args ...interface{}) (int32, error) {
+		s, err := in.ReadString(format)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := fmt.Fprintf(out, s, args...)
+		return int32(n), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := in.Call("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "hello\n-42 2.5\n"
+	if out.String() != want {
+		t.Errorf("output mismatch; expected %q, got %q", want, out.String())
+	}
+	if got.Int != uint64(len(want)) {
+		t.Errorf("return value mismatch; expected %d, got %d", len(want), got.Int)
+	}
+	// Type mismatch of argument.
+	if err := in.Bind("puts", func(s int64) int32 { return 0 }); err != nil {
+		t.Fatal(err)
+	}
+	wantErr := "function @main: invalid argument 0 in call to Go function bound to @puts: unable to marshal i8* as Go type int64"
+	if _, err := in.Call("main"); err == nil || err.Error() != wantErr {
+		t.Errorf("error mismatch; expected %q, got %v", wantErr, err)
+	}
+	// Invalid Go function.
+	if err := in.Bind("puts", 42); err == nil {
+		t.Errorf("expected error for binding of non-function")
+	}
+}
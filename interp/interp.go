@@ -0,0 +1,620 @@
+// Package interp implements an interpreter of LLVM IR modules.
+//
+// The interpreter executes function definitions of a module instruction by
+// instruction, and calls Go functions bound to function declarations (see
+// Interpreter.Bind). Memory is byte-addressable and laid out according to the
+// data layout of the module.
+package interp
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+
+	"github.com/llir/l/datalayout"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// ErrStepLimit is returned by Call and CallFunc if execution is aborted after
+// executing MaxSteps instructions and terminators.
+var ErrStepLimit = errors.New("step limit exceeded")
+
+// === [ Values ] ==============================================================
+
+// Value is a runtime value of the interpreter.
+//
+// Integers are stored zero-extended in Int, pointers are stored as addresses in
+// Int, floating-point values are stored in Float (rounded to single precision
+// for values of type float), and the elements of arrays, structs and vectors
+// are stored in Elems.
+type Value struct {
+	// Integer value or address.
+	Int uint64
+	// Floating-point value.
+	Float float64
+	// Elements of aggregate or vector value.
+	Elems []Value
+}
+
+// === [ Interpreter ] =========================================================
+
+// Interpreter is an interpreter of an LLVM IR module.
+type Interpreter struct {
+	// Module being interpreted.
+	Module *ir.Module
+	// Data layout of the module.
+	Layout *datalayout.DataLayout
+	// Maximum number of instructions and terminators executed by each call to
+	// Call or CallFunc; or 0 if unlimited.
+	MaxSteps int
+
+	// Memory; the first nullSize bytes are reserved for the null pointer.
+	mem []byte
+	// Address of each global variable and function of the module.
+	addrs map[value.Value]uint64
+	// Function of each function address.
+	funcs map[uint64]*ir.Function
+	// Go functions bound to function declarations, by function name.
+	bindings map[string]reflect.Value
+	// Number of instructions and terminators executed by the current call.
+	steps int
+}
+
+// New returns a new interpreter of the given module. The global variables of
+// the module are allocated and initialized.
+func New(m *ir.Module) (*Interpreter, error) {
+	dl, err := datalayout.Parse(m.DataLayout)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	in := &Interpreter{
+		Module:   m,
+		Layout:   dl,
+		mem:      make([]byte, nullSize),
+		addrs:    make(map[value.Value]uint64),
+		funcs:    make(map[uint64]*ir.Function),
+		bindings: make(map[string]reflect.Value),
+	}
+	// Allocate global variables and functions before evaluating initializers,
+	// as initializers may refer to the address of any global.
+	for _, g := range m.Globals {
+		if !dl.IsSized(g.ContentType) {
+			return nil, errors.Errorf("unable to allocate global variable %v; unsized content type %v", g.Ident(), g.ContentType)
+		}
+		align := dl.ABIAlign(g.ContentType)
+		if g.Align > align {
+			align = g.Align
+		}
+		in.addrs[g] = in.alloc(dl.TypeAllocSize(g.ContentType), align)
+	}
+	for _, f := range m.Funcs {
+		addr := in.alloc(1, 1)
+		in.addrs[f] = addr
+		in.funcs[addr] = f
+	}
+	for _, g := range m.Globals {
+		init := g.Init
+		if init == nil && g.LazyInit != nil {
+			init = g.LazyInit()
+		}
+		if init == nil {
+			continue
+		}
+		v, err := in.evalConst(init)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to initialize global variable %v", g.Ident())
+		}
+		if err := in.store(in.addrs[g], g.ContentType, v); err != nil {
+			return nil, errors.Wrapf(err, "unable to initialize global variable %v", g.Ident())
+		}
+	}
+	return in, nil
+}
+
+// Call calls the function of the given name with the given arguments, and
+// returns its return value.
+func (in *Interpreter) Call(name string, args ...Value) (Value, error) {
+	v, ok := in.Module.Lookup(name)
+	if !ok {
+		return Value{}, errors.Errorf("unable to locate function %q", name)
+	}
+	f, ok := v.(*ir.Function)
+	if !ok {
+		return Value{}, errors.Errorf("invalid function %q; expected *ir.Function, got %T", name, v)
+	}
+	return in.CallFunc(f, args...)
+}
+
+// CallFunc calls the given function with the given arguments, and returns its
+// return value. Arguments are truncated to the types of the function
+// parameters.
+func (in *Interpreter) CallFunc(f *ir.Function, args ...Value) (Value, error) {
+	if len(args) != len(f.Sig.Params) {
+		return Value{}, errors.Errorf("invalid number of arguments in call to %v; expected %d, got %d", f.Ident(), len(f.Sig.Params), len(args))
+	}
+	argTypes := f.Sig.Params
+	for i, arg := range args {
+		args[i] = normalize(argTypes[i], arg)
+	}
+	in.steps = 0
+	return in.call(f, args, argTypes)
+}
+
+// frame is the stack frame of a function call.
+type frame struct {
+	// Function being executed.
+	f *ir.Function
+	// Value of each parameter and instruction.
+	locals map[value.Value]Value
+}
+
+// call calls the given function with the given arguments of the given types.
+func (in *Interpreter) call(f *ir.Function, args []Value, argTypes []types.Type) (Value, error) {
+	if len(f.Blocks) == 0 {
+		return in.callGo(f, args, argTypes)
+	}
+	if len(args) < len(f.Params) || (!f.Sig.Variadic && len(args) != len(f.Params)) {
+		return Value{}, errors.Errorf("invalid number of arguments in call to %v; expected %d, got %d", f.Ident(), len(f.Params), len(args))
+	}
+	fr := &frame{f: f, locals: make(map[value.Value]Value)}
+	for i, param := range f.Params {
+		fr.locals[param] = args[i]
+	}
+	var pred *ir.BasicBlock
+	block := f.Blocks[0]
+	for {
+		next, ret, err := in.execBlock(fr, block, pred)
+		if err != nil {
+			return Value{}, errors.Wrapf(err, "function %v", f.Ident())
+		}
+		if next == nil {
+			return ret, nil
+		}
+		pred, block = block, next
+	}
+}
+
+// execBlock executes the given basic block, entered from the given predecessor
+// basic block, and returns the successor basic block to execute next; or nil
+// and the return value if the function returns.
+func (in *Interpreter) execBlock(fr *frame, block, pred *ir.BasicBlock) (next *ir.BasicBlock, ret Value, err error) {
+	// Evaluate phi instructions simultaneously.
+	phis := make(map[*ir.InstPhi]Value)
+	for _, inst := range block.Insts {
+		phi, ok := inst.(*ir.InstPhi)
+		if !ok {
+			continue
+		}
+		inc, ok := incoming(phi, pred)
+		if !ok {
+			return nil, Value{}, errors.Errorf("unable to locate incoming value of predecessor %v in phi instruction %v", blockIdent(pred), phi.Ident())
+		}
+		v, err := in.eval(fr, inc)
+		if err != nil {
+			return nil, Value{}, errors.WithStack(err)
+		}
+		phis[phi] = v
+	}
+	for phi, v := range phis {
+		fr.locals[phi] = v
+	}
+	for _, inst := range block.Insts {
+		if _, ok := inst.(*ir.InstPhi); ok {
+			continue
+		}
+		if err := in.step(); err != nil {
+			return nil, Value{}, err
+		}
+		if err := in.exec(fr, inst); err != nil {
+			return nil, Value{}, errors.WithStack(err)
+		}
+	}
+	if err := in.step(); err != nil {
+		return nil, Value{}, err
+	}
+	switch term := block.Term.(type) {
+	case *ir.TermRet:
+		if term.X == nil {
+			return nil, Value{}, nil
+		}
+		ret, err := in.eval(fr, term.X)
+		return nil, ret, errors.WithStack(err)
+	case *ir.TermBr:
+		return term.Target, Value{}, nil
+	case *ir.TermCondBr:
+		cond, err := in.eval(fr, term.Cond)
+		if err != nil {
+			return nil, Value{}, errors.WithStack(err)
+		}
+		if cond.Int&1 != 0 {
+			return term.TargetTrue, Value{}, nil
+		}
+		return term.TargetFalse, Value{}, nil
+	case *ir.TermSwitch:
+		x, err := in.eval(fr, term.X)
+		if err != nil {
+			return nil, Value{}, errors.WithStack(err)
+		}
+		for _, c := range term.Cases {
+			y, err := in.evalConst(c.X)
+			if err != nil {
+				return nil, Value{}, errors.WithStack(err)
+			}
+			if x.Int == y.Int {
+				return c.Target, Value{}, nil
+			}
+		}
+		return term.TargetDefault, Value{}, nil
+	case *ir.TermUnreachable:
+		return nil, Value{}, errors.Errorf("unreachable executed in basic block %v", blockIdent(block))
+	case nil:
+		return nil, Value{}, errors.Errorf("missing terminator of basic block %v", blockIdent(block))
+	default:
+		return nil, Value{}, errors.Errorf("support for terminator %T not yet implemented", term)
+	}
+}
+
+// exec executes the given instruction.
+func (in *Interpreter) exec(fr *frame, inst ir.Instruction) error {
+	var v Value
+	var err error
+	switch inst := inst.(type) {
+	// Unary instructions.
+	case *ir.InstFNeg:
+		v, err = in.fneg(fr, inst.X)
+	// Binary instructions.
+	case *ir.InstAdd:
+		v, err = in.binary(fr, opAdd, inst.X, inst.Y)
+	case *ir.InstFAdd:
+		v, err = in.binary(fr, opFAdd, inst.X, inst.Y)
+	case *ir.InstSub:
+		v, err = in.binary(fr, opSub, inst.X, inst.Y)
+	case *ir.InstFSub:
+		v, err = in.binary(fr, opFSub, inst.X, inst.Y)
+	case *ir.InstMul:
+		v, err = in.binary(fr, opMul, inst.X, inst.Y)
+	case *ir.InstFMul:
+		v, err = in.binary(fr, opFMul, inst.X, inst.Y)
+	case *ir.InstUDiv:
+		v, err = in.binary(fr, opUDiv, inst.X, inst.Y)
+	case *ir.InstSDiv:
+		v, err = in.binary(fr, opSDiv, inst.X, inst.Y)
+	case *ir.InstFDiv:
+		v, err = in.binary(fr, opFDiv, inst.X, inst.Y)
+	case *ir.InstURem:
+		v, err = in.binary(fr, opURem, inst.X, inst.Y)
+	case *ir.InstSRem:
+		v, err = in.binary(fr, opSRem, inst.X, inst.Y)
+	case *ir.InstFRem:
+		v, err = in.binary(fr, opFRem, inst.X, inst.Y)
+	// Bitwise instructions.
+	case *ir.InstShl:
+		v, err = in.binary(fr, opShl, inst.X, inst.Y)
+	case *ir.InstLShr:
+		v, err = in.binary(fr, opLShr, inst.X, inst.Y)
+	case *ir.InstAShr:
+		v, err = in.binary(fr, opAShr, inst.X, inst.Y)
+	case *ir.InstAnd:
+		v, err = in.binary(fr, opAnd, inst.X, inst.Y)
+	case *ir.InstOr:
+		v, err = in.binary(fr, opOr, inst.X, inst.Y)
+	case *ir.InstXor:
+		v, err = in.binary(fr, opXor, inst.X, inst.Y)
+	// Vector instructions.
+	case *ir.InstExtractElement:
+		v, err = in.extractElement(fr, inst.X, inst.Index)
+	case *ir.InstInsertElement:
+		v, err = in.insertElement(fr, inst.X, inst.Elem, inst.Index)
+	case *ir.InstShuffleVector:
+		v, err = in.shuffleVector(fr, inst.X, inst.Y, inst.Mask)
+	// Aggregate instructions.
+	case *ir.InstExtractValue:
+		v, err = in.extractValue(fr, inst.X, inst.Indices)
+	case *ir.InstInsertValue:
+		v, err = in.insertValue(fr, inst.X, inst.Elem, inst.Indices)
+	// Memory instructions.
+	case *ir.InstAlloca:
+		v, err = in.alloca(fr, inst)
+	case *ir.InstLoad:
+		var src Value
+		if src, err = in.eval(fr, inst.Src); err == nil {
+			v, err = in.load(src.Int, inst.Type())
+		}
+	case *ir.InstStore:
+		src, err := in.eval(fr, inst.Src)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		dst, err := in.eval(fr, inst.Dst)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return in.store(dst.Int, inst.Src.Type(), src)
+	case *ir.InstFence:
+		// Execution is single-threaded; nothing to do.
+		return nil
+	case *ir.InstCmpXchg:
+		v, err = in.cmpXchg(fr, inst)
+	case *ir.InstGetElementPtr:
+		v, err = in.gep(fr, inst.ElemType, inst.Src, inst.Indices)
+	// Conversion instructions.
+	case *ir.InstTrunc:
+		v, err = in.conversion(fr, convTrunc, inst.From, inst.To)
+	case *ir.InstZExt:
+		v, err = in.conversion(fr, convZExt, inst.From, inst.To)
+	case *ir.InstSExt:
+		v, err = in.conversion(fr, convSExt, inst.From, inst.To)
+	case *ir.InstFPTrunc:
+		v, err = in.conversion(fr, convFPTrunc, inst.From, inst.To)
+	case *ir.InstFPExt:
+		v, err = in.conversion(fr, convFPExt, inst.From, inst.To)
+	case *ir.InstFPToUI:
+		v, err = in.conversion(fr, convFPToUI, inst.From, inst.To)
+	case *ir.InstFPToSI:
+		v, err = in.conversion(fr, convFPToSI, inst.From, inst.To)
+	case *ir.InstUIToFP:
+		v, err = in.conversion(fr, convUIToFP, inst.From, inst.To)
+	case *ir.InstSIToFP:
+		v, err = in.conversion(fr, convSIToFP, inst.From, inst.To)
+	case *ir.InstPtrToInt:
+		v, err = in.conversion(fr, convPtrToInt, inst.From, inst.To)
+	case *ir.InstIntToPtr:
+		v, err = in.conversion(fr, convIntToPtr, inst.From, inst.To)
+	case *ir.InstBitCast:
+		v, err = in.conversion(fr, convBitCast, inst.From, inst.To)
+	case *ir.InstAddrSpaceCast:
+		v, err = in.conversion(fr, convAddrSpaceCast, inst.From, inst.To)
+	// Other instructions.
+	case *ir.InstICmp:
+		v, err = in.icmp(fr, inst.Pred, inst.X, inst.Y)
+	case *ir.InstFCmp:
+		v, err = in.fcmp(fr, inst.Pred, inst.X, inst.Y)
+	case *ir.InstSelect:
+		v, err = in.selectValue(fr, inst.Cond, inst.X, inst.Y)
+	case *ir.InstFreeze:
+		v, err = in.eval(fr, inst.X)
+	case *ir.InstCall:
+		if v, err = in.callInst(fr, inst); err == nil && inst.Type().Equal(types.Void) {
+			return nil
+		}
+	default:
+		return errors.Errorf("support for instruction %T not yet implemented", inst)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fr.locals[inst.(value.Value)] = v
+	return nil
+}
+
+// callInst executes the given call instruction.
+func (in *Interpreter) callInst(fr *frame, inst *ir.InstCall) (Value, error) {
+	var callee *ir.Function
+	if f, ok := inst.Callee.(*ir.Function); ok {
+		callee = f
+	} else {
+		addr, err := in.eval(fr, inst.Callee)
+		if err != nil {
+			return Value{}, errors.WithStack(err)
+		}
+		if callee, ok = in.funcs[addr.Int]; !ok {
+			return Value{}, errors.Errorf("call through invalid function pointer 0x%X", addr.Int)
+		}
+	}
+	args := make([]Value, len(inst.Args))
+	argTypes := make([]types.Type, len(inst.Args))
+	for i, arg := range inst.Args {
+		v, err := in.eval(fr, arg)
+		if err != nil {
+			return Value{}, errors.WithStack(err)
+		}
+		args[i] = v
+		argTypes[i] = arg.Type()
+	}
+	return in.call(callee, args, argTypes)
+}
+
+// step records the execution of an instruction or terminator, and reports an
+// error if the step limit is exceeded.
+func (in *Interpreter) step() error {
+	in.steps++
+	if in.MaxSteps > 0 && in.steps > in.MaxSteps {
+		return ErrStepLimit
+	}
+	return nil
+}
+
+// --- [ Operands ] ------------------------------------------------------------
+
+// eval returns the value of the given operand in the given stack frame.
+func (in *Interpreter) eval(fr *frame, v value.Value) (Value, error) {
+	if c, ok := v.(ir.Constant); ok {
+		return in.evalConst(c)
+	}
+	if fr != nil {
+		if x, ok := fr.locals[v]; ok {
+			return x, nil
+		}
+	}
+	return Value{}, errors.Errorf("use of undefined value %v", v.Ident())
+}
+
+// evalConst returns the value of the given constant.
+func (in *Interpreter) evalConst(c ir.Constant) (Value, error) {
+	switch c := c.(type) {
+	// Simple constants.
+	case *ir.ConstInt:
+		if c.Typ.BitSize > 64 {
+			return Value{}, errors.Errorf("support for integers wider than 64 bits not yet implemented; got %v", c.Typ)
+		}
+		x := new(big.Int).And(c.X, maxUint64)
+		return Value{Int: mask(x.Uint64(), c.Typ.BitSize)}, nil
+	case *ir.ConstFloat:
+		if c.NaN {
+			return Value{Float: math.NaN()}, nil
+		}
+		x, _ := c.X.Float64()
+		return Value{Float: roundFloat(c.Typ, x)}, nil
+	case *ir.ConstNull, *ir.ConstNone:
+		return Value{}, nil
+	// Complex constants.
+	case *ir.ConstStruct:
+		return in.evalConsts(c.Fields)
+	case *ir.ConstArray:
+		return in.evalConsts(c.Elems)
+	case *ir.ConstCharArray:
+		elems := make([]Value, len(c.X))
+		for i, b := range c.X {
+			elems[i] = Value{Int: uint64(b)}
+		}
+		return Value{Elems: elems}, nil
+	case *ir.ConstVector:
+		return in.evalConsts(c.Elems)
+	case *ir.ConstZeroInitializer:
+		return zero(c.Typ), nil
+	case *ir.ConstUndef:
+		return zero(c.Typ), nil
+	// Global variable and function addresses.
+	case *ir.Global, *ir.Function:
+		addr, ok := in.addrs[c]
+		if !ok {
+			return Value{}, errors.Errorf("reference to global %v not present in module", c.Ident())
+		}
+		return Value{Int: addr}, nil
+	// Binary expressions.
+	case *ir.ExprAdd:
+		return in.binary(nil, opAdd, c.X, c.Y)
+	case *ir.ExprFAdd:
+		return in.binary(nil, opFAdd, c.X, c.Y)
+	case *ir.ExprSub:
+		return in.binary(nil, opSub, c.X, c.Y)
+	case *ir.ExprFSub:
+		return in.binary(nil, opFSub, c.X, c.Y)
+	case *ir.ExprMul:
+		return in.binary(nil, opMul, c.X, c.Y)
+	case *ir.ExprFMul:
+		return in.binary(nil, opFMul, c.X, c.Y)
+	case *ir.ExprUDiv:
+		return in.binary(nil, opUDiv, c.X, c.Y)
+	case *ir.ExprSDiv:
+		return in.binary(nil, opSDiv, c.X, c.Y)
+	case *ir.ExprFDiv:
+		return in.binary(nil, opFDiv, c.X, c.Y)
+	case *ir.ExprURem:
+		return in.binary(nil, opURem, c.X, c.Y)
+	case *ir.ExprSRem:
+		return in.binary(nil, opSRem, c.X, c.Y)
+	case *ir.ExprFRem:
+		return in.binary(nil, opFRem, c.X, c.Y)
+	// Bitwise expressions.
+	case *ir.ExprShl:
+		return in.binary(nil, opShl, c.X, c.Y)
+	case *ir.ExprLShr:
+		return in.binary(nil, opLShr, c.X, c.Y)
+	case *ir.ExprAShr:
+		return in.binary(nil, opAShr, c.X, c.Y)
+	case *ir.ExprAnd:
+		return in.binary(nil, opAnd, c.X, c.Y)
+	case *ir.ExprOr:
+		return in.binary(nil, opOr, c.X, c.Y)
+	case *ir.ExprXor:
+		return in.binary(nil, opXor, c.X, c.Y)
+	// Vector expressions.
+	case *ir.ExprExtractElement:
+		return in.extractElement(nil, c.X, c.Index)
+	case *ir.ExprInsertElement:
+		return in.insertElement(nil, c.X, c.Elem, c.Index)
+	case *ir.ExprShuffleVector:
+		return in.shuffleVector(nil, c.X, c.Y, c.Mask)
+	// Aggregate expressions.
+	case *ir.ExprExtractValue:
+		return in.extractValue(nil, c.X, c.Indices)
+	case *ir.ExprInsertValue:
+		return in.insertValue(nil, c.X, c.Elem, c.Indices)
+	// Memory expressions.
+	case *ir.ExprGetElementPtr:
+		indices := make([]value.Value, len(c.Indices))
+		for i, index := range c.Indices {
+			indices[i] = index.Index
+		}
+		return in.gep(nil, c.ElemType, c.Src, indices)
+	// Conversion expressions.
+	case *ir.ExprTrunc:
+		return in.conversion(nil, convTrunc, c.From, c.To)
+	case *ir.ExprZExt:
+		return in.conversion(nil, convZExt, c.From, c.To)
+	case *ir.ExprSExt:
+		return in.conversion(nil, convSExt, c.From, c.To)
+	case *ir.ExprFPTrunc:
+		return in.conversion(nil, convFPTrunc, c.From, c.To)
+	case *ir.ExprFPExt:
+		return in.conversion(nil, convFPExt, c.From, c.To)
+	case *ir.ExprFPToUI:
+		return in.conversion(nil, convFPToUI, c.From, c.To)
+	case *ir.ExprFPToSI:
+		return in.conversion(nil, convFPToSI, c.From, c.To)
+	case *ir.ExprUIToFP:
+		return in.conversion(nil, convUIToFP, c.From, c.To)
+	case *ir.ExprSIToFP:
+		return in.conversion(nil, convSIToFP, c.From, c.To)
+	case *ir.ExprPtrToInt:
+		return in.conversion(nil, convPtrToInt, c.From, c.To)
+	case *ir.ExprIntToPtr:
+		return in.conversion(nil, convIntToPtr, c.From, c.To)
+	case *ir.ExprBitCast:
+		return in.conversion(nil, convBitCast, c.From, c.To)
+	case *ir.ExprAddrSpaceCast:
+		return in.conversion(nil, convAddrSpaceCast, c.From, c.To)
+	// Other expressions.
+	case *ir.ExprICmp:
+		return in.icmp(nil, c.Pred, c.X, c.Y)
+	case *ir.ExprFCmp:
+		return in.fcmp(nil, c.Pred, c.X, c.Y)
+	case *ir.ExprSelect:
+		return in.selectValue(nil, c.Cond, c.X, c.Y)
+	default:
+		return Value{}, errors.Errorf("support for constant %T not yet implemented", c)
+	}
+}
+
+// evalConsts returns an aggregate value with the values of the given constants
+// as elements.
+func (in *Interpreter) evalConsts(cs []ir.Constant) (Value, error) {
+	elems := make([]Value, len(cs))
+	for i, c := range cs {
+		v, err := in.evalConst(c)
+		if err != nil {
+			return Value{}, errors.WithStack(err)
+		}
+		elems[i] = v
+	}
+	return Value{Elems: elems}, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// maxUint64 is the maximum 64-bit unsigned integer.
+var maxUint64 = new(big.Int).SetUint64(math.MaxUint64)
+
+// incoming returns the incoming value of the given phi instruction from the
+// given predecessor basic block. The boolean return value indicates success.
+func incoming(phi *ir.InstPhi, pred *ir.BasicBlock) (value.Value, bool) {
+	for _, inc := range phi.Incs {
+		if inc.Pred == pred {
+			return inc.X, true
+		}
+	}
+	return nil, false
+}
+
+// blockIdent returns the identifier of the given basic block; or "<nil>" if
+// nil.
+func blockIdent(block *ir.BasicBlock) string {
+	if block == nil {
+		return "<nil>"
+	}
+	return block.Ident()
+}
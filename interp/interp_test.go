@@ -0,0 +1,147 @@
+package interp
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+func TestCall(t *testing.T) {
+	m := &ir.Module{}
+	// fact computes n! iteratively.
+	//
+	//    define i32 @fact(i32 %n) {
+	//    entry:
+	//       br label %loop
+	//    loop:
+	//       %i = phi i32 [ %n, %entry ], [ %i.next, %loop ]
+	//       %acc = phi i32 [ 1, %entry ], [ %acc.next, %loop ]
+	//       %acc.next = mul i32 %acc, %i
+	//       %i.next = sub i32 %i, 1
+	//       %cond = icmp sgt i32 %i.next, 1
+	//       br i1 %cond, label %loop, label %exit
+	//    exit:
+	//       ret i32 %acc.next
+	//    }
+	n := ir.NewParam(types.I32, "n")
+	fact := m.NewFunc("fact", types.I32, n)
+	entry, loop, exit := ir.NewBlock("entry"), ir.NewBlock("loop"), ir.NewBlock("exit")
+	entry.NewBr(loop)
+	i := loop.NewPhi(ir.NewIncoming(n, entry))
+	acc := loop.NewPhi(ir.NewIncoming(ir.NewInt(types.I32, 1), entry))
+	accNext := loop.NewMul(acc, i)
+	iNext := loop.NewSub(i, ir.NewInt(types.I32, 1))
+	i.Incs = append(i.Incs, ir.NewIncoming(iNext, loop))
+	acc.Incs = append(acc.Incs, ir.NewIncoming(accNext, loop))
+	loop.NewCondBr(loop.NewICmp(enum.IPredSGT, iNext, ir.NewInt(types.I32, 1)), loop, exit)
+	exit.NewRet(accNext)
+	fact.Blocks = append(fact.Blocks, entry, loop, exit)
+	// fib computes the n:th Fibonacci number recursively.
+	x := ir.NewParam(types.I64, "x")
+	fib := m.NewFunc("fib", types.I64, x)
+	entry, rec, base := ir.NewBlock("entry"), ir.NewBlock("rec"), ir.NewBlock("base")
+	entry.NewCondBr(entry.NewICmp(enum.IPredSLT, x, ir.NewInt(types.I64, 2)), base, rec)
+	a := rec.NewCall(fib, rec.NewSub(x, ir.NewInt(types.I64, 1)))
+	b := rec.NewCall(fib, rec.NewSub(x, ir.NewInt(types.I64, 2)))
+	rec.NewRet(rec.NewAdd(a, b))
+	base.NewRet(x)
+	fib.Blocks = append(fib.Blocks, entry, rec, base)
+	// field stores y to the second field of a stack allocated { i8, i32 } and
+	// to the global variable @g, and returns the sum of both loaded values.
+	g := m.NewGlobalDef("g", ir.NewInt(types.I32, 10))
+	y := ir.NewParam(types.I32, "y")
+	field := m.NewFunc("field", types.I32, y)
+	entry = ir.NewBlock("entry")
+	st := types.NewStruct(types.I8, types.I32)
+	p := entry.NewAlloca(st)
+	q := entry.NewGetElementPtr(st, p, ir.NewInt(types.I64, 0), ir.NewInt(types.I32, 1))
+	entry.NewStore(y, q)
+	old := entry.NewLoad(g)
+	entry.NewStore(entry.NewAdd(old, y), g)
+	entry.NewRet(entry.NewAdd(entry.NewLoad(q), entry.NewLoad(g)))
+	field.Blocks = append(field.Blocks, entry)
+	// avg returns the average of two integers as a double.
+	u := ir.NewParam(types.I32, "u")
+	v := ir.NewParam(types.I32, "v")
+	avg := m.NewFunc("avg", types.Double, u, v)
+	entry = ir.NewBlock("entry")
+	sum := entry.NewSIToFP(entry.NewAdd(u, v), types.Double)
+	entry.NewRet(entry.NewFDiv(sum, ir.NewFloat(types.Double, 2)))
+	avg.Blocks = append(avg.Blocks, entry)
+	// div returns u / v.
+	u = ir.NewParam(types.I32, "u")
+	v = ir.NewParam(types.I32, "v")
+	div := m.NewFunc("div", types.I32, u, v)
+	entry = ir.NewBlock("entry")
+	entry.NewRet(entry.NewSDiv(u, v))
+	div.Blocks = append(div.Blocks, entry)
+	// neg returns -x.
+	x = ir.NewParam(types.Double, "x")
+	neg := m.NewFunc("neg", types.Double, x)
+	entry = ir.NewBlock("entry")
+	entry.NewRet(entry.NewFNeg(x))
+	neg.Blocks = append(neg.Blocks, entry)
+
+	in, err := New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := []struct {
+		name string
+		args []Value
+		want Value
+		err  string
+	}{
+		// i=0
+		{name: "fact", args: []Value{{Int: 5}}, want: Value{Int: 120}},
+		// i=1
+		{name: "fib", args: []Value{{Int: 10}}, want: Value{Int: 55}},
+		// i=2
+		{name: "field", args: []Value{{Int: 7}}, want: Value{Int: 24}},
+		// i=3
+		{name: "avg", args: []Value{{Int: 3}, {Int: 0xFFFFFFFF}}, want: Value{Float: 1}},
+		// i=4
+		{name: "div", args: []Value{{Int: 0xFFFFFFF9}, {Int: 2}}, want: Value{Int: 0xFFFFFFFD}},
+		// i=5
+		{name: "div", args: []Value{{Int: 1}, {Int: 0}}, err: "function @div: integer division by zero"},
+		// i=6
+		{name: "fact", args: nil, err: "invalid number of arguments in call to @fact; expected 1, got 0"},
+		// i=7
+		{name: "neg", args: []Value{{Float: 1.5}}, want: Value{Float: -1.5}},
+	}
+	for i, gold := range golden {
+		got, err := in.Call(gold.name, gold.args...)
+		if len(gold.err) > 0 {
+			if err == nil || err.Error() != gold.err {
+				t.Errorf("i=%d: error mismatch; expected %q, got %v", i, gold.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("i=%d: unable to call %q; %v", i, gold.name, err)
+			continue
+		}
+		if got.Int != gold.want.Int || got.Float != gold.want.Float {
+			t.Errorf("i=%d: return value mismatch of %q; expected %+v, got %+v", i, gold.name, gold.want, got)
+		}
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	m := &ir.Module{}
+	f := m.NewFunc("loop", types.Void)
+	entry := ir.NewBlock("entry")
+	entry.NewBr(entry)
+	f.Blocks = append(f.Blocks, entry)
+	in, err := New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.MaxSteps = 1000
+	if _, err := in.Call("loop"); errors.Cause(err) != ErrStepLimit {
+		t.Errorf("error mismatch; expected %v, got %v", ErrStepLimit, err)
+	}
+}
@@ -0,0 +1,213 @@
+package interp
+
+import (
+	"math"
+
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// --- [ Memory ] --------------------------------------------------------------
+
+// nullSize is the number of bytes reserved at address 0, so that accesses
+// through null pointers and small offsets from null pointers are detected.
+const nullSize = 16
+
+// Alloc allocates size bytes of zeroed memory, suitably aligned for any type,
+// and returns its address. Allocated memory is never freed.
+func (in *Interpreter) Alloc(size int64) Pointer {
+	return Pointer(in.alloc(size, 16))
+}
+
+// ReadBytes returns a copy of n bytes of memory at the given address.
+func (in *Interpreter) ReadBytes(p Pointer, n int64) ([]byte, error) {
+	buf, err := in.access(uint64(p), n)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append([]byte(nil), buf...), nil
+}
+
+// ReadString returns the NUL-terminated string at the given address.
+func (in *Interpreter) ReadString(p Pointer) (string, error) {
+	for end := uint64(p); ; end++ {
+		buf, err := in.access(end, 1)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if buf[0] == 0 {
+			return string(in.mem[p:end]), nil
+		}
+	}
+}
+
+// WriteBytes writes the given bytes to memory at the given address.
+func (in *Interpreter) WriteBytes(p Pointer, b []byte) error {
+	buf, err := in.access(uint64(p), int64(len(b)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	copy(buf, b)
+	return nil
+}
+
+// alloc allocates size bytes of zeroed memory with the given alignment, and
+// returns its address.
+func (in *Interpreter) alloc(size, align int64) uint64 {
+	addr := int64(len(in.mem))
+	if align > 1 {
+		addr = (addr + align - 1) / align * align
+	}
+	end := addr + size
+	if end == addr {
+		// Give zero-sized allocations distinct addresses.
+		end++
+	}
+	in.mem = append(in.mem, make([]byte, end-int64(len(in.mem)))...)
+	return uint64(addr)
+}
+
+// access returns the n bytes of memory at the given address.
+func (in *Interpreter) access(addr uint64, n int64) ([]byte, error) {
+	if addr < nullSize || n < 0 || addr+uint64(n) > uint64(len(in.mem)) || addr+uint64(n) < addr {
+		return nil, errors.Errorf("invalid memory access of %d bytes at address 0x%X", n, addr)
+	}
+	return in.mem[addr : addr+uint64(n)], nil
+}
+
+// load returns the value of the given type stored at the given address.
+func (in *Interpreter) load(addr uint64, t types.Type) (Value, error) {
+	switch t := t.(type) {
+	case *types.IntType, *types.PointerType:
+		x, err := in.loadInt(addr, in.Layout.TypeStoreSize(t))
+		return Value{Int: mask(x, in.bitSize(t))}, err
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindFloat:
+			x, err := in.loadInt(addr, 4)
+			return Value{Float: float64(math.Float32frombits(uint32(x)))}, err
+		case types.FloatKindDouble:
+			x, err := in.loadInt(addr, 8)
+			return Value{Float: math.Float64frombits(x)}, err
+		}
+	case *types.ArrayType:
+		return in.loadElems(addr, t.ElemType, t.Len)
+	case *types.VectorType:
+		return in.loadElems(addr, t.ElemType, t.Len)
+	case *types.StructType:
+		if t.Opaque {
+			break
+		}
+		layout := in.Layout.StructLayout(t)
+		elems := make([]Value, len(t.Fields))
+		for i, field := range t.Fields {
+			v, err := in.load(addr+uint64(layout.Offsets[i]), field)
+			if err != nil {
+				return Value{}, errors.WithStack(err)
+			}
+			elems[i] = v
+		}
+		return Value{Elems: elems}, nil
+	}
+	return Value{}, errors.Errorf("support for loading values of type %v not yet implemented", t)
+}
+
+// loadElems returns the n consecutive values of the given element type stored
+// at the given address.
+func (in *Interpreter) loadElems(addr uint64, elemType types.Type, n int64) (Value, error) {
+	size := uint64(in.Layout.TypeAllocSize(elemType))
+	return elementwise(int(n), func(i int) (Value, error) {
+		return in.load(addr+uint64(i)*size, elemType)
+	})
+}
+
+// loadInt returns the integer of the given size in bytes stored at the given
+// address.
+func (in *Interpreter) loadInt(addr uint64, size int64) (uint64, error) {
+	if size > 8 {
+		return 0, errors.Errorf("support for loading integers wider than 64 bits not yet implemented")
+	}
+	buf, err := in.access(addr, size)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	var x uint64
+	for i := range buf {
+		b := buf[i]
+		if in.Layout.IsBigEndian() {
+			b = buf[len(buf)-1-i]
+		}
+		x |= uint64(b) << (8 * uint(i))
+	}
+	return x, nil
+}
+
+// store stores the given value of the given type at the given address.
+func (in *Interpreter) store(addr uint64, t types.Type, v Value) error {
+	switch t := t.(type) {
+	case *types.IntType, *types.PointerType:
+		return in.storeInt(addr, in.Layout.TypeStoreSize(t), v.Int)
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindFloat:
+			return in.storeInt(addr, 4, uint64(math.Float32bits(float32(v.Float))))
+		case types.FloatKindDouble:
+			return in.storeInt(addr, 8, math.Float64bits(v.Float))
+		}
+	case *types.ArrayType:
+		return in.storeElems(addr, t.ElemType, t.Len, v)
+	case *types.VectorType:
+		return in.storeElems(addr, t.ElemType, t.Len, v)
+	case *types.StructType:
+		if t.Opaque {
+			break
+		}
+		if len(v.Elems) != len(t.Fields) {
+			return errors.Errorf("invalid struct value; expected %d fields, got %d", len(t.Fields), len(v.Elems))
+		}
+		layout := in.Layout.StructLayout(t)
+		for i, field := range t.Fields {
+			if err := in.store(addr+uint64(layout.Offsets[i]), field, v.Elems[i]); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}
+	return errors.Errorf("support for storing values of type %v not yet implemented", t)
+}
+
+// storeElems stores the n elements of the given value of the given element
+// type consecutively at the given address.
+func (in *Interpreter) storeElems(addr uint64, elemType types.Type, n int64, v Value) error {
+	if int64(len(v.Elems)) != n {
+		return errors.Errorf("invalid array or vector value; expected %d elements, got %d", n, len(v.Elems))
+	}
+	size := uint64(in.Layout.TypeAllocSize(elemType))
+	for i, elem := range v.Elems {
+		if err := in.store(addr+uint64(i)*size, elemType, elem); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// storeInt stores the given integer of the given size in bytes at the given
+// address.
+func (in *Interpreter) storeInt(addr uint64, size int64, x uint64) error {
+	if size > 8 {
+		return errors.Errorf("support for storing integers wider than 64 bits not yet implemented")
+	}
+	buf, err := in.access(addr, size)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for i := range buf {
+		b := byte(x >> (8 * uint(i)))
+		if in.Layout.IsBigEndian() {
+			buf[len(buf)-1-i] = b
+		} else {
+			buf[i] = b
+		}
+	}
+	return nil
+}
@@ -0,0 +1,725 @@
+package interp
+
+import (
+	"math"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// --- [ Unary operations ] ----------------------------------------------------
+
+// fneg returns the negation of the given floating-point operand.
+func (in *Interpreter) fneg(fr *frame, x value.Value) (Value, error) {
+	xv, err := in.eval(fr, x)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	return fnegOp(x.Type(), xv)
+}
+
+// fnegOp returns the negation of the given floating-point operand of the given
+// type; the sign bit is flipped, also of zeros and NaNs.
+func fnegOp(t types.Type, x Value) (Value, error) {
+	switch t := t.(type) {
+	case *types.FloatType:
+		return Value{Float: -x.Float}, nil
+	case *types.VectorType:
+		return elementwise(len(x.Elems), func(i int) (Value, error) {
+			return fnegOp(t.ElemType, x.Elems[i])
+		})
+	default:
+		return Value{}, errors.Errorf("invalid operand type of fneg; expected floating-point or vector type, got %v", t)
+	}
+}
+
+// --- [ Binary and bitwise operations ] ---------------------------------------
+
+// binOp is a binary or bitwise operation.
+type binOp uint8
+
+// Binary and bitwise operations.
+const (
+	opAdd binOp = iota
+	opFAdd
+	opSub
+	opFSub
+	opMul
+	opFMul
+	opUDiv
+	opSDiv
+	opFDiv
+	opURem
+	opSRem
+	opFRem
+	opShl
+	opLShr
+	opAShr
+	opAnd
+	opOr
+	opXor
+)
+
+// binary returns the result of the given binary or bitwise operation on the
+// given operands.
+func (in *Interpreter) binary(fr *frame, op binOp, x, y value.Value) (Value, error) {
+	xv, err := in.eval(fr, x)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	yv, err := in.eval(fr, y)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	return binaryOp(op, x.Type(), xv, yv)
+}
+
+// binaryOp returns the result of the given binary or bitwise operation on
+// operands of the given type.
+func binaryOp(op binOp, t types.Type, x, y Value) (Value, error) {
+	switch t := t.(type) {
+	case *types.IntType:
+		if t.BitSize > 64 {
+			return Value{}, errors.Errorf("support for integers wider than 64 bits not yet implemented; got %v", t)
+		}
+		return intOp(op, t.BitSize, x.Int, y.Int)
+	case *types.FloatType:
+		return floatOp(op, t, x.Float, y.Float)
+	case *types.VectorType:
+		return elementwise(len(x.Elems), func(i int) (Value, error) {
+			return binaryOp(op, t.ElemType, x.Elems[i], y.Elems[i])
+		})
+	default:
+		return Value{}, errors.Errorf("invalid operand type of binary operation; expected integer, floating-point or vector type, got %v", t)
+	}
+}
+
+// intOp returns the result of the given binary or bitwise operation on integer
+// operands of the given bit size.
+func intOp(op binOp, bits int64, x, y uint64) (Value, error) {
+	var z uint64
+	switch op {
+	case opAdd:
+		z = x + y
+	case opSub:
+		z = x - y
+	case opMul:
+		z = x * y
+	case opUDiv, opURem:
+		if y == 0 {
+			return Value{}, errors.New("integer division by zero")
+		}
+		if op == opUDiv {
+			z = x / y
+		} else {
+			z = x % y
+		}
+	case opSDiv, opSRem:
+		sx, sy := sext(x, bits), sext(y, bits)
+		if sy == 0 {
+			return Value{}, errors.New("integer division by zero")
+		}
+		if sy == -1 && sx == sext(1<<uint(bits-1), bits) {
+			return Value{}, errors.New("signed integer division overflow")
+		}
+		if op == opSDiv {
+			z = uint64(sx / sy)
+		} else {
+			z = uint64(sx % sy)
+		}
+	case opShl, opLShr, opAShr:
+		// Shift amounts of at least the bit size produce poison values; use 0.
+		if y >= uint64(bits) {
+			return Value{}, nil
+		}
+		switch op {
+		case opShl:
+			z = x << y
+		case opLShr:
+			z = x >> y
+		case opAShr:
+			z = uint64(sext(x, bits) >> y)
+		}
+	case opAnd:
+		z = x & y
+	case opOr:
+		z = x | y
+	case opXor:
+		z = x ^ y
+	default:
+		return Value{}, errors.Errorf("invalid integer operation %d", op)
+	}
+	return Value{Int: mask(z, bits)}, nil
+}
+
+// floatOp returns the result of the given binary operation on floating-point
+// operands of the given type.
+func floatOp(op binOp, t *types.FloatType, x, y float64) (Value, error) {
+	var z float64
+	switch op {
+	case opFAdd:
+		z = x + y
+	case opFSub:
+		z = x - y
+	case opFMul:
+		z = x * y
+	case opFDiv:
+		z = x / y
+	case opFRem:
+		z = math.Mod(x, y)
+	default:
+		return Value{}, errors.Errorf("invalid floating-point operation %d", op)
+	}
+	return Value{Float: roundFloat(t, z)}, nil
+}
+
+// --- [ Conversion operations ] -----------------------------------------------
+
+// convOp is a conversion operation.
+type convOp uint8
+
+// Conversion operations.
+const (
+	convTrunc convOp = iota
+	convZExt
+	convSExt
+	convFPTrunc
+	convFPExt
+	convFPToUI
+	convFPToSI
+	convUIToFP
+	convSIToFP
+	convPtrToInt
+	convIntToPtr
+	convBitCast
+	convAddrSpaceCast
+)
+
+// conversion returns the result of the given conversion of the given value to
+// the given type.
+func (in *Interpreter) conversion(fr *frame, op convOp, from value.Value, to types.Type) (Value, error) {
+	x, err := in.eval(fr, from)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	return in.convertOp(op, from.Type(), to, x)
+}
+
+// convertOp returns the result of the given conversion of a value of type from
+// to type to.
+func (in *Interpreter) convertOp(op convOp, from, to types.Type, x Value) (Value, error) {
+	if fromVec, ok := from.(*types.VectorType); ok {
+		if toVec, ok := to.(*types.VectorType); ok && fromVec.Len == toVec.Len {
+			return elementwise(len(x.Elems), func(i int) (Value, error) {
+				return in.convertOp(op, fromVec.ElemType, toVec.ElemType, x.Elems[i])
+			})
+		}
+		if op != convBitCast {
+			return Value{}, errors.Errorf("invalid conversion from %v to %v", from, to)
+		}
+	}
+	switch op {
+	case convTrunc, convZExt, convPtrToInt, convIntToPtr:
+		return Value{Int: mask(x.Int, in.bitSize(to))}, nil
+	case convSExt:
+		return Value{Int: mask(uint64(sext(x.Int, in.bitSize(from))), in.bitSize(to))}, nil
+	case convFPTrunc, convFPExt:
+		return Value{Float: roundFloat(to, x.Float)}, nil
+	case convFPToUI:
+		if x.Float >= math.MaxInt64 {
+			return Value{Int: mask(uint64(x.Float), in.bitSize(to))}, nil
+		}
+		return Value{Int: mask(uint64(int64(x.Float)), in.bitSize(to))}, nil
+	case convFPToSI:
+		return Value{Int: mask(uint64(int64(x.Float)), in.bitSize(to))}, nil
+	case convUIToFP:
+		return Value{Float: roundFloat(to, float64(x.Int))}, nil
+	case convSIToFP:
+		return Value{Float: roundFloat(to, float64(sext(x.Int, in.bitSize(from))))}, nil
+	case convBitCast:
+		return bitCast(from, to, x)
+	case convAddrSpaceCast:
+		return x, nil
+	default:
+		return Value{}, errors.Errorf("invalid conversion operation %d", op)
+	}
+}
+
+// bitCast returns the given value of type from reinterpreted as type to.
+func bitCast(from, to types.Type, x Value) (Value, error) {
+	fromFloat, isFromFloat := from.(*types.FloatType)
+	toFloat, isToFloat := to.(*types.FloatType)
+	switch {
+	case isFromFloat && isToFloat:
+		return x, nil
+	case isFromFloat:
+		switch fromFloat.Kind {
+		case types.FloatKindFloat:
+			return Value{Int: uint64(math.Float32bits(float32(x.Float)))}, nil
+		case types.FloatKindDouble:
+			return Value{Int: math.Float64bits(x.Float)}, nil
+		}
+	case isToFloat:
+		switch toFloat.Kind {
+		case types.FloatKindFloat:
+			return Value{Float: float64(math.Float32frombits(uint32(x.Int)))}, nil
+		case types.FloatKindDouble:
+			return Value{Float: math.Float64frombits(x.Int)}, nil
+		}
+	default:
+		_, isFromVec := from.(*types.VectorType)
+		_, isToVec := to.(*types.VectorType)
+		if !isFromVec && !isToVec {
+			// Integer to integer or pointer to pointer.
+			return x, nil
+		}
+	}
+	return Value{}, errors.Errorf("support for bitcast from %v to %v not yet implemented", from, to)
+}
+
+// --- [ Comparison operations ] -----------------------------------------------
+
+// icmp returns the result of the given integer comparison of the given
+// operands.
+func (in *Interpreter) icmp(fr *frame, pred enum.IPred, x, y value.Value) (Value, error) {
+	xv, err := in.eval(fr, x)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	yv, err := in.eval(fr, y)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	return in.icmpOp(pred, x.Type(), xv, yv)
+}
+
+// icmpOp returns the result of the given integer comparison of operands of
+// the given type.
+func (in *Interpreter) icmpOp(pred enum.IPred, t types.Type, x, y Value) (Value, error) {
+	if t, ok := t.(*types.VectorType); ok {
+		return elementwise(len(x.Elems), func(i int) (Value, error) {
+			return in.icmpOp(pred, t.ElemType, x.Elems[i], y.Elems[i])
+		})
+	}
+	bits := in.bitSize(t)
+	sx, sy := sext(x.Int, bits), sext(y.Int, bits)
+	var z bool
+	switch pred {
+	case enum.IPredEQ:
+		z = x.Int == y.Int
+	case enum.IPredNE:
+		z = x.Int != y.Int
+	case enum.IPredSGE:
+		z = sx >= sy
+	case enum.IPredSGT:
+		z = sx > sy
+	case enum.IPredSLE:
+		z = sx <= sy
+	case enum.IPredSLT:
+		z = sx < sy
+	case enum.IPredUGE:
+		z = x.Int >= y.Int
+	case enum.IPredUGT:
+		z = x.Int > y.Int
+	case enum.IPredULE:
+		z = x.Int <= y.Int
+	case enum.IPredULT:
+		z = x.Int < y.Int
+	default:
+		return Value{}, errors.Errorf("support for integer predicate %v not yet implemented", pred)
+	}
+	return boolValue(z), nil
+}
+
+// fcmp returns the result of the given floating-point comparison of the given
+// operands.
+func (in *Interpreter) fcmp(fr *frame, pred enum.FPred, x, y value.Value) (Value, error) {
+	xv, err := in.eval(fr, x)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	yv, err := in.eval(fr, y)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	return fcmpOp(pred, xv, yv)
+}
+
+// fcmpOp returns the result of the given floating-point comparison of the
+// given scalar or vector operands.
+func fcmpOp(pred enum.FPred, x, y Value) (Value, error) {
+	if x.Elems != nil {
+		return elementwise(len(x.Elems), func(i int) (Value, error) {
+			return fcmpOp(pred, x.Elems[i], y.Elems[i])
+		})
+	}
+	unordered := math.IsNaN(x.Float) || math.IsNaN(y.Float)
+	var z bool
+	switch pred {
+	case enum.FPredFalse:
+		z = false
+	case enum.FPredOEQ:
+		z = x.Float == y.Float
+	case enum.FPredOGE:
+		z = x.Float >= y.Float
+	case enum.FPredOGT:
+		z = x.Float > y.Float
+	case enum.FPredOLE:
+		z = x.Float <= y.Float
+	case enum.FPredOLT:
+		z = x.Float < y.Float
+	case enum.FPredONE:
+		z = !unordered && x.Float != y.Float
+	case enum.FPredORD:
+		z = !unordered
+	case enum.FPredTrue:
+		z = true
+	case enum.FPredUEQ:
+		z = unordered || x.Float == y.Float
+	case enum.FPredUGE:
+		z = unordered || x.Float >= y.Float
+	case enum.FPredUGT:
+		z = unordered || x.Float > y.Float
+	case enum.FPredULE:
+		z = unordered || x.Float <= y.Float
+	case enum.FPredULT:
+		z = unordered || x.Float < y.Float
+	case enum.FPredUNE:
+		z = x.Float != y.Float
+	case enum.FPredUNO:
+		z = unordered
+	default:
+		return Value{}, errors.Errorf("support for floating-point predicate %v not yet implemented", pred)
+	}
+	return boolValue(z), nil
+}
+
+// selectValue returns x if cond is true and y otherwise, elementwise for
+// vector conditions.
+func (in *Interpreter) selectValue(fr *frame, cond, x, y value.Value) (Value, error) {
+	c, err := in.eval(fr, cond)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	xv, err := in.eval(fr, x)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	yv, err := in.eval(fr, y)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	if c.Elems == nil {
+		if c.Int&1 != 0 {
+			return xv, nil
+		}
+		return yv, nil
+	}
+	return elementwise(len(c.Elems), func(i int) (Value, error) {
+		if c.Elems[i].Int&1 != 0 {
+			return xv.Elems[i], nil
+		}
+		return yv.Elems[i], nil
+	})
+}
+
+// --- [ Vector and aggregate operations ] -------------------------------------
+
+// extractElement returns the element at the given index of the given vector.
+func (in *Interpreter) extractElement(fr *frame, x, index value.Value) (Value, error) {
+	xv, err := in.eval(fr, x)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	i, err := in.eval(fr, index)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	if i.Int >= uint64(len(xv.Elems)) {
+		// Out of bounds indices produce poison values.
+		return Value{}, nil
+	}
+	return xv.Elems[i.Int], nil
+}
+
+// insertElement returns a copy of the given vector with the element at the
+// given index replaced by elem.
+func (in *Interpreter) insertElement(fr *frame, x, elem, index value.Value) (Value, error) {
+	xv, err := in.eval(fr, x)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	e, err := in.eval(fr, elem)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	i, err := in.eval(fr, index)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	elems := append([]Value(nil), xv.Elems...)
+	if i.Int < uint64(len(elems)) {
+		elems[i.Int] = e
+	}
+	return Value{Elems: elems}, nil
+}
+
+// shuffleVector returns the vector of elements selected by the given mask from
+// the concatenation of the vectors x and y.
+func (in *Interpreter) shuffleVector(fr *frame, x, y, mask value.Value) (Value, error) {
+	xv, err := in.eval(fr, x)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	yv, err := in.eval(fr, y)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	m, err := in.eval(fr, mask)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	src := append(append([]Value(nil), xv.Elems...), yv.Elems...)
+	return elementwise(len(m.Elems), func(i int) (Value, error) {
+		j := m.Elems[i].Int
+		if j >= uint64(len(src)) {
+			return Value{}, nil
+		}
+		return src[j], nil
+	})
+}
+
+// extractValue returns the element at the given indices of the given aggregate.
+func (in *Interpreter) extractValue(fr *frame, x value.Value, indices []int64) (Value, error) {
+	v, err := in.eval(fr, x)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	for _, index := range indices {
+		if index < 0 || index >= int64(len(v.Elems)) {
+			return Value{}, errors.Errorf("aggregate index %d out of bounds", index)
+		}
+		v = v.Elems[index]
+	}
+	return v, nil
+}
+
+// insertValue returns a copy of the given aggregate with the element at the
+// given indices replaced by elem.
+func (in *Interpreter) insertValue(fr *frame, x, elem value.Value, indices []int64) (Value, error) {
+	v, err := in.eval(fr, x)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	e, err := in.eval(fr, elem)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	return replaceElem(v, indices, e)
+}
+
+// replaceElem returns a copy of the given aggregate with the element at the
+// given indices replaced by elem.
+func replaceElem(v Value, indices []int64, elem Value) (Value, error) {
+	if len(indices) == 0 {
+		return elem, nil
+	}
+	index := indices[0]
+	if index < 0 || index >= int64(len(v.Elems)) {
+		return Value{}, errors.Errorf("aggregate index %d out of bounds", index)
+	}
+	elems := append([]Value(nil), v.Elems...)
+	e, err := replaceElem(elems[index], indices[1:], elem)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	elems[index] = e
+	return Value{Elems: elems}, nil
+}
+
+// --- [ Memory operations ] ---------------------------------------------------
+
+// alloca executes the given alloca instruction.
+func (in *Interpreter) alloca(fr *frame, inst *ir.InstAlloca) (Value, error) {
+	n := int64(1)
+	if inst.NElems != nil {
+		v, err := in.eval(fr, inst.NElems)
+		if err != nil {
+			return Value{}, errors.WithStack(err)
+		}
+		n = sext(v.Int, in.bitSize(inst.NElems.Type()))
+		if n < 0 {
+			return Value{}, errors.Errorf("invalid number of elements in alloca; expected >= 0, got %d", n)
+		}
+	}
+	if !in.Layout.IsSized(inst.ElemType) {
+		return Value{}, errors.Errorf("invalid alloca of unsized type %v", inst.ElemType)
+	}
+	align := in.Layout.ABIAlign(inst.ElemType)
+	if int64(inst.Alignment) > align {
+		align = int64(inst.Alignment)
+	}
+	return Value{Int: in.alloc(n*in.Layout.TypeAllocSize(inst.ElemType), align)}, nil
+}
+
+// cmpXchg executes the given cmpxchg instruction.
+func (in *Interpreter) cmpXchg(fr *frame, inst *ir.InstCmpXchg) (Value, error) {
+	ptr, err := in.eval(fr, inst.Ptr)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	cmp, err := in.eval(fr, inst.Cmp)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	newVal, err := in.eval(fr, inst.New)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	t := inst.Cmp.Type()
+	old, err := in.load(ptr.Int, t)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	success := old.Int == cmp.Int
+	if success {
+		if err := in.store(ptr.Int, t, newVal); err != nil {
+			return Value{}, errors.WithStack(err)
+		}
+	}
+	return Value{Elems: []Value{old, boolValue(success)}}, nil
+}
+
+// gep returns the address computed by a getelementptr with the given source
+// element type, source address and indices.
+func (in *Interpreter) gep(fr *frame, elemType types.Type, src value.Value, indices []value.Value) (Value, error) {
+	base, err := in.eval(fr, src)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	idxs := make([]int64, len(indices))
+	for i, index := range indices {
+		v, err := in.eval(fr, index)
+		if err != nil {
+			return Value{}, errors.WithStack(err)
+		}
+		if v.Elems != nil {
+			return Value{}, errors.Errorf("support for vector getelementptr indices not yet implemented")
+		}
+		idxs[i] = sext(v.Int, in.bitSize(index.Type()))
+	}
+	offset, err := in.Layout.IndexedOffset(elemType, idxs)
+	if err != nil {
+		return Value{}, errors.WithStack(err)
+	}
+	return Value{Int: mask(base.Int+uint64(offset), in.bitSize(src.Type()))}, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// elementwise returns a vector or aggregate value of n elements computed by f.
+func elementwise(n int, f func(i int) (Value, error)) (Value, error) {
+	elems := make([]Value, n)
+	for i := range elems {
+		v, err := f(i)
+		if err != nil {
+			return Value{}, errors.WithStack(err)
+		}
+		elems[i] = v
+	}
+	return Value{Elems: elems}, nil
+}
+
+// bitSize returns the size in bits of the given integer or pointer type.
+func (in *Interpreter) bitSize(t types.Type) int64 {
+	switch t := t.(type) {
+	case *types.IntType:
+		return t.BitSize
+	case *types.PointerType:
+		return int64(in.Layout.PointerSizeInBits(int(t.AddrSpace)))
+	default:
+		return 64
+	}
+}
+
+// mask returns x truncated to the given number of bits.
+func mask(x uint64, bits int64) uint64 {
+	if bits >= 64 {
+		return x
+	}
+	return x & (1<<uint(bits) - 1)
+}
+
+// sext returns x sign-extended from the given number of bits.
+func sext(x uint64, bits int64) int64 {
+	if bits >= 64 || bits <= 0 {
+		return int64(x)
+	}
+	shift := uint(64 - bits)
+	return int64(x<<shift) >> shift
+}
+
+// roundFloat returns x rounded to the precision of the given floating-point
+// type.
+func roundFloat(t types.Type, x float64) float64 {
+	if t, ok := t.(*types.FloatType); ok && t.Kind == types.FloatKindFloat {
+		return float64(float32(x))
+	}
+	return x
+}
+
+// boolValue returns the i1 value of the given boolean.
+func boolValue(x bool) Value {
+	if x {
+		return Value{Int: 1}
+	}
+	return Value{}
+}
+
+// zero returns the zero value of the given type.
+func zero(t types.Type) Value {
+	switch t := t.(type) {
+	case *types.ArrayType:
+		return Value{Elems: zeros(t.ElemType, t.Len)}
+	case *types.VectorType:
+		return Value{Elems: zeros(t.ElemType, t.Len)}
+	case *types.StructType:
+		elems := make([]Value, len(t.Fields))
+		for i, field := range t.Fields {
+			elems[i] = zero(field)
+		}
+		return Value{Elems: elems}
+	default:
+		return Value{}
+	}
+}
+
+// zeros returns n zero values of the given type.
+func zeros(t types.Type, n int64) []Value {
+	elems := make([]Value, n)
+	for i := range elems {
+		elems[i] = zero(t)
+	}
+	return elems
+}
+
+// normalize returns the given value truncated or rounded to the given type.
+func normalize(t types.Type, v Value) Value {
+	switch t := t.(type) {
+	case *types.IntType:
+		return Value{Int: mask(v.Int, t.BitSize)}
+	case *types.FloatType:
+		return Value{Float: roundFloat(t, v.Float)}
+	case *types.VectorType:
+		elems := make([]Value, len(v.Elems))
+		for i, elem := range v.Elems {
+			elems[i] = normalize(t.ElemType, elem)
+		}
+		return Value{Elems: elems}
+	default:
+		return v
+	}
+}
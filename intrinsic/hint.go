@@ -0,0 +1,141 @@
+package intrinsic
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Optimization hint intrinsics ] ========================================
+
+// --- [ assume ] --------------------------------------------------------------
+
+// NewAssume appends to the basic block a call to the llvm.assume intrinsic,
+// which lets the optimizer assume that the condition holds, together with the
+// assumptions of the given operand bundles (e.g. AlignBundle). The condition
+// may be nil for assumptions stated only by operand bundles, in which case it
+// is true.
+func NewAssume(m *ir.Module, block *ir.BasicBlock, cond value.Value, bundles ...*ir.OperandBundle) (*ir.InstCall, error) {
+	if cond == nil {
+		cond = ir.True
+	}
+	if !cond.Type().Equal(types.I1) {
+		return nil, errors.Errorf("invalid condition type of llvm.assume; expected i1, got %v", cond.Type())
+	}
+	inst, err := newCall(m, block, "llvm.assume", types.NewFunc(types.Void, types.I1), cond)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	inst.OperandBundles = bundles
+	return inst, nil
+}
+
+// AlignBundle returns an "align" operand bundle of llvm.assume, stating that
+// ptr is aligned to the given number of bytes.
+func AlignBundle(ptr value.Value, align int64) *ir.OperandBundle {
+	return ir.NewOperandBundle("align", ptr, ir.NewInt(types.I64, align))
+}
+
+// NonNullBundle returns a "nonnull" operand bundle of llvm.assume, stating that
+// ptr is not null.
+func NonNullBundle(ptr value.Value) *ir.OperandBundle {
+	return ir.NewOperandBundle("nonnull", ptr)
+}
+
+// DereferenceableBundle returns a "dereferenceable" operand bundle of
+// llvm.assume, stating that the given number of bytes starting at ptr are
+// dereferenceable.
+func DereferenceableBundle(ptr value.Value, n int64) *ir.OperandBundle {
+	return ir.NewOperandBundle("dereferenceable", ptr, ir.NewInt(types.I64, n))
+}
+
+// --- [ expect ] --------------------------------------------------------------
+
+// NewExpect appends to the basic block a call to the llvm.expect intrinsic,
+// which returns x, and lets the optimizer expect x to be equal to the given
+// constant.
+func NewExpect(m *ir.Module, block *ir.BasicBlock, x value.Value, expected ir.Constant) (*ir.InstCall, error) {
+	typ := x.Type()
+	elem := typ
+	if vecType, ok := typ.(*types.VectorType); ok {
+		elem = vecType.ElemType
+	}
+	if _, ok := elem.(*types.IntType); !ok {
+		return nil, errors.Errorf("invalid operand type of llvm.expect; expected integer type or vector of integer types, got %v", typ)
+	}
+	if !expected.Type().Equal(typ) {
+		return nil, errors.Errorf("invalid expected value type of llvm.expect; expected %v, got %v", typ, expected.Type())
+	}
+	name := overloadName("llvm.expect", typ)
+	return newCall(m, block, name, types.NewFunc(typ, typ, typ), x, expected)
+}
+
+// --- [ annotation ] ----------------------------------------------------------
+
+// NewAnnotation appends to the basic block a call to the llvm.annotation
+// intrinsic, which returns the integer x, annotated with the given annotation
+// string and source location. The strings are stored in private global
+// variables of the "llvm.metadata" section, which are shared between
+// annotations.
+func NewAnnotation(m *ir.Module, block *ir.BasicBlock, x value.Value, annotation, file string, line int64) (*ir.InstCall, error) {
+	typ, ok := x.Type().(*types.IntType)
+	if !ok {
+		return nil, errors.Errorf("invalid operand type of llvm.annotation; expected integer type, got %v", x.Type())
+	}
+	// <int> (<int> val, i8* annotation, i8* file, i32 line)
+	name := overloadName("llvm.annotation", typ)
+	sig := types.NewFunc(typ, typ, types.I8Ptr, types.I8Ptr, types.I32)
+	return newCall(m, block, name, sig, x, annotationString(m, annotation), annotationString(m, file), ir.NewInt(types.I32, line))
+}
+
+// ### [ Helper functions ] ####################################################
+
+// annotationSection is the section of the global variables of annotation
+// strings.
+const annotationSection = "llvm.metadata"
+
+// annotationString returns a pointer to the NULL-terminated annotation string
+// s, stored in a global variable of the module.
+func annotationString(m *ir.Module, s string) ir.Constant {
+	data := append([]byte(s), 0)
+	var g *ir.Global
+	for _, global := range m.Globals {
+		if global.Section != annotationSection || !global.Immutable {
+			continue
+		}
+		if c, ok := global.Init.(*ir.ConstCharArray); ok && bytes.Equal(c.X, data) {
+			g = global
+			break
+		}
+	}
+	if g == nil {
+		g = m.NewGlobalDef(uniqueGlobalName(m, ".str"), ir.NewCharArray(data))
+		g.Linkage = enum.LinkagePrivate
+		g.UnnamedAddr = enum.UnnamedAddrUnnamedAddr
+		g.Immutable = true
+		g.Section = annotationSection
+	}
+	zero := ir.NewInt(types.I64, 0)
+	gep := ir.NewGetElementPtrExpr(g.ContentType, g, ir.NewIndex(zero), ir.NewIndex(zero))
+	gep.InBounds = true
+	return gep
+}
+
+// uniqueGlobalName returns a global name based on the given name, which is
+// unique within the module; e.g. ".str", ".str.1".
+func uniqueGlobalName(m *ir.Module, name string) string {
+	if _, ok := m.Lookup(name); !ok {
+		return name
+	}
+	for i := 1; ; i++ {
+		uniqueName := fmt.Sprintf("%s.%d", name, i)
+		if _, ok := m.Lookup(uniqueName); !ok {
+			return uniqueName
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package intrinsic
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestHints(t *testing.T) {
+	m := &ir.Module{}
+	p := ir.NewParam(types.I8Ptr, "p")
+	x := ir.NewParam(types.I64, "x")
+	f := m.NewFunc("f", types.Void, p, x)
+	entry := ir.NewBlock("entry")
+	f.Blocks = []*ir.BasicBlock{entry}
+	assume, err := NewAssume(m, entry, nil, AlignBundle(p, 16), NonNullBundle(p))
+	if err != nil {
+		t.Fatalf("unable to create llvm.assume; %+v", err)
+	}
+	expect, err := NewExpect(m, entry, x, ir.NewInt(types.I64, 1))
+	if err != nil {
+		t.Fatalf("unable to create llvm.expect; %+v", err)
+	}
+	annotation, err := NewAnnotation(m, entry, x, "hot", "foo.c", 42)
+	if err != nil {
+		t.Fatalf("unable to create llvm.annotation; %+v", err)
+	}
+	// Annotation strings are shared between annotations.
+	if _, err := NewAnnotation(m, entry, x, "cold", "foo.c", 43); err != nil {
+		t.Fatalf("unable to create llvm.annotation; %+v", err)
+	}
+	golden := []struct {
+		inst *ir.InstCall
+		want string
+	}{
+		// i=0
+		{inst: assume, want: `call void @llvm.assume(i1 true) [ "align"(i8* %p, i64 16), "nonnull"(i8* %p) ]`},
+		// i=1
+		{inst: expect, want: "call i64 @llvm.expect.i64(i64 %x, i64 1)"},
+		// i=2
+		{inst: annotation, want: `call i64 @llvm.annotation.i64(i64 %x, i8* getelementptr inbounds ([4 x i8], [4 x i8]* @.str, i64 0, i64 0), i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.str.1, i64 0, i64 0), i32 42)`},
+	}
+	for i, g := range golden {
+		if got := g.inst.Def(); g.want != got {
+			t.Errorf("i=%d: instruction mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+	}
+	if got, want := len(m.Globals), 3; got != want {
+		t.Errorf("number of global variables mismatch; expected %d, got %d", want, got)
+	}
+	want := `@.str = private unnamed_addr constant [4 x i8] c"hot\00", section "llvm.metadata"`
+	if got := m.Globals[0].Def(); want != got {
+		t.Errorf("global variable mismatch; expected `%v`, got `%v`", want, got)
+	}
+	if _, err := NewExpect(m, entry, p, ir.NewNull(types.I8Ptr)); err == nil {
+		t.Errorf("expected error for llvm.expect with pointer operand")
+	}
+}
@@ -0,0 +1,125 @@
+// Package intrinsic provides builders for calls to LLVM IR intrinsic
+// functions.
+package intrinsic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Intrinsic functions ] =================================================
+
+// Declare returns the declaration of the intrinsic function with the given
+// name (without '@' prefix) and function signature in the module. The
+// intrinsic function is declared if not already present in the module.
+func Declare(m *ir.Module, name string, sig *types.FuncType) (*ir.Function, error) {
+	if v, ok := m.Lookup(name); ok {
+		f, ok := v.(*ir.Function)
+		if !ok {
+			return nil, errors.Errorf("invalid intrinsic %v; expected function, got %T", v.Ident(), v)
+		}
+		if !f.Sig.Equal(sig) {
+			return nil, errors.Errorf("invalid signature of intrinsic %v; expected %v, got %v", f.Ident(), sig, f.Sig)
+		}
+		return f, nil
+	}
+	var params []*ir.Param
+	for _, param := range sig.Params {
+		params = append(params, ir.NewParam(param, ""))
+	}
+	f := m.NewFunc(name, sig.RetType, params...)
+	f.Sig.Variadic = sig.Variadic
+	return f, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// newCall appends to the basic block a call to the intrinsic function with the
+// given name and function signature, which is declared in the module if not
+// already present.
+func newCall(m *ir.Module, block *ir.BasicBlock, name string, sig *types.FuncType, args ...value.Value) (*ir.InstCall, error) {
+	f, err := Declare(m, name, sig)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return block.NewCall(f, args...), nil
+}
+
+// overloadName returns the name of the overloaded intrinsic function with the
+// given base name (e.g. "llvm.sqrt") and overloaded types; e.g.
+// "llvm.sqrt.f64".
+func overloadName(base string, overloads ...types.Type) string {
+	buf := &strings.Builder{}
+	buf.WriteString(base)
+	for _, t := range overloads {
+		fmt.Fprintf(buf, ".%s", mangleType(t))
+	}
+	return buf.String()
+}
+
+// mangleType returns the mangled name of the given type, as used in the names
+// of overloaded intrinsic functions.
+func mangleType(t types.Type) string {
+	switch t := t.(type) {
+	case *types.VoidType:
+		return "isVoid"
+	case *types.IntType:
+		return fmt.Sprintf("i%d", t.BitSize)
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindHalf:
+			return "f16"
+		case types.FloatKindFloat:
+			return "f32"
+		case types.FloatKindDouble:
+			return "f64"
+		case types.FloatKindX86FP80:
+			return "f80"
+		case types.FloatKindFP128:
+			return "f128"
+		case types.FloatKindPPCFP128:
+			return "ppcf128"
+		}
+	case *types.MMXType:
+		return "x86mmx"
+	case *types.MetadataType:
+		return "Metadata"
+	case *types.PointerType:
+		return fmt.Sprintf("p%d%s", int64(t.AddrSpace), mangleType(t.ElemType))
+	case *types.VectorType:
+		if t.Scalable {
+			return fmt.Sprintf("nxv%d%s", t.Len, mangleType(t.ElemType))
+		}
+		return fmt.Sprintf("v%d%s", t.Len, mangleType(t.ElemType))
+	case *types.ArrayType:
+		return fmt.Sprintf("a%d%s", t.Len, mangleType(t.ElemType))
+	case *types.StructType:
+		if len(t.Alias) > 0 {
+			return "s_" + t.Alias
+		}
+		buf := &strings.Builder{}
+		buf.WriteString("sl_")
+		for _, field := range t.Fields {
+			buf.WriteString(mangleType(field))
+		}
+		buf.WriteString("s")
+		return buf.String()
+	case *types.FuncType:
+		buf := &strings.Builder{}
+		fmt.Fprintf(buf, "f_%s", mangleType(t.RetType))
+		for _, param := range t.Params {
+			buf.WriteString(mangleType(param))
+		}
+		if t.Variadic {
+			buf.WriteString("vararg")
+		}
+		buf.WriteString("f")
+		return buf.String()
+	}
+	panic(errors.Errorf("support for type %T not yet implemented", t))
+}
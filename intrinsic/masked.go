@@ -0,0 +1,141 @@
+package intrinsic
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Masked memory intrinsics ] ============================================
+
+// The masked memory intrinsics access the elements of vectors which are enabled
+// by a mask operand (a vector of i1). Disabled elements are not accessed, and
+// the results of disabled elements of loads and gathers are taken from the
+// passthru operand.
+
+// NewMaskedLoad appends to the basic block a call to the llvm.masked.load
+// intrinsic, which loads the elements of the vector pointed to by src enabled
+// by the mask, with the given alignment in bytes.
+func NewMaskedLoad(m *ir.Module, block *ir.BasicBlock, src value.Value, align int64, mask, passthru value.Value) (*ir.InstCall, error) {
+	const base = "llvm.masked.load"
+	ptrType, vecType, err := maskedPointer(base, src.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	maskType, err := maskedOperands(base, vecType, align, mask, passthru)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// <vec> (<vec>* ptr, i32 align, <mask> mask, <vec> passthru)
+	name := overloadName(base, vecType, ptrType)
+	sig := types.NewFunc(vecType, ptrType, types.I32, maskType, vecType)
+	return newCall(m, block, name, sig, src, ir.NewInt(types.I32, align), mask, passthru)
+}
+
+// NewMaskedStore appends to the basic block a call to the llvm.masked.store
+// intrinsic, which stores the elements of the vector x enabled by the mask to
+// the vector pointed to by dst, with the given alignment in bytes.
+func NewMaskedStore(m *ir.Module, block *ir.BasicBlock, x, dst value.Value, align int64, mask value.Value) (*ir.InstCall, error) {
+	const base = "llvm.masked.store"
+	ptrType, vecType, err := maskedPointer(base, dst.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	maskType, err := maskedOperands(base, vecType, align, mask, x)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// void (<vec> val, <vec>* ptr, i32 align, <mask> mask)
+	name := overloadName(base, vecType, ptrType)
+	sig := types.NewFunc(types.Void, vecType, ptrType, types.I32, maskType)
+	return newCall(m, block, name, sig, x, dst, ir.NewInt(types.I32, align), mask)
+}
+
+// NewMaskedGather appends to the basic block a call to the llvm.masked.gather
+// intrinsic, which loads the elements pointed to by the vector of pointers
+// enabled by the mask, with the given alignment in bytes.
+func NewMaskedGather(m *ir.Module, block *ir.BasicBlock, ptrs value.Value, align int64, mask, passthru value.Value) (*ir.InstCall, error) {
+	const base = "llvm.masked.gather"
+	ptrsType, vecType, err := maskedPointers(base, ptrs.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	maskType, err := maskedOperands(base, vecType, align, mask, passthru)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// <vec> (<ptrs> ptrs, i32 align, <mask> mask, <vec> passthru)
+	name := overloadName(base, vecType, ptrsType)
+	sig := types.NewFunc(vecType, ptrsType, types.I32, maskType, vecType)
+	return newCall(m, block, name, sig, ptrs, ir.NewInt(types.I32, align), mask, passthru)
+}
+
+// NewMaskedScatter appends to the basic block a call to the
+// llvm.masked.scatter intrinsic, which stores the elements of the vector x
+// enabled by the mask to the locations pointed to by the vector of pointers,
+// with the given alignment in bytes.
+func NewMaskedScatter(m *ir.Module, block *ir.BasicBlock, x, ptrs value.Value, align int64, mask value.Value) (*ir.InstCall, error) {
+	const base = "llvm.masked.scatter"
+	ptrsType, vecType, err := maskedPointers(base, ptrs.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	maskType, err := maskedOperands(base, vecType, align, mask, x)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// void (<vec> val, <ptrs> ptrs, i32 align, <mask> mask)
+	name := overloadName(base, vecType, ptrsType)
+	sig := types.NewFunc(types.Void, vecType, ptrsType, types.I32, maskType)
+	return newCall(m, block, name, sig, x, ptrs, ir.NewInt(types.I32, align), mask)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// maskedPointer returns the pointer type and pointed-to vector type of the
+// pointer operand of the given masked load or store intrinsic.
+func maskedPointer(base string, t types.Type) (*types.PointerType, *types.VectorType, error) {
+	ptrType, ok := t.(*types.PointerType)
+	if !ok {
+		return nil, nil, errors.Errorf("invalid pointer operand type of %s; expected *types.PointerType, got %T", base, t)
+	}
+	vecType, ok := ptrType.ElemType.(*types.VectorType)
+	if !ok {
+		return nil, nil, errors.Errorf("invalid pointer operand type of %s; expected pointer to vector type, got %v", base, ptrType)
+	}
+	return ptrType, vecType, nil
+}
+
+// maskedPointers returns the vector of pointers type and the vector type of
+// the pointed-to elements of the pointers operand of the given masked gather or
+// scatter intrinsic.
+func maskedPointers(base string, t types.Type) (*types.VectorType, *types.VectorType, error) {
+	ptrsType, ok := t.(*types.VectorType)
+	if !ok {
+		return nil, nil, errors.Errorf("invalid pointers operand type of %s; expected *types.VectorType, got %T", base, t)
+	}
+	ptrType, ok := ptrsType.ElemType.(*types.PointerType)
+	if !ok {
+		return nil, nil, errors.Errorf("invalid pointers operand type of %s; expected vector of pointers, got %v", base, ptrsType)
+	}
+	vecType := &types.VectorType{Len: ptrsType.Len, ElemType: ptrType.ElemType, Scalable: ptrsType.Scalable}
+	return ptrsType, vecType, nil
+}
+
+// maskedOperands validates the alignment, mask and value operands (passthru or
+// stored value) of the given masked memory intrinsic on the given vector type,
+// and returns the mask type.
+func maskedOperands(base string, vecType *types.VectorType, align int64, mask, x value.Value) (*types.VectorType, error) {
+	if align <= 0 || align&(align-1) != 0 || align > 1<<29 {
+		return nil, errors.Errorf("invalid alignment %d of %s; expected power of two", align, base)
+	}
+	maskType := &types.VectorType{Len: vecType.Len, ElemType: types.I1, Scalable: vecType.Scalable}
+	if !mask.Type().Equal(maskType) {
+		return nil, errors.Errorf("invalid mask type of %s; expected %v, got %v", base, maskType, mask.Type())
+	}
+	if !x.Type().Equal(vecType) {
+		return nil, errors.Errorf("invalid operand type of %s; expected %v, got %v", base, vecType, x.Type())
+	}
+	return maskType, nil
+}
@@ -0,0 +1,64 @@
+package intrinsic
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestMasked(t *testing.T) {
+	m := &ir.Module{}
+	vecType := types.NewVector(4, types.I32)
+	p := ir.NewParam(types.NewPointer(vecType), "p")
+	ptrs := ir.NewParam(types.NewVector(4, types.I32Ptr), "ptrs")
+	mask := ir.NewParam(types.NewVector(4, types.I1), "mask")
+	v := ir.NewParam(vecType, "v")
+	f := m.NewFunc("f", types.Void, p, ptrs, mask, v)
+	entry := ir.NewBlock("entry")
+	f.Blocks = []*ir.BasicBlock{entry}
+	load, err := NewMaskedLoad(m, entry, p, 4, mask, v)
+	if err != nil {
+		t.Fatalf("unable to create llvm.masked.load; %+v", err)
+	}
+	store, err := NewMaskedStore(m, entry, v, p, 16, mask)
+	if err != nil {
+		t.Fatalf("unable to create llvm.masked.store; %+v", err)
+	}
+	gather, err := NewMaskedGather(m, entry, ptrs, 4, mask, v)
+	if err != nil {
+		t.Fatalf("unable to create llvm.masked.gather; %+v", err)
+	}
+	scatter, err := NewMaskedScatter(m, entry, v, ptrs, 4, mask)
+	if err != nil {
+		t.Fatalf("unable to create llvm.masked.scatter; %+v", err)
+	}
+	golden := []struct {
+		inst *ir.InstCall
+		want string
+	}{
+		// i=0
+		{inst: load, want: "call <4 x i32> @llvm.masked.load.v4i32.p0v4i32(<4 x i32>* %p, i32 4, <4 x i1> %mask, <4 x i32> %v)"},
+		// i=1
+		{inst: store, want: "call void @llvm.masked.store.v4i32.p0v4i32(<4 x i32> %v, <4 x i32>* %p, i32 16, <4 x i1> %mask)"},
+		// i=2
+		{inst: gather, want: "call <4 x i32> @llvm.masked.gather.v4i32.v4p0i32(<4 x i32*> %ptrs, i32 4, <4 x i1> %mask, <4 x i32> %v)"},
+		// i=3
+		{inst: scatter, want: "call void @llvm.masked.scatter.v4i32.v4p0i32(<4 x i32> %v, <4 x i32*> %ptrs, i32 4, <4 x i1> %mask)"},
+	}
+	for i, g := range golden {
+		if got := g.inst.Def(); g.want != got {
+			t.Errorf("i=%d: instruction mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+	}
+	// Invalid alignment, mask and passthru operands.
+	if _, err := NewMaskedLoad(m, entry, p, 3, mask, v); err == nil {
+		t.Errorf("expected error for non-power of two alignment")
+	}
+	if _, err := NewMaskedLoad(m, entry, p, 4, v, v); err == nil {
+		t.Errorf("expected error for non-i1 mask")
+	}
+	if _, err := NewMaskedGather(m, entry, ptrs, 4, mask, ir.NewParam(types.NewVector(8, types.I32), "w")); err == nil {
+		t.Errorf("expected error for passthru of mismatched vector length")
+	}
+}
@@ -0,0 +1,209 @@
+package intrinsic
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Math intrinsics ] =====================================================
+
+// The math intrinsics are overloaded on floating-point types and vectors of
+// floating-point types; e.g. llvm.sqrt.f64 and llvm.sqrt.v4f32. The operands
+// and result of the intrinsics are of the same type.
+
+// --- [ Unary math intrinsics ] -----------------------------------------------
+
+// NewSqrt appends to the basic block a call to the llvm.sqrt intrinsic, which
+// returns the square root of x.
+func NewSqrt(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.sqrt", x)
+}
+
+// NewFAbs appends to the basic block a call to the llvm.fabs intrinsic, which
+// returns the absolute value of x.
+func NewFAbs(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.fabs", x)
+}
+
+// NewExp appends to the basic block a call to the llvm.exp intrinsic, which
+// returns e raised to the power of x.
+func NewExp(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.exp", x)
+}
+
+// NewExp2 appends to the basic block a call to the llvm.exp2 intrinsic, which
+// returns 2 raised to the power of x.
+func NewExp2(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.exp2", x)
+}
+
+// NewLog appends to the basic block a call to the llvm.log intrinsic, which
+// returns the natural logarithm of x.
+func NewLog(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.log", x)
+}
+
+// NewLog2 appends to the basic block a call to the llvm.log2 intrinsic, which
+// returns the base-2 logarithm of x.
+func NewLog2(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.log2", x)
+}
+
+// NewLog10 appends to the basic block a call to the llvm.log10 intrinsic,
+// which returns the base-10 logarithm of x.
+func NewLog10(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.log10", x)
+}
+
+// NewSin appends to the basic block a call to the llvm.sin intrinsic, which
+// returns the sine of x.
+func NewSin(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.sin", x)
+}
+
+// NewCos appends to the basic block a call to the llvm.cos intrinsic, which
+// returns the cosine of x.
+func NewCos(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.cos", x)
+}
+
+// NewFloor appends to the basic block a call to the llvm.floor intrinsic,
+// which returns x rounded towards negative infinity.
+func NewFloor(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.floor", x)
+}
+
+// NewCeil appends to the basic block a call to the llvm.ceil intrinsic, which
+// returns x rounded towards positive infinity.
+func NewCeil(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.ceil", x)
+}
+
+// NewTrunc appends to the basic block a call to the llvm.trunc intrinsic, which
+// returns x rounded towards zero.
+func NewTrunc(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.trunc", x)
+}
+
+// NewRound appends to the basic block a call to the llvm.round intrinsic, which
+// returns x rounded to the nearest integer, with halfway cases rounded away
+// from zero.
+func NewRound(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.round", x)
+}
+
+// NewRint appends to the basic block a call to the llvm.rint intrinsic, which
+// returns x rounded to the nearest integer in the current rounding mode, and
+// may raise an inexact floating-point exception.
+func NewRint(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.rint", x)
+}
+
+// NewNearbyInt appends to the basic block a call to the llvm.nearbyint
+// intrinsic, which returns x rounded to the nearest integer in the current
+// rounding mode.
+func NewNearbyInt(m *ir.Module, block *ir.BasicBlock, x value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.nearbyint", x)
+}
+
+// --- [ Binary math intrinsics ] ----------------------------------------------
+
+// NewPow appends to the basic block a call to the llvm.pow intrinsic, which
+// returns x raised to the power of y.
+func NewPow(m *ir.Module, block *ir.BasicBlock, x, y value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.pow", x, y)
+}
+
+// NewMinNum appends to the basic block a call to the llvm.minnum intrinsic,
+// which returns the minimum of x and y; NaN operands are ignored.
+func NewMinNum(m *ir.Module, block *ir.BasicBlock, x, y value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.minnum", x, y)
+}
+
+// NewMaxNum appends to the basic block a call to the llvm.maxnum intrinsic,
+// which returns the maximum of x and y; NaN operands are ignored.
+func NewMaxNum(m *ir.Module, block *ir.BasicBlock, x, y value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.maxnum", x, y)
+}
+
+// NewMinimum appends to the basic block a call to the llvm.minimum intrinsic,
+// which returns the minimum of x and y; NaN operands are propagated.
+func NewMinimum(m *ir.Module, block *ir.BasicBlock, x, y value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.minimum", x, y)
+}
+
+// NewMaximum appends to the basic block a call to the llvm.maximum intrinsic,
+// which returns the maximum of x and y; NaN operands are propagated.
+func NewMaximum(m *ir.Module, block *ir.BasicBlock, x, y value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.maximum", x, y)
+}
+
+// NewCopySign appends to the basic block a call to the llvm.copysign
+// intrinsic, which returns the magnitude of x with the sign of y.
+func NewCopySign(m *ir.Module, block *ir.BasicBlock, x, y value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.copysign", x, y)
+}
+
+// NewPowI appends to the basic block a call to the llvm.powi intrinsic, which
+// returns x raised to the i32 integer power y.
+func NewPowI(m *ir.Module, block *ir.BasicBlock, x, y value.Value) (*ir.InstCall, error) {
+	typ, err := fpType("llvm.powi", x.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !y.Type().Equal(types.I32) {
+		return nil, errors.Errorf("invalid exponent type of llvm.powi; expected i32, got %v", y.Type())
+	}
+	name := overloadName("llvm.powi", typ)
+	return newCall(m, block, name, types.NewFunc(typ, typ, types.I32), x, y)
+}
+
+// --- [ Ternary math intrinsics ] ---------------------------------------------
+
+// NewFMA appends to the basic block a call to the llvm.fma intrinsic, which
+// returns x*y+z computed without intermediate rounding.
+func NewFMA(m *ir.Module, block *ir.BasicBlock, x, y, z value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.fma", x, y, z)
+}
+
+// NewFMulAdd appends to the basic block a call to the llvm.fmuladd intrinsic,
+// which returns x*y+z, fused into an fma if the target supports it.
+func NewFMulAdd(m *ir.Module, block *ir.BasicBlock, x, y, z value.Value) (*ir.InstCall, error) {
+	return newMath(m, block, "llvm.fmuladd", x, y, z)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// newMath appends to the basic block a call to the math intrinsic with the
+// given base name, overloaded on the type of the operands.
+func newMath(m *ir.Module, block *ir.BasicBlock, base string, x value.Value, rest ...value.Value) (*ir.InstCall, error) {
+	typ, err := fpType(base, x.Type())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	args := append([]value.Value{x}, rest...)
+	params := make([]types.Type, len(args))
+	for i, arg := range args {
+		if !arg.Type().Equal(typ) {
+			return nil, errors.Errorf("invalid operand type of %s; expected %v, got %v", base, typ, arg.Type())
+		}
+		params[i] = typ
+	}
+	name := overloadName(base, typ)
+	return newCall(m, block, name, types.NewFunc(typ, params...), args...)
+}
+
+// fpType validates that the given operand type of the intrinsic is a
+// floating-point type or a vector of floating-point types.
+func fpType(base string, t types.Type) (types.Type, error) {
+	elem := t
+	if vecType, ok := t.(*types.VectorType); ok {
+		elem = vecType.ElemType
+	}
+	if _, ok := elem.(*types.FloatType); !ok {
+		return nil, errors.Errorf("invalid operand type of %s; expected floating-point type or vector of floating-point types, got %v", base, t)
+	}
+	return t, nil
+}
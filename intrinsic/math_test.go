@@ -0,0 +1,67 @@
+package intrinsic
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestMath(t *testing.T) {
+	m := &ir.Module{}
+	vecType := types.NewVector(4, types.Float)
+	x := ir.NewParam(types.Double, "x")
+	y := ir.NewParam(types.Double, "y")
+	v := ir.NewParam(vecType, "v")
+	n := ir.NewParam(types.I32, "n")
+	f := m.NewFunc("f", types.Void, x, y, v, n)
+	entry := ir.NewBlock("entry")
+	f.Blocks = []*ir.BasicBlock{entry}
+	sqrt, err := NewSqrt(m, entry, x)
+	if err != nil {
+		t.Fatalf("unable to create llvm.sqrt; %+v", err)
+	}
+	// Declarations are shared by calls of the same overload.
+	if _, err := NewSqrt(m, entry, y); err != nil {
+		t.Fatalf("unable to create llvm.sqrt; %+v", err)
+	}
+	fabs, err := NewFAbs(m, entry, v)
+	if err != nil {
+		t.Fatalf("unable to create llvm.fabs; %+v", err)
+	}
+	fma, err := NewFMA(m, entry, v, v, v)
+	if err != nil {
+		t.Fatalf("unable to create llvm.fma; %+v", err)
+	}
+	powi, err := NewPowI(m, entry, x, n)
+	if err != nil {
+		t.Fatalf("unable to create llvm.powi; %+v", err)
+	}
+	golden := []struct {
+		inst *ir.InstCall
+		want string
+	}{
+		// i=0
+		{inst: sqrt, want: "call double @llvm.sqrt.f64(double %x)"},
+		// i=1
+		{inst: fabs, want: "call <4 x float> @llvm.fabs.v4f32(<4 x float> %v)"},
+		// i=2
+		{inst: fma, want: "call <4 x float> @llvm.fma.v4f32(<4 x float> %v, <4 x float> %v, <4 x float> %v)"},
+		// i=3
+		{inst: powi, want: "call double @llvm.powi.f64(double %x, i32 %n)"},
+	}
+	for i, g := range golden {
+		if got := g.inst.Def(); g.want != got {
+			t.Errorf("i=%d: instruction mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+	}
+	if got, want := len(m.Funcs), 5; got != want {
+		t.Errorf("number of functions mismatch; expected %d, got %d", want, got)
+	}
+	if _, err := NewPow(m, entry, x, v); err == nil {
+		t.Errorf("expected error for llvm.pow with mismatched operand types")
+	}
+	if _, err := NewLog(m, entry, n); err == nil {
+		t.Errorf("expected error for llvm.log with integer operand")
+	}
+}
@@ -0,0 +1,293 @@
+package intrinsic
+
+import (
+	"strings"
+
+	"github.com/llir/l/analysis"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Garbage collection statepoints ] ======================================
+
+// Operand bundle tags of statepoints.
+const (
+	// BundleDeopt is the tag of the operand bundle holding the deoptimization
+	// state of a statepoint.
+	BundleDeopt = "deopt"
+	// BundleGCLive is the tag of the operand bundle holding the pointers which
+	// are live across a statepoint, and may be relocated by the garbage
+	// collector.
+	BundleGCLive = "gc-live"
+)
+
+// DefaultStatepointID is the statepoint ID used by RewriteStatepoint.
+const DefaultStatepointID = 0xABCDEF00
+
+// NewStatepoint appends to the basic block a call to the
+// llvm.experimental.gc.statepoint intrinsic, which calls the given callee with
+// the given arguments at a statepoint with the given ID and number of patch
+// bytes. The deoptimization state and live pointers of the statepoint are
+// recorded in "deopt" and "gc-live" operand bundles respectively, if present.
+// The result of the call is a token, as used by NewGCResult and NewGCRelocate.
+func NewStatepoint(m *ir.Module, block *ir.BasicBlock, id int64, numPatchBytes int32, callee value.Value, args, deopt, gcLive []value.Value) (*ir.InstCall, error) {
+	inst, err := statepoint(m, id, numPatchBytes, callee, args, deopt, gcLive)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block.InsertInst(len(block.Insts), inst)
+	return inst, nil
+}
+
+// NewGCResult appends to the basic block a call to the
+// llvm.experimental.gc.result intrinsic, which returns the result of the call
+// of the given statepoint.
+func NewGCResult(m *ir.Module, block *ir.BasicBlock, token *ir.InstCall) (*ir.InstCall, error) {
+	inst, err := gcResult(m, token)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block.InsertInst(len(block.Insts), inst)
+	return inst, nil
+}
+
+// NewGCRelocate appends to the basic block a call to the
+// llvm.experimental.gc.relocate intrinsic, which returns the relocated value
+// of the live pointer of the given statepoint at the derived index, with the
+// base pointer of its object at the base index. The indices refer to the
+// inputs of the "gc-live" operand bundle of the statepoint.
+func NewGCRelocate(m *ir.Module, block *ir.BasicBlock, token *ir.InstCall, base, derived int) (*ir.InstCall, error) {
+	inst, err := gcRelocate(m, token, base, derived)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block.InsertInst(len(block.Insts), inst)
+	return inst, nil
+}
+
+// Statepoint is a statepoint sequence, as created by RewriteStatepoint.
+type Statepoint struct {
+	// Call to llvm.experimental.gc.statepoint.
+	Token *ir.InstCall
+	// Call to llvm.experimental.gc.result; or nil if the callee returns void.
+	Result *ir.InstCall
+	// Calls to llvm.experimental.gc.relocate, one for each live pointer.
+	Relocates []*ir.InstCall
+}
+
+// RewriteStatepoint rewrites the given call instruction of the function into a
+// statepoint sequence, with the given deoptimization state and live pointers.
+// The statepoint has ID DefaultStatepointID and zero patch bytes.
+//
+// Uses of the result of the call are replaced with the gc.result of the
+// statepoint. Uses of each live pointer which are dominated by the statepoint
+// are replaced with its gc.relocate; the live pointers are treated as base
+// pointers. Uses which are not dominated by the statepoint (e.g. uses after a
+// join with paths not passing through the statepoint) are left unchanged.
+func RewriteStatepoint(m *ir.Module, f *ir.Function, call *ir.InstCall, deopt, gcLive []value.Value) (*Statepoint, error) {
+	block, index := findInst(f, call)
+	if block == nil {
+		return nil, errors.Errorf("unable to locate call instruction %v in function %v", call.Ident(), f.Ident())
+	}
+	if len(call.OperandBundles) > 0 {
+		return nil, errors.Errorf("unable to rewrite call instruction %v with operand bundles into statepoint", call.Ident())
+	}
+	token, err := statepoint(m, DefaultStatepointID, 0, call.Callee, call.Args, deopt, gcLive)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	token.Tail = call.Tail
+	token.CallingConv = call.CallingConv
+	token.Metadata = call.Metadata
+	sp := &Statepoint{Token: token}
+	seq := []ir.Instruction{token}
+	if !call.Type().Equal(types.Void) {
+		result, err := gcResult(m, token)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		result.LocalName = call.LocalName
+		sp.Result = result
+		seq = append(seq, result)
+	}
+	for i := range gcLive {
+		relocate, err := gcRelocate(m, token, i, i)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sp.Relocates = append(sp.Relocates, relocate)
+		seq = append(seq, relocate)
+	}
+	block.RemoveInst(call)
+	block.InsertInst(index, seq...)
+	if sp.Result != nil {
+		ir.ReplaceUses(f, call, sp.Result)
+	}
+	if len(gcLive) > 0 {
+		doms := analysis.Dominators(f)
+		after := index + len(seq)
+		for i, live := range gcLive {
+			replaceDominatedUses(f, doms, block, after, live, sp.Relocates[i])
+		}
+	}
+	return sp, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// statepoint returns a new call to the llvm.experimental.gc.statepoint
+// intrinsic; as described by NewStatepoint.
+func statepoint(m *ir.Module, id int64, numPatchBytes int32, callee value.Value, args, deopt, gcLive []value.Value) (*ir.InstCall, error) {
+	calleeType, ok := callee.Type().(*types.PointerType)
+	if !ok {
+		return nil, errors.Errorf("invalid callee type of statepoint; expected *types.PointerType, got %T", callee.Type())
+	}
+	sig, ok := calleeType.ElemType.(*types.FuncType)
+	if !ok {
+		return nil, errors.Errorf("invalid callee type of statepoint; expected pointer to *types.FuncType, got %v", calleeType)
+	}
+	if len(args) < len(sig.Params) || (!sig.Variadic && len(args) != len(sig.Params)) {
+		return nil, errors.Errorf("invalid number of arguments of statepoint callee %v; expected %d, got %d", callee.Ident(), len(sig.Params), len(args))
+	}
+	for i, param := range sig.Params {
+		if !param.Equal(args[i].Type()) {
+			return nil, errors.Errorf("invalid type of argument %d of statepoint callee %v; expected %v, got %v", i, callee.Ident(), param, args[i].Type())
+		}
+	}
+	for i, live := range gcLive {
+		if _, ok := live.Type().(*types.PointerType); !ok {
+			return nil, errors.Errorf("invalid type of live value %d of statepoint; expected pointer type, got %v", i, live.Type())
+		}
+	}
+	// token (i64 id, i32 num_patch_bytes, <callee type> target,
+	//        i32 num_call_args, i32 flags, ..., i32 num_transition_args,
+	//        i32 num_deopt_args)
+	name := overloadName("llvm.experimental.gc.statepoint", calleeType)
+	decl := types.NewFunc(types.Token, types.I64, types.I32, calleeType, types.I32, types.I32)
+	decl.Variadic = true
+	f, err := Declare(m, name, decl)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ops := []value.Value{
+		ir.NewInt(types.I64, id),
+		ir.NewInt(types.I32, int64(numPatchBytes)),
+		callee,
+		ir.NewInt(types.I32, int64(len(args))),
+		ir.NewInt(types.I32, 0), // flags
+	}
+	ops = append(ops, args...)
+	// The transition and deoptimization arguments are passed in operand bundles.
+	ops = append(ops, ir.NewInt(types.I32, 0), ir.NewInt(types.I32, 0))
+	inst := ir.NewCall(f, ops...)
+	if len(deopt) > 0 {
+		inst.OperandBundles = append(inst.OperandBundles, ir.NewOperandBundle(BundleDeopt, deopt...))
+	}
+	if len(gcLive) > 0 {
+		inst.OperandBundles = append(inst.OperandBundles, ir.NewOperandBundle(BundleGCLive, gcLive...))
+	}
+	return inst, nil
+}
+
+// gcResult returns a new call to the llvm.experimental.gc.result intrinsic;
+// as described by NewGCResult.
+func gcResult(m *ir.Module, token *ir.InstCall) (*ir.InstCall, error) {
+	sig, err := statepointTarget(token)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if sig.RetType.Equal(types.Void) {
+		return nil, errors.Errorf("invalid gc.result of statepoint %v; callee returns void", token.Ident())
+	}
+	name := overloadName("llvm.experimental.gc.result", sig.RetType)
+	f, err := Declare(m, name, types.NewFunc(sig.RetType, types.Token))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ir.NewCall(f, token), nil
+}
+
+// gcRelocate returns a new call to the llvm.experimental.gc.relocate intrinsic;
+// as described by NewGCRelocate.
+func gcRelocate(m *ir.Module, token *ir.InstCall, base, derived int) (*ir.InstCall, error) {
+	if _, err := statepointTarget(token); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var gcLive []value.Value
+	for _, bundle := range token.OperandBundles {
+		if bundle.Tag == BundleGCLive {
+			gcLive = bundle.Inputs
+		}
+	}
+	for _, index := range []int{base, derived} {
+		if index < 0 || index >= len(gcLive) {
+			return nil, errors.Errorf("invalid gc-live index %d of statepoint %v; expected index in range [0, %d)", index, token.Ident(), len(gcLive))
+		}
+	}
+	typ := gcLive[derived].Type()
+	name := overloadName("llvm.experimental.gc.relocate", typ)
+	f, err := Declare(m, name, types.NewFunc(typ, types.Token, types.I32, types.I32))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ir.NewCall(f, token, ir.NewInt(types.I32, int64(base)), ir.NewInt(types.I32, int64(derived))), nil
+}
+
+// statepointTarget returns the function signature of the callee of the given
+// statepoint.
+func statepointTarget(token *ir.InstCall) (*types.FuncType, error) {
+	f, ok := token.Callee.(*ir.Function)
+	if !ok || !strings.HasPrefix(f.Name(), "llvm.experimental.gc.statepoint.") || len(token.Args) < 3 {
+		return nil, errors.Errorf("invalid token %v; expected call to llvm.experimental.gc.statepoint", token.Ident())
+	}
+	// The callee is stored as the third argument of the statepoint.
+	if t, ok := token.Args[2].Type().(*types.PointerType); ok {
+		if sig, ok := t.ElemType.(*types.FuncType); ok {
+			return sig, nil
+		}
+	}
+	return nil, errors.Errorf("invalid callee type of statepoint %v; expected pointer to *types.FuncType, got %v", token.Ident(), token.Args[2].Type())
+}
+
+// findInst returns the basic block of the given function containing the
+// instruction, and the index of the instruction within the basic block. The
+// returned basic block is nil if the instruction is not present.
+func findInst(f *ir.Function, inst ir.Instruction) (*ir.BasicBlock, int) {
+	for _, block := range f.Blocks {
+		for i, x := range block.Insts {
+			if x == inst {
+				return block, i
+			}
+		}
+	}
+	return nil, 0
+}
+
+// replaceDominatedUses replaces the uses of old with new in the instructions
+// and terminators of the function which are dominated by the instruction
+// preceding the given index of the basic block. Uses in phi instructions are
+// dominated if their incoming basic block is dominated by the basic block.
+func replaceDominatedUses(f *ir.Function, doms *analysis.DomTree, block *ir.BasicBlock, index int, old, new value.Value) {
+	for _, b := range f.Blocks {
+		dominated := doms.Dominates(block, b)
+		for i, inst := range b.Insts {
+			if phi, ok := inst.(*ir.InstPhi); ok {
+				for _, inc := range phi.Incs {
+					if inc.X == old && doms.Dominates(block, inc.Pred) {
+						inc.X = new
+					}
+				}
+				continue
+			}
+			if !dominated || (b == block && i < index) {
+				continue
+			}
+			ir.ReplaceUses(inst, old, new)
+		}
+		if dominated && b.Term != nil {
+			ir.ReplaceUses(b.Term, old, new)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package intrinsic
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+func TestRewriteStatepoint(t *testing.T) {
+	// define i32 @f(i8 addrspace(1)* %p) {
+	// entry:
+	//    %r = call i32 @g(i32 42)
+	//    call void @use(i8 addrspace(1)* %p)
+	//    ret i32 %r
+	// }
+	m := &ir.Module{}
+	ptrType := types.NewPointer(types.I8)
+	ptrType.AddrSpace = 1
+	g := m.NewFunc("g", types.I32, ir.NewParam(types.I32, "x"))
+	use := m.NewFunc("use", types.Void, ir.NewParam(ptrType, "q"))
+	p := ir.NewParam(ptrType, "p")
+	f := m.NewFunc("f", types.I32, p)
+	entry := ir.NewBlock("entry")
+	f.Blocks = []*ir.BasicBlock{entry}
+	call := entry.NewCall(g, ir.NewInt(types.I32, 42))
+	call.SetName("r")
+	entry.NewCall(use, p)
+	entry.NewRet(call)
+	deopt := []value.Value{ir.NewInt(types.I32, 7)}
+	sp, err := RewriteStatepoint(m, f, call, deopt, []value.Value{p})
+	if err != nil {
+		t.Fatalf("unable to rewrite call into statepoint; %+v", err)
+	}
+	sp.Relocates[0].SetName("p.relocated")
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %+v", err)
+	}
+	want := `define i32 @f(i8 addrspace(1)* %p) {
+entry:
+	%0 = call token (i64, i32, i32 (i32)*, i32, i32, ...) @llvm.experimental.gc.statepoint.p0f_i32i32f(i64 2882400000, i32 0, i32 (i32)* @g, i32 1, i32 0, i32 42, i32 0, i32 0) [ "deopt"(i32 7), "gc-live"(i8 addrspace(1)* %p) ]
+	%r = call i32 @llvm.experimental.gc.result.i32(token %0)
+	%p.relocated = call i8 addrspace(1)* @llvm.experimental.gc.relocate.p1i8(token %0, i32 0, i32 0)
+	call void @use(i8 addrspace(1)* %p.relocated)
+	ret i32 %r
+}`
+	if got := f.Def(); want != got {
+		t.Errorf("function mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// The statepoint intrinsics are declared once.
+	if _, err := RewriteStatepoint(m, f, entry.Insts[3].(*ir.InstCall), nil, nil); err != nil {
+		t.Fatalf("unable to rewrite call into statepoint; %+v", err)
+	}
+	if got, want := len(m.Funcs), 7; got != want {
+		t.Errorf("number of functions mismatch; expected %d, got %d", want, got)
+	}
+}
+
+func TestGCRelocateInvalidIndex(t *testing.T) {
+	m := &ir.Module{}
+	g := m.NewFunc("g", types.Void)
+	f := m.NewFunc("f", types.Void)
+	entry := ir.NewBlock("entry")
+	f.Blocks = []*ir.BasicBlock{entry}
+	token, err := NewStatepoint(m, entry, 0, 0, g, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to create statepoint; %+v", err)
+	}
+	if _, err := NewGCRelocate(m, entry, token, 0, 0); err == nil {
+		t.Errorf("expected error for gc.relocate of statepoint without live pointers")
+	}
+	if _, err := NewGCResult(m, entry, token); err == nil {
+		t.Errorf("expected error for gc.result of statepoint calling void function")
+	}
+}
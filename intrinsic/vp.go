@@ -0,0 +1,226 @@
+package intrinsic
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Vector predication intrinsics ] =======================================
+
+// Vector predication intrinsics operate on the elements of vectors which are
+// enabled by a mask operand (a vector of i1), and positioned below the
+// explicit vector length (EVL) operand (an i32). The results of disabled
+// elements are undefined.
+
+// vpIntBinaryOps is the set of predicated binary operations on integer
+// vectors.
+var vpIntBinaryOps = map[string]bool{
+	"add":  true,
+	"sub":  true,
+	"mul":  true,
+	"sdiv": true,
+	"udiv": true,
+	"srem": true,
+	"urem": true,
+	"and":  true,
+	"or":   true,
+	"xor":  true,
+	"shl":  true,
+	"lshr": true,
+	"ashr": true,
+	"smin": true,
+	"smax": true,
+	"umin": true,
+	"umax": true,
+}
+
+// vpFloatBinaryOps is the set of predicated binary operations on
+// floating-point vectors.
+var vpFloatBinaryOps = map[string]bool{
+	"fadd": true,
+	"fsub": true,
+	"fmul": true,
+	"fdiv": true,
+	"frem": true,
+}
+
+// vpIntReduceOps is the set of predicated reductions of integer vectors.
+var vpIntReduceOps = map[string]bool{
+	"add":  true,
+	"mul":  true,
+	"and":  true,
+	"or":   true,
+	"xor":  true,
+	"smin": true,
+	"smax": true,
+	"umin": true,
+	"umax": true,
+}
+
+// vpFloatReduceOps is the set of predicated reductions of floating-point
+// vectors.
+var vpFloatReduceOps = map[string]bool{
+	"fadd": true,
+	"fmul": true,
+	"fmin": true,
+	"fmax": true,
+}
+
+// NewVPBinary appends to the basic block a call to the llvm.vp.<op>
+// intrinsic, which applies the given predicated binary operation (e.g. "add"
+// or "fmul") to the elements of the vectors x and y enabled by the mask and
+// explicit vector length.
+func NewVPBinary(m *ir.Module, block *ir.BasicBlock, op string, x, y, mask, evl value.Value) (*ir.InstCall, error) {
+	vecType, err := vpVector(x.Type(), "llvm.vp."+op, op, vpIntBinaryOps, vpFloatBinaryOps)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !y.Type().Equal(vecType) {
+		return nil, errors.Errorf("invalid operand type of llvm.vp.%s; expected %v, got %v", op, vecType, y.Type())
+	}
+	maskType, err := vpPredicate(vecType, mask, evl)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// <vec> (<vec> x, <vec> y, <mask> mask, i32 evl)
+	name := overloadName("llvm.vp."+op, vecType)
+	sig := types.NewFunc(vecType, vecType, vecType, maskType, types.I32)
+	return newCall(m, block, name, sig, x, y, mask, evl)
+}
+
+// NewVPReduce appends to the basic block a call to the llvm.vp.reduce.<op>
+// intrinsic, which reduces the start value and the elements of the vector
+// enabled by the mask and explicit vector length, using the given reduction
+// operation (e.g. "add" or "fmax").
+func NewVPReduce(m *ir.Module, block *ir.BasicBlock, op string, start, vec, mask, evl value.Value) (*ir.InstCall, error) {
+	vecType, err := vpVector(vec.Type(), "llvm.vp.reduce."+op, op, vpIntReduceOps, vpFloatReduceOps)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !start.Type().Equal(vecType.ElemType) {
+		return nil, errors.Errorf("invalid start value type of llvm.vp.reduce.%s; expected %v, got %v", op, vecType.ElemType, start.Type())
+	}
+	maskType, err := vpPredicate(vecType, mask, evl)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// <elem> (<elem> start, <vec> vec, <mask> mask, i32 evl)
+	name := overloadName("llvm.vp.reduce."+op, vecType)
+	sig := types.NewFunc(vecType.ElemType, vecType.ElemType, vecType, maskType, types.I32)
+	return newCall(m, block, name, sig, start, vec, mask, evl)
+}
+
+// NewVPSelect appends to the basic block a call to the llvm.vp.select
+// intrinsic, which selects the elements of x where the condition mask is set
+// and the elements of y otherwise, for the elements below the explicit vector
+// length.
+func NewVPSelect(m *ir.Module, block *ir.BasicBlock, cond, x, y, evl value.Value) (*ir.InstCall, error) {
+	vecType, ok := x.Type().(*types.VectorType)
+	if !ok {
+		return nil, errors.Errorf("invalid operand type of llvm.vp.select; expected *types.VectorType, got %T", x.Type())
+	}
+	if !y.Type().Equal(vecType) {
+		return nil, errors.Errorf("invalid operand type of llvm.vp.select; expected %v, got %v", vecType, y.Type())
+	}
+	maskType, err := vpPredicate(vecType, cond, evl)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// <vec> (<mask> cond, <vec> on_true, <vec> on_false, i32 evl)
+	name := overloadName("llvm.vp.select", vecType)
+	sig := types.NewFunc(vecType, maskType, vecType, vecType, types.I32)
+	return newCall(m, block, name, sig, cond, x, y, evl)
+}
+
+// NewVPLoad appends to the basic block a call to the llvm.vp.load intrinsic,
+// which loads the elements of the vector pointed to by src enabled by the mask
+// and explicit vector length.
+func NewVPLoad(m *ir.Module, block *ir.BasicBlock, src, mask, evl value.Value) (*ir.InstCall, error) {
+	ptrType, vecType, err := vpPointer(src.Type(), "load")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	maskType, err := vpPredicate(vecType, mask, evl)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// <vec> (<vec>* ptr, <mask> mask, i32 evl)
+	name := overloadName("llvm.vp.load", vecType, ptrType)
+	sig := types.NewFunc(vecType, ptrType, maskType, types.I32)
+	return newCall(m, block, name, sig, src, mask, evl)
+}
+
+// NewVPStore appends to the basic block a call to the llvm.vp.store intrinsic,
+// which stores the elements of the vector x enabled by the mask and explicit
+// vector length to the vector pointed to by dst.
+func NewVPStore(m *ir.Module, block *ir.BasicBlock, x, dst, mask, evl value.Value) (*ir.InstCall, error) {
+	ptrType, vecType, err := vpPointer(dst.Type(), "store")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !x.Type().Equal(vecType) {
+		return nil, errors.Errorf("invalid operand type of llvm.vp.store; expected %v, got %v", vecType, x.Type())
+	}
+	maskType, err := vpPredicate(vecType, mask, evl)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// void (<vec> val, <vec>* ptr, <mask> mask, i32 evl)
+	name := overloadName("llvm.vp.store", vecType, ptrType)
+	sig := types.NewFunc(types.Void, vecType, ptrType, maskType, types.I32)
+	return newCall(m, block, name, sig, x, dst, mask, evl)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// vpVector returns the vector type of the operand of the predicated intrinsic
+// with the given name, validating the operation against the operations on
+// integer and floating-point vectors.
+func vpVector(t types.Type, name, op string, intOps, floatOps map[string]bool) (*types.VectorType, error) {
+	vecType, ok := t.(*types.VectorType)
+	if !ok {
+		return nil, errors.Errorf("invalid operand type of %s; expected *types.VectorType, got %T", name, t)
+	}
+	switch vecType.ElemType.(type) {
+	case *types.IntType:
+		if !intOps[op] {
+			return nil, errors.Errorf("invalid operation %s on integer vector type %v", name, vecType)
+		}
+	case *types.FloatType:
+		if !floatOps[op] {
+			return nil, errors.Errorf("invalid operation %s on floating-point vector type %v", name, vecType)
+		}
+	default:
+		return nil, errors.Errorf("invalid element type of %s operand; expected integer or floating-point type, got %v", name, vecType.ElemType)
+	}
+	return vecType, nil
+}
+
+// vpPointer returns the pointer type and pointed-to vector type of the pointer
+// operand of the given predicated memory operation.
+func vpPointer(t types.Type, op string) (*types.PointerType, *types.VectorType, error) {
+	ptrType, ok := t.(*types.PointerType)
+	if !ok {
+		return nil, nil, errors.Errorf("invalid pointer operand type of llvm.vp.%s; expected *types.PointerType, got %T", op, t)
+	}
+	vecType, ok := ptrType.ElemType.(*types.VectorType)
+	if !ok {
+		return nil, nil, errors.Errorf("invalid pointer operand type of llvm.vp.%s; expected pointer to vector type, got %v", op, ptrType)
+	}
+	return ptrType, vecType, nil
+}
+
+// vpPredicate validates the mask and explicit vector length operands of a
+// predicated operation on the given vector type, and returns the mask type.
+func vpPredicate(vecType *types.VectorType, mask, evl value.Value) (*types.VectorType, error) {
+	maskType := &types.VectorType{Len: vecType.Len, ElemType: types.I1, Scalable: vecType.Scalable}
+	if !mask.Type().Equal(maskType) {
+		return nil, errors.Errorf("invalid mask type; expected %v, got %v", maskType, mask.Type())
+	}
+	if !evl.Type().Equal(types.I32) {
+		return nil, errors.Errorf("invalid explicit vector length type; expected i32, got %v", evl.Type())
+	}
+	return maskType, nil
+}
@@ -0,0 +1,89 @@
+package intrinsic
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestNewVPBinary(t *testing.T) {
+	m := &ir.Module{}
+	vecType := types.NewScalableVector(4, types.I32)
+	x := ir.NewParam(vecType, "x")
+	y := ir.NewParam(vecType, "y")
+	mask := ir.NewParam(types.NewScalableVector(4, types.I1), "mask")
+	fixedMask := ir.NewParam(types.NewVector(4, types.I1), "fixed_mask")
+	evl := ir.NewParam(types.I32, "evl")
+	f := m.NewFunc("f", vecType, x, y, mask, fixedMask, evl)
+	entry := ir.NewBlock("entry")
+	f.Blocks = []*ir.BasicBlock{entry}
+	sum, err := NewVPBinary(m, entry, "add", x, y, mask, evl)
+	if err != nil {
+		t.Fatalf("unable to create llvm.vp.add; %+v", err)
+	}
+	want := "call <vscale x 4 x i32> @llvm.vp.add.nxv4i32(<vscale x 4 x i32> %x, <vscale x 4 x i32> %y, <vscale x 4 x i1> %mask, i32 %evl)"
+	if got := sum.Def(); want != got {
+		t.Errorf("instruction mismatch; expected `%v`, got `%v`", want, got)
+	}
+	golden := []struct {
+		op   string
+		mask *ir.Param
+		evl  *ir.Param
+	}{
+		// i=0: fixed-length mask of scalable vector.
+		{op: "add", mask: fixedMask, evl: evl},
+		// i=1: floating-point operation on integer vector.
+		{op: "fadd", mask: mask, evl: evl},
+		// i=2: non-i32 explicit vector length.
+		{op: "add", mask: mask, evl: x},
+		// i=3: unknown operation.
+		{op: "foo", mask: mask, evl: evl},
+	}
+	for i, g := range golden {
+		if _, err := NewVPBinary(m, entry, g.op, x, y, g.mask, g.evl); err == nil {
+			t.Errorf("i=%d: expected error for invalid llvm.vp.%s", i, g.op)
+		}
+	}
+}
+
+func TestNewVPLoadStore(t *testing.T) {
+	m := &ir.Module{}
+	vecType := types.NewVector(8, types.Float)
+	p := ir.NewParam(types.NewPointer(vecType), "p")
+	mask := ir.NewParam(types.NewVector(8, types.I1), "mask")
+	evl := ir.NewParam(types.I32, "evl")
+	f := m.NewFunc("f", types.Void, p, mask, evl)
+	entry := ir.NewBlock("entry")
+	f.Blocks = []*ir.BasicBlock{entry}
+	v, err := NewVPLoad(m, entry, p, mask, evl)
+	if err != nil {
+		t.Fatalf("unable to create llvm.vp.load; %+v", err)
+	}
+	v.SetName("v")
+	s, err := NewVPReduce(m, entry, "fadd", ir.NewParam(types.Float, "start"), v, mask, evl)
+	if err != nil {
+		t.Fatalf("unable to create llvm.vp.reduce.fadd; %+v", err)
+	}
+	s.SetName("s")
+	store, err := NewVPStore(m, entry, v, p, mask, evl)
+	if err != nil {
+		t.Fatalf("unable to create llvm.vp.store; %+v", err)
+	}
+	golden := []struct {
+		inst *ir.InstCall
+		want string
+	}{
+		// i=0
+		{inst: v, want: "call <8 x float> @llvm.vp.load.v8f32.p0v8f32(<8 x float>* %p, <8 x i1> %mask, i32 %evl)"},
+		// i=1
+		{inst: s, want: "call float @llvm.vp.reduce.fadd.v8f32(float %start, <8 x float> %v, <8 x i1> %mask, i32 %evl)"},
+		// i=2
+		{inst: store, want: "call void @llvm.vp.store.v8f32.p0v8f32(<8 x float> %v, <8 x float>* %p, <8 x i1> %mask, i32 %evl)"},
+	}
+	for i, g := range golden {
+		if got := g.inst.Def(); g.want != got {
+			t.Errorf("i=%d: instruction mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+	}
+}
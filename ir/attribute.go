@@ -0,0 +1,145 @@
+package ir
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/l/internal/enc"
+)
+
+// === [ Attributes ] ==========================================================
+
+// FuncAttribute is a function attribute.
+//
+// A FuncAttribute has one of the following underlying types.
+//
+//    enum.FuncAttr
+//    ir.AttrString
+//    ir.AttrPair
+//    *ir.AttrGroupDef
+type FuncAttribute interface {
+	fmt.Stringer
+	// IsFuncAttribute ensures that only function attributes can be assigned to
+	// the ir.FuncAttribute interface.
+	IsFuncAttribute()
+}
+
+// ParamAttribute is a parameter attribute.
+//
+// A ParamAttribute has one of the following underlying types.
+//
+//    enum.ParamAttr
+//    ir.AttrString
+//    ir.AttrPair
+type ParamAttribute interface {
+	fmt.Stringer
+	// IsParamAttribute ensures that only parameter attributes can be assigned
+	// to the ir.ParamAttribute interface.
+	IsParamAttribute()
+}
+
+// ReturnAttribute is a return attribute.
+//
+// A ReturnAttribute has one of the following underlying types.
+//
+//    enum.ParamAttr
+//    ir.AttrString
+//    ir.AttrPair
+type ReturnAttribute interface {
+	fmt.Stringer
+	// IsReturnAttribute ensures that only return attributes can be assigned to
+	// the ir.ReturnAttribute interface.
+	IsReturnAttribute()
+}
+
+// --- [ String attributes ] ---------------------------------------------------
+
+// AttrString is an attribute string (e.g. `"no-frame-pointer-elim"`).
+type AttrString string
+
+// String returns the LLVM syntax representation of the attribute string.
+func (attr AttrString) String() string {
+	return enc.Quote([]byte(attr))
+}
+
+// IsFuncAttribute ensures that only function attributes can be assigned to
+// the ir.FuncAttribute interface.
+func (AttrString) IsFuncAttribute() {}
+
+// IsParamAttribute ensures that only parameter attributes can be assigned to
+// the ir.ParamAttribute interface.
+func (AttrString) IsParamAttribute() {}
+
+// IsReturnAttribute ensures that only return attributes can be assigned to
+// the ir.ReturnAttribute interface.
+func (AttrString) IsReturnAttribute() {}
+
+// AttrPair is an attribute key-value pair (e.g. `"target-cpu"="skylake"`).
+type AttrPair struct {
+	// Attribute key.
+	Key string
+	// Attribute value.
+	Value string
+}
+
+// NewAttrPair returns a new attribute key-value pair based on the given key
+// and value.
+func NewAttrPair(key, value string) AttrPair {
+	return AttrPair{Key: key, Value: value}
+}
+
+// String returns the LLVM syntax representation of the attribute key-value
+// pair.
+func (attr AttrPair) String() string {
+	return fmt.Sprintf("%s=%s", enc.Quote([]byte(attr.Key)), enc.Quote([]byte(attr.Value)))
+}
+
+// IsFuncAttribute ensures that only function attributes can be assigned to
+// the ir.FuncAttribute interface.
+func (AttrPair) IsFuncAttribute() {}
+
+// IsParamAttribute ensures that only parameter attributes can be assigned to
+// the ir.ParamAttribute interface.
+func (AttrPair) IsParamAttribute() {}
+
+// IsReturnAttribute ensures that only return attributes can be assigned to
+// the ir.ReturnAttribute interface.
+func (AttrPair) IsReturnAttribute() {}
+
+// ~~~ [ Attribute Group Definition ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// AttrGroupDef is an attribute group definition.
+type AttrGroupDef struct {
+	// Attribute group ID (without '#' prefix).
+	ID int64
+	// Function attributes.
+	FuncAttrs []FuncAttribute
+}
+
+// NewAttrGroupDef returns a new attribute group definition based on the given
+// attribute group ID and function attributes.
+func NewAttrGroupDef(id int64, funcAttrs ...FuncAttribute) *AttrGroupDef {
+	return &AttrGroupDef{ID: id, FuncAttrs: funcAttrs}
+}
+
+// String returns the string representation of the attribute group definition.
+func (a *AttrGroupDef) String() string {
+	return enc.AttrGroupID(fmt.Sprint(a.ID))
+}
+
+// Def returns the LLVM syntax representation of the attribute group
+// definition.
+func (a *AttrGroupDef) Def() string {
+	// "attributes" AttrGroupID "=" "{" FuncAttrs "}"
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "attributes %v = {", a)
+	for _, attr := range a.FuncAttrs {
+		fmt.Fprintf(buf, " %v", attr)
+	}
+	buf.WriteString(" }")
+	return buf.String()
+}
+
+// IsFuncAttribute ensures that only function attributes can be assigned to
+// the ir.FuncAttribute interface.
+func (*AttrGroupDef) IsFuncAttribute() {}
@@ -2,10 +2,13 @@ package ir
 
 import (
 	"fmt"
+	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/llir/l/internal/enc"
 	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
 )
 
 // === [ Basic blocks ] ========================================================
@@ -19,6 +22,15 @@ type BasicBlock struct {
 	Insts []Instruction
 	// Terminator of the basic block.
 	Term Terminator
+
+	// Source locations (file:line) of the Go code which created or inserted the
+	// instructions and terminator of the basic block, as recorded by the
+	// builder and insertion methods if RecordLocations of the parent module is
+	// set.
+	locs map[interface{}]string
+	// Parent function of the basic block, as linked by NewBlock of functions;
+	// or nil if not present.
+	parent *Function
 }
 
 // NewBlock returns a new basic block based on the given label name. An empty
@@ -27,6 +39,15 @@ func NewBlock(name string) *BasicBlock {
 	return &BasicBlock{LocalName: name}
 }
 
+// Location returns the source location (file:line) of the Go code which
+// created or inserted the given instruction or terminator of the basic block,
+// as recorded by the builder and insertion methods if RecordLocations of the
+// parent module is set. The boolean return value indicates success.
+func (block *BasicBlock) Location(inst interface{}) (string, bool) {
+	loc, ok := block.locs[inst]
+	return loc, ok
+}
+
 // String returns the LLVM syntax representation of the basic block as a
 // type-value pair.
 func (block *BasicBlock) String() string {
@@ -50,12 +71,37 @@ func (block *BasicBlock) Name() string {
 
 // SetName sets the name of the basic block.
 func (block *BasicBlock) SetName(name string) {
-	block.LocalName = name
+	old := block.LocalName
+	block.LocalName = block.module().internName(name)
+	if old != name {
+		block.module().notifyRename(block, old)
+	}
 }
 
 // Def returns the LLVM syntax representation of the basic block definition.
 func (block *BasicBlock) Def() string {
+	return block.def(Printer{})
+}
+
+// def returns the LLVM syntax representation of the basic block definition,
+// with the given printing options.
+func (block *BasicBlock) def(p Printer) string {
 	// OptLabelIdent Instructions Terminator
+	// Annotate errors with the basic block and instruction being printed.
+	var cur interface{}
+	curIndex := 0
+	defer func() {
+		if r := recover(); r != nil {
+			panic(annotate(r, func(e *Error) {
+				if len(e.Block) == 0 {
+					e.Block = block.Ident()
+				}
+				if len(e.Inst) == 0 && cur != nil {
+					e.Inst = instIdent(cur, curIndex)
+				}
+			}))
+		}
+	}()
 	buf := &strings.Builder{}
 	if isLocalID(block.LocalName) {
 		//fmt.Fprintf(buf, "; <label>:%v\n", enc.Label(block.LocalName))
@@ -63,9 +109,105 @@ func (block *BasicBlock) Def() string {
 		// TODO: Store block name without ':' suffix or '%' prefix.
 		fmt.Fprintf(buf, "%v\n", enc.Label(block.LocalName))
 	}
-	for _, inst := range block.Insts {
-		fmt.Fprintf(buf, "\t%v\n", inst.Def())
+	for i, inst := range block.Insts {
+		cur, curIndex = inst, i
+		writeComments(buf, "\t", commentsOf(inst))
+		buf.WriteString("\t")
+		if n, ok := inst.(value.Named); ok && !isVoidValue(n) {
+			fmt.Fprintf(buf, "%v = ", n.Ident())
+		}
+		buf.WriteString(inst.Def())
+		block.writeLocation(buf, p, inst)
+		buf.WriteString("\n")
+	}
+	cur, curIndex = block.Term, len(block.Insts)
+	writeComments(buf, "\t", commentsOf(block.Term))
+	buf.WriteString("\t")
+	if n, ok := block.Term.(value.Named); ok && !isVoidValue(n) {
+		fmt.Fprintf(buf, "%v = ", n.Ident())
 	}
-	fmt.Fprintf(buf, "\t%v", block.Term.Def())
+	buf.WriteString(block.Term.Def())
+	block.writeLocation(buf, p, block.Term)
 	return buf.String()
 }
+
+// ### [ Helper functions ] ####################################################
+
+// appendInst appends the given instruction to the basic block, recording the
+// source location of the caller of the builder method if RecordLocations of
+// the parent module is set.
+func (block *BasicBlock) appendInst(inst Instruction) {
+	block.Insts = append(block.Insts, inst)
+	setParent(inst, block)
+	m := block.module()
+	if m != nil && m.RecordLocations {
+		block.recordLocation(inst)
+	}
+	m.notifyInsert(block, inst)
+}
+
+// insertInst inserts the given instructions at index i of the instructions of
+// the basic block, recording the source location of the caller of the
+// insertion method if RecordLocations of the parent module is set.
+func (block *BasicBlock) insertInst(i int, insts []Instruction) {
+	s := make([]Instruction, 0, len(block.Insts)+len(insts))
+	s = append(s, block.Insts[:i]...)
+	s = append(s, insts...)
+	s = append(s, block.Insts[i:]...)
+	block.Insts = s
+	m := block.module()
+	for _, inst := range insts {
+		setParent(inst, block)
+		if m != nil && m.RecordLocations {
+			block.recordLocation(inst)
+		}
+		m.notifyInsert(block, inst)
+	}
+}
+
+// setTerm sets the terminator of the basic block, recording the source
+// location of the caller of the builder method if RecordLocations of the
+// parent module is set.
+func (block *BasicBlock) setTerm(term Terminator) {
+	old := block.Term
+	block.Term = term
+	setParent(old, nil)
+	setParent(term, block)
+	m := block.module()
+	if m != nil && m.RecordLocations {
+		block.recordLocation(term)
+	}
+	if m != nil {
+		if old != nil {
+			m.notifyRemove(block, old)
+		}
+		m.notifyInsert(block, term)
+	}
+}
+
+// recordLocation records the source location of the caller of the builder or
+// insertion method which created or inserted the given instruction or
+// terminator.
+func (block *BasicBlock) recordLocation(inst interface{}) {
+	// Skip recordLocation, appendInst, insertInst or setTerm, and the builder or
+	// insertion method.
+	_, file, line, ok := runtime.Caller(3)
+	if !ok {
+		return
+	}
+	if block.locs == nil {
+		block.locs = make(map[interface{}]string)
+	}
+	block.locs[inst] = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+}
+
+// writeLocation writes the source location of the given instruction or
+// terminator as a trailing comment if the Locations printing option is set.
+func (block *BasicBlock) writeLocation(buf *strings.Builder, p Printer, inst interface{}) {
+	if !p.Locations {
+		return
+	}
+	if loc, ok := block.locs[inst]; ok {
+		fmt.Fprintf(buf, " ; created at %s", loc)
+	}
+}
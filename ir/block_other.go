@@ -14,7 +14,7 @@ import (
 // integer comparison predicate and integer scalar or vector operands.
 func (block *BasicBlock) NewICmp(pred enum.IPred, x, y value.Value) *InstICmp {
 	inst := NewICmp(pred, x, y)
-	block.Insts = append(block.Insts, inst)
+	block.appendInst(inst)
 	return inst
 }
 
@@ -25,7 +25,7 @@ func (block *BasicBlock) NewICmp(pred enum.IPred, x, y value.Value) *InstICmp {
 // operands.
 func (block *BasicBlock) NewFCmp(pred enum.FPred, x, y value.Value) *InstFCmp {
 	inst := NewFCmp(pred, x, y)
-	block.Insts = append(block.Insts, inst)
+	block.appendInst(inst)
 	return inst
 }
 
@@ -35,7 +35,7 @@ func (block *BasicBlock) NewFCmp(pred enum.FPred, x, y value.Value) *InstFCmp {
 // incoming values.
 func (block *BasicBlock) NewPhi(incs ...*Incoming) *InstPhi {
 	inst := NewPhi(incs...)
-	block.Insts = append(block.Insts, inst)
+	block.appendInst(inst)
 	return inst
 }
 
@@ -44,8 +44,18 @@ func (block *BasicBlock) NewPhi(incs ...*Incoming) *InstPhi {
 // NewSelect appends a new select instruction to the basic block based on the
 // given selection condition and operands.
 func (block *BasicBlock) NewSelect(cond, x, y value.Value) *InstSelect {
-	inst := NewSelect(cond, x, x)
-	block.Insts = append(block.Insts, inst)
+	inst := NewSelect(cond, x, y)
+	block.appendInst(inst)
+	return inst
+}
+
+// ~~~ [ freeze ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFreeze appends a new freeze instruction to the basic block based on the
+// given operand.
+func (block *BasicBlock) NewFreeze(x value.Value) *InstFreeze {
+	inst := NewFreeze(x)
+	block.appendInst(inst)
 	return inst
 }
 
@@ -55,9 +65,9 @@ func (block *BasicBlock) NewSelect(cond, x, y value.Value) *InstSelect {
 // callee and function arguments.
 //
 // TODO: specify the set of underlying types of callee.
-func (block *BasicBlock) NewCall(callee value.Value, args ...Arg) *InstCall {
+func (block *BasicBlock) NewCall(callee value.Value, args ...value.Value) *InstCall {
 	inst := NewCall(callee, args...)
-	block.Insts = append(block.Insts, inst)
+	block.appendInst(inst)
 	return inst
 }
 
@@ -67,7 +77,7 @@ func (block *BasicBlock) NewCall(callee value.Value, args ...Arg) *InstCall {
 // given variable argument list and argument type.
 func (block *BasicBlock) NewVAArg(vaList value.Value, argType types.Type) *InstVAArg {
 	inst := NewVAArg(vaList, argType)
-	block.Insts = append(block.Insts, inst)
+	block.appendInst(inst)
 	return inst
 }
 
@@ -75,9 +85,9 @@ func (block *BasicBlock) NewVAArg(vaList value.Value, argType types.Type) *InstV
 
 // NewLandingPad appends a new landingpad instruction to the basic block based
 // on the given result type and filter/catch clauses.
-func (block *BasicBlock) NewLandingPad(resultType types.Type, clauses ...*enum.Clause) *InstLandingPad {
+func (block *BasicBlock) NewLandingPad(resultType types.Type, clauses ...*Clause) *InstLandingPad {
 	inst := NewLandingPad(resultType, clauses...)
-	block.Insts = append(block.Insts, inst)
+	block.appendInst(inst)
 	return inst
 }
 
@@ -85,9 +95,9 @@ func (block *BasicBlock) NewLandingPad(resultType types.Type, clauses ...*enum.C
 
 // NewCatchPad appends a new catchpad instruction to the basic block based on
 // the given exception scope and exception arguments.
-func (block *BasicBlock) NewCatchPad(scope *TermCatchSwitch, args ...Arg) *InstCatchPad {
+func (block *BasicBlock) NewCatchPad(scope *TermCatchSwitch, args ...value.Value) *InstCatchPad {
 	inst := NewCatchPad(scope, args...)
-	block.Insts = append(block.Insts, inst)
+	block.appendInst(inst)
 	return inst
 }
 
@@ -95,8 +105,18 @@ func (block *BasicBlock) NewCatchPad(scope *TermCatchSwitch, args ...Arg) *InstC
 
 // NewCleanupPad appends a new cleanuppad instruction to the basic block based
 // on the given exception scope and exception arguments.
-func (block *BasicBlock) NewCleanupPad(scope enum.ExceptionScope, args ...Arg) *InstCleanupPad {
+func (block *BasicBlock) NewCleanupPad(scope enum.ExceptionScope, args ...value.Value) *InstCleanupPad {
 	inst := NewCleanupPad(scope, args...)
-	block.Insts = append(block.Insts, inst)
+	block.appendInst(inst)
+	return inst
+}
+
+// ~~~ [ raw ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewRawInst appends a new raw instruction to the basic block based on the
+// given LLVM syntax representation.
+func (block *BasicBlock) NewRawInst(text string) *RawInst {
+	inst := NewRawInst(text)
+	block.appendInst(inst)
 	return inst
 }
@@ -13,7 +13,7 @@ import (
 // on the given return value. A nil return value indicates a void return.
 func (block *BasicBlock) NewRet(x value.Value) *TermRet {
 	term := NewRet(x)
-	block.Term = term
+	block.setTerm(term)
 	return term
 }
 
@@ -23,7 +23,7 @@ func (block *BasicBlock) NewRet(x value.Value) *TermRet {
 // terminator based on the given target basic block.
 func (block *BasicBlock) NewBr(target *BasicBlock) *TermBr {
 	term := NewBr(target)
-	block.Term = term
+	block.setTerm(term)
 	return term
 }
 
@@ -34,7 +34,7 @@ func (block *BasicBlock) NewBr(target *BasicBlock) *TermBr {
 // basic blocks.
 func (block *BasicBlock) NewCondBr(cond value.Value, targetTrue, targetFalse *BasicBlock) *TermCondBr {
 	term := NewCondBr(cond, targetTrue, targetFalse)
-	block.Term = term
+	block.setTerm(term)
 	return term
 }
 
@@ -45,7 +45,7 @@ func (block *BasicBlock) NewCondBr(cond value.Value, targetTrue, targetFalse *Ba
 // cases.
 func (block *BasicBlock) NewSwitch(x value.Value, targetDefault *BasicBlock, cases ...*Case) *TermSwitch {
 	term := NewSwitch(x, targetDefault, cases...)
-	block.Term = term
+	block.setTerm(term)
 	return term
 }
 
@@ -56,7 +56,7 @@ func (block *BasicBlock) NewSwitch(x value.Value, targetDefault *BasicBlock, cas
 // constant) and set of valid target basic blocks.
 func (block *BasicBlock) NewIndirectBr(addr *ConstBlockAddress, validTargets ...*BasicBlock) *TermIndirectBr {
 	term := NewIndirectBr(addr, validTargets...)
-	block.Term = term
+	block.setTerm(term)
 	return term
 }
 
@@ -67,9 +67,9 @@ func (block *BasicBlock) NewIndirectBr(addr *ConstBlockAddress, validTargets ...
 // for normal and exceptional execution.
 //
 // TODO: specify the set of underlying types of invokee.
-func (block *BasicBlock) NewInvoke(invokee value.Value, args []Arg, normal, exception *BasicBlock) *TermInvoke {
+func (block *BasicBlock) NewInvoke(invokee value.Value, args []value.Value, normal, exception *BasicBlock) *TermInvoke {
 	term := NewInvoke(invokee, args, normal, exception)
-	block.Term = term
+	block.setTerm(term)
 	return term
 }
 
@@ -79,7 +79,7 @@ func (block *BasicBlock) NewInvoke(invokee value.Value, args []Arg, normal, exce
 // based on the given exception argument to propagate.
 func (block *BasicBlock) NewResume(x value.Value) *TermResume {
 	term := NewResume(x)
-	block.Term = term
+	block.setTerm(term)
 	return term
 }
 
@@ -90,7 +90,7 @@ func (block *BasicBlock) NewResume(x value.Value) *TermResume {
 // target.
 func (block *BasicBlock) NewCatchSwitch(scope enum.ExceptionScope, handlers []*BasicBlock, unwindTarget enum.UnwindTarget) *TermCatchSwitch {
 	term := NewCatchSwitch(scope, handlers, unwindTarget)
-	block.Term = term
+	block.setTerm(term)
 	return term
 }
 
@@ -100,7 +100,7 @@ func (block *BasicBlock) NewCatchSwitch(scope enum.ExceptionScope, handlers []*B
 // terminator based on the given exit catchpad and target basic block.
 func (block *BasicBlock) NewCatchRet(from *InstCatchPad, to *BasicBlock) *TermCatchRet {
 	term := NewCatchRet(from, to)
-	block.Term = term
+	block.setTerm(term)
 	return term
 }
 
@@ -110,7 +110,7 @@ func (block *BasicBlock) NewCatchRet(from *InstCatchPad, to *BasicBlock) *TermCa
 // terminator based on the given exit cleanuppad and unwind target.
 func (block *BasicBlock) NewCleanupRet(from *InstCleanupPad, to enum.UnwindTarget) *TermCleanupRet {
 	term := NewCleanupRet(from, to)
-	block.Term = term
+	block.setTerm(term)
 	return term
 }
 
@@ -120,6 +120,16 @@ func (block *BasicBlock) NewCleanupRet(from *InstCleanupPad, to enum.UnwindTarge
 // terminator.
 func (block *BasicBlock) NewUnreachable() *TermUnreachable {
 	term := NewUnreachable()
-	block.Term = term
+	block.setTerm(term)
+	return term
+}
+
+// ~~~ [ raw ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewRawTerm sets the terminator of the basic block to a new raw terminator
+// based on the given LLVM syntax representation and successor basic blocks.
+func (block *BasicBlock) NewRawTerm(text string, succs ...*BasicBlock) *RawTerm {
+	term := NewRawTerm(text, succs...)
+	block.setTerm(term)
 	return term
 }
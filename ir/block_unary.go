@@ -0,0 +1,17 @@
+package ir
+
+import (
+	"github.com/llir/l/ir/value"
+)
+
+// --- [ Unary instructions ] --------------------------------------------------
+
+// ~~~ [ fneg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewFNeg appends a new fneg instruction to the basic block based on the given
+// operand.
+func (block *BasicBlock) NewFNeg(x value.Value) *InstFNeg {
+	inst := NewFNeg(x)
+	block.appendInst(inst)
+	return inst
+}
@@ -0,0 +1,158 @@
+package ir
+
+import (
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Validation ] ==========================================================
+
+// Validator is implemented by instructions, terminators and constant
+// expressions the type of which is derived from their operands. The Type
+// method of such values returns types.Invalid rather than panicking if the
+// operands are of unexpected types, so that invalid modules may still be
+// printed for debugging; Validate reports the details.
+type Validator interface {
+	// Validate reports whether the operands of the value are valid, returning
+	// an error describing the first invalid operand; or nil if valid.
+	Validate() error
+}
+
+// Validate returns the first error reported by the Validate method of the
+// given global variable, instruction or terminator, or of the constant
+// expressions it uses; or nil if valid. Named operands are validated
+// separately, and are not descended into.
+func Validate(root interface{}) (err error) {
+	Walk(root, func(n interface{}) bool {
+		if err != nil {
+			return false
+		}
+		if _, ok := n.(value.Named); ok && n != root {
+			return false
+		}
+		if v, ok := n.(Validator); ok {
+			err = v.Validate()
+		}
+		return err == nil
+	})
+	return err
+}
+
+// === [ Error accumulation ] ==================================================
+
+// Check checks the module for invalid IR by validating and printing each global
+// variable, and each instruction and terminator of each function (including
+// the constant expressions they use), and returns an ErrorList
+// of every error encountered rather than stopping at the first; or nil if the
+// module is valid. The errors of the module builder methods collected if
+// CollectErrors is set are reported first. Errors are annotated with the
+// identifiers of the enclosing global variable or function, basic block and
+// instruction.
+//
+// Instructions using the result of an invalid instruction are skipped, to
+// avoid reporting the same error repeatedly. Local IDs are assigned to unnamed
+// local variables and basic blocks of functions without errors.
+func (m *Module) Check() error {
+	errs := append(ErrorList(nil), m.errs...)
+	for _, g := range m.Globals {
+		if e := try(func() { g.Def() }); e != nil {
+			errs = append(errs, e)
+		} else if err := Validate(g); err != nil {
+			e := annotate(err, func(*Error) {})
+			e.Global = g.Ident()
+			errs = append(errs, e)
+		}
+	}
+	for _, f := range m.Funcs {
+		errs = append(errs, checkFunc(f)...)
+	}
+	return errs.Err()
+}
+
+// ### [ Helper functions ] ####################################################
+
+// checkFunc checks the instructions and terminators of the given function,
+// and returns the errors encountered.
+func checkFunc(f *Function) ErrorList {
+	var errs ErrorList
+	annotated := func(e *Error, block *BasicBlock, inst interface{}, index int) *Error {
+		e.Global = f.Ident()
+		if block != nil {
+			e.Block = block.Ident()
+		}
+		if inst != nil {
+			e.Inst = instIdent(inst, index)
+		}
+		return e
+	}
+	// Invalid instructions and instructions using them.
+	failed := make(map[value.Value]bool)
+	check := func(block *BasicBlock, inst interface{}, index int) {
+		for _, x := range operandsOf(inst) {
+			if failed[x] {
+				if v, ok := inst.(value.Value); ok {
+					failed[v] = true
+				}
+				return
+			}
+		}
+		e := try(func() {
+			if err := Validate(inst); err != nil {
+				panic(err)
+			}
+			inst.(interface{ Def() string }).Def()
+		})
+		if e != nil {
+			if v, ok := inst.(value.Value); ok {
+				failed[v] = true
+			}
+			errs = append(errs, annotated(e, block, inst, index))
+		}
+	}
+	for _, block := range f.Blocks {
+		for i, inst := range block.Insts {
+			check(block, inst, i)
+		}
+		if block.Term == nil {
+			errs = append(errs, annotated(&Error{Err: errors.New("missing terminator")}, block, nil, 0))
+			continue
+		}
+		check(block, block.Term, len(block.Insts))
+	}
+	// Assign local IDs of valid functions, as the types of instructions are
+	// needed to determine which are assigned IDs.
+	if len(errs) == 0 {
+		if err := f.AssignIDs(); err != nil {
+			errs = append(errs, annotated(&Error{Err: err}, nil, nil, 0))
+		}
+	}
+	return errs
+}
+
+// try invokes f and returns the IR error it panics with; or nil if it does not
+// panic. Panic values of other types than *ir.Error are wrapped.
+func try(f func()) (e *Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = annotate(r, func(*Error) {})
+		}
+	}()
+	f()
+	return nil
+}
+
+// operandsOf returns the operands of the given instruction or terminator.
+func operandsOf(inst interface{}) []value.Value {
+	var xs []value.Value
+	Walk(inst, func(n interface{}) bool {
+		if n == inst {
+			return true
+		}
+		if x, ok := n.(value.Value); ok {
+			xs = append(xs, x)
+			return false
+		}
+		return true
+	})
+	return xs
+}
@@ -0,0 +1,234 @@
+package ir
+
+import (
+	"reflect"
+
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+// === [ Clone ] ===============================================================
+
+// CloneFunc returns a copy of the given function, with new parameters, basic
+// blocks, instructions and terminators. Uses of the parameters, basic blocks,
+// instructions and terminators of the original function are remapped to their
+// copies. Other operands (e.g. constants, global variables and functions) are
+// shared with the original function.
+//
+// The source locations recorded for instructions and terminators are retained
+// by the copy.
+func CloneFunc(f *Function) *Function {
+	nf := *f
+	nf.Typ = nil
+	nf.parent = nil
+	remap := make(map[value.Value]value.Value)
+	nf.Params = make([]*Param, len(f.Params))
+	for i, param := range f.Params {
+		p := *param
+		p.Attrs = append([]ParamAttribute(nil), param.Attrs...)
+		nf.Params[i] = &p
+		remap[param] = &p
+	}
+	nf.Blocks = make([]*BasicBlock, len(f.Blocks))
+	for i, block := range f.Blocks {
+		b := &BasicBlock{LocalName: block.LocalName}
+		nf.Blocks[i] = b
+		remap[block] = b
+	}
+	// Copy instructions and terminators before remapping operands, as operands
+	// may refer to instructions of later basic blocks.
+	for i, block := range f.Blocks {
+		b := nf.Blocks[i]
+		for _, inst := range block.Insts {
+			c := cloneInst(inst)
+			if v, ok := inst.(value.Value); ok {
+				remap[v] = c.(value.Value)
+			}
+			b.Insts = append(b.Insts, c)
+			b.copyLocation(block, inst, c)
+		}
+		if block.Term != nil {
+			b.Term = cloneNode(block.Term).(Terminator)
+			if v, ok := block.Term.(value.Value); ok {
+				remap[v] = b.Term.(value.Value)
+			}
+			b.copyLocation(block, block.Term, b.Term)
+		}
+	}
+	for _, b := range nf.Blocks {
+		for _, inst := range b.Insts {
+			remapOperands(inst, remap)
+		}
+		if b.Term != nil {
+			remapOperands(b.Term, remap)
+		}
+	}
+	nf.Metadata = append(MDAttachments(nil), f.Metadata...)
+	nf.Comments = append([]string(nil), f.Comments...)
+	return &nf
+}
+
+// CloneInst returns a copy of the given instruction, with operands remapped
+// based on the given mapping from original values to their replacements.
+// Operands not present in remap are shared with the original instruction.
+func CloneInst(inst Instruction, remap map[value.Value]value.Value) Instruction {
+	c := cloneInst(inst)
+	remapOperands(c, remap)
+	return c
+}
+
+// ### [ Helper functions ] ####################################################
+
+// cloneInst returns a copy of the given instruction.
+func cloneInst(inst Instruction) Instruction {
+	if c, ok := inst.(CustomCloner); ok {
+		return c.Clone()
+	}
+	return cloneNode(inst).(Instruction)
+}
+
+// cloneNode returns a shallow copy of the given instruction or terminator, with
+// slices copied and cached successors cleared.
+func cloneNode(node interface{}) interface{} {
+	v := reflect.ValueOf(node).Elem()
+	c := reflect.New(v.Type())
+	c.Elem().Set(v)
+	// The copy is not yet inserted into a basic block.
+	setParent(c.Interface(), nil)
+	if _, ok := node.(CustomInst); ok {
+		return c.Interface()
+	}
+	copyFields(c.Elem())
+	return c.Interface()
+}
+
+// copyFields copies the slices and auxiliary structures (e.g. incoming values
+// and switch cases) stored in the struct fields of the given node, so that the
+// node may be modified without affecting the original.
+func copyFields(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch t.Field(i).Name {
+		case "Typ":
+			// Keep cached types.
+			continue
+		case "Successors":
+			// Clear cached successors, except for raw terminators.
+			if _, ok := v.Addr().Interface().(*RawTerm); !ok {
+				field.Set(reflect.Zero(field.Type()))
+				continue
+			}
+		}
+		field.Set(copyValue(field))
+	}
+}
+
+// copyValue returns a copy of the given slice or auxiliary structure. Values,
+// types and other values are returned as is.
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return c
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		switch x := v.Interface().(type) {
+		case *Arg:
+			arg := *x
+			arg.Attrs = append([]ParamAttribute(nil), x.Attrs...)
+			return reflect.ValueOf(&arg).Convert(v.Type())
+		case *OperandBundle:
+			bundle := *x
+			bundle.Inputs = append([]value.Value(nil), x.Inputs...)
+			return reflect.ValueOf(&bundle).Convert(v.Type())
+		case *Incoming, *Case, *Clause:
+			elem := reflect.ValueOf(x).Elem()
+			c := reflect.New(elem.Type())
+			c.Elem().Set(elem)
+			return c.Convert(v.Type())
+		}
+	}
+	return v
+}
+
+// remapOperands remaps the operands of the given copied instruction or
+// terminator.
+func remapOperands(node interface{}, remap map[value.Value]value.Value) {
+	if c, ok := node.(CustomInst); ok {
+		for _, x := range c.Operands() {
+			if y, ok := remap[*x]; ok {
+				*x = y
+			}
+		}
+		return
+	}
+	remapFields(reflect.ValueOf(node).Elem(), remap)
+}
+
+// remapFields remaps the operands stored in the struct fields of the given
+// node.
+func remapFields(v reflect.Value, remap map[value.Value]value.Value) {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		if t.Field(i).Name == "Typ" {
+			continue
+		}
+		remapField(v.Field(i), remap)
+	}
+}
+
+// remapField remaps the operands stored in the given struct field value.
+func remapField(v reflect.Value, remap map[value.Value]value.Value) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() || !v.CanInterface() {
+			return
+		}
+		x := v.Interface()
+		switch x := x.(type) {
+		case *Arg:
+			remapFields(reflect.ValueOf(x).Elem(), remap)
+			return
+		case *Incoming, *Case, *OperandBundle, *Clause:
+			remapFields(reflect.ValueOf(x).Elem(), remap)
+			return
+		case types.Type:
+			return
+		case value.Value:
+			if y, ok := remap[x]; ok && v.CanSet() {
+				if ny := reflect.ValueOf(y); ny.Type().AssignableTo(v.Type()) {
+					v.Set(ny)
+				}
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			remapField(v.Index(i), remap)
+		}
+	}
+}
+
+// copyLocation copies the recorded source location of the given instruction or
+// terminator of the original basic block to its copy.
+func (block *BasicBlock) copyLocation(orig *BasicBlock, inst, c interface{}) {
+	loc, ok := orig.locs[inst]
+	if !ok {
+		return
+	}
+	if block.locs == nil {
+		block.locs = make(map[interface{}]string)
+	}
+	block.locs[c] = loc
+}
@@ -0,0 +1,74 @@
+package ir
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+func TestCloneFunc(t *testing.T) {
+	x := NewParam(types.I32, "x")
+	f := NewFunc("f", types.I32, x)
+	entry := NewBlock("entry")
+	loop := NewBlock("loop")
+	exit := NewBlock("exit")
+	entry.NewBr(loop)
+	i := loop.NewPhi(NewIncoming(NewInt(types.I32, 0), entry))
+	i.SetName("i")
+	next := loop.NewAdd(i, NewInt(types.I32, 1))
+	next.SetName("next")
+	i.Incs = append(i.Incs, NewIncoming(next, loop))
+	frozen := &instFreeze{LocalName: "frozen", X: next}
+	loop.Insts = append(loop.Insts, frozen)
+	loop.NewSwitch(frozen, exit, NewCase(NewInt(types.I32, 10), exit), NewCase(NewInt(types.I32, 1), loop))
+	exit.NewRet(x)
+	f.Blocks = append(f.Blocks, entry, loop, exit)
+	want := f.Def()
+	g := CloneFunc(f)
+	if got := g.Def(); want != got {
+		t.Errorf("function mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// Uses are remapped to the copies.
+	gLoop := g.Blocks[1]
+	gi := gLoop.Insts[0].(*InstPhi)
+	gNext := gLoop.Insts[1].(*InstAdd)
+	gFrozen := gLoop.Insts[2].(*instFreeze)
+	switch {
+	case gi == i || gNext == next || gFrozen == frozen:
+		t.Errorf("instructions not copied")
+	case gi.Incs[1].X != gNext || gi.Incs[1].Pred != gLoop:
+		t.Errorf("incoming value not remapped; got %v", gi.Incs[1])
+	case gFrozen.X != gNext:
+		t.Errorf("custom instruction operand not remapped; got %v", gFrozen.X)
+	case g.Blocks[2].Term.(*TermRet).X != g.Params[0]:
+		t.Errorf("parameter not remapped")
+	case gLoop.Term.Succs()[2] != gLoop:
+		t.Errorf("successor not remapped")
+	}
+	// The original function is unchanged.
+	g.Blocks[2].Term.(*TermRet).X = gNext
+	ReplaceUses(g, gNext, NewInt(types.I32, 42))
+	if gFrozen.X.Ident() != "42" {
+		t.Errorf("custom instruction operand not replaced; got %v", gFrozen.X)
+	}
+	if got := f.Def(); want != got {
+		t.Errorf("original function modified; expected `%v`, got `%v`", want, got)
+	}
+}
+
+// instFreeze is a custom freeze instruction.
+type instFreeze struct {
+	CustomInstBase
+	LocalName string
+	X         value.Value
+}
+
+func (inst *instFreeze) String() string           { return fmt.Sprintf("%v %v", inst.Type(), inst.Ident()) }
+func (inst *instFreeze) Type() types.Type         { return inst.X.Type() }
+func (inst *instFreeze) Ident() string            { return "%" + inst.LocalName }
+func (inst *instFreeze) Name() string             { return inst.LocalName }
+func (inst *instFreeze) SetName(name string)      { inst.LocalName = name }
+func (inst *instFreeze) Def() string              { return fmt.Sprintf("freeze %v", inst.X) }
+func (inst *instFreeze) Operands() []*value.Value { return []*value.Value{&inst.X} }
@@ -66,5 +66,5 @@ func (c *ConstFloat) Type() types.Type {
 // Ident returns the identifier associated with the constant.
 func (c *ConstFloat) Ident() string {
 	// float_lit
-	panic("not yet implemented")
+	panic(unimplemented("(*ConstFloat).Ident"))
 }
@@ -67,7 +67,7 @@ func (c *ConstInt) Ident() string {
 		case 1:
 			return "true"
 		default:
-			panic(fmt.Errorf("invalid integer value of boolean type; expected 0 or 1, got %d", x))
+			panic(errorf("invalid integer value of boolean type; expected 0 or 1, got %d", x))
 		}
 	}
 	return c.X.String()
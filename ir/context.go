@@ -0,0 +1,227 @@
+package ir
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/l/ir/types"
+)
+
+// === [ Contexts ] ============================================================
+
+// Context owns the interned types, uniqued constants and metadata numbering of
+// the modules created in the context; analogous to LLVMContext.
+//
+// A context and its modules are not safe for concurrent use. Independent
+// modules may be constructed concurrently in separate contexts, as contexts
+// share no mutable state; the convenience types of the types package are
+// immutable and shared by all contexts.
+//
+// Interned types must not be modified (e.g. named by SetAlias), as they are
+// shared by all uses in the context; use Module.NewTypeDef with a copy of the
+// type (e.g. by types.Copy) to define a named type.
+type Context struct {
+	// Interned types; type key -> type.
+	types map[string]types.Type
+	// Uniqued constants; constant key -> constant.
+	consts map[constKey]Constant
+	// Next free metadata ID of the modules of the context.
+	mdID int64
+	// Interned names of the modules of the context, as returned by Intern.
+	names map[string]string
+}
+
+// NewContext returns a new context.
+func NewContext() *Context {
+	return &Context{
+		types:  make(map[string]types.Type),
+		consts: make(map[constKey]Constant),
+	}
+}
+
+// NewModule returns a new module of the context. Metadata IDs assigned by
+// NewMetadataDef are unique across the modules of the context.
+func (ctx *Context) NewModule() *Module {
+	return &Module{ctx: ctx}
+}
+
+// Context returns the context of the module; or nil if created without
+// context.
+func (m *Module) Context() *Context {
+	return m.ctx
+}
+
+// --- [ Types ] ---------------------------------------------------------------
+
+// IntType returns the integer type of the given bit size. The shared
+// convenience types (e.g. types.I32) are returned for common bit sizes.
+func (ctx *Context) IntType(bitSize int64) *types.IntType {
+	switch bitSize {
+	case 1:
+		return types.I1
+	case 8:
+		return types.I8
+	case 16:
+		return types.I16
+	case 32:
+		return types.I32
+	case 64:
+		return types.I64
+	}
+	key := fmt.Sprintf("i%d", bitSize)
+	return ctx.intern(key, func() types.Type {
+		return types.NewInt(bitSize)
+	}).(*types.IntType)
+}
+
+// PointerType returns the pointer type of the given element type in the
+// default address space.
+func (ctx *Context) PointerType(elemType types.Type) *types.PointerType {
+	return ctx.PointerTypeIn(elemType, 0)
+}
+
+// PointerTypeIn returns the pointer type of the given element type in the
+// given address space.
+func (ctx *Context) PointerTypeIn(elemType types.Type, addrSpace types.AddrSpace) *types.PointerType {
+	key := fmt.Sprintf("ptr %p %d", elemType, addrSpace)
+	return ctx.intern(key, func() types.Type {
+		t := types.NewPointer(elemType)
+		t.AddrSpace = addrSpace
+		return t
+	}).(*types.PointerType)
+}
+
+// ArrayType returns the array type of the given length and element type.
+func (ctx *Context) ArrayType(len int64, elemType types.Type) *types.ArrayType {
+	key := fmt.Sprintf("array %d %p", len, elemType)
+	return ctx.intern(key, func() types.Type {
+		return types.NewArray(len, elemType)
+	}).(*types.ArrayType)
+}
+
+// VectorType returns the vector type of the given length and element type.
+func (ctx *Context) VectorType(len int64, elemType types.Type) *types.VectorType {
+	key := fmt.Sprintf("vector %d %p", len, elemType)
+	return ctx.intern(key, func() types.Type {
+		return types.NewVector(len, elemType)
+	}).(*types.VectorType)
+}
+
+// FuncType returns the function type of the given return type and parameter
+// types.
+func (ctx *Context) FuncType(retType types.Type, params ...types.Type) *types.FuncType {
+	return ctx.funcType(retType, params, false)
+}
+
+// VariadicFuncType returns the variadic function type of the given return type
+// and parameter types.
+func (ctx *Context) VariadicFuncType(retType types.Type, params ...types.Type) *types.FuncType {
+	return ctx.funcType(retType, params, true)
+}
+
+// StructType returns the literal struct type of the given field types.
+func (ctx *Context) StructType(fields ...types.Type) *types.StructType {
+	key := "struct " + typeKeys(fields)
+	return ctx.intern(key, func() types.Type {
+		return types.NewStruct(fields...)
+	}).(*types.StructType)
+}
+
+// --- [ Constants ] -----------------------------------------------------------
+
+// Int returns the integer constant of the given type and value.
+func (ctx *Context) Int(typ *types.IntType, x int64) *ConstInt {
+	key := constKey{typ: typ, kind: "int", x: x}
+	return ctx.unique(key, func() Constant {
+		return NewInt(typ, x)
+	}).(*ConstInt)
+}
+
+// Null returns the null pointer constant of the given type.
+func (ctx *Context) Null(typ *types.PointerType) *ConstNull {
+	key := constKey{typ: typ, kind: "null"}
+	return ctx.unique(key, func() Constant {
+		return NewNull(typ)
+	}).(*ConstNull)
+}
+
+// Undef returns the undefined value of the given type.
+func (ctx *Context) Undef(typ types.Type) *ConstUndef {
+	key := constKey{typ: typ, kind: "undef"}
+	return ctx.unique(key, func() Constant {
+		return NewUndef(typ)
+	}).(*ConstUndef)
+}
+
+// ZeroInitializer returns the zeroinitializer constant of the given type.
+func (ctx *Context) ZeroInitializer(typ types.Type) *ConstZeroInitializer {
+	key := constKey{typ: typ, kind: "zeroinitializer"}
+	return ctx.unique(key, func() Constant {
+		return NewZeroInitializer(typ)
+	}).(*ConstZeroInitializer)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// constKey is the key of a uniqued constant.
+type constKey struct {
+	// Type of the constant.
+	typ types.Type
+	// Kind of the constant (e.g. "int" or "undef").
+	kind string
+	// Integer value of integer constants.
+	x int64
+}
+
+// intern returns the interned type of the given key, created by create if not
+// yet present.
+func (ctx *Context) intern(key string, create func() types.Type) types.Type {
+	if t, ok := ctx.types[key]; ok {
+		return t
+	}
+	t := create()
+	ctx.types[key] = t
+	return t
+}
+
+// unique returns the uniqued constant of the given key, created by create if
+// not yet present.
+func (ctx *Context) unique(key constKey, create func() Constant) Constant {
+	if c, ok := ctx.consts[key]; ok {
+		return c
+	}
+	c := create()
+	ctx.consts[key] = c
+	return c
+}
+
+// funcType returns the function type of the given return type, parameter types
+// and variadicity.
+func (ctx *Context) funcType(retType types.Type, params []types.Type, variadic bool) *types.FuncType {
+	key := fmt.Sprintf("func %p %s %v", retType, typeKeys(params), variadic)
+	return ctx.intern(key, func() types.Type {
+		t := types.NewFunc(retType, params...)
+		t.Variadic = variadic
+		return t
+	}).(*types.FuncType)
+}
+
+// typeKeys returns the key of the given list of types, based on type identity.
+func typeKeys(ts []types.Type) string {
+	var keys []string
+	for _, t := range ts {
+		keys = append(keys, fmt.Sprintf("%p", t))
+	}
+	return strings.Join(keys, ",")
+}
+
+// nextMetadataID returns the next free metadata ID of the context, which is at
+// least min.
+func (ctx *Context) nextMetadataID(min int64) int64 {
+	if ctx.mdID < min {
+		ctx.mdID = min
+	}
+	id := ctx.mdID
+	ctx.mdID++
+	return id
+}
@@ -0,0 +1,46 @@
+package ir
+
+import "github.com/llir/l/ir/value"
+
+// === [ Custom instructions ] =================================================
+
+// CustomInst is an extension point for instructions defined outside of the ir
+// package (e.g. experimental dialect-style extensions). Custom instructions
+// satisfy the Instruction interface by embedding CustomInstBase, and may be
+// appended to basic blocks like any other instruction.
+//
+// Custom instructions producing a result should also implement value.Named,
+// in which case they are assigned local IDs and printed with a result
+// assignment (e.g. `%x = ...`).
+//
+// The operands of custom instructions are accessed through Operands; Walk
+// visits them, ReplaceUses replaces them and CloneFunc remaps them. Analyses
+// and transformations of the ir package and its subpackages treat custom
+// instructions conservatively; i.e. as having side effects.
+//
+// CloneFunc copies custom instructions using Clone if implemented (i.e. if the
+// custom instruction implements CustomCloner), and makes a shallow copy
+// otherwise. Custom instructions storing operands in slices should implement
+// CustomCloner, as the shallow copy would share its operands with the
+// original.
+type CustomInst interface {
+	Instruction
+	// Operands returns pointers to the operands of the instruction.
+	Operands() []*value.Value
+}
+
+// CustomCloner is implemented by custom instructions which provide their own
+// copy operation.
+type CustomCloner interface {
+	// Clone returns a copy of the custom instruction. Operands of the copy are
+	// remapped by the caller.
+	Clone() CustomInst
+}
+
+// CustomInstBase is embedded by custom instructions to satisfy the Instruction
+// interface.
+type CustomInstBase struct{}
+
+// isInstruction ensures that only instructions can be assigned to the
+// instruction.Instruction interface.
+func (CustomInstBase) isInstruction() {}
@@ -1,6 +1,28 @@
 // Package enum defines enumerate types of LLVM IR.
 package enum
 
+//go:generate stringer -linecomment -type AtomicOp
+
+// AtomicOp is an atomicrmw binary operation.
+type AtomicOp uint8
+
+// AtomicRMW binary operations.
+const (
+	AtomicOpAdd  AtomicOp = iota // add
+	AtomicOpAnd                  // and
+	AtomicOpFAdd                 // fadd
+	AtomicOpFSub                 // fsub
+	AtomicOpMax                  // max
+	AtomicOpMin                  // min
+	AtomicOpNAnd                 // nand
+	AtomicOpOr                   // or
+	AtomicOpSub                  // sub
+	AtomicOpUMax                 // umax
+	AtomicOpUMin                 // umin
+	AtomicOpXChg                 // xchg
+	AtomicOpXor                  // xor
+)
+
 //go:generate stringer -linecomment -type AtomicOrdering
 
 // AtomicOrdering is an atomic ordering attribute.
@@ -81,6 +103,17 @@ const (
 	CallingConvAMDGPUES      // cc 96
 )
 
+//go:generate stringer -linecomment -type ClauseType
+
+// ClauseType is the clause type of a landingpad clause.
+type ClauseType uint8
+
+// Clause types.
+const (
+	ClauseTypeCatch  ClauseType = iota // catch
+	ClauseTypeFilter                   // filter
+)
+
 //go:generate stringer -linecomment -type DLLStorageClass
 
 // DLLStorageClass specifies the DLL storage class of a global identifier.
@@ -135,6 +168,56 @@ const (
 	FPredUNO                // uno
 )
 
+//go:generate stringer -linecomment -type FuncAttr
+
+// FuncAttr is a function attribute.
+type FuncAttr uint8
+
+// Function attributes.
+const (
+	FuncAttrAlwaysInline                FuncAttr = iota // alwaysinline
+	FuncAttrArgMemOnly                                  // argmemonly
+	FuncAttrBuiltin                                     // builtin
+	FuncAttrCold                                        // cold
+	FuncAttrConvergent                                  // convergent
+	FuncAttrInaccessibleMemOrArgMemOnly                 // inaccessiblemem_or_argmemonly
+	FuncAttrInaccessibleMemOnly                         // inaccessiblememonly
+	FuncAttrInlineHint                                  // inlinehint
+	FuncAttrJumpTable                                   // jumptable
+	FuncAttrMinSize                                     // minsize
+	FuncAttrNaked                                       // naked
+	FuncAttrNoBuiltin                                   // nobuiltin
+	FuncAttrNoDuplicate                                 // noduplicate
+	FuncAttrNoImplicitFloat                             // noimplicitfloat
+	FuncAttrNoInline                                    // noinline
+	FuncAttrNonLazyBind                                 // nonlazybind
+	FuncAttrNoRecurse                                   // norecurse
+	FuncAttrNoRedZone                                   // noredzone
+	FuncAttrNoReturn                                    // noreturn
+	FuncAttrNoUnwind                                    // nounwind
+	FuncAttrOptNone                                     // optnone
+	FuncAttrOptSize                                     // optsize
+	FuncAttrReadNone                                    // readnone
+	FuncAttrReadOnly                                    // readonly
+	FuncAttrReturnsTwice                                // returns_twice
+	FuncAttrSafeStack                                   // safestack
+	FuncAttrSanitizeAddress                             // sanitize_address
+	FuncAttrSanitizeHWAddress                           // sanitize_hwaddress
+	FuncAttrSanitizeMemory                              // sanitize_memory
+	FuncAttrSanitizeThread                              // sanitize_thread
+	FuncAttrSpeculatable                                // speculatable
+	FuncAttrSSP                                         // ssp
+	FuncAttrSSPReq                                      // sspreq
+	FuncAttrSSPStrong                                   // sspstrong
+	FuncAttrStrictFP                                    // strictfp
+	FuncAttrUwtable                                     // uwtable
+	FuncAttrWriteOnly                                   // writeonly
+)
+
+// IsFuncAttribute ensures that only function attributes can be assigned to
+// the ir.FuncAttribute interface.
+func (FuncAttr) IsFuncAttribute() {}
+
 //go:generate stringer -linecomment -type IPred
 
 // IPred is an integer comparison predicate.
@@ -187,6 +270,39 @@ const (
 	OverflowFlagNUW                     // nuw
 )
 
+//go:generate stringer -linecomment -type ParamAttr
+
+// ParamAttr is a parameter attribute.
+type ParamAttr uint8
+
+// Parameter attributes.
+const (
+	ParamAttrByval      ParamAttr = iota // byval
+	ParamAttrInAlloca                    // inalloca
+	ParamAttrInReg                       // inreg
+	ParamAttrNest                        // nest
+	ParamAttrNoAlias                     // noalias
+	ParamAttrNoCapture                   // nocapture
+	ParamAttrNonNull                     // nonnull
+	ParamAttrReadNone                    // readnone
+	ParamAttrReadOnly                    // readonly
+	ParamAttrReturned                    // returned
+	ParamAttrSignExt                     // signext
+	ParamAttrSRet                        // sret
+	ParamAttrSwiftError                  // swifterror
+	ParamAttrSwiftSelf                   // swiftself
+	ParamAttrWriteOnly                   // writeonly
+	ParamAttrZeroExt                     // zeroext
+)
+
+// IsParamAttribute ensures that only parameter attributes can be assigned to
+// the ir.ParamAttribute interface.
+func (ParamAttr) IsParamAttribute() {}
+
+// IsReturnAttribute ensures that only return attributes can be assigned to
+// the ir.ReturnAttribute interface.
+func (ParamAttr) IsReturnAttribute() {}
+
 //go:generate stringer -linecomment -type Preemption
 
 // Preemption specifies the preemtion of a global identifier.
@@ -4,17 +4,13 @@
 
 package enum
 
-type AtomicOp uint
-
-type Clause struct {
-}
-
+// ExceptionScope is the parent exception scope of an exception handling pad or
+// catchswitch; a none token constant (ir.None), or the token of a catchpad or
+// cleanuppad instruction.
 type ExceptionScope interface {
-	isExceptionScope()
-}
-
-type OperandBundle struct {
-	// TODO: implement body.
+	// IsExceptionScope ensures that only exception scopes can be assigned to
+	// the enum.ExceptionScope interface.
+	IsExceptionScope()
 }
 
 // TODO: consider getting rid of UnwindTarget, and let unwind targets be of type
@@ -23,16 +19,3 @@ type OperandBundle struct {
 type UnwindTarget interface {
 	IsUnwindTarget()
 }
-
-// TODO: add proper implementations.
-type FuncAttribute interface {
-	isFuncAttribute()
-}
-
-type ParamAttribute interface {
-	isParamAttribute()
-}
-
-type ReturnAttribute interface {
-	isReturnAttribute()
-}
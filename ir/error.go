@@ -0,0 +1,136 @@
+package ir
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Errors ] ==============================================================
+
+// ErrNotImplemented is the underlying error of IR errors caused by features
+// which are not yet implemented.
+var ErrNotImplemented = errors.New("not yet implemented")
+
+// Error is an error of an IR node, annotated with the identifiers of the
+// function, basic block and instruction in which it occurred.
+//
+// Methods which cannot return an error (e.g. Type and Def) panic with an
+// *ir.Error, which is annotated with context as it propagates through the Def
+// methods of enclosing basic blocks, functions and global variables.
+type Error struct {
+	// Global identifier of the function or global variable (e.g. "@f"); or
+	// empty if unknown.
+	Global string
+	// Local identifier of the basic block (e.g. "%entry"); or empty if unknown.
+	Block string
+	// Local identifier of the instruction or terminator (e.g. "%x"), or its
+	// index within the basic block (e.g. "#2") if unnamed; or empty if unknown.
+	Inst string
+	// Underlying error.
+	Err error
+}
+
+// Error returns the error message of the IR error, prefixed by its context.
+func (e *Error) Error() string {
+	var ctx []string
+	if len(e.Global) > 0 {
+		ctx = append(ctx, e.Global)
+	}
+	if len(e.Block) > 0 {
+		ctx = append(ctx, e.Block)
+	}
+	if len(e.Inst) > 0 {
+		ctx = append(ctx, e.Inst)
+	}
+	if len(ctx) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", strings.Join(ctx, ": "), e.Err)
+}
+
+// Unwrap returns the underlying error of the IR error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error of the IR error.
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// ErrorList is a list of IR errors; e.g. as collected by Module.Check.
+type ErrorList []*Error
+
+// Error returns the error messages of the IR errors, one per line.
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return list[0].Error()
+	}
+	var msgs []string
+	for _, e := range list {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("%d errors:\n\t%s", len(list), strings.Join(msgs, "\n\t"))
+}
+
+// Err returns the list as an error; or nil if empty.
+func (list ErrorList) Err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+// ### [ Helper functions ] ####################################################
+
+// errorf returns a new IR error based on the given format specifier and
+// arguments.
+func errorf(format string, args ...interface{}) *Error {
+	return &Error{Err: fmt.Errorf(format, args...)}
+}
+
+// unimplemented returns a new IR error reporting that the given method is not
+// yet implemented.
+func unimplemented(method string) *Error {
+	return &Error{Err: errors.Wrap(ErrNotImplemented, method)}
+}
+
+// annotate returns the IR error of the given recovered panic value, after
+// updating its context using update. Panic values of other types than
+// *ir.Error are wrapped.
+//
+// Use annotate in deferred functions to add context to panics propagating
+// through Def methods.
+//
+//    defer func() {
+//       if r := recover(); r != nil {
+//          panic(annotate(r, func(e *Error) { ... }))
+//       }
+//    }()
+func annotate(r interface{}, update func(e *Error)) *Error {
+	e, ok := r.(*Error)
+	if !ok {
+		err, ok := r.(error)
+		if !ok {
+			err = fmt.Errorf("%v", r)
+		}
+		e = &Error{Err: err}
+	}
+	update(e)
+	return e
+}
+
+// instIdent returns the local identifier of the given instruction or
+// terminator, or its index within the basic block if unnamed.
+func instIdent(inst interface{}, index int) string {
+	if n, ok := inst.(value.Named); ok && !isUnnamed(n.Name()) {
+		return n.Ident()
+	}
+	return fmt.Sprintf("#%d", index)
+}
@@ -0,0 +1,134 @@
+package ir
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestErrorContext(t *testing.T) {
+	m := &Module{}
+	f := m.NewFunc("f", types.Void)
+	entry := NewBlock("entry")
+	entry.NewAdd(NewInt(types.I32, 1), NewInt(types.I32, 2))
+	// Floating-point constants may not yet be printed.
+	entry.NewFAdd(NewFloat(types.Double, 1), NewFloat(types.Double, 2))
+	entry.NewRet(nil)
+	f.Blocks = append(f.Blocks, entry)
+	e := recoverError(func() { m.Def() })
+	if e == nil {
+		t.Fatalf("expected panic with *ir.Error")
+	}
+	want := "@f: %entry: #1: " + unimplemented("(*ConstFloat).Ident").Error()
+	if got := e.Error(); want != got {
+		t.Errorf("error mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestErrorNotImplemented(t *testing.T) {
+	cmp := NewICmpExpr(enum.IPredEQ, NewInt(types.I32, 1), NewInt(types.I32, 2))
+	e := recoverError(func() { cmp.Simplify() })
+	if e == nil {
+		t.Fatalf("expected panic with *ir.Error")
+	}
+	if !errors.Is(e, ErrNotImplemented) {
+		t.Errorf("underlying error mismatch; expected %v, got %v", ErrNotImplemented, e.Err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m := &Module{}
+	f := m.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	// Invalid callee type.
+	call := entry.NewCall(NewInt(types.I32, 3))
+	call.SetName("y")
+	entry.NewRet(nil)
+	// Invalid modules may be printed for debugging.
+	if got, want := call.Type(), types.Invalid; got != want {
+		t.Errorf("type mismatch; expected %v, got %v", want, got)
+	}
+	want := "call <invalid> 3()"
+	if got := m.Def(); !strings.Contains(got, want) {
+		t.Errorf("expected %q in output, got %q", want, got)
+	}
+	err := call.Validate()
+	if err == nil {
+		t.Fatalf("expected error of invalid callee type")
+	}
+	want = "invalid callee type; expected *types.PointerType, got *types.IntType"
+	if got := err.Error(); want != got {
+		t.Errorf("error mismatch; expected %q, got %q", want, got)
+	}
+	// Valid constant expressions.
+	cmp := NewICmpExpr(enum.IPredEQ, NewInt(types.I32, 1), NewInt(types.I32, 2))
+	if err := cmp.Validate(); err != nil {
+		t.Errorf("unexpected error; %v", err)
+	}
+	if got, want := cmp.Type(), types.I1; !got.Equal(want) {
+		t.Errorf("type mismatch; expected %v, got %v", want, got)
+	}
+	// Invalid struct field index.
+	x := NewUndef(types.NewStruct(types.I32, types.I64))
+	if got, want := NewExtractValueExpr(x, 1).Type(), types.I64; !got.Equal(want) {
+		t.Errorf("type mismatch; expected %v, got %v", want, got)
+	}
+	extract := NewExtractValueExpr(x, 2)
+	if got, want := extract.Type(), types.Invalid; got != want {
+		t.Errorf("type mismatch; expected %v, got %v", want, got)
+	}
+	if err := extract.Validate(); err == nil {
+		t.Errorf("expected error of invalid struct field index")
+	}
+}
+
+// recoverError invokes f and returns the *ir.Error it panics with, or nil if
+// it does not panic with an *ir.Error.
+func recoverError(f func()) (e *Error) {
+	defer func() {
+		e, _ = recover().(*Error)
+	}()
+	f()
+	return nil
+}
+
+func TestModuleCheck(t *testing.T) {
+	m := &Module{CollectErrors: true}
+	m.NewGlobalDecl("x", types.I32)
+	m.Ref("x", types.I64Ptr)
+	f := m.NewFunc("f", types.Void)
+	m.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	// Invalid callee type, and a use of the invalid call.
+	call := entry.NewCall(NewInt(types.I32, 3))
+	call.SetName("y")
+	entry.NewAdd(call, call)
+	// Invalid icmp operand type.
+	entry.NewICmp(enum.IPredEQ, NewUndef(types.Float), NewUndef(types.Float))
+	entry.NewRet(nil)
+	err := m.Check()
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("error type mismatch; expected ErrorList, got %T", err)
+	}
+	want := []string{
+		"@x: type mismatch of reference to @x; expected i32*, got i64*",
+		`@f: global identifier "@f" already present; prev void ()* @f, new void ()* @f`,
+		"@f: %entry: %y: invalid callee type; expected *types.PointerType, got *types.IntType",
+		"@f: %entry: #2: invalid icmp operand type; expected *types.IntType, *types.PointerType or *types.VectorType, got *types.FloatType",
+	}
+	if len(list) != len(want) {
+		t.Fatalf("number of errors mismatch; expected %d, got %d (%v)", len(want), len(list), list)
+	}
+	for i, e := range list {
+		if got := e.Error(); got != want[i] {
+			t.Errorf("error %d mismatch; expected %q, got %q", i, want[i], got)
+		}
+	}
+	if m.Errors() == nil {
+		t.Errorf("expected collected errors of module builder methods")
+	}
+}
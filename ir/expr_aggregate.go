@@ -31,9 +31,20 @@ func (e *ExprExtractValue) String() string {
 	return fmt.Sprintf("%v %v", e.Type(), e.Ident())
 }
 
-// Type returns the type of the constant expression.
+// Type returns the type of the constant expression; or types.Invalid if the
+// indices are invalid.
 func (e *ExprExtractValue) Type() types.Type {
-	panic("not yet implemented")
+	t, err := aggregateElemType(e.X.Type(), e.Indices)
+	if err != nil {
+		return types.Invalid
+	}
+	return t
+}
+
+// Validate reports whether the indices of the constant expression are valid.
+func (e *ExprExtractValue) Validate() error {
+	_, err := aggregateElemType(e.X.Type(), e.Indices)
+	return err
 }
 
 // Ident returns the identifier associated with the constant expression.
@@ -51,7 +62,7 @@ func (e *ExprExtractValue) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprExtractValue) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprExtractValue).Simplify"))
 }
 
 // ~~~ [ insertvalue ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -80,7 +91,7 @@ func (e *ExprInsertValue) String() string {
 
 // Type returns the type of the constant expression.
 func (e *ExprInsertValue) Type() types.Type {
-	panic("not yet implemented")
+	return e.X.Type()
 }
 
 // Ident returns the identifier associated with the constant expression.
@@ -98,5 +109,5 @@ func (e *ExprInsertValue) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprInsertValue) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprInsertValue).Simplify"))
 }
@@ -54,7 +54,7 @@ func (e *ExprAdd) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprAdd) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprAdd).Simplify"))
 }
 
 // ~~~ [ fadd ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -90,7 +90,7 @@ func (e *ExprFAdd) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprFAdd) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprFAdd).Simplify"))
 }
 
 // ~~~ [ sub ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -137,7 +137,7 @@ func (e *ExprSub) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprSub) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprSub).Simplify"))
 }
 
 // ~~~ [ fsub ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -173,7 +173,7 @@ func (e *ExprFSub) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprFSub) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprFSub).Simplify"))
 }
 
 // ~~~ [ mul ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -220,7 +220,7 @@ func (e *ExprMul) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprMul) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprMul).Simplify"))
 }
 
 // ~~~ [ fmul ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -256,7 +256,7 @@ func (e *ExprFMul) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprFMul) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprFMul).Simplify"))
 }
 
 // ~~~ [ udiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -303,7 +303,7 @@ func (e *ExprUDiv) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprUDiv) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprUDiv).Simplify"))
 }
 
 // ~~~ [ sdiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -350,7 +350,7 @@ func (e *ExprSDiv) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprSDiv) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprSDiv).Simplify"))
 }
 
 // ~~~ [ fdiv ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -386,7 +386,7 @@ func (e *ExprFDiv) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprFDiv) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprFDiv).Simplify"))
 }
 
 // ~~~ [ urem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -422,7 +422,7 @@ func (e *ExprURem) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprURem) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprURem).Simplify"))
 }
 
 // ~~~ [ srem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -458,7 +458,7 @@ func (e *ExprSRem) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprSRem) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprSRem).Simplify"))
 }
 
 // ~~~ [ frem ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -494,5 +494,5 @@ func (e *ExprFRem) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprFRem) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprFRem).Simplify"))
 }
@@ -54,7 +54,7 @@ func (e *ExprShl) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprShl) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprShl).Simplify"))
 }
 
 // ~~~ [ lshr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -102,7 +102,7 @@ func (e *ExprLShr) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprLShr) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprLShr).Simplify"))
 }
 
 // ~~~ [ ashr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -150,7 +150,7 @@ func (e *ExprAShr) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprAShr) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprAShr).Simplify"))
 }
 
 // ~~~ [ and ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -186,7 +186,7 @@ func (e *ExprAnd) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprAnd) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprAnd).Simplify"))
 }
 
 // ~~~ [ or ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -222,7 +222,7 @@ func (e *ExprOr) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprOr) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprOr).Simplify"))
 }
 
 // ~~~ [ xor ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -258,5 +258,5 @@ func (e *ExprXor) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprXor) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprXor).Simplify"))
 }
@@ -44,7 +44,7 @@ func (e *ExprTrunc) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprTrunc) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprTrunc).Simplify"))
 }
 
 // ~~~ [ zext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -83,7 +83,7 @@ func (e *ExprZExt) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprZExt) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprZExt).Simplify"))
 }
 
 // ~~~ [ sext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -122,7 +122,7 @@ func (e *ExprSExt) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprSExt) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprSExt).Simplify"))
 }
 
 // ~~~ [ fptrunc ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -161,7 +161,7 @@ func (e *ExprFPTrunc) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprFPTrunc) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprFPTrunc).Simplify"))
 }
 
 // ~~~ [ fpext ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -200,7 +200,7 @@ func (e *ExprFPExt) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprFPExt) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprFPExt).Simplify"))
 }
 
 // ~~~ [ fptoui ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -239,7 +239,7 @@ func (e *ExprFPToUI) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprFPToUI) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprFPToUI).Simplify"))
 }
 
 // ~~~ [ fptosi ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -278,7 +278,7 @@ func (e *ExprFPToSI) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprFPToSI) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprFPToSI).Simplify"))
 }
 
 // ~~~ [ uitofp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -317,7 +317,7 @@ func (e *ExprUIToFP) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprUIToFP) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprUIToFP).Simplify"))
 }
 
 // ~~~ [ sitofp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -356,7 +356,7 @@ func (e *ExprSIToFP) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprSIToFP) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprSIToFP).Simplify"))
 }
 
 // ~~~ [ ptrtoint ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -395,7 +395,7 @@ func (e *ExprPtrToInt) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprPtrToInt) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprPtrToInt).Simplify"))
 }
 
 // ~~~ [ inttoptr ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -434,7 +434,7 @@ func (e *ExprIntToPtr) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprIntToPtr) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprIntToPtr).Simplify"))
 }
 
 // ~~~ [ bitcast ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -473,7 +473,7 @@ func (e *ExprBitCast) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprBitCast) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprBitCast).Simplify"))
 }
 
 // ~~~ [ addrspacecast ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -512,5 +512,5 @@ func (e *ExprAddrSpaceCast) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprAddrSpaceCast) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprAddrSpaceCast).Simplify"))
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
 )
 
 // --- [ Memory expressions ] --------------------------------------------------
@@ -37,10 +38,30 @@ func (e *ExprGetElementPtr) String() string {
 	return fmt.Sprintf("%v %v", e.Type(), e.Ident())
 }
 
-// Type returns the type of the constant expression.
+// Type returns the type of the constant expression; or types.Invalid if the
+// indices are invalid.
 func (e *ExprGetElementPtr) Type() types.Type {
 	// TODO: cache type?
-	return types.NewPointer(e.ElemType)
+	t, err := e.typ()
+	if err != nil {
+		return types.Invalid
+	}
+	return t
+}
+
+// Validate reports whether the indices of the constant expression are valid.
+func (e *ExprGetElementPtr) Validate() error {
+	_, err := e.typ()
+	return err
+}
+
+// typ returns the type of the constant expression.
+func (e *ExprGetElementPtr) typ() (types.Type, error) {
+	indices := make([]value.Value, len(e.Indices))
+	for i, index := range e.Indices {
+		indices[i] = index.Index
+	}
+	return gepType(e.ElemType, e.Src.Type(), indices)
 }
 
 // Ident returns the identifier associated with the constant expression.
@@ -62,7 +83,7 @@ func (e *ExprGetElementPtr) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprGetElementPtr) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprGetElementPtr).Simplify"))
 }
 
 // ___ [ gep indices ] _________________________________________________________
@@ -31,9 +31,21 @@ func (e *ExprICmp) String() string {
 	return fmt.Sprintf("%v %v", e.Type(), e.Ident())
 }
 
-// Type returns the type of the constant expression.
+// Type returns the type of the constant expression; or types.Invalid if the
+// operand type is invalid.
 func (e *ExprICmp) Type() types.Type {
-	panic("not yet implemented")
+	t, err := icmpType(e.X.Type())
+	if err != nil {
+		return types.Invalid
+	}
+	return t
+}
+
+// Validate reports whether the operand type of the constant expression is
+// valid.
+func (e *ExprICmp) Validate() error {
+	_, err := icmpType(e.X.Type())
+	return err
 }
 
 // Ident returns the identifier associated with the constant expression.
@@ -45,7 +57,7 @@ func (e *ExprICmp) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprICmp) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprICmp).Simplify"))
 }
 
 // ~~~ [ fcmp ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -70,9 +82,21 @@ func (e *ExprFCmp) String() string {
 	return fmt.Sprintf("%v %v", e.Type(), e.Ident())
 }
 
-// Type returns the type of the constant expression.
+// Type returns the type of the constant expression; or types.Invalid if the
+// operand type is invalid.
 func (e *ExprFCmp) Type() types.Type {
-	panic("not yet implemented")
+	t, err := fcmpType(e.X.Type())
+	if err != nil {
+		return types.Invalid
+	}
+	return t
+}
+
+// Validate reports whether the operand type of the constant expression is
+// valid.
+func (e *ExprFCmp) Validate() error {
+	_, err := fcmpType(e.X.Type())
+	return err
 }
 
 // Ident returns the identifier associated with the constant expression.
@@ -84,7 +108,7 @@ func (e *ExprFCmp) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprFCmp) Simplify() Constant {
-	panic("not yet implemented")
+	panic(unimplemented("(*ExprFCmp).Simplify"))
 }
 
 // ~~~ [ select ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -92,7 +116,7 @@ func (e *ExprFCmp) Simplify() Constant {
 // ExprSelect is an LLVM IR select expression.
 type ExprSelect struct {
 	// Selection condition.
-	Cond Constant
+	Cond Constant // boolean or boolean vector
 	// Operands.
 	X, Y Constant
 }
@@ -100,7 +124,7 @@ type ExprSelect struct {
 // NewSelectExpr returns a new select expression based on the given selection
 // condition and operands.
 func NewSelectExpr(cond, x, y Constant) *ExprSelect {
-	return &ExprSelect{Cond: cond, X: x, Y: x}
+	return &ExprSelect{Cond: cond, X: x, Y: y}
 }
 
 // String returns the LLVM syntax representation of the constant expression as a
@@ -114,6 +138,12 @@ func (e *ExprSelect) Type() types.Type {
 	return e.X.Type()
 }
 
+// Validate reports whether the condition and operand types of the constant
+// expression are valid.
+func (e *ExprSelect) Validate() error {
+	return validateSelect(e.Cond.Type(), e.X.Type(), e.Y.Type())
+}
+
 // Ident returns the identifier associated with the constant expression.
 func (e *ExprSelect) Ident() string {
 	// "select" "(" Type Constant "," Type Constant "," Type Constant ")"
@@ -123,5 +153,40 @@ func (e *ExprSelect) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprSelect) Simplify() Constant {
-	panic("not yet implemented")
+	cond := simplify(e.Cond)
+	if c, ok := cond.(*ConstInt); ok {
+		if c.X.Sign() != 0 {
+			return simplify(e.X)
+		}
+		return simplify(e.Y)
+	}
+	// Per-lane selection based on a constant boolean vector.
+	mask, ok := vectorElems(cond)
+	if !ok {
+		return e
+	}
+	xElems, ok := vectorElems(simplify(e.X))
+	if !ok {
+		return e
+	}
+	yElems, ok := vectorElems(simplify(e.Y))
+	if !ok {
+		return e
+	}
+	if len(mask) != len(xElems) || len(mask) != len(yElems) {
+		return e
+	}
+	elems := make([]Constant, len(mask))
+	for i, m := range mask {
+		switch {
+		case isZeroConst(m):
+			elems[i] = yElems[i]
+		case isConstInt(m):
+			elems[i] = xElems[i]
+		default:
+			// Undefined mask element; unable to fold.
+			return e
+		}
+	}
+	return newVectorConst(e.Type().(*types.VectorType), elems)
 }
@@ -46,7 +46,19 @@ func (e *ExprExtractElement) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprExtractElement) Simplify() Constant {
-	panic("not yet implemented")
+	elems, ok := vectorElems(simplify(e.X))
+	if !ok {
+		return e
+	}
+	index, ok := constIndex(simplify(e.Index))
+	if !ok {
+		return e
+	}
+	if index < 0 || index >= int64(len(elems)) {
+		// Out of bounds element index yields an undefined value.
+		return NewUndef(e.Type())
+	}
+	return elems[index]
 }
 
 // ~~~ [ insertelement ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -88,7 +100,23 @@ func (e *ExprInsertElement) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprInsertElement) Simplify() Constant {
-	panic("not yet implemented")
+	elems, ok := vectorElems(simplify(e.X))
+	if !ok {
+		return e
+	}
+	index, ok := constIndex(simplify(e.Index))
+	if !ok {
+		return e
+	}
+	typ := e.X.Type().(*types.VectorType)
+	if index < 0 || index >= int64(len(elems)) {
+		// Out of bounds element index yields an undefined value.
+		return NewUndef(typ)
+	}
+	newElems := make([]Constant, len(elems))
+	copy(newElems, elems)
+	newElems[index] = simplify(e.Elem)
+	return newVectorConst(typ, newElems)
 }
 
 // ~~~ [ shufflevector ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -115,7 +143,10 @@ func (e *ExprShuffleVector) String() string {
 
 // Type returns the type of the constant expression.
 func (e *ExprShuffleVector) Type() types.Type {
-	return e.Mask.Type()
+	// TODO: cache type?
+	xType := e.X.Type().(*types.VectorType)
+	maskType := e.Mask.Type().(*types.VectorType)
+	return &types.VectorType{Len: maskType.Len, ElemType: xType.ElemType, Scalable: maskType.Scalable}
 }
 
 // Ident returns the identifier associated with the constant expression.
@@ -127,5 +158,143 @@ func (e *ExprShuffleVector) Ident() string {
 // Simplify returns an equivalent (and potentially simplified) constant to the
 // constant expression.
 func (e *ExprShuffleVector) Simplify() Constant {
-	panic("not yet implemented")
+	xElems, ok := vectorElems(simplify(e.X))
+	if !ok {
+		return e
+	}
+	yElems, ok := vectorElems(simplify(e.Y))
+	if !ok {
+		return e
+	}
+	mask, ok := vectorElems(simplify(e.Mask))
+	if !ok {
+		return e
+	}
+	typ := e.Type().(*types.VectorType)
+	elems := make([]Constant, len(mask))
+	n := int64(len(xElems))
+	for i, m := range mask {
+		if _, ok := m.(*ConstUndef); ok {
+			// Undefined mask element yields an undefined vector element.
+			elems[i] = NewUndef(typ.ElemType)
+			continue
+		}
+		index, ok := constIndex(m)
+		if !ok {
+			return e
+		}
+		switch {
+		case index >= 0 && index < n:
+			elems[i] = xElems[index]
+		case index >= n && index < n+int64(len(yElems)):
+			elems[i] = yElems[index-n]
+		default:
+			elems[i] = NewUndef(typ.ElemType)
+		}
+	}
+	return newVectorConst(typ, elems)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// simplify returns the simplified constant of c if c is a constant expression,
+// and c otherwise.
+func simplify(c Constant) Constant {
+	if e, ok := c.(Expression); ok {
+		return e.Simplify()
+	}
+	return c
+}
+
+// vectorElems returns the elements of the given vector constant, expanding
+// zeroinitializer and undef vectors to their per-element constants. The boolean
+// return value indicates success; scalable vectors are not expanded, as their
+// number of elements is unknown until runtime.
+func vectorElems(c Constant) ([]Constant, bool) {
+	switch c := c.(type) {
+	case *ConstVector:
+		if c.Typ != nil && c.Typ.Scalable {
+			return nil, false
+		}
+		return c.Elems, true
+	case *ConstZeroInitializer:
+		typ, ok := c.Typ.(*types.VectorType)
+		if !ok || typ.Scalable {
+			return nil, false
+		}
+		return splatElems(typ, NewZeroInitializer(typ.ElemType)), true
+	case *ConstUndef:
+		typ, ok := c.Typ.(*types.VectorType)
+		if !ok || typ.Scalable {
+			return nil, false
+		}
+		return splatElems(typ, NewUndef(typ.ElemType)), true
+	}
+	return nil, false
+}
+
+// splatElems returns a slice with len(typ) copies of the given element.
+func splatElems(typ *types.VectorType, elem Constant) []Constant {
+	elems := make([]Constant, typ.Len)
+	for i := range elems {
+		elems[i] = elem
+	}
+	return elems
+}
+
+// newVectorConst returns a vector constant of the given type and elements. If
+// every element is undefined or zero, an undef or zeroinitializer constant of
+// the vector type is returned instead.
+func newVectorConst(typ *types.VectorType, elems []Constant) Constant {
+	allUndef, allZero := true, true
+	for _, elem := range elems {
+		if _, ok := elem.(*ConstUndef); !ok {
+			allUndef = false
+		}
+		if !isZeroConst(elem) {
+			allZero = false
+		}
+	}
+	switch {
+	case len(elems) == 0:
+		// Leave empty vectors as is.
+	case allUndef:
+		return NewUndef(typ)
+	case allZero:
+		return NewZeroInitializer(typ)
+	}
+	return NewVector(typ, elems...)
+}
+
+// isZeroConst reports whether the given constant is an integer zero or a
+// zeroinitializer constant.
+func isZeroConst(c Constant) bool {
+	switch c := c.(type) {
+	case *ConstInt:
+		return c.X.Sign() == 0
+	case *ConstZeroInitializer:
+		return true
+	}
+	return false
+}
+
+// isConstInt reports whether the given constant is an integer constant.
+func isConstInt(c Constant) bool {
+	_, ok := c.(*ConstInt)
+	return ok
+}
+
+// constIndex returns the integer value of the given index constant. The boolean
+// return value indicates success.
+func constIndex(c Constant) (int64, bool) {
+	switch c := c.(type) {
+	case *ConstInt:
+		if !c.X.IsInt64() {
+			return 0, false
+		}
+		return c.X.Int64(), true
+	case *ConstZeroInitializer:
+		return 0, true
+	}
+	return 0, false
 }
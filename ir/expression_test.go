@@ -1,5 +1,11 @@
 package ir
 
+import (
+	"testing"
+
+	"github.com/llir/l/ir/types"
+)
+
 // Assert that each constant expression implements the ir.Expression interface.
 var (
 	// Binary expressions.
@@ -50,3 +56,138 @@ var (
 	_ Expression = (*ExprFCmp)(nil)
 	_ Expression = (*ExprSelect)(nil)
 )
+
+func TestVectorExprSimplify(t *testing.T) {
+	i32 := types.I32
+	v2 := types.NewVector(2, i32)
+	v4 := types.NewVector(4, i32)
+	nxv4 := types.NewScalableVector(4, i32)
+	a := NewVector(v2, NewInt(i32, 1), NewInt(i32, 2))
+	b := NewVector(v2, NewInt(i32, 3), NewInt(i32, 4))
+	mask := NewVector(types.NewVector(4, i32), NewInt(i32, 3), NewUndef(i32), NewInt(i32, 0), NewInt(i32, 2))
+	golden := []struct {
+		in   Expression
+		want string
+	}{
+		// i=0
+		{
+			in:   NewExtractElementExpr(a, NewInt(i32, 1)),
+			want: "i32 2",
+		},
+		// i=1
+		{
+			in:   NewInsertElementExpr(a, NewInt(i32, 7), NewInt(i32, 0)),
+			want: "<2 x i32> <i32 7, i32 2>",
+		},
+		// i=2
+		{
+			in:   NewShuffleVectorExpr(a, b, mask),
+			want: "<4 x i32> <i32 4, i32 undef, i32 1, i32 3>",
+		},
+		// i=3: splat of insertelement into undef.
+		{
+			in:   NewShuffleVectorExpr(NewInsertElementExpr(NewUndef(v2), NewInt(i32, 5), NewInt(i32, 0)), NewUndef(v2), NewZeroInitializer(v4)),
+			want: "<4 x i32> <i32 5, i32 5, i32 5, i32 5>",
+		},
+		// i=4
+		{
+			in:   NewInsertElementExpr(NewZeroInitializer(v2), NewInt(i32, 0), NewInt(i32, 1)),
+			want: "<2 x i32> zeroinitializer",
+		},
+		// i=5
+		{
+			in:   NewExtractElementExpr(a, NewInt(i32, 2)),
+			want: "i32 undef",
+		},
+		// i=6: scalable vectors are not folded, as lane 5 may exist at runtime.
+		{
+			in:   NewExtractElementExpr(NewZeroInitializer(nxv4), NewInt(i32, 5)),
+			want: "i32 extractelement (<vscale x 4 x i32> zeroinitializer, i32 5)",
+		},
+		// i=7
+		{
+			in:   NewInsertElementExpr(NewZeroInitializer(nxv4), NewInt(i32, 7), NewInt(i32, 0)),
+			want: "<vscale x 4 x i32> insertelement (<vscale x 4 x i32> zeroinitializer, i32 7, i32 0)",
+		},
+		// i=8
+		{
+			in:   NewShuffleVectorExpr(NewUndef(nxv4), NewUndef(nxv4), NewZeroInitializer(nxv4)),
+			want: "<vscale x 4 x i32> shufflevector (<vscale x 4 x i32> undef, <vscale x 4 x i32> undef, <vscale x 4 x i32> zeroinitializer)",
+		},
+	}
+	for i, g := range golden {
+		got := g.in.Simplify().String()
+		if g.want != got {
+			t.Errorf("i=%d: simplified constant mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+	}
+}
+
+func TestSelectExprSimplify(t *testing.T) {
+	i1, i32 := types.I1, types.I32
+	v2 := types.NewVector(2, i32)
+	nxv4 := types.NewScalableVector(4, i32)
+	a := NewVector(v2, NewInt(i32, 1), NewInt(i32, 2))
+	b := NewVector(v2, NewInt(i32, 3), NewInt(i32, 4))
+	golden := []struct {
+		in   Expression
+		want string
+	}{
+		// i=0
+		{
+			in:   NewSelectExpr(NewInt(i1, 1), NewInt(i32, 1), NewInt(i32, 2)),
+			want: "i32 1",
+		},
+		// i=1
+		{
+			in:   NewSelectExpr(NewInt(i1, 0), NewInt(i32, 1), NewInt(i32, 2)),
+			want: "i32 2",
+		},
+		// i=2
+		{
+			in:   NewSelectExpr(NewVector(types.NewVector(2, i1), NewInt(i1, 0), NewInt(i1, 1)), a, b),
+			want: "<2 x i32> <i32 3, i32 2>",
+		},
+		// i=3
+		{
+			in:   NewSelectExpr(NewZeroInitializer(types.NewVector(2, i1)), a, b),
+			want: "<2 x i32> <i32 3, i32 4>",
+		},
+		// i=4: scalable vectors are not folded.
+		{
+			in:   NewSelectExpr(NewZeroInitializer(types.NewScalableVector(4, i1)), NewZeroInitializer(nxv4), NewUndef(nxv4)),
+			want: "<vscale x 4 x i32> select (<vscale x 4 x i1> zeroinitializer, <vscale x 4 x i32> zeroinitializer, <vscale x 4 x i32> undef)",
+		},
+	}
+	for i, g := range golden {
+		got := g.in.Simplify().String()
+		if g.want != got {
+			t.Errorf("i=%d: simplified constant mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+	}
+}
+
+func TestSelectExprValidate(t *testing.T) {
+	v4 := types.NewVector(4, types.I32)
+	golden := []struct {
+		cond, x, y Constant
+		valid      bool
+	}{
+		// i=0
+		{cond: NewInt(types.I1, 1), x: NewInt(types.I32, 1), y: NewInt(types.I32, 2), valid: true},
+		// i=1
+		{cond: NewZeroInitializer(types.NewVector(4, types.I1)), x: NewZeroInitializer(v4), y: NewUndef(v4), valid: true},
+		// i=2
+		{cond: NewZeroInitializer(types.NewScalableVector(4, types.I1)), x: NewZeroInitializer(v4), y: NewUndef(v4), valid: false},
+		// i=3
+		{cond: NewInt(types.I32, 1), x: NewInt(types.I32, 1), y: NewInt(types.I32, 2), valid: false},
+		// i=4
+		{cond: NewInt(types.I1, 1), x: NewInt(types.I32, 1), y: NewInt(types.I64, 2), valid: false},
+	}
+	for i, g := range golden {
+		e := NewSelectExpr(g.cond, g.x, g.y)
+		if err := e.Validate(); (err == nil) != g.valid {
+			t.Errorf("i=%d: validity mismatch; expected %v, got error %v", i, g.valid, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package ir
+
+import (
+	"sort"
+
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Standalone fragments ] ================================================
+
+// Fragment returns the LLVM syntax representation of a standalone module
+// containing the given function of the module, together with exactly the type
+// definitions, global variable and function declarations, attribute group
+// definitions and metadata definitions it references; e.g. to produce a
+// compilable snippet for bug reports. Local IDs are assigned to unnamed local
+// variables and basic blocks of the function.
+//
+// Global variables and functions referenced by the function are declared
+// rather than defined, with linkage only valid for definitions (e.g. private
+// and internal) omitted.
+func (m *Module) Fragment(f *Function) (string, error) {
+	if err := f.AssignIDs(); err != nil {
+		return "", errors.WithStack(err)
+	}
+	frag := &Module{
+		SourceFilename: m.SourceFilename,
+		DataLayout:     m.DataLayout,
+		TargetTriple:   m.TargetTriple,
+	}
+	// Referenced types, global variables and functions.
+	seenTypes := make(map[types.Type]bool)
+	var globals []*Global
+	var funcs []*Function
+	seen := make(map[value.Value]bool)
+	Walk(f, func(n interface{}) bool {
+		switch n := n.(type) {
+		case *Global:
+			if !seen[n] {
+				seen[n] = true
+				globals = append(globals, n)
+			}
+		case *Function:
+			if n != f && !seen[n] {
+				seen[n] = true
+				funcs = append(funcs, n)
+			}
+		}
+		if v, ok := n.(value.Value); ok {
+			collectTypes(v.Type(), seenTypes)
+		}
+		return true
+	})
+	collectTypes(f.Sig, seenTypes)
+	for _, t := range m.TypeDefs {
+		if seenTypes[t] {
+			frag.TypeDefs = append(frag.TypeDefs, t)
+		}
+	}
+	// Declarations of global variables and functions.
+	attrs := append([]FuncAttribute(nil), f.FuncAttrs...)
+	for _, g := range globals {
+		frag.Globals = append(frag.Globals, globalDecl(g))
+	}
+	for _, callee := range funcs {
+		decl := funcDecl(callee)
+		attrs = append(attrs, decl.FuncAttrs...)
+		frag.Funcs = append(frag.Funcs, decl)
+	}
+	frag.Funcs = append(frag.Funcs, f)
+	// Attribute group definitions.
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if call, ok := inst.(*InstCall); ok {
+				attrs = append(attrs, call.FuncAttrs...)
+			}
+		}
+		if term, ok := block.Term.(*TermInvoke); ok {
+			attrs = append(attrs, term.FuncAttrs...)
+		}
+	}
+	for _, a := range m.AttrGroupDefs {
+		for _, attr := range attrs {
+			if attr == a {
+				frag.AttrGroupDefs = append(frag.AttrGroupDefs, a)
+				break
+			}
+		}
+	}
+	// Metadata definitions, in order of metadata ID.
+	frag.MetadataDefs = referencedMetadata(f)
+	sort.SliceStable(frag.MetadataDefs, func(i, j int) bool {
+		return frag.MetadataDefs[i].ID() < frag.MetadataDefs[j].ID()
+	})
+	return frag.Def(), nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// collectTypes adds the given type and the types it contains (e.g. element
+// types and struct fields) to the set of types.
+func collectTypes(t types.Type, seen map[types.Type]bool) {
+	if t == nil || seen[t] {
+		return
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *types.FuncType:
+		collectTypes(t.RetType, seen)
+		for _, param := range t.Params {
+			collectTypes(param, seen)
+		}
+	case *types.PointerType:
+		collectTypes(t.ElemType, seen)
+	case *types.VectorType:
+		collectTypes(t.ElemType, seen)
+	case *types.ArrayType:
+		collectTypes(t.ElemType, seen)
+	case *types.StructType:
+		for _, field := range t.Fields {
+			collectTypes(field, seen)
+		}
+	}
+}
+
+// globalDecl returns a declaration of the given global variable.
+func globalDecl(g *Global) *Global {
+	decl := &Global{
+		GlobalName:      g.GlobalName,
+		Immutable:       g.Immutable,
+		ContentType:     g.ContentType,
+		Typ:             g.Typ,
+		Preemption:      g.Preemption,
+		Visibility:      g.Visibility,
+		DLLStorageClass: g.DLLStorageClass,
+		TLSModel:        g.TLSModel,
+		UnnamedAddr:     g.UnnamedAddr,
+		Align:           g.Align,
+	}
+	if g.Linkage == enum.LinkageExternWeak {
+		decl.Linkage = g.Linkage
+	}
+	return decl
+}
+
+// funcDecl returns a declaration of the given function.
+func funcDecl(f *Function) *Function {
+	decl := &Function{
+		Sig:             f.Sig,
+		GlobalName:      f.GlobalName,
+		Typ:             f.Typ,
+		Preemption:      f.Preemption,
+		Visibility:      f.Visibility,
+		DLLStorageClass: f.DLLStorageClass,
+		CallingConv:     f.CallingConv,
+		ReturnAttrs:     f.ReturnAttrs,
+		UnnamedAddr:     f.UnnamedAddr,
+		FuncAttrs:       f.FuncAttrs,
+		Align:           f.Align,
+		GC:              f.GC,
+	}
+	for _, param := range f.Params {
+		decl.Params = append(decl.Params, &Param{Typ: param.Typ, Attrs: param.Attrs})
+	}
+	if f.Linkage == enum.LinkageExternWeak {
+		decl.Linkage = f.Linkage
+	}
+	return decl
+}
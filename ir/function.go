@@ -2,7 +2,6 @@ package ir
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/llir/l/internal/enc"
@@ -20,7 +19,7 @@ type Function struct {
 	Sig *types.FuncType
 	// Function name (without '@' prefix).
 	GlobalName string
-	// Function parameters.
+	// Function parameters; the types of Params are mirrored by Sig.Params.
 	Params []*Param
 	// Basic blocks.
 	Blocks []*BasicBlock
@@ -42,15 +41,19 @@ type Function struct {
 	// (optional) Calling convention; zero value if not present.
 	CallingConv enum.CallingConv
 	// (optional) Return attributes.
-	ReturnAttrs []enum.ReturnAttribute
+	ReturnAttrs []ReturnAttribute
 	// (optional) Unnamed address.
 	UnnamedAddr enum.UnnamedAddr
 	// (optional) Function attributes.
-	FuncAttrs []enum.FuncAttribute
-	// (optional) Section; nil if not present.
+	FuncAttrs []FuncAttribute
+	// (optional) Section; empty if not present.
 	Section string
+	// (optional) Partition; empty if not present.
+	Partition string
 	// (optional) Comdat; nil if not present.
 	Comdat *ComdatDef
+	// (optional) Alignment; zero if not present.
+	Align int64
 	// (optional) Garbage collection; empty if not present.
 	GC string
 	// (optional) Prefix; nil if not present.
@@ -63,26 +66,28 @@ type Function struct {
 	// TODO: add support for UseListOrder.
 	//UseListOrders []*UseListOrder
 	// (optional) Metadata attachments.
-	// TODO: add support for metadata.
-	//Metadata []*metadata.MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the function.
+	Comments []string
+
+	// Parent module of the function, as linked by the module builder methods;
+	// or nil if not present.
+	parent *Module
 }
 
 // TODO: decide whether to have the function name parameter be the first
 // argument (to be consistent with NewGlobal) or after retType (to be consistent
 // with order of occurence in LLVM IR syntax).
 
-// NewFunction returns a new function based on the given function name, return
+// NewFunc returns a new function based on the given function name, return
 // type and function parameters.
-func NewFunction(name string, retType types.Type, params ...*Param) *Function {
-	panic("not yet implemented")
-	/*
-		paramTypes := make([]types.Type, len(params))
-		for i, param := range f.Params {
-			paramType[i] = param.Type()
-		}
-		sig := types.NewFunc(f.RetType, paramTypes...)
-		return &Function{Sig: sig, GlobalName: name, Params: params}
-	*/
+func NewFunc(name string, retType types.Type, params ...*Param) *Function {
+	paramTypes := make([]types.Type, len(params))
+	for i, param := range params {
+		paramTypes[i] = param.Type()
+	}
+	sig := types.NewFunc(retType, paramTypes...)
+	return &Function{Sig: sig, GlobalName: name, Params: params}
 }
 
 // String returns the LLVM syntax representation of the function as a type-value
@@ -112,24 +117,38 @@ func (f *Function) Name() string {
 
 // SetName sets the name of the function.
 func (f *Function) SetName(name string) {
-	f.GlobalName = name
+	f.GlobalName = f.module().internName(name)
 }
 
 // Def returns the LLVM syntax representation of the function definition or
 // declaration.
 func (f *Function) Def() string {
+	return f.def(Printer{})
+}
+
+// def returns the LLVM syntax representation of the function definition or
+// declaration, with the given printing options.
+func (f *Function) def(p Printer) string {
 	// "declare" MetadataAttachments OptExternLinkage FunctionHeader
 	// "define" OptLinkage FunctionHeader MetadataAttachments FunctionBody
+	defer func() {
+		if r := recover(); r != nil {
+			panic(annotate(r, func(e *Error) {
+				if len(e.Global) == 0 {
+					e.Global = f.Ident()
+				}
+			}))
+		}
+	}()
 	buf := &strings.Builder{}
 	if len(f.Blocks) == 0 {
 		// Function declaration.
 		//
 		//    "declare" MetadataAttachments OptExternLinkage FunctionHeader
 		buf.WriteString("declare")
-		// TODO: add metadata support.
-		//for _, md := range f.Metadata {
-		//	fmt.Fprintf(buf, " %v", md)
-		//}
+		for _, md := range f.Metadata {
+			fmt.Fprintf(buf, " %v", md)
+		}
 		if f.Linkage != enum.LinkageNone {
 			fmt.Fprintf(buf, " %v", f.Linkage)
 		}
@@ -144,14 +163,59 @@ func (f *Function) Def() string {
 		fmt.Fprintf(buf, " %v", f.Linkage)
 	}
 	buf.WriteString(headerString(f))
-	// TODO: add metadata support.
-	//for _, md := range f.Metadata {
-	//	fmt.Fprintf(buf, " %v", md)
-	//}
-	fmt.Fprintf(buf, " %v", bodyString(f))
+	for _, md := range f.Metadata {
+		fmt.Fprintf(buf, " %v", md)
+	}
+	fmt.Fprintf(buf, " %v", bodyString(f, p))
 	return buf.String()
 }
 
+// IsDeclaration reports whether the function is a function declaration; i.e.
+// has no basic blocks.
+func (f *Function) IsDeclaration() bool {
+	return len(f.Blocks) == 0
+}
+
+// NewBlock appends a new basic block to the function based on the given label
+// name. An empty label name indicates an unnamed basic block.
+//
+// Appending a basic block to a function declaration upgrades it in place to a
+// function definition. Existing uses of the function (e.g. as callee or in
+// constants) remain valid, as the function value is unchanged.
+func (f *Function) NewBlock(name string) *BasicBlock {
+	block := NewBlock(f.module().internName(name))
+	block.parent = f
+	f.Blocks = append(f.Blocks, block)
+	f.module().notifyInsert(f, block)
+	return block
+}
+
+// Define prepares the function declaration to be upgraded in place to a
+// function definition, by a subsequent call to NewBlock. If params are given,
+// they replace the (typically unnamed) parameters of the declaration; the
+// parameter types must match the function signature. The extern_weak linkage,
+// which is only valid for declarations, is cleared.
+func (f *Function) Define(params ...*Param) error {
+	if !f.IsDeclaration() {
+		return errors.Errorf("unable to define function %v; already defined", f.Ident())
+	}
+	if len(params) > 0 {
+		if len(params) != len(f.Sig.Params) {
+			return errors.Errorf("invalid number of parameters of function %v; expected %d, got %d", f.Ident(), len(f.Sig.Params), len(params))
+		}
+		for i, param := range params {
+			if !param.Type().Equal(f.Sig.Params[i]) {
+				return errors.Errorf("invalid type of parameter %d of function %v; expected %v, got %v", i, f.Ident(), f.Sig.Params[i], param.Type())
+			}
+		}
+		f.Params = params
+	}
+	if f.Linkage == enum.LinkageExternWeak {
+		f.Linkage = enum.LinkageNone
+	}
+	return nil
+}
+
 // AssignIDs assigns IDs to unnamed local variables.
 func (f *Function) AssignIDs() error {
 	if len(f.Blocks) == 0 {
@@ -162,12 +226,12 @@ func (f *Function) AssignIDs() error {
 	setName := func(n value.Named) error {
 		got := n.Name()
 		if isUnnamed(got) {
-			name := strconv.Itoa(id)
+			name := localID(id)
 			n.SetName(name)
 			names[name] = n
 			id++
 		} else if isLocalID(got) {
-			want := strconv.Itoa(id)
+			want := localID(id)
 			if want != got {
 				return errors.Errorf("invalid local ID in function %q, expected %s, got %s", enc.Global(f.GlobalName), enc.Local(want), enc.Local(got))
 			}
@@ -224,7 +288,8 @@ func (f *Function) AssignIDs() error {
 func headerString(hdr *Function) string {
 	// OptPreemptionSpecifier OptVisibility OptDLLStorageClass OptCallingConv
 	// ReturnAttrs Type GlobalIdent "(" Params ")" OptUnnamedAddr FuncAttrs
-	// OptSection OptComdat OptGC OptPrefix OptPrologue OptPersonality
+	// OptSection OptPartition OptComdat OptAlignment OptGC OptPrefix OptPrologue
+	// OptPersonality
 	buf := &strings.Builder{}
 	if hdr.Preemption != enum.PreemptionNone {
 		fmt.Fprintf(buf, " %v", hdr.Preemption)
@@ -265,8 +330,18 @@ func headerString(hdr *Function) string {
 	if len(hdr.Section) > 0 {
 		fmt.Fprintf(buf, " section %v", enc.Quote([]byte(hdr.Section)))
 	}
+	if len(hdr.Partition) > 0 {
+		fmt.Fprintf(buf, " partition %v", enc.Quote([]byte(hdr.Partition)))
+	}
 	if hdr.Comdat != nil {
-		fmt.Fprintf(buf, " %v", hdr.Comdat)
+		if hdr.Comdat.Name == hdr.GlobalName {
+			buf.WriteString(" comdat")
+		} else {
+			fmt.Fprintf(buf, " comdat(%v)", enc.Comdat(hdr.Comdat.Name))
+		}
+	}
+	if hdr.Align != 0 {
+		fmt.Fprintf(buf, " align %d", hdr.Align)
 	}
 	if len(hdr.GC) > 0 {
 		fmt.Fprintf(buf, " gc %v", enc.Quote([]byte(hdr.GC)))
@@ -283,13 +358,14 @@ func headerString(hdr *Function) string {
 	return buf.String()
 }
 
-// bodyString returns the string representation of the function body.
-func bodyString(body *Function) string {
+// bodyString returns the string representation of the function body, with the
+// given printing options.
+func bodyString(body *Function, p Printer) string {
 	// "{" BasicBlockList UseListOrders "}"
 	buf := &strings.Builder{}
 	buf.WriteString("{\n")
 	for _, block := range body.Blocks {
-		fmt.Fprintf(buf, "%v\n", block.Def())
+		fmt.Fprintf(buf, "%v\n", block.def(p))
 	}
 	// TODO: add support for use list orders.
 	//for _, useList := range body.UseListOrders {
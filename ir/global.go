@@ -19,7 +19,7 @@ type Global struct {
 	Immutable bool
 	// Content type.
 	ContentType types.Type
-	// Initial value; or nil if declaration.
+	// Initial value; or nil if declaration or lazily initialized.
 	Init Constant
 
 	// extra.
@@ -40,6 +40,10 @@ type Global struct {
 	TLSModel enum.TLSModel
 	// (optional) Unnamed address; zero value if not present.
 	UnnamedAddr enum.UnnamedAddr
+	// (optional) Lazy initializer, which supplies the initial value of the
+	// global variable when printed; nil if not present. Used in place of Init
+	// to avoid materializing large constants up front.
+	LazyInit func() Constant
 	// (optional) Externally initialized; false if not present.
 	ExternallyInitialized bool
 	// (optional) Section name; empty if not present.
@@ -49,10 +53,12 @@ type Global struct {
 	// (optional) Alignment; zero if not present.
 	Align int64
 	// (optional) Function attributes.
-	FuncAttrs []enum.FuncAttribute
+	FuncAttrs []FuncAttribute
 	// (optional) Metadata attachments.
-	// TODO: add support for metadata.
-	//Metadata []*metadata.MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the global
+	// variable.
+	Comments []string
 }
 
 // NewGlobalDecl returns a new global variable declaration based on the given
@@ -67,6 +73,13 @@ func NewGlobalDef(name string, init Constant) *Global {
 	return &Global{GlobalName: name, ContentType: init.Type(), Init: init}
 }
 
+// NewGlobalLazy returns a new global variable definition based on the given
+// global variable name, content type and lazy initializer. The initial value is
+// supplied by init each time the global variable definition is printed.
+func NewGlobalLazy(name string, contentType types.Type, init func() Constant) *Global {
+	return &Global{GlobalName: name, ContentType: contentType, LazyInit: init}
+}
+
 // String returns the LLVM syntax representation of the global variable as a
 // type-value pair.
 func (g *Global) String() string {
@@ -103,10 +116,26 @@ func (g *Global) Def() string {
 	// GlobalIdent "=" OptLinkage OptPreemptionSpecifier OptVisibility
 	// OptDLLStorageClass OptThreadLocal OptUnnamedAddr OptAddrSpace
 	// OptExternallyInitialized Immutable Type Constant GlobalAttrs FuncAttrs
+	defer func() {
+		if r := recover(); r != nil {
+			panic(annotate(r, func(e *Error) {
+				if len(e.Global) == 0 {
+					e.Global = g.Ident()
+				}
+			}))
+		}
+	}()
 	buf := &strings.Builder{}
 	fmt.Fprintf(buf, "%s =", g.Ident())
+	init := g.Init
+	if init == nil && g.LazyInit != nil {
+		init = g.LazyInit()
+	}
 	if g.Linkage != enum.LinkageNone {
 		fmt.Fprintf(buf, " %s", g.Linkage)
+	} else if init == nil {
+		// Global variable declaration.
+		buf.WriteString(" external")
 	}
 	if g.Preemption != enum.PreemptionNone {
 		fmt.Fprintf(buf, " %s", g.Preemption)
@@ -123,11 +152,11 @@ func (g *Global) Def() string {
 	if g.UnnamedAddr != enum.UnnamedAddrNone {
 		fmt.Fprintf(buf, " %s", g.UnnamedAddr)
 	}
-	if g.Typ.AddrSpace != 0 {
+	if g.Typ != nil && g.Typ.AddrSpace != 0 {
 		fmt.Fprintf(buf, " %s", g.Typ.AddrSpace)
 	}
 	if g.ExternallyInitialized {
-		buf.WriteString(" externallyinitialized")
+		buf.WriteString(" externally_initialized")
 	}
 	if g.Immutable {
 		buf.WriteString(" constant")
@@ -135,8 +164,8 @@ func (g *Global) Def() string {
 		buf.WriteString(" global")
 	}
 	fmt.Fprintf(buf, " %s", g.ContentType)
-	if g.Init != nil {
-		fmt.Fprintf(buf, " %s", g.Init.Ident())
+	if init != nil {
+		fmt.Fprintf(buf, " %s", init.Ident())
 	}
 	if g.Section != "" {
 		fmt.Fprintf(buf, ", section %s", quote(g.Section))
@@ -147,10 +176,9 @@ func (g *Global) Def() string {
 	if g.Align != 0 {
 		fmt.Fprintf(buf, ", align %d", g.Align)
 	}
-	// TODO: add metadata.
-	//for _, md := range g.Metadata {
-	//	fmt.Fprintf(buf, ", %s", md)
-	//}
+	for _, md := range g.Metadata {
+		fmt.Fprintf(buf, ", %s", md)
+	}
 	// TODO: add function attributes.
 	//for _, attr := range g.FuncAttrs {
 	//	fmt.Fprintf(buf, " %s", attr)
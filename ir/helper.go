@@ -7,19 +7,105 @@ import (
 	"github.com/llir/l/internal/enc"
 	"github.com/llir/l/ir/enum"
 	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
 )
 
-// TODO: move to the right place.
+// --- [ Function arguments ] --------------------------------------------------
+
+// Arg is a function argument with optional parameter attributes, as used at
+// call sites.
+type Arg struct {
+	// Argument value.
+	value.Value
+	// (optional) Parameter attributes.
+	Attrs []ParamAttribute
+}
+
+// NewArg returns a new function argument based on the given value and
+// parameter attributes.
+func NewArg(x value.Value, attrs ...ParamAttribute) *Arg {
+	return &Arg{Value: x, Attrs: attrs}
+}
+
+// String returns the LLVM syntax representation of the function argument.
+func (arg *Arg) String() string {
+	// Type ParamAttrs Value
+	buf := &strings.Builder{}
+	buf.WriteString(arg.Type().String())
+	for _, attr := range arg.Attrs {
+		fmt.Fprintf(buf, " %v", attr)
+	}
+	fmt.Fprintf(buf, " %v", arg.Ident())
+	return buf.String()
+}
+
+// --- [ Operand bundles ] ----------------------------------------------------
 
-// TODO: figure out definition of arg.
-type Arg interface {
-	String() string
-	isArg()
+// OperandBundle is a tagged list of operands (e.g. "deopt"), as used at call
+// sites.
+type OperandBundle struct {
+	// Operand bundle tag.
+	Tag string
+	// Operand bundle inputs.
+	Inputs []value.Value
 }
 
+// NewOperandBundle returns a new operand bundle based on the given tag and
+// inputs.
+func NewOperandBundle(tag string, inputs ...value.Value) *OperandBundle {
+	return &OperandBundle{Tag: tag, Inputs: inputs}
+}
+
+// String returns the LLVM syntax representation of the operand bundle.
+func (b *OperandBundle) String() string {
+	// StringLit "(" TypeValues ")"
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "%v(", quote(b.Tag))
+	for i, input := range b.Inputs {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(input.String())
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
+// TODO: move to the right place.
+
 // TODO: remove IsUnwindTarget? or unexport.
 func (*BasicBlock) IsUnwindTarget() {}
 
+// IsExceptionScope ensures that only exception scopes can be assigned to the
+// enum.ExceptionScope interface.
+func (*ConstNone) IsExceptionScope() {}
+
+// IsExceptionScope ensures that only exception scopes can be assigned to the
+// enum.ExceptionScope interface.
+func (*InstCatchPad) IsExceptionScope() {}
+
+// IsExceptionScope ensures that only exception scopes can be assigned to the
+// enum.ExceptionScope interface.
+func (*InstCleanupPad) IsExceptionScope() {}
+
+// scopeIdent returns the identifier of the given exception scope; "none" if
+// nil or the none token constant.
+func scopeIdent(scope enum.ExceptionScope) string {
+	if v, ok := scope.(value.Value); ok {
+		return v.Ident()
+	}
+	return "none"
+}
+
+// unwindTargetString returns the string representation of the given unwind
+// target; "to caller" if nil.
+func unwindTargetString(unwindTarget enum.UnwindTarget) string {
+	if block, ok := unwindTarget.(*BasicBlock); ok && block != nil {
+		return block.String()
+	}
+	return "to caller"
+}
+
 // --- [ Function parameters ] -------------------------------------------------
 
 // Param is an LLVM IR function parameter.
@@ -32,7 +118,7 @@ type Param struct {
 	// extra.
 
 	// (optional) Parameter attributes.
-	Attrs []enum.ParamAttribute
+	Attrs []ParamAttribute
 }
 
 // NewParam returns a new function parameter based on the given type and name.
@@ -89,12 +175,7 @@ func isUnnamed(name string) bool {
 
 // isLocalID reports whether the given identifier is a local ID (e.g. "%42").
 func isLocalID(name string) bool {
-	for _, r := range name {
-		if !strings.ContainsRune("0123456789", r) {
-			return false
-		}
-	}
-	return len(name) > 0
+	return enc.IsID(name)
 }
 
 // quote returns s as a double-quoted string literal.
@@ -107,3 +188,290 @@ func quote(s string) string {
 func unquote(s string) string {
 	return string(enc.Unquote(s))
 }
+
+// writeComments writes the given comments to buf as `;` comment lines with the
+// given indentation. Comments spanning multiple lines are split into one
+// comment line per line.
+func writeComments(buf *strings.Builder, indent string, comments []string) {
+	for _, comment := range comments {
+		for _, line := range strings.Split(comment, "\n") {
+			if len(line) == 0 {
+				fmt.Fprintf(buf, "%s;\n", indent)
+				continue
+			}
+			fmt.Fprintf(buf, "%s; %s\n", indent, line)
+		}
+	}
+}
+
+// commenter is implemented by instructions and terminators with comments.
+type commenter interface {
+	// comments returns the comments of the instruction or terminator.
+	comments() []string
+}
+
+// commentsOf returns the comments of the given instruction or terminator; or
+// nil if not present (e.g. custom instructions).
+func commentsOf(inst interface{}) []string {
+	if c, ok := inst.(commenter); ok {
+		return c.comments()
+	}
+	return nil
+}
+
+// icmpType returns the result type of an icmp with operands of the given type.
+func icmpType(xType types.Type) (types.Type, error) {
+	switch xType := xType.(type) {
+	case *types.IntType, *types.PointerType:
+		return types.I1, nil
+	case *types.VectorType:
+		return &types.VectorType{Len: xType.Len, ElemType: types.I1, Scalable: xType.Scalable}, nil
+	default:
+		return nil, errorf("invalid icmp operand type; expected *types.IntType, *types.PointerType or *types.VectorType, got %T", xType)
+	}
+}
+
+// fcmpType returns the result type of an fcmp with operands of the given type.
+func fcmpType(xType types.Type) (types.Type, error) {
+	switch xType := xType.(type) {
+	case *types.FloatType:
+		return types.I1, nil
+	case *types.VectorType:
+		return &types.VectorType{Len: xType.Len, ElemType: types.I1, Scalable: xType.Scalable}, nil
+	default:
+		return nil, errorf("invalid fcmp operand type; expected *types.FloatType or *types.VectorType, got %T", xType)
+	}
+}
+
+// validateSelect reports whether the condition and operand types of a select
+// instruction or constant expression are valid; i.e. an i1 condition, or a
+// vector of i1 condition of the same length as the vector operands, and
+// operands of the same type.
+func validateSelect(condType, xType, yType types.Type) error {
+	if !xType.Equal(yType) {
+		return errorf("operand type mismatch of select; %v and %v", xType, yType)
+	}
+	if condType.Equal(types.I1) {
+		return nil
+	}
+	cond, ok := condType.(*types.VectorType)
+	if !ok || !cond.ElemType.Equal(types.I1) {
+		return errorf("invalid condition type of select; expected i1 or vector of i1, got %v", condType)
+	}
+	x, ok := xType.(*types.VectorType)
+	if !ok || x.Len != cond.Len || x.Scalable != cond.Scalable {
+		return errorf("vector length mismatch of select; condition %v and operand %v", condType, xType)
+	}
+	return nil
+}
+
+// calleeSig returns the function signature of a callee of the given type. The
+// role of the callee (e.g. "callee" or "invokee") is used in error messages.
+func calleeSig(role string, t types.Type) (*types.FuncType, error) {
+	ptr, err := pointerType(role, t)
+	if err != nil {
+		return nil, err
+	}
+	sig, ok := ptr.ElemType.(*types.FuncType)
+	if !ok {
+		return nil, errorf("invalid %s type; expected *types.FuncType, got %T", role, ptr.ElemType)
+	}
+	return sig, nil
+}
+
+// callSig returns the function signature of a call site with the given callee
+// type (or nil to infer) and callee. The role of the callee (e.g. "callee" or
+// "invokee") is used in error messages.
+func callSig(role string, calleeType *types.FuncType, callee value.Value) (*types.FuncType, error) {
+	if calleeType != nil {
+		return calleeType, nil
+	}
+	return calleeSig(role, callee.Type())
+}
+
+// validateCall reports whether the callee type of a call site with the given
+// callee type (or nil to infer), callee, arguments and cached result type (or
+// nil if not yet cached) is valid; i.e. whether the fixed arguments agree with
+// the parameter types of the callee, the result type with the return type of
+// the callee, and the byval and sret parameter attributes of the arguments with
+// those of the parameters of callee functions. The role of the callee (e.g.
+// "callee" or "invokee") is used in error messages.
+func validateCall(role string, calleeType *types.FuncType, callee value.Value, args []value.Value, typ types.Type) error {
+	sig, err := calleeSig(role, callee.Type())
+	switch {
+	case calleeType == nil && err != nil:
+		return err
+	case calleeType != nil && err == nil && !sig.Equal(calleeType):
+		return errorf("%s type mismatch; expected %v, got %v", role, calleeType, sig)
+	case calleeType != nil:
+		sig = calleeType
+	}
+	switch {
+	case len(args) < len(sig.Params):
+		return errorf("too few arguments of %s %v; expected %d, got %d", role, callee.Ident(), len(sig.Params), len(args))
+	case len(args) > len(sig.Params) && !sig.Variadic:
+		return errorf("too many arguments of %s %v; expected %d, got %d", role, callee.Ident(), len(sig.Params), len(args))
+	}
+	for i, param := range sig.Params {
+		if !args[i].Type().Equal(param) {
+			return errorf("invalid argument %d type of %s %v; expected %v, got %v", i, role, callee.Ident(), param, args[i].Type())
+		}
+	}
+	if typ != nil && !typ.Equal(sig.RetType) {
+		return errorf("result type mismatch of %s %v; expected %v, got %v", role, callee.Ident(), sig.RetType, typ)
+	}
+	if f, ok := callee.(*Function); ok {
+		for i, param := range f.Params {
+			if i >= len(args) {
+				break
+			}
+			var attrs []ParamAttribute
+			if arg, ok := args[i].(*Arg); ok {
+				attrs = arg.Attrs
+			}
+			for _, attr := range []enum.ParamAttr{enum.ParamAttrByval, enum.ParamAttrSRet} {
+				if hasParamAttr(attrs, attr) != hasParamAttr(param.Attrs, attr) {
+					return errorf("%v attribute mismatch of argument %d of %s %v", attr, i, role, callee.Ident())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hasParamAttr reports whether the given parameter attribute is present among
+// attrs.
+func hasParamAttr(attrs []ParamAttribute, attr enum.ParamAttr) bool {
+	for _, a := range attrs {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// callType returns the type printed at a call site with the given result type,
+// callee type (or nil to infer) and callee; the function type of the callee if
+// variadic or if the callee type may not be inferred from the callee, and the
+// result type otherwise.
+func callType(retType types.Type, calleeType *types.FuncType, callee value.Value) types.Type {
+	sig, err := calleeSig("callee", callee.Type())
+	switch {
+	case calleeType != nil && (calleeType.Variadic || err != nil || !sig.Equal(calleeType)):
+		return calleeType
+	case calleeType == nil && err == nil && sig.Variadic:
+		return sig
+	}
+	return retType
+}
+
+// pointerType returns the given type as a pointer type. The role of the
+// operand (e.g. "source") is used in error messages.
+func pointerType(role string, t types.Type) (*types.PointerType, error) {
+	ptr, ok := t.(*types.PointerType)
+	if !ok {
+		return nil, errorf("invalid %s type; expected *types.PointerType, got %T", role, t)
+	}
+	return ptr, nil
+}
+
+// vectorType returns the given type as a vector type.
+func vectorType(t types.Type) (*types.VectorType, error) {
+	vec, ok := t.(*types.VectorType)
+	if !ok {
+		return nil, errorf("invalid vector type; expected *types.VectorType, got %T", t)
+	}
+	return vec, nil
+}
+
+// gepType returns the result type of a getelementptr with the given source
+// element type, source address type and element indices; i.e. a pointer to the
+// indexed element type, in the address space of the source address.
+//
+// The result type is a vector of pointers if the source address or any of the
+// indices is a vector (e.g. <4 x i32*>), with the vector length thereof.
+func gepType(elemType, srcType types.Type, indices []value.Value) (types.Type, error) {
+	t := elemType
+	for i, index := range indices {
+		if i == 0 {
+			// The first index steps over values of the source element type.
+			continue
+		}
+		switch tt := t.(type) {
+		case *types.ArrayType:
+			t = tt.ElemType
+		case *types.VectorType:
+			t = tt.ElemType
+		case *types.StructType:
+			c, ok := structIndex(index)
+			if !ok {
+				return nil, errorf("invalid struct field index %v; expected integer constant", index)
+			}
+			if !c.X.IsInt64() || c.X.Int64() < 0 || c.X.Int64() >= int64(len(tt.Fields)) {
+				return nil, errorf("invalid struct field index %v of %v; expected index in range [0, %d)", c.X, tt, len(tt.Fields))
+			}
+			t = tt.Fields[c.X.Int64()]
+		default:
+			return nil, errorf("invalid getelementptr index into non-aggregate type %v", t)
+		}
+	}
+	typ := types.NewPointer(t)
+	// Vector length of vector getelementptr; or nil if scalar.
+	var vec *types.VectorType
+	setVec := func(v *types.VectorType) error {
+		if vec != nil && (vec.Len != v.Len || vec.Scalable != v.Scalable) {
+			return errorf("vector length mismatch of getelementptr operands; %v and %v", vec, v)
+		}
+		vec = v
+		return nil
+	}
+	src := srcType
+	if v, ok := srcType.(*types.VectorType); ok {
+		if err := setVec(v); err != nil {
+			return nil, err
+		}
+		src = v.ElemType
+	}
+	if src, ok := src.(*types.PointerType); ok {
+		typ.AddrSpace = src.AddrSpace
+	}
+	for _, index := range indices {
+		if v, ok := index.Type().(*types.VectorType); ok {
+			if err := setVec(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if vec != nil {
+		result := types.NewVector(vec.Len, typ)
+		result.Scalable = vec.Scalable
+		return result, nil
+	}
+	return typ, nil
+}
+
+// structIndex returns the integer constant of the given struct field index; a
+// scalar integer constant, or a splat vector of integer constants (e.g. as used
+// by vector getelementptr).
+func structIndex(index value.Value) (*ConstInt, bool) {
+	switch index := index.(type) {
+	case *ConstInt:
+		return index, true
+	case *ConstVector:
+		if len(index.Elems) == 0 {
+			return nil, false
+		}
+		first, ok := index.Elems[0].(*ConstInt)
+		if !ok {
+			return nil, false
+		}
+		for _, elem := range index.Elems[1:] {
+			c, ok := elem.(*ConstInt)
+			if !ok || c.X.Cmp(first.X) != 0 {
+				return nil, false
+			}
+		}
+		return first, true
+	}
+	return nil, false
+}
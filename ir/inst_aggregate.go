@@ -27,7 +27,14 @@ type InstExtractValue struct {
 	// Type of result produced by the instruction.
 	Typ types.Type
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewExtractValue returns a new extractvalue instruction based on the given
@@ -42,15 +49,26 @@ func (inst *InstExtractValue) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction; or types.Invalid if the indices are
+// invalid.
 func (inst *InstExtractValue) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		inst.Typ = aggregateElemType(inst.X.Type(), inst.Indices)
+		t, err := aggregateElemType(inst.X.Type(), inst.Indices)
+		if err != nil {
+			return types.Invalid
+		}
+		inst.Typ = t
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the indices of the instruction are valid.
+func (inst *InstExtractValue) Validate() error {
+	_, err := aggregateElemType(inst.X.Type(), inst.Indices)
+	return err
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstExtractValue) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -63,7 +81,9 @@ func (inst *InstExtractValue) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstExtractValue) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -98,7 +118,14 @@ type InstInsertValue struct {
 	// Type of result produced by the instruction.
 	Typ types.Type
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewInsertValue returns a new insertvalue instruction based on the given
@@ -134,7 +161,9 @@ func (inst *InstInsertValue) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstInsertValue) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -155,17 +184,20 @@ func (inst *InstInsertValue) Def() string {
 
 // aggregateElemType returns the element type at the position in the aggregate
 // type specified by the given indices.
-func aggregateElemType(t types.Type, indices []int64) types.Type {
+func aggregateElemType(t types.Type, indices []int64) (types.Type, error) {
 	// Base case.
 	if len(indices) == 0 {
-		return t
+		return t, nil
 	}
 	switch t := t.(type) {
 	case *types.ArrayType:
 		return aggregateElemType(t.ElemType, indices[1:])
 	case *types.StructType:
+		if indices[0] < 0 || indices[0] >= int64(len(t.Fields)) {
+			return nil, errorf("invalid struct field index %d of %v; expected index in range [0, %d)", indices[0], t, len(t.Fields))
+		}
 		return aggregateElemType(t.Fields[indices[0]], indices[1:])
 	default:
-		panic(fmt.Errorf("support for aggregate type %T not yet implemented", t))
+		return nil, errorf("invalid aggregate type; expected *types.ArrayType or *types.StructType, got %T", t)
 	}
 }
@@ -28,7 +28,14 @@ type InstAdd struct {
 	// (optional) Overflow flags.
 	OverflowFlags []enum.OverflowFlag
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewAdd returns a new add instruction based on the given operands.
@@ -63,7 +70,9 @@ func (inst *InstAdd) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstAdd) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -97,7 +106,14 @@ type InstFAdd struct {
 	// (optional) Fast math flags.
 	FastMathFlags []enum.FastMathFlag
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewFAdd returns a new fadd instruction based on the given operands.
@@ -132,7 +148,9 @@ func (inst *InstFAdd) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstFAdd) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -166,7 +184,14 @@ type InstSub struct {
 	// (optional) Overflow flags.
 	OverflowFlags []enum.OverflowFlag
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewSub returns a new sub instruction based on the given operands.
@@ -201,7 +226,9 @@ func (inst *InstSub) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstSub) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -235,7 +262,14 @@ type InstFSub struct {
 	// (optional) Fast math flags.
 	FastMathFlags []enum.FastMathFlag
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewFSub returns a new fsub instruction based on the given operands.
@@ -270,7 +304,9 @@ func (inst *InstFSub) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstFSub) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -304,7 +340,14 @@ type InstMul struct {
 	// (optional) Overflow flags.
 	OverflowFlags []enum.OverflowFlag
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewMul returns a new mul instruction based on the given operands.
@@ -339,7 +382,9 @@ func (inst *InstMul) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstMul) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -373,7 +418,14 @@ type InstFMul struct {
 	// (optional) Fast math flags.
 	FastMathFlags []enum.FastMathFlag
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewFMul returns a new fmul instruction based on the given operands.
@@ -408,7 +460,9 @@ func (inst *InstFMul) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstFMul) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -442,7 +496,14 @@ type InstUDiv struct {
 	// (optional) Exact.
 	Exact bool
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewUDiv returns a new udiv instruction based on the given operands.
@@ -477,7 +538,9 @@ func (inst *InstUDiv) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstUDiv) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -511,7 +574,14 @@ type InstSDiv struct {
 	// (optional) Exact.
 	Exact bool
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewSDiv returns a new sdiv instruction based on the given operands.
@@ -546,7 +616,9 @@ func (inst *InstSDiv) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstSDiv) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -580,7 +652,14 @@ type InstFDiv struct {
 	// (optional) Fast math flags.
 	FastMathFlags []enum.FastMathFlag
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewFDiv returns a new fdiv instruction based on the given operands.
@@ -615,7 +694,9 @@ func (inst *InstFDiv) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstFDiv) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -647,7 +728,14 @@ type InstURem struct {
 	// Type of result produced by the instruction.
 	Typ types.Type
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewURem returns a new urem instruction based on the given operands.
@@ -682,7 +770,9 @@ func (inst *InstURem) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstURem) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -710,7 +800,14 @@ type InstSRem struct {
 	// Type of result produced by the instruction.
 	Typ types.Type
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewSRem returns a new srem instruction based on the given operands.
@@ -745,7 +842,9 @@ func (inst *InstSRem) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstSRem) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -775,7 +874,14 @@ type InstFRem struct {
 	// (optional) Fast math flags.
 	FastMathFlags []enum.FastMathFlag
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewFRem returns a new frem instruction based on the given operands.
@@ -810,7 +916,9 @@ func (inst *InstFRem) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstFRem) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
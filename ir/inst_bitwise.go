@@ -28,7 +28,14 @@ type InstShl struct {
 	// (optional) Overflow flags.
 	OverflowFlags []enum.OverflowFlag
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewShl returns a new shl instruction based on the given operands.
@@ -63,7 +70,9 @@ func (inst *InstShl) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstShl) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -97,7 +106,14 @@ type InstLShr struct {
 	// (optional) Exact.
 	Exact bool
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewLShr returns a new lshr instruction based on the given operands.
@@ -132,7 +148,9 @@ func (inst *InstLShr) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstLShr) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -166,7 +184,14 @@ type InstAShr struct {
 	// (optional) Exact.
 	Exact bool
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewAShr returns a new ashr instruction based on the given operands.
@@ -201,7 +226,9 @@ func (inst *InstAShr) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstAShr) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -233,7 +260,14 @@ type InstAnd struct {
 	// Type of result produced by the instruction.
 	Typ types.Type
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewAnd returns a new and instruction based on the given operands.
@@ -268,7 +302,9 @@ func (inst *InstAnd) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstAnd) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -296,7 +332,14 @@ type InstOr struct {
 	// Type of result produced by the instruction.
 	Typ types.Type
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewOr returns a new or instruction based on the given operands.
@@ -331,7 +374,9 @@ func (inst *InstOr) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstOr) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -359,7 +404,14 @@ type InstXor struct {
 	// Type of result produced by the instruction.
 	Typ types.Type
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewXor returns a new xor instruction based on the given operands.
@@ -394,7 +446,9 @@ func (inst *InstXor) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstXor) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
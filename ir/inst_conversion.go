@@ -25,7 +25,14 @@ type InstTrunc struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewTrunc returns a new trunc instruction based on the given source value and
@@ -57,7 +64,9 @@ func (inst *InstTrunc) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstTrunc) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -85,7 +94,14 @@ type InstZExt struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewZExt returns a new zext instruction based on the given source value and
@@ -117,7 +133,9 @@ func (inst *InstZExt) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstZExt) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -145,7 +163,14 @@ type InstSExt struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewSExt returns a new sext instruction based on the given source value and
@@ -177,7 +202,9 @@ func (inst *InstSExt) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstSExt) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -205,7 +232,14 @@ type InstFPTrunc struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewFPTrunc returns a new fptrunc instruction based on the given source value
@@ -237,7 +271,9 @@ func (inst *InstFPTrunc) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstFPTrunc) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -265,7 +301,14 @@ type InstFPExt struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewFPExt returns a new fpext instruction based on the given source value and
@@ -297,7 +340,9 @@ func (inst *InstFPExt) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstFPExt) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -325,7 +370,14 @@ type InstFPToUI struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewFPToUI returns a new fptoui instruction based on the given source value
@@ -357,7 +409,9 @@ func (inst *InstFPToUI) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstFPToUI) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -385,7 +439,14 @@ type InstFPToSI struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewFPToSI returns a new fptosi instruction based on the given source value
@@ -417,7 +478,9 @@ func (inst *InstFPToSI) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstFPToSI) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -445,7 +508,14 @@ type InstUIToFP struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewUIToFP returns a new uitofp instruction based on the given source value
@@ -477,7 +547,9 @@ func (inst *InstUIToFP) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstUIToFP) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -505,7 +577,14 @@ type InstSIToFP struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewSIToFP returns a new sitofp instruction based on the given source value
@@ -537,7 +616,9 @@ func (inst *InstSIToFP) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstSIToFP) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -565,7 +646,14 @@ type InstPtrToInt struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewPtrToInt returns a new ptrtoint instruction based on the given source
@@ -597,7 +685,9 @@ func (inst *InstPtrToInt) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstPtrToInt) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -625,7 +715,14 @@ type InstIntToPtr struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewIntToPtr returns a new inttoptr instruction based on the given source
@@ -657,7 +754,9 @@ func (inst *InstIntToPtr) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstIntToPtr) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -685,7 +784,14 @@ type InstBitCast struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewBitCast returns a new bitcast instruction based on the given source value
@@ -717,7 +823,9 @@ func (inst *InstBitCast) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstBitCast) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -745,7 +853,14 @@ type InstAddrSpaceCast struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewAddrSpaceCast returns a new addrspacecast instruction based on the given
@@ -777,7 +892,9 @@ func (inst *InstAddrSpaceCast) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstAddrSpaceCast) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -25,8 +25,7 @@ type InstAlloca struct {
 
 	// extra.
 
-	// Type of result produced by the instruction, including an optional address
-	// space.
+	// Type of result produced by the instruction.
 	Typ *types.PointerType
 	// (optional) In-alloca.
 	InAlloca bool
@@ -34,8 +33,17 @@ type InstAlloca struct {
 	SwiftError bool
 	// (optional) Alignment; zero if not present.
 	Alignment int
+	// (optional) Address space of the result pointer; zero if not present.
+	AddrSpace types.AddrSpace
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewAlloca returns a new alloca instruction based on the given element type.
@@ -54,6 +62,7 @@ func (inst *InstAlloca) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
 		inst.Typ = types.NewPointer(inst.ElemType)
+		inst.Typ.AddrSpace = inst.AddrSpace
 	}
 	return inst.Typ
 }
@@ -70,7 +79,9 @@ func (inst *InstAlloca) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstAlloca) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -91,8 +102,8 @@ func (inst *InstAlloca) Def() string {
 	if inst.Alignment != 0 {
 		fmt.Fprintf(buf, ", align %v", inst.Alignment)
 	}
-	if inst.Typ.AddrSpace != 0 {
-		fmt.Fprintf(buf, ", %v", inst.Typ.AddrSpace)
+	if inst.AddrSpace != 0 {
+		fmt.Fprintf(buf, ", %v", inst.AddrSpace)
 	}
 	for _, md := range inst.Metadata {
 		fmt.Fprintf(buf, ", %v", md)
@@ -124,7 +135,14 @@ type InstLoad struct {
 	// (optional) Alignment; zero if not present.
 	Alignment int
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewLoad returns a new load instruction based on the given source address.
@@ -138,19 +156,26 @@ func (inst *InstLoad) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction; or types.Invalid if the source
+// type is invalid.
 func (inst *InstLoad) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		t, ok := inst.Src.Type().(*types.PointerType)
-		if !ok {
-			panic(fmt.Errorf("invalid source type; expected *types.PointerType, got %T", inst.Src.Type()))
+		t, err := pointerType("source", inst.Src.Type())
+		if err != nil {
+			return types.Invalid
 		}
 		inst.Typ = t.ElemType
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the source type of the instruction is valid.
+func (inst *InstLoad) Validate() error {
+	_, err := pointerType("source", inst.Src.Type())
+	return err
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstLoad) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -163,7 +188,9 @@ func (inst *InstLoad) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstLoad) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -178,7 +205,7 @@ func (inst *InstLoad) Def() string {
 	if inst.Volatile {
 		buf.WriteString(" volatile")
 	}
-	fmt.Fprintf(buf, " %v, %v", inst.Typ, inst.Src)
+	fmt.Fprintf(buf, " %v, %v", inst.Type(), inst.Src)
 	if len(inst.SyncScope) > 0 {
 		fmt.Fprintf(buf, " syncscope(%v)", enc.Quote([]byte(inst.SyncScope)))
 	}
@@ -216,7 +243,10 @@ type InstStore struct {
 	// (optional) Alignment; zero if not present.
 	Alignment int
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewStore returns a new store instruction based on the given source value and
@@ -265,7 +295,10 @@ type InstFence struct {
 	// (optional) Sync scope; empty if not present.
 	SyncScope string
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewFence returns a new fence instruction based on the given atomic ordering.
@@ -317,7 +350,14 @@ type InstCmpXchg struct {
 	// (optional) Sync scope; empty if not present.
 	SyncScope string
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewCmpXchg returns a new cmpxchg instruction based on the given address,
@@ -355,7 +395,9 @@ func (inst *InstCmpXchg) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstCmpXchg) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -405,7 +447,14 @@ type InstAtomicRMW struct {
 	// (optional) Sync scope; empty if not present.
 	SyncScope string
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewAtomicRMW returns a new atomicrmw instruction based on the given atomic
@@ -420,19 +469,26 @@ func (inst *InstAtomicRMW) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction; or types.Invalid if the destination
+// type is invalid.
 func (inst *InstAtomicRMW) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		t, ok := inst.Dst.Type().(*types.PointerType)
-		if !ok {
-			panic(fmt.Errorf("invalid destination type; expected *types.PointerType, got %T", inst.Dst.Type()))
+		t, err := pointerType("destination", inst.Dst.Type())
+		if err != nil {
+			return types.Invalid
 		}
 		inst.Typ = t.ElemType
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the destination type of the instruction is valid.
+func (inst *InstAtomicRMW) Validate() error {
+	_, err := pointerType("destination", inst.Dst.Type())
+	return err
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstAtomicRMW) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -445,7 +501,9 @@ func (inst *InstAtomicRMW) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstAtomicRMW) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -487,7 +545,14 @@ type InstGetElementPtr struct {
 	// (optional) In-bounds.
 	InBounds bool
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewGetElementPtr returns a new getelementptr instruction based on the given
@@ -502,15 +567,26 @@ func (inst *InstGetElementPtr) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction; or types.Invalid if the indices are
+// invalid.
 func (inst *InstGetElementPtr) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		inst.Typ = types.NewPointer(inst.ElemType)
+		t, err := gepType(inst.ElemType, inst.Src.Type(), inst.Indices)
+		if err != nil {
+			return types.Invalid
+		}
+		inst.Typ = t
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the indices of the instruction are valid.
+func (inst *InstGetElementPtr) Validate() error {
+	_, err := gepType(inst.ElemType, inst.Src.Type(), inst.Indices)
+	return err
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstGetElementPtr) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -523,7 +599,9 @@ func (inst *InstGetElementPtr) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstGetElementPtr) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
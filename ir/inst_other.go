@@ -28,7 +28,14 @@ type InstICmp struct {
 	// Type of result produced by the instruction.
 	Typ types.Type // boolean or boolean vector
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewICmp returns a new icmp instruction based on the given integer comparison
@@ -43,22 +50,26 @@ func (inst *InstICmp) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction; or types.Invalid if the operand
+// type is invalid.
 func (inst *InstICmp) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		switch xType := inst.X.Type().(type) {
-		case *types.IntType, *types.PointerType:
-			inst.Typ = types.I1
-		case *types.VectorType:
-			inst.Typ = types.NewVector(xType.Len, types.I1)
-		default:
-			panic(fmt.Errorf("invalid icmp operand type; expected *types.IntType, *types.PointerType or *types.VectorType, got %T", xType))
+		t, err := icmpType(inst.X.Type())
+		if err != nil {
+			return types.Invalid
 		}
+		inst.Typ = t
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the operand type of the instruction is valid.
+func (inst *InstICmp) Validate() error {
+	_, err := icmpType(inst.X.Type())
+	return err
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstICmp) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -71,7 +82,9 @@ func (inst *InstICmp) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstICmp) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -103,7 +116,14 @@ type InstFCmp struct {
 	// (optional) Fast math flags.
 	FastMathFlags []enum.FastMathFlag
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewFCmp returns a new fcmp instruction based on the given floating-point
@@ -118,22 +138,26 @@ func (inst *InstFCmp) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction; or types.Invalid if the operand
+// type is invalid.
 func (inst *InstFCmp) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		switch xType := inst.X.Type().(type) {
-		case *types.FloatType:
-			inst.Typ = types.I1
-		case *types.VectorType:
-			inst.Typ = types.NewVector(xType.Len, types.I1)
-		default:
-			panic(fmt.Errorf("invalid fcmp operand type; expected *types.FloatType or *types.VectorType, got %T", xType))
+		t, err := fcmpType(inst.X.Type())
+		if err != nil {
+			return types.Invalid
 		}
+		inst.Typ = t
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the operand type of the instruction is valid.
+func (inst *InstFCmp) Validate() error {
+	_, err := fcmpType(inst.X.Type())
+	return err
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstFCmp) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -146,7 +170,9 @@ func (inst *InstFCmp) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstFCmp) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -178,7 +204,14 @@ type InstPhi struct {
 	// Type of result produced by the instruction.
 	Typ types.Type // type of incoming value
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewPhi returns a new phi instruction based on the given incoming values.
@@ -192,15 +225,45 @@ func (inst *InstPhi) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// AddIncoming appends an incoming value from the given predecessor basic block
+// to the phi instruction.
+func (inst *InstPhi) AddIncoming(x value.Value, pred *BasicBlock) {
+	inst.Incs = append(inst.Incs, NewIncoming(x, pred))
+}
+
+// Type returns the type of the instruction, as inferred from the first incoming
+// value; or types.Invalid if the instruction has no incoming values.
 func (inst *InstPhi) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
+		if len(inst.Incs) == 0 {
+			return types.Invalid
+		}
 		inst.Typ = inst.Incs[0].X.Type()
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the instruction has incoming values of the same
+// type.
+func (inst *InstPhi) Validate() error {
+	if len(inst.Incs) == 0 {
+		if inst.Typ != nil {
+			// Type specified explicitly (e.g. of a phi instruction with incoming
+			// values yet to be added).
+			return nil
+		}
+		return errorf("invalid phi instruction; expected at least one incoming value")
+	}
+	t := inst.Type()
+	for _, inc := range inst.Incs {
+		if got := inc.X.Type(); !got.Equal(t) {
+			return errorf("incoming value type mismatch of phi instruction; expected %v, got %v", t, got)
+		}
+	}
+	return nil
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstPhi) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -213,14 +276,16 @@ func (inst *InstPhi) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstPhi) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
 func (inst *InstPhi) Def() string {
 	// "phi" Type IncList OptCommaSepMetadataAttachmentList
 	buf := &strings.Builder{}
-	fmt.Fprintf(buf, "phi %v ", inst.Typ)
+	fmt.Fprintf(buf, "phi %v ", inst.Type())
 	for i, inc := range inst.Incs {
 		if i != 0 {
 			buf.WriteString(", ")
@@ -271,13 +336,20 @@ type InstSelect struct {
 	// Type of result produced by the instruction.
 	Typ types.Type
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewSelect returns a new select instruction based on the given selection
 // condition and operands.
 func NewSelect(cond, x, y value.Value) *InstSelect {
-	return &InstSelect{Cond: cond, X: x, Y: x}
+	return &InstSelect{Cond: cond, X: x, Y: y}
 }
 
 // String returns the LLVM syntax representation of the instruction as a
@@ -286,14 +358,23 @@ func (inst *InstSelect) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction. The result type is the type of the
+// operands, for both scalar and per-lane (vector condition) selection.
 func (inst *InstSelect) Type() types.Type {
+	// Cache type if not present.
 	if inst.Typ == nil {
 		inst.Typ = inst.X.Type()
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the condition and operand types of the instruction
+// are valid; i.e. an i1 condition, or a vector of i1 condition of the same
+// length as the vector operands, and operands of the same type.
+func (inst *InstSelect) Validate() error {
+	return validateSelect(inst.Cond.Type(), inst.X.Type(), inst.Y.Type())
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstSelect) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -306,7 +387,9 @@ func (inst *InstSelect) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstSelect) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -320,6 +403,78 @@ func (inst *InstSelect) Def() string {
 	return buf.String()
 }
 
+// ~~~ [ freeze ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// InstFreeze is an LLVM IR freeze instruction.
+type InstFreeze struct {
+	// Name of local variable associated with the result.
+	LocalName string
+	// Operand.
+	X value.Value
+
+	// extra.
+
+	// Type of result produced by the instruction.
+	Typ types.Type
+	// (optional) Metadata.
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
+}
+
+// NewFreeze returns a new freeze instruction based on the given operand.
+func NewFreeze(x value.Value) *InstFreeze {
+	return &InstFreeze{X: x}
+}
+
+// String returns the LLVM syntax representation of the instruction as a
+// type-value pair.
+func (inst *InstFreeze) String() string {
+	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
+}
+
+// Type returns the type of the instruction.
+func (inst *InstFreeze) Type() types.Type {
+	// Cache type if not present.
+	if inst.Typ == nil {
+		inst.Typ = inst.X.Type()
+	}
+	return inst.Typ
+}
+
+// Ident returns the identifier associated with the instruction.
+func (inst *InstFreeze) Ident() string {
+	return enc.Local(inst.LocalName)
+}
+
+// Name returns the name of the instruction.
+func (inst *InstFreeze) Name() string {
+	return inst.LocalName
+}
+
+// SetName sets the name of the instruction.
+func (inst *InstFreeze) SetName(name string) {
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
+}
+
+// Def returns the LLVM syntax representation of the instruction.
+func (inst *InstFreeze) Def() string {
+	// "freeze" Type Value OptCommaSepMetadataAttachmentList
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "freeze %v", inst.X)
+	for _, md := range inst.Metadata {
+		fmt.Fprintf(buf, ", %v", md)
+	}
+	return buf.String()
+}
+
 // ~~~ [ call ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstCall is an LLVM IR call instruction.
@@ -329,14 +484,17 @@ type InstCall struct {
 	// Callee.
 	// TODO: specify the set of underlying types of Callee.
 	Callee value.Value
-	// Function arguments.
-	Args []Arg
+	// Function arguments; *ir.Arg may be used to specify parameter attributes.
+	Args []value.Value
 
 	// extra.
 
-	// Type of result produced by the instruction, or function signature of the
-	// callee (as used when callee is variadic).
+	// Type of result produced by the instruction.
 	Typ types.Type
+	// (optional) Function type of the callee; nil to infer from the type of
+	// Callee. The callee type is printed at call sites of variadic callees, and
+	// of callees the type of which may not be inferred.
+	CalleeType *types.FuncType
 	// (optional) Tail; zero if not present.
 	Tail enum.Tail
 	// (optional) Fast math flags.
@@ -344,22 +502,29 @@ type InstCall struct {
 	// (optional) Calling convention; zero if not present.
 	CallingConv enum.CallingConv
 	// (optional) Return attributes.
-	ReturnAttrs []enum.ReturnAttribute
+	ReturnAttrs []ReturnAttribute
 	// (optional) Address space; zero if not present.
 	AddrSpace types.AddrSpace
 	// (optional) Function attributes.
-	FuncAttrs []enum.FuncAttribute
+	FuncAttrs []FuncAttribute
 	// (optional) Operand bundles.
-	OperandBundles []enum.OperandBundle
+	OperandBundles []*OperandBundle
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewCall returns a new call instruction based on the given callee and function
 // arguments.
 //
 // TODO: specify the set of underlying types of callee.
-func NewCall(callee value.Value, args ...Arg) *InstCall {
+func NewCall(callee value.Value, args ...value.Value) *InstCall {
 	return &InstCall{Callee: callee, Args: args}
 }
 
@@ -369,30 +534,26 @@ func (inst *InstCall) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction; or types.Invalid if the callee
+// type is invalid.
 func (inst *InstCall) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		t, ok := inst.Callee.Type().(*types.PointerType)
-		if !ok {
-			panic(fmt.Errorf("invalid callee type; expected *types.PointerType, got %T", inst.Callee.Type()))
+		sig, err := callSig("callee", inst.CalleeType, inst.Callee)
+		if err != nil {
+			return types.Invalid
 		}
-		sig, ok := t.ElemType.(*types.FuncType)
-		if !ok {
-			panic(fmt.Errorf("invalid callee type; expected *types.FuncType, got %T", t.ElemType))
-		}
-		if sig.Variadic {
-			inst.Typ = sig
-		} else {
-			inst.Typ = sig.RetType
-		}
-	}
-	if t, ok := inst.Typ.(*types.FuncType); ok {
-		return t.RetType
+		inst.Typ = sig.RetType
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the callee type of the instruction is valid, and
+// whether the fixed arguments and result type agree with the callee.
+func (inst *InstCall) Validate() error {
+	return validateCall("callee", inst.CalleeType, inst.Callee, inst.Args, inst.Typ)
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstCall) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -405,7 +566,9 @@ func (inst *InstCall) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstCall) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -425,7 +588,9 @@ func (inst *InstCall) Def() string {
 	for _, attr := range inst.ReturnAttrs {
 		fmt.Fprintf(buf, " %v", attr)
 	}
-	fmt.Fprintf(buf, " %v %v(", inst.Type(), inst.Callee.Ident())
+	// Calls of variadic functions specify the function type of the callee.
+	typ := callType(inst.Type(), inst.CalleeType, inst.Callee)
+	fmt.Fprintf(buf, " %v %v(", typ, inst.Callee.Ident())
 	for i, arg := range inst.Args {
 		if i != 0 {
 			buf.WriteString(", ")
@@ -437,11 +602,14 @@ func (inst *InstCall) Def() string {
 		fmt.Fprintf(buf, " %v", attr)
 	}
 	if len(inst.OperandBundles) > 0 {
-		buf.WriteString("[")
-		for _, operandBundle := range inst.OperandBundles {
-			fmt.Fprintf(buf, " %v", operandBundle)
+		buf.WriteString(" [ ")
+		for i, operandBundle := range inst.OperandBundles {
+			if i != 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(operandBundle.String())
 		}
-		buf.WriteString("]")
+		buf.WriteString(" ]")
 	}
 	for _, md := range inst.Metadata {
 		fmt.Fprintf(buf, ", %v", md)
@@ -463,7 +631,14 @@ type InstVAArg struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewVAArg returns a new va_arg instruction based on the given variable
@@ -495,7 +670,9 @@ func (inst *InstVAArg) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstVAArg) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -521,17 +698,24 @@ type InstLandingPad struct {
 	Cleanup bool
 	// Filter and catch clauses; zero or more if Cleanup is true, otherwise one
 	// or more.
-	Clauses []*enum.Clause
+	Clauses []*Clause
 
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewLandingPad returns a new landingpad instruction based on the given result
 // type and filter/catch clauses.
-func NewLandingPad(resultType types.Type, clauses ...*enum.Clause) *InstLandingPad {
+func NewLandingPad(resultType types.Type, clauses ...*Clause) *InstLandingPad {
 	return &InstLandingPad{ResultType: resultType, Clauses: clauses}
 }
 
@@ -558,7 +742,9 @@ func (inst *InstLandingPad) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstLandingPad) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -578,6 +764,30 @@ func (inst *InstLandingPad) Def() string {
 	return buf.String()
 }
 
+// ___ [ Landingpad clause ] ___________________________________________________
+
+// Clause is a catch or filter clause of a landingpad instruction.
+type Clause struct {
+	// Clause type.
+	Type enum.ClauseType
+	// Operand; the type info of the caught exception of catch clauses (e.g.
+	// @_ZTIi), or an array of type infos of the permitted exceptions of filter
+	// clauses.
+	X Constant
+}
+
+// NewClause returns a new landingpad clause based on the given clause type and
+// operand.
+func NewClause(clauseType enum.ClauseType, x Constant) *Clause {
+	return &Clause{Type: clauseType, X: x}
+}
+
+// String returns the string representation of the landingpad clause.
+func (clause *Clause) String() string {
+	// ClauseType Type Constant
+	return fmt.Sprintf("%v %v", clause.Type, clause.X)
+}
+
 // ~~~ [ catchpad ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // InstCatchPad is an LLVM IR catchpad instruction.
@@ -587,17 +797,24 @@ type InstCatchPad struct {
 	// Exception scope.
 	Scope *TermCatchSwitch // TODO: rename to From? rename to Within?
 	// Exception arguments.
-	Args []Arg
+	Args []value.Value
 
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewCatchPad returns a new catchpad instruction based on the given exception
 // scope and exception arguments.
-func NewCatchPad(scope *TermCatchSwitch, args ...Arg) *InstCatchPad {
+func NewCatchPad(scope *TermCatchSwitch, args ...value.Value) *InstCatchPad {
 	return &InstCatchPad{Scope: scope, Args: args}
 }
 
@@ -624,14 +841,16 @@ func (inst *InstCatchPad) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstCatchPad) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
 func (inst *InstCatchPad) Def() string {
 	// "catchpad" "within" LocalIdent "[" ExceptionArgs "]" OptCommaSepMetadataAttachmentList
 	buf := &strings.Builder{}
-	fmt.Fprintf(buf, "catchpad within %v [", inst.Scope)
+	fmt.Fprintf(buf, "catchpad within %v [", inst.Scope.Ident())
 	for i, arg := range inst.Args {
 		if i != 0 {
 			buf.WriteString(", ")
@@ -654,17 +873,24 @@ type InstCleanupPad struct {
 	// Exception scope.
 	Scope enum.ExceptionScope // TODO: rename to Parent? rename to From?
 	// Exception arguments.
-	Args []Arg
+	Args []value.Value
 
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewCleanupPad returns a new cleanuppad instruction based on the given
 // exception scope and exception arguments.
-func NewCleanupPad(scope enum.ExceptionScope, args ...Arg) *InstCleanupPad {
+func NewCleanupPad(scope enum.ExceptionScope, args ...value.Value) *InstCleanupPad {
 	return &InstCleanupPad{Scope: scope, Args: args}
 }
 
@@ -691,14 +917,16 @@ func (inst *InstCleanupPad) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstCleanupPad) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
 func (inst *InstCleanupPad) Def() string {
 	// "cleanuppad" "within" ExceptionScope "[" ExceptionArgs "]" OptCommaSepMetadataAttachmentList
 	buf := &strings.Builder{}
-	fmt.Fprintf(buf, "cleanuppad within %v [", inst.Scope)
+	fmt.Fprintf(buf, "cleanuppad within %v [", scopeIdent(inst.Scope))
 	for i, arg := range inst.Args {
 		if i != 0 {
 			buf.WriteString(", ")
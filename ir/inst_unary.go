@@ -0,0 +1,91 @@
+package ir
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/l/internal/enc"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+// --- [ Unary instructions ] --------------------------------------------------
+
+// ~~~ [ fneg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// InstFNeg is an LLVM IR fneg instruction.
+type InstFNeg struct {
+	// Name of local variable associated with the result.
+	LocalName string
+	// Operand.
+	X value.Value // floating-point scalar or floating-point vector
+
+	// extra.
+
+	// Type of result produced by the instruction.
+	Typ types.Type
+	// (optional) Fast math flags.
+	FastMathFlags []enum.FastMathFlag
+	// (optional) Metadata.
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
+}
+
+// NewFNeg returns a new fneg instruction based on the given operand.
+func NewFNeg(x value.Value) *InstFNeg {
+	return &InstFNeg{X: x}
+}
+
+// String returns the LLVM syntax representation of the instruction as a
+// type-value pair.
+func (inst *InstFNeg) String() string {
+	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
+}
+
+// Type returns the type of the instruction.
+func (inst *InstFNeg) Type() types.Type {
+	// Cache type if not present.
+	if inst.Typ == nil {
+		inst.Typ = inst.X.Type()
+	}
+	return inst.Typ
+}
+
+// Ident returns the identifier associated with the instruction.
+func (inst *InstFNeg) Ident() string {
+	return enc.Local(inst.LocalName)
+}
+
+// Name returns the name of the instruction.
+func (inst *InstFNeg) Name() string {
+	return inst.LocalName
+}
+
+// SetName sets the name of the instruction.
+func (inst *InstFNeg) SetName(name string) {
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
+}
+
+// Def returns the LLVM syntax representation of the instruction.
+func (inst *InstFNeg) Def() string {
+	// "fneg" FastMathFlags Type Value OptCommaSepMetadataAttachmentList
+	buf := &strings.Builder{}
+	buf.WriteString("fneg")
+	for _, flag := range inst.FastMathFlags {
+		fmt.Fprintf(buf, " %v", flag)
+	}
+	fmt.Fprintf(buf, " %v", inst.X)
+	for _, md := range inst.Metadata {
+		fmt.Fprintf(buf, ", %v", md)
+	}
+	return buf.String()
+}
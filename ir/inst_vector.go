@@ -27,7 +27,14 @@ type InstExtractElement struct {
 	// Type of result produced by the instruction.
 	Typ types.Type
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewExtractElement returns a new extractelement instruction based on the given
@@ -42,19 +49,26 @@ func (inst *InstExtractElement) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction; or types.Invalid if the vector type
+// is invalid.
 func (inst *InstExtractElement) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		t, ok := inst.X.Type().(*types.VectorType)
-		if !ok {
-			panic(fmt.Errorf("invalid vector type; expected *types.VectorType, got %T", inst.X.Type()))
+		t, err := vectorType(inst.X.Type())
+		if err != nil {
+			return types.Invalid
 		}
 		inst.Typ = t.ElemType
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the vector type of the instruction is valid.
+func (inst *InstExtractElement) Validate() error {
+	_, err := vectorType(inst.X.Type())
+	return err
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstExtractElement) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -67,7 +81,9 @@ func (inst *InstExtractElement) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstExtractElement) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -99,7 +115,14 @@ type InstInsertElement struct {
 	// Type of result produced by the instruction.
 	Typ *types.VectorType
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewInsertElement returns a new insertelement instruction based on the given
@@ -114,19 +137,26 @@ func (inst *InstInsertElement) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction; or types.Invalid if the vector type
+// is invalid.
 func (inst *InstInsertElement) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		t, ok := inst.X.Type().(*types.VectorType)
-		if !ok {
-			panic(fmt.Errorf("invalid vector type; expected *types.VectorType, got %T", inst.X.Type()))
+		t, err := vectorType(inst.X.Type())
+		if err != nil {
+			return types.Invalid
 		}
 		inst.Typ = t
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the vector type of the instruction is valid.
+func (inst *InstInsertElement) Validate() error {
+	_, err := vectorType(inst.X.Type())
+	return err
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstInsertElement) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -139,7 +169,9 @@ func (inst *InstInsertElement) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstInsertElement) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -169,7 +201,14 @@ type InstShuffleVector struct {
 	// Type of result produced by the instruction.
 	Typ *types.VectorType
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the instruction, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewShuffleVector returns a new shufflevector instruction based on the given
@@ -184,23 +223,40 @@ func (inst *InstShuffleVector) String() string {
 	return fmt.Sprintf("%v %v", inst.Type(), inst.Ident())
 }
 
-// Type returns the type of the instruction.
+// Type returns the type of the instruction; or types.Invalid if the vector or
+// mask type is invalid.
 func (inst *InstShuffleVector) Type() types.Type {
 	// Cache type if not present.
 	if inst.Typ == nil {
-		xType, ok := inst.X.Type().(*types.VectorType)
-		if !ok {
-			panic(fmt.Errorf("invalid vector type; expected *types.VectorType, got %T", inst.X.Type()))
-		}
-		maskType, ok := inst.Mask.Type().(*types.VectorType)
-		if !ok {
-			panic(fmt.Errorf("invalid vector type; expected *types.VectorType, got %T", inst.Mask.Type()))
+		t, err := inst.typ()
+		if err != nil {
+			return types.Invalid
 		}
-		inst.Typ = types.NewVector(maskType.Len, xType.ElemType)
+		inst.Typ = t
 	}
 	return inst.Typ
 }
 
+// Validate reports whether the vector and mask types of the instruction are
+// valid.
+func (inst *InstShuffleVector) Validate() error {
+	_, err := inst.typ()
+	return err
+}
+
+// typ returns the type of the instruction.
+func (inst *InstShuffleVector) typ() (*types.VectorType, error) {
+	xType, err := vectorType(inst.X.Type())
+	if err != nil {
+		return nil, err
+	}
+	maskType, err := vectorType(inst.Mask.Type())
+	if err != nil {
+		return nil, err
+	}
+	return &types.VectorType{Len: maskType.Len, ElemType: xType.ElemType, Scalable: maskType.Scalable}, nil
+}
+
 // Ident returns the identifier associated with the instruction.
 func (inst *InstShuffleVector) Ident() string {
 	return enc.Local(inst.LocalName)
@@ -213,7 +269,9 @@ func (inst *InstShuffleVector) Name() string {
 
 // SetName sets the name of the instruction.
 func (inst *InstShuffleVector) SetName(name string) {
-	inst.LocalName = name
+	old := inst.LocalName
+	inst.LocalName = inst.internName(name)
+	inst.notifyRename(inst, old)
 }
 
 // Def returns the LLVM syntax representation of the instruction.
@@ -8,6 +8,12 @@ package ir
 //
 // An Instruction has one of the following underlying types.
 //
+// Unary instructions
+//
+// https://llvm.org/docs/LangRef.html#unary-operations
+//
+//    *ir.InstFNeg   // https://godoc.org/github.com/llir/l/ir#InstFNeg
+//
 // Binary instructions
 //
 // https://llvm.org/docs/LangRef.html#binary-operations
@@ -89,11 +95,16 @@ package ir
 //    *ir.InstFCmp         // https://godoc.org/github.com/llir/l/ir#InstFCmp
 //    *ir.InstPhi          // https://godoc.org/github.com/llir/l/ir#InstPhi
 //    *ir.InstSelect       // https://godoc.org/github.com/llir/l/ir#InstSelect
+//    *ir.InstFreeze       // https://godoc.org/github.com/llir/l/ir#InstFreeze
 //    *ir.InstCall         // https://godoc.org/github.com/llir/l/ir#InstCall
 //    *ir.InstVAArg        // https://godoc.org/github.com/llir/l/ir#InstVAArg
 //    *ir.InstLandingPad   // https://godoc.org/github.com/llir/l/ir#InstLandingPad
 //    *ir.InstCatchPad     // https://godoc.org/github.com/llir/l/ir#InstCatchPad
 //    *ir.InstCleanupPad   // https://godoc.org/github.com/llir/l/ir#InstCleanupPad
+//
+// Raw instructions
+//
+//    *ir.RawInst          // https://godoc.org/github.com/llir/l/ir#RawInst
 type Instruction interface {
 	// Def returns the LLVM syntax representation of the instruction.
 	Def() string
@@ -102,6 +113,9 @@ type Instruction interface {
 	isInstruction()
 }
 
+// Unary instructions.
+func (*InstFNeg) isInstruction() {}
+
 // Binary instructions.
 func (*InstAdd) isInstruction()  {}
 func (*InstFAdd) isInstruction() {}
@@ -162,8 +176,79 @@ func (*InstICmp) isInstruction()       {}
 func (*InstFCmp) isInstruction()       {}
 func (*InstPhi) isInstruction()        {}
 func (*InstSelect) isInstruction()     {}
+func (*InstFreeze) isInstruction()     {}
 func (*InstCall) isInstruction()       {}
 func (*InstVAArg) isInstruction()      {}
 func (*InstLandingPad) isInstruction() {}
 func (*InstCatchPad) isInstruction()   {}
 func (*InstCleanupPad) isInstruction() {}
+
+// Comments of unary instructions.
+func (inst *InstFNeg) comments() []string { return inst.Comments }
+
+// Comments of binary instructions.
+func (inst *InstAdd) comments() []string  { return inst.Comments }
+func (inst *InstFAdd) comments() []string { return inst.Comments }
+func (inst *InstSub) comments() []string  { return inst.Comments }
+func (inst *InstFSub) comments() []string { return inst.Comments }
+func (inst *InstMul) comments() []string  { return inst.Comments }
+func (inst *InstFMul) comments() []string { return inst.Comments }
+func (inst *InstUDiv) comments() []string { return inst.Comments }
+func (inst *InstSDiv) comments() []string { return inst.Comments }
+func (inst *InstFDiv) comments() []string { return inst.Comments }
+func (inst *InstURem) comments() []string { return inst.Comments }
+func (inst *InstSRem) comments() []string { return inst.Comments }
+func (inst *InstFRem) comments() []string { return inst.Comments }
+
+// Comments of bitwise instructions.
+func (inst *InstShl) comments() []string  { return inst.Comments }
+func (inst *InstLShr) comments() []string { return inst.Comments }
+func (inst *InstAShr) comments() []string { return inst.Comments }
+func (inst *InstAnd) comments() []string  { return inst.Comments }
+func (inst *InstOr) comments() []string   { return inst.Comments }
+func (inst *InstXor) comments() []string  { return inst.Comments }
+
+// Comments of vector instructions.
+func (inst *InstExtractElement) comments() []string { return inst.Comments }
+func (inst *InstInsertElement) comments() []string  { return inst.Comments }
+func (inst *InstShuffleVector) comments() []string  { return inst.Comments }
+
+// Comments of aggregate instructions.
+func (inst *InstExtractValue) comments() []string { return inst.Comments }
+func (inst *InstInsertValue) comments() []string  { return inst.Comments }
+
+// Comments of memory instructions.
+func (inst *InstAlloca) comments() []string        { return inst.Comments }
+func (inst *InstLoad) comments() []string          { return inst.Comments }
+func (inst *InstStore) comments() []string         { return inst.Comments }
+func (inst *InstFence) comments() []string         { return inst.Comments }
+func (inst *InstCmpXchg) comments() []string       { return inst.Comments }
+func (inst *InstAtomicRMW) comments() []string     { return inst.Comments }
+func (inst *InstGetElementPtr) comments() []string { return inst.Comments }
+
+// Comments of conversion instructions.
+func (inst *InstTrunc) comments() []string         { return inst.Comments }
+func (inst *InstZExt) comments() []string          { return inst.Comments }
+func (inst *InstSExt) comments() []string          { return inst.Comments }
+func (inst *InstFPTrunc) comments() []string       { return inst.Comments }
+func (inst *InstFPExt) comments() []string         { return inst.Comments }
+func (inst *InstFPToUI) comments() []string        { return inst.Comments }
+func (inst *InstFPToSI) comments() []string        { return inst.Comments }
+func (inst *InstUIToFP) comments() []string        { return inst.Comments }
+func (inst *InstSIToFP) comments() []string        { return inst.Comments }
+func (inst *InstPtrToInt) comments() []string      { return inst.Comments }
+func (inst *InstIntToPtr) comments() []string      { return inst.Comments }
+func (inst *InstBitCast) comments() []string       { return inst.Comments }
+func (inst *InstAddrSpaceCast) comments() []string { return inst.Comments }
+
+// Comments of other instructions.
+func (inst *InstICmp) comments() []string       { return inst.Comments }
+func (inst *InstFCmp) comments() []string       { return inst.Comments }
+func (inst *InstPhi) comments() []string        { return inst.Comments }
+func (inst *InstSelect) comments() []string     { return inst.Comments }
+func (inst *InstFreeze) comments() []string     { return inst.Comments }
+func (inst *InstCall) comments() []string       { return inst.Comments }
+func (inst *InstVAArg) comments() []string      { return inst.Comments }
+func (inst *InstLandingPad) comments() []string { return inst.Comments }
+func (inst *InstCatchPad) comments() []string   { return inst.Comments }
+func (inst *InstCleanupPad) comments() []string { return inst.Comments }
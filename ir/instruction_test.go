@@ -1,7 +1,16 @@
 package ir
 
+import (
+	"testing"
+
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
 // Assert that each instruction implements the ir.Instruction interface.
 var (
+	// Unary instructions.
+	_ Instruction = (*InstFNeg)(nil)
 	// Binary instructions.
 	_ Instruction = (*InstAdd)(nil)
 	_ Instruction = (*InstFAdd)(nil)
@@ -56,6 +65,7 @@ var (
 	_ Instruction = (*InstFCmp)(nil)
 	_ Instruction = (*InstPhi)(nil)
 	_ Instruction = (*InstSelect)(nil)
+	_ Instruction = (*InstFreeze)(nil)
 	_ Instruction = (*InstCall)(nil)
 	_ Instruction = (*InstVAArg)(nil)
 	_ Instruction = (*InstLandingPad)(nil)
@@ -78,3 +88,176 @@ var (
 	_ Terminator = (*TermCleanupRet)(nil)
 	_ Terminator = (*TermUnreachable)(nil)
 )
+
+// Assert that each instruction and terminator has comments.
+var (
+	// Unary instructions.
+	_ commenter = (*InstFNeg)(nil)
+	// Binary instructions.
+	_ commenter = (*InstAdd)(nil)
+	_ commenter = (*InstFAdd)(nil)
+	_ commenter = (*InstSub)(nil)
+	_ commenter = (*InstFSub)(nil)
+	_ commenter = (*InstMul)(nil)
+	_ commenter = (*InstFMul)(nil)
+	_ commenter = (*InstUDiv)(nil)
+	_ commenter = (*InstSDiv)(nil)
+	_ commenter = (*InstFDiv)(nil)
+	_ commenter = (*InstURem)(nil)
+	_ commenter = (*InstSRem)(nil)
+	_ commenter = (*InstFRem)(nil)
+	// Bitwise instructions.
+	_ commenter = (*InstShl)(nil)
+	_ commenter = (*InstLShr)(nil)
+	_ commenter = (*InstAShr)(nil)
+	_ commenter = (*InstAnd)(nil)
+	_ commenter = (*InstOr)(nil)
+	_ commenter = (*InstXor)(nil)
+	// Vector instructions.
+	_ commenter = (*InstExtractElement)(nil)
+	_ commenter = (*InstInsertElement)(nil)
+	_ commenter = (*InstShuffleVector)(nil)
+	// Aggregate instructions.
+	_ commenter = (*InstExtractValue)(nil)
+	_ commenter = (*InstInsertValue)(nil)
+	// Memory instructions.
+	_ commenter = (*InstAlloca)(nil)
+	_ commenter = (*InstLoad)(nil)
+	_ commenter = (*InstStore)(nil)
+	_ commenter = (*InstFence)(nil)
+	_ commenter = (*InstCmpXchg)(nil)
+	_ commenter = (*InstAtomicRMW)(nil)
+	_ commenter = (*InstGetElementPtr)(nil)
+	// Conversion instructions.
+	_ commenter = (*InstTrunc)(nil)
+	_ commenter = (*InstZExt)(nil)
+	_ commenter = (*InstSExt)(nil)
+	_ commenter = (*InstFPTrunc)(nil)
+	_ commenter = (*InstFPExt)(nil)
+	_ commenter = (*InstFPToUI)(nil)
+	_ commenter = (*InstFPToSI)(nil)
+	_ commenter = (*InstUIToFP)(nil)
+	_ commenter = (*InstSIToFP)(nil)
+	_ commenter = (*InstPtrToInt)(nil)
+	_ commenter = (*InstIntToPtr)(nil)
+	_ commenter = (*InstBitCast)(nil)
+	_ commenter = (*InstAddrSpaceCast)(nil)
+	// Other instructions.
+	_ commenter = (*InstICmp)(nil)
+	_ commenter = (*InstFCmp)(nil)
+	_ commenter = (*InstPhi)(nil)
+	_ commenter = (*InstSelect)(nil)
+	_ commenter = (*InstFreeze)(nil)
+	_ commenter = (*InstCall)(nil)
+	_ commenter = (*InstVAArg)(nil)
+	_ commenter = (*InstLandingPad)(nil)
+	_ commenter = (*InstCatchPad)(nil)
+	_ commenter = (*InstCleanupPad)(nil)
+	// Raw instructions.
+	_ commenter = (*RawInst)(nil)
+	// Terminators.
+	_ commenter = (*TermRet)(nil)
+	_ commenter = (*TermBr)(nil)
+	_ commenter = (*TermCondBr)(nil)
+	_ commenter = (*TermSwitch)(nil)
+	_ commenter = (*TermIndirectBr)(nil)
+	_ commenter = (*TermInvoke)(nil)
+	_ commenter = (*TermResume)(nil)
+	_ commenter = (*TermCatchSwitch)(nil)
+	_ commenter = (*TermCatchRet)(nil)
+	_ commenter = (*TermCleanupRet)(nil)
+	_ commenter = (*TermUnreachable)(nil)
+	_ commenter = (*RawTerm)(nil)
+)
+
+func TestGetElementPtrType(t *testing.T) {
+	st := types.NewStruct(types.I32, types.NewArray(4, types.I64))
+	stPtr := NewParam(types.NewPointer(st), "p")
+	i32Ptr := NewParam(types.NewPointer(types.I32), "q")
+	ptrs := NewParam(types.NewVector(2, types.NewPointer(st)), "ps")
+	idx := NewParam(types.I64, "idx")
+	idxs := NewParam(types.NewVector(4, types.I64), "idxs")
+	zero := NewInt(types.I64, 0)
+	one := NewInt(types.I32, 1)
+	splat := NewVector(types.NewVector(2, types.I32), one, one)
+	golden := []struct {
+		inst *InstGetElementPtr
+		want string
+	}{
+		// i=0
+		{
+			inst: NewGetElementPtr(st, stPtr, zero, one, idx),
+			want: "i64*",
+		},
+		// i=1
+		{
+			inst: NewGetElementPtr(types.I32, i32Ptr, idxs),
+			want: "<4 x i32*>",
+		},
+		// i=2
+		{
+			inst: NewGetElementPtr(st, ptrs, zero, splat, zero),
+			want: "<2 x i64*>",
+		},
+		// i=3
+		{
+			inst: NewGetElementPtr(st, ptrs, idxs),
+			want: "<invalid>",
+		},
+	}
+	for i, g := range golden {
+		if got := g.inst.Type().String(); g.want != got {
+			t.Errorf("i=%d: type mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
+
+func TestPhi(t *testing.T) {
+	f := NewFunc("f", types.I32, NewParam(types.I32, "a"), NewParam(types.I32, "b"))
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	phi := NewPhi()
+	if got, want := phi.Type(), types.Invalid; got != want {
+		t.Errorf("type mismatch; expected %v, got %v", want, got)
+	}
+	if err := phi.Validate(); err == nil {
+		t.Errorf("expected error of phi instruction without incoming values")
+	}
+	phi.AddIncoming(f.Params[0], entry)
+	phi.AddIncoming(f.Params[1], loop)
+	if err := phi.Validate(); err != nil {
+		t.Errorf("unexpected error; %v", err)
+	}
+	if got, want := phi.Def(), "phi i32 [ %a, %entry ], [ %b, %loop ]"; want != got {
+		t.Errorf("phi mismatch; expected %q, got %q", want, got)
+	}
+	phi.AddIncoming(NewInt(types.I64, 1), loop)
+	if err := phi.Validate(); err == nil {
+		t.Errorf("expected error of incoming value type mismatch")
+	}
+}
+
+func TestSelectValidate(t *testing.T) {
+	v4 := types.NewVector(4, types.I32)
+	golden := []struct {
+		cond, x, y value.Value
+		valid      bool
+	}{
+		// i=0
+		{cond: NewParam(types.I1, "c"), x: NewParam(types.I32, "x"), y: NewParam(types.I32, "y"), valid: true},
+		// i=1
+		{cond: NewParam(types.NewVector(4, types.I1), "c"), x: NewParam(v4, "x"), y: NewParam(v4, "y"), valid: true},
+		// i=2
+		{cond: NewParam(types.NewVector(2, types.I1), "c"), x: NewParam(v4, "x"), y: NewParam(v4, "y"), valid: false},
+		// i=3
+		{cond: NewParam(types.I32, "c"), x: NewParam(types.I32, "x"), y: NewParam(types.I32, "y"), valid: false},
+		// i=4
+		{cond: NewParam(types.I1, "c"), x: NewParam(types.I32, "x"), y: NewParam(types.I64, "y"), valid: false},
+	}
+	for i, g := range golden {
+		inst := NewSelect(g.cond, g.x, g.y)
+		if err := inst.Validate(); (err == nil) != g.valid {
+			t.Errorf("i=%d: validity mismatch; expected %v, got error %v", i, g.valid, err)
+		}
+	}
+}
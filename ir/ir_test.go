@@ -1,10 +1,17 @@
 package ir
 
 import (
+	"fmt"
+	"io"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"unsafe"
 
+	"github.com/llir/l/ir/enum"
 	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
 )
 
 func TestModuleString(t *testing.T) {
@@ -27,6 +34,257 @@ func TestModuleString(t *testing.T) {
 			},
 			want: "%foo = type { i32 }",
 		},
+		// Global variable declaration and definitions.
+		{
+			in: func() *Module {
+				m := &Module{}
+				m.NewGlobalDecl("x", types.I32)
+				y := m.NewGlobalDef("y", NewInt(types.I32, 42))
+				y.ExternallyInitialized = true
+				m.NewGlobalLazy("z", types.I32, func() Constant {
+					return NewInt(types.I32, 7)
+				})
+				return m
+			}(),
+			want: "@x = external global i32\n@y = externally_initialized global i32 42\n@z = global i32 7",
+		},
+		// Function definition with parameters used as operands.
+		{
+			in: func() *Module {
+				m := &Module{}
+				x := NewParam(types.I32, "x")
+				y := NewParam(types.I32, "y")
+				y.Attrs = append(y.Attrs, enum.ParamAttrSignExt)
+				f := m.NewFunc("add", types.I32, x, y)
+				entry := NewBlock("entry")
+				sum := entry.NewAdd(x, y)
+				sum.SetName("sum")
+				entry.NewRet(sum)
+				f.Blocks = append(f.Blocks, entry)
+				return m
+			}(),
+			want: "define i32 @add(i32 %x, i32 signext %y) {\nentry:\n\t%sum = add i32 %x, %y\n\tret i32 %sum\n}",
+		},
+		// Function declaration with section, partition and alignment.
+		{
+			in: func() *Module {
+				m := &Module{}
+				f := m.NewFunc("reset", types.Void)
+				f.Section = ".text.boot"
+				f.Partition = "part1"
+				f.Comdat = &ComdatDef{Name: "reset", Kind: enum.SelectionKindAny}
+				f.Align = 16
+				return m
+			}(),
+			want: `declare void @reset() section ".text.boot" partition "part1" comdat align 16`,
+		},
+		// Attribute group definition.
+		{
+			in: &Module{
+				AttrGroupDefs: []*AttrGroupDef{
+					NewAttrGroupDef(0, enum.FuncAttrNoUnwind, AttrString("no-frame-pointer-elim"), NewAttrPair("target-cpu", "skylake")),
+				},
+			},
+			want: `attributes #0 = { nounwind "no-frame-pointer-elim" "target-cpu"="skylake" }`,
+		},
+		// Metadata attachments and definitions.
+		{
+			in: func() *Module {
+				m := &Module{}
+				prof := NewTuple(NewMDString("function_entry_count"), NewMDValue(NewInt(types.I64, 100)))
+				prof.MetadataID = 0
+				m.MetadataDefs = append(m.MetadataDefs, prof)
+				f := m.NewFunc("f", types.Void)
+				f.Metadata = append(f.Metadata, NewMetadataAttachment(MDKindProf, prof))
+				entry := NewBlock("entry")
+				entry.NewRet(nil)
+				f.Blocks = append(f.Blocks, entry)
+				return m
+			}(),
+			want: "define void @f() !prof !0 {\nentry:\n\tret void\n}\n!0 = !{!\"function_entry_count\", i64 100}",
+		},
+		// Named metadata and specialized metadata nodes.
+		{
+			in: func() *Module {
+				m := &Module{}
+				file := NewSpecialized("DIFile", NewMDField("filename", "foo.c"), NewMDField("directory", ""))
+				m.NewMetadataDef(file)
+				cu := NewSpecialized("DICompileUnit", NewMDField("language", MDEnum("DW_LANG_C99")), NewMDField("file", file), NewMDField("isOptimized", false), NewMDField("enums", nil))
+				cu.Distinct = true
+				m.NewMetadataDef(cu)
+				m.NamedMetadata("llvm.dbg.cu").Nodes = []MDNode{cu}
+				return m
+			}(),
+			want: "!llvm.dbg.cu = !{!1}\n!0 = !DIFile(filename: \"foo.c\", directory: \"\")\n!1 = distinct !DICompileUnit(language: DW_LANG_C99, file: !0, isOptimized: false, enums: null)",
+		},
+		// Raw global entities, instructions and terminators.
+		{
+			in: func() *Module {
+				m := &Module{}
+				m.NewGlobalDecl("x", types.I32)
+				y := m.NewRawGlobal("y", types.NewPointer(types.I32), "@y = alias i32, i32* @x")
+				f := m.NewFunc("f", types.Void)
+				entry := NewBlock("entry")
+				exit := NewBlock("exit")
+				entry.NewRawInst("%0 = freeze i32 1")
+				entry.NewRawTerm("callbr void asm \"\", \"\"() to label %exit []", exit)
+				exit.NewStore(NewInt(types.I32, 2), y)
+				exit.NewRet(nil)
+				f.Blocks = append(f.Blocks, entry, exit)
+				return m
+			}(),
+			want: "@x = external global i32\n@y = alias i32, i32* @x\ndefine void @f() {\nentry:\n\t%0 = freeze i32 1\n\tcallbr void asm \"\", \"\"() to label %exit []\nexit:\n\tstore i32 2, i32* @y\n\tret void\n}",
+		},
+		// Comments.
+		{
+			in: func() *Module {
+				m := &Module{}
+				g := m.NewGlobalDef("x", NewInt(types.I32, 1))
+				g.Comments = append(g.Comments, "counter")
+				f := m.NewFunc("f", types.Void)
+				f.Comments = append(f.Comments, "f increments x.\n\nCalled once.")
+				entry := NewBlock("entry")
+				load := entry.NewLoad(g)
+				load.SetName("0")
+				add := entry.NewAdd(load, NewInt(types.I32, 1))
+				add.SetName("1")
+				add.Comments = append(add.Comments, "x + 1")
+				entry.NewStore(add, g)
+				entry.NewRet(nil)
+				f.Blocks = append(f.Blocks, entry)
+				return m
+			}(),
+			want: "; counter\n@x = global i32 1\n; f increments x.\n;\n; Called once.\ndefine void @f() {\nentry:\n\t%0 = load i32, i32* @x\n\t; x + 1\n\t%1 = add i32 %0, 1\n\tstore i32 %1, i32* @x\n\tret void\n}",
+		},
+		// Freeze instruction.
+		{
+			in: func() *Module {
+				m := &Module{}
+				f := m.NewFunc("f", types.I32, NewParam(types.I32, "x"))
+				entry := f.NewBlock("entry")
+				frozen := entry.NewFreeze(f.Params[0])
+				frozen.SetName("frozen")
+				entry.NewRet(frozen)
+				return m
+			}(),
+			want: "define i32 @f(i32 %x) {\nentry:\n\t%frozen = freeze i32 %x\n\tret i32 %frozen\n}",
+		},
+		// Unary instructions.
+		{
+			in: func() *Module {
+				m := &Module{}
+				f := m.NewFunc("f", types.Double, NewParam(types.Double, "x"))
+				entry := f.NewBlock("entry")
+				neg := entry.NewFNeg(f.Params[0])
+				neg.SetName("neg")
+				neg.FastMathFlags = []enum.FastMathFlag{enum.FastMathFlagNNaN, enum.FastMathFlagNSZ}
+				entry.NewRet(neg)
+				return m
+			}(),
+			want: "define double @f(double %x) {\nentry:\n\t%neg = fneg nnan nsz double %x\n\tret double %neg\n}",
+		},
+		// Atomic instructions.
+		{
+			in: func() *Module {
+				m := &Module{}
+				g := m.NewGlobalDef("x", NewInt(types.I32, 0))
+				f := m.NewFunc("f", types.Void)
+				entry := f.NewBlock("entry")
+				old := entry.NewAtomicRMW(enum.AtomicOpAdd, g, NewInt(types.I32, 1), enum.AtomicOrderingSeqCst)
+				old.SetName("old")
+				umax := entry.NewAtomicRMW(enum.AtomicOpUMax, g, old, enum.AtomicOrderingMonotonic)
+				umax.SetName("max")
+				umax.Volatile = true
+				umax.SyncScope = "agent"
+				entry.NewRet(nil)
+				return m
+			}(),
+			want: "@x = global i32 0\ndefine void @f() {\nentry:\n\t%old = atomicrmw add i32* @x, i32 1 seq_cst\n\t%max = atomicrmw volatile umax i32* @x, i32 %old syncscope(\"agent\") monotonic\n\tret void\n}",
+		},
+		// Landingpad clauses.
+		{
+			in: func() *Module {
+				m := &Module{}
+				typeInfo := m.NewGlobalDecl("_ZTIi", types.I8Ptr)
+				f := m.NewFunc("f", types.Void)
+				lpad := f.NewBlock("lpad")
+				filter := NewArray(types.NewArray(1, typeInfo.Type()), typeInfo)
+				lp := lpad.NewLandingPad(types.NewStruct(types.I8Ptr, types.I32), NewClause(enum.ClauseTypeCatch, typeInfo), NewClause(enum.ClauseTypeFilter, filter))
+				lp.SetName("lp")
+				lp.Cleanup = true
+				lpad.NewUnreachable()
+				return m
+			}(),
+			want: "@_ZTIi = external global i8*\ndefine void @f() {\nlpad:\n\t%lp = landingpad { i8*, i32 } cleanup catch i8** @_ZTIi filter [1 x i8**] [i8** @_ZTIi]\n\tunreachable\n}",
+		},
+		// Exception handling pads.
+		{
+			in: func() *Module {
+				m := &Module{}
+				f := m.NewFunc("f", types.Void)
+				dispatch := f.NewBlock("dispatch")
+				handler := f.NewBlock("handler")
+				cleanup := f.NewBlock("cleanup")
+				exit := f.NewBlock("exit")
+				cs := dispatch.NewCatchSwitch(None, []*BasicBlock{handler}, nil)
+				cs.SetName("cs")
+				catch := handler.NewCatchPad(cs, NewNull(types.I8Ptr))
+				catch.SetName("catch")
+				handler.NewCatchRet(catch, exit)
+				pad := cleanup.NewCleanupPad(catch)
+				pad.SetName("pad")
+				cleanup.NewCleanupRet(pad, exit)
+				exit.NewRet(nil)
+				return m
+			}(),
+			want: "define void @f() {\ndispatch:\n\t%cs = catchswitch within none [label %handler] unwind to caller\nhandler:\n\t%catch = catchpad within %cs [i8* null]\n\tcatchret from %catch to label %exit\ncleanup:\n\t%pad = cleanuppad within %catch []\n\tcleanupret from %pad unwind label %exit\nexit:\n\tret void\n}",
+		},
+		// Alloca instruction printed without computing its type first.
+		{
+			in: func() *Module {
+				m := &Module{}
+				f := m.NewFunc("f", types.Void)
+				entry := f.NewBlock("entry")
+				p := entry.NewAlloca(types.I32)
+				p.SetName("p")
+				p.Alignment = 4
+				entry.NewRet(nil)
+				return m
+			}(),
+			want: "define void @f() {\nentry:\n\t%p = alloca i32, align 4\n\tret void\n}",
+		},
+		// Alloca instruction in a non-default address space.
+		{
+			in: func() *Module {
+				m := &Module{}
+				f := m.NewFunc("f", types.Void)
+				entry := f.NewBlock("entry")
+				p := entry.NewAlloca(types.I32)
+				p.SetName("p")
+				p.AddrSpace = 5
+				q := entry.NewAlloca(p.Type())
+				q.SetName("q")
+				entry.NewRet(nil)
+				return m
+			}(),
+			want: "define void @f() {\nentry:\n\t%p = alloca i32, addrspace(5)\n\t%q = alloca i32 addrspace(5)*\n\tret void\n}",
+		},
+		// Load instruction printed without computing its type first.
+		{
+			in: func() *Module {
+				m := &Module{}
+				g := m.NewGlobalDef("x", NewInt(types.I32, 1))
+				f := m.NewFunc("f", types.Void)
+				entry := f.NewBlock("entry")
+				x := entry.NewLoad(g)
+				x.SetName("x")
+				x.Volatile = true
+				x.Alignment = 4
+				entry.NewRet(nil)
+				return m
+			}(),
+			want: "@x = global i32 1\ndefine void @f() {\nentry:\n\t%x = load volatile i32, i32* @x, align 4\n\tret void\n}",
+		},
 	}
 	for _, g := range golden {
 		got := strings.TrimSpace(g.in.Def())
@@ -35,3 +293,753 @@ func TestModuleString(t *testing.T) {
 		}
 	}
 }
+
+func TestModuleLookup(t *testing.T) {
+	m := &Module{}
+	g := m.NewGlobalDecl("x", types.I32)
+	f := m.NewFunc("f", types.Void)
+	golden := []struct {
+		name string
+		want value.Named
+	}{
+		{name: "x", want: g},
+		{name: "f", want: f},
+		{name: "y", want: nil},
+	}
+	for _, gold := range golden {
+		got, ok := m.Lookup(gold.name)
+		if ok != (gold.want != nil) || got != gold.want {
+			t.Errorf("symbol mismatch for %q; expected %v, got %v", gold.name, gold.want, got)
+		}
+	}
+}
+
+func TestModuleNewTypeDefShared(t *testing.T) {
+	m := &Module{}
+	typ := m.NewTypeDef("word", types.I32)
+	if typ == types.I32 || types.I32.Alias != "" {
+		t.Errorf("shared type %v named in place", types.I32)
+	}
+	if got, want := typ.String(), "%word"; got != want {
+		t.Errorf("type mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := NewContext()
+	i7 := ctx.IntType(7)
+	if ctx.IntType(7) != i7 || ctx.IntType(32) != types.I32 {
+		t.Errorf("integer types not interned")
+	}
+	p := ctx.PointerType(i7)
+	if ctx.PointerType(i7) != p || ctx.PointerTypeIn(i7, 1) == p {
+		t.Errorf("pointer types not interned by element type and address space")
+	}
+	sig := ctx.FuncType(types.Void, p, types.I32)
+	if ctx.FuncType(types.Void, p, types.I32) != sig || ctx.VariadicFuncType(types.Void, p, types.I32) == sig {
+		t.Errorf("function types not interned by signature")
+	}
+	if ctx.Int(i7, 3) != ctx.Int(i7, 3) || ctx.Int(i7, 3) == ctx.Int(i7, 4) {
+		t.Errorf("integer constants not uniqued by value")
+	}
+	if NewContext().StructType(i7) == ctx.StructType(i7) {
+		t.Errorf("types shared by independent contexts")
+	}
+	// Metadata IDs are unique across the modules of a context.
+	a, b := ctx.NewModule(), ctx.NewModule()
+	a.NewMetadataDef(NewTuple())
+	b.NewMetadataDef(NewTuple())
+	if a.MetadataDefs[0].ID() != 0 || b.MetadataDefs[0].ID() != 1 || b.Context() != ctx {
+		t.Errorf("metadata IDs not unique across modules of context; got !%d and !%d", a.MetadataDefs[0].ID(), b.MetadataDefs[0].ID())
+	}
+	// Independent modules may be constructed concurrently in separate contexts.
+	done := make(chan string)
+	for i := 0; i < 4; i++ {
+		go func() {
+			ctx := NewContext()
+			m := ctx.NewModule()
+			f := m.NewFunc("f", ctx.IntType(24), NewParam(ctx.PointerType(types.I8), "p"))
+			f.NewBlock("").NewRet(ctx.Int(ctx.IntType(24), 1))
+			m.NewMetadataDef(NewTuple())
+			done <- m.Def()
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+}
+
+func TestModuleInternString(t *testing.T) {
+	m := &Module{}
+	m.NewGlobalDecl(".str", types.I32)
+	hello := m.InternString("hello")
+	world := m.InternString("world")
+	if got := m.InternString("hello"); got.Src != hello.Src {
+		t.Errorf("expected string literal to be pooled; expected %v, got %v", hello.Src.Ident(), got.Src.Ident())
+	}
+	if world.Src == hello.Src {
+		t.Errorf("expected distinct string literals to be stored in distinct global variables")
+	}
+	want := `@.str = external global i32
+@.str.1 = private unnamed_addr constant [6 x i8] c"hello\00"
+@.str.2 = private unnamed_addr constant [6 x i8] c"world\00"
+`
+	if got := m.Def(); want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+	if want, got := "i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.str.1, i64 0, i64 0)", hello.String(); want != got {
+		t.Errorf("string pointer mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// Removed string literals are not reused.
+	m.RemoveGlobal(m.Globals[1])
+	if got := m.InternString("hello"); got.Src == hello.Src {
+		t.Errorf("expected removed string literal not to be reused")
+	}
+}
+
+func TestWriteDef(t *testing.T) {
+	m := &Module{}
+	m.TargetTriple = "x86_64-unknown-linux-gnu"
+	point := m.NewTypeDef("point", types.NewStruct(types.I32, types.I32))
+	g := m.NewGlobalDef("origin", NewZeroInitializer(point))
+	file := NewTuple(NewMDString("a.c"))
+	m.NewMetadataDef(file)
+	unit := NewTuple(NewMDString("unit"), file)
+	m.NewMetadataDef(unit)
+	f := m.NewFunc("f", types.I32)
+	f.Metadata = append(f.Metadata, NewMetadataAttachment(RegisterMDKind("unit"), unit))
+	entry := f.NewBlock("")
+	entry.NewRet(entry.NewLoad(NewGetElementPtrExpr(point, g, NewIndex(NewInt(types.I32, 0)), NewIndex(NewInt(types.I32, 1)))))
+	ctx := NewWriteContext(m)
+	buf := &strings.Builder{}
+	for _, w := range []interface {
+		WriteDef(w io.Writer, ctx *WriteContext) error
+	}{g, f} {
+		if err := w.WriteDef(buf, ctx); err != nil {
+			t.Fatalf("unable to write definition; %+v", err)
+		}
+	}
+	want := `target triple = "x86_64-unknown-linux-gnu"
+%point = type { i32, i32 }
+@origin = global %point zeroinitializer
+define i32 @f() !unit !1 {
+	%1 = load i32, i32* getelementptr (%point, %point* @origin, i32 0, i32 1)
+	ret i32 %1
+}
+!1 = !{!"unit", !0}
+!0 = !{!"a.c"}
+`
+	if got := buf.String(); want != got {
+		t.Errorf("output mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// Subsequent writes only include new type definitions and metadata
+	// definitions.
+	buf.Reset()
+	m.NewTypeDef("pair", types.NewStruct(types.I64, types.I64))
+	h := m.NewFunc("h", types.Void)
+	h.Metadata = append(h.Metadata, NewMetadataAttachment(RegisterMDKind("file"), file))
+	if err := h.WriteDef(buf, ctx); err != nil {
+		t.Fatalf("unable to write definition; %+v", err)
+	}
+	want = `%pair = type { i64, i64 }
+declare !file !0 void @h()
+`
+	if got := buf.String(); want != got {
+		t.Errorf("output mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
+
+func TestModuleFragment(t *testing.T) {
+	m := &Module{}
+	m.DataLayout = "e-m:e-i64:64-n8:16:32:64-S128"
+	point := m.NewTypeDef("point", types.NewStruct(types.I32, types.I32))
+	m.NewTypeDef("unused", types.NewStruct(types.I8))
+	g := m.NewGlobalDef("origin", NewZeroInitializer(point))
+	g.Linkage = enum.LinkageInternal
+	m.NewGlobalDef("other", NewInt(types.I32, 0))
+	group := NewAttrGroupDef(0, enum.FuncAttrNoUnwind)
+	m.AttrGroupDefs = append(m.AttrGroupDefs, group, NewAttrGroupDef(1, enum.FuncAttrCold))
+	abort := m.NewFunc("abort", types.Void)
+	abort.FuncAttrs = append(abort.FuncAttrs, group)
+	h := m.NewFunc("h", types.I32, NewParam(types.I32, "x"))
+	h.Linkage = enum.LinkagePrivate
+	h.NewBlock("entry").NewRet(h.Params[0])
+	m.NewMetadataDef(NewTuple(NewMDString("unused")))
+	loc := NewTuple(NewMDString("a.c"))
+	m.NewMetadataDef(loc)
+	f := m.NewFunc("f", types.I32)
+	entry := f.NewBlock("entry")
+	x := entry.NewLoad(NewGetElementPtrExpr(point, g, NewIndex(NewInt(types.I32, 0)), NewIndex(NewInt(types.I32, 1))))
+	y := entry.NewCall(h, x)
+	y.Metadata = append(y.Metadata, NewMetadataAttachment(RegisterMDKind("loc"), loc))
+	entry.NewCall(abort)
+	entry.NewRet(y)
+	got, err := m.Fragment(f)
+	if err != nil {
+		t.Fatalf("unable to print fragment; %+v", err)
+	}
+	want := `target datalayout = "e-m:e-i64:64-n8:16:32:64-S128"
+%point = type { i32, i32 }
+@origin = external global %point
+declare i32 @h(i32)
+declare void @abort() #0
+define i32 @f() {
+entry:
+	%0 = load i32, i32* getelementptr (%point, %point* @origin, i32 0, i32 1)
+	%1 = call i32 @h(i32 %0), !loc !1
+	call void @abort()
+	ret i32 %1
+}
+attributes #0 = { nounwind }
+!1 = !{!"a.c"}
+`
+	if want != got {
+		t.Errorf("fragment mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
+
+func TestModuleFinish(t *testing.T) {
+	m := &Module{}
+	// Reference the type %T, the function @g and the global variable @x before
+	// they are added to the module.
+	tRef := m.TypeRef("T")
+	gType := types.NewPointer(types.NewFunc(types.I32, types.NewPointer(tRef)))
+	gRef := m.Ref("g", gType)
+	xRef := m.Ref("x", types.NewPointer(tRef))
+	m.NewGlobalDef("fp", gRef)
+	f := m.NewFunc("f", types.I32)
+	entry := f.NewBlock("entry")
+	r := entry.NewCall(gRef, xRef)
+	entry.NewRet(r)
+	if err := m.Finish(); err == nil {
+		t.Fatalf("expected unresolved references")
+	} else if want, got := "unresolved references to @g, @x, %T", err.Error(); want != got {
+		t.Errorf("error mismatch; expected %q, got %q", want, got)
+	}
+	m.NewTypeDef("T", types.NewStruct(types.I32))
+	g := m.NewFunc("g", types.I32, NewParam(types.NewPointer(tRef), "p"))
+	x := m.NewGlobalDecl("x", tRef)
+	if err := m.Finish(); err != nil {
+		t.Fatalf("unable to resolve references; %v", err)
+	}
+	if r.Callee != g || r.Args[0] != x || m.Globals[0].Init != g {
+		t.Errorf("placeholder references not resolved")
+	}
+	if got := m.Ref("g", gType); got != g {
+		t.Errorf("reference mismatch; expected %v, got %v", g, got)
+	}
+	want := `%T = type { i32 }`
+	if got := strings.SplitN(m.Def(), "\n", 2)[0]; want != got {
+		t.Errorf("type definition mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
+
+func TestFunctionDefine(t *testing.T) {
+	m := &Module{}
+	g := m.NewFunc("g", types.I32, NewParam(types.I32, ""))
+	g.Linkage = enum.LinkageExternWeak
+	// Take the address of the declaration, and call it.
+	m.NewGlobalDef("gp", g)
+	f := m.NewFunc("f", types.I32)
+	entry := f.NewBlock("entry")
+	r := entry.NewCall(g, NewInt(types.I32, 42))
+	entry.NewRet(r)
+	if !g.IsDeclaration() {
+		t.Fatalf("expected function declaration")
+	}
+	x := NewParam(types.I32, "x")
+	if err := g.Define(NewParam(types.I64, "y")); err == nil {
+		t.Errorf("expected error for parameter type mismatch")
+	}
+	if err := g.Define(x); err != nil {
+		t.Fatalf("unable to define function; %v", err)
+	}
+	g.NewBlock("entry").NewRet(x)
+	if err := g.Define(); err == nil {
+		t.Errorf("expected error for function already defined")
+	}
+	want := `@gp = global i32 (i32)* @g
+define i32 @g(i32 %x) {
+entry:
+	ret i32 %x
+}
+define i32 @f() {
+entry:
+	%0 = call i32 @g(i32 42)
+	ret i32 %0
+}
+`
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %v", err)
+	}
+	if got := m.Def(); want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
+
+func TestLocations(t *testing.T) {
+	m := &Module{RecordLocations: true}
+	f := m.NewFunc("f", types.Void, NewParam(types.NewStruct(types.I32), "x"))
+	entry := f.NewBlock("entry")
+	entry.NewExtractValue(f.Params[0], 0)
+	_, _, line, _ := runtime.Caller(0)
+	entry.NewRet(nil)
+	if len(entry.Insts) != 1 {
+		t.Fatalf("instruction count mismatch; expected 1, got %d", len(entry.Insts))
+	}
+	entry.Insts[0].(value.Named).SetName("0")
+	want := fmt.Sprintf("entry:\n\t%%0 = extractvalue { i32 } %%x, 0 ; created at ir_test.go:%d\n\tret void ; created at ir_test.go:%d", line-1, line+1)
+	if got := (Printer{Locations: true}).Block(entry); want != got {
+		t.Errorf("basic block mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// Locations are only printed if requested.
+	want = "entry:\n\t%0 = extractvalue { i32 } %x, 0\n\tret void"
+	if got := entry.Def(); want != got {
+		t.Errorf("basic block mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// Inserted instructions have the location of the insertion.
+	x := NewExtractValue(f.Params[0], 0)
+	entry.InsertBefore(entry.Insts[0], x)
+	_, _, line, _ = runtime.Caller(0)
+	if got, ok := entry.Location(x); !ok || got != fmt.Sprintf("ir_test.go:%d", line-1) {
+		t.Errorf("location mismatch of inserted instruction; expected ir_test.go:%d, got %q", line-1, got)
+	}
+	// Locations are only recorded for modules with RecordLocations set.
+	other := &Module{}
+	g := other.NewFunc("g", types.Void)
+	block := g.NewBlock("entry")
+	block.NewRet(nil)
+	if _, ok := block.Location(block.Term); ok {
+		t.Errorf("unexpected location of terminator in module without RecordLocations")
+	}
+}
+
+func TestMDKind(t *testing.T) {
+	if got, want := MDKindLoop.String(), "llvm.loop"; got != want {
+		t.Errorf("kind name mismatch; expected %q, got %q", want, got)
+	}
+	if kind, ok := LookupMDKind("prof"); !ok || kind != MDKindProf {
+		t.Errorf("kind mismatch; expected %v, got %v", MDKindProf, kind)
+	}
+	custom := RegisterMDKind("my.kind")
+	if custom <= MDKindNoSanitize {
+		t.Errorf("expected custom kind after well-known kinds, got %d", custom)
+	}
+	if again := RegisterMDKind("my.kind"); again != custom {
+		t.Errorf("kind mismatch of repeated registration; expected %v, got %v", custom, again)
+	}
+	inst := NewAdd(NewInt(types.I32, 1), NewInt(types.I32, 2))
+	prof := NewTuple(NewMDString("branch_weights"))
+	SetMetadata(inst, NewMetadataAttachment(MDKindProf, NewTuple()))
+	SetMetadata(inst, NewMetadataAttachment(MDKindProf, prof))
+	SetMetadata(inst, NewMetadataAttachment(custom, NewTuple()))
+	if got := len(inst.Metadata); got != 2 {
+		t.Fatalf("number of attachments mismatch; expected 2, got %d", got)
+	}
+	if got := inst.Metadata.Prof(); got != prof {
+		t.Errorf("!prof attachment mismatch; expected %v, got %v", prof, got)
+	}
+	if got := inst.Metadata.Dbg(); got != nil {
+		t.Errorf("unexpected !dbg attachment %v", got)
+	}
+	want := "add i32 1, 2, !prof !{!\"branch_weights\"}, !my.kind !{}"
+	if got := inst.Def(); got != want {
+		t.Errorf("instruction mismatch; expected `%v`, got `%v`", want, got)
+	}
+	inst.Metadata.Delete(MDKindProf)
+	if got := inst.Metadata.Prof(); got != nil {
+		t.Errorf("unexpected !prof attachment %v after delete", got)
+	}
+}
+
+func TestCallVariadic(t *testing.T) {
+	m := &Module{}
+	printf := m.NewFunc("printf", types.I32, NewParam(types.I8Ptr, ""))
+	printf.Sig.Variadic = true
+	f := m.NewFunc("f", types.Void)
+	entry := f.NewBlock("")
+	format := NewNull(types.I8Ptr)
+	call := entry.NewCall(printf, format, NewInt(types.I32, 42))
+	call.SetName("n")
+	// Call of a callee the type of which may not be inferred.
+	raw := entry.NewCall(NewUndef(types.I8Ptr), format)
+	raw.CalleeType = types.NewFunc(types.Void, types.I8Ptr)
+	entry.NewRet(nil)
+	golden := []struct {
+		inst *InstCall
+		want string
+	}{
+		// i=0
+		{inst: call, want: "call i32 (i8*, ...) @printf(i8* null, i32 42)"},
+		// i=1
+		{inst: raw, want: "call void (i8*) undef(i8* null)"},
+	}
+	for i, g := range golden {
+		if got := g.inst.Def(); got != g.want {
+			t.Errorf("i=%d: instruction mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+		if err := g.inst.Validate(); err != nil {
+			t.Errorf("i=%d: unexpected error; %v", i, err)
+		}
+	}
+	if got := call.Type(); !got.Equal(types.I32) {
+		t.Errorf("type mismatch; expected i32, got %v", got)
+	}
+	// Invalid fixed argument type.
+	invalid := NewCall(printf, NewInt(types.I32, 1))
+	want := "invalid argument 0 type of callee @printf; expected i8*, got i32"
+	if err := invalid.Validate(); err == nil || err.Error() != want {
+		t.Errorf("error mismatch; expected %q, got %v", want, err)
+	}
+}
+
+func TestMemoryStats(t *testing.T) {
+	m := &Module{}
+	m.NewGlobalDef("x", NewInt(types.I32, 1))
+	f := m.NewFunc("f", types.I32, NewParam(types.I32, "a"))
+	entry := f.NewBlock("entry")
+	add := entry.NewAdd(f.Params[0], NewInt(types.I32, 2))
+	mul := entry.NewMul(add, add)
+	ret := entry.NewRet(mul)
+	SetMetadata(ret, NewMetadataAttachment(MDKindProf, NewTuple(NewMDString("foo"))))
+	stats := m.MemoryStats()
+	golden := []struct {
+		name string
+		got  NodeStats
+		// Expected number of nodes.
+		want int
+	}{
+		// add, mul, ret
+		{name: "insts", got: stats.Insts, want: 3},
+		// i32 1, i32 2
+		{name: "consts", got: stats.Consts, want: 2},
+		// !{!"foo"}, !"foo"
+		{name: "metadata", got: stats.Metadata, want: 2},
+		// @x, @f, %a, %entry
+		{name: "other", got: stats.Other, want: 4},
+	}
+	for _, g := range golden {
+		if g.got.Count != g.want {
+			t.Errorf("%s: node count mismatch; expected %d, got %d", g.name, g.want, g.got.Count)
+		}
+		if g.got.Bytes <= 0 {
+			t.Errorf("%s: expected positive number of bytes, got %d", g.name, g.got.Bytes)
+		}
+	}
+	// Strings: "x", "f", "a", "entry", "foo".
+	if want := int64(len("x" + "f" + "a" + "entry" + "foo")); stats.Strings.Bytes != want {
+		t.Errorf("string bytes mismatch; expected %d, got %d", want, stats.Strings.Bytes)
+	}
+	total := stats.Total()
+	if total.Count != stats.Insts.Count+stats.Consts.Count+stats.Metadata.Count+stats.Types.Count+stats.Strings.Count+stats.Other.Count {
+		t.Errorf("total node count mismatch; got %d", total.Count)
+	}
+	if s := stats.String(); !strings.Contains(s, "insts:") || !strings.Contains(s, "total:") {
+		t.Errorf("unexpected memory statistics table; got %q", s)
+	}
+}
+
+func TestObserver(t *testing.T) {
+	m := &Module{}
+	// Functions present before the observer is set are linked by SetObserver.
+	g := m.NewFunc("g", types.Void)
+	gEntry := g.NewBlock("entry")
+	var events []string
+	name := func(x interface{}) string {
+		switch x := x.(type) {
+		case *Module:
+			return "module"
+		case Instruction:
+			return x.Def()
+		case Terminator:
+			return x.Def()
+		case value.Named:
+			return x.Ident()
+		}
+		return fmt.Sprintf("%T", x)
+	}
+	m.SetObserver(&Observer{
+		Insert: func(parent, node interface{}) {
+			events = append(events, fmt.Sprintf("insert %s into %s", name(node), name(parent)))
+		},
+		Remove: func(parent, node interface{}) {
+			events = append(events, fmt.Sprintf("remove %s from %s", name(node), name(parent)))
+		},
+		Rename: func(v value.Named, old string) {
+			events = append(events, fmt.Sprintf("rename @%s to %s", old, v.Ident()))
+		},
+	})
+	gEntry.NewRet(nil)
+	x := m.NewGlobalDef("x", NewInt(types.I32, 1))
+	f := m.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	load := entry.NewLoad(x)
+	entry.NewUnreachable()
+	entry.NewRet(nil)
+	entry.RemoveInst(load)
+	f.RemoveBlock(entry)
+	if err := m.Rename(x, "y"); err != nil {
+		t.Fatalf("unable to rename global variable; %+v", err)
+	}
+	m.RemoveGlobal(x)
+	m.RemoveFunc(f)
+	// Mutations of removed nodes are not observed.
+	entry.NewRet(nil)
+	want := []string{
+		"insert ret void into %entry",
+		"insert @x into module",
+		"insert @f into module",
+		"insert %entry into @f",
+		"insert load i32, i32* @x into %entry",
+		"insert unreachable into %entry",
+		"remove unreachable from %entry",
+		"insert ret void into %entry",
+		"remove load i32, i32* @x from %entry",
+		"remove %entry from @f",
+		"rename @x to @y",
+		"remove @y from module",
+		"remove @f from module",
+	}
+	if got := strings.Join(events, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("events mismatch; expected\n%s\ngot\n%s", strings.Join(want, "\n"), got)
+	}
+}
+
+func TestObserverInsert(t *testing.T) {
+	m := &Module{}
+	f := m.NewFunc("f", types.I32, NewParam(types.I32, "a"))
+	entry := f.NewBlock("entry")
+	var events []string
+	name := func(x interface{}) string {
+		if n, ok := x.(value.Named); ok {
+			return n.Ident()
+		}
+		return fmt.Sprintf("%T", x)
+	}
+	m.SetObserver(&Observer{
+		Insert: func(parent, node interface{}) {
+			events = append(events, fmt.Sprintf("insert %s into %s", name(node), name(parent)))
+		},
+		Remove: func(parent, node interface{}) {
+			events = append(events, fmt.Sprintf("remove %s from %s", name(node), name(parent)))
+		},
+		Rename: func(v value.Named, old string) {
+			events = append(events, fmt.Sprintf("rename %q to %s", old, v.Ident()))
+		},
+	})
+	entry.NewRet(f.Params[0])
+	x := NewAdd(f.Params[0], NewInt(types.I32, 1))
+	// Renaming instructions not yet inserted is not observed.
+	x.SetName("x")
+	entry.InsertInst(0, x)
+	y := NewMul(f.Params[0], f.Params[0])
+	y.SetName("y")
+	entry.InsertBefore(x, y)
+	x.SetName("z")
+	entry.SetName("start")
+	// Renaming copies of inserted instructions is not observed.
+	c := CloneInst(x, nil)
+	c.(value.Named).SetName("c")
+	entry.RemoveInst(x)
+	// Renaming removed instructions is not observed.
+	x.SetName("w")
+	want := []string{
+		"insert *ir.TermRet into %entry",
+		"insert %x into %entry",
+		"insert %y into %entry",
+		`rename "x" to %z`,
+		`rename "entry" to %start`,
+		"remove %z from %start",
+	}
+	if got := strings.Join(events, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("events mismatch; expected\n%s\ngot\n%s", strings.Join(want, "\n"), got)
+	}
+	if len(entry.Insts) != 1 || entry.Insts[0] != y {
+		t.Errorf("instructions mismatch; expected [%v], got %v", y.Ident(), entry.Insts)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	m := &Module{}
+	f := m.NewFunc("f", types.I32)
+	main := m.NewFunc("main", types.I32)
+	entry := main.NewBlock("entry")
+	entry.NewCall(NewFunc("puts", types.I32, NewParam(types.I8Ptr, "")), m.InternString("hi"))
+	entry.NewRet(entry.NewCall(f))
+	m.AttrGroupDefs = append(m.AttrGroupDefs, NewAttrGroupDef(0, enum.FuncAttrNoUnwind))
+	// Build shards concurrently.
+	shards := []*Module{m.NewShard(), m.NewShard()}
+	build := []func(shard *Module){
+		func(shard *Module) {
+			// Definition of @f, using a placeholder of %T and @g.
+			t := shard.TypeRef("T")
+			flag := NewTuple(NewMDString("shard"))
+			shard.NewMetadataDef(flag)
+			shard.NamedMetadata("llvm.module.flags").Nodes = append(shard.NamedMetadata("llvm.module.flags").Nodes, flag)
+			counter := shard.NewGlobalDef("counter", NewInt(types.I32, 1))
+			counter.Linkage = enum.LinkageInternal
+			str := shard.NewGlobalDef(".str", NewCharArrayFromString("x"))
+			str.Linkage = enum.LinkagePrivate
+			f := shard.NewFunc("f", types.I32)
+			a := NewAttrGroupDef(0, enum.FuncAttrNoUnwind)
+			shard.AttrGroupDefs = append(shard.AttrGroupDefs, a)
+			f.FuncAttrs = append(f.FuncAttrs, a)
+			entry := f.NewBlock("entry")
+			entry.NewAlloca(t)
+			entry.NewLoad(counter)
+			g := shard.Ref("g", types.NewPointer(types.NewFunc(types.I32)))
+			entry.NewRet(entry.NewCall(g))
+		},
+		func(shard *Module) {
+			// Definition of @g and %T.
+			shard.NewTypeDef("T", types.NewStruct(types.I32))
+			flag := NewTuple(NewMDString("shard"))
+			shard.NewMetadataDef(flag)
+			shard.NamedMetadata("llvm.module.flags").Nodes = append(shard.NamedMetadata("llvm.module.flags").Nodes, flag)
+			counter := shard.NewGlobalDef("counter", NewInt(types.I32, 2))
+			counter.Linkage = enum.LinkageInternal
+			g := shard.NewFunc("g", types.I32)
+			a := NewAttrGroupDef(0, enum.FuncAttrNoInline)
+			shard.AttrGroupDefs = append(shard.AttrGroupDefs, a)
+			g.FuncAttrs = append(g.FuncAttrs, a)
+			entry := g.NewBlock("entry")
+			entry.NewCall(NewFunc("puts", types.I32, NewParam(types.I8Ptr, "")), shard.InternString("hi"))
+			entry.NewRet(entry.NewLoad(counter))
+		},
+	}
+	var wg sync.WaitGroup
+	for i := range shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			build[i](shards[i])
+		}(i)
+	}
+	wg.Wait()
+	if err := m.Merge(shards...); err != nil {
+		t.Fatalf("unable to merge shards; %+v", err)
+	}
+	if err := m.Finish(); err != nil {
+		t.Fatalf("unable to resolve references; %+v", err)
+	}
+	for _, f := range m.Funcs {
+		if err := f.AssignIDs(); err != nil {
+			t.Fatalf("unable to assign IDs; %v", err)
+		}
+	}
+	want := `%T = type { i32 }
+@.str = private unnamed_addr constant [3 x i8] c"hi\00"
+@counter = internal global i32 1
+@.str.1 = private global [1 x i8] c"x"
+@counter.1 = internal global i32 2
+define i32 @main() {
+entry:
+	%0 = call i32 @puts(i8* getelementptr inbounds ([3 x i8], [3 x i8]* @.str, i64 0, i64 0))
+	%1 = call i32 @f()
+	ret i32 %1
+}
+define i32 @f() #0 {
+entry:
+	%0 = alloca %T
+	%1 = load i32, i32* @counter
+	%2 = call i32 @g()
+	ret i32 %2
+}
+define i32 @g() #1 {
+entry:
+	%0 = call i32 @puts(i8* getelementptr inbounds ([3 x i8], [3 x i8]* @.str, i64 0, i64 0))
+	%1 = load i32, i32* @counter.1
+	ret i32 %1
+}
+attributes #0 = { nounwind }
+attributes #1 = { noinline }
+!llvm.module.flags = !{!0}
+!0 = !{!"shard"}
+`
+	if got := m.Def(); got != want {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+	if v, ok := m.Lookup("f"); !ok || v == value.Value(f) {
+		t.Errorf("declaration of @f not replaced by definition; got %v", v)
+	}
+	// Duplicate definitions; the module is left unchanged, also by shards
+	// preceding the failing shard.
+	before := m.Def()
+	shard := m.NewShard()
+	h := shard.NewFunc("h", types.I32)
+	h.NewBlock("entry").NewRet(NewInt(types.I32, 1))
+	shard.NewFunc("f", types.I32)
+	dup := m.NewShard()
+	dup.NewFunc("g", types.I32).NewBlock("entry").NewRet(NewInt(types.I32, 0))
+	if err := m.Merge(shard, dup); err == nil {
+		t.Errorf("expected error on duplicate definition of @g")
+	}
+	if got := m.Def(); got != before {
+		t.Errorf("module changed by failed merge; expected `%v`, got `%v`", before, got)
+	}
+	if _, ok := m.Lookup("h"); ok {
+		t.Errorf("unexpected @h in module after failed merge")
+	}
+	if len(shard.Funcs) != 2 || shard.Funcs[0] != h {
+		t.Errorf("shard changed by failed merge; got functions %v", shard.Funcs)
+	}
+}
+
+func TestInternNames(t *testing.T) {
+	m := &Module{}
+	var names []string
+	var mds []*MDString
+	for i := 0; i < 2; i++ {
+		f := m.NewFunc(fmt.Sprintf("f%d", i), types.I32)
+		entry := f.NewBlock(string([]byte("entry")))
+		tmp := entry.NewAdd(NewInt(types.I32, 1), NewInt(types.I32, 2))
+		tmp.SetName(string([]byte("tmp")))
+		entry.NewRet(tmp)
+		md := NewMDString(string([]byte("file.c")))
+		m.NewMetadataDef(NewTuple(md))
+		names = append(names, entry.LocalName, tmp.LocalName)
+		mds = append(mds, md)
+	}
+	if unsafe.StringData(names[0]) == unsafe.StringData(names[2]) {
+		t.Fatalf("expected names of separate storage before interning")
+	}
+	m.InternNames()
+	f0, f1 := m.Funcs[0], m.Funcs[1]
+	pairs := [][2]string{
+		{f0.Blocks[0].LocalName, f1.Blocks[0].LocalName},
+		{f0.Blocks[0].Insts[0].(*InstAdd).LocalName, f1.Blocks[0].Insts[0].(*InstAdd).LocalName},
+		{mds[0].Value, mds[1].Value},
+	}
+	for i, pair := range pairs {
+		if pair[0] != pair[1] || unsafe.StringData(pair[0]) != unsafe.StringData(pair[1]) {
+			t.Errorf("i=%d: names %q and %q not interned", i, pair[0], pair[1])
+		}
+	}
+	if got, want := m.Intern(string([]byte("tmp"))), pairs[1][0]; unsafe.StringData(got) != unsafe.StringData(want) {
+		t.Errorf("expected interned name %q", want)
+	}
+}
+
+func TestInternBuilder(t *testing.T) {
+	m := &Module{}
+	m.InternNames()
+	var names []string
+	for i := 0; i < 2; i++ {
+		f := m.NewFunc(fmt.Sprintf("f%d", i), types.I32)
+		entry := f.NewBlock(string([]byte("entry")))
+		tmp := entry.NewAdd(NewInt(types.I32, 1), NewInt(types.I32, 2))
+		tmp.SetName(string([]byte("tmp")))
+		ret := NewSub(tmp, NewInt(types.I32, 1))
+		ret.SetName(string([]byte("idx")))
+		entry.InsertInst(1, ret)
+		entry.NewRet(ret)
+		md := NewMDString(string([]byte("file.c")))
+		m.NewMetadataDef(NewTuple(md))
+		names = append(names, entry.LocalName, tmp.LocalName, ret.LocalName, md.Value)
+	}
+	for i := 0; i < 4; i++ {
+		if unsafe.StringData(names[i]) != unsafe.StringData(names[i+4]) {
+			t.Errorf("i=%d: name %q not interned", i, names[i])
+		}
+	}
+}
@@ -2,6 +2,384 @@
 
 package ir
 
-// TODO: implement.
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/llir/l/internal/enc"
+	"github.com/llir/l/ir/value"
+)
+
+// === [ Metadata ] ============================================================
+
+// Metadata is an LLVM IR metadata node or metadata value.
+//
+// A Metadata has one of the following underlying types.
+//
+//    *ir.MDTuple         // https://godoc.org/github.com/llir/l/ir#MDTuple
+//    *ir.MDSpecialized   // https://godoc.org/github.com/llir/l/ir#MDSpecialized
+//    *ir.MDString        // https://godoc.org/github.com/llir/l/ir#MDString
+//    *ir.MDValue         // https://godoc.org/github.com/llir/l/ir#MDValue
+type Metadata interface {
+	// String returns the LLVM syntax representation of the metadata, as used
+	// when referenced.
+	fmt.Stringer
+	// IsMetadata ensures that only metadata can be assigned to the
+	// ir.Metadata interface.
+	IsMetadata()
+}
+
+// MDNode is a metadata node, which may be defined with a metadata ID.
+//
+// A MDNode has one of the following underlying types.
+//
+//    *ir.MDTuple         // https://godoc.org/github.com/llir/l/ir#MDTuple
+//    *ir.MDSpecialized   // https://godoc.org/github.com/llir/l/ir#MDSpecialized
+type MDNode interface {
+	Metadata
+	// ID returns the metadata ID of the node (without '!' prefix); or -1 if the
+	// node is printed inline.
+	ID() int64
+	// SetID sets the metadata ID of the node.
+	SetID(id int64)
+	// Def returns the LLVM syntax representation of the node definition.
+	Def() string
+}
+
+// --- [ Metadata tuples ] -----------------------------------------------------
+
+// MDTuple is a metadata tuple (e.g. `!{!"foo", i32 42}`).
+type MDTuple struct {
+	// Metadata ID (without '!' prefix); or -1 if the tuple is printed inline.
+	MetadataID int64
+	// Tuple fields; nil fields are printed as null.
+	Fields []Metadata
+
+	// extra.
+
+	// (optional) Distinct; false if not present.
+	Distinct bool
+}
+
+// NewTuple returns a new inline metadata tuple based on the given fields.
+func NewTuple(fields ...Metadata) *MDTuple {
+	return &MDTuple{MetadataID: -1, Fields: fields}
+}
+
+// String returns the LLVM syntax representation of the metadata tuple, as used
+// when referenced; the metadata ID if present, and the inline tuple otherwise.
+func (md *MDTuple) String() string {
+	if md.MetadataID >= 0 {
+		return enc.MetadataID(md.MetadataID)
+	}
+	return md.Def()
+}
+
+// Def returns the LLVM syntax representation of the metadata tuple definition.
+func (md *MDTuple) Def() string {
+	// OptDistinct "!" "{" MDFields "}"
+	buf := &strings.Builder{}
+	if md.Distinct {
+		buf.WriteString("distinct ")
+	}
+	buf.WriteString("!{")
+	for i, field := range md.Fields {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		if field == nil {
+			buf.WriteString("null")
+			continue
+		}
+		buf.WriteString(field.String())
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// IsMetadata ensures that only metadata can be assigned to the ir.Metadata
+// interface.
+func (*MDTuple) IsMetadata() {}
+
+// ID returns the metadata ID of the metadata tuple; or -1 if printed inline.
+func (md *MDTuple) ID() int64 {
+	return md.MetadataID
+}
+
+// SetID sets the metadata ID of the metadata tuple.
+func (md *MDTuple) SetID(id int64) {
+	md.MetadataID = id
+}
+
+// --- [ Specialized metadata nodes ] ------------------------------------------
+
+// MDSpecialized is a specialized metadata node (e.g.
+// `!DILocation(line: 2, column: 7, scope: !3)`).
+type MDSpecialized struct {
+	// Metadata ID (without '!' prefix); or -1 if the node is printed inline.
+	MetadataID int64
+	// Kind of the specialized metadata node (without '!' prefix); e.g.
+	// "DILocation".
+	Kind string
+	// Fields of the specialized metadata node, in order of occurrence.
+	Fields []*MDField
+
+	// extra.
+
+	// (optional) Distinct; false if not present.
+	Distinct bool
+}
+
+// NewSpecialized returns a new inline specialized metadata node based on the
+// given kind and fields.
+func NewSpecialized(kind string, fields ...*MDField) *MDSpecialized {
+	return &MDSpecialized{MetadataID: -1, Kind: kind, Fields: fields}
+}
+
+// String returns the LLVM syntax representation of the specialized metadata
+// node, as used when referenced; the metadata ID if present, and the inline
+// node otherwise.
+func (md *MDSpecialized) String() string {
+	if md.MetadataID >= 0 {
+		return enc.MetadataID(md.MetadataID)
+	}
+	return md.Def()
+}
+
+// Def returns the LLVM syntax representation of the specialized metadata node
+// definition.
+func (md *MDSpecialized) Def() string {
+	// OptDistinct "!" Kind "(" MDFields ")"
+	buf := &strings.Builder{}
+	if md.Distinct {
+		buf.WriteString("distinct ")
+	}
+	fmt.Fprintf(buf, "!%s(", md.Kind)
+	for i, field := range md.Fields {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(field.String())
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
+// IsMetadata ensures that only metadata can be assigned to the ir.Metadata
+// interface.
+func (*MDSpecialized) IsMetadata() {}
+
+// ID returns the metadata ID of the specialized metadata node; or -1 if printed
+// inline.
+func (md *MDSpecialized) ID() int64 {
+	return md.MetadataID
+}
+
+// SetID sets the metadata ID of the specialized metadata node.
+func (md *MDSpecialized) SetID(id int64) {
+	md.MetadataID = id
+}
+
+// Field returns the field of the specialized metadata node with the given
+// name, or nil if not present.
+func (md *MDSpecialized) Field(name string) *MDField {
+	for _, field := range md.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// Operands returns the metadata operands of the specialized metadata node; the
+// values of fields of metadata type, in order of occurrence.
+func (md *MDSpecialized) Operands() []Metadata {
+	var operands []Metadata
+	for _, field := range md.Fields {
+		if operand, ok := field.Value.(Metadata); ok {
+			operands = append(operands, operand)
+		}
+	}
+	return operands
+}
+
+// MDField is a field of a specialized metadata node (e.g. `line: 2`).
+type MDField struct {
+	// Field name; e.g. "line".
+	Name string
+	// Field value; one of the following types.
+	//
+	//    int64      // e.g. `line: 2`
+	//    uint64     // e.g. `size: 64`
+	//    bool       // e.g. `isOptimized: false`
+	//    string     // e.g. `filename: "foo.c"`
+	//    MDEnum     // e.g. `tag: DW_TAG_member`
+	//    Metadata   // e.g. `scope: !3`
+	//    nil        // `null`
+	Value interface{}
+}
+
+// NewMDField returns a new field of a specialized metadata node based on the
+// given field name and value.
+func NewMDField(name string, value interface{}) *MDField {
+	return &MDField{Name: name, Value: value}
+}
+
+// String returns the LLVM syntax representation of the field.
+func (field *MDField) String() string {
+	// Name ":" Value
+	switch v := field.Value.(type) {
+	case nil:
+		return fmt.Sprintf("%s: null", field.Name)
+	case int64, uint64, bool:
+		return fmt.Sprintf("%s: %v", field.Name, v)
+	case string:
+		return fmt.Sprintf("%s: %s", field.Name, quote(v))
+	case MDEnum:
+		return fmt.Sprintf("%s: %s", field.Name, string(v))
+	case Metadata:
+		return fmt.Sprintf("%s: %v", field.Name, v)
+	default:
+		panic(errorf("support for metadata field value type %T not yet implemented", v))
+	}
+}
+
+// MDEnum is an enumerator or flags value of a specialized metadata node field,
+// printed verbatim (e.g. `DW_LANG_C99` or `DIFlagPrototyped | DIFlagPublic`).
+type MDEnum string
+
+// --- [ Metadata strings ] ----------------------------------------------------
+
+// MDString is a metadata string (e.g. `!"foo"`).
+type MDString struct {
+	// String value.
+	Value string
+}
+
+// NewMDString returns a new metadata string based on the given string value.
+func NewMDString(s string) *MDString {
+	return &MDString{Value: s}
+}
+
+// String returns the LLVM syntax representation of the metadata string.
+func (md *MDString) String() string {
+	return enc.MetadataString(md.Value)
+}
+
+// IsMetadata ensures that only metadata can be assigned to the ir.Metadata
+// interface.
+func (*MDString) IsMetadata() {}
+
+// --- [ Metadata values ] -----------------------------------------------------
+
+// MDValue is a value used as metadata (e.g. `i32 42`).
+type MDValue struct {
+	// Value.
+	Value value.Value
+}
+
+// NewMDValue returns a new metadata value based on the given value.
+func NewMDValue(v value.Value) *MDValue {
+	return &MDValue{Value: v}
+}
+
+// String returns the LLVM syntax representation of the metadata value.
+func (md *MDValue) String() string {
+	return md.Value.String()
+}
+
+// IsMetadata ensures that only metadata can be assigned to the ir.Metadata
+// interface.
+func (*MDValue) IsMetadata() {}
+
+// --- [ Named metadata definitions ] ------------------------------------------
+
+// NamedMetadataDef is a named metadata definition (e.g. `!llvm.dbg.cu = !{!0}`).
+type NamedMetadataDef struct {
+	// Metadata name (without '!' prefix); e.g. "llvm.dbg.cu".
+	Name string
+	// Metadata nodes.
+	Nodes []MDNode
+}
+
+// NewNamedMetadataDef returns a new named metadata definition based on the
+// given metadata name and nodes.
+func NewNamedMetadataDef(name string, nodes ...MDNode) *NamedMetadataDef {
+	return &NamedMetadataDef{Name: name, Nodes: nodes}
+}
+
+// Def returns the LLVM syntax representation of the named metadata definition.
+func (md *NamedMetadataDef) Def() string {
+	// MetadataName "=" "!" "{" MetadataNodes "}"
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "%s = !{", enc.MetadataName(md.Name))
+	for i, node := range md.Nodes {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(node.String())
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// --- [ Metadata attachments ] ------------------------------------------------
+
+// MetadataAttachment is a metadata attachment of an instruction, function or
+// global variable (e.g. `!prof !0`).
 type MetadataAttachment struct {
+	// Metadata attachment kind; e.g. MDKindProf.
+	Kind MDKind
+	// Attached metadata node.
+	Node Metadata
+}
+
+// NewMetadataAttachment returns a new metadata attachment based on the given
+// attachment kind and metadata node.
+func NewMetadataAttachment(kind MDKind, node Metadata) MetadataAttachment {
+	return MetadataAttachment{Kind: kind, Node: node}
+}
+
+// String returns the LLVM syntax representation of the metadata attachment.
+func (md MetadataAttachment) String() string {
+	// MetadataName MDNode
+	return fmt.Sprintf("%v %v", enc.MetadataName(md.Kind.String()), md.Node)
+}
+
+// MetadataAttachments returns the metadata attachments of the given
+// instruction, terminator, global variable or function. The boolean return
+// value indicates whether the node supports metadata attachments.
+func MetadataAttachments(node interface{}) (MDAttachments, bool) {
+	field, ok := metadataField(node)
+	if !ok {
+		return nil, false
+	}
+	return field.Interface().(MDAttachments), true
+}
+
+// SetMetadata sets the metadata attachment of the given instruction,
+// terminator, global variable or function, replacing the existing attachment
+// of the same kind if present. SetMetadata panics if the node does not support
+// metadata attachments.
+func SetMetadata(node interface{}, md MetadataAttachment) {
+	field, ok := metadataField(node)
+	if !ok {
+		panic(errorf("support for metadata attachments of %T not yet implemented", node))
+	}
+	field.Addr().Interface().(*MDAttachments).Set(md)
+}
+
+// metadataField returns the Metadata struct field of the given IR node, which
+// is a pointer to a struct. The boolean return value indicates success.
+func metadataField(node interface{}) (reflect.Value, bool) {
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	field := v.Elem().FieldByName("Metadata")
+	if !field.IsValid() || field.Type() != reflect.TypeOf(MDAttachments(nil)) {
+		return reflect.Value{}, false
+	}
+	return field, true
 }
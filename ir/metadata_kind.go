@@ -0,0 +1,237 @@
+package ir
+
+import (
+	"fmt"
+	"sync"
+)
+
+// --- [ Metadata attachment kinds ] -------------------------------------------
+
+// MDKind is the kind of a metadata attachment (e.g. !dbg or !prof).
+//
+// The well-known kinds of LLVM are predefined, in the order of their fixed
+// metadata kind IDs in LLVM. Custom kinds are registered by RegisterMDKind.
+type MDKind uint
+
+// Well-known metadata attachment kinds.
+const (
+	MDKindDbg                   MDKind = iota // dbg
+	MDKindTBAA                                // tbaa
+	MDKindProf                                // prof
+	MDKindFPMath                              // fpmath
+	MDKindRange                               // range
+	MDKindTBAAStruct                          // tbaa.struct
+	MDKindInvariantLoad                       // invariant.load
+	MDKindAliasScope                          // alias.scope
+	MDKindNoAlias                             // noalias
+	MDKindNonTemporal                         // nontemporal
+	MDKindMemParallelLoopAccess               // llvm.mem.parallel_loop_access
+	MDKindNonNull                             // nonnull
+	MDKindDereferenceable                     // dereferenceable
+	MDKindDereferenceableOrNull               // dereferenceable_or_null
+	MDKindMakeImplicit                        // make.implicit
+	MDKindUnpredictable                       // unpredictable
+	MDKindInvariantGroup                      // invariant.group
+	MDKindAlign                               // align
+	MDKindLoop                                // llvm.loop
+	MDKindType                                // type
+	MDKindSectionPrefix                       // section_prefix
+	MDKindAbsoluteSymbol                      // absolute_symbol
+	MDKindAssociated                          // associated
+	MDKindCallees                             // callees
+	MDKindIrrLoop                             // irr_loop
+	MDKindAccessGroup                         // llvm.access.group
+	MDKindCallback                            // callback
+	MDKindPreserveAccessIndex                 // llvm.preserve.access.index
+	MDKindVCallVisibility                     // vcall_visibility
+	MDKindNoUndef                             // noundef
+	MDKindAnnotation                          // annotation
+	MDKindNoSanitize                          // nosanitize
+)
+
+// mdKinds is the registry of metadata attachment kinds, indexed by kind.
+var mdKinds = struct {
+	sync.Mutex
+	// Kind names, indexed by kind.
+	names []string
+	// Kinds, indexed by name.
+	kinds map[string]MDKind
+}{
+	names: []string{
+		"dbg",
+		"tbaa",
+		"prof",
+		"fpmath",
+		"range",
+		"tbaa.struct",
+		"invariant.load",
+		"alias.scope",
+		"noalias",
+		"nontemporal",
+		"llvm.mem.parallel_loop_access",
+		"nonnull",
+		"dereferenceable",
+		"dereferenceable_or_null",
+		"make.implicit",
+		"unpredictable",
+		"invariant.group",
+		"align",
+		"llvm.loop",
+		"type",
+		"section_prefix",
+		"absolute_symbol",
+		"associated",
+		"callees",
+		"irr_loop",
+		"llvm.access.group",
+		"callback",
+		"llvm.preserve.access.index",
+		"vcall_visibility",
+		"noundef",
+		"annotation",
+		"nosanitize",
+	},
+}
+
+func init() {
+	mdKinds.kinds = make(map[string]MDKind)
+	for kind, name := range mdKinds.names {
+		mdKinds.kinds[name] = MDKind(kind)
+	}
+}
+
+// RegisterMDKind returns the metadata attachment kind of the given name
+// (without '!' prefix), registering a custom kind if not yet present; e.g. for
+// attachments specific to a frontend. RegisterMDKind is safe for concurrent
+// use.
+func RegisterMDKind(name string) MDKind {
+	mdKinds.Lock()
+	defer mdKinds.Unlock()
+	if kind, ok := mdKinds.kinds[name]; ok {
+		return kind
+	}
+	kind := MDKind(len(mdKinds.names))
+	mdKinds.names = append(mdKinds.names, name)
+	mdKinds.kinds[name] = kind
+	return kind
+}
+
+// LookupMDKind returns the metadata attachment kind of the given name (without
+// '!' prefix). The boolean return value indicates whether the kind is
+// well-known or registered.
+func LookupMDKind(name string) (MDKind, bool) {
+	mdKinds.Lock()
+	defer mdKinds.Unlock()
+	kind, ok := mdKinds.kinds[name]
+	return kind, ok
+}
+
+// String returns the name of the metadata attachment kind (without '!'
+// prefix).
+func (kind MDKind) String() string {
+	mdKinds.Lock()
+	defer mdKinds.Unlock()
+	if int(kind) < len(mdKinds.names) {
+		return mdKinds.names[kind]
+	}
+	return fmt.Sprintf("MDKind(%d)", uint(kind))
+}
+
+// --- [ Metadata attachment lists ] -------------------------------------------
+
+// MDAttachments is a list of metadata attachments of an instruction,
+// terminator, global variable or function; with at most one attachment of each
+// kind.
+type MDAttachments []MetadataAttachment
+
+// Get returns the metadata node attached with the given kind; or nil if not
+// present.
+func (mds MDAttachments) Get(kind MDKind) Metadata {
+	for _, md := range mds {
+		if md.Kind == kind {
+			return md.Node
+		}
+	}
+	return nil
+}
+
+// Set sets the metadata attachment, replacing the existing attachment of the
+// same kind if present.
+func (mds *MDAttachments) Set(md MetadataAttachment) {
+	for i := range *mds {
+		if (*mds)[i].Kind == md.Kind {
+			(*mds)[i] = md
+			return
+		}
+	}
+	*mds = append(*mds, md)
+}
+
+// Delete removes the metadata attachment of the given kind if present.
+func (mds *MDAttachments) Delete(kind MDKind) {
+	for i := range *mds {
+		if (*mds)[i].Kind == kind {
+			*mds = append((*mds)[:i], (*mds)[i+1:]...)
+			return
+		}
+	}
+}
+
+// ~~~ [ Typed accessors ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Dbg returns the !dbg attachment (e.g. a DILocation of an instruction or a
+// DISubprogram of a function); or nil if not present or not a specialized
+// metadata node.
+func (mds MDAttachments) Dbg() *MDSpecialized {
+	node, _ := mds.Get(MDKindDbg).(*MDSpecialized)
+	return node
+}
+
+// TBAA returns the !tbaa attachment of type-based alias analysis; or nil if not
+// present or not a metadata node.
+func (mds MDAttachments) TBAA() MDNode {
+	node, _ := mds.Get(MDKindTBAA).(MDNode)
+	return node
+}
+
+// Prof returns the !prof attachment of profile data (e.g. branch weights); or
+// nil if not present or not a metadata tuple.
+func (mds MDAttachments) Prof() *MDTuple {
+	return mds.tuple(MDKindProf)
+}
+
+// Range returns the !range attachment of value ranges; or nil if not present
+// or not a metadata tuple.
+func (mds MDAttachments) Range() *MDTuple {
+	return mds.tuple(MDKindRange)
+}
+
+// AliasScope returns the !alias.scope attachment; or nil if not present or not
+// a metadata tuple.
+func (mds MDAttachments) AliasScope() *MDTuple {
+	return mds.tuple(MDKindAliasScope)
+}
+
+// NoAlias returns the !noalias attachment; or nil if not present or not a
+// metadata tuple.
+func (mds MDAttachments) NoAlias() *MDTuple {
+	return mds.tuple(MDKindNoAlias)
+}
+
+// Loop returns the !llvm.loop attachment of loop properties; or nil if not
+// present or not a metadata tuple.
+func (mds MDAttachments) Loop() *MDTuple {
+	return mds.tuple(MDKindLoop)
+}
+
+// NoSanitize reports whether the !nosanitize attachment is present.
+func (mds MDAttachments) NoSanitize() bool {
+	return mds.Get(MDKindNoSanitize) != nil
+}
+
+// tuple returns the metadata tuple attached with the given kind; or nil if not
+// present or not a metadata tuple.
+func (mds MDAttachments) tuple(kind MDKind) *MDTuple {
+	node, _ := mds.Get(kind).(*MDTuple)
+	return node
+}
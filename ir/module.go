@@ -8,6 +8,8 @@ import (
 	"github.com/llir/l/internal/enc"
 	"github.com/llir/l/ir/enum"
 	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
 )
 
 // === [ Modules ] =============================================================
@@ -18,6 +20,8 @@ type Module struct {
 	TypeDefs []types.Type
 	// Global variable declarations and definitions.
 	Globals []*Global
+	// Raw global entities, printed verbatim after global variables.
+	RawGlobals []*RawGlobal
 	// Function declarations and definitions.
 	Funcs []*Function
 
@@ -25,11 +29,48 @@ type Module struct {
 
 	// (optional) Source filename; or empty if not present.
 	SourceFilename string
+	// (optional) Data layout; or empty if not present.
+	DataLayout string
+	// (optional) Target triple; or empty if not present.
+	TargetTriple string
+	// (optional) Attribute group definitions.
+	AttrGroupDefs []*AttrGroupDef
+	// (optional) Named metadata definitions.
+	NamedMetadataDefs []*NamedMetadataDef
+	// (optional) Metadata definitions; metadata nodes with a metadata ID.
+	MetadataDefs []MDNode
+	// Collect the errors of the module builder methods (e.g. duplicate global
+	// identifiers) rather than panicking, to be reported by Errors and Check.
+	CollectErrors bool
+	// Record the source location (file:line) of the Go code which created or
+	// inserted each instruction and terminator by the builder and insertion
+	// methods of basic blocks (e.g. NewAdd, NewRet and InsertInst) of the
+	// functions of the module; as printed by Printer if Locations is set.
+	RecordLocations bool
+
+	// Symbol table of global identifiers, as registered by the module builder
+	// methods (e.g. NewFunc); global name (without '@' prefix) -> value.
+	symbols map[string]value.Named
+	// Placeholder references to global variables and functions not yet present
+	// in the module, as returned by Ref; global name -> placeholder.
+	refs map[string]*GlobalRef
+	// Placeholder struct types of type definitions not yet present in the
+	// module, as returned by TypeRef; type name -> placeholder.
+	typeRefs map[string]*types.StructType
+	// Pooled string literals, as returned by InternString; string contents
+	// (including NULL-terminator) -> global variable.
+	strs map[string]*Global
+	// Interned names of the module, as returned by Intern; unused if the module
+	// has a context.
+	names map[string]string
+	// Context of the module; or nil if created without context.
+	ctx *Context
+	// Errors of the module builder methods, as collected if CollectErrors is
+	// set.
+	errs ErrorList
+	// Mutation observer of the module; or nil if not present.
+	obs *Observer
 	/*
-		// (optional) Data layout; or empty if not present.
-		DataLayout string
-		// (optional) Target triple; or empty if not present.
-		TargetTriple string
 		// (optional) Module-level inline assembly.
 		ModuleAsms []string
 		// (optional) Comdat definitions.
@@ -37,14 +78,6 @@ type Module struct {
 		// (optional) Indirect symbol definitions (aliases and IFuncs).
 		// TODO: figure out how to represent aliases and IFuncs.
 		//IndirectSymbols []*IndirectSymbol
-		// (optional) Attribute group definitions.
-		AttrGroupDefs []*enum.AttrGroupDef
-		// (optional) Named metadata definitions.
-		// TODO: figure out how to represent metadata.
-		//NamedMetadataDefs []*metadata.NamedMetadataDef
-		// (optional) Metadata definitions.
-		// TODO: figure out how to represent metadata.
-		//MetadataDefs []*metadata.MetadataDef
 		// (optional) Use-list order directives.
 		UseListOrders []*enum.UseListOrder
 		// (optional) Basic block specific use-list order directives.
@@ -54,22 +87,114 @@ type Module struct {
 
 // Def returns the LLVM syntax representation of the module.
 func (m *Module) Def() string {
+	return m.def(Printer{})
+}
+
+// def returns the LLVM syntax representation of the module, with the given
+// printing options.
+func (m *Module) def(p Printer) string {
 	buf := &strings.Builder{}
+	writeHeader(buf, m)
 	// Type definitions.
 	for _, t := range m.TypeDefs {
 		// LocalIdent "=" "type" OpaqueType
 		// LocalIdent "=" "type" Type
 		fmt.Fprintf(buf, "%s = type %s\n", t, t.Def())
 	}
-	// TODO: implement Module.Def.
+	// Global variable declarations and definitions.
+	for _, g := range m.Globals {
+		writeComments(buf, "", g.Comments)
+		fmt.Fprintln(buf, g.Def())
+	}
+	// Raw global entities.
+	for _, g := range m.RawGlobals {
+		writeComments(buf, "", g.Comments)
+		fmt.Fprintln(buf, g.Def())
+	}
 	// Function declarations and definitions.
 	for _, f := range m.Funcs {
-		fmt.Fprintln(buf, f.Def())
+		writeComments(buf, "", f.Comments)
+		fmt.Fprintln(buf, f.def(p))
+	}
+	// Attribute group definitions.
+	for _, a := range m.AttrGroupDefs {
+		fmt.Fprintln(buf, a.Def())
+	}
+	// Named metadata definitions.
+	for _, md := range m.NamedMetadataDefs {
+		fmt.Fprintln(buf, md.Def())
+	}
+	// Metadata definitions.
+	for _, md := range m.MetadataDefs {
+		// MetadataID "=" OptDistinct MDTuple
+		// MetadataID "=" OptDistinct SpecializedMDNode
+		fmt.Fprintf(buf, "%s = %s\n", md, md.Def())
 	}
 	// TODO: implement Module.Def.
 	return buf.String()
 }
 
+// Lookup returns the function or global variable with the given global name
+// (without '@' prefix), as registered by the module builder methods. The
+// boolean return value indicates success.
+func (m *Module) Lookup(name string) (value.Named, bool) {
+	v, ok := m.symbols[name]
+	return v, ok
+}
+
+// Rename renames the given global variable or function of the module to the
+// given global name (without '@' prefix), and updates the symbol table of the
+// module. Uses of the value are unaffected, as operands refer to values rather
+// than names. An empty name leaves the value unnamed and unregistered. An error
+// is returned if the new name is already registered to another value.
+func (m *Module) Rename(v value.Named, name string) error {
+	if prev, ok := m.symbols[name]; ok && prev != v {
+		return errors.Errorf("global identifier %q already present; prev %v", enc.Global(name), prev)
+	}
+	old := v.Name()
+	if m.symbols[old] == v {
+		delete(m.symbols, old)
+	}
+	v.SetName(name)
+	m.register(v)
+	m.notifyRename(v, old)
+	return nil
+}
+
+// register adds the given named global value to the symbol table of the
+// module. Unnamed values are not registered.
+func (m *Module) register(v value.Named) {
+	name := v.Name()
+	if isUnnamed(name) {
+		return
+	}
+	if m.symbols == nil {
+		m.symbols = make(map[string]value.Named)
+	}
+	if prev, ok := m.symbols[name]; ok {
+		e := errorf("global identifier %q already present; prev %v, new %v", enc.Global(name), prev, v)
+		e.Global = enc.Global(name)
+		m.fail(e)
+		return
+	}
+	m.symbols[name] = v
+}
+
+// Errors returns the errors of the module builder methods collected if
+// CollectErrors is set, as an ErrorList; or nil if none.
+func (m *Module) Errors() error {
+	return m.errs.Err()
+}
+
+// fail reports the given error of a module builder method; collected if
+// CollectErrors is set, and panicked otherwise.
+func (m *Module) fail(e *Error) {
+	if !m.CollectErrors {
+		panic(e)
+	}
+	m.errs = append(m.errs, e)
+}
+
 // ~~~ [ Comdat Definition ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // ComdatDef is a comdat definition top-level entity.
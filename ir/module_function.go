@@ -4,10 +4,40 @@ import "github.com/llir/l/ir/types"
 
 // --- [ Functions ] -----------------------------------------------------------
 
-// NewFunction appends a new function to the module based on the given function
-// name, return type and function parameters.
-func (m *Module) NewFunction(name string, retType types.Type, params ...*Param) *Function {
-	f := NewFunction(name, retType, params...)
-	m.Funcs = append(m.Funcs, f)
+// NewFunc appends a new function to the module based on the given function
+// name, return type and function parameters. The function is registered in the
+// symbol table of the module.
+func (m *Module) NewFunc(name string, retType types.Type, params ...*Param) *Function {
+	f := NewFunc(name, retType, params...)
+	m.AddFunc(f)
 	return f
 }
+
+// AddFunc appends the given function to the module, and registers it in the
+// symbol table of the module.
+func (m *Module) AddFunc(f *Function) {
+	f.GlobalName = m.internName(f.GlobalName)
+	for _, param := range f.Params {
+		param.LocalName = m.internName(param.LocalName)
+	}
+	m.register(f)
+	f.parent = m
+	m.Funcs = append(m.Funcs, f)
+	m.notifyInsert(m, f)
+}
+
+// RemoveFunc removes the given function from the module, and from the symbol
+// table of the module. Uses of the function are unaffected.
+func (m *Module) RemoveFunc(f *Function) {
+	if m.symbols[f.GlobalName] == f {
+		delete(m.symbols, f.GlobalName)
+	}
+	for i, x := range m.Funcs {
+		if x == f {
+			m.Funcs = append(m.Funcs[:i:i], m.Funcs[i+1:]...)
+			f.parent = nil
+			m.notifyRemove(m, f)
+			return
+		}
+	}
+}
@@ -5,17 +5,62 @@ import "github.com/llir/l/ir/types"
 // --- [ Global variables ] ----------------------------------------------------
 
 // NewGlobalDecl appends a new global variable declaration to the module based
-// on the given global variable name and content type.
+// on the given global variable name and content type. The global variable is
+// registered in the symbol table of the module.
 func (m *Module) NewGlobalDecl(name string, contentType types.Type) *Global {
-	g := NewGlobalDecl(name, contentType)
+	g := NewGlobalDecl(m.internName(name), contentType)
+	m.register(g)
 	m.Globals = append(m.Globals, g)
+	m.notifyInsert(m, g)
 	return g
 }
 
 // NewGlobalDef appends a new global variable definition to the module based on
-// the given global variable name and initial value.
+// the given global variable name and initial value. The global variable is
+// registered in the symbol table of the module.
 func (m *Module) NewGlobalDef(name string, init Constant) *Global {
-	g := NewGlobalDef(name, init)
+	g := NewGlobalDef(m.internName(name), init)
+	m.register(g)
 	m.Globals = append(m.Globals, g)
+	m.notifyInsert(m, g)
+	return g
+}
+
+// NewGlobalLazy appends a new global variable definition to the module based on
+// the given global variable name, content type and lazy initializer. The
+// global variable is registered in the symbol table of the module.
+func (m *Module) NewGlobalLazy(name string, contentType types.Type, init func() Constant) *Global {
+	g := NewGlobalLazy(m.internName(name), contentType, init)
+	m.register(g)
+	m.Globals = append(m.Globals, g)
+	m.notifyInsert(m, g)
+	return g
+}
+
+// RemoveGlobal removes the given global variable from the module, and from the
+// symbol table of the module. Uses of the global variable are unaffected.
+func (m *Module) RemoveGlobal(g *Global) {
+	if m.symbols[g.GlobalName] == g {
+		delete(m.symbols, g.GlobalName)
+	}
+	for i, x := range m.Globals {
+		if x == g {
+			m.Globals = append(m.Globals[:i:i], m.Globals[i+1:]...)
+			m.notifyRemove(m, g)
+			return
+		}
+	}
+}
+
+// --- [ Raw global entities ]  -------------------------------------------------
+
+// NewRawGlobal appends a new raw global entity to the module based on the given
+// global name, operand type and LLVM syntax representation. Raw global entities
+// with a global name are registered in the symbol table of the module.
+func (m *Module) NewRawGlobal(name string, typ types.Type, text string) *RawGlobal {
+	g := NewRawGlobal(name, typ, text)
+	m.register(g)
+	m.RawGlobals = append(m.RawGlobals, g)
+	m.notifyInsert(m, g)
 	return g
 }
@@ -0,0 +1,628 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/llir/l/internal/enc"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// --- [ Shards ] --------------------------------------------------------------
+
+// NewShard returns a new module to be merged into the module by Merge; e.g. to
+// build independent functions on separate goroutines, one shard per goroutine.
+// The shard has the source filename, data layout and target triple of the
+// module. If the module has a context, the shard has a new context of its own,
+// as contexts are not safe for concurrent use.
+//
+// Shards may refer to global variables and functions of the module and of
+// other shards by Ref, and to named types by TypeRef; the placeholder
+// references are resolved by Finish of the module after merging.
+func (m *Module) NewShard() *Module {
+	shard := &Module{
+		SourceFilename: m.SourceFilename,
+		DataLayout:     m.DataLayout,
+		TargetTriple:   m.TargetTriple,
+		CollectErrors:  m.CollectErrors,
+	}
+	if m.ctx != nil {
+		shard.ctx = NewContext()
+	}
+	return shard
+}
+
+// Merge moves the contents of the given shards (e.g. as returned by NewShard)
+// into the module, in order. The shards must not be used after merging.
+//
+// Symbol tables are unified by global name. A declaration and a definition of
+// the same global variable or function are unified into the definition, with
+// uses of the declaration replaced. Global variables and functions of private
+// or internal linkage are renamed on name collisions (e.g. "@.str" to
+// "@.str.1"). Duplicate definitions of global variables and functions of
+// other linkage are reported as errors.
+//
+// Type definitions are unified by type name; an opaque placeholder struct type
+// (e.g. as returned by TypeRef) is filled in with the body of the definition.
+// Pooled string literals (as returned by InternString) and uniqued constants
+// of contexts are unified by contents, attribute group definitions are unified
+// by attributes, metadata IDs of the shards are renumbered, and named metadata
+// definitions are concatenated by name; with identical module flags unified.
+//
+// The shards are checked for conflicts (e.g. duplicate definitions and
+// mismatching type definitions) before any is merged; on error, the module and
+// the shards are left unchanged.
+func (m *Module) Merge(shards ...*Module) error {
+	if err := m.checkMerge(shards); err != nil {
+		return errors.WithStack(err)
+	}
+	mg := &merger{
+		m:     m,
+		remap: make(map[value.Value]value.Value),
+		attrs: make(map[string]*AttrGroupDef),
+	}
+	for _, a := range m.AttrGroupDefs {
+		key := attrKey(a)
+		if _, ok := mg.attrs[key]; !ok {
+			mg.attrs[key] = a
+		}
+	}
+	for _, shard := range shards {
+		if err := mg.merge(shard); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	mg.finish()
+	return nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// checkMerge reports whether the given shards may be merged into the module;
+// i.e. whether the data layouts, target triples, type definitions and global
+// values of the shards and the module agree, as unified by Merge.
+func (m *Module) checkMerge(shards []*Module) error {
+	dataLayout, targetTriple := m.DataLayout, m.TargetTriple
+	// Type definitions; type name -> type.
+	defs := make(map[string]types.Type)
+	// Placeholder struct types not yet filled in; type name -> placeholder.
+	placeholders := make(map[string]bool)
+	for _, t := range m.TypeDefs {
+		defs[t.String()] = t
+		if st, ok := t.(*types.StructType); ok && m.typeRefs[st.Alias] == st {
+			placeholders[t.String()] = true
+		}
+	}
+	// Symbol table of global values; global name -> value.
+	symbols := make(map[string]value.Named)
+	for name, v := range m.symbols {
+		symbols[name] = v
+	}
+	for _, shard := range shards {
+		if len(shard.DataLayout) > 0 && len(dataLayout) > 0 && shard.DataLayout != dataLayout {
+			return errors.Errorf("data layout mismatch of shard; expected %q, got %q", dataLayout, shard.DataLayout)
+		}
+		if len(shard.TargetTriple) > 0 && len(targetTriple) > 0 && shard.TargetTriple != targetTriple {
+			return errors.Errorf("target triple mismatch of shard; expected %q, got %q", targetTriple, shard.TargetTriple)
+		}
+		if len(dataLayout) == 0 {
+			dataLayout = shard.DataLayout
+		}
+		if len(targetTriple) == 0 {
+			targetTriple = shard.TargetTriple
+		}
+		for _, t := range shard.TypeDefs {
+			name := t.String()
+			st, ok := t.(*types.StructType)
+			placeholder := ok && shard.typeRefs[st.Alias] == st
+			prev, ok := defs[name]
+			switch {
+			case !ok:
+				defs[name] = t
+				placeholders[name] = placeholder
+			case prev == t, placeholder:
+				// Placeholder of the shard filled in by the definition.
+			case placeholders[name]:
+				// Placeholder filled in by the definition of the shard.
+				defs[name] = t
+				placeholders[name] = false
+			case prev.Def() != t.Def():
+				return errors.Errorf("type definition mismatch of %v; expected %v, got %v", t, prev.Def(), t.Def())
+			}
+		}
+		var vs []value.Named
+		for _, g := range shard.Globals {
+			vs = append(vs, g)
+		}
+		for _, g := range shard.RawGlobals {
+			vs = append(vs, g)
+		}
+		for _, f := range shard.Funcs {
+			vs = append(vs, f)
+		}
+		for _, v := range vs {
+			name := v.Name()
+			if isUnnamed(name) {
+				continue
+			}
+			action, err := unifySymbol(symbols[name], v)
+			if err != nil {
+				return err
+			}
+			switch action {
+			case unifyAdd, unifyRenameModule, unifyReplaceModule:
+				symbols[name] = v
+			}
+		}
+	}
+	return nil
+}
+
+// unifyAction is the action of unifying a global value of a shard with the
+// global value of the same name in the module.
+type unifyAction uint8
+
+// Unify actions.
+const (
+	// Add the global value of the shard.
+	unifyAdd unifyAction = iota
+	// Rename the global value of the shard, and add it.
+	unifyRenameShard
+	// Rename the global value of the module, and add the global value of the
+	// shard.
+	unifyRenameModule
+	// Keep the global value of the module, and replace uses of the declaration
+	// of the shard.
+	unifyKeepModule
+	// Replace the declaration of the module with the definition of the shard.
+	unifyReplaceModule
+)
+
+// unifySymbol returns the action of unifying the given global value v of a
+// shard with the global value prev of the same name in the module (or nil if
+// not present).
+func unifySymbol(prev, v value.Named) (unifyAction, error) {
+	switch {
+	case prev == nil:
+		return unifyAdd, nil
+	case isLocalLinkage(v):
+		return unifyRenameShard, nil
+	case isLocalLinkage(prev):
+		return unifyRenameModule, nil
+	case !prev.Type().Equal(v.Type()):
+		return 0, errors.Errorf("type mismatch of %v; expected %v, got %v", enc.Global(v.Name()), prev.Type(), v.Type())
+	case isDecl(v):
+		return unifyKeepModule, nil
+	case isDecl(prev):
+		return unifyReplaceModule, nil
+	}
+	return 0, errors.Errorf("duplicate definition of %v", enc.Global(v.Name()))
+}
+
+// merger tracks the state of merging shards into a module.
+type merger struct {
+	// Module merged into.
+	m *Module
+	// Replaced values; old value -> new value.
+	remap map[value.Value]value.Value
+	// Global variables and functions replaced by their definitions.
+	removed map[value.Named]bool
+	// Unified attribute group definitions; attribute key -> definition.
+	attrs map[string]*AttrGroupDef
+	// Replaced attribute group definitions; old -> new.
+	attrRemap map[*AttrGroupDef]*AttrGroupDef
+	// Metadata definitions dropped by unification.
+	droppedMD map[MDNode]bool
+}
+
+// merge moves the contents of the given shard into the module.
+func (mg *merger) merge(shard *Module) error {
+	m := mg.m
+	// Data layouts and target triples agree, as checked by checkMerge.
+	if len(m.DataLayout) == 0 {
+		m.DataLayout = shard.DataLayout
+	}
+	if len(m.TargetTriple) == 0 {
+		m.TargetTriple = shard.TargetTriple
+	}
+	if err := mg.mergeTypeDefs(shard); err != nil {
+		return err
+	}
+	mg.mergeConsts(shard)
+	for _, g := range shard.Globals {
+		if err := mg.mergeSymbol(g); err != nil {
+			return err
+		}
+	}
+	for _, g := range shard.RawGlobals {
+		if err := mg.mergeSymbol(g); err != nil {
+			return err
+		}
+	}
+	for _, f := range shard.Funcs {
+		if err := mg.mergeSymbol(f); err != nil {
+			return err
+		}
+	}
+	mg.mergeRefs(shard)
+	mg.mergeAttrGroupDefs(shard)
+	mg.mergeMetadata(shard)
+	m.errs = append(m.errs, shard.errs...)
+	return nil
+}
+
+// mergeTypeDefs unifies the type definitions of the shard with those of the
+// module, by type name.
+func (mg *merger) mergeTypeDefs(shard *Module) error {
+	m := mg.m
+	// The string representation of named types is their local identifier.
+	defs := make(map[string]types.Type)
+	for _, t := range m.TypeDefs {
+		defs[t.String()] = t
+	}
+	for _, t := range shard.TypeDefs {
+		prev, ok := defs[t.String()]
+		if !ok {
+			defs[t.String()] = t
+			m.TypeDefs = append(m.TypeDefs, t)
+			if st, ok := t.(*types.StructType); ok && shard.typeRefs[st.Alias] == st {
+				// Unresolved placeholder of the shard.
+				if m.typeRefs == nil {
+					m.typeRefs = make(map[string]*types.StructType)
+				}
+				m.typeRefs[st.Alias] = st
+			}
+			continue
+		}
+		if prev == t {
+			continue
+		}
+		pst, ok1 := prev.(*types.StructType)
+		st, ok2 := t.(*types.StructType)
+		switch {
+		case ok1 && ok2 && shard.typeRefs[st.Alias] == st:
+			// Fill in the placeholder of the shard, as used by the shard.
+			fillStruct(st, pst)
+		case ok1 && ok2 && m.typeRefs[pst.Alias] == pst:
+			// Fill in the placeholder of the module.
+			fillStruct(pst, st)
+			delete(m.typeRefs, pst.Alias)
+		case prev.Def() != t.Def():
+			return errors.Errorf("type definition mismatch of %v; expected %v, got %v", t, prev.Def(), t.Def())
+		}
+	}
+	return nil
+}
+
+// mergeConsts unifies the pooled string literals and uniqued constants of the
+// shard with those of the module.
+func (mg *merger) mergeConsts(shard *Module) {
+	m := mg.m
+	for contents, g := range shard.strs {
+		if shard.symbols[g.GlobalName] != g {
+			continue
+		}
+		if prev, ok := m.strs[contents]; ok && m.symbols[prev.GlobalName] == prev {
+			// Drop the string literal of the shard; uses are replaced.
+			mg.replace(g, prev)
+			continue
+		}
+		if m.strs == nil {
+			m.strs = make(map[string]*Global)
+		}
+		m.strs[contents] = g
+	}
+	if m.ctx == nil || shard.ctx == nil || m.ctx == shard.ctx {
+		return
+	}
+	for key, c := range shard.ctx.consts {
+		if prev, ok := m.ctx.consts[key]; ok {
+			mg.remap[c] = prev
+			continue
+		}
+		m.ctx.consts[key] = c
+	}
+	for key, t := range shard.ctx.types {
+		if _, ok := m.ctx.types[key]; !ok {
+			m.ctx.types[key] = t
+		}
+	}
+}
+
+// mergeSymbol merges the given global variable, raw global entity or function
+// of a shard into the module, unifying it with the global value of the same
+// name in the module.
+func (mg *merger) mergeSymbol(v value.Named) error {
+	m := mg.m
+	if mg.isReplaced(v) {
+		// Dropped string literal.
+		return nil
+	}
+	name := v.Name()
+	if isUnnamed(name) {
+		mg.add(v)
+		return nil
+	}
+	prev := m.symbols[name]
+	action, err := unifySymbol(prev, v)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case unifyAdd:
+		mg.add(v)
+	case unifyRenameShard:
+		v.SetName(m.uniqueName(name))
+		mg.add(v)
+	case unifyRenameModule:
+		if err := m.Rename(prev, m.uniqueName(name)); err != nil {
+			return err
+		}
+		mg.add(v)
+	case unifyKeepModule:
+		mg.replace(v, prev)
+	case unifyReplaceModule:
+		delete(m.symbols, name)
+		mg.replace(prev, v)
+		if mg.removed == nil {
+			mg.removed = make(map[value.Named]bool)
+		}
+		mg.removed[prev] = true
+		mg.add(v)
+	}
+	return nil
+}
+
+// add appends the given global value of a shard to the module.
+func (mg *merger) add(v value.Named) {
+	m := mg.m
+	switch v := v.(type) {
+	case *Global:
+		m.register(v)
+		m.Globals = append(m.Globals, v)
+		m.notifyInsert(m, v)
+	case *RawGlobal:
+		m.register(v)
+		m.RawGlobals = append(m.RawGlobals, v)
+		m.notifyInsert(m, v)
+	case *Function:
+		m.AddFunc(v)
+		for _, block := range v.Blocks {
+			block.parent = v
+		}
+	}
+}
+
+// mergeRefs unifies the placeholder references of the shard with the global
+// values and placeholder references of the module, by global name.
+func (mg *merger) mergeRefs(shard *Module) {
+	m := mg.m
+	for name, r := range shard.refs {
+		if v, ok := m.symbols[name]; ok && v.Type().Equal(r.Typ) {
+			mg.replace(r, v)
+			continue
+		}
+		if prev, ok := m.refs[name]; ok {
+			mg.replace(r, prev)
+			continue
+		}
+		if m.refs == nil {
+			m.refs = make(map[string]*GlobalRef)
+		}
+		m.refs[name] = r
+	}
+}
+
+// mergeAttrGroupDefs unifies the attribute group definitions of the shard with
+// those of the module, by attributes. Attribute group IDs of the shard are
+// renumbered after those of the module.
+func (mg *merger) mergeAttrGroupDefs(shard *Module) {
+	m := mg.m
+	for _, a := range shard.AttrGroupDefs {
+		key := attrKey(a)
+		if prev, ok := mg.attrs[key]; ok {
+			if mg.attrRemap == nil {
+				mg.attrRemap = make(map[*AttrGroupDef]*AttrGroupDef)
+			}
+			mg.attrRemap[a] = prev
+			continue
+		}
+		id := int64(0)
+		for _, b := range m.AttrGroupDefs {
+			if b.ID >= id {
+				id = b.ID + 1
+			}
+		}
+		a.ID = id
+		mg.attrs[key] = a
+		m.AttrGroupDefs = append(m.AttrGroupDefs, a)
+	}
+}
+
+// mergeMetadata renumbers the metadata definitions of the shard after those of
+// the module, and concatenates the named metadata definitions of the shard
+// with those of the module by name.
+func (mg *merger) mergeMetadata(shard *Module) {
+	m := mg.m
+	offset := int64(0)
+	for _, md := range m.MetadataDefs {
+		if md.ID() >= offset {
+			offset = md.ID() + 1
+		}
+	}
+	if m.ctx != nil && m.ctx.mdID > offset {
+		offset = m.ctx.mdID
+	}
+	max := offset - 1
+	for _, md := range shard.MetadataDefs {
+		if md.ID() < 0 {
+			continue
+		}
+		md.SetID(md.ID() + offset)
+		if md.ID() > max {
+			max = md.ID()
+		}
+		m.MetadataDefs = append(m.MetadataDefs, md)
+	}
+	if m.ctx != nil && max >= offset {
+		// Reserve the metadata IDs of the shard.
+		m.ctx.nextMetadataID(max)
+	}
+	for _, named := range shard.NamedMetadataDefs {
+		target := m.NamedMetadata(named.Name)
+		for _, node := range named.Nodes {
+			if named.Name == "llvm.module.flags" && hasNode(target.Nodes, node) {
+				if mg.droppedMD == nil {
+					mg.droppedMD = make(map[MDNode]bool)
+				}
+				mg.droppedMD[node] = true
+				continue
+			}
+			target.Nodes = append(target.Nodes, node)
+		}
+	}
+}
+
+// finish replaces uses of unified values and attribute group definitions in
+// the module, and removes unified global values and metadata definitions.
+func (mg *merger) finish() {
+	m := mg.m
+	if len(mg.remap) > 0 {
+		// Resolve chains of replacements (e.g. declaration -> declaration ->
+		// definition).
+		for old, new := range mg.remap {
+			for {
+				next, ok := mg.remap[new]
+				if !ok || next == new {
+					break
+				}
+				new = next
+			}
+			mg.remap[old] = new
+		}
+		replaceUses(m, mg.remap)
+	}
+	if len(mg.removed) > 0 {
+		globals := m.Globals[:0]
+		for _, g := range m.Globals {
+			if !mg.removed[g] {
+				globals = append(globals, g)
+			}
+		}
+		m.Globals = globals
+		var funcs []*Function
+		for _, f := range m.Funcs {
+			if mg.removed[f] {
+				f.parent = nil
+				m.notifyRemove(m, f)
+				continue
+			}
+			funcs = append(funcs, f)
+		}
+		m.Funcs = funcs
+	}
+	if len(mg.attrRemap) > 0 {
+		Walk(m, func(n interface{}) bool {
+			switch n := n.(type) {
+			case *Function:
+				mg.remapAttrs(n.FuncAttrs)
+			case *Global:
+				mg.remapAttrs(n.FuncAttrs)
+			case *InstCall:
+				mg.remapAttrs(n.FuncAttrs)
+			case *TermInvoke:
+				mg.remapAttrs(n.FuncAttrs)
+			}
+			return true
+		})
+	}
+	if len(mg.droppedMD) > 0 {
+		var defs []MDNode
+		for _, md := range m.MetadataDefs {
+			if !mg.droppedMD[md] {
+				defs = append(defs, md)
+			}
+		}
+		m.MetadataDefs = defs
+	}
+}
+
+// replace records the replacement of uses of old with new.
+func (mg *merger) replace(old, new value.Value) {
+	mg.remap[old] = new
+}
+
+// isReplaced reports whether uses of the given value are replaced.
+func (mg *merger) isReplaced(v value.Value) bool {
+	_, ok := mg.remap[v]
+	return ok
+}
+
+// remapAttrs replaces unified attribute group definitions in the given
+// function attributes.
+func (mg *merger) remapAttrs(attrs []FuncAttribute) {
+	for i, attr := range attrs {
+		if a, ok := attr.(*AttrGroupDef); ok {
+			if new, ok := mg.attrRemap[a]; ok {
+				attrs[i] = new
+			}
+		}
+	}
+}
+
+// attrKey returns the key of the attributes of the given attribute group
+// definition.
+func attrKey(a *AttrGroupDef) string {
+	var keys []string
+	for _, attr := range a.FuncAttrs {
+		keys = append(keys, attr.String())
+	}
+	return strings.Join(keys, " ")
+}
+
+// fillStruct fills in the given opaque placeholder struct type with the body of
+// the given struct type.
+func fillStruct(ref, t *types.StructType) {
+	ref.Packed = t.Packed
+	ref.Fields = t.Fields
+	ref.Opaque = t.Opaque
+}
+
+// isLocalLinkage reports whether the given global value has private or
+// internal linkage.
+func isLocalLinkage(v value.Named) bool {
+	var linkage enum.Linkage
+	switch v := v.(type) {
+	case *Global:
+		linkage = v.Linkage
+	case *Function:
+		linkage = v.Linkage
+	default:
+		return false
+	}
+	return linkage == enum.LinkagePrivate || linkage == enum.LinkageInternal
+}
+
+// isDecl reports whether the given global value is a declaration.
+func isDecl(v value.Named) bool {
+	switch v := v.(type) {
+	case *Global:
+		return v.Init == nil && v.LazyInit == nil
+	case *Function:
+		return v.IsDeclaration()
+	}
+	return false
+}
+
+// hasNode reports whether a metadata node identical in contents to the given
+// node is present in nodes.
+func hasNode(nodes []MDNode, node MDNode) bool {
+	for _, n := range nodes {
+		if n == node || n.Def() == node.Def() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package ir
+
+// --- [ Metadata definitions ] ------------------------------------------------
+
+// NewMetadataDef appends the given metadata node to the metadata definitions of
+// the module, and assigns it the next free metadata ID; of the context of the
+// module if present, so that metadata IDs are unique across the modules of the
+// context.
+func (m *Module) NewMetadataDef(md MDNode) {
+	id := int64(0)
+	for _, def := range m.MetadataDefs {
+		if def.ID() >= id {
+			id = def.ID() + 1
+		}
+	}
+	if m.ctx != nil {
+		id = m.ctx.nextMetadataID(id)
+	}
+	md.SetID(id)
+	if m.internTable() != nil {
+		m.newMDInterner().intern(md)
+	}
+	m.MetadataDefs = append(m.MetadataDefs, md)
+}
+
+// NamedMetadata returns the named metadata definition with the given metadata
+// name (without '!' prefix) of the module. If not yet present, an empty named
+// metadata definition is appended to the module.
+func (m *Module) NamedMetadata(name string) *NamedMetadataDef {
+	for _, md := range m.NamedMetadataDefs {
+		if md.Name == name {
+			return md
+		}
+	}
+	md := NewNamedMetadataDef(m.internName(name))
+	m.NamedMetadataDefs = append(m.NamedMetadataDefs, md)
+	return md
+}
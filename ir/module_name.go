@@ -0,0 +1,179 @@
+package ir
+
+import (
+	"strconv"
+
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+// --- [ Name interning ] ------------------------------------------------------
+
+// Intern returns the canonical copy of the given string in the name table of
+// the context, so that identical names (e.g. "tmp" and "idx" of millions of
+// temporaries) of the modules of the context share storage. Once the name
+// table is created, names of the modules of the context are interned by their
+// builder methods.
+func (ctx *Context) Intern(s string) string {
+	if ctx.names == nil {
+		ctx.names = make(map[string]string)
+	}
+	return intern(ctx.names, s)
+}
+
+// Intern returns the canonical copy of the given string in the name table of
+// the module; or in the name table of its context, if the module has a
+// context. Once the name table is created, names of the module are interned by
+// its builder methods.
+func (m *Module) Intern(s string) string {
+	if m.ctx != nil {
+		return m.ctx.Intern(s)
+	}
+	if m.names == nil {
+		m.names = make(map[string]string)
+	}
+	return intern(m.names, s)
+}
+
+// InternNames interns the names of the module, so that identical names share
+// storage. The global and local names of global variables, functions,
+// parameters, basic blocks and instructions, the names of named struct types
+// and named metadata, and the string fields of metadata nodes (e.g. metadata
+// strings and file names of debug information) are interned by Intern.
+//
+// InternNames is intended to be invoked before or after constructing large
+// modules (e.g. by translation of input with many identically named
+// temporaries); subsequently, names are interned as they are created or set by
+// the builder methods of the module (e.g. NewBlock, NewMetadataDef and SetName
+// of basic blocks and instructions). The interned names are retained for the
+// lifetime of the module or context.
+func (m *Module) InternNames() {
+	// Create the name table, to enable interning by builder methods.
+	m.Intern("")
+	for _, t := range m.TypeDefs {
+		if st, ok := t.(*types.StructType); ok {
+			st.Alias = m.Intern(st.Alias)
+		}
+	}
+	mi := m.newMDInterner()
+	Walk(m, func(n interface{}) bool {
+		switch n := n.(type) {
+		case value.Named:
+			if name := n.Name(); !isUnnamed(name) {
+				n.SetName(m.Intern(name))
+			}
+		case MetadataAttachment:
+			mi.intern(n.Node)
+		case Metadata:
+			mi.intern(n)
+		}
+		return true
+	})
+	for _, md := range m.MetadataDefs {
+		mi.intern(md)
+	}
+	for _, named := range m.NamedMetadataDefs {
+		named.Name = m.Intern(named.Name)
+		for _, node := range named.Nodes {
+			mi.intern(node)
+		}
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// internTable returns the name table of the module; or of its context, if the
+// module has a context. A nil table indicates that names of the module are not
+// interned.
+func (m *Module) internTable() map[string]string {
+	if m == nil {
+		return nil
+	}
+	if m.ctx != nil {
+		return m.ctx.names
+	}
+	return m.names
+}
+
+// internName returns the canonical copy of the given name in the name table of
+// the module, if present; as used by the builder methods to intern names once
+// interning has been enabled by Intern or InternNames.
+func (m *Module) internName(name string) string {
+	if table := m.internTable(); table != nil {
+		return intern(table, name)
+	}
+	return name
+}
+
+// intern returns the canonical copy of the given string in the given table.
+func intern(table map[string]string, s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	if t, ok := table[s]; ok {
+		return t
+	}
+	table[s] = s
+	return s
+}
+
+// localIDs is the table of common local IDs, shared by the functions of all
+// modules (e.g. "0", "1", "2").
+var localIDs = func() []string {
+	ids := make([]string, 1024)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i)
+	}
+	return ids
+}()
+
+// localID returns the local name of the given local ID; shared for common
+// local IDs.
+func localID(id int) string {
+	if id < len(localIDs) {
+		return localIDs[id]
+	}
+	return strconv.Itoa(id)
+}
+
+// mdInterner interns the string fields of metadata nodes.
+type mdInterner struct {
+	m *Module
+	// Visited metadata nodes.
+	seen map[Metadata]bool
+}
+
+// newMDInterner returns a new interner of the string fields of metadata nodes,
+// using the name table of the module.
+func (m *Module) newMDInterner() *mdInterner {
+	return &mdInterner{m: m, seen: make(map[Metadata]bool)}
+}
+
+// intern interns the string fields of the given metadata node and of the
+// metadata nodes reachable from it. Values used by metadata (e.g. constants of
+// metadata values) are interned at their definition.
+func (mi *mdInterner) intern(md Metadata) {
+	if md == nil || mi.seen[md] {
+		return
+	}
+	mi.seen[md] = true
+	switch md := md.(type) {
+	case *MDTuple:
+		for _, field := range md.Fields {
+			mi.intern(field)
+		}
+	case *MDSpecialized:
+		md.Kind = mi.m.Intern(md.Kind)
+		for _, field := range md.Fields {
+			field.Name = mi.m.Intern(field.Name)
+			switch v := field.Value.(type) {
+			case string:
+				field.Value = mi.m.Intern(v)
+			case Metadata:
+				mi.intern(v)
+			}
+		}
+	case *MDString:
+		md.Value = mi.m.Intern(md.Value)
+	}
+}
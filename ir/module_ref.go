@@ -0,0 +1,185 @@
+package ir
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/llir/l/internal/enc"
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// --- [ Forward references ] --------------------------------------------------
+
+// GlobalRef is a placeholder reference to a global variable or function which
+// is not yet present in the module, as returned by Module.Ref. Uses of the
+// placeholder are replaced with the global variable or function by
+// Module.Finish.
+type GlobalRef struct {
+	// Global name of the referenced global variable or function (without '@'
+	// prefix).
+	GlobalName string
+	// Pointer type of the referenced global variable or function.
+	Typ types.Type
+}
+
+// String returns the LLVM syntax representation of the placeholder reference
+// as a type-value pair.
+func (r *GlobalRef) String() string {
+	return fmt.Sprintf("%v %v", r.Type(), r.Ident())
+}
+
+// Type returns the type of the placeholder reference.
+func (r *GlobalRef) Type() types.Type {
+	return r.Typ
+}
+
+// Ident returns the identifier associated with the placeholder reference.
+func (r *GlobalRef) Ident() string {
+	return enc.Global(r.GlobalName)
+}
+
+// Name returns the name of the placeholder reference.
+func (r *GlobalRef) Name() string {
+	return r.GlobalName
+}
+
+// SetName sets the name of the placeholder reference.
+func (r *GlobalRef) SetName(name string) {
+	r.GlobalName = name
+}
+
+// isConstant ensures that only constants can be assigned to the ir.Constant
+// interface.
+func (*GlobalRef) isConstant() {}
+
+// Ref returns a reference to the global variable or function with the given
+// global name (without '@' prefix) and pointer type. If not yet present in the
+// module, a placeholder reference is returned, which may be used as operand
+// until resolved by Finish. Repeated references to the same global name share
+// a placeholder.
+func (m *Module) Ref(name string, typ types.Type) Constant {
+	if v, ok := m.Lookup(name); ok {
+		if !v.Type().Equal(typ) {
+			e := errorf("type mismatch of reference to %v; expected %v, got %v", v.Ident(), v.Type(), typ)
+			e.Global = v.Ident()
+			m.fail(e)
+		}
+		return v.(Constant)
+	}
+	if r, ok := m.refs[name]; ok {
+		if !r.Typ.Equal(typ) {
+			e := errorf("type mismatch of reference to %v; expected %v, got %v", r.Ident(), r.Typ, typ)
+			e.Global = r.Ident()
+			m.fail(e)
+		}
+		return r
+	}
+	if m.refs == nil {
+		m.refs = make(map[string]*GlobalRef)
+	}
+	r := &GlobalRef{GlobalName: name, Typ: typ}
+	m.refs[name] = r
+	return r
+}
+
+// TypeRef returns the named struct type with the given name (without '%'
+// prefix) of the type definitions of the module. If not yet present, an opaque
+// placeholder struct type is appended to the type definitions, the body of
+// which is filled in by a later call to NewTypeDef with the same name.
+func (m *Module) TypeRef(name string) *types.StructType {
+	for _, t := range m.TypeDefs {
+		// The string representation of named types is their local identifier.
+		if t.String() == enc.Local(name) {
+			st, ok := t.(*types.StructType)
+			if !ok {
+				m.fail(errorf("invalid type of reference to %v; expected *types.StructType, got %T", enc.Local(name), t))
+				// Opaque placeholder, not added to the module.
+				return &types.StructType{Alias: name, Opaque: true}
+			}
+			return st
+		}
+	}
+	t := &types.StructType{Alias: name, Opaque: true}
+	if m.typeRefs == nil {
+		m.typeRefs = make(map[string]*types.StructType)
+	}
+	m.typeRefs[name] = t
+	m.TypeDefs = append(m.TypeDefs, t)
+	return t
+}
+
+// NewTypeDef appends a new type definition to the module based on the given
+// type name (without '%' prefix) and type, and returns the named type. If the
+// type name was referenced before by TypeRef, the placeholder struct type is
+// updated in place with the body of the given struct type, and returned.
+// Shared convenience types (e.g. types.I32) are copied before being named.
+func (m *Module) NewTypeDef(name string, t types.Type) types.Type {
+	if ref, ok := m.typeRefs[name]; ok {
+		st, ok := t.(*types.StructType)
+		if !ok {
+			m.fail(errorf("invalid type definition of %v; expected *types.StructType, got %T", enc.Local(name), t))
+			return ref
+		}
+		ref.Packed = st.Packed
+		ref.Fields = st.Fields
+		ref.Opaque = st.Opaque
+		delete(m.typeRefs, name)
+		return ref
+	}
+	if types.IsShared(t) {
+		t = types.Copy(t)
+	}
+	t.SetAlias(name)
+	m.TypeDefs = append(m.TypeDefs, t)
+	return t
+}
+
+// Finish resolves the placeholder references of the module, as returned by Ref
+// and TypeRef. Uses of placeholder references are replaced with the global
+// variable or function of the same name. An *UnresolvedError is returned if
+// global variables, functions or type definitions are still missing.
+func (m *Module) Finish() error {
+	var unresolved []string
+	names := make([]string, 0, len(m.refs))
+	for name := range m.refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r := m.refs[name]
+		v, ok := m.Lookup(name)
+		if !ok {
+			unresolved = append(unresolved, r.Ident())
+			continue
+		}
+		if !v.Type().Equal(r.Typ) {
+			return errors.Errorf("type mismatch of reference to %v; expected %v, got %v", v.Ident(), v.Type(), r.Typ)
+		}
+		ReplaceUses(m, r, v)
+		delete(m.refs, name)
+	}
+	for _, t := range m.TypeDefs {
+		if st, ok := t.(*types.StructType); ok && m.typeRefs[st.Alias] == st {
+			unresolved = append(unresolved, st.String())
+		}
+	}
+	if len(unresolved) > 0 {
+		return &UnresolvedError{Idents: unresolved}
+	}
+	return nil
+}
+
+// UnresolvedError is the error returned by Module.Finish if placeholder
+// references could not be resolved.
+type UnresolvedError struct {
+	// Identifiers of the unresolved global variables, functions and types (e.g.
+	// "@f" and "%T").
+	Idents []string
+}
+
+// Error returns the error message of the unresolved references.
+func (e *UnresolvedError) Error() string {
+	return fmt.Sprintf("unresolved references to %s", strings.Join(e.Idents, ", "))
+}
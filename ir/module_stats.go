@@ -0,0 +1,224 @@
+package ir
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/llir/l/ir/types"
+)
+
+// --- [ Memory statistics ] ---------------------------------------------------
+
+// MemoryStats is the approximate memory footprint of a module, in node counts
+// and bytes per category of nodes.
+//
+// Each node is attributed the size of its struct and of the backing arrays of
+// its slices. Auxiliary structures (e.g. incoming values of phi instructions,
+// switch cases and metadata fields) are attributed to the category of the
+// node owning them. Unexported fields (e.g. caches and symbol tables) are not
+// included.
+type MemoryStats struct {
+	// Instructions and terminators.
+	Insts NodeStats
+	// Constants and constant expressions.
+	Consts NodeStats
+	// Metadata nodes.
+	Metadata NodeStats
+	// Types.
+	Types NodeStats
+	// String data (e.g. names, comments, and metadata strings); the count is
+	// the number of non-empty strings.
+	Strings NodeStats
+	// Other nodes; global variables, functions, parameters, basic blocks and
+	// attribute group definitions.
+	Other NodeStats
+}
+
+// NodeStats is the number of nodes and approximate bytes of a category.
+type NodeStats struct {
+	// Number of nodes.
+	Count int
+	// Approximate number of bytes.
+	Bytes int64
+}
+
+// MemoryStats returns the approximate memory footprint of the module, in node
+// counts and bytes per category of nodes (e.g. instructions, constants and
+// metadata), to locate the source of memory usage of large modules.
+//
+// Nodes shared by several parents (e.g. types and constants) are counted
+// once. Lazy initializers of global variables are not materialized.
+func (m *Module) MemoryStats() *MemoryStats {
+	s := &memStats{
+		stats: &MemoryStats{},
+		seen:  make(map[memKey]bool),
+	}
+	s.visit(reflect.ValueOf(m), &s.stats.Other)
+	return s.stats
+}
+
+// Total returns the total number of nodes and approximate bytes of all
+// categories.
+func (stats *MemoryStats) Total() NodeStats {
+	var total NodeStats
+	for _, c := range stats.categories() {
+		total.Count += c.stats.Count
+		total.Bytes += c.stats.Bytes
+	}
+	return total
+}
+
+// String returns a table of the memory statistics, with one line per category.
+func (stats *MemoryStats) String() string {
+	buf := &strings.Builder{}
+	for _, c := range stats.categories() {
+		fmt.Fprintf(buf, "%-12s %10d nodes %12d bytes\n", c.name+":", c.stats.Count, c.stats.Bytes)
+	}
+	total := stats.Total()
+	fmt.Fprintf(buf, "%-12s %10d nodes %12d bytes\n", "total:", total.Count, total.Bytes)
+	return buf.String()
+}
+
+// categories returns the named categories of the memory statistics.
+func (stats *MemoryStats) categories() []struct {
+	name  string
+	stats NodeStats
+} {
+	return []struct {
+		name  string
+		stats NodeStats
+	}{
+		{name: "insts", stats: stats.Insts},
+		{name: "consts", stats: stats.Consts},
+		{name: "metadata", stats: stats.Metadata},
+		{name: "types", stats: stats.Types},
+		{name: "strings", stats: stats.Strings},
+		{name: "other", stats: stats.Other},
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// memStats tracks the memory statistics of a module.
+type memStats struct {
+	stats *MemoryStats
+	// Visited pointers.
+	seen map[memKey]bool
+}
+
+// memKey identifies a visited pointer.
+type memKey struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+// Interface types of node categories.
+var (
+	instructionType = reflect.TypeOf((*Instruction)(nil)).Elem()
+	terminatorType  = reflect.TypeOf((*Terminator)(nil)).Elem()
+	constantType    = reflect.TypeOf((*Constant)(nil)).Elem()
+	metadataType    = reflect.TypeOf((*Metadata)(nil)).Elem()
+	typeType        = reflect.TypeOf((*types.Type)(nil)).Elem()
+)
+
+// category returns the category of nodes of the given pointer type; or nil if
+// the pointer type is not a node (e.g. auxiliary structures).
+func (s *memStats) category(t reflect.Type) *NodeStats {
+	switch t {
+	case reflect.TypeOf(&Global{}), reflect.TypeOf(&Function{}), reflect.TypeOf(&Param{}), reflect.TypeOf(&BasicBlock{}), reflect.TypeOf(&AttrGroupDef{}):
+		return &s.stats.Other
+	}
+	switch {
+	case t.Implements(instructionType), t.Implements(terminatorType):
+		return &s.stats.Insts
+	case t.Implements(typeType):
+		return &s.stats.Types
+	case t.Implements(metadataType):
+		return &s.stats.Metadata
+	case t.Implements(constantType):
+		return &s.stats.Consts
+	}
+	return nil
+}
+
+// visit accounts for the memory reachable from the given value, attributed to
+// the given category unless the value is a node of another category.
+func (s *memStats) visit(v reflect.Value, owner *NodeStats) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		key := memKey{typ: v.Type(), ptr: v.Pointer()}
+		if s.seen[key] {
+			return
+		}
+		s.seen[key] = true
+		cat := s.category(v.Type())
+		if cat != nil {
+			cat.Count++
+		} else {
+			// Auxiliary structure of the owner.
+			cat = owner
+		}
+		elem := v.Elem()
+		cat.Bytes += int64(elem.Type().Size())
+		s.visitElem(elem, cat)
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		x := v.Elem()
+		if x.Kind() != reflect.Ptr {
+			// Non-pointer values stored in interfaces (e.g. attributes) are
+			// boxed.
+			owner.Bytes += int64(x.Type().Size())
+		}
+		s.visit(x, owner)
+	default:
+		s.visitElem(v, owner)
+	}
+}
+
+// visitElem accounts for the memory reachable from the given non-pointer
+// value, not including the size of the value itself.
+func (s *memStats) visitElem(v reflect.Value, owner *NodeStats) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() > 0 {
+			s.stats.Strings.Count++
+			s.stats.Strings.Bytes += int64(v.Len())
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		owner.Bytes += int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			s.visitElem(v.Index(i), owner)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			s.visitElem(v.Index(i), owner)
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(big.Int{}) && v.CanAddr() {
+			// Words of arbitrary precision integers.
+			x := v.Addr().Interface().(*big.Int)
+			owner.Bytes += int64(len(x.Bits())) * int64(reflect.TypeOf(big.Word(0)).Size())
+			return
+		}
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if len(t.Field(i).PkgPath) > 0 {
+				// Skip unexported fields.
+				continue
+			}
+			s.visitElem(v.Field(i), owner)
+		}
+	case reflect.Ptr, reflect.Interface:
+		s.visit(v, owner)
+	}
+}
@@ -0,0 +1,52 @@
+package ir
+
+import (
+	"fmt"
+
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+// --- [ String literals ] -----------------------------------------------------
+
+// InternString returns a pointer (i8*) to the NULL-terminated string literal s,
+// as a getelementptr constant expression into a private unnamed_addr constant
+// global variable of the module; e.g.
+//
+//    @.str = private unnamed_addr constant [6 x i8] c"hello\00"
+//
+// String literals are pooled, so that each string is stored once in the
+// module. A new global variable named ".str", ".str.1", etc. is appended to the
+// module for strings not yet present in the pool.
+func (m *Module) InternString(s string) *ExprGetElementPtr {
+	contents := s + "\x00"
+	g, ok := m.strs[contents]
+	if !ok || m.symbols[g.GlobalName] != g {
+		g = m.NewGlobalDef(m.uniqueName(".str"), NewCharArrayFromString(contents))
+		g.Linkage = enum.LinkagePrivate
+		g.UnnamedAddr = enum.UnnamedAddrUnnamedAddr
+		g.Immutable = true
+		if m.strs == nil {
+			m.strs = make(map[string]*Global)
+		}
+		m.strs[contents] = g
+	}
+	zero := NewInt(types.I64, 0)
+	gep := NewGetElementPtrExpr(g.ContentType, g, NewIndex(zero), NewIndex(zero))
+	gep.InBounds = true
+	return gep
+}
+
+// uniqueName returns a global name based on the given name, which is not yet
+// registered in the symbol table of the module; e.g. ".str", ".str.1".
+func (m *Module) uniqueName(name string) string {
+	if _, ok := m.symbols[name]; !ok {
+		return name
+	}
+	for i := 1; ; i++ {
+		uniqueName := fmt.Sprintf("%s.%d", name, i)
+		if _, ok := m.symbols[uniqueName]; !ok {
+			return uniqueName
+		}
+	}
+}
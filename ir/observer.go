@@ -0,0 +1,175 @@
+package ir
+
+import "github.com/llir/l/ir/value"
+
+// === [ Mutation observers ] ==================================================
+
+// Observer is a set of optional callbacks invoked on mutations of a module, so
+// that incremental analyses (e.g. symbol tables and control flow graph caches)
+// and debugging tools may stay in sync with the module without full
+// recomputation. Nil callbacks are ignored.
+//
+// Mutations performed by the builder and mutator methods are observed; e.g.
+// NewFunc, RemoveGlobal and Rename of modules, NewBlock and RemoveBlock of
+// functions, the instruction and terminator builder methods, InsertInst,
+// InsertBefore and RemoveInst of basic blocks, and SetName of basic blocks and
+// of the instructions and terminators inserted by these methods. Direct
+// modifications of exported fields (e.g. appending to Insts of a basic block)
+// are not observed.
+type Observer struct {
+	// Insert is invoked after the given node was inserted into parent; i.e. an
+	// instruction or terminator into a *BasicBlock, a *BasicBlock into a
+	// *Function, or a *Global, *RawGlobal or *Function into a *Module.
+	Insert func(parent, node interface{})
+	// Remove is invoked after the given node was removed from parent, with the
+	// same kinds of parents and nodes as Insert. Replacing the terminator of a
+	// basic block removes the old terminator before inserting the new.
+	Remove func(parent, node interface{})
+	// Rename is invoked after the given global variable or function was renamed
+	// from the given old name by Rename, or the given basic block, instruction
+	// or terminator by SetName.
+	Rename func(v value.Named, old string)
+}
+
+// SetObserver sets the mutation observer of the module; or removes it if obs
+// is nil. The functions and basic blocks present in the module are linked to
+// the module, to have their subsequent mutations observed.
+func (m *Module) SetObserver(obs *Observer) {
+	m.obs = obs
+	for _, f := range m.Funcs {
+		f.parent = m
+		for _, block := range f.Blocks {
+			block.parent = f
+		}
+	}
+}
+
+// RemoveBlock removes the given basic block from the function. Uses of the
+// basic block are unaffected.
+func (f *Function) RemoveBlock(block *BasicBlock) {
+	for i, b := range f.Blocks {
+		if b == block {
+			f.Blocks = append(f.Blocks[:i:i], f.Blocks[i+1:]...)
+			block.parent = nil
+			f.module().notifyRemove(f, block)
+			return
+		}
+	}
+}
+
+// InsertInst inserts the given instructions at index i of the instructions of
+// the basic block. Uses of the instructions are unaffected.
+func (block *BasicBlock) InsertInst(i int, insts ...Instruction) {
+	block.insertInst(i, insts)
+}
+
+// InsertBefore inserts the given instructions before the instruction before of
+// the basic block. Uses of the instructions are unaffected.
+func (block *BasicBlock) InsertBefore(before Instruction, insts ...Instruction) {
+	for i, x := range block.Insts {
+		if x == before {
+			block.insertInst(i, insts)
+			return
+		}
+	}
+	panic(errorf("unable to locate instruction %v in basic block %v", before, block.Ident()))
+}
+
+// RemoveInst removes the given instruction from the basic block. Uses of the
+// instruction are unaffected.
+func (block *BasicBlock) RemoveInst(inst Instruction) {
+	for i, x := range block.Insts {
+		if x == inst {
+			block.Insts = append(block.Insts[:i:i], block.Insts[i+1:]...)
+			setParent(inst, nil)
+			block.module().notifyRemove(block, inst)
+			return
+		}
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// instParent is the parent basic block of an instruction or terminator, as
+// linked by the methods of basic blocks which insert it; or nil if not present.
+// It is embedded in the instructions and terminators producing named values,
+// to have their renaming observed.
+type instParent struct {
+	parent *BasicBlock
+}
+
+// setParent sets the parent basic block of the instruction or terminator.
+func (p *instParent) setParent(block *BasicBlock) {
+	p.parent = block
+}
+
+// notifyRename notifies the mutation observer of the parent module, if any, of
+// the renaming of v from the given old name.
+func (p *instParent) notifyRename(v value.Named, old string) {
+	if p.parent != nil && v.Name() != old {
+		p.parent.module().notifyRename(v, old)
+	}
+}
+
+// internName returns the given name, interned in the name table of the parent
+// module if present.
+func (p *instParent) internName(name string) string {
+	if p.parent == nil {
+		return name
+	}
+	return p.parent.module().internName(name)
+}
+
+// setParent sets the parent basic block of the given instruction or
+// terminator, if it has one. The name of the instruction or terminator is
+// interned in the name table of the parent module if present.
+func setParent(inst interface{}, block *BasicBlock) {
+	if p, ok := inst.(interface{ setParent(block *BasicBlock) }); ok {
+		p.setParent(block)
+	}
+	if n, ok := inst.(value.Named); ok && block != nil {
+		if name := n.Name(); !isUnnamed(name) {
+			// Interned by SetName, now that the parent is linked.
+			n.SetName(name)
+		}
+	}
+}
+
+// module returns the parent module of the function; or nil if not linked to a
+// module.
+func (f *Function) module() *Module {
+	if f == nil {
+		return nil
+	}
+	return f.parent
+}
+
+// module returns the parent module of the basic block; or nil if not linked to
+// a module.
+func (block *BasicBlock) module() *Module {
+	return block.parent.module()
+}
+
+// notifyInsert notifies the mutation observer of the module, if any, of the
+// insertion of node into parent.
+func (m *Module) notifyInsert(parent, node interface{}) {
+	if m != nil && m.obs != nil && m.obs.Insert != nil {
+		m.obs.Insert(parent, node)
+	}
+}
+
+// notifyRemove notifies the mutation observer of the module, if any, of the
+// removal of node from parent.
+func (m *Module) notifyRemove(parent, node interface{}) {
+	if m != nil && m.obs != nil && m.obs.Remove != nil {
+		m.obs.Remove(parent, node)
+	}
+}
+
+// notifyRename notifies the mutation observer of the module, if any, of the
+// renaming of v from the given old name.
+func (m *Module) notifyRename(v value.Named, old string) {
+	if m != nil && m.obs != nil && m.obs.Rename != nil {
+		m.obs.Rename(v, old)
+	}
+}
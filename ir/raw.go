@@ -0,0 +1,151 @@
+package ir
+
+import (
+	"fmt"
+
+	"github.com/llir/l/internal/enc"
+	"github.com/llir/l/ir/types"
+)
+
+// === [ Raw entities ] ========================================================
+
+// Raw entities are escape hatches for LLVM IR syntax not yet modelled by the
+// ir package. The Def method of a raw entity returns its user-supplied text
+// verbatim, which is neither parsed nor validated.
+
+// --- [ Raw instructions ] ----------------------------------------------------
+
+// RawInst is a raw instruction; its LLVM syntax representation is given
+// verbatim, including the result assignment if any (e.g. `%x = freeze i32 %y`).
+type RawInst struct {
+	// LLVM syntax representation of the instruction.
+	Text string
+
+	// extra.
+
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+}
+
+// NewRawInst returns a new raw instruction based on the given LLVM syntax
+// representation.
+func NewRawInst(text string) *RawInst {
+	return &RawInst{Text: text}
+}
+
+// Def returns the LLVM syntax representation of the instruction.
+func (inst *RawInst) Def() string {
+	return inst.Text
+}
+
+// isInstruction ensures that only instructions can be assigned to the
+// instruction.Instruction interface.
+func (*RawInst) isInstruction() {}
+
+// comments returns the comments of the instruction.
+func (inst *RawInst) comments() []string {
+	return inst.Comments
+}
+
+// --- [ Raw terminators ] -----------------------------------------------------
+
+// RawTerm is a raw terminator; its LLVM syntax representation is given
+// verbatim (e.g. `callbr void asm "", "r,!i"(i32 %x) to label %a [label %b]`).
+type RawTerm struct {
+	// LLVM syntax representation of the terminator.
+	Text string
+	// Successor basic blocks of the terminator, as used by control flow
+	// analyses.
+	Successors []*BasicBlock
+
+	// extra.
+
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+}
+
+// NewRawTerm returns a new raw terminator based on the given LLVM syntax
+// representation and successor basic blocks.
+func NewRawTerm(text string, succs ...*BasicBlock) *RawTerm {
+	return &RawTerm{Text: text, Successors: succs}
+}
+
+// Def returns the LLVM syntax representation of the terminator.
+func (term *RawTerm) Def() string {
+	return term.Text
+}
+
+// Succs returns the successor basic blocks of the terminator.
+func (term *RawTerm) Succs() []*BasicBlock {
+	return term.Successors
+}
+
+// comments returns the comments of the terminator.
+func (term *RawTerm) comments() []string {
+	return term.Comments
+}
+
+// --- [ Raw global entities ] -------------------------------------------------
+
+// RawGlobal is a raw module-level entity (e.g. a global variable, alias or
+// IFunc definition); its LLVM syntax representation is given verbatim. Raw
+// global entities are printed after the global variables of a module.
+//
+// A raw global entity with a global name may be used as an operand, in which
+// case Typ specifies its type (e.g. `i32*` for a global variable of content
+// type `i32`).
+type RawGlobal struct {
+	// LLVM syntax representation of the global entity.
+	Text string
+
+	// extra.
+
+	// (optional) Global name (without '@' prefix); empty if not present.
+	GlobalName string
+	// (optional) Type of the global entity when used as an operand; nil if not
+	// present.
+	Typ types.Type
+	// (optional) Comments; printed as `;` comment lines preceding the global
+	// entity.
+	Comments []string
+}
+
+// NewRawGlobal returns a new raw global entity based on the given global name,
+// operand type and LLVM syntax representation. An empty global name indicates
+// that the raw global entity may not be used as an operand.
+func NewRawGlobal(name string, typ types.Type, text string) *RawGlobal {
+	return &RawGlobal{Text: text, GlobalName: name, Typ: typ}
+}
+
+// String returns the LLVM syntax representation of the raw global entity as a
+// type-value pair.
+func (g *RawGlobal) String() string {
+	return fmt.Sprintf("%v %v", g.Type(), g.Ident())
+}
+
+// Type returns the type of the raw global entity.
+func (g *RawGlobal) Type() types.Type {
+	return g.Typ
+}
+
+// Ident returns the identifier associated with the raw global entity.
+func (g *RawGlobal) Ident() string {
+	return enc.Global(g.GlobalName)
+}
+
+// Name returns the name of the raw global entity.
+func (g *RawGlobal) Name() string {
+	return g.GlobalName
+}
+
+// SetName sets the name of the raw global entity.
+func (g *RawGlobal) SetName(name string) {
+	g.GlobalName = name
+}
+
+// Def returns the LLVM syntax representation of the raw global entity.
+func (g *RawGlobal) Def() string {
+	return g.Text
+}
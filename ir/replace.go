@@ -0,0 +1,123 @@
+package ir
+
+import (
+	"reflect"
+
+	"github.com/llir/l/ir/value"
+)
+
+// === [ Replace ] =============================================================
+
+// ReplaceUses replaces each use of old with new in the operands of the given IR
+// node (e.g. module, function, basic block or instruction). Uses within
+// constant expressions and aggregate constants are replaced in place.
+//
+// Operands nested in function arguments, incoming values, switch cases, gep
+// indices and operand bundles are replaced as these are visited by Walk.
+func ReplaceUses(node interface{}, old, new value.Value) {
+	replaceUses(node, map[value.Value]value.Value{old: new})
+}
+
+// ### [ Helper functions ] ####################################################
+
+// replaceUses replaces each use of a value in the operands of the given IR node
+// with its replacement in remap.
+func replaceUses(node interface{}, remap map[value.Value]value.Value) {
+	Walk(node, func(n interface{}) bool {
+		switch n := n.(type) {
+		case *Module, *BasicBlock, *Param, *AttrGroupDef, *RawGlobal:
+			// no operands.
+		case *Global:
+			replaceGlobal(n, remap)
+		case *Function:
+			replaceFunc(n, remap)
+		case CustomInst:
+			for _, x := range n.Operands() {
+				if new, ok := remap[*x]; ok {
+					*x = new
+				}
+			}
+		default:
+			replaceFields(reflect.ValueOf(n), remap)
+		}
+		return true
+	})
+}
+
+// replaceGlobal replaces the initializer of the given global variable if it is
+// a use of a value in remap.
+func replaceGlobal(g *Global, remap map[value.Value]value.Value) {
+	if g.Init == nil {
+		return
+	}
+	if new, ok := remap[g.Init]; ok {
+		if c, ok := new.(Constant); ok {
+			g.Init = c
+		}
+	}
+}
+
+// replaceFunc replaces the prefix, prologue and personality of the given
+// function if they are uses of a value in remap.
+func replaceFunc(f *Function, remap map[value.Value]value.Value) {
+	for _, field := range []*Constant{&f.Prefix, &f.Prologue, &f.Personality} {
+		if *field == nil {
+			continue
+		}
+		if new, ok := remap[*field]; ok {
+			if c, ok := new.(Constant); ok {
+				*field = c
+			}
+		}
+	}
+}
+
+// replaceFields replaces uses of the values in remap in the struct fields of
+// the given node.
+func replaceFields(v reflect.Value, remap map[value.Value]value.Value) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		switch t.Field(i).Name {
+		case "Typ", "Successors":
+			// Skip cached types and successors.
+			continue
+		}
+		replaceField(v.Field(i), remap)
+	}
+}
+
+// replaceField replaces uses of the values in remap in the given struct field
+// value.
+func replaceField(v reflect.Value, remap map[value.Value]value.Value) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() || !v.CanInterface() {
+			return
+		}
+		x, ok := v.Interface().(value.Value)
+		if !ok {
+			return
+		}
+		new, ok := remap[x]
+		if !ok {
+			return
+		}
+		nv := reflect.ValueOf(new)
+		if v.CanSet() && nv.Type().AssignableTo(v.Type()) {
+			v.Set(nv)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			replaceField(v.Index(i), remap)
+		}
+	}
+}
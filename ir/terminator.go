@@ -31,6 +31,10 @@ import (
 //    *ir.TermCatchRet      // https://godoc.org/github.com/llir/l/ir#TermCatchRet
 //    *ir.TermCleanupRet    // https://godoc.org/github.com/llir/l/ir#TermCleanupRet
 //    *ir.TermUnreachable   // https://godoc.org/github.com/llir/l/ir#TermUnreachable
+//
+// Raw terminators
+//
+//    *ir.RawTerm           // https://godoc.org/github.com/llir/l/ir#RawTerm
 type Terminator interface {
 	// Def returns the LLVM syntax representation of the terminator.
 	Def() string
@@ -38,6 +42,19 @@ type Terminator interface {
 	Succs() []*BasicBlock
 }
 
+// Comments of terminators.
+func (term *TermRet) comments() []string         { return term.Comments }
+func (term *TermBr) comments() []string          { return term.Comments }
+func (term *TermCondBr) comments() []string      { return term.Comments }
+func (term *TermSwitch) comments() []string      { return term.Comments }
+func (term *TermIndirectBr) comments() []string  { return term.Comments }
+func (term *TermInvoke) comments() []string      { return term.Comments }
+func (term *TermResume) comments() []string      { return term.Comments }
+func (term *TermCatchSwitch) comments() []string { return term.Comments }
+func (term *TermCatchRet) comments() []string    { return term.Comments }
+func (term *TermCleanupRet) comments() []string  { return term.Comments }
+func (term *TermUnreachable) comments() []string { return term.Comments }
+
 // --- [ ret ] -----------------------------------------------------------------
 
 // TermRet is an LLVM IR ret terminator.
@@ -48,7 +65,10 @@ type TermRet struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewRet returns a new ret terminator based on the given return value. A nil
@@ -91,7 +111,10 @@ type TermBr struct {
 	// Successor basic blocks of the terminator.
 	Successors []*BasicBlock
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewBr returns a new unconditional br terminator based on the given target
@@ -136,7 +159,10 @@ type TermCondBr struct {
 	// Successor basic blocks of the terminator.
 	Successors []*BasicBlock
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewCondBr returns a new conditional br terminator based on the given
@@ -181,7 +207,10 @@ type TermSwitch struct {
 	// Successor basic blocks of the terminator.
 	Successors []*BasicBlock
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewSwitch returns a new switch terminator based on the given control
@@ -235,6 +264,12 @@ func NewCase(x Constant, target *BasicBlock) *Case {
 	return &Case{X: x, Target: target}
 }
 
+// String returns the LLVM syntax representation of the switch case.
+func (c *Case) String() string {
+	// Type Constant "," LabelType LocalIdent
+	return fmt.Sprintf("%v, %v", c.X, c.Target)
+}
+
 // --- [ indirectbr ] ----------------------------------------------------------
 
 // TermIndirectBr is an LLVM IR indirectbr terminator.
@@ -247,7 +282,10 @@ type TermIndirectBr struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewIndirectBr returns a new indirectbr terminator based on the given target
@@ -289,8 +327,8 @@ type TermInvoke struct {
 	// Invokee (callee function).
 	// TODO: specify the set of underlying types of Invokee.
 	Invokee value.Value
-	// Function arguments.
-	Args []Arg
+	// Function arguments; *ir.Arg may be used to specify parameter attributes.
+	Args []value.Value
 	// Normal control flow return point.
 	Normal *BasicBlock
 	// Exception control flow return point.
@@ -298,23 +336,33 @@ type TermInvoke struct {
 
 	// extra.
 
-	// Type of result produced by the terminator, or function signature of the
-	// invokee (as used when invokee is variadic).
+	// Type of result produced by the terminator.
 	Typ types.Type
+	// (optional) Function type of the invokee; nil to infer from the type of
+	// Invokee. The invokee type is printed at invoke sites of variadic
+	// invokees, and of invokees the type of which may not be inferred.
+	InvokeeType *types.FuncType
 	// Successor basic blocks of the terminator.
 	Successors []*BasicBlock
 	// (optional) Calling convention; zero if not present.
 	CallingConv enum.CallingConv
 	// (optional) Return attributes.
-	ReturnAttrs []enum.ReturnAttribute
+	ReturnAttrs []ReturnAttribute
 	// (optional) Address space; zero if not present.
 	AddrSpace types.AddrSpace
 	// (optional) Function attributes.
-	FuncAttrs []enum.FuncAttribute
+	FuncAttrs []FuncAttribute
 	// (optional) Operand bundles.
-	OperandBundles []enum.OperandBundle
+	OperandBundles []*OperandBundle
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the terminator, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewInvoke returns a new invoke terminator based on the given invokee, function
@@ -322,7 +370,7 @@ type TermInvoke struct {
 // execution.
 //
 // TODO: specify the set of underlying types of invokee.
-func NewInvoke(invokee value.Value, args []Arg, normal, exception *BasicBlock) *TermInvoke {
+func NewInvoke(invokee value.Value, args []value.Value, normal, exception *BasicBlock) *TermInvoke {
 	return &TermInvoke{Invokee: invokee, Args: args, Normal: normal, Exception: exception}
 }
 
@@ -332,30 +380,26 @@ func (term *TermInvoke) String() string {
 	return fmt.Sprintf("%v %v", term.Type(), term.Ident())
 }
 
-// Type returns the type of the terminator.
+// Type returns the type of the terminator; or types.Invalid if the invokee type
+// is invalid.
 func (term *TermInvoke) Type() types.Type {
 	// Cache type if not present.
 	if term.Typ == nil {
-		t, ok := term.Invokee.Type().(*types.PointerType)
-		if !ok {
-			panic(fmt.Errorf("invalid invokee type; expected *types.PointerType, got %T", term.Invokee.Type()))
+		sig, err := callSig("invokee", term.InvokeeType, term.Invokee)
+		if err != nil {
+			return types.Invalid
 		}
-		sig, ok := t.ElemType.(*types.FuncType)
-		if !ok {
-			panic(fmt.Errorf("invalid invokee type; expected *types.FuncType, got %T", t.ElemType))
-		}
-		if sig.Variadic {
-			term.Typ = sig
-		} else {
-			term.Typ = sig.RetType
-		}
-	}
-	if t, ok := term.Typ.(*types.FuncType); ok {
-		return t.RetType
+		term.Typ = sig.RetType
 	}
 	return term.Typ
 }
 
+// Validate reports whether the invokee type of the terminator is valid, and
+// whether the fixed arguments and result type agree with the invokee.
+func (term *TermInvoke) Validate() error {
+	return validateCall("invokee", term.InvokeeType, term.Invokee, term.Args, term.Typ)
+}
+
 // Ident returns the identifier associated with the terminator.
 func (term *TermInvoke) Ident() string {
 	return enc.Local(term.LocalName)
@@ -368,7 +412,9 @@ func (term *TermInvoke) Name() string {
 
 // SetName sets the name of the terminator.
 func (term *TermInvoke) SetName(name string) {
-	term.LocalName = name
+	old := term.LocalName
+	term.LocalName = term.internName(name)
+	term.notifyRename(term, old)
 }
 
 // Succs returns the successor basic blocks of the terminator.
@@ -391,7 +437,9 @@ func (term *TermInvoke) Def() string {
 	for _, attr := range term.ReturnAttrs {
 		fmt.Fprintf(buf, " %v", attr)
 	}
-	fmt.Fprintf(buf, "%v %v(", term.Type(), term.Invokee.Ident())
+	// Invokes of variadic functions specify the function type of the invokee.
+	typ := callType(term.Type(), term.InvokeeType, term.Invokee)
+	fmt.Fprintf(buf, " %v %v(", typ, term.Invokee.Ident())
 	for i, arg := range term.Args {
 		if i != 0 {
 			buf.WriteString(", ")
@@ -403,11 +451,14 @@ func (term *TermInvoke) Def() string {
 		fmt.Fprintf(buf, " %v", attr)
 	}
 	if len(term.OperandBundles) > 0 {
-		buf.WriteString("[")
-		for _, operandBundle := range term.OperandBundles {
-			fmt.Fprintf(buf, " %v", operandBundle)
+		buf.WriteString(" [ ")
+		for i, operandBundle := range term.OperandBundles {
+			if i != 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(operandBundle.String())
 		}
-		buf.WriteString("]")
+		buf.WriteString(" ]")
 	}
 	fmt.Fprintf(buf, " to %v unwind %v", term.Normal, term.Exception)
 	for _, md := range term.Metadata {
@@ -426,7 +477,10 @@ type TermResume struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewResume returns a new resume terminator based on the given exception
@@ -470,7 +524,14 @@ type TermCatchSwitch struct {
 	// Successor basic blocks of the terminator.
 	Successors []*BasicBlock
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
+
+	// Parent basic block of the terminator, as linked by the methods of basic
+	// blocks which insert it; or nil if not present.
+	instParent
 }
 
 // NewCatchSwitch returns a new catchswitch terminator based on the given
@@ -502,7 +563,9 @@ func (term *TermCatchSwitch) Name() string {
 
 // SetName sets the name of the terminator.
 func (term *TermCatchSwitch) SetName(name string) {
-	term.LocalName = name
+	old := term.LocalName
+	term.LocalName = term.internName(name)
+	term.notifyRename(term, old)
 }
 
 // Succs returns the successor basic blocks of the terminator.
@@ -510,7 +573,10 @@ func (term *TermCatchSwitch) Succs() []*BasicBlock {
 	// Cache successors if not present.
 	if term.Successors == nil {
 		if unwindTarget, ok := term.UnwindTarget.(*BasicBlock); ok {
-			term.Successors = append(term.Handlers, unwindTarget)
+			// Copy handlers to not overwrite the backing array of term.Handlers.
+			succs := make([]*BasicBlock, 0, len(term.Handlers)+1)
+			succs = append(succs, term.Handlers...)
+			term.Successors = append(succs, unwindTarget)
 		} else {
 			term.Successors = term.Handlers
 		}
@@ -522,14 +588,14 @@ func (term *TermCatchSwitch) Succs() []*BasicBlock {
 func (term *TermCatchSwitch) Def() string {
 	// "catchswitch" "within" ExceptionScope "[" LabelList "]" "unwind" UnwindTarget OptCommaSepMetadataAttachmentList
 	buf := &strings.Builder{}
-	fmt.Fprintf(buf, "catchswitch within %v [", term.Scope)
+	fmt.Fprintf(buf, "catchswitch within %v [", scopeIdent(term.Scope))
 	for i, handler := range term.Handlers {
 		if i != 0 {
 			buf.WriteString(", ")
 		}
 		buf.WriteString(handler.String())
 	}
-	fmt.Fprintf(buf, "] unwind %v", term.UnwindTarget)
+	fmt.Fprintf(buf, "] unwind %v", unwindTargetString(term.UnwindTarget))
 	for _, md := range term.Metadata {
 		fmt.Fprintf(buf, ", %v", md)
 	}
@@ -550,7 +616,10 @@ type TermCatchRet struct {
 	// Successor basic blocks of the terminator.
 	Successors []*BasicBlock
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewCatchRet returns a new catchret terminator based on the given exit
@@ -572,7 +641,7 @@ func (term *TermCatchRet) Succs() []*BasicBlock {
 func (term *TermCatchRet) Def() string {
 	// "catchret" "from" Value "to" LabelType LocalIdent OptCommaSepMetadataAttachmentList
 	buf := &strings.Builder{}
-	fmt.Fprintf(buf, "catchret from %v to %v", term.From, term.To)
+	fmt.Fprintf(buf, "catchret from %v to %v", term.From.Ident(), term.To)
 	for _, md := range term.Metadata {
 		fmt.Fprintf(buf, ", %v", md)
 	}
@@ -593,7 +662,10 @@ type TermCleanupRet struct {
 	// Successor basic blocks of the terminator.
 	Successors []*BasicBlock
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewCleanupRet returns a new cleanupret terminator based on the given exit
@@ -619,7 +691,7 @@ func (term *TermCleanupRet) Succs() []*BasicBlock {
 func (term *TermCleanupRet) Def() string {
 	// "cleanupret" "from" Value "unwind" UnwindTarget OptCommaSepMetadataAttachmentList
 	buf := &strings.Builder{}
-	fmt.Fprintf(buf, "cleanupret from %v unwind %v", term.From, term.UnwindTarget)
+	fmt.Fprintf(buf, "cleanupret from %v unwind %v", term.From.Ident(), unwindTargetString(term.UnwindTarget))
 	for _, md := range term.Metadata {
 		fmt.Fprintf(buf, ", %v", md)
 	}
@@ -633,7 +705,10 @@ type TermUnreachable struct {
 	// extra.
 
 	// (optional) Metadata.
-	Metadata []MetadataAttachment
+	Metadata MDAttachments
+	// (optional) Comments; printed as `;` comment lines preceding the
+	// instruction.
+	Comments []string
 }
 
 // NewUnreachable returns a new unreachable terminator.
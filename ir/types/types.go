@@ -1,4 +1,10 @@
 // Package types declares the data types of LLVM IR.
+//
+// The convenience types of the package (e.g. types.I32 and types.Void) are
+// shared by all modules of the process. They are immutable, and may therefore
+// be used concurrently by independent modules; SetAlias panics on convenience
+// types, which must be copied (e.g. by Copy) to be named. Other types are
+// owned by their creator, and may not be modified concurrently with their use.
 package types
 
 import (
@@ -18,6 +24,8 @@ var (
 	Label    = &LabelType{}    // label
 	Token    = &TokenType{}    // token
 	Metadata = &MetadataType{} // metadata
+	// Type of invalid values.
+	Invalid = &InvalidType{} // <invalid>
 	// Integer types.
 	I1  = &IntType{BitSize: 1}  // i1
 	I8  = &IntType{BitSize: 8}  // i8
@@ -39,6 +47,85 @@ var (
 	I64Ptr = &PointerType{ElemType: I64} // i64*
 )
 
+// shared is the set of convenience types shared by all modules.
+var shared = map[Type]bool{
+	Void:     true,
+	MMX:      true,
+	Label:    true,
+	Token:    true,
+	Metadata: true,
+	Invalid:  true,
+	I1:       true,
+	I8:       true,
+	I16:      true,
+	I32:      true,
+	I64:      true,
+	Half:     true,
+	Float:    true,
+	Double:   true,
+	X86FP80:  true,
+	FP128:    true,
+	PPCFP128: true,
+	I1Ptr:    true,
+	I8Ptr:    true,
+	I16Ptr:   true,
+	I32Ptr:   true,
+	I64Ptr:   true,
+}
+
+// IsShared reports whether the given type is one of the immutable convenience
+// types shared by all modules of the process (e.g. types.I32).
+func IsShared(t Type) bool {
+	return shared[t]
+}
+
+// Copy returns a shallow copy of the given type; e.g. to name a copy of a
+// shared convenience type.
+func Copy(t Type) Type {
+	switch t := t.(type) {
+	case *VoidType:
+		c := *t
+		return &c
+	case *FuncType:
+		c := *t
+		return &c
+	case *IntType:
+		c := *t
+		return &c
+	case *FloatType:
+		c := *t
+		return &c
+	case *MMXType:
+		c := *t
+		return &c
+	case *PointerType:
+		c := *t
+		return &c
+	case *VectorType:
+		c := *t
+		return &c
+	case *LabelType:
+		c := *t
+		return &c
+	case *TokenType:
+		c := *t
+		return &c
+	case *MetadataType:
+		c := *t
+		return &c
+	case *ArrayType:
+		c := *t
+		return &c
+	case *StructType:
+		c := *t
+		return &c
+	case *InvalidType:
+		c := *t
+		return &c
+	}
+	panic(fmt.Errorf("support for type %T not yet implemented", t))
+}
+
 // Type is an LLVM IR type.
 type Type interface {
 	fmt.Stringer
@@ -80,8 +167,10 @@ func (t *VoidType) Def() string {
 	return "void"
 }
 
-// SetAlias sets the type name alias of the type.
+// SetAlias sets the type name alias of the type. It panics if the type is a
+// shared convenience type.
 func (t *VoidType) SetAlias(alias string) {
+	checkShared(t, alias)
 	t.Alias = alias
 }
 
@@ -200,8 +289,10 @@ func (t *IntType) Def() string {
 	return fmt.Sprintf("i%d", t.BitSize)
 }
 
-// SetAlias sets the type name alias of the type.
+// SetAlias sets the type name alias of the type. It panics if the type is a
+// shared convenience type.
 func (t *IntType) SetAlias(alias string) {
+	checkShared(t, alias)
 	t.Alias = alias
 }
 
@@ -236,8 +327,10 @@ func (t *FloatType) Def() string {
 	return t.Kind.String()
 }
 
-// SetAlias sets the type name alias of the type.
+// SetAlias sets the type name alias of the type. It panics if the type is a
+// shared convenience type.
 func (t *FloatType) SetAlias(alias string) {
+	checkShared(t, alias)
 	t.Alias = alias
 }
 
@@ -286,8 +379,10 @@ func (t *MMXType) Def() string {
 	return "x86_mmx"
 }
 
-// SetAlias sets the type name alias of the type.
+// SetAlias sets the type name alias of the type. It panics if the type is a
+// shared convenience type.
 func (t *MMXType) SetAlias(alias string) {
+	checkShared(t, alias)
 	t.Alias = alias
 }
 
@@ -337,8 +432,10 @@ func (t *PointerType) Def() string {
 	return buf.String()
 }
 
-// SetAlias sets the type name alias of the type.
+// SetAlias sets the type name alias of the type. It panics if the type is a
+// shared convenience type.
 func (t *PointerType) SetAlias(alias string) {
+	checkShared(t, alias)
 	t.Alias = alias
 }
 
@@ -357,10 +454,13 @@ func (a AddrSpace) String() string {
 type VectorType struct {
 	// Type name alias; or empty if not present.
 	Alias string
-	// Vector length.
+	// Vector length; or minimum vector length of scalable vectors.
 	Len int64
 	// Element type.
 	ElemType Type
+	// Scalable vector; the vector length is a runtime multiple (vscale) of
+	// Len.
+	Scalable bool
 }
 
 // NewVector returns a new vector type based on the given vector length and
@@ -372,10 +472,20 @@ func NewVector(len int64, elemType Type) *VectorType {
 	}
 }
 
+// NewScalableVector returns a new scalable vector type based on the given
+// minimum vector length and element type.
+func NewScalableVector(len int64, elemType Type) *VectorType {
+	return &VectorType{
+		Len:      len,
+		ElemType: elemType,
+		Scalable: true,
+	}
+}
+
 // Equal reports whether t and u are of equal type.
 func (t *VectorType) Equal(u Type) bool {
 	if u, ok := u.(*VectorType); ok {
-		if t.Len != u.Len {
+		if t.Len != u.Len || t.Scalable != u.Scalable {
 			return false
 		}
 		return t.ElemType.Equal(u.ElemType)
@@ -393,7 +503,10 @@ func (t *VectorType) String() string {
 
 // Def returns the LLVM syntax representation of the definition of the type.
 func (t *VectorType) Def() string {
-	// "<" int_lit "x" Type ">"
+	// "<" OptVScale int_lit "x" Type ">"
+	if t.Scalable {
+		return fmt.Sprintf("<vscale x %d x %v>", t.Len, t.ElemType)
+	}
 	return fmt.Sprintf("<%d x %v>", t.Len, t.ElemType)
 }
 
@@ -432,8 +545,10 @@ func (t *LabelType) Def() string {
 	return "label"
 }
 
-// SetAlias sets the type name alias of the type.
+// SetAlias sets the type name alias of the type. It panics if the type is a
+// shared convenience type.
 func (t *LabelType) SetAlias(alias string) {
+	checkShared(t, alias)
 	t.Alias = alias
 }
 
@@ -467,8 +582,10 @@ func (t *TokenType) Def() string {
 	return "token"
 }
 
-// SetAlias sets the type name alias of the type.
+// SetAlias sets the type name alias of the type. It panics if the type is a
+// shared convenience type.
 func (t *TokenType) SetAlias(alias string) {
+	checkShared(t, alias)
 	t.Alias = alias
 }
 
@@ -502,8 +619,10 @@ func (t *MetadataType) Def() string {
 	return "metadata"
 }
 
-// SetAlias sets the type name alias of the type.
+// SetAlias sets the type name alias of the type. It panics if the type is a
+// shared convenience type.
 func (t *MetadataType) SetAlias(alias string) {
+	checkShared(t, alias)
 	t.Alias = alias
 }
 
@@ -645,6 +764,40 @@ func (t *StructType) SetAlias(alias string) {
 	t.Alias = alias
 }
 
+// --- [ Invalid types ] -------------------------------------------------------
+
+// InvalidType is the type of invalid values; e.g. of instructions with
+// operands of unexpected types, the type of which may not be determined. Its
+// string representation "<invalid>" is not valid LLVM IR, but allows modules
+// with invalid values to be printed for debugging.
+type InvalidType struct {
+	// Type name alias; or empty if not present.
+	Alias string
+}
+
+// Equal reports whether t and u are of equal type. Invalid types are not equal
+// to any type.
+func (t *InvalidType) Equal(u Type) bool {
+	return false
+}
+
+// String returns the string representation of the invalid type.
+func (t *InvalidType) String() string {
+	return t.Def()
+}
+
+// Def returns the string representation of the definition of the type.
+func (t *InvalidType) Def() string {
+	return "<invalid>"
+}
+
+// SetAlias sets the type name alias of the type. It panics if the type is a
+// shared convenience type.
+func (t *InvalidType) SetAlias(alias string) {
+	checkShared(t, alias)
+	t.Alias = alias
+}
+
 // Convenience functions.
 
 // IsPointer reports whether the given type is a pointer type.
@@ -652,3 +805,19 @@ func IsPointer(t Type) bool {
 	_, ok := t.(*PointerType)
 	return ok
 }
+
+// IsVector reports whether the given type is a vector type.
+func IsVector(t Type) bool {
+	_, ok := t.(*VectorType)
+	return ok
+}
+
+// ### [ Helper functions ] ####################################################
+
+// checkShared panics if the given type is a shared convenience type, the alias
+// of which may not be set.
+func checkShared(t Type, alias string) {
+	if shared[t] {
+		panic(fmt.Errorf("unable to set type name alias %q of shared convenience type %v; copy the type first", alias, t))
+	}
+}
@@ -1,5 +1,7 @@
 package types
 
+import "testing"
+
 // Assert that each type implements the types.Type interface.
 var (
 	_ Type = (*VoidType)(nil)
@@ -14,4 +16,22 @@ var (
 	_ Type = (*MetadataType)(nil)
 	_ Type = (*ArrayType)(nil)
 	_ Type = (*StructType)(nil)
+	_ Type = (*InvalidType)(nil)
 )
+
+func TestSharedSetAlias(t *testing.T) {
+	defer func() {
+		if e := recover(); e == nil {
+			t.Errorf("expected panic on setting alias of shared type")
+		}
+		if I32.Alias != "" {
+			t.Errorf("alias of shared type modified; got %q", I32.Alias)
+		}
+	}()
+	c := Copy(I32)
+	c.SetAlias("foo")
+	if IsShared(c) || c.String() != "%foo" || I32.String() != "i32" {
+		t.Errorf("invalid copy of shared type; got %v (shared %v)", c, IsShared(c))
+	}
+	I32.SetAlias("foo")
+}
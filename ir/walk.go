@@ -0,0 +1,155 @@
+package ir
+
+import (
+	"reflect"
+
+	"github.com/llir/l/ir/value"
+)
+
+// === [ Walk ] ================================================================
+
+// Walk traverses the given IR node in depth-first order, invoking visit for
+// each node encountered. If visit returns false, the children of the node are
+// skipped.
+//
+// The traversal order is as follows.
+//
+//    *ir.Module: global variables, raw global entities, functions, attribute
+//    group definitions
+//    *ir.Global: initial value (if present), metadata attachments
+//    *ir.Function: parameters, basic blocks, prefix, prologue, personality,
+//    metadata attachments
+//    *ir.BasicBlock: instructions, terminator
+//    instructions, terminators and constants: operands and metadata
+//    attachments, in the order of their struct fields
+//    custom instructions: operands, in the order of Operands
+//
+// Named values used as operands (e.g. instructions, basic blocks, parameters,
+// global variables and functions) are visited but not descended into, as they
+// are visited in full at their point of definition. Constants used as
+// operands (e.g. constant expressions, arrays and structures) are descended
+// into.
+func Walk(node interface{}, visit func(n interface{}) bool) {
+	if !visit(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *Module:
+		for _, g := range n.Globals {
+			Walk(g, visit)
+		}
+		for _, g := range n.RawGlobals {
+			Walk(g, visit)
+		}
+		for _, f := range n.Funcs {
+			Walk(f, visit)
+		}
+		for _, a := range n.AttrGroupDefs {
+			Walk(a, visit)
+		}
+	case *Global:
+		if n.Init != nil {
+			walkOperand(n.Init, visit)
+		}
+		for _, md := range n.Metadata {
+			visit(md)
+		}
+	case *Function:
+		for _, param := range n.Params {
+			Walk(param, visit)
+		}
+		for _, block := range n.Blocks {
+			Walk(block, visit)
+		}
+		for _, c := range []Constant{n.Prefix, n.Prologue, n.Personality} {
+			if c != nil {
+				walkOperand(c, visit)
+			}
+		}
+		for _, md := range n.Metadata {
+			visit(md)
+		}
+	case *BasicBlock:
+		for _, inst := range n.Insts {
+			Walk(inst, visit)
+		}
+		if n.Term != nil {
+			Walk(n.Term, visit)
+		}
+	case *Param, *AttrGroupDef, *RawGlobal:
+		// no children.
+	case CustomInst:
+		for _, x := range n.Operands() {
+			if *x != nil {
+				walkOperand(*x, visit)
+			}
+		}
+	default:
+		walkFields(reflect.ValueOf(node), visit)
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// walkOperand visits the given operand, and descends into its operands unless
+// it is a named value.
+func walkOperand(x value.Value, visit func(n interface{}) bool) {
+	if _, ok := x.(value.Named); ok {
+		visit(x)
+		return
+	}
+	Walk(x, visit)
+}
+
+// walkFields walks the operands and metadata attachments stored in the struct
+// fields of the given node.
+func walkFields(v reflect.Value, visit func(n interface{}) bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		switch t.Field(i).Name {
+		case "Typ", "Successors":
+			// Skip cached types and successors.
+			continue
+		}
+		walkField(v.Field(i), visit)
+	}
+}
+
+// walkField walks the operands and metadata attachments stored in the given
+// struct field value.
+func walkField(v reflect.Value, visit func(n interface{}) bool) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() || !v.CanInterface() {
+			return
+		}
+		if x, ok := v.Interface().(value.Value); ok {
+			walkOperand(x, visit)
+			return
+		}
+		switch n := v.Interface().(type) {
+		case *Incoming, *Case, *Index, *OperandBundle, *Clause:
+			Walk(n, visit)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkField(v.Index(i), visit)
+		}
+	case reflect.Struct:
+		if !v.CanInterface() {
+			return
+		}
+		if md, ok := v.Interface().(MetadataAttachment); ok {
+			visit(md)
+		}
+	}
+}
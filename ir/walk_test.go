@@ -0,0 +1,43 @@
+package ir
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/llir/l/ir/types"
+)
+
+func TestWalk(t *testing.T) {
+	m := &Module{}
+	g := m.NewGlobalDef("g", NewInt(types.I32, 1))
+	x := NewParam(types.I32, "x")
+	f := m.NewFunc("f", types.I32, x)
+	entry := NewBlock("entry")
+	add := entry.NewAdd(x, NewAddExpr(NewInt(types.I32, 2), NewInt(types.I32, 3)))
+	entry.NewRet(add)
+	f.Blocks = append(f.Blocks, entry)
+	var got []string
+	Walk(m, func(n interface{}) bool {
+		got = append(got, fmt.Sprintf("%T", n))
+		// Skip the children of global variables.
+		return n != g
+	})
+	want := []string{
+		"*ir.Module",
+		"*ir.Global",
+		"*ir.Function",
+		"*ir.Param",
+		"*ir.BasicBlock",
+		"*ir.InstAdd",
+		"*ir.Param",
+		"*ir.ExprAdd",
+		"*ir.ConstInt",
+		"*ir.ConstInt",
+		"*ir.TermRet",
+		"*ir.InstAdd",
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("walk order mismatch; expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,193 @@
+package ir
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// === [ Printing options ] ====================================================
+
+// Printer prints the LLVM syntax representation of modules, functions and
+// basic blocks with printing options; the zero value prints the same as their
+// Def methods. A printer may be used concurrently by multiple goroutines.
+type Printer struct {
+	// Print the source locations of instructions and terminators, as recorded
+	// if RecordLocations of the module is set, as trailing comments (e.g.
+	// `; created at foo.go:123`).
+	Locations bool
+}
+
+// Module returns the LLVM syntax representation of the given module.
+func (p Printer) Module(m *Module) string {
+	return m.def(p)
+}
+
+// Func returns the LLVM syntax representation of the given function definition
+// or declaration.
+func (p Printer) Func(f *Function) string {
+	return f.def(p)
+}
+
+// Block returns the LLVM syntax representation of the given basic block
+// definition.
+func (p Printer) Block(block *BasicBlock) string {
+	return block.def(p)
+}
+
+// === [ Incremental emission ] ================================================
+
+// WriteContext is the context of incremental emission of the global variables
+// and functions of a module (e.g. for a REPL), which keeps track of the module
+// header, type definitions, attribute group definitions and metadata
+// definitions already written; so that each is written once, before or
+// alongside the first entity which needs it.
+type WriteContext struct {
+	// Module of the written entities.
+	m *Module
+	// Module header written.
+	header bool
+	// Type definitions written.
+	typeDefs map[types.Type]bool
+	// Attribute group definitions written.
+	attrGroupDefs map[*AttrGroupDef]bool
+	// Metadata definitions written; metadata ID -> written.
+	metadataDefs map[int64]bool
+}
+
+// NewWriteContext returns a new context for incremental emission of the
+// entities of the given module.
+func NewWriteContext(m *Module) *WriteContext {
+	return &WriteContext{
+		m:             m,
+		typeDefs:      make(map[types.Type]bool),
+		attrGroupDefs: make(map[*AttrGroupDef]bool),
+		metadataDefs:  make(map[int64]bool),
+	}
+}
+
+// WriteDef writes the LLVM syntax representation of the function definition
+// or declaration to w, as part of the incremental emission of its module.
+// Local IDs are assigned to unnamed local variables and basic blocks of
+// function definitions.
+//
+// The module header and the type definitions of the module not yet written
+// are written before the function. The attribute group definitions of the
+// module not yet written, and the metadata definitions referenced by the
+// function not yet written, are written after the function.
+func (f *Function) WriteDef(w io.Writer, ctx *WriteContext) error {
+	if err := f.AssignIDs(); err != nil {
+		return errors.WithStack(err)
+	}
+	return ctx.write(w, f, f.Comments, f.Def)
+}
+
+// WriteDef writes the LLVM syntax representation of the global variable
+// definition or declaration to w, as part of the incremental emission of its
+// module, as described by Function.WriteDef.
+func (g *Global) WriteDef(w io.Writer, ctx *WriteContext) error {
+	return ctx.write(w, g, g.Comments, g.Def)
+}
+
+// ### [ Helper functions ] ####################################################
+
+// write writes the given global variable or function to w, preceded by the
+// module header and type definitions not yet written, and followed by the
+// attribute group definitions and metadata definitions not yet written.
+func (ctx *WriteContext) write(w io.Writer, node interface{}, comments []string, def func() string) error {
+	buf := &strings.Builder{}
+	if !ctx.header {
+		ctx.header = true
+		writeHeader(buf, ctx.m)
+	}
+	for _, t := range ctx.m.TypeDefs {
+		if !ctx.typeDefs[t] {
+			ctx.typeDefs[t] = true
+			fmt.Fprintf(buf, "%s = type %s\n", t, t.Def())
+		}
+	}
+	writeComments(buf, "", comments)
+	fmt.Fprintln(buf, def())
+	for _, a := range ctx.m.AttrGroupDefs {
+		if !ctx.attrGroupDefs[a] {
+			ctx.attrGroupDefs[a] = true
+			fmt.Fprintln(buf, a.Def())
+		}
+	}
+	for _, md := range referencedMetadata(node) {
+		if !ctx.metadataDefs[md.ID()] {
+			ctx.metadataDefs[md.ID()] = true
+			fmt.Fprintf(buf, "%s = %s\n", md, md.Def())
+		}
+	}
+	_, err := io.WriteString(w, buf.String())
+	return errors.WithStack(err)
+}
+
+// writeHeader writes the module header (source filename, data layout and
+// target triple) of the given module to buf.
+func writeHeader(buf *strings.Builder, m *Module) {
+	// Source filename.
+	if len(m.SourceFilename) > 0 {
+		// "source_filename" "=" StringLit
+		fmt.Fprintf(buf, "source_filename = %s\n", quote(m.SourceFilename))
+	}
+	// Data layout.
+	if len(m.DataLayout) > 0 {
+		// "target" "datalayout" "=" StringLit
+		fmt.Fprintf(buf, "target datalayout = %s\n", quote(m.DataLayout))
+	}
+	// Target triple.
+	if len(m.TargetTriple) > 0 {
+		// "target" "triple" "=" StringLit
+		fmt.Fprintf(buf, "target triple = %s\n", quote(m.TargetTriple))
+	}
+}
+
+// referencedMetadata returns the metadata nodes with metadata IDs referenced
+// by the metadata attachments of the given IR node (e.g. function or global
+// variable), directly or through other metadata nodes, in order of first
+// reference.
+func referencedMetadata(node interface{}) []MDNode {
+	var nodes []MDNode
+	seen := make(map[Metadata]bool)
+	var visit func(md Metadata)
+	visit = func(md Metadata) {
+		if md == nil || seen[md] {
+			return
+		}
+		seen[md] = true
+		switch md := md.(type) {
+		case *MDTuple:
+			if md.ID() >= 0 {
+				nodes = append(nodes, md)
+			}
+			for _, field := range md.Fields {
+				visit(field)
+			}
+		case *MDSpecialized:
+			if md.ID() >= 0 {
+				nodes = append(nodes, md)
+			}
+			for _, operand := range md.Operands() {
+				visit(operand)
+			}
+		case MDNode:
+			// Metadata nodes defined outside of the ir package (e.g. DWARF
+			// expressions).
+			if md.ID() >= 0 {
+				nodes = append(nodes, md)
+			}
+		}
+	}
+	Walk(node, func(n interface{}) bool {
+		if md, ok := n.(MetadataAttachment); ok {
+			visit(md.Node)
+		}
+		return true
+	})
+	return nodes
+}
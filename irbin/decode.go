@@ -0,0 +1,272 @@
+package irbin
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/llir/l/internal/enc"
+	"github.com/llir/l/ir"
+	"github.com/pkg/errors"
+)
+
+// maxLen is the maximum length of decoded strings and slices, to guard against
+// corrupt data.
+const maxLen = 1 << 30
+
+// chunkSize is the maximum number of bytes of a string allocated before being
+// read; longer strings grow as their contents are read, so that a corrupt
+// length prefix does not allocate more memory than the input provides.
+const chunkSize = 1 << 16
+
+// decoder is a decoder of object graphs, as encoded by encoder.
+type decoder struct {
+	r *bufio.Reader
+	// Decoded pointers, indexed by object ID; including the shared values.
+	refs []reflect.Value
+	// Concrete types of interface values, indexed by type ID.
+	types []reflect.Type
+}
+
+// newDecoder returns a new decoder reading from r.
+func newDecoder(r *bufio.Reader) *decoder {
+	d := &decoder{r: r}
+	for _, x := range shared {
+		d.refs = append(d.refs, reflect.ValueOf(x))
+	}
+	return d
+}
+
+// decode decodes a value into the given settable value.
+func (d *decoder) decode(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.SetBool(b != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := binary.ReadVarint(d.r)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Type() == mdKindType {
+			name, err := d.str()
+			if err != nil {
+				return err
+			}
+			// Only register custom kinds of valid names, as the name is
+			// registered for the lifetime of the program.
+			if !validMDKindName(name) {
+				return errors.Errorf("invalid metadata attachment kind name %q", name)
+			}
+			v.SetUint(uint64(ir.RegisterMDKind(name)))
+			return nil
+		}
+		x, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		v.SetUint(x)
+	case reflect.Float32, reflect.Float64:
+		var b [8]byte
+		if _, err := io.ReadFull(d.r, b[:]); err != nil {
+			return errors.WithStack(err)
+		}
+		v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(b[:])))
+	case reflect.String:
+		s, err := d.str()
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		n, err := d.len()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// nil slice.
+			return nil
+		}
+		// Grow the slice as elements are decoded, rather than trusting the
+		// length prefix for allocation.
+		s := reflect.MakeSlice(v.Type(), 0, 0)
+		zero := reflect.Zero(v.Type().Elem())
+		for i := 0; i < int(n-1); i++ {
+			s = reflect.Append(s, zero)
+			if err := d.decode(s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		return d.decodeStruct(v)
+	case reflect.Ptr:
+		return d.decodePtr(v)
+	case reflect.Interface:
+		return d.decodeInterface(v)
+	default:
+		return errors.Errorf("support for decoding values of type %v not yet implemented", v.Type())
+	}
+	return nil
+}
+
+// decodeStruct decodes a struct value into the given settable value.
+func (d *decoder) decodeStruct(v reflect.Value) error {
+	switch v.Type() {
+	case bigIntType:
+		buf, err := d.str()
+		if err != nil {
+			return err
+		}
+		return errors.WithStack(v.Addr().Interface().(*big.Int).GobDecode([]byte(buf)))
+	case bigFloatType:
+		buf, err := d.str()
+		if err != nil {
+			return err
+		}
+		return errors.WithStack(v.Addr().Interface().(*big.Float).GobDecode([]byte(buf)))
+	}
+	for _, i := range fields(v.Type()) {
+		if err := d.decode(v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodePtr decodes a pointer value into the given settable value.
+func (d *decoder) decodePtr(v reflect.Value) error {
+	x, err := d.uvarint()
+	if err != nil {
+		return err
+	}
+	switch {
+	case x == 0:
+		// nil pointer.
+		v.Set(reflect.Zero(v.Type()))
+	case x == 1:
+		// Record the pointer before decoding the pointed-to value, as it may
+		// refer back to the pointer (e.g. a phi instruction using itself).
+		p := reflect.New(v.Type().Elem())
+		d.refs = append(d.refs, p)
+		v.Set(p)
+		return d.decode(p.Elem())
+	default:
+		id := x - 2
+		if id >= uint64(len(d.refs)) {
+			return errors.Errorf("invalid object ID %d; expected < %d", id, len(d.refs))
+		}
+		p := d.refs[id]
+		if p.Type() != v.Type() {
+			return errors.Errorf("type mismatch of object %d; expected %v, got %v", id, v.Type(), p.Type())
+		}
+		v.Set(p)
+	}
+	return nil
+}
+
+// decodeInterface decodes an interface value into the given settable value.
+func (d *decoder) decodeInterface(v reflect.Value) error {
+	x, err := d.uvarint()
+	if err != nil {
+		return err
+	}
+	var t reflect.Type
+	switch {
+	case x == 0:
+		// nil interface.
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	case x == 1:
+		name, err := d.str()
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if t, ok = lookupType(name); !ok {
+			return errors.Errorf("type %q not registered", name)
+		}
+		d.types = append(d.types, t)
+	default:
+		id := x - 2
+		if id >= uint64(len(d.types)) {
+			return errors.Errorf("invalid type ID %d; expected < %d", id, len(d.types))
+		}
+		t = d.types[id]
+	}
+	if !t.AssignableTo(v.Type()) {
+		return errors.Errorf("type %v not assignable to %v", t, v.Type())
+	}
+	c := reflect.New(t).Elem()
+	if err := d.decode(c); err != nil {
+		return err
+	}
+	v.Set(c)
+	return nil
+}
+
+// uvarint decodes an unsigned integer.
+func (d *decoder) uvarint() (uint64, error) {
+	x, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return x, nil
+}
+
+// len decodes the length of a string or slice.
+func (d *decoder) len() (uint64, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > maxLen {
+		return 0, errors.Errorf("invalid length %d; exceeds maximum length %d", n, uint64(maxLen))
+	}
+	return n, nil
+}
+
+// str decodes a string.
+func (d *decoder) str() (string, error) {
+	n, err := d.len()
+	if err != nil {
+		return "", err
+	}
+	if n <= chunkSize {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(d.r, buf); err != nil {
+			return "", errors.WithStack(err)
+		}
+		return string(buf), nil
+	}
+	buf := &strings.Builder{}
+	buf.Grow(chunkSize)
+	if _, err := io.CopyN(buf, d.r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", errors.WithStack(err)
+	}
+	return buf.String(), nil
+}
+
+// validMDKindName reports whether the given metadata attachment kind name
+// (without '!' prefix) is a valid metadata name, which requires no escaping.
+func validMDKindName(name string) bool {
+	return len(name) > 0 && enc.MetadataName(name) == "!"+name
+}
@@ -0,0 +1,195 @@
+package irbin
+
+import (
+	"bufio"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"reflect"
+
+	"github.com/llir/l/ir"
+	"github.com/pkg/errors"
+)
+
+// encoder is an encoder of object graphs.
+//
+// Values are encoded based on their kind, as follows.
+//
+//    bool: byte 0 or 1
+//    signed integers: varint
+//    unsigned integers: uvarint; metadata attachment kinds by name
+//    floating-point numbers: 8 bytes IEEE 754 (little-endian)
+//    strings: uvarint length, bytes
+//    slices: uvarint length+1 (0 for nil), elements
+//    arrays: elements
+//    structs: encoded fields in order; big.Int and big.Float in gob encoding
+//    pointers: uvarint 0 for nil, 1 followed by the pointed-to value at first
+//    occurrence, and ID+2 at later occurrences
+//    interfaces: uvarint 0 for nil, 1 followed by the registered type name at
+//    first occurrence of a concrete type and ID+2 at later occurrences,
+//    followed by the concrete value
+type encoder struct {
+	w *bufio.Writer
+	// Object IDs of pointers, including the shared values.
+	refs map[interface{}]uint64
+	// Type IDs of concrete types of interface values.
+	typeIDs map[reflect.Type]uint64
+	// Scratch buffer of varints.
+	buf [binary.MaxVarintLen64]byte
+}
+
+// newEncoder returns a new encoder writing to w.
+func newEncoder(w *bufio.Writer) *encoder {
+	e := &encoder{
+		w:       w,
+		refs:    make(map[interface{}]uint64),
+		typeIDs: make(map[reflect.Type]uint64),
+	}
+	for i, x := range shared {
+		e.refs[x] = uint64(i)
+	}
+	return e
+}
+
+// encode encodes the given value.
+func (e *encoder) encode(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.w.WriteByte(1)
+		} else {
+			e.w.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.varint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Type() == mdKindType {
+			// Custom metadata attachment kinds are process specific; encode by
+			// name.
+			e.str(v.Interface().(ir.MDKind).String())
+			return nil
+		}
+		e.uvarint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.Float()))
+		e.w.Write(b[:])
+	case reflect.String:
+		e.str(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.uvarint(0)
+			return nil
+		}
+		e.uvarint(uint64(v.Len()) + 1)
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	case reflect.Ptr:
+		return e.encodePtr(v)
+	case reflect.Interface:
+		return e.encodeInterface(v)
+	default:
+		return errors.Errorf("support for encoding values of type %v not yet implemented", v.Type())
+	}
+	return nil
+}
+
+// encodeStruct encodes the given struct value.
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	switch v.Type() {
+	case bigIntType:
+		buf, err := v.Addr().Interface().(*big.Int).GobEncode()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		e.str(string(buf))
+		return nil
+	case bigFloatType:
+		buf, err := v.Addr().Interface().(*big.Float).GobEncode()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		e.str(string(buf))
+		return nil
+	}
+	for _, i := range fields(v.Type()) {
+		if err := e.encode(v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodePtr encodes the given pointer value.
+func (e *encoder) encodePtr(v reflect.Value) error {
+	if v.IsNil() {
+		e.uvarint(0)
+		return nil
+	}
+	x := v.Interface()
+	if id, ok := e.refs[x]; ok {
+		e.uvarint(id + 2)
+		return nil
+	}
+	e.refs[x] = uint64(len(e.refs))
+	e.uvarint(1)
+	elem := v.Elem()
+	if g, ok := x.(*ir.Global); ok && g.Init == nil && g.LazyInit != nil {
+		// Materialize lazy initializer.
+		c := *g
+		c.Init = g.LazyInit()
+		elem = reflect.ValueOf(&c).Elem()
+	}
+	return e.encode(elem)
+}
+
+// encodeInterface encodes the given interface value.
+func (e *encoder) encodeInterface(v reflect.Value) error {
+	if v.IsNil() {
+		e.uvarint(0)
+		return nil
+	}
+	x := v.Elem()
+	t := x.Type()
+	if id, ok := e.typeIDs[t]; ok {
+		e.uvarint(id + 2)
+	} else {
+		name, ok := lookupName(t)
+		if !ok {
+			return errors.Errorf("type %v not registered", t)
+		}
+		e.typeIDs[t] = uint64(len(e.typeIDs))
+		e.uvarint(1)
+		e.str(name)
+	}
+	return e.encode(x)
+}
+
+// uvarint encodes the given unsigned integer.
+func (e *encoder) uvarint(x uint64) {
+	n := binary.PutUvarint(e.buf[:], x)
+	e.w.Write(e.buf[:n])
+}
+
+// varint encodes the given signed integer.
+func (e *encoder) varint(x int64) {
+	n := binary.PutVarint(e.buf[:], x)
+	e.w.Write(e.buf[:n])
+}
+
+// str encodes the given string.
+func (e *encoder) str(s string) {
+	e.uvarint(uint64(len(s)))
+	e.w.WriteString(s)
+}
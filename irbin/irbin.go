@@ -0,0 +1,394 @@
+// Package irbin implements a compact binary encoding of LLVM IR modules, for
+// build systems to cache generated IR between runs.
+//
+// The encoding is native to this package; it is not LLVM bitcode. Modules are
+// encoded as object graphs, in which each IR node (e.g. instruction, basic
+// block, type or metadata node) is encoded once at its first occurrence and
+// referenced by ID at later occurrences. The encoding is versioned; Decode
+// rejects data of other versions, which should be regenerated.
+//
+// The concrete types of interface values (e.g. the operands of instructions)
+// are identified by registered names. The types of the ir package and its
+// subpackages are registered by default; custom instructions (see
+// ir.CustomInst) and metadata nodes defined outside of the ir package must be
+// registered by Register before encoding or decoding.
+package irbin
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"sync"
+
+	"github.com/llir/l/debuginfo"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// Version is the version of the binary encoding, as recorded after the magic
+// header of encoded modules. The version is incremented on incompatible
+// changes to the encoding, including changes to the set of shared values.
+const Version = 1
+
+// magic is the magic header of encoded modules.
+const magic = "\x00llirbin"
+
+// === [ Encoding ] ============================================================
+
+// Encode writes the binary encoding of the given module to w.
+//
+// Lazy initializers of global variables are materialized, and encoded as the
+// initial values of the global variables. Source locations recorded by the
+// builder methods are not encoded.
+func Encode(w io.Writer, m *ir.Module) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(magic)
+	e := newEncoder(bw)
+	e.uvarint(Version)
+	if err := e.encode(reflect.ValueOf(m)); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Decode reads a module from the binary encoding of r.
+//
+// The symbol table of the decoded module is rebuilt from its global variables
+// and functions, as used by Lookup and the module builder methods. The decoded
+// module is not associated with a context.
+func Decode(r io.Reader) (*ir.Module, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, header); err != nil || string(header) != magic {
+		return nil, errors.New("invalid magic header of binary encoded module")
+	}
+	d := newDecoder(br)
+	version, err := d.uvarint()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if version != Version {
+		return nil, errors.Errorf("unsupported version of binary encoded module; expected %d, got %d", Version, version)
+	}
+	var m *ir.Module
+	if err := d.decode(reflect.ValueOf(&m).Elem()); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if m == nil {
+		return nil, errors.New("invalid binary encoded module; nil module")
+	}
+	// Rebuild symbol table.
+	for _, g := range m.Globals {
+		if err := m.Rename(g, g.Name()); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	for _, f := range m.Funcs {
+		if err := m.Rename(f, f.Name()); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return m, nil
+}
+
+// --- [ Registry ] ------------------------------------------------------------
+
+// registry is the registry of concrete types of interface values, as
+// identified by name in the binary encoding.
+var registry = struct {
+	sync.RWMutex
+	// Type names, indexed by type.
+	names map[reflect.Type]string
+	// Types, indexed by name.
+	types map[string]reflect.Type
+}{
+	names: make(map[reflect.Type]string),
+	types: make(map[string]reflect.Type),
+}
+
+// Register registers the concrete type of the given value, to be encoded and
+// decoded when stored in interface values (e.g. custom instructions used as
+// operands or stored in basic blocks). The type is identified by its package
+// path and name; e.g. "*github.com/foo/bar.InstFoo". Register is safe for
+// concurrent use.
+func Register(x interface{}) {
+	t := reflect.TypeOf(x)
+	name := typeName(t)
+	registry.Lock()
+	defer registry.Unlock()
+	if prev, ok := registry.types[name]; ok && prev != t {
+		panic(fmt.Errorf("irbin: name %q of type %v already registered to type %v", name, t, prev))
+	}
+	registry.names[t] = name
+	registry.types[name] = t
+}
+
+// lookupName returns the registered name of the given type. The boolean return
+// value indicates success.
+func lookupName(t reflect.Type) (string, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	name, ok := registry.names[t]
+	return name, ok
+}
+
+// lookupType returns the registered type of the given name. The boolean return
+// value indicates success.
+func lookupType(name string) (reflect.Type, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	t, ok := registry.types[name]
+	return t, ok
+}
+
+// typeName returns the name of the given type, qualified by package path.
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		return "*" + typeName(t.Elem())
+	}
+	if len(t.PkgPath()) > 0 {
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
+}
+
+func init() {
+	for _, x := range []interface{}{
+		// Basic types of metadata field values.
+		false,
+		int64(0),
+		uint64(0),
+		"",
+		// Types.
+		&types.VoidType{},
+		&types.FuncType{},
+		&types.IntType{},
+		&types.FloatType{},
+		&types.MMXType{},
+		&types.PointerType{},
+		&types.VectorType{},
+		&types.LabelType{},
+		&types.TokenType{},
+		&types.MetadataType{},
+		&types.ArrayType{},
+		&types.StructType{},
+		&types.InvalidType{},
+		// Global values and local values.
+		&ir.Global{},
+		&ir.Function{},
+		&ir.GlobalRef{},
+		&ir.RawGlobal{},
+		&ir.Param{},
+		&ir.Arg{},
+		&ir.BasicBlock{},
+		// Constants.
+		&ir.ConstInt{},
+		&ir.ConstFloat{},
+		&ir.ConstNull{},
+		&ir.ConstNone{},
+		&ir.ConstStruct{},
+		&ir.ConstArray{},
+		&ir.ConstCharArray{},
+		&ir.ConstVector{},
+		&ir.ConstZeroInitializer{},
+		&ir.ConstUndef{},
+		&ir.ConstBlockAddress{},
+		// Constant expressions.
+		&ir.ExprAdd{},
+		&ir.ExprFAdd{},
+		&ir.ExprSub{},
+		&ir.ExprFSub{},
+		&ir.ExprMul{},
+		&ir.ExprFMul{},
+		&ir.ExprUDiv{},
+		&ir.ExprSDiv{},
+		&ir.ExprFDiv{},
+		&ir.ExprURem{},
+		&ir.ExprSRem{},
+		&ir.ExprFRem{},
+		&ir.ExprShl{},
+		&ir.ExprLShr{},
+		&ir.ExprAShr{},
+		&ir.ExprAnd{},
+		&ir.ExprOr{},
+		&ir.ExprXor{},
+		&ir.ExprExtractElement{},
+		&ir.ExprInsertElement{},
+		&ir.ExprShuffleVector{},
+		&ir.ExprExtractValue{},
+		&ir.ExprInsertValue{},
+		&ir.ExprGetElementPtr{},
+		&ir.ExprTrunc{},
+		&ir.ExprZExt{},
+		&ir.ExprSExt{},
+		&ir.ExprFPTrunc{},
+		&ir.ExprFPExt{},
+		&ir.ExprFPToUI{},
+		&ir.ExprFPToSI{},
+		&ir.ExprUIToFP{},
+		&ir.ExprSIToFP{},
+		&ir.ExprPtrToInt{},
+		&ir.ExprIntToPtr{},
+		&ir.ExprBitCast{},
+		&ir.ExprAddrSpaceCast{},
+		&ir.ExprICmp{},
+		&ir.ExprFCmp{},
+		&ir.ExprSelect{},
+		// Instructions.
+		&ir.InstFNeg{},
+		&ir.InstAdd{},
+		&ir.InstFAdd{},
+		&ir.InstSub{},
+		&ir.InstFSub{},
+		&ir.InstMul{},
+		&ir.InstFMul{},
+		&ir.InstUDiv{},
+		&ir.InstSDiv{},
+		&ir.InstFDiv{},
+		&ir.InstURem{},
+		&ir.InstSRem{},
+		&ir.InstFRem{},
+		&ir.InstShl{},
+		&ir.InstLShr{},
+		&ir.InstAShr{},
+		&ir.InstAnd{},
+		&ir.InstOr{},
+		&ir.InstXor{},
+		&ir.InstExtractElement{},
+		&ir.InstInsertElement{},
+		&ir.InstShuffleVector{},
+		&ir.InstExtractValue{},
+		&ir.InstInsertValue{},
+		&ir.InstAlloca{},
+		&ir.InstLoad{},
+		&ir.InstStore{},
+		&ir.InstFence{},
+		&ir.InstCmpXchg{},
+		&ir.InstAtomicRMW{},
+		&ir.InstGetElementPtr{},
+		&ir.InstTrunc{},
+		&ir.InstZExt{},
+		&ir.InstSExt{},
+		&ir.InstFPTrunc{},
+		&ir.InstFPExt{},
+		&ir.InstFPToUI{},
+		&ir.InstFPToSI{},
+		&ir.InstUIToFP{},
+		&ir.InstSIToFP{},
+		&ir.InstPtrToInt{},
+		&ir.InstIntToPtr{},
+		&ir.InstBitCast{},
+		&ir.InstAddrSpaceCast{},
+		&ir.InstICmp{},
+		&ir.InstFCmp{},
+		&ir.InstPhi{},
+		&ir.InstSelect{},
+		&ir.InstFreeze{},
+		&ir.InstCall{},
+		&ir.InstVAArg{},
+		&ir.InstLandingPad{},
+		&ir.InstCatchPad{},
+		&ir.InstCleanupPad{},
+		&ir.RawInst{},
+		// Terminators.
+		&ir.TermRet{},
+		&ir.TermBr{},
+		&ir.TermCondBr{},
+		&ir.TermSwitch{},
+		&ir.TermIndirectBr{},
+		&ir.TermInvoke{},
+		&ir.TermResume{},
+		&ir.TermCatchSwitch{},
+		&ir.TermCatchRet{},
+		&ir.TermCleanupRet{},
+		&ir.TermUnreachable{},
+		&ir.RawTerm{},
+		// Attributes.
+		enum.FuncAttr(0),
+		enum.ParamAttr(0),
+		ir.AttrString(""),
+		ir.AttrPair{},
+		&ir.AttrGroupDef{},
+		// Metadata.
+		&ir.MDTuple{},
+		&ir.MDSpecialized{},
+		&ir.MDString{},
+		&ir.MDValue{},
+		ir.MDEnum(""),
+		&debuginfo.DIExpression{},
+	} {
+		Register(x)
+	}
+}
+
+// --- [ Shared values ] -------------------------------------------------------
+
+// shared lists the values shared by all modules of the process (e.g.
+// types.I32 and ir.True), which are encoded by reference to preserve their
+// identity. The shared values are assigned the first object IDs, in order.
+var shared = []interface{}{
+	types.Void,
+	types.MMX,
+	types.Label,
+	types.Token,
+	types.Metadata,
+	types.Invalid,
+	types.I1,
+	types.I8,
+	types.I16,
+	types.I32,
+	types.I64,
+	types.Half,
+	types.Float,
+	types.Double,
+	types.X86FP80,
+	types.FP128,
+	types.PPCFP128,
+	types.I1Ptr,
+	types.I8Ptr,
+	types.I16Ptr,
+	types.I32Ptr,
+	types.I64Ptr,
+	ir.None,
+	ir.True,
+	ir.False,
+}
+
+// Types with custom encodings.
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	mdKindType   = reflect.TypeOf(ir.MDKind(0))
+)
+
+// ### [ Helper functions ] ####################################################
+
+// fieldCache caches the encoded fields of struct types.
+var fieldCache sync.Map // map[reflect.Type][]int
+
+// fields returns the indices of the encoded fields of the given struct type;
+// i.e. the exported fields other than functions.
+func fields(t reflect.Type) []int {
+	if indices, ok := fieldCache.Load(t); ok {
+		return indices.([]int)
+	}
+	var indices []int
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 || field.Type.Kind() == reflect.Func {
+			// Skip unexported fields and functions (e.g. lazy initializers of
+			// global variables).
+			continue
+		}
+		indices = append(indices, i)
+	}
+	fieldCache.Store(t, indices)
+	return indices
+}
@@ -0,0 +1,190 @@
+package irbin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/llir/l/debuginfo"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/llir/l/irgen"
+)
+
+func TestRoundTrip(t *testing.T) {
+	m := &ir.Module{SourceFilename: "foo.c"}
+	point := m.NewTypeDef("point", types.NewStruct(types.I32, types.I32))
+	m.NewGlobalDef("origin", ir.NewZeroInitializer(point))
+	m.NewGlobalLazy("lazy", types.NewArray(2, types.I64), func() ir.Constant {
+		return ir.NewArray(types.NewArray(2, types.I64), ir.NewInt(types.I64, 1), ir.NewInt(types.I64, 2))
+	})
+	g := m.NewFunc("g", types.I32, ir.NewParam(types.I32, "x"))
+	f := m.NewFunc("f", types.I32, ir.NewParam(types.I1, "c"))
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	exit := f.NewBlock("exit")
+	entry.NewBr(loop)
+	phi := loop.NewPhi(ir.NewIncoming(ir.NewInt(types.I32, 0), entry))
+	inc := loop.NewAdd(phi, ir.NewInt(types.I32, 1))
+	phi.Incs = append(phi.Incs, ir.NewIncoming(inc, loop))
+	call := loop.NewCall(g, inc)
+	ir.SetMetadata(call, ir.NewMetadataAttachment(ir.RegisterMDKind("irbin.test"), ir.NewMDString("x")))
+	loop.NewCondBr(f.Params[0], loop, exit)
+	exit.NewRet(call)
+	file := ir.NewSpecialized("DIFile", ir.NewMDField("filename", "foo.c"), ir.NewMDField("directory", "/tmp"))
+	m.NewMetadataDef(file)
+	unit := debuginfo.NewCompileUnit(m, "DW_LANG_C99", file, "irbin", debuginfo.EmissionFullDebug)
+	sp := debuginfo.NewSubprogram("f", file, file, 1, debuginfo.NewSubroutineType(nil), unit)
+	debuginfo.AttachSubprogram(m, f, sp)
+	expr := debuginfo.NewDIExpression().Deref()
+	m.NewMetadataDef(expr)
+	want := m.Def()
+	got := roundTrip(t, m)
+	if s := got.Def(); s != want {
+		t.Errorf("module mismatch; expected %q, got %q", want, s)
+	}
+	// Check identity of shared values and uses.
+	nf, ok := got.Lookup("f")
+	if !ok {
+		t.Fatalf("unable to locate function @f in decoded module")
+	}
+	nloop := nf.(*ir.Function).Blocks[1]
+	nphi := nloop.Insts[0].(*ir.InstPhi)
+	if nphi.Incs[1].X != nloop.Insts[1].(value.Value) {
+		t.Errorf("use of instruction not preserved")
+	}
+	if nphi.Type() != types.I32 {
+		t.Errorf("identity of shared type not preserved")
+	}
+}
+
+func TestRoundTripGenerate(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		m := irgen.Generate(seed, nil)
+		want := m.Def()
+		if got := roundTrip(t, m).Def(); got != want {
+			t.Errorf("seed %d: module mismatch; expected %q, got %q", seed, want, got)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, &ir.Module{}); err != nil {
+		t.Fatalf("unable to encode module; %+v", err)
+	}
+	data := buf.Bytes()
+	golden := []struct {
+		data []byte
+		err  string
+	}{
+		// i=0
+		{data: []byte("foo"), err: "invalid magic header"},
+		// i=1
+		{data: append([]byte(magic), Version+1), err: "unsupported version"},
+		// i=2
+		{data: data[:len(data)-1], err: "EOF"},
+	}
+	for i, g := range golden {
+		_, err := Decode(bytes.NewReader(g.data))
+		if err == nil || !strings.Contains(err.Error(), g.err) {
+			t.Errorf("i=%d: error mismatch; expected %q, got %v", i, g.err, err)
+		}
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	m := &ir.Module{SourceFilename: "foo.c"}
+	f := m.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	ret := entry.NewRet(nil)
+	ir.SetMetadata(ret, ir.NewMetadataAttachment(ir.RegisterMDKind("irbin.corrupt"), ir.NewMDString("x")))
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, m); err != nil {
+		t.Fatalf("unable to encode module; %+v", err)
+	}
+	data := buf.Bytes()
+	// replace returns a copy of data with the first occurrence of the encoded
+	// string s replaced by the given bytes.
+	replace := func(s string, new []byte) []byte {
+		old := append([]byte{byte(len(s))}, s...)
+		if !bytes.Contains(data, old) {
+			t.Fatalf("unable to locate encoded string %q", s)
+		}
+		return bytes.Replace(data, old, new, 1)
+	}
+	hugeLen := func(rest string) []byte {
+		b := make([]byte, binary.MaxVarintLen64)
+		n := binary.PutUvarint(b, maxLen)
+		return append(b[:n], rest...)
+	}
+	golden := []struct {
+		data []byte
+		err  string
+	}{
+		// i=0; corrupt string length.
+		{data: replace("foo.c", hugeLen("foo.c")), err: "EOF"},
+		// i=1; corrupt slice length of type definitions.
+		{data: append(append([]byte(magic), Version, 1), hugeLen("")...), err: "EOF"},
+		// i=2; invalid metadata attachment kind name.
+		{data: replace("irbin.corrupt", append([]byte{byte(len("irbin corrupt"))}, "irbin corrupt"...)), err: "invalid metadata attachment kind name"},
+	}
+	for i, g := range golden {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := Decode(bytes.NewReader(g.data))
+		runtime.ReadMemStats(&after)
+		if err == nil || !strings.Contains(err.Error(), g.err) {
+			t.Errorf("i=%d: error mismatch; expected %q, got %v", i, g.err, err)
+		}
+		// The length prefix alone must not cause large allocations.
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<24 {
+			t.Errorf("i=%d: decoding allocated %d bytes", i, n)
+		}
+	}
+	if _, ok := ir.LookupMDKind("irbin corrupt"); ok {
+		t.Errorf("invalid metadata attachment kind name registered")
+	}
+}
+
+// instFoo is an unregistered custom instruction.
+type instFoo struct {
+	ir.CustomInstBase
+	X value.Value
+}
+
+func (inst *instFoo) Def() string              { return "foo" }
+func (inst *instFoo) Operands() []*value.Value { return []*value.Value{&inst.X} }
+
+func TestEncodeUnregistered(t *testing.T) {
+	m := &ir.Module{}
+	f := m.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	entry.Insts = append(entry.Insts, &instFoo{X: ir.NewInt(types.I32, 1)})
+	entry.NewRet(nil)
+	if err := Encode(&bytes.Buffer{}, m); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("error mismatch; expected unregistered type, got %v", err)
+	}
+	Register(&instFoo{})
+	got := roundTrip(t, m)
+	inst, ok := got.Funcs[0].Blocks[0].Insts[0].(*instFoo)
+	if !ok || inst.X.Ident() != "1" {
+		t.Errorf("custom instruction mismatch; got %#v", got.Funcs[0].Blocks[0].Insts[0])
+	}
+}
+
+// roundTrip returns the decoded binary encoding of the given module.
+func roundTrip(t *testing.T, m *ir.Module) *ir.Module {
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, m); err != nil {
+		t.Fatalf("unable to encode module; %+v", err)
+	}
+	got, err := Decode(buf)
+	if err != nil {
+		t.Fatalf("unable to decode module; %+v", err)
+	}
+	return got
+}
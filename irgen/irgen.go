@@ -0,0 +1,437 @@
+// Package irgen implements generation of random well-typed LLVM IR modules,
+// for stress-testing passes, the printer and consumers of LLVM IR.
+//
+// Generated modules are deterministic for a given seed and configuration.
+// Operands are well-typed and dominate their uses, and integer division and
+// remainder are only performed by non-zero constants; so that generated
+// functions may also be executed (e.g. by an interpreter).
+package irgen
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/llir/l/analysis"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+// === [ Generator ] ===========================================================
+
+// Config is the configuration of the random module generator.
+type Config struct {
+	// Number of functions of the module.
+	Funcs int
+	// Maximum number of parameters of each function.
+	MaxParams int
+	// Maximum number of basic blocks of each function.
+	MaxBlocks int
+	// Maximum number of instructions of each basic block, excluding phi
+	// instructions and the instructions computing terminator operands.
+	MaxInsts int
+	// Integer types of parameters, return values and instructions.
+	IntTypes []*types.IntType
+	// Generate floating-point values and instructions (float and double).
+	Floats bool
+	// Generate stack memory accesses (alloca, load and store instructions).
+	Memory bool
+	// Generate back edges, and thus loops. Generated functions with loops may
+	// not terminate when executed.
+	Loops bool
+	// Maximum depth of the call graph; or 0 to generate no calls. Functions
+	// only call functions defined before them, so the call graph is acyclic.
+	CallDepth int
+}
+
+// DefaultConfig is the default configuration of the random module generator.
+var DefaultConfig = &Config{
+	Funcs:     4,
+	MaxParams: 3,
+	MaxBlocks: 6,
+	MaxInsts:  6,
+	IntTypes:  []*types.IntType{types.I1, types.I8, types.I32, types.I64},
+	Floats:    true,
+	Memory:    true,
+	Loops:     true,
+	CallDepth: 2,
+}
+
+// Generate returns a random module generated from the given seed according to
+// the given configuration. The default configuration is used if cfg is nil.
+func Generate(seed int64, cfg *Config) *ir.Module {
+	if cfg == nil {
+		cfg = DefaultConfig
+	}
+	g := &generator{
+		cfg:   cfg,
+		rnd:   rand.New(rand.NewSource(seed)),
+		m:     &ir.Module{},
+		depth: make(map[*ir.Function]int),
+	}
+	g.types = append(g.types, intTypes(cfg)...)
+	if cfg.Floats {
+		g.types = append(g.types, types.Float, types.Double)
+	}
+	for i := 0; i < cfg.Funcs; i++ {
+		g.genFunc(fmt.Sprintf("f%d", i))
+	}
+	return g.m
+}
+
+// ### [ Helper functions ] ####################################################
+
+// generator is a random module generator.
+type generator struct {
+	// Generator configuration.
+	cfg *Config
+	// Source of randomness.
+	rnd *rand.Rand
+	// Module being generated.
+	m *ir.Module
+	// Types of parameters, return values and instructions.
+	types []types.Type
+	// Depth of the call graph rooted at each generated function.
+	depth map[*ir.Function]int
+}
+
+// intTypes returns the integer types of the configuration; or i32 if none.
+func intTypes(cfg *Config) []types.Type {
+	var ts []types.Type
+	for _, t := range cfg.IntTypes {
+		ts = append(ts, t)
+	}
+	if len(ts) == 0 {
+		ts = append(ts, types.I32)
+	}
+	return ts
+}
+
+// randType returns a random type of the generator.
+func (g *generator) randType() types.Type {
+	return g.types[g.rnd.Intn(len(g.types))]
+}
+
+// randIntType returns a random integer type of the configuration.
+func (g *generator) randIntType() *types.IntType {
+	ts := intTypes(g.cfg)
+	return ts[g.rnd.Intn(len(ts))].(*types.IntType)
+}
+
+// randConst returns a random constant of the given integer or floating-point
+// type.
+func (g *generator) randConst(t types.Type) ir.Constant {
+	switch t := t.(type) {
+	case *types.IntType:
+		if t.BitSize == 1 {
+			return ir.NewInt(t, int64(g.rnd.Intn(2)))
+		}
+		return ir.NewInt(t, int64(g.rnd.Intn(17)-8))
+	case *types.FloatType:
+		// Floating-point constants are given as integer to floating-point
+		// conversions, as the LLVM syntax representation of float literals is
+		// not yet implemented.
+		return ir.NewSIToFPExpr(ir.NewInt(types.I32, int64(g.rnd.Intn(33)-16)), t)
+	}
+	return ir.NewZeroInitializer(t)
+}
+
+// genFunc generates a function definition of the given name.
+func (g *generator) genFunc(name string) {
+	var params []*ir.Param
+	for i := g.rnd.Intn(g.cfg.MaxParams + 1); i > 0; i-- {
+		params = append(params, ir.NewParam(g.randType(), ""))
+	}
+	f := g.m.NewFunc(name, g.randType(), params...)
+	fg := &funcGen{g: g, f: f, defs: make(map[*ir.BasicBlock][]value.Value)}
+	fg.genCFG()
+	fg.preds = analysis.Preds(f)
+	fg.dom = analysis.Dominators(f)
+	for _, block := range analysis.ReversePostorder(f) {
+		fg.genBlock(block)
+	}
+	fg.patchPhis()
+}
+
+// funcGen is a random function generator.
+type funcGen struct {
+	// Module generator.
+	g *generator
+	// Function being generated.
+	f *ir.Function
+	// Predecessors of each basic block of the function.
+	preds map[*ir.BasicBlock][]*ir.BasicBlock
+	// Dominator tree of the function.
+	dom *analysis.DomTree
+	// Values defined in each basic block, in order of definition.
+	defs map[*ir.BasicBlock][]value.Value
+	// Phi instructions, the incoming values of which are patched once all
+	// basic blocks are generated.
+	phis []*ir.InstPhi
+	// Callees (non-nil if a function may be called).
+	callees []*ir.Function
+}
+
+// genCFG generates the basic blocks and terminators of the function. Each
+// basic block except the last branches to its successor, which ensures that
+// all basic blocks are reachable; and may branch to a random other basic
+// block. Terminator operands are placeholders until the basic block is
+// generated.
+func (fg *funcGen) genCFG() {
+	g := fg.g
+	n := 1 + g.rnd.Intn(g.cfg.MaxBlocks)
+	for i := 0; i < n; i++ {
+		name := "entry"
+		if i > 0 {
+			name = fmt.Sprintf("bb%d", i)
+		}
+		fg.f.NewBlock(name)
+	}
+	blocks := fg.f.Blocks
+	for i, block := range blocks {
+		if i == n-1 {
+			block.NewRet(g.randConst(fg.f.Sig.RetType))
+			continue
+		}
+		next := blocks[i+1]
+		// Random other target; the entry basic block may not have predecessors.
+		lo := 1
+		if !g.cfg.Loops {
+			lo = i + 1
+		}
+		other := blocks[lo+g.rnd.Intn(n-lo)]
+		switch g.rnd.Intn(4) {
+		case 0:
+			block.NewBr(next)
+		case 1:
+			t := g.randIntType()
+			var cases []*ir.Case
+			if t.BitSize > 1 {
+				for j := 0; j < 1+g.rnd.Intn(3); j++ {
+					cases = append(cases, ir.NewCase(ir.NewInt(t, int64(j)), blocks[lo+g.rnd.Intn(n-lo)]))
+				}
+			}
+			block.NewSwitch(g.randConst(t), next, cases...)
+		default:
+			block.NewCondBr(ir.True, next, other)
+		}
+	}
+	// Functions of sufficiently shallow call graphs may be called.
+	for _, callee := range g.m.Funcs {
+		if callee != fg.f && g.depth[callee] < g.cfg.CallDepth {
+			fg.callees = append(fg.callees, callee)
+		}
+	}
+}
+
+// genBlock generates the instructions of the given basic block, and the
+// operands of its terminator.
+func (fg *funcGen) genBlock(block *ir.BasicBlock) {
+	g := fg.g
+	preds := fg.preds[block]
+	if len(preds) > 1 {
+		for i := g.rnd.Intn(3); i > 0; i-- {
+			t := g.randType()
+			var incs []*ir.Incoming
+			for _, pred := range preds {
+				incs = append(incs, ir.NewIncoming(g.randConst(t), pred))
+			}
+			phi := block.NewPhi(incs...)
+			fg.phis = append(fg.phis, phi)
+			fg.define(block, phi)
+		}
+	}
+	if block == fg.f.Blocks[0] && g.cfg.Memory {
+		for i := 1 + g.rnd.Intn(2); i > 0; i-- {
+			t := g.randType()
+			ptr := block.NewAlloca(t)
+			block.NewStore(g.randConst(t), ptr)
+			fg.define(block, ptr)
+		}
+	}
+	for i := g.rnd.Intn(g.cfg.MaxInsts + 1); i > 0; i-- {
+		fg.genInst(block)
+	}
+	switch term := block.Term.(type) {
+	case *ir.TermRet:
+		term.X = fg.operand(block, fg.f.Sig.RetType)
+	case *ir.TermCondBr:
+		term.Cond = fg.genCond(block)
+	case *ir.TermSwitch:
+		if x, ok := fg.pick(block, term.X.Type()); ok {
+			term.X = x
+		}
+	}
+}
+
+// genInst generates a random instruction at the end of the given basic block.
+func (fg *funcGen) genInst(block *ir.BasicBlock) {
+	g := fg.g
+	switch g.rnd.Intn(8) {
+	case 0, 1:
+		t := g.randIntType()
+		x, y := fg.operand(block, t), fg.operand(block, t)
+		var inst value.Value
+		switch g.rnd.Intn(8) {
+		case 0:
+			inst = block.NewAdd(x, y)
+		case 1:
+			inst = block.NewSub(x, y)
+		case 2:
+			inst = block.NewMul(x, y)
+		case 3:
+			inst = block.NewAnd(x, y)
+		case 4:
+			inst = block.NewOr(x, y)
+		case 5:
+			inst = block.NewXor(x, y)
+		case 6:
+			// Division by non-zero constant.
+			d := ir.NewInt(t, int64(1+g.rnd.Intn(7)))
+			if t.BitSize < 4 {
+				d = ir.NewInt(t, 1)
+			}
+			inst = block.NewUDiv(x, d)
+		default:
+			amount := ir.NewInt(t, int64(g.rnd.Intn(int(t.BitSize))))
+			inst = block.NewShl(x, amount)
+		}
+		fg.define(block, inst)
+	case 2:
+		fg.define(block, fg.genCond(block))
+	case 3:
+		t := g.randType()
+		fg.define(block, block.NewSelect(fg.operand(block, types.I1), fg.operand(block, t), fg.operand(block, t)))
+	case 4:
+		from, to := g.randIntType(), g.randIntType()
+		x := fg.operand(block, from)
+		switch {
+		case from.BitSize > to.BitSize:
+			fg.define(block, block.NewTrunc(x, to))
+		case from.BitSize < to.BitSize && g.rnd.Intn(2) == 0:
+			fg.define(block, block.NewZExt(x, to))
+		case from.BitSize < to.BitSize:
+			fg.define(block, block.NewSExt(x, to))
+		}
+	case 5:
+		if !g.cfg.Floats {
+			return
+		}
+		t := types.Double
+		if g.rnd.Intn(2) == 0 {
+			t = types.Float
+		}
+		x, y := fg.operand(block, t), fg.operand(block, t)
+		switch g.rnd.Intn(4) {
+		case 0:
+			fg.define(block, block.NewFAdd(x, y))
+		case 1:
+			fg.define(block, block.NewFSub(x, y))
+		case 2:
+			fg.define(block, block.NewFMul(x, y))
+		default:
+			fg.define(block, block.NewSIToFP(fg.operand(block, g.randIntType()), t))
+		}
+	case 6:
+		var ptrs []value.Value
+		for _, v := range fg.avail(block) {
+			if _, ok := v.(*ir.InstAlloca); ok {
+				ptrs = append(ptrs, v)
+			}
+		}
+		if len(ptrs) == 0 {
+			return
+		}
+		ptr := ptrs[g.rnd.Intn(len(ptrs))].(*ir.InstAlloca)
+		if g.rnd.Intn(2) == 0 {
+			fg.define(block, block.NewLoad(ptr))
+		} else {
+			block.NewStore(fg.operand(block, ptr.ElemType), ptr)
+		}
+	default:
+		if len(fg.callees) == 0 {
+			return
+		}
+		callee := fg.callees[g.rnd.Intn(len(fg.callees))]
+		var args []value.Value
+		for _, param := range callee.Params {
+			args = append(args, fg.operand(block, param.Type()))
+		}
+		fg.define(block, block.NewCall(callee, args...))
+		if d := g.depth[callee] + 1; d > g.depth[fg.f] {
+			g.depth[fg.f] = d
+		}
+	}
+}
+
+// genCond generates a random comparison at the end of the given basic block.
+func (fg *funcGen) genCond(block *ir.BasicBlock) value.Value {
+	g := fg.g
+	t := g.randType()
+	x, y := fg.operand(block, t), fg.operand(block, t)
+	if _, ok := t.(*types.FloatType); ok {
+		preds := []enum.FPred{enum.FPredOEQ, enum.FPredOLT, enum.FPredUGE, enum.FPredUNE}
+		return block.NewFCmp(preds[g.rnd.Intn(len(preds))], x, y)
+	}
+	preds := []enum.IPred{enum.IPredEQ, enum.IPredNE, enum.IPredSLT, enum.IPredSGE, enum.IPredULT, enum.IPredUGT}
+	return block.NewICmp(preds[g.rnd.Intn(len(preds))], x, y)
+}
+
+// patchPhis replaces the placeholder incoming values of the phi instructions
+// of the function with random values available at the end of the
+// predecessors.
+func (fg *funcGen) patchPhis() {
+	for _, phi := range fg.phis {
+		for _, inc := range phi.Incs {
+			if x, ok := fg.pick(inc.Pred, phi.Type()); ok {
+				inc.X = x
+			}
+		}
+	}
+}
+
+// define records the given value as defined in the given basic block.
+func (fg *funcGen) define(block *ir.BasicBlock, v value.Value) {
+	fg.defs[block] = append(fg.defs[block], v)
+}
+
+// avail returns the values available at the end of the given basic block; the
+// parameters of the function, and the values defined so far in the basic
+// block and its dominators.
+func (fg *funcGen) avail(block *ir.BasicBlock) []value.Value {
+	var vs []value.Value
+	for _, param := range fg.f.Params {
+		vs = append(vs, param)
+	}
+	for b := block; b != nil; b = fg.dom.Idom(b) {
+		vs = append(vs, fg.defs[b]...)
+	}
+	return vs
+}
+
+// pick returns a random value of the given type available at the end of the
+// given basic block. The boolean return value indicates success.
+func (fg *funcGen) pick(block *ir.BasicBlock, t types.Type) (value.Value, bool) {
+	var vs []value.Value
+	for _, v := range fg.avail(block) {
+		if v.Type().Equal(t) {
+			vs = append(vs, v)
+		}
+	}
+	if len(vs) == 0 {
+		return nil, false
+	}
+	return vs[fg.g.rnd.Intn(len(vs))], true
+}
+
+// operand returns a random operand of the given type at the end of the given
+// basic block; an available value, or a random constant.
+func (fg *funcGen) operand(block *ir.BasicBlock, t types.Type) value.Value {
+	if fg.g.rnd.Intn(4) != 0 {
+		if x, ok := fg.pick(block, t); ok {
+			return x
+		}
+	}
+	return fg.g.randConst(t)
+}
@@ -0,0 +1,136 @@
+package irgen
+
+import (
+	"testing"
+
+	"github.com/llir/l/analysis"
+	"github.com/llir/l/interp"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/value"
+	"github.com/llir/l/verify"
+	"github.com/pkg/errors"
+)
+
+func TestGenerate(t *testing.T) {
+	for seed := int64(0); seed < 50; seed++ {
+		m := Generate(seed, nil)
+		if err := verify.Module(m); err != nil {
+			t.Errorf("seed %d: verification failed; %+v", seed, err)
+			continue
+		}
+		for _, f := range m.Funcs {
+			if err := f.AssignIDs(); err != nil {
+				t.Errorf("seed %d: unable to assign IDs of %s; %+v", seed, f.Ident(), err)
+			}
+			checkDominance(t, seed, f)
+		}
+		s := m.Def()
+		if got := def(t, Generate(seed, nil)); got != s {
+			t.Errorf("seed %d: non-deterministic output; expected %q, got %q", seed, s, got)
+		}
+		in, err := interp.New(m)
+		if err != nil {
+			t.Errorf("seed %d: unable to create interpreter; %+v", seed, err)
+			continue
+		}
+		in.MaxSteps = 10000
+		for _, f := range m.Funcs {
+			args := make([]interp.Value, len(f.Params))
+			if _, err := in.CallFunc(f, args...); err != nil && errors.Cause(err) != interp.ErrStepLimit {
+				t.Errorf("seed %d: unable to execute %s; %+v\n%s", seed, f.Ident(), err, s)
+			}
+		}
+	}
+}
+
+func TestGenerateConfig(t *testing.T) {
+	cfg := &Config{Funcs: 3, MaxParams: 2, MaxBlocks: 4, MaxInsts: 4}
+	for seed := int64(0); seed < 20; seed++ {
+		m := Generate(seed, cfg)
+		if len(m.Funcs) != cfg.Funcs {
+			t.Errorf("seed %d: number of functions mismatch; expected %d, got %d", seed, cfg.Funcs, len(m.Funcs))
+		}
+		for _, f := range m.Funcs {
+			if len(f.Blocks) > cfg.MaxBlocks {
+				t.Errorf("seed %d: too many basic blocks in %s; expected at most %d, got %d", seed, f.Ident(), cfg.MaxBlocks, len(f.Blocks))
+			}
+			for _, block := range f.Blocks {
+				for _, inst := range block.Insts {
+					switch inst.(type) {
+					case *ir.InstCall, *ir.InstAlloca, *ir.InstFAdd, *ir.InstFSub, *ir.InstFMul, *ir.InstSIToFP:
+						t.Errorf("seed %d: unexpected instruction %T in %s", seed, inst, f.Ident())
+					}
+				}
+			}
+		}
+	}
+}
+
+// def returns the LLVM syntax representation of the given module, after
+// assigning local IDs.
+func def(t *testing.T, m *ir.Module) string {
+	for _, f := range m.Funcs {
+		if err := f.AssignIDs(); err != nil {
+			t.Fatalf("unable to assign IDs of %s; %+v", f.Ident(), err)
+		}
+	}
+	return m.Def()
+}
+
+// checkDominance checks that the definition of each operand of the given
+// function dominates its uses.
+func checkDominance(t *testing.T, seed int64, f *ir.Function) {
+	dom := analysis.Dominators(f)
+	// Basic block and index of each instruction.
+	blocks := make(map[value.Value]*ir.BasicBlock)
+	index := make(map[value.Value]int)
+	for _, block := range f.Blocks {
+		for i, inst := range block.Insts {
+			if v, ok := inst.(value.Value); ok {
+				blocks[v] = block
+				index[v] = i
+			}
+		}
+	}
+	for _, block := range f.Blocks {
+		check := func(x value.Value, use *ir.BasicBlock, i int) {
+			def, ok := blocks[x]
+			if !ok {
+				return
+			}
+			if def == use && index[x] >= i || !dom.Dominates(def, use) {
+				t.Errorf("seed %d: definition of %s does not dominate its use in %s of %s", seed, x.Ident(), use.Ident(), f.Ident())
+			}
+		}
+		for i, inst := range block.Insts {
+			if phi, ok := inst.(*ir.InstPhi); ok {
+				for _, inc := range phi.Incs {
+					check(inc.X, inc.Pred, len(inc.Pred.Insts))
+				}
+				continue
+			}
+			for _, x := range operands(inst) {
+				check(x, block, i)
+			}
+		}
+		for _, x := range operands(block.Term) {
+			check(x, block, len(block.Insts))
+		}
+	}
+}
+
+// operands returns the operands of the given instruction or terminator.
+func operands(inst interface{}) []value.Value {
+	var xs []value.Value
+	ir.Walk(inst, func(n interface{}) bool {
+		if n == inst {
+			return true
+		}
+		if x, ok := n.(value.Value); ok {
+			xs = append(xs, x)
+			return false
+		}
+		return true
+	})
+	return xs
+}
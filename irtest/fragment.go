@@ -0,0 +1,68 @@
+package irtest
+
+import (
+	"fmt"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// === [ Fragment parsing ] ====================================================
+
+// fragmentPath is the path reported to the parser for modules wrapping LLVM IR
+// fragments.
+const fragmentPath = "<fragment>"
+
+// ParseType parses the given LLVM IR type (e.g. `{ i32, i8* }`), using the
+// given parser to parse a module wrapping the type.
+func ParseType(parse ParseFunc, s string) (types.Type, error) {
+	g, err := parseGlobal(parse, fmt.Sprintf("@0 = external global %s\n", s))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse type %q", s)
+	}
+	return g.ContentType, nil
+}
+
+// ParseConstant parses the given LLVM IR type-constant pair (e.g. `i32 42`),
+// using the given parser to parse a module wrapping the constant.
+func ParseConstant(parse ParseFunc, s string) (ir.Constant, error) {
+	g, err := parseGlobal(parse, fmt.Sprintf("@0 = global %s\n", s))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse constant %q", s)
+	}
+	if g.Init == nil {
+		return nil, errors.Errorf("unable to parse constant %q; missing initializer", s)
+	}
+	return g.Init, nil
+}
+
+// ParseInstruction parses the given LLVM IR instruction (e.g.
+// `%x = add i32 1, 2`), using the given parser to parse a module wrapping the
+// instruction in the entry basic block of a function. Operands of the
+// instruction are limited to constants and global values.
+func ParseInstruction(parse ParseFunc, s string) (ir.Instruction, error) {
+	m, err := parse(fragmentPath, fmt.Sprintf("define void @0() {\n\t%s\n\tunreachable\n}\n", s))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse instruction %q", s)
+	}
+	if len(m.Funcs) != 1 || len(m.Funcs[0].Blocks) != 1 || len(m.Funcs[0].Blocks[0].Insts) != 1 {
+		return nil, errors.Errorf("unable to parse instruction %q; expected exactly one instruction", s)
+	}
+	return m.Funcs[0].Blocks[0].Insts[0], nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// parseGlobal parses the given module containing a single global variable,
+// and returns the global variable.
+func parseGlobal(parse ParseFunc, content string) (*ir.Global, error) {
+	m, err := parse(fragmentPath, content)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(m.Globals) != 1 {
+		return nil, errors.Errorf("expected exactly one global variable, got %d", len(m.Globals))
+	}
+	return m.Globals[0], nil
+}
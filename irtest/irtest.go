@@ -0,0 +1,199 @@
+// Package irtest implements helpers for golden file tests of LLVM IR modules;
+// e.g. to set up conformance suites of parsers and printers of LLVM IR
+// assembly. It also implements parsing of LLVM IR fragments (types, constants
+// and instructions), for tests dealing with snippets rather than modules.
+package irtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/pkg/errors"
+)
+
+// === [ Golden file tests ] ===================================================
+
+// ParseFunc parses the given LLVM IR assembly read from the given path into a
+// module. There is no LLVM IR parser in this repository, so the parser is
+// provided by the caller; of golden file tests and fragment parsing alike.
+type ParseFunc func(path, content string) (*ir.Module, error)
+
+// RoundTrip tests that the given LLVM IR assembly golden files round-trip;
+// i.e. that each file is parsed, printed, parsed again and printed again, with
+// the first print equal to the golden output and equal output of both prints.
+// The golden output of a file is the contents of the file with a ".golden"
+// extension appended to its path (e.g. "foo.ll.golden") if present, and the
+// contents of the file itself otherwise. Failures are reported with a line diff
+// of the outputs.
+func RoundTrip(t testing.TB, parse ParseFunc, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Errorf("unable to read %q; %+v", path, err)
+			continue
+		}
+		input := string(buf)
+		want, wantPath := input, path
+		if buf, err := ioutil.ReadFile(path + ".golden"); err == nil {
+			want, wantPath = string(buf), path+".golden"
+		} else if !os.IsNotExist(err) {
+			t.Errorf("unable to read %q; %+v", path+".golden", err)
+			continue
+		}
+		first, err := reprint(parse, path, input)
+		if err != nil {
+			t.Errorf("%+v", err)
+			continue
+		}
+		if first != want {
+			t.Errorf("output mismatch of %q (- %s, + first print):\n%s", path, wantPath, Diff(want, first))
+		}
+		second, err := reprint(parse, path, first)
+		if err != nil {
+			t.Errorf("unable to parse printed module of %q; %+v", path, err)
+			continue
+		}
+		if first != second {
+			t.Errorf("round-trip mismatch of %q (- first print, + second print):\n%s", path, Diff(first, second))
+		}
+	}
+}
+
+// Golden tests that the LLVM syntax representation of the given module is
+// equal to the contents of the given golden file, after assigning local IDs.
+// Failures are reported with a line diff of the golden file and output.
+func Golden(t testing.TB, m *ir.Module, path string) {
+	t.Helper()
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("unable to read %q; %+v", path, err)
+		return
+	}
+	got, err := def(m)
+	if err != nil {
+		t.Errorf("%+v", err)
+		return
+	}
+	if got != string(want) {
+		t.Errorf("output mismatch of %q (- golden, + output):\n%s", path, Diff(string(want), got))
+	}
+}
+
+// Diff returns a line diff of the given strings, with removed lines prefixed
+// by "-", added lines prefixed by "+", and at most three lines of unchanged
+// context surrounding each change.
+func Diff(a, b string) string {
+	const context = 3
+	ops := diffLines(strings.Split(a, "\n"), strings.Split(b, "\n"))
+	// Lines within the context of a change.
+	near := make([]bool, len(ops))
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		for j := i - context; j <= i+context; j++ {
+			if j >= 0 && j < len(ops) {
+				near[j] = true
+			}
+		}
+	}
+	buf := &strings.Builder{}
+	skipped := false
+	for i, op := range ops {
+		if !near[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			buf.WriteString("...\n")
+			skipped = false
+		}
+		fmt.Fprintf(buf, "%c %s\n", op.kind, op.line)
+	}
+	if skipped {
+		buf.WriteString("...\n")
+	}
+	return buf.String()
+}
+
+// ### [ Helper functions ] ####################################################
+
+// reprint parses the given LLVM IR assembly, and returns the LLVM syntax
+// representation of the parsed module.
+func reprint(parse ParseFunc, path, content string) (string, error) {
+	m, err := parse(path, content)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to parse %q", path)
+	}
+	return def(m)
+}
+
+// def returns the LLVM syntax representation of the given module, after
+// assigning local IDs.
+func def(m *ir.Module) (string, error) {
+	for _, f := range m.Funcs {
+		if err := f.AssignIDs(); err != nil {
+			return "", errors.Wrapf(err, "unable to assign IDs of %v", f.Ident())
+		}
+	}
+	return m.Def(), nil
+}
+
+// diffOp is a line diff operation.
+type diffOp struct {
+	// Operation kind; ' ' (unchanged), '-' (removed) or '+' (added).
+	kind byte
+	// Line of the operation.
+	line string
+}
+
+// diffLines returns the line diff operations transforming a into b, based on
+// their longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{kind: '-', line: a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{kind: '-', line: a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{kind: '+', line: b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,162 @@
+package irtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\n"
+	b := "a\nb\nc\nd\ne\nF\ng\nh\ni\n"
+	want := `...
+  c
+  d
+  e
+- f
++ F
+  g
+  h
+  i
+...
+`
+	if got := Diff(a, b); got != want {
+		t.Errorf("diff mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "irtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.ll")
+	if err := ioutil.WriteFile(path, []byte("target triple = \"x86_64\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// parse parses the target triple of the module.
+	parse := func(path, content string) (*ir.Module, error) {
+		m := &ir.Module{}
+		if _, err := fmt.Sscanf(content, "target triple = %q\n", &m.TargetTriple); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	r := &recorder{TB: t}
+	RoundTrip(r, parse, path)
+	if len(r.errs) > 0 {
+		t.Errorf("unexpected round-trip failure; %v", r.errs)
+	}
+	// unstable appends to the target triple of the module on each parse.
+	unstable := func(path, content string) (*ir.Module, error) {
+		m, err := parse(path, content)
+		if err != nil {
+			return nil, err
+		}
+		m.TargetTriple += "-x"
+		return m, nil
+	}
+	r = &recorder{TB: t}
+	RoundTrip(r, unstable, path)
+	wants := []string{
+		"- target triple = \"x86_64\"\n+ target triple = \"x86_64-x\"",
+		"-x\"\n+ target triple = \"x86_64-x-x\"",
+	}
+	if len(r.errs) != len(wants) {
+		t.Fatalf("round-trip failure mismatch; expected %d failures, got %q", len(wants), r.errs)
+	}
+	for i, want := range wants {
+		if !strings.Contains(r.errs[i], want) {
+			t.Errorf("i=%d: round-trip failure mismatch; expected diff containing %q, got %q", i, want, r.errs[i])
+		}
+	}
+	// Input with a trailing comment, not retained by the printer.
+	path = filepath.Join(dir, "b.ll")
+	if err := ioutil.WriteFile(path, []byte("target triple = \"x86_64\"\n; comment\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r = &recorder{TB: t}
+	RoundTrip(r, parse, path)
+	if len(r.errs) != 1 || !strings.Contains(r.errs[0], "- ; comment\n") {
+		t.Errorf("round-trip failure mismatch; expected diff of comment, got %q", r.errs)
+	}
+	if err := ioutil.WriteFile(path+".golden", []byte("target triple = \"x86_64\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r = &recorder{TB: t}
+	RoundTrip(r, parse, path)
+	if len(r.errs) > 0 {
+		t.Errorf("unexpected round-trip failure with golden file; %v", r.errs)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "irtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.ll")
+	golden := "define i32 @f(i32) {\n0:\n\tret i32 %0\n}\n"
+	if err := ioutil.WriteFile(path, []byte(golden), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &ir.Module{}
+	x := ir.NewParam(types.I32, "")
+	f := m.NewFunc("f", types.I32, x)
+	f.NewBlock("").NewRet(x)
+	r := &recorder{TB: t}
+	Golden(r, m, path)
+	if len(r.errs) != 1 || !strings.Contains(r.errs[0], "- 0:\n") {
+		t.Errorf("golden failure mismatch; expected diff of basic block label, got %q", r.errs)
+	}
+}
+
+func TestParseFragment(t *testing.T) {
+	g := ir.NewGlobalDef("0", ir.NewInt(types.I32, 42))
+	f := ir.NewFunc("0", types.Void)
+	entry := f.NewBlock("")
+	add := entry.NewAdd(ir.NewInt(types.I32, 1), ir.NewInt(types.I32, 2))
+	entry.NewUnreachable()
+	// parse returns the module of the expected wrapped fragment.
+	parse := func(path, content string) (*ir.Module, error) {
+		switch content {
+		case "@0 = external global i32\n", "@0 = global i32 42\n":
+			return &ir.Module{Globals: []*ir.Global{g}}, nil
+		case "define void @0() {\n\t%x = add i32 1, 2\n\tunreachable\n}\n":
+			return &ir.Module{Funcs: []*ir.Function{f}}, nil
+		}
+		return nil, fmt.Errorf("unexpected content %q", content)
+	}
+	if got, err := ParseType(parse, "i32"); err != nil || got != g.ContentType {
+		t.Errorf("type mismatch; expected %v, got %v (%v)", g.ContentType, got, err)
+	}
+	if got, err := ParseConstant(parse, "i32 42"); err != nil || got != g.Init {
+		t.Errorf("constant mismatch; expected %v, got %v (%v)", g.Init, got, err)
+	}
+	if got, err := ParseInstruction(parse, "%x = add i32 1, 2"); err != nil || got != add {
+		t.Errorf("instruction mismatch; expected %v, got %v (%v)", add, got, err)
+	}
+	if _, err := ParseType(parse, "i64"); err == nil {
+		t.Errorf("expected error on parse failure")
+	}
+}
+
+// recorder records the errors reported by tests.
+type recorder struct {
+	testing.TB
+	// Reported errors.
+	errs []string
+}
+
+// Errorf records the formatted error.
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
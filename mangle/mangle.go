@@ -0,0 +1,309 @@
+// Package mangle implements renaming of the global variables and functions of
+// LLVM IR modules according to naming schemes, as used when combining modules
+// from multiple sources.
+package mangle
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Renaming ] ============================================================
+
+// Scheme is a naming scheme, which maps a global variable or function to its
+// new global name (without '@' prefix).
+type Scheme func(v value.Named) string
+
+// Rename renames the global variables and functions of the module for which
+// keep reports false according to the given naming scheme; all are renamed if
+// keep is nil. Uses of the renamed values are updated implicitly, as operands
+// refer to values rather than names.
+//
+// Collisions between new names, and between new names and the names of values
+// which are not renamed, are resolved by adding a numeric suffix (e.g. "f.1").
+func Rename(m *ir.Module, scheme Scheme, keep func(v value.Named) bool) error {
+	// Global names of values which are not renamed, and new global names.
+	taken := make(map[string]bool)
+	var renamed []value.Named
+	var newNames []string
+	for _, v := range globalValues(m) {
+		if keep != nil && keep(v) {
+			taken[v.Name()] = true
+			continue
+		}
+		renamed = append(renamed, v)
+		newNames = append(newNames, scheme(v))
+	}
+	// Unregister the values to rename first, to allow for new names which
+	// equal the old names of other values (e.g. when swapping names).
+	for _, v := range renamed {
+		if err := m.Rename(v, ""); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for i, v := range renamed {
+		name := uniqueName(taken, newNames[i])
+		taken[name] = true
+		if err := m.Rename(v, name); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// --- [ Naming schemes ] ------------------------------------------------------
+
+// Prefix returns a naming scheme which prefixes global names with the given
+// prefix.
+func Prefix(prefix string) Scheme {
+	return func(v value.Named) string {
+		return prefix + v.Name()
+	}
+}
+
+// HashSuffix returns a naming scheme which suffixes global names with a hash
+// of the global name and the given salt (e.g. the source filename of the
+// module); e.g. "f.3f2a81c4".
+func HashSuffix(salt string) Scheme {
+	return func(v value.Named) string {
+		sum := sha1.Sum([]byte(salt + "\x00" + v.Name()))
+		return fmt.Sprintf("%s.%s", v.Name(), hex.EncodeToString(sum[:4]))
+	}
+}
+
+// Itanium returns a naming scheme which mangles global names according to the
+// Itanium C++ ABI, as if declared within the given nested namespaces. Function
+// names are mangled with the parameter types of their function signature (e.g.
+// "_ZN2ns1fEiPc" for ns::f(int, char*)), where array parameters decay to
+// pointers and repeated namespace prefixes and types are substituted (e.g.
+// "_ZN2ns1fEPcS0_" for ns::f(char*, char*)). Names of global variables outside
+// of namespaces are left unchanged.
+func Itanium(namespaces ...string) Scheme {
+	return func(v value.Named) string {
+		f, isFunc := v.(*ir.Function)
+		if !isFunc && len(namespaces) == 0 {
+			return v.Name()
+		}
+		m := &itaniumMangler{}
+		buf := &strings.Builder{}
+		buf.WriteString("_Z")
+		if len(namespaces) > 0 {
+			buf.WriteString("N")
+			for i, ns := range namespaces {
+				buf.WriteString(sourceName(ns))
+				// Namespace prefixes are substitution candidates.
+				m.subs = append(m.subs, "N"+strings.Join(namespaces[:i+1], "::"))
+			}
+			buf.WriteString(sourceName(v.Name()))
+			buf.WriteString("E")
+		} else {
+			buf.WriteString(sourceName(v.Name()))
+		}
+		if isFunc {
+			if len(f.Sig.Params) == 0 && !f.Sig.Variadic {
+				buf.WriteString("v")
+			}
+			for _, param := range f.Sig.Params {
+				// Array parameters decay to pointers.
+				if t, ok := param.(*types.ArrayType); ok {
+					param = types.NewPointer(t.ElemType)
+				}
+				buf.WriteString(m.mangleType(param))
+			}
+			if f.Sig.Variadic {
+				buf.WriteString("z")
+			}
+		}
+		return buf.String()
+	}
+}
+
+// itaniumMangler is an Itanium C++ ABI mangler of a single name, keeping track
+// of substitution candidates.
+type itaniumMangler struct {
+	// Substitution candidates, in order of appearance; the unsubstituted
+	// mangling of types, and "N" followed by the qualified name of namespace
+	// prefixes.
+	subs []string
+}
+
+// mangleType returns the Itanium C++ ABI mangling of the given type, replacing
+// previously seen types by substitutions (e.g. "S_" and "S0_"), and recording
+// new substitution candidates.
+func (m *itaniumMangler) mangleType(t types.Type) string {
+	key := itaniumType(t)
+	switch t.(type) {
+	case *types.VoidType, *types.IntType, *types.FloatType:
+		// Builtin types are not substitution candidates, except for vendor
+		// extended types.
+		if !strings.HasPrefix(key, "u") {
+			return key
+		}
+	}
+	for i, sub := range m.subs {
+		if sub == key {
+			return seqID(i)
+		}
+	}
+	s := key
+	switch t := t.(type) {
+	case *types.PointerType:
+		s = "P" + m.mangleType(t.ElemType)
+	case *types.ArrayType:
+		s = fmt.Sprintf("A%d_%s", t.Len, m.mangleType(t.ElemType))
+	case *types.VectorType:
+		s = fmt.Sprintf("Dv%d_%s", t.Len, m.mangleType(t.ElemType))
+	case *types.FuncType:
+		buf := &strings.Builder{}
+		buf.WriteString("F")
+		buf.WriteString(m.mangleType(t.RetType))
+		if len(t.Params) == 0 && !t.Variadic {
+			buf.WriteString("v")
+		}
+		for _, param := range t.Params {
+			buf.WriteString(m.mangleType(param))
+		}
+		if t.Variadic {
+			buf.WriteString("z")
+		}
+		buf.WriteString("E")
+		s = buf.String()
+	}
+	m.subs = append(m.subs, key)
+	return s
+}
+
+// ### [ Helper functions ] ####################################################
+
+// globalValues returns the named global variables and functions of the module,
+// in module order.
+func globalValues(m *ir.Module) []value.Named {
+	var vs []value.Named
+	for _, g := range m.Globals {
+		if len(g.Name()) > 0 {
+			vs = append(vs, g)
+		}
+	}
+	for _, f := range m.Funcs {
+		if len(f.Name()) > 0 {
+			vs = append(vs, f)
+		}
+	}
+	return vs
+}
+
+// uniqueName returns a global name based on the given name which is not
+// present in the set of taken names.
+func uniqueName(taken map[string]bool, name string) string {
+	if !taken[name] {
+		return name
+	}
+	for i := 1; ; i++ {
+		uniqueName := fmt.Sprintf("%s.%d", name, i)
+		if !taken[uniqueName] {
+			return uniqueName
+		}
+	}
+}
+
+// sourceName returns the Itanium C++ ABI source name of the given identifier;
+// its length followed by the identifier.
+func sourceName(ident string) string {
+	return fmt.Sprintf("%d%s", len(ident), ident)
+}
+
+// seqID returns the Itanium C++ ABI substitution of the i-th substitution
+// candidate; "S_", followed by "S0_" through "S9_", "SA_" through "SZ_", "S10_",
+// etc.
+func seqID(i int) string {
+	if i == 0 {
+		return "S_"
+	}
+	return "S" + strings.ToUpper(strconv.FormatInt(int64(i-1), 36)) + "_"
+}
+
+// itaniumType returns the Itanium C++ ABI mangling of the given type without
+// substitutions, mapping LLVM IR types to their corresponding C types (e.g. i32
+// to int).
+func itaniumType(t types.Type) string {
+	switch t := t.(type) {
+	case *types.VoidType:
+		return "v"
+	case *types.IntType:
+		switch t.BitSize {
+		case 1:
+			return "b" // bool
+		case 8:
+			return "c" // char
+		case 16:
+			return "s" // short
+		case 32:
+			return "i" // int
+		case 64:
+			return "l" // long
+		case 128:
+			return "n" // __int128
+		}
+		// Vendor extended type.
+		return "u" + sourceName(fmt.Sprintf("_BitInt%d", t.BitSize))
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindHalf:
+			return "DF16_" // _Float16
+		case types.FloatKindFloat:
+			return "f" // float
+		case types.FloatKindDouble:
+			return "d" // double
+		case types.FloatKindX86FP80:
+			return "e" // long double
+		case types.FloatKindFP128:
+			return "g" // __float128
+		}
+		return "u9__ibm128"
+	case *types.PointerType:
+		return "P" + itaniumType(t.ElemType)
+	case *types.ArrayType:
+		return fmt.Sprintf("A%d_%s", t.Len, itaniumType(t.ElemType))
+	case *types.VectorType:
+		return fmt.Sprintf("Dv%d_%s", t.Len, itaniumType(t.ElemType))
+	case *types.StructType:
+		if len(t.Alias) > 0 {
+			return sourceName(t.Alias)
+		}
+	case *types.FuncType:
+		buf := &strings.Builder{}
+		buf.WriteString("F")
+		buf.WriteString(itaniumType(t.RetType))
+		if len(t.Params) == 0 && !t.Variadic {
+			buf.WriteString("v")
+		}
+		for _, param := range t.Params {
+			buf.WriteString(itaniumType(param))
+		}
+		if t.Variadic {
+			buf.WriteString("z")
+		}
+		buf.WriteString("E")
+		return buf.String()
+	}
+	// Literal struct types and other types without C counterpart are mangled as
+	// vendor extended types.
+	return "u" + sourceName(strings.Map(identRune, t.String()))
+}
+
+// identRune maps characters which are not valid in identifiers to '_'.
+func identRune(r rune) rune {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '_':
+		return r
+	}
+	return '_'
+}
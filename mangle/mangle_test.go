@@ -0,0 +1,135 @@
+package mangle
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+func TestRename(t *testing.T) {
+	m := &ir.Module{}
+	x := m.NewGlobalDef("x", ir.NewInt(types.I32, 0))
+	f := m.NewFunc("f", types.I32, ir.NewParam(types.I32, "a"), ir.NewParam(types.I8Ptr, "b"))
+	g := m.NewFunc("g", types.Void)
+	entry := g.NewBlock("entry")
+	call := entry.NewCall(f, ir.NewInt(types.I32, 1), ir.NewNull(types.I8Ptr))
+	entry.NewRet(nil)
+	golden := []struct {
+		scheme Scheme
+		want   []string
+	}{
+		// i=0
+		{scheme: Itanium("ns"), want: []string{"_ZN2ns1xE", "_ZN2ns1fEiPc", "_ZN2ns1gEv"}},
+		// i=1
+		{scheme: Itanium(), want: []string{"x", "_Z1fiPc", "_Z1gv"}},
+		// i=2
+		{scheme: Prefix("m1."), want: []string{"m1.x", "m1.f", "m1.g"}},
+	}
+	for i, gold := range golden {
+		// Reset names.
+		for _, v := range []value.Named{x, f, g} {
+			if err := m.Rename(v, ""); err != nil {
+				t.Fatalf("i=%d: unable to clear name; %+v", i, err)
+			}
+		}
+		for j, v := range []value.Named{x, f, g} {
+			if err := m.Rename(v, []string{"x", "f", "g"}[j]); err != nil {
+				t.Fatalf("i=%d: unable to reset name; %+v", i, err)
+			}
+		}
+		if err := Rename(m, gold.scheme, nil); err != nil {
+			t.Fatalf("i=%d: unable to rename; %+v", i, err)
+		}
+		for j, v := range []value.Named{x, f, g} {
+			if got := v.Name(); gold.want[j] != got {
+				t.Errorf("i=%d: name mismatch; expected %q, got %q", i, gold.want[j], got)
+			}
+			if got, ok := m.Lookup(gold.want[j]); !ok || got != v {
+				t.Errorf("i=%d: symbol table not updated for %q", i, gold.want[j])
+			}
+		}
+	}
+	// Uses refer to the renamed value.
+	if want, got := "call i32 @m1.f(i32 1, i8* null)", call.Def(); want != got {
+		t.Errorf("call mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
+
+func TestItanium(t *testing.T) {
+	// Expected names as produced by GCC and Clang.
+	charPtr := types.NewPointer(types.I8)
+	st := types.NewStruct(types.I32)
+	st.SetAlias("T")
+	fnPtr := types.NewPointer(types.NewFunc(types.Void, types.I32))
+	intArrayPtr := types.NewPointer(types.NewArray(4, types.I32))
+	golden := []struct {
+		namespaces []string
+		params     []types.Type
+		want       string
+	}{
+		// i=0; void ns::f(char*, char*)
+		{namespaces: []string{"ns"}, params: []types.Type{charPtr, charPtr}, want: "_ZN2ns1fEPcS0_"},
+		// i=1; void f(char*, char*)
+		{params: []types.Type{charPtr, charPtr}, want: "_Z1fPcS_"},
+		// i=2; void f(char**, char*, char**)
+		{params: []types.Type{types.NewPointer(charPtr), charPtr, types.NewPointer(charPtr)}, want: "_Z1fPPcS_S0_"},
+		// i=3; void f(int, int)
+		{params: []types.Type{types.I32, types.I32}, want: "_Z1fii"},
+		// i=4; void f(T*, T)
+		{params: []types.Type{types.NewPointer(st), st}, want: "_Z1fP1TS_"},
+		// i=5; void a::b::f(void (*)(int), void (*)(int))
+		{namespaces: []string{"a", "b"}, params: []types.Type{fnPtr, fnPtr}, want: "_ZN1a1b1fEPFviES2_"},
+		// i=6; void f(int[4], int*)
+		{params: []types.Type{types.NewArray(4, types.I32), types.I32Ptr}, want: "_Z1fPiS_"},
+		// i=7; void f(int (*)[4], int (*)[4])
+		{params: []types.Type{intArrayPtr, intArrayPtr}, want: "_Z1fPA4_iS0_"},
+	}
+	for i, g := range golden {
+		var params []*ir.Param
+		for _, param := range g.params {
+			params = append(params, ir.NewParam(param, ""))
+		}
+		f := ir.NewFunc("f", types.Void, params...)
+		if got := Itanium(g.namespaces...)(f); g.want != got {
+			t.Errorf("i=%d: name mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
+
+func TestRenameCollision(t *testing.T) {
+	m := &ir.Module{}
+	a := m.NewFunc("a", types.Void)
+	b := m.NewFunc("b", types.Void)
+	c := m.NewFunc("p.a", types.Void)
+	// Rename all functions but p.a, and map both a and b to the same name.
+	same := func(v value.Named) string {
+		return "p.a"
+	}
+	keep := func(v value.Named) bool {
+		return v == c
+	}
+	if err := Rename(m, same, keep); err != nil {
+		t.Fatalf("unable to rename; %+v", err)
+	}
+	if a.Name() != "p.a.1" || b.Name() != "p.a.2" || c.Name() != "p.a" {
+		t.Errorf("collision not resolved; got %q, %q, %q", a.Name(), b.Name(), c.Name())
+	}
+	// Swap names.
+	swap := func(v value.Named) string {
+		if v == a {
+			return "p.a.2"
+		}
+		return "p.a.1"
+	}
+	if err := Rename(m, swap, keep); err != nil {
+		t.Fatalf("unable to rename; %+v", err)
+	}
+	if a.Name() != "p.a.2" || b.Name() != "p.a.1" {
+		t.Errorf("names not swapped; got %q, %q", a.Name(), b.Name())
+	}
+	if HashSuffix("foo.c")(a) == HashSuffix("bar.c")(a) {
+		t.Errorf("expected distinct hash suffixes for distinct salts")
+	}
+}
@@ -0,0 +1,311 @@
+// Package reduce implements reduction of LLVM IR test cases by delta
+// debugging; i.e. iterative removal of the functions, global variables, basic
+// blocks and instructions of a module which are not needed to trigger a bug,
+// similar to llvm-reduce.
+package reduce
+
+import (
+	"github.com/llir/l/analysis"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// Interesting reports whether the given module is interesting; e.g. whether it
+// still triggers the bug being reduced.
+//
+// The predicate must not modify the module.
+type Interesting func(m *ir.Module) bool
+
+// Module reduces the given module in place to a smaller module which is still
+// interesting according to the given predicate. An error is returned if the
+// module is not interesting before reduction.
+//
+// The module is reduced by repeatedly trying to remove chunks of, in order,
+// functions, function bodies, global variables, basic blocks and instructions;
+// halving the chunk size until single entities are tried, and keeping each
+// removal for which the module remains interesting. Reduction stops when no
+// entity may be removed.
+//
+// Uses of removed functions, global variables and instructions are replaced
+// with undef. Terminators branching to removed basic blocks are replaced with
+// unreachable, and phi instructions are updated to the remaining predecessors.
+func Module(m *ir.Module, interesting Interesting) error {
+	if !interesting(m) {
+		return errors.New("module not interesting before reduction")
+	}
+	r := &reducer{m: m, interesting: interesting}
+	for {
+		changed := false
+		changed = r.reduceFuncs() || changed
+		changed = r.reduceBodies() || changed
+		changed = r.reduceGlobals() || changed
+		for _, f := range m.Funcs {
+			changed = r.reduceBlocks(f) || changed
+			changed = r.reduceInsts(f) || changed
+		}
+		if !changed {
+			return nil
+		}
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// reducer is a test case reducer.
+type reducer struct {
+	// Module being reduced.
+	m *ir.Module
+	// Predicate reporting whether a module is interesting.
+	interesting Interesting
+}
+
+// reduceFuncs tries to remove the functions of the module, and reports whether
+// the module was changed.
+func (r *reducer) reduceFuncs() bool {
+	return reduceChunks(len(r.m.Funcs), func(lo, hi int) bool {
+		funcs := r.m.Funcs
+		removed := funcs[lo:hi]
+		r.m.Funcs = append(append([]*ir.Function(nil), funcs[:lo]...), funcs[hi:]...)
+		// Replace uses by unique undef values, which identify the uses to restore
+		// if the module is not interesting.
+		undefs := make([]*ir.ConstUndef, len(removed))
+		for i, f := range removed {
+			undefs[i] = ir.NewUndef(f.Type())
+			ir.ReplaceUses(r.m, f, undefs[i])
+		}
+		if r.interesting(r.m) {
+			for _, f := range removed {
+				r.m.RemoveFunc(f)
+			}
+			return true
+		}
+		r.m.Funcs = funcs
+		for i, f := range removed {
+			ir.ReplaceUses(r.m, undefs[i], f)
+		}
+		return false
+	})
+}
+
+// reduceBodies tries to turn the function definitions of the module into
+// function declarations, and reports whether the module was changed.
+func (r *reducer) reduceBodies() bool {
+	var defs []*ir.Function
+	for _, f := range r.m.Funcs {
+		if len(f.Blocks) > 0 {
+			defs = append(defs, f)
+		}
+	}
+	return reduceChunks(len(defs), func(lo, hi int) bool {
+		removed := defs[lo:hi]
+		saved := make([]ir.Function, len(removed))
+		for i, f := range removed {
+			saved[i] = *f
+			f.Blocks = nil
+			f.Personality = nil
+			if f.Linkage == enum.LinkagePrivate || f.Linkage == enum.LinkageInternal {
+				// Local linkage is only valid for definitions.
+				f.Linkage = enum.LinkageNone
+			}
+		}
+		if r.interesting(r.m) {
+			defs = append(defs[:lo:lo], defs[hi:]...)
+			return true
+		}
+		for i, f := range removed {
+			*f = saved[i]
+		}
+		return false
+	})
+}
+
+// reduceGlobals tries to remove the global variables of the module, and
+// reports whether the module was changed.
+func (r *reducer) reduceGlobals() bool {
+	return reduceChunks(len(r.m.Globals), func(lo, hi int) bool {
+		globals := r.m.Globals
+		removed := globals[lo:hi]
+		r.m.Globals = append(append([]*ir.Global(nil), globals[:lo]...), globals[hi:]...)
+		undefs := make([]*ir.ConstUndef, len(removed))
+		for i, g := range removed {
+			undefs[i] = ir.NewUndef(g.Type())
+			ir.ReplaceUses(r.m, g, undefs[i])
+		}
+		if r.interesting(r.m) {
+			for _, g := range removed {
+				r.m.RemoveGlobal(g)
+			}
+			return true
+		}
+		r.m.Globals = globals
+		for i, g := range removed {
+			ir.ReplaceUses(r.m, undefs[i], g)
+		}
+		return false
+	})
+}
+
+// reduceBlocks tries to remove the basic blocks of the given function, except
+// the entry basic block, and reports whether the function was changed.
+func (r *reducer) reduceBlocks(f *ir.Function) bool {
+	if len(f.Blocks) < 2 {
+		return false
+	}
+	return reduceChunks(len(f.Blocks)-1, func(lo, hi int) bool {
+		return r.tryFunc(f, func(f *ir.Function) {
+			removeBlocks(f, f.Blocks[1+lo:1+hi])
+		})
+	})
+}
+
+// reduceInsts tries to remove the instructions of the given function, and
+// reports whether the function was changed.
+func (r *reducer) reduceInsts(f *ir.Function) bool {
+	n := 0
+	for _, block := range f.Blocks {
+		n += len(block.Insts)
+	}
+	return reduceChunks(n, func(lo, hi int) bool {
+		return r.tryFunc(f, func(f *ir.Function) {
+			removeInsts(f, lo, hi)
+		})
+	})
+}
+
+// tryFunc applies the given edit to a copy of the function f, which replaces
+// the contents of f if the module remains interesting. The boolean return
+// value indicates whether f was changed.
+func (r *reducer) tryFunc(f *ir.Function, edit func(f *ir.Function)) bool {
+	saved := *f
+	*f = *ir.CloneFunc(f)
+	f.Typ = saved.Typ
+	edit(f)
+	if r.interesting(r.m) {
+		return true
+	}
+	*f = saved
+	return false
+}
+
+// reduceChunks repeatedly tries to remove chunks of n entities, halving the
+// chunk size from n to 1, and reports whether any chunk was removed. remove
+// tries to remove the entities in the range [lo, hi), and reports whether they
+// were removed; in which case subsequent entities are shifted to take their
+// place.
+func reduceChunks(n int, remove func(lo, hi int) bool) bool {
+	changed := false
+	for size := n; size > 0; size /= 2 {
+		for lo := 0; lo < n; {
+			hi := lo + size
+			if hi > n {
+				hi = n
+			}
+			if remove(lo, hi) {
+				n -= hi - lo
+				changed = true
+			} else {
+				lo = hi
+			}
+		}
+	}
+	return changed
+}
+
+// removeBlocks removes the given basic blocks of the function. Terminators
+// branching to removed basic blocks are replaced with unreachable.
+func removeBlocks(f *ir.Function, blocks []*ir.BasicBlock) {
+	removed := make(map[*ir.BasicBlock]bool)
+	for _, block := range blocks {
+		removed[block] = true
+	}
+	var keep []*ir.BasicBlock
+	for _, block := range f.Blocks {
+		if removed[block] {
+			for _, inst := range block.Insts {
+				replaceWithUndef(f, inst)
+			}
+			replaceWithUndef(f, block.Term)
+			continue
+		}
+		for _, succ := range block.Term.Succs() {
+			if removed[succ] {
+				block.Term = ir.NewUnreachable()
+				break
+			}
+		}
+		keep = append(keep, block)
+	}
+	f.Blocks = keep
+	fixPhis(f)
+}
+
+// removeInsts removes the instructions in the range [lo, hi) of the function,
+// in order of basic block.
+func removeInsts(f *ir.Function, lo, hi int) {
+	i := 0
+	for _, block := range f.Blocks {
+		var insts []ir.Instruction
+		for _, inst := range block.Insts {
+			if lo <= i && i < hi {
+				replaceWithUndef(f, inst)
+			} else {
+				insts = append(insts, inst)
+			}
+			i++
+		}
+		block.Insts = insts
+	}
+}
+
+// fixPhis removes the incoming values of the phi instructions of the given
+// function from basic blocks which are no longer predecessors. Phi
+// instructions without incoming values are removed.
+func fixPhis(f *ir.Function) {
+	preds := analysis.Preds(f)
+	for _, block := range f.Blocks {
+		var insts []ir.Instruction
+		for _, inst := range block.Insts {
+			if phi, ok := inst.(*ir.InstPhi); ok {
+				t := phi.Type()
+				var incs []*ir.Incoming
+				for _, inc := range phi.Incs {
+					if isPred(preds[block], inc.Pred) {
+						incs = append(incs, inc)
+					}
+				}
+				phi.Incs = incs
+				if len(incs) == 0 {
+					ir.ReplaceUses(f, phi, ir.NewUndef(t))
+					continue
+				}
+			}
+			insts = append(insts, inst)
+		}
+		block.Insts = insts
+	}
+}
+
+// isPred reports whether the given basic block is one of the predecessors.
+func isPred(preds []*ir.BasicBlock, block *ir.BasicBlock) bool {
+	for _, pred := range preds {
+		if pred == block {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceWithUndef replaces the uses of the given instruction or terminator in
+// the function with undef.
+func replaceWithUndef(f *ir.Function, inst interface{}) {
+	v, ok := inst.(value.Value)
+	if !ok {
+		return
+	}
+	if t := v.Type(); !t.Equal(types.Void) {
+		ir.ReplaceUses(f, v, ir.NewUndef(t))
+	}
+}
@@ -0,0 +1,69 @@
+package reduce
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestModule(t *testing.T) {
+	m := &ir.Module{}
+	g := m.NewGlobalDef("g", ir.NewInt(types.I32, 0))
+	x := ir.NewParam(types.I32, "x")
+	helper := m.NewFunc("helper", types.I32, x)
+	entry := helper.NewBlock("entry")
+	entry.NewRet(entry.NewAdd(x, ir.NewInt(types.I32, 1)))
+	a := ir.NewParam(types.I32, "a")
+	f := m.NewFunc("f", types.I32, a)
+	entry = f.NewBlock("entry")
+	left, right, exit := f.NewBlock("left"), f.NewBlock("right"), f.NewBlock("exit")
+	b := entry.NewCall(helper, a)
+	b.SetName("b")
+	c := entry.NewAdd(a, b)
+	c.SetName("c")
+	entry.NewCondBr(entry.NewICmp(enum.IPredSLT, c, ir.NewInt(types.I32, 10)), left, right)
+	d := left.NewUDiv(c, ir.NewInt(types.I32, 0))
+	d.SetName("d")
+	left.NewBr(exit)
+	right.NewStore(c, g)
+	right.NewBr(exit)
+	phi := exit.NewPhi(ir.NewIncoming(d, left), ir.NewIncoming(c, right))
+	phi.SetName("e")
+	exit.NewRet(phi)
+	// The module is interesting if it contains a division by zero.
+	interesting := func(m *ir.Module) bool {
+		for _, f := range m.Funcs {
+			for _, block := range f.Blocks {
+				for _, inst := range block.Insts {
+					if div, ok := inst.(*ir.InstUDiv); ok && div.Y.Ident() == "0" {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+	if err := Module(m, interesting); err != nil {
+		t.Fatalf("unable to reduce module; %+v", err)
+	}
+	want := `define i32 @f(i32 %a) {
+entry:
+	unreachable
+left:
+	%d = udiv i32 undef, 0
+	unreachable
+}
+`
+	if got := m.Def(); got != want {
+		t.Errorf("module mismatch; expected %q, got %q", want, got)
+	}
+	if _, ok := m.Lookup("helper"); ok {
+		t.Errorf("removed function @helper present in symbol table")
+	}
+	// Modules without division by zero are not interesting.
+	if err := Module(&ir.Module{}, interesting); err == nil {
+		t.Errorf("expected error for module not interesting before reduction")
+	}
+}
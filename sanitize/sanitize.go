@@ -0,0 +1,222 @@
+// Package sanitize implements the function attributes and metadata of LLVM IR
+// used by sanitizers (e.g. AddressSanitizer and ThreadSanitizer), to represent
+// and emit instrumented IR.
+package sanitize
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// === [ Sanitizer attributes ] ================================================
+
+// Sanitizer is a sanitizer enabled for functions by a function attribute.
+type Sanitizer uint8
+
+// Sanitizers.
+const (
+	Address   Sanitizer = iota + 1 // sanitize_address
+	HWAddress                      // sanitize_hwaddress
+	Memory                         // sanitize_memory
+	Thread                         // sanitize_thread
+)
+
+// Attr returns the function attribute which enables the sanitizer.
+func (s Sanitizer) Attr() enum.FuncAttr {
+	switch s {
+	case Address:
+		return enum.FuncAttrSanitizeAddress
+	case HWAddress:
+		return enum.FuncAttrSanitizeHWAddress
+	case Memory:
+		return enum.FuncAttrSanitizeMemory
+	case Thread:
+		return enum.FuncAttrSanitizeThread
+	}
+	panic(errors.Errorf("support for sanitizer %d not yet implemented", uint8(s)))
+}
+
+// String returns the string representation of the sanitizer; the name of its
+// function attribute.
+func (s Sanitizer) String() string {
+	return s.Attr().String()
+}
+
+// Enable enables the given sanitizers for the function, by adding their
+// function attributes unless already present.
+func Enable(f *ir.Function, sanitizers ...Sanitizer) {
+	for _, s := range sanitizers {
+		if !Enabled(f, s) {
+			f.FuncAttrs = append(f.FuncAttrs, s.Attr())
+		}
+	}
+}
+
+// Disable disables the given sanitizers for the function, by removing their
+// function attributes; as for functions annotated no_sanitize in the source.
+func Disable(f *ir.Function, sanitizers ...Sanitizer) {
+	attrs := f.FuncAttrs[:0]
+	for _, attr := range f.FuncAttrs {
+		if !hasAttr(sanitizers, attr) {
+			attrs = append(attrs, attr)
+		}
+	}
+	f.FuncAttrs = attrs
+}
+
+// Enabled reports whether the given sanitizer is enabled for the function.
+func Enabled(f *ir.Function, s Sanitizer) bool {
+	for _, attr := range f.FuncAttrs {
+		if attr == s.Attr() {
+			return true
+		}
+	}
+	return false
+}
+
+// === [ Sanitizer metadata ] ==================================================
+
+// --- [ nosanitize ] ----------------------------------------------------------
+
+// NoSanitize marks the given instruction or terminator with a !nosanitize
+// metadata attachment, to exclude it from instrumentation by sanitizers (e.g.
+// instructions inserted by the instrumentation itself).
+func NoSanitize(inst interface{}) {
+	ir.SetMetadata(inst, ir.NewMetadataAttachment(ir.MDKindNoSanitize, ir.NewTuple()))
+}
+
+// IsNoSanitize reports whether the given instruction or terminator is marked
+// with a !nosanitize metadata attachment.
+func IsNoSanitize(inst interface{}) bool {
+	mds, _ := ir.MetadataAttachments(inst)
+	return mds.NoSanitize()
+}
+
+// --- [ AddressSanitizer global descriptions ] --------------------------------
+
+// asanGlobals is the name of the named metadata of AddressSanitizer global
+// descriptions.
+const asanGlobals = "llvm.asan.globals"
+
+// GlobalDesc is an AddressSanitizer description of a global variable, as
+// recorded by the !llvm.asan.globals named metadata of a module; e.g.
+//
+//    !0 = !{i32* @g, !1, !"g", i1 false, i1 false}
+//    !1 = !{!"foo.c", i32 2, i32 5}
+type GlobalDesc struct {
+	// Global variable.
+	Global *ir.Global
+	// (optional) Source location of the global variable; empty filename if not
+	// present.
+	Filename     string
+	Line, Column int64
+	// (optional) Source name of the global variable; empty if not present.
+	Name string
+	// Dynamically initialized global variable (e.g. by a C++ constructor).
+	DynInit bool
+	// Global variable excluded from instrumentation (e.g. by no_sanitize).
+	Excluded bool
+}
+
+// AddGlobalDesc adds the given AddressSanitizer global description to the
+// !llvm.asan.globals named metadata of the module.
+func AddGlobalDesc(m *ir.Module, desc *GlobalDesc) {
+	var loc, name ir.Metadata
+	if len(desc.Filename) > 0 {
+		loc = ir.NewTuple(ir.NewMDString(desc.Filename), ir.NewMDValue(ir.NewInt(types.I32, desc.Line)), ir.NewMDValue(ir.NewInt(types.I32, desc.Column)))
+	}
+	if len(desc.Name) > 0 {
+		name = ir.NewMDString(desc.Name)
+	}
+	md := ir.NewTuple(ir.NewMDValue(desc.Global), loc, name, ir.NewMDValue(boolConst(desc.DynInit)), ir.NewMDValue(boolConst(desc.Excluded)))
+	m.NewMetadataDef(md)
+	named := m.NamedMetadata(asanGlobals)
+	named.Nodes = append(named.Nodes, md)
+}
+
+// GlobalDescs returns the AddressSanitizer global descriptions recorded by the
+// !llvm.asan.globals named metadata of the module.
+func GlobalDescs(m *ir.Module) ([]*GlobalDesc, error) {
+	var descs []*GlobalDesc
+	for _, named := range m.NamedMetadataDefs {
+		if named.Name != asanGlobals {
+			continue
+		}
+		for _, node := range named.Nodes {
+			desc, err := parseGlobalDesc(node)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			descs = append(descs, desc)
+		}
+	}
+	return descs, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// hasAttr reports whether the given function attribute enables one of the
+// given sanitizers.
+func hasAttr(sanitizers []Sanitizer, attr ir.FuncAttribute) bool {
+	for _, s := range sanitizers {
+		if attr == s.Attr() {
+			return true
+		}
+	}
+	return false
+}
+
+// boolConst returns the i1 constant of the given boolean value.
+func boolConst(x bool) *ir.ConstInt {
+	if x {
+		return ir.True
+	}
+	return ir.False
+}
+
+// parseGlobalDesc parses the given AddressSanitizer global description
+// metadata node.
+func parseGlobalDesc(node ir.MDNode) (*GlobalDesc, error) {
+	tuple, ok := node.(*ir.MDTuple)
+	if !ok || len(tuple.Fields) != 5 {
+		return nil, errors.Errorf("invalid AddressSanitizer global description %v; expected tuple of 5 fields", node)
+	}
+	desc := &GlobalDesc{}
+	if v, ok := tuple.Fields[0].(*ir.MDValue); ok {
+		desc.Global, _ = v.Value.(*ir.Global)
+	}
+	if desc.Global == nil {
+		return nil, errors.Errorf("invalid global variable of AddressSanitizer global description %v", node)
+	}
+	if loc, ok := tuple.Fields[1].(*ir.MDTuple); ok {
+		if len(loc.Fields) != 3 {
+			return nil, errors.Errorf("invalid source location of AddressSanitizer global description %v", node)
+		}
+		filename, ok := loc.Fields[0].(*ir.MDString)
+		if !ok {
+			return nil, errors.Errorf("invalid source filename of AddressSanitizer global description %v", node)
+		}
+		desc.Filename = filename.Value
+		desc.Line = intField(loc.Fields[1])
+		desc.Column = intField(loc.Fields[2])
+	}
+	if name, ok := tuple.Fields[2].(*ir.MDString); ok {
+		desc.Name = name.Value
+	}
+	desc.DynInit = intField(tuple.Fields[3]) != 0
+	desc.Excluded = intField(tuple.Fields[4]) != 0
+	return desc, nil
+}
+
+// intField returns the integer value of the given metadata field, or zero if
+// not an integer constant.
+func intField(md ir.Metadata) int64 {
+	if v, ok := md.(*ir.MDValue); ok {
+		if c, ok := v.Value.(*ir.ConstInt); ok {
+			return c.X.Int64()
+		}
+	}
+	return 0
+}
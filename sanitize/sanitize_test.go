@@ -0,0 +1,55 @@
+package sanitize
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestSanitize(t *testing.T) {
+	m := &ir.Module{}
+	g := m.NewGlobalDef("g", ir.NewInt(types.I32, 0))
+	f := m.NewFunc("f", types.Void)
+	f.FuncAttrs = append(f.FuncAttrs, enum.FuncAttrNoUnwind)
+	entry := f.NewBlock("entry")
+	load := entry.NewLoad(g)
+	entry.NewRet(nil)
+	Enable(f, Address, Thread, Address)
+	Disable(f, Thread)
+	NoSanitize(load)
+	if !Enabled(f, Address) || Enabled(f, Thread) {
+		t.Errorf("sanitizer mismatch; expected sanitize_address only, got %v", f.FuncAttrs)
+	}
+	if !IsNoSanitize(load) || IsNoSanitize(entry.Term) {
+		t.Errorf("nosanitize mismatch of %v and %v", load.Def(), entry.Term.Def())
+	}
+	want := &GlobalDesc{Global: g, Filename: "foo.c", Line: 2, Column: 5, Name: "g", Excluded: true}
+	AddGlobalDesc(m, want)
+	AddGlobalDesc(m, &GlobalDesc{Global: g, DynInit: true})
+	wantDef := `@g = global i32 0
+define void @f() nounwind sanitize_address {
+entry:
+	%0 = load i32, i32* @g, !nosanitize !{}
+	ret void
+}
+!llvm.asan.globals = !{!0, !1}
+!0 = !{i32* @g, !{!"foo.c", i32 2, i32 5}, !"g", i1 false, i1 true}
+!1 = !{i32* @g, null, null, i1 true, i1 false}
+`
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %+v", err)
+	}
+	if got := m.Def(); wantDef != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", wantDef, got)
+	}
+	descs, err := GlobalDescs(m)
+	if err != nil {
+		t.Fatalf("unable to parse AddressSanitizer global descriptions; %+v", err)
+	}
+	if len(descs) != 2 || !reflect.DeepEqual(descs[0], want) || !descs[1].DynInit {
+		t.Errorf("global description mismatch; expected %v, got %v", want, descs)
+	}
+}
@@ -0,0 +1,248 @@
+// Package ssa implements on-the-fly construction of static single assignment
+// form for language frontends.
+//
+// Frontends declare mutable variables and issue reads and writes of these
+// variables per basic block. Phi instructions are inserted as needed, and
+// trivial phi instructions are removed, as described by Braun et al.
+//
+// References:
+//    Simple and Efficient Construction of Static Single Assignment Form (2013)
+//    https://pp.info.uni-karlsruhe.de/uploads/publikationen/braun13cc.pdf
+package ssa
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+// Variable is a mutable source language variable.
+type Variable struct {
+	// Variable name.
+	Name string
+	// Variable type.
+	Typ types.Type
+}
+
+// Builder is an SSA construction helper of a function.
+//
+// The basic blocks passed to the builder must be part of the function. A basic
+// block is sealed once all its predecessors are known; i.e. when the
+// terminators of every predecessor basic block have been set.
+type Builder struct {
+	// Function under construction.
+	f *ir.Function
+	// Current definition of each variable per basic block.
+	defs map[*Variable]map[*ir.BasicBlock]value.Value
+	// Sealed basic blocks.
+	sealed map[*ir.BasicBlock]bool
+	// Operandless phi instructions of unsealed basic blocks, in order of
+	// insertion.
+	incompletePhis map[*ir.BasicBlock][]*incompletePhi
+	// Parent basic block of each inserted phi instruction.
+	phiBlocks map[*ir.InstPhi]*ir.BasicBlock
+}
+
+// NewBuilder returns a new SSA construction helper for the given function.
+func NewBuilder(f *ir.Function) *Builder {
+	return &Builder{
+		f:              f,
+		defs:           make(map[*Variable]map[*ir.BasicBlock]value.Value),
+		sealed:         make(map[*ir.BasicBlock]bool),
+		incompletePhis: make(map[*ir.BasicBlock][]*incompletePhi),
+		phiBlocks:      make(map[*ir.InstPhi]*ir.BasicBlock),
+	}
+}
+
+// NewVariable returns a new mutable variable based on the given name and type.
+func (b *Builder) NewVariable(name string, typ types.Type) *Variable {
+	v := &Variable{Name: name, Typ: typ}
+	b.defs[v] = make(map[*ir.BasicBlock]value.Value)
+	return v
+}
+
+// Write records x as the current value of the variable in the given basic
+// block.
+func (b *Builder) Write(v *Variable, block *ir.BasicBlock, x value.Value) {
+	b.defs[v][block] = x
+}
+
+// Read returns the current value of the variable in the given basic block,
+// inserting phi instructions as needed. Reading a variable which has not been
+// written on some path yields an undefined value.
+func (b *Builder) Read(v *Variable, block *ir.BasicBlock) value.Value {
+	if x, ok := b.defs[v][block]; ok {
+		// Local value numbering.
+		return x
+	}
+	// Global value numbering.
+	return b.readRecursive(v, block)
+}
+
+// Seal marks the given basic block as sealed, signaling that all its
+// predecessors are known. Phi instructions inserted while the basic block was
+// unsealed receive their incoming values.
+func (b *Builder) Seal(block *ir.BasicBlock) {
+	for _, p := range b.incompletePhis[block] {
+		if _, ok := b.phiBlocks[p.phi]; !ok {
+			// Phi instruction already removed.
+			continue
+		}
+		b.addPhiOperands(p.v, p.phi)
+	}
+	delete(b.incompletePhis, block)
+	b.sealed[block] = true
+}
+
+// ### [ Helper functions ] ####################################################
+
+// incompletePhi is an operandless phi instruction for a variable of an unsealed
+// basic block.
+type incompletePhi struct {
+	// Variable of the phi instruction.
+	v *Variable
+	// Phi instruction.
+	phi *ir.InstPhi
+}
+
+// readRecursive returns the value of the variable in the given basic block, as
+// defined in its predecessors.
+func (b *Builder) readRecursive(v *Variable, block *ir.BasicBlock) value.Value {
+	var x value.Value
+	if !b.sealed[block] {
+		// Incomplete CFG.
+		phi := b.newPhi(v, block)
+		b.incompletePhis[block] = append(b.incompletePhis[block], &incompletePhi{v: v, phi: phi})
+		x = phi
+	} else if preds := b.preds(block); len(preds) == 0 {
+		// Variable read before write.
+		x = ir.NewUndef(v.Typ)
+	} else if len(preds) == 1 {
+		// Optimize the common case of one predecessor; no phi needed.
+		x = b.Read(v, preds[0])
+	} else {
+		// Break potential cycles with operandless phi.
+		phi := b.newPhi(v, block)
+		b.Write(v, block, phi)
+		x = b.addPhiOperands(v, phi)
+	}
+	b.Write(v, block, x)
+	return x
+}
+
+// newPhi inserts an operandless phi instruction for the variable after the
+// phi instructions of the given basic block.
+func (b *Builder) newPhi(v *Variable, block *ir.BasicBlock) *ir.InstPhi {
+	phi := &ir.InstPhi{Typ: v.Typ}
+	i := 0
+	for i < len(block.Insts) {
+		if _, ok := block.Insts[i].(*ir.InstPhi); !ok {
+			break
+		}
+		i++
+	}
+	block.InsertInst(i, phi)
+	b.phiBlocks[phi] = block
+	return phi
+}
+
+// addPhiOperands adds an incoming value for each predecessor of the basic
+// block of the phi instruction, and returns the phi instruction or the value
+// it was simplified to.
+func (b *Builder) addPhiOperands(v *Variable, phi *ir.InstPhi) value.Value {
+	for _, pred := range b.preds(b.phiBlocks[phi]) {
+		phi.Incs = append(phi.Incs, ir.NewIncoming(b.Read(v, pred), pred))
+	}
+	return b.tryRemoveTrivialPhi(phi)
+}
+
+// tryRemoveTrivialPhi removes the given phi instruction if it merges only one
+// distinct value (besides itself), replacing its uses with that value.
+func (b *Builder) tryRemoveTrivialPhi(phi *ir.InstPhi) value.Value {
+	var same value.Value
+	for _, inc := range phi.Incs {
+		if inc.X == same || inc.X == phi {
+			// Unique value or self-reference.
+			continue
+		}
+		if same != nil {
+			// The phi merges at least two values; not trivial.
+			return phi
+		}
+		same = inc.X
+	}
+	if same == nil {
+		// The phi is unreachable or in the entry block.
+		same = ir.NewUndef(phi.Typ)
+	}
+	// Remember all users except the phi itself.
+	users := b.phiUsers(phi)
+	// Reroute all uses of phi to same and remove phi.
+	ir.ReplaceUses(b.f, phi, same)
+	for _, blockDefs := range b.defs {
+		for block, x := range blockDefs {
+			if x == phi {
+				blockDefs[block] = same
+			}
+		}
+	}
+	b.removePhi(phi)
+	// Try to recursively remove all phi users, which might have become
+	// trivial.
+	for _, user := range users {
+		if _, ok := b.phiBlocks[user]; ok {
+			b.tryRemoveTrivialPhi(user)
+		}
+	}
+	return same
+}
+
+// phiUsers returns the phi instructions inserted by the builder which use the
+// given phi instruction, except the phi instruction itself, in order of basic
+// blocks and instructions of the function.
+func (b *Builder) phiUsers(phi *ir.InstPhi) []*ir.InstPhi {
+	var users []*ir.InstPhi
+	for _, block := range b.f.Blocks {
+		for _, inst := range block.Insts {
+			user, ok := inst.(*ir.InstPhi)
+			if !ok || user == phi {
+				continue
+			}
+			if _, ok := b.phiBlocks[user]; !ok {
+				continue
+			}
+			for _, inc := range user.Incs {
+				if inc.X == phi {
+					users = append(users, user)
+					break
+				}
+			}
+		}
+	}
+	return users
+}
+
+// removePhi removes the given phi instruction from its parent basic block.
+func (b *Builder) removePhi(phi *ir.InstPhi) {
+	block := b.phiBlocks[phi]
+	delete(b.phiBlocks, phi)
+	block.RemoveInst(phi)
+}
+
+// preds returns the predecessor basic blocks of the given basic block, in the
+// order of the basic blocks of the function.
+func (b *Builder) preds(block *ir.BasicBlock) []*ir.BasicBlock {
+	var preds []*ir.BasicBlock
+	for _, pred := range b.f.Blocks {
+		if pred.Term == nil {
+			continue
+		}
+		for _, succ := range pred.Term.Succs() {
+			if succ == block {
+				preds = append(preds, pred)
+				break
+			}
+		}
+	}
+	return preds
+}
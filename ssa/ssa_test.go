@@ -0,0 +1,152 @@
+package ssa
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestBuilder(t *testing.T) {
+	// Build the following function, where x is a mutable variable.
+	//
+	//    x = 0
+	//    for x < n {
+	//       x = x + 1
+	//    }
+	//    return x
+	n := ir.NewParam(types.I32, "n")
+	f := ir.NewFunc("f", types.I32, n)
+	entry := ir.NewBlock("entry")
+	loop := ir.NewBlock("loop")
+	body := ir.NewBlock("body")
+	exit := ir.NewBlock("exit")
+	f.Blocks = append(f.Blocks, entry, loop, body, exit)
+	b := NewBuilder(f)
+	x := b.NewVariable("x", types.I32)
+	// entry.
+	b.Write(x, entry, ir.NewInt(types.I32, 0))
+	entry.NewBr(loop)
+	b.Seal(entry)
+	// loop; unsealed as the back edge from body is not yet known.
+	cond := loop.NewICmp(enum.IPredSLT, b.Read(x, loop), n)
+	cond.SetName("cond")
+	loop.NewCondBr(cond, body, exit)
+	// body.
+	b.Seal(body)
+	inc := body.NewAdd(b.Read(x, body), ir.NewInt(types.I32, 1))
+	inc.SetName("inc")
+	b.Write(x, body, inc)
+	body.NewBr(loop)
+	b.Seal(loop)
+	// exit.
+	b.Seal(exit)
+	exit.NewRet(b.Read(x, exit))
+	if len(loop.Insts) != 2 {
+		t.Fatalf("invalid number of instructions in loop; expected 2 (phi and icmp), got %d", len(loop.Insts))
+	}
+	phi, ok := loop.Insts[0].(*ir.InstPhi)
+	if !ok {
+		t.Fatalf("invalid first instruction of loop; expected *ir.InstPhi, got %T", loop.Insts[0])
+	}
+	phi.SetName("x")
+	want := "phi i32 [ 0, %entry ], [ %inc, %body ]"
+	if got := phi.Def(); want != got {
+		t.Errorf("phi mismatch; expected `%v`, got `%v`", want, got)
+	}
+	if got := exit.Term.(*ir.TermRet).X; got != phi {
+		t.Errorf("return value mismatch; expected %v, got %v", phi, got)
+	}
+}
+
+func TestBuilderTrivialPhi(t *testing.T) {
+	// A variable which is not modified in a loop requires no phi.
+	f := ir.NewFunc("f", types.I32)
+	entry := ir.NewBlock("entry")
+	loop := ir.NewBlock("loop")
+	exit := ir.NewBlock("exit")
+	f.Blocks = append(f.Blocks, entry, loop, exit)
+	b := NewBuilder(f)
+	x := b.NewVariable("x", types.I32)
+	c := ir.NewInt(types.I32, 42)
+	b.Write(x, entry, c)
+	entry.NewBr(loop)
+	b.Seal(entry)
+	b.Read(x, loop)
+	loop.NewCondBr(ir.NewInt(types.I1, 0), loop, exit)
+	b.Seal(loop)
+	b.Seal(exit)
+	exit.NewRet(b.Read(x, exit))
+	if len(loop.Insts) != 0 {
+		t.Errorf("invalid number of instructions in loop; expected 0, got %d", len(loop.Insts))
+	}
+	if got := exit.Term.(*ir.TermRet).X; got != c {
+		t.Errorf("return value mismatch; expected %v, got %v", c, got)
+	}
+}
+
+func TestBuilderPhiOrder(t *testing.T) {
+	// Phi instructions are inserted in the order their variables are read; also
+	// those inserted while sealing a basic block.
+	//
+	//    loop:
+	//       if c {
+	//          x, y, z, w = 1, 2, 3, 4
+	//       }
+	//       if c { goto loop }
+	c := ir.NewParam(types.I1, "c")
+	f := ir.NewFunc("f", types.Void, c)
+	entry := ir.NewBlock("entry")
+	loop := ir.NewBlock("loop")
+	then := ir.NewBlock("then")
+	latch := ir.NewBlock("latch")
+	exit := ir.NewBlock("exit")
+	f.Blocks = append(f.Blocks, entry, loop, then, latch, exit)
+	b := NewBuilder(f)
+	var vars []*Variable
+	for _, name := range []string{"x", "y", "z", "w"} {
+		v := b.NewVariable(name, types.I32)
+		b.Write(v, entry, ir.NewInt(types.I32, 0))
+		vars = append(vars, v)
+	}
+	entry.NewBr(loop)
+	b.Seal(entry)
+	// loop; unsealed as the back edge from latch is not yet known.
+	for _, v := range vars {
+		b.Read(v, loop)
+	}
+	loop.NewCondBr(c, then, latch)
+	// then.
+	b.Seal(then)
+	for i, v := range vars {
+		b.Write(v, then, ir.NewInt(types.I32, int64(i+1)))
+	}
+	then.NewBr(latch)
+	// latch.
+	b.Seal(latch)
+	latch.NewCondBr(c, loop, exit)
+	// Sealing loop reads the variables in latch, inserting phis in latch.
+	b.Seal(loop)
+	b.Seal(exit)
+	exit.NewRet(nil)
+	if len(loop.Insts) != len(vars) || len(latch.Insts) != len(vars) {
+		t.Fatalf("invalid number of instructions; expected %d phis in loop and latch, got %d and %d", len(vars), len(loop.Insts), len(latch.Insts))
+	}
+	for i := range vars {
+		loopPhi, ok := loop.Insts[i].(*ir.InstPhi)
+		if !ok {
+			t.Fatalf("invalid instruction %d of loop; expected *ir.InstPhi, got %T", i, loop.Insts[i])
+		}
+		latchPhi, ok := latch.Insts[i].(*ir.InstPhi)
+		if !ok {
+			t.Fatalf("invalid instruction %d of latch; expected *ir.InstPhi, got %T", i, latch.Insts[i])
+		}
+		loopPhi.SetName(vars[i].Name)
+		want := fmt.Sprintf("phi i32 [ %%%s, %%loop ], [ %d, %%then ]", vars[i].Name, i+1)
+		if got := latchPhi.Def(); want != got {
+			t.Errorf("phi %d of latch mismatch; expected `%v`, got `%v`", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Package summary implements ThinLTO-style summaries of LLVM IR modules, for
+// summary-based whole-program decisions (e.g. importing and internalization)
+// across modules.
+package summary
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Module summaries ] ====================================================
+
+// ModuleSummary is a summary of an LLVM IR module.
+type ModuleSummary struct {
+	// Source filename of the module; or empty if not present.
+	SourceFilename string `json:"source_filename,omitempty"`
+	// Summaries of the functions of the module, in module order.
+	Funcs []*FuncSummary `json:"funcs,omitempty"`
+	// Summaries of the global variables of the module, in module order.
+	Globals []*GlobalSummary `json:"globals,omitempty"`
+}
+
+// FuncSummary is a summary of a function declaration or definition.
+type FuncSummary struct {
+	// Global name of the function (without '@' prefix).
+	Name string `json:"name"`
+	// Global unique identifier of the function; see GUID.
+	GUID uint64 `json:"guid"`
+	// Linkage of the function (e.g. "external" or "internal").
+	Linkage string `json:"linkage"`
+	// Function declaration.
+	Declaration bool `json:"declaration,omitempty"`
+	// Size of the function; the number of instructions and terminators.
+	Size int `json:"size"`
+	// Number of basic blocks of the function.
+	NumBlocks int `json:"num_blocks"`
+	// Direct calls to other functions, sorted by callee name.
+	Calls []*CallEdge `json:"calls,omitempty"`
+	// Number of indirect calls.
+	NumIndirectCalls int `json:"num_indirect_calls,omitempty"`
+	// Global names of the global variables and functions referenced other than
+	// as direct callees, sorted by name.
+	Refs []string `json:"refs,omitempty"`
+}
+
+// CallEdge is a call edge of the call graph, from a function to a callee.
+type CallEdge struct {
+	// Global name of the callee (without '@' prefix).
+	Callee string `json:"callee"`
+	// Number of call sites of the callee in the calling function.
+	Count int `json:"count"`
+}
+
+// GlobalSummary is a summary of a global variable declaration or definition.
+type GlobalSummary struct {
+	// Global name of the global variable (without '@' prefix).
+	Name string `json:"name"`
+	// Global unique identifier of the global variable; see GUID.
+	GUID uint64 `json:"guid"`
+	// Linkage of the global variable (e.g. "external" or "internal").
+	Linkage string `json:"linkage"`
+	// Global variable declaration.
+	Declaration bool `json:"declaration,omitempty"`
+	// Immutable global variable (constant).
+	Immutable bool `json:"immutable,omitempty"`
+	// Global names of the global variables and functions referenced by the
+	// initial value, sorted by name.
+	Refs []string `json:"refs,omitempty"`
+}
+
+// New returns the summary of the given module.
+func New(m *ir.Module) *ModuleSummary {
+	s := &ModuleSummary{SourceFilename: m.SourceFilename}
+	for _, f := range m.Funcs {
+		s.Funcs = append(s.Funcs, funcSummary(m, f))
+	}
+	for _, g := range m.Globals {
+		s.Globals = append(s.Globals, globalSummary(m, g))
+	}
+	return s
+}
+
+// Func returns the summary of the function with the given global name, or nil
+// if not present.
+func (s *ModuleSummary) Func(name string) *FuncSummary {
+	for _, f := range s.Funcs {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Global returns the summary of the global variable with the given global
+// name, or nil if not present.
+func (s *ModuleSummary) Global(name string) *GlobalSummary {
+	for _, g := range s.Globals {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+// Encode writes the JSON encoding of the module summary to w.
+func (s *ModuleSummary) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(s); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Decode reads a JSON encoded module summary from r.
+func Decode(r io.Reader) (*ModuleSummary, error) {
+	s := &ModuleSummary{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return s, nil
+}
+
+// GUID returns the global unique identifier of the global variable or function
+// with the given global name and linkage, defined in a module with the given
+// source filename. As in LLVM, the GUID is the lower 64 bits of the MD5 hash of
+// the global name, prefixed by the source filename for local linkage.
+func GUID(name string, linkage enum.Linkage, sourceFilename string) uint64 {
+	if isLocal(linkage) {
+		if len(sourceFilename) == 0 {
+			sourceFilename = "<unknown>"
+		}
+		name = sourceFilename + ":" + name
+	}
+	sum := md5.Sum([]byte(name))
+	return binary.LittleEndian.Uint64(sum[:8])
+}
+
+// ### [ Helper functions ] ####################################################
+
+// funcSummary returns the summary of the given function of the module.
+func funcSummary(m *ir.Module, f *ir.Function) *FuncSummary {
+	s := &FuncSummary{
+		Name:        f.Name(),
+		GUID:        GUID(f.Name(), f.Linkage, m.SourceFilename),
+		Linkage:     linkageString(f.Linkage),
+		Declaration: len(f.Blocks) == 0,
+		NumBlocks:   len(f.Blocks),
+	}
+	calls := make(map[string]int)
+	refs := make(map[string]bool)
+	visit := func(node interface{}) {
+		var callee value.Value
+		switch node := node.(type) {
+		case *ir.InstCall:
+			callee = node.Callee
+		case *ir.TermInvoke:
+			callee = node.Invokee
+		}
+		// Global name of the direct callee; or empty if not present.
+		calleeName := ""
+		if callee != nil {
+			if g, ok := callee.(*ir.Function); ok {
+				calleeName = g.Name()
+				calls[calleeName]++
+			} else {
+				s.NumIndirectCalls++
+			}
+		}
+		for _, name := range globalRefs(node) {
+			if len(calleeName) > 0 && name == calleeName {
+				// Skip the first use of the callee, which is the direct call.
+				calleeName = ""
+				continue
+			}
+			refs[name] = true
+		}
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			s.Size++
+			visit(inst)
+		}
+		if block.Term != nil {
+			s.Size++
+			visit(block.Term)
+		}
+	}
+	for _, c := range []ir.Constant{f.Prefix, f.Prologue, f.Personality} {
+		if c != nil {
+			for _, name := range globalRefs(c) {
+				refs[name] = true
+			}
+		}
+	}
+	for _, callee := range sortedKeys(calls) {
+		s.Calls = append(s.Calls, &CallEdge{Callee: callee, Count: calls[callee]})
+	}
+	s.Refs = sortedNames(refs)
+	return s
+}
+
+// globalSummary returns the summary of the given global variable of the
+// module.
+func globalSummary(m *ir.Module, g *ir.Global) *GlobalSummary {
+	s := &GlobalSummary{
+		Name:        g.Name(),
+		GUID:        GUID(g.Name(), g.Linkage, m.SourceFilename),
+		Linkage:     linkageString(g.Linkage),
+		Declaration: g.Init == nil && g.LazyInit == nil,
+		Immutable:   g.Immutable,
+	}
+	init := g.Init
+	if init == nil && g.LazyInit != nil {
+		init = g.LazyInit()
+	}
+	if init != nil {
+		refs := make(map[string]bool)
+		for _, name := range globalRefs(init) {
+			refs[name] = true
+		}
+		s.Refs = sortedNames(refs)
+	}
+	return s
+}
+
+// globalRefs returns the global names of the global variables and functions
+// used as operands of the given instruction, terminator or constant, in
+// operand order. Names are repeated for repeated uses.
+func globalRefs(node interface{}) []string {
+	var names []string
+	ir.Walk(node, func(n interface{}) bool {
+		switch n := n.(type) {
+		case *ir.Global:
+			names = append(names, n.Name())
+			return false
+		case *ir.Function:
+			names = append(names, n.Name())
+			return false
+		}
+		return true
+	})
+	return names
+}
+
+// isLocal reports whether the given linkage is local to its module.
+func isLocal(linkage enum.Linkage) bool {
+	return linkage == enum.LinkagePrivate || linkage == enum.LinkageInternal
+}
+
+// linkageString returns the string representation of the given linkage, where
+// the default linkage is external.
+func linkageString(linkage enum.Linkage) string {
+	if linkage == enum.LinkageNone {
+		return enum.LinkageExternal.String()
+	}
+	return linkage.String()
+}
+
+// sortedKeys returns the keys of the given map in sorted order.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedNames returns the names of the given set in sorted order; or nil if
+// empty.
+func sortedNames(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
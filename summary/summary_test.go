@@ -0,0 +1,76 @@
+package summary
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestNew(t *testing.T) {
+	// @x = internal global i32 0
+	// @fp = constant void ()* @h
+	//
+	// declare void @h()
+	//
+	// define void @g() {
+	// entry:
+	//    store i32 1, i32* @x
+	//    call void @h()
+	//    call void @h()
+	//    ret void
+	// }
+	m := &ir.Module{SourceFilename: "foo.c"}
+	x := m.NewGlobalDef("x", ir.NewInt(types.I32, 0))
+	x.Linkage = enum.LinkageInternal
+	h := m.NewFunc("h", types.Void)
+	fp := m.NewGlobalDef("fp", h)
+	fp.Immutable = true
+	g := m.NewFunc("g", types.Void)
+	entry := g.NewBlock("entry")
+	entry.NewStore(ir.NewInt(types.I32, 1), x)
+	entry.NewCall(h)
+	entry.NewCall(h)
+	entry.NewRet(nil)
+	s := New(m)
+	want := &FuncSummary{
+		Name:      "g",
+		GUID:      GUID("g", enum.LinkageNone, "foo.c"),
+		Linkage:   "external",
+		Size:      4,
+		NumBlocks: 1,
+		Calls:     []*CallEdge{{Callee: "h", Count: 2}},
+		Refs:      []string{"x"},
+	}
+	if got := s.Func("g"); !reflect.DeepEqual(want, got) {
+		t.Errorf("function summary mismatch; expected %+v, got %+v", want, got)
+	}
+	if got := s.Func("h"); !got.Declaration || got.Size != 0 {
+		t.Errorf("expected declaration summary of @h, got %+v", got)
+	}
+	if got, want := s.Global("fp").Refs, []string{"h"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("global variable references mismatch; expected %v, got %v", want, got)
+	}
+	// GUIDs of local symbols are qualified by the source filename.
+	if GUID("x", enum.LinkageInternal, "foo.c") == GUID("x", enum.LinkageInternal, "bar.c") {
+		t.Errorf("expected distinct GUIDs of internal global variables of different modules")
+	}
+	if s.Global("x").GUID != GUID("x", enum.LinkageInternal, "foo.c") {
+		t.Errorf("GUID mismatch of internal global variable")
+	}
+	// Serialization round-trip.
+	buf := &bytes.Buffer{}
+	if err := s.Encode(buf); err != nil {
+		t.Fatalf("unable to encode module summary; %+v", err)
+	}
+	got, err := Decode(buf)
+	if err != nil {
+		t.Fatalf("unable to decode module summary; %+v", err)
+	}
+	if !reflect.DeepEqual(s, got) {
+		t.Errorf("module summary mismatch after round-trip; expected %+v, got %+v", s, got)
+	}
+}
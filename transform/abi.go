@@ -0,0 +1,275 @@
+package transform
+
+import (
+	"strings"
+
+	"github.com/llir/l/datalayout"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Aggregate ABI lowering ] ==============================================
+
+// LowerAggregates rewrites the functions of the module which return or take as
+// parameters aggregates (structures and arrays) larger than maxSize bytes, and
+// reports whether the module was changed. Sizes are given by the allocation
+// size of the data layout of the module.
+//
+// Large aggregate return values are returned through a new leading sret
+// pointer parameter (named "agg.result"), in which the function stores the
+// return value before returning void. Large aggregate parameters are passed by
+// pointer to a copy of the argument, as byval pointer parameters which are
+// loaded in the entry basic block of the function.
+//
+// Direct call instructions of the rewritten functions are updated
+// accordingly, by passing pointers to stack temporaries allocated in the entry
+// basic block of the caller. Functions used in any other way (e.g. address
+// taken, or called by invoke or callbr terminators) and intrinsics are left as
+// is, as their uses may not be rewritten.
+func LowerAggregates(m *ir.Module, maxSize int64) (bool, error) {
+	dl, err := datalayout.Parse(m.DataLayout)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	calls := directCalls(m)
+	changed := false
+	for _, f := range m.Funcs {
+		fcalls, ok := calls[f]
+		if !ok || strings.HasPrefix(f.Name(), "llvm.") {
+			continue
+		}
+		l := &abiLowering{dl: dl, maxSize: maxSize}
+		if !l.lowerFunc(f) {
+			continue
+		}
+		for _, call := range fcalls {
+			l.lowerCall(call.caller, call.inst)
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// abiLowering is the aggregate ABI lowering of a function.
+type abiLowering struct {
+	// Data layout of the module.
+	dl *datalayout.DataLayout
+	// Maximum size in bytes of aggregates passed by value.
+	maxSize int64
+	// Aggregate return value returned through an sret pointer parameter.
+	sret bool
+	// Return type of the function before lowering.
+	retType types.Type
+	// Indices of aggregate parameters passed as byval pointer parameters, in
+	// the original parameter list.
+	byval map[int]bool
+}
+
+// isLarge reports whether the given type is an aggregate larger than the
+// maximum size of aggregates passed by value.
+func (l *abiLowering) isLarge(t types.Type) bool {
+	switch t.(type) {
+	case *types.StructType, *types.ArrayType:
+		return l.dl.TypeAllocSize(t) > l.maxSize
+	}
+	return false
+}
+
+// lowerFunc rewrites the signature and body of the given function, and reports
+// whether the function was changed.
+func (l *abiLowering) lowerFunc(f *ir.Function) bool {
+	retType := f.Sig.RetType
+	l.retType = retType
+	l.sret = l.isLarge(retType)
+	l.byval = make(map[int]bool)
+	for i, param := range f.Params {
+		if l.isLarge(param.Type()) {
+			l.byval[i] = true
+		}
+	}
+	if !l.sret && len(l.byval) == 0 {
+		return false
+	}
+	names := localNames(f)
+	var params []*ir.Param
+	var sret *ir.Param
+	if l.sret {
+		sret = ir.NewParam(types.NewPointer(retType), uniqueName(names, "agg.result"))
+		sret.Attrs = append(sret.Attrs, enum.ParamAttrSRet, enum.ParamAttrNoAlias)
+		params = append(params, sret)
+		f.ReturnAttrs = nil
+	}
+	// Loads of byval pointer parameters, inserted in the entry basic block.
+	var loads []ir.Instruction
+	for i, param := range f.Params {
+		if !l.byval[i] {
+			params = append(params, param)
+			continue
+		}
+		ptr := ir.NewParam(types.NewPointer(param.Type()), param.Name())
+		ptr.Attrs = append(ptr.Attrs, enum.ParamAttrByval)
+		params = append(params, ptr)
+		if len(f.Blocks) == 0 {
+			continue
+		}
+		load := ir.NewLoad(ptr)
+		load.SetName(derivedName(names, param.Name(), "val"))
+		ir.ReplaceUses(f, param, load)
+		loads = append(loads, load)
+	}
+	if l.sret {
+		retType = types.Void
+	}
+	setParams(f, retType, params)
+	if len(f.Blocks) == 0 {
+		return true
+	}
+	entry := f.Blocks[0]
+	entry.InsertInst(0, loads...)
+	if l.sret {
+		for _, block := range f.Blocks {
+			ret, ok := block.Term.(*ir.TermRet)
+			if !ok || ret.X == nil {
+				continue
+			}
+			block.NewStore(ret.X, sret)
+			ret.X = nil
+		}
+	}
+	return true
+}
+
+// lowerCall rewrites the given direct call instruction of the caller function,
+// to call the rewritten callee.
+func (l *abiLowering) lowerCall(caller *ir.Function, call *ir.InstCall) {
+	var allocas, before, after []ir.Instruction
+	var args []value.Value
+	if l.sret {
+		tmp := ir.NewAlloca(l.retType)
+		tmp.Alignment = int(l.dl.ABIAlign(l.retType))
+		allocas = append(allocas, tmp)
+		args = append(args, ir.NewArg(tmp, enum.ParamAttrSRet, enum.ParamAttrNoAlias))
+		load := ir.NewLoad(tmp)
+		load.SetName(call.Name())
+		call.SetName("")
+		ir.ReplaceUses(caller, call, load)
+		after = append(after, load)
+		call.ReturnAttrs = nil
+	}
+	for i, arg := range call.Args {
+		if !l.byval[i] {
+			args = append(args, arg)
+			continue
+		}
+		if a, ok := arg.(*ir.Arg); ok {
+			arg = a.Value
+		}
+		tmp := ir.NewAlloca(arg.Type())
+		tmp.Alignment = int(l.dl.ABIAlign(arg.Type()))
+		allocas = append(allocas, tmp)
+		before = append(before, ir.NewStore(arg, tmp))
+		args = append(args, ir.NewArg(tmp, enum.ParamAttrByval))
+	}
+	call.Args = args
+	// Recompute the cached type of the call instruction.
+	call.Typ = nil
+	for _, block := range caller.Blocks {
+		for i, inst := range block.Insts {
+			if inst != call {
+				continue
+			}
+			block.InsertInst(i+1, after...)
+			block.InsertInst(i, before...)
+			break
+		}
+	}
+	// Insert allocas after the leading allocas of the entry basic block.
+	entry := caller.Blocks[0]
+	i := insertionPoint(entry)
+	for i < len(entry.Insts) {
+		if _, ok := entry.Insts[i].(*ir.InstAlloca); !ok {
+			break
+		}
+		i++
+	}
+	entry.InsertInst(i, allocas...)
+}
+
+// setParams sets the return type and parameters of the given function, and
+// updates its signature to match.
+func setParams(f *ir.Function, retType types.Type, params []*ir.Param) {
+	var paramTypes []types.Type
+	for _, param := range params {
+		paramTypes = append(paramTypes, param.Type())
+	}
+	sig := types.NewFunc(retType, paramTypes...)
+	sig.Variadic = f.Sig.Variadic
+	f.Params = params
+	f.Sig = sig
+	if f.Typ != nil {
+		addrSpace := f.Typ.AddrSpace
+		f.Typ = types.NewPointer(sig)
+		f.Typ.AddrSpace = addrSpace
+	}
+}
+
+// directCall is a direct call instruction of a function.
+type directCall struct {
+	// Caller function.
+	caller *ir.Function
+	// Call instruction.
+	inst *ir.InstCall
+}
+
+// directCalls returns the direct call instructions of the functions of the
+// module which are only used as callee of direct call instructions; function
+// -> calls. Functions used in any other way are not present in the map.
+func directCalls(m *ir.Module) map[*ir.Function][]directCall {
+	calls := make(map[*ir.Function][]directCall)
+	// Number of uses of each function other than as callee of direct call
+	// instructions.
+	uses := make(map[*ir.Function]int)
+	for _, f := range m.Funcs {
+		calls[f] = nil
+	}
+	countUses := func(root interface{}) {
+		// The root node is visited first, as a definition rather than a use.
+		first := true
+		ir.Walk(root, func(n interface{}) bool {
+			if f, ok := n.(*ir.Function); ok && !first {
+				uses[f]++
+			}
+			first = false
+			return true
+		})
+	}
+	for _, g := range m.Globals {
+		countUses(g)
+	}
+	for _, f := range m.Funcs {
+		countUses(f)
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				call, ok := inst.(*ir.InstCall)
+				if !ok {
+					continue
+				}
+				if callee, ok := call.Callee.(*ir.Function); ok {
+					calls[callee] = append(calls[callee], directCall{caller: f, inst: call})
+					uses[callee]--
+				}
+			}
+		}
+	}
+	for f, n := range uses {
+		if n != 0 {
+			delete(calls, f)
+		}
+	}
+	return calls
+}
@@ -0,0 +1,86 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestLowerAggregates(t *testing.T) {
+	m := &ir.Module{}
+	m.DataLayout = "e-m:e-i64:64-f80:128-n8:16:32:64-S128"
+	big := types.NewStruct(types.I64, types.I64, types.I64)
+	small := types.NewStruct(types.I64, types.I64)
+	// make returns a large aggregate.
+	x := ir.NewParam(types.I64, "x")
+	mk := m.NewFunc("make", big, x)
+	entry := mk.NewBlock("entry")
+	v := entry.NewInsertValue(ir.NewZeroInitializer(big), x, 0)
+	v.SetName("v")
+	entry.NewRet(v)
+	// first takes a large aggregate and a small aggregate.
+	a := ir.NewParam(big, "a")
+	b := ir.NewParam(small, "b")
+	first := m.NewFunc("first", types.I64, a, b)
+	entry = first.NewBlock("entry")
+	y := entry.NewExtractValue(a, 0)
+	y.SetName("y")
+	entry.NewRet(y)
+	// escaped has its address taken, and is left as is.
+	escaped := m.NewFunc("escaped", big)
+	m.NewGlobalDef("fp", escaped)
+	// main calls make and first.
+	main := m.NewFunc("main", types.I64)
+	entry = main.NewBlock("entry")
+	s := entry.NewCall(mk, ir.NewInt(types.I64, 42))
+	s.SetName("s")
+	r := entry.NewCall(first, s, ir.NewZeroInitializer(small))
+	r.SetName("r")
+	entry.NewCall(escaped)
+	entry.NewRet(r)
+	changed, err := LowerAggregates(m, 16)
+	if err != nil {
+		t.Fatalf("unable to lower aggregates; %+v", err)
+	}
+	if !changed {
+		t.Fatalf("expected module to be changed")
+	}
+	want := `target datalayout = "e-m:e-i64:64-f80:128-n8:16:32:64-S128"
+@fp = global { i64, i64, i64 } ()* @escaped
+define void @make({ i64, i64, i64 }* sret noalias %agg.result, i64 %x) {
+entry:
+	%v = insertvalue { i64, i64, i64 } zeroinitializer, i64 %x, 0
+	store { i64, i64, i64 } %v, { i64, i64, i64 }* %agg.result
+	ret void
+}
+define i64 @first({ i64, i64, i64 }* byval %a, { i64, i64 } %b) {
+entry:
+	%a.val = load { i64, i64, i64 }, { i64, i64, i64 }* %a
+	%y = extractvalue { i64, i64, i64 } %a.val, 0
+	ret i64 %y
+}
+declare { i64, i64, i64 } @escaped()
+define i64 @main() {
+entry:
+	%0 = alloca { i64, i64, i64 }, align 8
+	%1 = alloca { i64, i64, i64 }, align 8
+	call void @make({ i64, i64, i64 }* sret noalias %0, i64 42)
+	%s = load { i64, i64, i64 }, { i64, i64, i64 }* %0
+	store { i64, i64, i64 } %s, { i64, i64, i64 }* %1
+	%r = call i64 @first({ i64, i64, i64 }* byval %1, { i64, i64 } zeroinitializer)
+	%2 = call { i64, i64, i64 } @escaped()
+	ret i64 %r
+}
+`
+	if err := main.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %+v", err)
+	}
+	if got := m.Def(); want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// Lowered functions are left as is.
+	if changed, err := LowerAggregates(m, 16); err != nil || changed {
+		t.Errorf("expected lowered module to be left as is")
+	}
+}
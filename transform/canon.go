@@ -0,0 +1,237 @@
+// Package transform implements transformations of LLVM IR modules and
+// functions.
+package transform
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Canonicalization ] ====================================================
+
+// Canonicalize normalizes the given module, so that equivalent modules have
+// equal LLVM syntax representations. Canonicalize
+//
+//    * orders the operands of commutative instructions canonically; local
+//      values (in order of definition), then global values (in order of name),
+//      then constants (in order of representation),
+//    * renames the parameters, basic blocks and local variables of each
+//      function definition to sequential local IDs (%0, %1, ...),
+//    * sorts the attributes of each attribute group, and the attribute groups
+//      of the module by their attributes, and renumbers them (#0, #1, ...),
+//    * sorts the metadata definitions of the module in order of first use, and
+//      renumbers them (!0, !1, ...).
+func Canonicalize(m *ir.Module) error {
+	for _, f := range m.Funcs {
+		if err := CanonicalizeFunc(f); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	sortAttrGroups(m)
+	sortMetadata(m)
+	return nil
+}
+
+// CanonicalizeFunc orders the operands of commutative instructions of the
+// given function canonically, and renames its parameters, basic blocks and
+// local variables to sequential local IDs.
+func CanonicalizeFunc(f *ir.Function) error {
+	if len(f.Blocks) == 0 {
+		return nil
+	}
+	// Order of definition of local values.
+	order := make(map[value.Value]int)
+	for _, param := range f.Params {
+		order[param] = len(order)
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if v, ok := inst.(value.Value); ok {
+				order[v] = len(order)
+			}
+		}
+		if v, ok := block.Term.(value.Value); ok {
+			order[v] = len(order)
+		}
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			orderOperands(inst, order)
+		}
+	}
+	// Rename local values.
+	for _, param := range f.Params {
+		param.SetName("")
+	}
+	for _, block := range f.Blocks {
+		block.SetName("")
+		for _, inst := range block.Insts {
+			if n, ok := inst.(value.Named); ok {
+				n.SetName("")
+			}
+		}
+		if n, ok := block.Term.(value.Named); ok {
+			n.SetName("")
+		}
+	}
+	if err := f.AssignIDs(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// orderOperands orders the operands of the given instruction canonically if
+// commutative.
+func orderOperands(inst ir.Instruction, order map[value.Value]int) {
+	var x, y *value.Value
+	switch inst := inst.(type) {
+	case *ir.InstAdd:
+		x, y = &inst.X, &inst.Y
+	case *ir.InstFAdd:
+		x, y = &inst.X, &inst.Y
+	case *ir.InstMul:
+		x, y = &inst.X, &inst.Y
+	case *ir.InstFMul:
+		x, y = &inst.X, &inst.Y
+	case *ir.InstAnd:
+		x, y = &inst.X, &inst.Y
+	case *ir.InstOr:
+		x, y = &inst.X, &inst.Y
+	case *ir.InstXor:
+		x, y = &inst.X, &inst.Y
+	case *ir.InstICmp:
+		switch inst.Pred {
+		case enum.IPredEQ, enum.IPredNE:
+			x, y = &inst.X, &inst.Y
+		}
+	case *ir.InstFCmp:
+		switch inst.Pred {
+		case enum.FPredFalse, enum.FPredOEQ, enum.FPredONE, enum.FPredORD, enum.FPredTrue, enum.FPredUEQ, enum.FPredUNE, enum.FPredUNO:
+			x, y = &inst.X, &inst.Y
+		}
+	}
+	if x != nil && operandLess(*y, *x, order) {
+		*x, *y = *y, *x
+	}
+}
+
+// operandLess reports whether the operand a precedes the operand b in
+// canonical order.
+func operandLess(a, b value.Value, order map[value.Value]int) bool {
+	ra, rb := operandRank(a, order), operandRank(b, order)
+	if ra != rb {
+		return ra < rb
+	}
+	switch ra {
+	case rankLocal:
+		return order[a] < order[b]
+	case rankGlobal:
+		return a.(value.Named).Name() < b.(value.Named).Name()
+	default:
+		return a.String() < b.String()
+	}
+}
+
+// Operand ranks, in canonical order.
+const (
+	rankLocal = iota
+	rankGlobal
+	rankConst
+)
+
+// operandRank returns the rank of the given operand in canonical order.
+func operandRank(v value.Value, order map[value.Value]int) int {
+	if _, ok := order[v]; ok {
+		return rankLocal
+	}
+	switch v.(type) {
+	case *ir.Global, *ir.Function:
+		return rankGlobal
+	}
+	return rankConst
+}
+
+// sortAttrGroups sorts the attributes of each attribute group definition of
+// the given module, and the attribute group definitions by their attributes.
+// The attribute group definitions are renumbered in sorted order.
+func sortAttrGroups(m *ir.Module) {
+	keys := make(map[*ir.AttrGroupDef]string)
+	for _, a := range m.AttrGroupDefs {
+		sort.SliceStable(a.FuncAttrs, func(i, j int) bool {
+			return a.FuncAttrs[i].String() < a.FuncAttrs[j].String()
+		})
+		var attrs []string
+		for _, attr := range a.FuncAttrs {
+			attrs = append(attrs, attr.String())
+		}
+		keys[a] = strings.Join(attrs, " ")
+	}
+	sort.SliceStable(m.AttrGroupDefs, func(i, j int) bool {
+		return keys[m.AttrGroupDefs[i]] < keys[m.AttrGroupDefs[j]]
+	})
+	for i, a := range m.AttrGroupDefs {
+		a.ID = int64(i)
+	}
+}
+
+// sortMetadata sorts the metadata definitions of the given module in order of
+// first use (by named metadata definitions, then by metadata attachments),
+// followed by unused metadata definitions in order of their definition. The
+// metadata definitions are renumbered in sorted order.
+func sortMetadata(m *ir.Module) {
+	defined := make(map[ir.MDNode]bool)
+	for _, md := range m.MetadataDefs {
+		defined[md] = true
+	}
+	var defs []ir.MDNode
+	seen := make(map[ir.MDNode]bool)
+	var use func(md ir.Metadata)
+	use = func(md ir.Metadata) {
+		node, ok := md.(ir.MDNode)
+		if !ok || seen[node] {
+			return
+		}
+		seen[node] = true
+		if defined[node] {
+			defs = append(defs, node)
+		}
+		switch node := node.(type) {
+		case *ir.MDTuple:
+			for _, field := range node.Fields {
+				use(field)
+			}
+		case *ir.MDSpecialized:
+			for _, operand := range node.Operands() {
+				use(operand)
+			}
+		}
+	}
+	for _, md := range m.NamedMetadataDefs {
+		for _, node := range md.Nodes {
+			use(node)
+		}
+	}
+	ir.Walk(m, func(n interface{}) bool {
+		if md, ok := n.(ir.MetadataAttachment); ok {
+			use(md.Node)
+		}
+		return true
+	})
+	var unused []ir.MDNode
+	for _, md := range m.MetadataDefs {
+		if !seen[md] {
+			unused = append(unused, md)
+		}
+	}
+	m.MetadataDefs = append(defs, unused...)
+	for i, md := range m.MetadataDefs {
+		md.SetID(int64(i))
+	}
+}
@@ -0,0 +1,80 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestCanonicalize(t *testing.T) {
+	// Equivalent modules, differing in local names, operand order of
+	// commutative instructions, and numbering of attribute groups and metadata.
+	a := newModule("x", "sum", false)
+	b := newModule("a", "tmp", true)
+	for _, m := range []*ir.Module{a, b} {
+		if err := Canonicalize(m); err != nil {
+			t.Fatalf("unable to canonicalize module; %v", err)
+		}
+	}
+	want := `define i32 @f(i32) #0 !prof !0 {
+	%2 = add i32 %0, 1
+	%3 = icmp eq i32 %0, %2
+	ret i32 %2
+}
+attributes #0 = { noinline nounwind }
+attributes #1 = { readonly }
+!0 = !{!"function_entry_count", i64 10}
+!1 = !{!"unused"}
+`
+	if got := a.Def(); want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+	if a.Def() != b.Def() {
+		t.Errorf("canonical form mismatch; `%v` != `%v`", a.Def(), b.Def())
+	}
+}
+
+// newModule returns a new module with the given local names. If swap is set,
+// the operands of commutative instructions are swapped, and attribute groups
+// and metadata definitions are listed in reverse order.
+func newModule(paramName, sumName string, swap bool) *ir.Module {
+	m := &ir.Module{}
+	x := ir.NewParam(types.I32, paramName)
+	f := m.NewFunc("f", types.I32, x)
+	entry := ir.NewBlock("entry")
+	one := ir.NewInt(types.I32, 1)
+	var sum *ir.InstAdd
+	var cond *ir.InstICmp
+	if swap {
+		sum = entry.NewAdd(one, x)
+		cond = entry.NewICmp(enum.IPredEQ, sum, x)
+	} else {
+		sum = entry.NewAdd(x, one)
+		cond = entry.NewICmp(enum.IPredEQ, x, sum)
+	}
+	sum.SetName(sumName)
+	cond.SetName(sumName + ".cond")
+	entry.NewRet(sum)
+	f.Blocks = append(f.Blocks, entry)
+	readonly := ir.NewAttrGroupDef(0, enum.FuncAttrReadOnly)
+	nounwind := ir.NewAttrGroupDef(1, enum.FuncAttrNoUnwind, enum.FuncAttrNoInline)
+	if swap {
+		nounwind.FuncAttrs[0], nounwind.FuncAttrs[1] = nounwind.FuncAttrs[1], nounwind.FuncAttrs[0]
+	}
+	f.FuncAttrs = append(f.FuncAttrs, nounwind)
+	unused := ir.NewTuple(ir.NewMDString("unused"))
+	prof := ir.NewTuple(ir.NewMDString("function_entry_count"), ir.NewMDValue(ir.NewInt(types.I64, 10)))
+	f.Metadata = append(f.Metadata, ir.NewMetadataAttachment(ir.MDKindProf, prof))
+	m.AttrGroupDefs = []*ir.AttrGroupDef{readonly, nounwind}
+	m.MetadataDefs = []ir.MDNode{unused, prof}
+	if swap {
+		m.AttrGroupDefs = []*ir.AttrGroupDef{nounwind, readonly}
+	}
+	for i, md := range m.MetadataDefs {
+		md.SetID(int64(i))
+	}
+	return m
+}
+
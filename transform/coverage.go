@@ -0,0 +1,258 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/llir/l/analysis"
+	"github.com/llir/l/internal/enc"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/sanitize"
+	"github.com/pkg/errors"
+)
+
+// === [ Coverage instrumentation ] ============================================
+
+// CoverageMode specifies the program points counted by coverage
+// instrumentation.
+type CoverageMode uint8
+
+// Coverage modes.
+const (
+	// CoverageBlocks counts executions of basic blocks.
+	CoverageBlocks CoverageMode = iota
+	// CoverageEdges counts executions of control flow edges and function
+	// entries.
+	CoverageEdges
+)
+
+// Coverage is the coverage instrumentation of a module, as inserted by
+// InsertCoverage.
+type Coverage struct {
+	// Global variable of the counter array; [N x i64].
+	Counters *ir.Global
+	// Global variable of the counter names array; [N x i8*].
+	Names *ir.Global
+	// Function which prints the name and value of each counter to standard
+	// output using printf; void ().
+	Dump *ir.Function
+	// Counted program points, indexed by counter.
+	Points []*CoveragePoint
+}
+
+// CoveragePoint is a program point counted by coverage instrumentation.
+type CoveragePoint struct {
+	// Function of the program point.
+	Func *ir.Function
+	// Counted basic block, or target basic block of counted control flow edge.
+	Block *ir.BasicBlock
+	// Source basic block of counted control flow edge; or nil for basic blocks
+	// and function entries.
+	Pred *ir.BasicBlock
+	// Counter name; e.g. "f:entry" for basic blocks and function entries, and
+	// "f:entry->exit" for control flow edges.
+	Name string
+}
+
+// InsertCoverage inserts coverage counters into the function definitions of
+// the module, which count the executions of basic blocks or control flow edges
+// as specified by mode. The counters are stored in a global array created in
+// the module, together with a dump function printing the counters.
+//
+// Critical edges are split to hold their counters. Edges which may not be split
+// (e.g. to exception handling pads) are not counted.
+func InsertCoverage(m *ir.Module, mode CoverageMode) *Coverage {
+	cov := &Coverage{}
+	var sites []*coverageSite
+	for _, f := range m.Funcs {
+		if len(f.Blocks) == 0 {
+			continue
+		}
+		switch mode {
+		case CoverageBlocks:
+			for _, block := range f.Blocks {
+				sites = append(sites, &coverageSite{block: block})
+				cov.Points = append(cov.Points, &CoveragePoint{Func: f, Block: block})
+			}
+		case CoverageEdges:
+			sites = append(sites, &coverageSite{block: f.Blocks[0]})
+			cov.Points = append(cov.Points, &CoveragePoint{Func: f, Block: f.Blocks[0]})
+			edgeSites, edgePoints := coverageEdges(f)
+			sites = append(sites, edgeSites...)
+			cov.Points = append(cov.Points, edgePoints...)
+		default:
+			panic(errors.Errorf("support for coverage mode %d not yet implemented", mode))
+		}
+	}
+	for _, point := range cov.Points {
+		point.Name = fmt.Sprintf("%s:%s", point.Func.Name(), blockName(point.Func, point.Block))
+		if point.Pred != nil {
+			point.Name = fmt.Sprintf("%s:%s->%s", point.Func.Name(), blockName(point.Func, point.Pred), blockName(point.Func, point.Block))
+		}
+	}
+	n := int64(len(cov.Points))
+	countersType := types.NewArray(n, types.I64)
+	cov.Counters = m.NewGlobalDef(uniqueGlobalName(m, "__cov_counters"), ir.NewZeroInitializer(countersType))
+	cov.Counters.Linkage = enum.LinkageInternal
+	var names []ir.Constant
+	for _, point := range cov.Points {
+		names = append(names, m.InternString(point.Name))
+	}
+	cov.Names = m.NewGlobalDef(uniqueGlobalName(m, "__cov_names"), ir.NewArray(types.NewArray(n, types.I8Ptr), names...))
+	cov.Names.Linkage = enum.LinkagePrivate
+	cov.Names.Immutable = true
+	for i, site := range sites {
+		counter := ir.NewGetElementPtrExpr(countersType, cov.Counters, ir.NewIndex(ir.NewInt(types.I64, 0)), ir.NewIndex(ir.NewInt(types.I64, int64(i))))
+		counter.InBounds = true
+		site.insert(counter)
+	}
+	cov.Dump = coverageDump(m, cov)
+	return cov
+}
+
+// ### [ Helper functions ] ####################################################
+
+// coverageSite is the insertion site of a coverage counter increment; the start
+// of a basic block, or the end of a basic block for control flow edges from
+// basic blocks with a single successor.
+type coverageSite struct {
+	// Basic block of the counter increment.
+	block *ir.BasicBlock
+	// Insert counter increment at the end of the basic block.
+	end bool
+}
+
+// insert inserts an increment of the given counter at the site.
+func (site *coverageSite) insert(counter ir.Constant) {
+	load := ir.NewLoad(counter)
+	add := ir.NewAdd(load, ir.NewInt(types.I64, 1))
+	store := ir.NewStore(add, counter)
+	inc := []ir.Instruction{load, add, store}
+	for _, inst := range inc {
+		sanitize.NoSanitize(inst)
+	}
+	block := site.block
+	i := len(block.Insts)
+	if !site.end {
+		i = insertionPoint(block)
+	}
+	block.InsertInst(i, inc...)
+}
+
+// coverageEdges returns the counter sites and program points of the control
+// flow edges of the given function, splitting critical edges.
+func coverageEdges(f *ir.Function) ([]*coverageSite, []*CoveragePoint) {
+	var sites []*coverageSite
+	var points []*CoveragePoint
+	preds := analysis.Preds(f)
+	// Basic blocks of the function before splitting edges.
+	blocks := append([]*ir.BasicBlock(nil), f.Blocks...)
+	for _, block := range blocks {
+		succs := distinctSuccs(block)
+		for _, succ := range succs {
+			point := &CoveragePoint{Func: f, Block: succ, Pred: block}
+			switch {
+			case len(succs) == 1:
+				sites = append(sites, &coverageSite{block: block, end: true})
+			case len(preds[succ]) == 1 && succ != f.Blocks[0]:
+				sites = append(sites, &coverageSite{block: succ})
+			case canSplit(succ, []*ir.BasicBlock{block}):
+				split := splitPreds(f, succ, []*ir.BasicBlock{block}, "cov")
+				sites = append(sites, &coverageSite{block: split})
+			default:
+				continue
+			}
+			points = append(points, point)
+		}
+	}
+	return sites, points
+}
+
+// distinctSuccs returns the distinct successors of the given basic block, in
+// order of occurrence.
+func distinctSuccs(block *ir.BasicBlock) []*ir.BasicBlock {
+	var succs []*ir.BasicBlock
+	seen := make(map[*ir.BasicBlock]bool)
+	for _, succ := range block.Term.Succs() {
+		if !seen[succ] {
+			seen[succ] = true
+			succs = append(succs, succ)
+		}
+	}
+	return succs
+}
+
+// blockName returns the name of the given basic block of the function, as used
+// in counter names; the label name, or the index of the basic block (e.g.
+// "#2") if unnamed.
+func blockName(f *ir.Function, block *ir.BasicBlock) string {
+	if len(block.LocalName) > 0 && !enc.IsID(block.LocalName) {
+		return block.LocalName
+	}
+	for i, b := range f.Blocks {
+		if b == block {
+			return fmt.Sprintf("#%d", i)
+		}
+	}
+	return "#?"
+}
+
+// coverageDump returns a new function of the module which prints the name and
+// value of each coverage counter using printf.
+func coverageDump(m *ir.Module, cov *Coverage) *ir.Function {
+	dump := m.NewFunc(uniqueGlobalName(m, "__cov_dump"), types.Void)
+	entry := dump.NewBlock("entry")
+	n := int64(len(cov.Points))
+	if n == 0 {
+		entry.NewRet(nil)
+		return dump
+	}
+	printf := declarePrintf(m)
+	format := m.InternString("%s %llu\n")
+	loop := dump.NewBlock("loop")
+	exit := dump.NewBlock("exit")
+	entry.NewBr(loop)
+	zero := ir.NewInt(types.I64, 0)
+	i := loop.NewPhi(ir.NewIncoming(zero, entry))
+	i.SetName("i")
+	namePtr := loop.NewGetElementPtr(cov.Names.ContentType, cov.Names, zero, i)
+	name := loop.NewLoad(namePtr)
+	countPtr := loop.NewGetElementPtr(cov.Counters.ContentType, cov.Counters, zero, i)
+	count := loop.NewLoad(countPtr)
+	loop.NewCall(printf, format, name, count)
+	next := loop.NewAdd(i, ir.NewInt(types.I64, 1))
+	next.SetName("next")
+	i.Incs = append(i.Incs, ir.NewIncoming(next, loop))
+	done := loop.NewICmp(enum.IPredEQ, next, ir.NewInt(types.I64, n))
+	loop.NewCondBr(done, exit, loop)
+	exit.NewRet(nil)
+	return dump
+}
+
+// declarePrintf returns the printf function of the module, declaring it if not
+// yet present.
+func declarePrintf(m *ir.Module) *ir.Function {
+	if v, ok := m.Lookup("printf"); ok {
+		if f, ok := v.(*ir.Function); ok {
+			return f
+		}
+	}
+	printf := m.NewFunc("printf", types.I32, ir.NewParam(types.I8Ptr, ""))
+	printf.Sig.Variadic = true
+	return printf
+}
+
+// uniqueGlobalName returns a global name based on the given name, which is unique
+// within the module; e.g. ".str", ".str.1".
+func uniqueGlobalName(m *ir.Module, name string) string {
+	if _, ok := m.Lookup(name); !ok {
+		return name
+	}
+	for i := 1; ; i++ {
+		uniqueName := fmt.Sprintf("%s.%d", name, i)
+		if _, ok := m.Lookup(uniqueName); !ok {
+			return uniqueName
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestInsertCoverage(t *testing.T) {
+	golden := []struct {
+		mode  CoverageMode
+		names []string
+		want  string
+	}{
+		// i=0
+		{
+			mode:  CoverageBlocks,
+			names: []string{"f:entry", "f:then", "f:exit"},
+			want: `define i32 @f(i1 %c) {
+entry:
+	%0 = load i64, i64* getelementptr inbounds ([3 x i64], [3 x i64]* @__cov_counters, i64 0, i64 0), !nosanitize !{}
+	%1 = add i64 %0, 1, !nosanitize !{}
+	store i64 %1, i64* getelementptr inbounds ([3 x i64], [3 x i64]* @__cov_counters, i64 0, i64 0), !nosanitize !{}
+	br i1 %c, label %then, label %exit
+then:
+	%2 = load i64, i64* getelementptr inbounds ([3 x i64], [3 x i64]* @__cov_counters, i64 0, i64 1), !nosanitize !{}
+	%3 = add i64 %2, 1, !nosanitize !{}
+	store i64 %3, i64* getelementptr inbounds ([3 x i64], [3 x i64]* @__cov_counters, i64 0, i64 1), !nosanitize !{}
+	br label %exit
+exit:
+	%x = phi i32 [ 1, %entry ], [ 2, %then ]
+	%4 = load i64, i64* getelementptr inbounds ([3 x i64], [3 x i64]* @__cov_counters, i64 0, i64 2), !nosanitize !{}
+	%5 = add i64 %4, 1, !nosanitize !{}
+	store i64 %5, i64* getelementptr inbounds ([3 x i64], [3 x i64]* @__cov_counters, i64 0, i64 2), !nosanitize !{}
+	ret i32 %x
+}`,
+		},
+		// i=1
+		{
+			mode:  CoverageEdges,
+			names: []string{"f:entry", "f:entry->then", "f:entry->exit", "f:then->exit"},
+			want: `define i32 @f(i1 %c) {
+entry:
+	%0 = load i64, i64* getelementptr inbounds ([4 x i64], [4 x i64]* @__cov_counters, i64 0, i64 0), !nosanitize !{}
+	%1 = add i64 %0, 1, !nosanitize !{}
+	store i64 %1, i64* getelementptr inbounds ([4 x i64], [4 x i64]* @__cov_counters, i64 0, i64 0), !nosanitize !{}
+	br i1 %c, label %then, label %exit.cov
+then:
+	%2 = load i64, i64* getelementptr inbounds ([4 x i64], [4 x i64]* @__cov_counters, i64 0, i64 1), !nosanitize !{}
+	%3 = add i64 %2, 1, !nosanitize !{}
+	store i64 %3, i64* getelementptr inbounds ([4 x i64], [4 x i64]* @__cov_counters, i64 0, i64 1), !nosanitize !{}
+	%4 = load i64, i64* getelementptr inbounds ([4 x i64], [4 x i64]* @__cov_counters, i64 0, i64 3), !nosanitize !{}
+	%5 = add i64 %4, 1, !nosanitize !{}
+	store i64 %5, i64* getelementptr inbounds ([4 x i64], [4 x i64]* @__cov_counters, i64 0, i64 3), !nosanitize !{}
+	br label %exit
+exit.cov:
+	%6 = load i64, i64* getelementptr inbounds ([4 x i64], [4 x i64]* @__cov_counters, i64 0, i64 2), !nosanitize !{}
+	%7 = add i64 %6, 1, !nosanitize !{}
+	store i64 %7, i64* getelementptr inbounds ([4 x i64], [4 x i64]* @__cov_counters, i64 0, i64 2), !nosanitize !{}
+	br label %exit
+exit:
+	%x = phi i32 [ 2, %then ], [ 1, %exit.cov ]
+	ret i32 %x
+}`,
+		},
+	}
+	for i, g := range golden {
+		m := &ir.Module{}
+		c := ir.NewParam(types.I1, "c")
+		f := m.NewFunc("f", types.I32, c)
+		entry := f.NewBlock("entry")
+		then := f.NewBlock("then")
+		exit := f.NewBlock("exit")
+		entry.NewCondBr(c, then, exit)
+		then.NewBr(exit)
+		x := exit.NewPhi(ir.NewIncoming(ir.NewInt(types.I32, 1), entry), ir.NewIncoming(ir.NewInt(types.I32, 2), then))
+		x.SetName("x")
+		exit.NewRet(x)
+		cov := InsertCoverage(m, g.mode)
+		if len(cov.Points) != len(g.names) {
+			t.Errorf("i=%d: number of counters mismatch; expected %d, got %d", i, len(g.names), len(cov.Points))
+			continue
+		}
+		for j, point := range cov.Points {
+			if point.Name != g.names[j] {
+				t.Errorf("i=%d: counter name mismatch at index %d; expected %q, got %q", i, j, g.names[j], point.Name)
+			}
+		}
+		if err := f.AssignIDs(); err != nil {
+			t.Fatalf("i=%d: unable to assign IDs; %+v", i, err)
+		}
+		if got := f.Def(); g.want != got {
+			t.Errorf("i=%d: function mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+		if err := cov.Dump.AssignIDs(); err != nil {
+			t.Fatalf("i=%d: unable to assign IDs; %+v", i, err)
+		}
+		if cov.Counters.Linkage != enum.LinkageInternal || len(cov.Dump.Blocks) != 3 {
+			t.Errorf("i=%d: unexpected coverage counters or dump function; %v", i, cov.Dump.Def())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package transform
+
+import (
+	"github.com/llir/l/ir"
+)
+
+// === [ Exception handling ] ==================================================
+
+// StripEH removes exception handling from the given function, for targets and
+// runtimes without support for unwinding, and reports whether the function was
+// changed.
+//
+// Each invoke terminator is replaced by a call instruction followed by a
+// branch to the normal return point, and the incoming values of phi
+// instructions from the dropped unwind edges are removed. Exception handling
+// blocks (e.g. landingpad and resume blocks), no longer reachable, are removed
+// and the personality function of the function is dropped.
+func StripEH(f *ir.Function) bool {
+	changed := false
+	for _, block := range f.Blocks {
+		invoke, ok := block.Term.(*ir.TermInvoke)
+		if !ok {
+			continue
+		}
+		call := &ir.InstCall{
+			LocalName:      invoke.LocalName,
+			Callee:         invoke.Invokee,
+			Args:           invoke.Args,
+			Typ:            invoke.Typ,
+			CalleeType:     invoke.InvokeeType,
+			CallingConv:    invoke.CallingConv,
+			ReturnAttrs:    invoke.ReturnAttrs,
+			AddrSpace:      invoke.AddrSpace,
+			FuncAttrs:      invoke.FuncAttrs,
+			OperandBundles: invoke.OperandBundles,
+			Metadata:       invoke.Metadata,
+			Comments:       invoke.Comments,
+		}
+		block.InsertInst(len(block.Insts), call)
+		ir.ReplaceUses(f, invoke, call)
+		if invoke.Exception != invoke.Normal {
+			removeIncs(invoke.Exception, block, false)
+		}
+		block.NewBr(invoke.Normal)
+		changed = true
+	}
+	if removeUnreachable(f) {
+		changed = true
+	}
+	if f.Personality != nil {
+		f.Personality = nil
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,54 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestStripEH(t *testing.T) {
+	// define i32 @f() personality i32 (...)* @personality {
+	// entry:
+	//    %r = invoke i32 @g()
+	//       to label %cont unwind label %lpad
+	// cont:
+	//    ret i32 %r
+	// lpad:
+	//    %lp = landingpad { i8*, i32 } cleanup
+	//    resume { i8*, i32 } %lp
+	// }
+	m := &ir.Module{}
+	personality := m.NewFunc("personality", types.I32)
+	personality.Sig.Variadic = true
+	g := m.NewFunc("g", types.I32)
+	f := m.NewFunc("f", types.I32)
+	f.Personality = personality
+	entry := ir.NewBlock("entry")
+	cont := ir.NewBlock("cont")
+	lpad := ir.NewBlock("lpad")
+	r := entry.NewInvoke(g, nil, cont, lpad)
+	r.SetName("r")
+	cont.NewRet(r)
+	lp := lpad.NewLandingPad(types.NewStruct(types.I8Ptr, types.I32))
+	lp.SetName("lp")
+	lp.Cleanup = true
+	lpad.NewResume(lp)
+	f.Blocks = []*ir.BasicBlock{entry, cont, lpad}
+	if !StripEH(f) {
+		t.Errorf("expected exception handling to be stripped")
+	}
+	want := `define i32 @f() {
+entry:
+	%r = call i32 @g()
+	br label %cont
+cont:
+	ret i32 %r
+}`
+	if got := f.Def(); want != got {
+		t.Errorf("function mismatch; expected `%v`, got `%v`", want, got)
+	}
+	if StripEH(f) {
+		t.Errorf("expected function without exception handling to be left as is")
+	}
+}
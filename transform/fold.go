@@ -0,0 +1,400 @@
+package transform
+
+import (
+	"math/big"
+
+	"github.com/llir/l/analysis"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Constant folding ] ====================================================
+
+// FoldConstants simplifies the body of the given function until no further
+// simplification applies, and reports whether the function was changed.
+// FoldConstants
+//
+//    * folds integer binary, bitwise, comparison, conversion and select
+//      instructions with constant operands,
+//    * folds phi instructions with a single distinct incoming value,
+//    * folds conditional branches and switches on constants to unconditional
+//      branches, and
+//    * removes basic blocks no longer reachable from the entry basic block.
+func FoldConstants(f *ir.Function) bool {
+	changed := false
+	for {
+		progress := false
+		for _, block := range f.Blocks {
+			insts := append([]ir.Instruction(nil), block.Insts...)
+			for _, inst := range insts {
+				if v, ok := foldInst(inst); ok {
+					ir.ReplaceUses(f, inst.(value.Value), v)
+					block.RemoveInst(inst)
+					progress = true
+				}
+			}
+		}
+		for _, block := range f.Blocks {
+			if foldTerm(block) {
+				progress = true
+			}
+		}
+		if removeUnreachable(f) {
+			progress = true
+		}
+		if !progress {
+			return changed
+		}
+		changed = true
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// foldInst returns the value of the given instruction if it may be determined
+// without execution. The boolean return value indicates success.
+func foldInst(inst ir.Instruction) (value.Value, bool) {
+	switch inst := inst.(type) {
+	// Binary instructions.
+	case *ir.InstAdd:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			return new(big.Int).Add(x, y)
+		})
+	case *ir.InstSub:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			return new(big.Int).Sub(x, y)
+		})
+	case *ir.InstMul:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			return new(big.Int).Mul(x, y)
+		})
+	case *ir.InstUDiv:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			x, y = unsigned(x, n), unsigned(y, n)
+			if y.Sign() == 0 {
+				return nil
+			}
+			return new(big.Int).Quo(x, y)
+		})
+	case *ir.InstSDiv:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			x, y = signed(x, n), signed(y, n)
+			if y.Sign() == 0 || overflowsSigned(new(big.Int).Quo(x, y), n) {
+				return nil
+			}
+			return new(big.Int).Quo(x, y)
+		})
+	case *ir.InstURem:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			x, y = unsigned(x, n), unsigned(y, n)
+			if y.Sign() == 0 {
+				return nil
+			}
+			return new(big.Int).Rem(x, y)
+		})
+	case *ir.InstSRem:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			x, y = signed(x, n), signed(y, n)
+			if y.Sign() == 0 || overflowsSigned(new(big.Int).Quo(x, y), n) {
+				return nil
+			}
+			return new(big.Int).Rem(x, y)
+		})
+	// Bitwise instructions.
+	case *ir.InstShl:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			if s, ok := shiftAmount(y, n); ok {
+				return new(big.Int).Lsh(x, s)
+			}
+			return nil
+		})
+	case *ir.InstLShr:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			if s, ok := shiftAmount(y, n); ok {
+				return new(big.Int).Rsh(unsigned(x, n), s)
+			}
+			return nil
+		})
+	case *ir.InstAShr:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			if s, ok := shiftAmount(y, n); ok {
+				return new(big.Int).Rsh(signed(x, n), s)
+			}
+			return nil
+		})
+	case *ir.InstAnd:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			return new(big.Int).And(unsigned(x, n), unsigned(y, n))
+		})
+	case *ir.InstOr:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			return new(big.Int).Or(unsigned(x, n), unsigned(y, n))
+		})
+	case *ir.InstXor:
+		return foldBinary(inst.X, inst.Y, func(x, y *big.Int, n int64) *big.Int {
+			return new(big.Int).Xor(unsigned(x, n), unsigned(y, n))
+		})
+	// Conversion instructions.
+	case *ir.InstTrunc:
+		return foldConv(inst.From, inst.To, signed)
+	case *ir.InstZExt:
+		return foldConv(inst.From, inst.To, unsigned)
+	case *ir.InstSExt:
+		return foldConv(inst.From, inst.To, signed)
+	// Other instructions.
+	case *ir.InstICmp:
+		x, ok1 := inst.X.(*ir.ConstInt)
+		y, ok2 := inst.Y.(*ir.ConstInt)
+		if !ok1 || !ok2 {
+			return nil, false
+		}
+		if icmp(inst.Pred, x, y) {
+			return ir.True, true
+		}
+		return ir.False, true
+	case *ir.InstSelect:
+		if cond, ok := inst.Cond.(*ir.ConstInt); ok {
+			if cond.X.Sign() != 0 {
+				return inst.X, true
+			}
+			return inst.Y, true
+		}
+		if sameValue(inst.X, inst.Y) {
+			return inst.X, true
+		}
+	case *ir.InstPhi:
+		var v value.Value
+		for _, inc := range inst.Incs {
+			if inc.X == inst || (v != nil && sameValue(v, inc.X)) {
+				continue
+			}
+			if v != nil {
+				return nil, false
+			}
+			v = inc.X
+		}
+		if v == nil {
+			return nil, false
+		}
+		// The incoming value of a phi instruction with several predecessors
+		// need not dominate the phi instruction, unless it is a constant or a
+		// function parameter.
+		switch v.(type) {
+		case ir.Constant, *ir.Param:
+			return v, true
+		}
+		return v, len(inst.Incs) == 1
+	}
+	return nil, false
+}
+
+// foldBinary folds the integer binary operation with the given constant
+// operands, where op returns the result of the operation on operands of bit
+// size n, or nil if the result is undefined.
+func foldBinary(x, y value.Value, op func(x, y *big.Int, n int64) *big.Int) (value.Value, bool) {
+	cx, ok1 := x.(*ir.ConstInt)
+	cy, ok2 := y.(*ir.ConstInt)
+	if !ok1 || !ok2 {
+		return nil, false
+	}
+	z := op(cx.X, cy.X, cx.Typ.BitSize)
+	if z == nil {
+		return nil, false
+	}
+	return newInt(cx.Typ, z), true
+}
+
+// foldConv folds the integer conversion of the given constant to the given
+// type, where ext returns the operand extended from its bit size.
+func foldConv(from value.Value, to types.Type, ext func(x *big.Int, n int64) *big.Int) (value.Value, bool) {
+	c, ok := from.(*ir.ConstInt)
+	if !ok {
+		return nil, false
+	}
+	t, ok := to.(*types.IntType)
+	if !ok {
+		return nil, false
+	}
+	return newInt(t, ext(c.X, c.Typ.BitSize)), true
+}
+
+// icmp reports whether the integer comparison of the given constants holds.
+func icmp(pred enum.IPred, x, y *ir.ConstInt) bool {
+	n := x.Typ.BitSize
+	us := unsigned(x.X, n).Cmp(unsigned(y.X, n))
+	ss := signed(x.X, n).Cmp(signed(y.X, n))
+	switch pred {
+	case enum.IPredEQ:
+		return us == 0
+	case enum.IPredNE:
+		return us != 0
+	case enum.IPredSGE:
+		return ss >= 0
+	case enum.IPredSGT:
+		return ss > 0
+	case enum.IPredSLE:
+		return ss <= 0
+	case enum.IPredSLT:
+		return ss < 0
+	case enum.IPredUGE:
+		return us >= 0
+	case enum.IPredUGT:
+		return us > 0
+	case enum.IPredULE:
+		return us <= 0
+	case enum.IPredULT:
+		return us < 0
+	default:
+		panic(errors.Errorf("support for integer comparison predicate %v not yet implemented", pred))
+	}
+}
+
+// foldTerm folds the terminator of the given basic block to an unconditional
+// branch if its branch condition is constant, and reports whether the
+// terminator was changed. Incoming values of phi instructions are removed for
+// the dropped control flow edges.
+func foldTerm(block *ir.BasicBlock) bool {
+	var target *ir.BasicBlock
+	switch term := block.Term.(type) {
+	case *ir.TermCondBr:
+		cond, ok := term.Cond.(*ir.ConstInt)
+		if !ok {
+			return false
+		}
+		target = term.TargetFalse
+		if cond.X.Sign() != 0 {
+			target = term.TargetTrue
+		}
+	case *ir.TermSwitch:
+		x, ok := term.X.(*ir.ConstInt)
+		if !ok {
+			return false
+		}
+		target = term.TargetDefault
+		for _, c := range term.Cases {
+			if y, ok := c.X.(*ir.ConstInt); ok && x.Typ.Equal(y.Typ) && x.X.Cmp(y.X) == 0 {
+				target = c.Target
+				break
+			}
+		}
+	default:
+		return false
+	}
+	seen := make(map[*ir.BasicBlock]bool)
+	for _, succ := range block.Term.Succs() {
+		if seen[succ] {
+			continue
+		}
+		seen[succ] = true
+		// Keep one incoming value for the remaining control flow edge.
+		removeIncs(succ, block, succ == target)
+	}
+	block.NewBr(target)
+	return true
+}
+
+// removeIncs removes the incoming values from the given predecessor of the
+// phi instructions of the given basic block. If keepOne is set, the first
+// incoming value from pred is kept.
+func removeIncs(block, pred *ir.BasicBlock, keepOne bool) {
+	for _, inst := range block.Insts {
+		phi, ok := inst.(*ir.InstPhi)
+		if !ok {
+			break
+		}
+		keep := keepOne
+		var incs []*ir.Incoming
+		for _, inc := range phi.Incs {
+			if inc.Pred == pred {
+				if !keep {
+					continue
+				}
+				keep = false
+			}
+			incs = append(incs, inc)
+		}
+		phi.Incs = incs
+	}
+}
+
+// removeUnreachable removes the basic blocks of the given function not
+// reachable from the entry basic block, and reports whether any basic block was
+// removed.
+func removeUnreachable(f *ir.Function) bool {
+	reachable := make(map[*ir.BasicBlock]bool)
+	for _, block := range analysis.ReversePostorder(f) {
+		reachable[block] = true
+	}
+	if len(reachable) == len(f.Blocks) {
+		return false
+	}
+	var blocks []*ir.BasicBlock
+	for _, block := range f.Blocks {
+		if !reachable[block] {
+			for _, succ := range block.Term.Succs() {
+				if reachable[succ] {
+					removeIncs(succ, block, false)
+				}
+			}
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	f.Blocks = blocks
+	return true
+}
+
+// sameValue reports whether the given values are identical, or equal integer
+// constants.
+func sameValue(a, b value.Value) bool {
+	if a == b {
+		return true
+	}
+	x, ok1 := a.(*ir.ConstInt)
+	y, ok2 := b.(*ir.ConstInt)
+	return ok1 && ok2 && x.Typ.Equal(y.Typ) && x.X.Cmp(y.X) == 0
+}
+
+// newInt returns a new integer constant of the given type, with the given
+// integer value wrapped to the bit size of the type.
+func newInt(t *types.IntType, x *big.Int) *ir.ConstInt {
+	if t.BitSize == 1 {
+		return &ir.ConstInt{Typ: t, X: unsigned(x, 1)}
+	}
+	return &ir.ConstInt{Typ: t, X: signed(x, t.BitSize)}
+}
+
+// unsigned returns x wrapped to an unsigned integer of bit size n.
+func unsigned(x *big.Int, n int64) *big.Int {
+	mod := new(big.Int).Lsh(big.NewInt(1), uint(n))
+	// Mod returns the Euclidean modulus, which is non-negative.
+	return new(big.Int).Mod(x, mod)
+}
+
+// signed returns x wrapped to a signed integer of bit size n.
+func signed(x *big.Int, n int64) *big.Int {
+	z := unsigned(x, n)
+	if z.Bit(int(n-1)) == 1 {
+		z.Sub(z, new(big.Int).Lsh(big.NewInt(1), uint(n)))
+	}
+	return z
+}
+
+// overflowsSigned reports whether x is out of range of signed integers of bit
+// size n.
+func overflowsSigned(x *big.Int, n int64) bool {
+	return signed(x, n).Cmp(x) != 0
+}
+
+// shiftAmount returns the shift amount y of a shift of an integer of bit size
+// n. The boolean return value is false if the result of the shift is poison.
+func shiftAmount(y *big.Int, n int64) (uint, bool) {
+	s := unsigned(y, n)
+	if s.Cmp(big.NewInt(n)) >= 0 {
+		return 0, false
+	}
+	return uint(s.Uint64()), true
+}
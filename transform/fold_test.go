@@ -0,0 +1,84 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+func TestFoldConstants(t *testing.T) {
+	golden := []struct {
+		inst func(block *ir.BasicBlock) ir.Instruction
+		want string
+	}{
+		// i=0
+		{
+			inst: func(block *ir.BasicBlock) ir.Instruction {
+				return block.NewAdd(ir.NewInt(types.I8, 127), ir.NewInt(types.I8, 1))
+			},
+			want: "i8 -128",
+		},
+		// i=1
+		{
+			inst: func(block *ir.BasicBlock) ir.Instruction {
+				return block.NewUDiv(ir.NewInt(types.I8, -1), ir.NewInt(types.I8, 16))
+			},
+			want: "i8 15",
+		},
+		// i=2
+		{
+			inst: func(block *ir.BasicBlock) ir.Instruction {
+				return block.NewAShr(ir.NewInt(types.I32, -8), ir.NewInt(types.I32, 2))
+			},
+			want: "i32 -2",
+		},
+		// i=3
+		{
+			inst: func(block *ir.BasicBlock) ir.Instruction {
+				return block.NewICmp(enum.IPredULT, ir.NewInt(types.I32, 1), ir.NewInt(types.I32, -1))
+			},
+			want: "i1 true",
+		},
+		// i=4
+		{
+			inst: func(block *ir.BasicBlock) ir.Instruction {
+				return block.NewZExt(ir.NewInt(types.I8, -1), types.I32)
+			},
+			want: "i32 255",
+		},
+		// i=5
+		{
+			inst: func(block *ir.BasicBlock) ir.Instruction {
+				return block.NewTrunc(ir.NewInt(types.I32, 511), types.I8)
+			},
+			want: "i8 -1",
+		},
+		// i=6; division by zero is not folded.
+		{
+			inst: func(block *ir.BasicBlock) ir.Instruction {
+				return block.NewSDiv(ir.NewInt(types.I32, 1), ir.NewInt(types.I32, 0))
+			},
+			want: "",
+		},
+	}
+	for i, g := range golden {
+		f := ir.NewFunc("f", types.Void)
+		block := ir.NewBlock("")
+		inst := g.inst(block)
+		ret := block.NewRet(inst.(value.Value))
+		f.Blocks = []*ir.BasicBlock{block}
+		FoldConstants(f)
+		if g.want == "" {
+			if ret.X != inst.(value.Value) {
+				t.Errorf("i=%d: unexpected folding to %v", i, ret.X)
+			}
+			continue
+		}
+		if got := ret.X.String(); g.want != got {
+			t.Errorf("i=%d: folded value mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,498 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/llir/l/analysis"
+	"github.com/llir/l/internal/enc"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Loop simplification ] =================================================
+
+// SimplifyLoops transforms the loops of the given function into simplified
+// form, and reports whether the function was changed. In simplified form, each
+// loop has
+//
+//    * a preheader; a single predecessor of the header outside the loop, which
+//      branches unconditionally to the header,
+//    * a single latch; a single basic block with a back edge to the header, and
+//    * dedicated exits; exit basic blocks with no predecessors outside the loop.
+//
+// Control flow edges which may not be split (e.g. edges of indirectbr
+// terminators and edges to exception handling pads) are left as is.
+func SimplifyLoops(f *ir.Function) (bool, error) {
+	changed := false
+	for simplifyLoop(f) {
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	// Local IDs are reassigned, as basic blocks and phi instructions are
+	// inserted.
+	clearLocalIDs(f)
+	if err := f.AssignIDs(); err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// simplifyLoop transforms the first loop of the given function not in
+// simplified form by inserting a preheader, latch or dedicated exit, and
+// reports whether the function was changed.
+func simplifyLoop(f *ir.Function) bool {
+	info := analysis.FindLoops(f)
+	preds := analysis.Preds(f)
+	loops := append([]*analysis.Loop(nil), info.Loops...)
+	for len(loops) > 0 {
+		l := loops[0]
+		loops = append(loops[1:], l.Children...)
+		// Insert preheader.
+		if _, ok := termOf(l.Preheader).(*ir.TermBr); !ok {
+			var outside []*ir.BasicBlock
+			for _, pred := range preds[l.Header] {
+				if !l.Contains(pred) {
+					outside = append(outside, pred)
+				}
+			}
+			if len(outside) > 0 && canSplit(l.Header, outside) {
+				splitPreds(f, l.Header, outside, "preheader")
+				return true
+			}
+		}
+		// Insert single latch.
+		if len(l.Latches) > 1 && canSplit(l.Header, l.Latches) {
+			splitPreds(f, l.Header, l.Latches, "backedge")
+			return true
+		}
+		// Insert dedicated exits.
+		for _, block := range l.Blocks {
+			for _, exit := range block.Term.Succs() {
+				if l.Contains(exit) {
+					continue
+				}
+				var inside []*ir.BasicBlock
+				dedicated := true
+				for _, pred := range preds[exit] {
+					if l.Contains(pred) {
+						inside = append(inside, pred)
+					} else {
+						dedicated = false
+					}
+				}
+				if !dedicated && canSplit(exit, inside) {
+					splitPreds(f, exit, inside, "loopexit")
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// === [ Loop rotation ] =======================================================
+
+// RotateLoops transforms the loops of the given function into simplified form,
+// and rotates each loop as by RotateLoop. RotateLoops reports whether the
+// function was changed.
+func RotateLoops(f *ir.Function) (bool, error) {
+	changed, err := SimplifyLoops(f)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	info := analysis.FindLoops(f)
+	loops := append([]*analysis.Loop(nil), info.Loops...)
+	for len(loops) > 0 {
+		l := loops[0]
+		loops = append(loops[1:], l.Children...)
+		rotated, err := RotateLoop(f, l)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		if rotated {
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// RotateLoop rotates the given loop of the function, so that the exit condition
+// of the loop is tested at the end of each iteration rather than at the start,
+// and reports whether the loop was rotated.
+//
+// The header of the loop is duplicated into the preheader, where it guards
+// entry to the loop, and moved to the end of the loop, where it becomes the
+// latch. The successor of the header within the loop becomes the new header.
+// Values defined in the header are merged with their duplicates by phi
+// instructions in the new header and the exit of the loop.
+//
+// Only loops in simplified form (see SimplifyLoops) are rotated, for which the
+// header is the only basic block exiting the loop, by a conditional branch.
+func RotateLoop(f *ir.Function, l *analysis.Loop) (bool, error) {
+	header, ph := l.Header, l.Preheader
+	if _, ok := termOf(ph).(*ir.TermBr); !ok || len(l.Latches) != 1 {
+		return false, nil
+	}
+	latch := l.Latches[0]
+	if _, ok := latch.Term.(*ir.TermBr); !ok || latch == header {
+		return false, nil
+	}
+	term, ok := header.Term.(*ir.TermCondBr)
+	if !ok {
+		return false, nil
+	}
+	body, exit := term.TargetTrue, term.TargetFalse
+	if !l.Contains(body) {
+		body, exit = exit, body
+	}
+	if !l.Contains(body) || l.Contains(exit) {
+		return false, nil
+	}
+	preds := analysis.Preds(f)
+	if len(preds[body]) != 1 || len(preds[exit]) != 1 {
+		return false, nil
+	}
+	for _, block := range l.Blocks {
+		if block == header {
+			continue
+		}
+		for _, succ := range block.Term.Succs() {
+			if !l.Contains(succ) {
+				return false, nil
+			}
+		}
+	}
+	names := localNames(f)
+	// Duplicate header into preheader.
+	remap := make(map[value.Value]value.Value)
+	var defs []value.Value
+	var phis []*ir.InstPhi
+	for _, inst := range header.Insts {
+		if phi, ok := inst.(*ir.InstPhi); ok {
+			remap[phi] = incomingFrom(phi, ph)
+			defs = append(defs, phi)
+			phis = append(phis, phi)
+			continue
+		}
+		c := ir.CloneInst(inst, remap)
+		if v, ok := inst.(value.Named); ok {
+			c.(value.Named).SetName(derivedName(names, v.Name(), "pre"))
+			remap[v] = c.(value.Value)
+			defs = append(defs, v)
+		}
+		ph.InsertInst(len(ph.Insts), c)
+	}
+	ph.NewCondBr(remapValue(remap, term.Cond), term.TargetTrue, term.TargetFalse)
+	// Add incoming values from the preheader to the existing phi instructions
+	// of the new header and exit.
+	bodyPhis := leadingPhis(body)
+	exitPhis := leadingPhis(exit)
+	for _, phi := range append(bodyPhis, exitPhis...) {
+		x := incomingFrom(phi, header)
+		phi.Incs = append(phi.Incs, ir.NewIncoming(remapValue(remap, x), ph))
+	}
+	removeIncs(header, ph, false)
+	// Merge values defined in the header with their duplicates.
+	isPhi := make(map[interface{}]bool)
+	for _, phi := range append(bodyPhis, exitPhis...) {
+		isPhi[phi] = true
+	}
+	for _, v := range defs {
+		var loopUses, exitUses []interface{}
+		for _, block := range f.Blocks {
+			switch {
+			case block == header:
+				for _, phi := range phis {
+					if usesValue(phi, v) {
+						loopUses = append(loopUses, phi)
+					}
+				}
+			case block == ph:
+				// no uses.
+			default:
+				for _, node := range blockNodes(block) {
+					if isPhi[node] || !usesValue(node, v) {
+						continue
+					}
+					if l.Contains(block) {
+						loopUses = append(loopUses, node)
+					} else {
+						exitUses = append(exitUses, node)
+					}
+				}
+			}
+		}
+		merge := func(block *ir.BasicBlock, uses []interface{}, suffix string) {
+			if len(uses) == 0 {
+				return
+			}
+			phi := ir.NewPhi(ir.NewIncoming(remap[v], ph), ir.NewIncoming(v, header))
+			phi.SetName(derivedName(names, v.(value.Named).Name(), suffix))
+			block.InsertInst(0, phi)
+			for _, use := range uses {
+				ir.ReplaceUses(use, v, phi)
+			}
+		}
+		merge(body, loopUses, "loop")
+		merge(exit, exitUses, "exit")
+	}
+	// The phi instructions of the header have a single incoming value from the
+	// latch.
+	for _, phi := range phis {
+		ir.ReplaceUses(f, phi, incomingFrom(phi, latch))
+		header.RemoveInst(phi)
+	}
+	// Move the header after the latch.
+	var blocks []*ir.BasicBlock
+	for _, block := range f.Blocks {
+		if block == header {
+			continue
+		}
+		blocks = append(blocks, block)
+		if block == latch {
+			blocks = append(blocks, header)
+		}
+	}
+	f.Blocks = blocks
+	clearLocalIDs(f)
+	if err := f.AssignIDs(); err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// splitPreds inserts a new basic block before the given basic block, and
+// redirects the control flow edges from the given predecessors to the new basic
+// block. Incoming values of phi instructions from the predecessors are merged
+// by phi instructions of the new basic block. The new basic block is named
+// after the given basic block with the given suffix. The predecessors are
+// listed in the order of the basic blocks of the function.
+func splitPreds(f *ir.Function, block *ir.BasicBlock, preds []*ir.BasicBlock, suffix string) *ir.BasicBlock {
+	names := localNames(f)
+	split := ir.NewBlock(derivedName(names, block.LocalName, suffix))
+	isPred := make(map[*ir.BasicBlock]bool)
+	for _, pred := range preds {
+		isPred[pred] = true
+	}
+	for _, phi := range leadingPhis(block) {
+		var moved, kept []*ir.Incoming
+		for _, inc := range phi.Incs {
+			if isPred[inc.Pred] {
+				moved = append(moved, inc)
+			} else {
+				kept = append(kept, inc)
+			}
+		}
+		if len(moved) == 0 {
+			continue
+		}
+		x := moved[0].X
+		for _, inc := range moved[1:] {
+			if !sameValue(x, inc.X) {
+				p := split.NewPhi(moved...)
+				p.SetName(derivedName(names, phi.LocalName, suffix))
+				x = p
+				break
+			}
+		}
+		phi.Incs = append(kept, ir.NewIncoming(x, split))
+	}
+	split.NewBr(block)
+	for _, pred := range preds {
+		retarget(pred.Term, block, split)
+	}
+	// Place the new basic block before the given basic block, or after the last
+	// predecessor if all predecessors follow the given basic block (e.g. for
+	// back edges).
+	var after *ir.BasicBlock
+	for _, b := range f.Blocks {
+		if b == block {
+			after = preds[len(preds)-1]
+			break
+		}
+		if isPred[b] {
+			break
+		}
+	}
+	var blocks []*ir.BasicBlock
+	for _, b := range f.Blocks {
+		if b == block && after == nil {
+			blocks = append(blocks, split)
+		}
+		blocks = append(blocks, b)
+		if b == after {
+			blocks = append(blocks, split)
+		}
+	}
+	f.Blocks = blocks
+	return split
+}
+
+// canSplit reports whether the control flow edges from the given predecessors
+// to the given basic block may be redirected to a new basic block.
+func canSplit(block *ir.BasicBlock, preds []*ir.BasicBlock) bool {
+	if len(block.Insts) == 0 {
+		if _, ok := block.Term.(*ir.TermCatchSwitch); ok {
+			return false
+		}
+	} else {
+		switch block.Insts[0].(type) {
+		case *ir.InstLandingPad, *ir.InstCatchPad, *ir.InstCleanupPad:
+			return false
+		}
+	}
+	for _, pred := range preds {
+		switch pred.Term.(type) {
+		case *ir.TermBr, *ir.TermCondBr, *ir.TermSwitch, *ir.TermInvoke, *ir.TermCatchRet:
+			// supported.
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// retarget redirects the control flow edges of the given terminator from old
+// to new.
+func retarget(term ir.Terminator, old, new *ir.BasicBlock) {
+	replace := func(target **ir.BasicBlock) {
+		if *target == old {
+			*target = new
+		}
+	}
+	switch term := term.(type) {
+	case *ir.TermBr:
+		replace(&term.Target)
+		term.Successors = nil
+	case *ir.TermCondBr:
+		replace(&term.TargetTrue)
+		replace(&term.TargetFalse)
+		term.Successors = nil
+	case *ir.TermSwitch:
+		replace(&term.TargetDefault)
+		for _, c := range term.Cases {
+			replace(&c.Target)
+		}
+		term.Successors = nil
+	case *ir.TermInvoke:
+		replace(&term.Normal)
+		term.Successors = nil
+	case *ir.TermCatchRet:
+		replace(&term.To)
+		term.Successors = nil
+	default:
+		panic(errors.Errorf("support for redirecting terminator %T not yet implemented", term))
+	}
+}
+
+// termOf returns the terminator of the given basic block, or nil if the basic
+// block is nil.
+func termOf(block *ir.BasicBlock) ir.Terminator {
+	if block == nil {
+		return nil
+	}
+	return block.Term
+}
+
+// leadingPhis returns the phi instructions at the start of the given basic
+// block.
+func leadingPhis(block *ir.BasicBlock) []*ir.InstPhi {
+	var phis []*ir.InstPhi
+	for _, inst := range block.Insts {
+		phi, ok := inst.(*ir.InstPhi)
+		if !ok {
+			break
+		}
+		phis = append(phis, phi)
+	}
+	return phis
+}
+
+// incomingFrom returns the incoming value of the given phi instruction from the
+// given predecessor basic block, or nil if not present.
+func incomingFrom(phi *ir.InstPhi, pred *ir.BasicBlock) value.Value {
+	for _, inc := range phi.Incs {
+		if inc.Pred == pred {
+			return inc.X
+		}
+	}
+	return nil
+}
+
+// remapValue returns the replacement of the given value, or the value itself
+// if not remapped.
+func remapValue(remap map[value.Value]value.Value, v value.Value) value.Value {
+	if x, ok := remap[v]; ok {
+		return x
+	}
+	return v
+}
+
+// blockNodes returns the instructions and terminator of the given basic block.
+func blockNodes(block *ir.BasicBlock) []interface{} {
+	var nodes []interface{}
+	for _, inst := range block.Insts {
+		nodes = append(nodes, inst)
+	}
+	return append(nodes, block.Term)
+}
+
+// usesValue reports whether the given IR node uses the given value as an
+// operand.
+func usesValue(node interface{}, v value.Value) bool {
+	found := false
+	ir.Walk(node, func(n interface{}) bool {
+		if n == v && n != node {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// localNames returns the set of local names of the given function.
+func localNames(f *ir.Function) map[string]bool {
+	names := make(map[string]bool)
+	for _, param := range f.Params {
+		names[param.Name()] = true
+	}
+	for _, block := range f.Blocks {
+		names[block.LocalName] = true
+		for _, inst := range block.Insts {
+			if n, ok := inst.(value.Named); ok {
+				names[n.Name()] = true
+			}
+		}
+		if n, ok := block.Term.(value.Named); ok {
+			names[n.Name()] = true
+		}
+	}
+	return names
+}
+
+// derivedName returns a local name derived from the given name and suffix,
+// which is unique within the given set of local names, and adds it to the set.
+// An empty name is returned for unnamed values and values named by local IDs.
+func derivedName(names map[string]bool, name, suffix string) string {
+	if len(name) == 0 || enc.IsID(name) {
+		return ""
+	}
+	return uniqueName(names, fmt.Sprintf("%s.%s", name, suffix))
+}
+
+// uniqueName returns the given local name if not present in the given set of
+// local names, or the name with a numeric suffix otherwise, and adds it to the
+// set.
+func uniqueName(names map[string]bool, name string) string {
+	unique := name
+	for i := 1; names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	names[unique] = true
+	return unique
+}
@@ -0,0 +1,147 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestSimplifyLoops(t *testing.T) {
+	// define i32 @f(i1 %c, i32 %n) {
+	// entry:
+	//    br i1 %c, label %loop, label %exit
+	// loop:
+	//    %i = phi i32 [ 0, %entry ], [ %next, %a ], [ %next, %b ]
+	//    %next = add i32 %i, 1
+	//    %cmp = icmp slt i32 %next, %n
+	//    br i1 %cmp, label %a, label %exit
+	// a:
+	//    br i1 %c, label %loop, label %b
+	// b:
+	//    br label %loop
+	// exit:
+	//    %r = phi i32 [ 0, %entry ], [ %i, %loop ]
+	//    ret i32 %r
+	// }
+	c := ir.NewParam(types.I1, "c")
+	n := ir.NewParam(types.I32, "n")
+	f := ir.NewFunc("f", types.I32, c, n)
+	entry := ir.NewBlock("entry")
+	loop := ir.NewBlock("loop")
+	a := ir.NewBlock("a")
+	b := ir.NewBlock("b")
+	exit := ir.NewBlock("exit")
+	zero := ir.NewInt(types.I32, 0)
+	entry.NewCondBr(c, loop, exit)
+	i := loop.NewPhi(ir.NewIncoming(zero, entry))
+	i.SetName("i")
+	next := loop.NewAdd(i, ir.NewInt(types.I32, 1))
+	next.SetName("next")
+	i.Incs = append(i.Incs, ir.NewIncoming(next, a), ir.NewIncoming(next, b))
+	cmp := loop.NewICmp(enum.IPredSLT, next, n)
+	cmp.SetName("cmp")
+	loop.NewCondBr(cmp, a, exit)
+	a.NewCondBr(c, loop, b)
+	b.NewBr(loop)
+	r := exit.NewPhi(ir.NewIncoming(zero, entry), ir.NewIncoming(i, loop))
+	r.SetName("r")
+	exit.NewRet(r)
+	f.Blocks = []*ir.BasicBlock{entry, loop, a, b, exit}
+	changed, err := SimplifyLoops(f)
+	if err != nil {
+		t.Fatalf("unable to simplify loops; %v", err)
+	}
+	if !changed {
+		t.Errorf("expected loops to be simplified")
+	}
+	want := `define i32 @f(i1 %c, i32 %n) {
+entry:
+	br i1 %c, label %loop.preheader, label %exit
+loop.preheader:
+	br label %loop
+loop:
+	%i = phi i32 [ 0, %loop.preheader ], [ %next, %loop.backedge ]
+	%next = add i32 %i, 1
+	%cmp = icmp slt i32 %next, %n
+	br i1 %cmp, label %a, label %exit.loopexit
+a:
+	br i1 %c, label %loop.backedge, label %b
+b:
+	br label %loop.backedge
+loop.backedge:
+	br label %loop
+exit.loopexit:
+	br label %exit
+exit:
+	%r = phi i32 [ 0, %entry ], [ %i, %exit.loopexit ]
+	ret i32 %r
+}`
+	if got := f.Def(); want != got {
+		t.Errorf("function mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// Simplified loops are left as is.
+	if changed, err := SimplifyLoops(f); err != nil || changed {
+		t.Errorf("expected simplified loops to be left as is; changed %v, err %v", changed, err)
+	}
+}
+
+func TestRotateLoops(t *testing.T) {
+	// define i32 @f(i32 %n) {
+	// entry:
+	//    br label %loop
+	// loop:
+	//    %i = phi i32 [ 0, %entry ], [ %next, %body ]
+	//    %cmp = icmp slt i32 %i, %n
+	//    br i1 %cmp, label %body, label %exit
+	// body:
+	//    %next = add i32 %i, 1
+	//    br label %loop
+	// exit:
+	//    ret i32 %i
+	// }
+	n := ir.NewParam(types.I32, "n")
+	f := ir.NewFunc("f", types.I32, n)
+	entry := ir.NewBlock("entry")
+	loop := ir.NewBlock("loop")
+	body := ir.NewBlock("body")
+	exit := ir.NewBlock("exit")
+	entry.NewBr(loop)
+	i := loop.NewPhi(ir.NewIncoming(ir.NewInt(types.I32, 0), entry))
+	i.SetName("i")
+	cmp := loop.NewICmp(enum.IPredSLT, i, n)
+	cmp.SetName("cmp")
+	loop.NewCondBr(cmp, body, exit)
+	next := body.NewAdd(i, ir.NewInt(types.I32, 1))
+	next.SetName("next")
+	body.NewBr(loop)
+	i.Incs = append(i.Incs, ir.NewIncoming(next, body))
+	exit.NewRet(i)
+	f.Blocks = []*ir.BasicBlock{entry, loop, body, exit}
+	changed, err := RotateLoops(f)
+	if err != nil {
+		t.Fatalf("unable to rotate loops; %v", err)
+	}
+	if !changed {
+		t.Errorf("expected loop to be rotated")
+	}
+	want := `define i32 @f(i32 %n) {
+entry:
+	%cmp.pre = icmp slt i32 0, %n
+	br i1 %cmp.pre, label %body, label %exit
+body:
+	%i.loop = phi i32 [ 0, %entry ], [ %next, %loop ]
+	%next = add i32 %i.loop, 1
+	br label %loop
+loop:
+	%cmp = icmp slt i32 %next, %n
+	br i1 %cmp, label %body, label %exit
+exit:
+	%i.exit = phi i32 [ 0, %entry ], [ %next, %loop ]
+	ret i32 %i.exit
+}`
+	if got := f.Def(); want != got {
+		t.Errorf("function mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
@@ -0,0 +1,78 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+)
+
+// === [ Constant merging ] ====================================================
+
+// MergeConstants merges identical constant global variables of the module
+// (e.g. string literals and lookup tables), and reports whether the module was
+// changed. Uses of merged global variables are replaced by the first identical
+// global variable of the module, and merged global variables are removed from
+// the module.
+//
+// Only constant global variables with private or internal linkage and the
+// unnamed_addr attribute are merged, as their addresses are not significant.
+// Merging is repeated until no more global variables are identical, so that
+// tables of pointers to merged global variables are merged in turn.
+func MergeConstants(m *ir.Module) bool {
+	changed := false
+	for {
+		// Canonical global variable of each mergeable initializer; key -> global.
+		canon := make(map[string]*ir.Global)
+		var merged []*ir.Global
+		for _, g := range m.Globals {
+			if !isMergeable(g) {
+				continue
+			}
+			key := mergeKey(g)
+			keep, ok := canon[key]
+			if !ok {
+				canon[key] = g
+				continue
+			}
+			if g.Align > keep.Align {
+				keep.Align = g.Align
+			}
+			ir.ReplaceUses(m, g, keep)
+			merged = append(merged, g)
+		}
+		if len(merged) == 0 {
+			return changed
+		}
+		for _, g := range merged {
+			m.RemoveGlobal(g)
+		}
+		changed = true
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// isMergeable reports whether the given global variable may be merged with
+// identical global variables.
+func isMergeable(g *ir.Global) bool {
+	switch {
+	case !g.Immutable || g.Init == nil:
+		return false
+	case g.Linkage != enum.LinkagePrivate && g.Linkage != enum.LinkageInternal:
+		return false
+	case g.UnnamedAddr != enum.UnnamedAddrUnnamedAddr:
+		return false
+	case g.ExternallyInitialized || g.TLSModel != enum.TLSModelNone:
+		return false
+	case g.Comdat != nil || len(g.Metadata) > 0:
+		return false
+	}
+	return true
+}
+
+// mergeKey returns the key of the given mergeable global variable; global
+// variables of the same key are identical.
+func mergeKey(g *ir.Global) string {
+	return fmt.Sprintf("%s %s section %q", g.Type(), g.Init.Ident(), g.Section)
+}
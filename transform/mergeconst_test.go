@@ -0,0 +1,70 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestMergeConstants(t *testing.T) {
+	m := &ir.Module{}
+	newConst := func(name string, init ir.Constant) *ir.Global {
+		g := m.NewGlobalDef(name, init)
+		g.Linkage = enum.LinkagePrivate
+		g.UnnamedAddr = enum.UnnamedAddrUnnamedAddr
+		g.Immutable = true
+		return g
+	}
+	str := func(g *ir.Global) ir.Constant {
+		zero := ir.NewInt(types.I64, 0)
+		return ir.NewGetElementPtrExpr(g.ContentType, g, ir.NewIndex(zero), ir.NewIndex(zero))
+	}
+	s0 := newConst("s0", ir.NewCharArrayFromString("hi\x00"))
+	s1 := newConst("s1", ir.NewCharArrayFromString("hi\x00"))
+	s1.Align = 8
+	newConst("s2", ir.NewCharArrayFromString("bye\x00"))
+	// Tables of identical strings are merged once the strings are merged.
+	t0 := newConst("t0", ir.NewArray(types.NewArray(1, types.I8Ptr), str(s0)))
+	t1 := newConst("t1", ir.NewArray(types.NewArray(1, types.I8Ptr), str(s1)))
+	// Global variables with significant addresses are not merged.
+	pub := m.NewGlobalDef("pub", ir.NewCharArrayFromString("hi\x00"))
+	pub.Immutable = true
+	f := m.NewFunc("f", types.I8Ptr)
+	entry := f.NewBlock("entry")
+	p := entry.NewGetElementPtr(t1.ContentType, t1, ir.NewInt(types.I64, 0), ir.NewInt(types.I64, 0))
+	entry.NewRet(entry.NewLoad(p))
+	if !MergeConstants(m) {
+		t.Fatalf("expected constants to be merged")
+	}
+	want := `@s0 = private unnamed_addr constant [3 x i8] c"hi\00", align 8
+@s2 = private unnamed_addr constant [4 x i8] c"bye\00"
+@t0 = private unnamed_addr constant [1 x i8*] [i8* getelementptr ([3 x i8], [3 x i8]* @s0, i64 0, i64 0)]
+@pub = constant [3 x i8] c"hi\00"
+define i8* @f() {
+entry:
+	%0 = getelementptr [1 x i8*], [1 x i8*]* @t0, i64 0, i64 0
+	%1 = load i8*, i8** %0
+	ret i8* %1
+}
+`
+	if err := f.AssignIDs(); err != nil {
+		t.Fatalf("unable to assign IDs; %+v", err)
+	}
+	if got := m.Def(); want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+	for _, name := range []string{"s1", "t1"} {
+		if _, ok := m.Lookup(name); ok {
+			t.Errorf("expected merged global variable %q to be removed from symbol table", name)
+		}
+	}
+	if _, ok := m.Lookup(t0.Name()); !ok {
+		t.Errorf("expected global variable %q to remain in symbol table", t0.Name())
+	}
+	// Nothing left to merge.
+	if MergeConstants(m) {
+		t.Errorf("expected module to be left as is")
+	}
+}
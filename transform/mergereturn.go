@@ -0,0 +1,45 @@
+package transform
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+// === [ Unified exit ] ========================================================
+
+// MergeReturns merges the ret terminators of the given function into a single
+// exit basic block, and reports whether the function was changed. Each ret
+// terminator is replaced by a branch to the exit basic block, in which a phi
+// instruction merges the return values of non-void functions.
+func MergeReturns(f *ir.Function) bool {
+	var rets []*ir.BasicBlock
+	for _, block := range f.Blocks {
+		if _, ok := block.Term.(*ir.TermRet); ok {
+			rets = append(rets, block)
+		}
+	}
+	if len(rets) < 2 {
+		return false
+	}
+	names := localNames(f)
+	exit := f.NewBlock(uniqueName(names, "UnifiedReturnBlock"))
+	var phi *ir.InstPhi
+	if !f.Sig.RetType.Equal(types.Void) {
+		phi = exit.NewPhi()
+		phi.SetName(uniqueName(names, "UnifiedRetVal"))
+		phi.Typ = f.Sig.RetType
+	}
+	for _, block := range rets {
+		ret := block.Term.(*ir.TermRet)
+		if phi != nil {
+			phi.Incs = append(phi.Incs, ir.NewIncoming(ret.X, block))
+		}
+		block.NewBr(exit)
+	}
+	if phi != nil {
+		exit.NewRet(phi)
+	} else {
+		exit.NewRet(nil)
+	}
+	return true
+}
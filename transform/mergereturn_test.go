@@ -0,0 +1,76 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestMergeReturns(t *testing.T) {
+	// define i32 @f(i1 %c) {
+	// entry:
+	//    br i1 %c, label %a, label %b
+	// a:
+	//    ret i32 1
+	// b:
+	//    ret i32 2
+	// }
+	c := ir.NewParam(types.I1, "c")
+	f := ir.NewFunc("f", types.I32, c)
+	entry := ir.NewBlock("entry")
+	a := ir.NewBlock("a")
+	b := ir.NewBlock("b")
+	entry.NewCondBr(c, a, b)
+	a.NewRet(ir.NewInt(types.I32, 1))
+	b.NewRet(ir.NewInt(types.I32, 2))
+	f.Blocks = []*ir.BasicBlock{entry, a, b}
+	if !MergeReturns(f) {
+		t.Errorf("expected returns to be merged")
+	}
+	want := `define i32 @f(i1 %c) {
+entry:
+	br i1 %c, label %a, label %b
+a:
+	br label %UnifiedReturnBlock
+b:
+	br label %UnifiedReturnBlock
+UnifiedReturnBlock:
+	%UnifiedRetVal = phi i32 [ 1, %a ], [ 2, %b ]
+	ret i32 %UnifiedRetVal
+}`
+	if got := f.Def(); want != got {
+		t.Errorf("function mismatch; expected `%v`, got `%v`", want, got)
+	}
+	// Single return.
+	if MergeReturns(f) {
+		t.Errorf("expected function with single return to be left as is")
+	}
+}
+
+func TestMergeReturnsObserved(t *testing.T) {
+	m := &ir.Module{}
+	c := ir.NewParam(types.I1, "c")
+	f := m.NewFunc("f", types.Void, c)
+	entry := f.NewBlock("entry")
+	a := f.NewBlock("a")
+	b := f.NewBlock("b")
+	entry.NewCondBr(c, a, b)
+	a.NewRet(nil)
+	b.NewRet(nil)
+	var inserted []*ir.BasicBlock
+	m.SetObserver(&ir.Observer{
+		Insert: func(parent, node interface{}) {
+			if block, ok := node.(*ir.BasicBlock); ok && parent == f {
+				inserted = append(inserted, block)
+			}
+		},
+	})
+	if !MergeReturns(f) {
+		t.Fatalf("expected returns to be merged")
+	}
+	exit := f.Blocks[len(f.Blocks)-1]
+	if len(inserted) != 1 || inserted[0] != exit {
+		t.Errorf("inserted basic blocks mismatch; expected [%v], got %v", exit.Ident(), inserted)
+	}
+}
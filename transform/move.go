@@ -0,0 +1,362 @@
+package transform
+
+import (
+	"math/big"
+
+	"github.com/llir/l/analysis"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Code motion ] =========================================================
+
+// Hoist moves the given instruction of the function to the end of the given
+// basic block, which must dominate the basic block of the instruction. An error
+// is returned if the move is not legal, in which case the function is left
+// unchanged.
+//
+// The instruction must be free of side effects (e.g. a binary instruction or a
+// non-volatile, non-atomic load), and its operands must be available at the end
+// of the target basic block. Instructions which may trap (e.g. loads and
+// integer division) are only hoisted if their basic block is executed whenever
+// the target basic block is, and loads are only hoisted if no instruction along
+// the paths from the target basic block to the load may write to memory.
+func Hoist(f *ir.Function, inst ir.Instruction, to *ir.BasicBlock) error {
+	from, err := checkMove(f, inst, to)
+	if err != nil {
+		return errors.Wrapf(err, "unable to hoist %v to %v", instIdent(inst), to.Ident())
+	}
+	dom := analysis.Dominators(f)
+	if !dom.Dominates(to, from) {
+		return errors.Errorf("unable to hoist %v to %v; target basic block does not dominate %v", instIdent(inst), to.Ident(), from.Ident())
+	}
+	// Operands must be available at the end of the target basic block.
+	blockOf := instBlocks(f)
+	for _, x := range operandsOf(inst) {
+		if def, ok := blockOf[x]; ok && !dom.Dominates(def, to) {
+			return errors.Errorf("unable to hoist %v to %v; operand %v not available", instIdent(inst), to.Ident(), x.Ident())
+		}
+	}
+	if mayTrap(inst) && !alwaysReaches(to, from) {
+		return errors.Errorf("unable to hoist %v to %v; instruction may trap and is not executed on every path from %v", instIdent(inst), to.Ident(), to.Ident())
+	}
+	if _, ok := inst.(*ir.InstLoad); ok {
+		// Instructions between the end of the target basic block and the load.
+		var between []interface{}
+		between = append(between, to.Term)
+		for _, x := range from.Insts {
+			if x == inst {
+				break
+			}
+			between = append(between, x)
+		}
+		if clobber, ok := findClobber(to, from, between); ok {
+			return errors.Errorf("unable to hoist %v to %v; memory may be written by %v", instIdent(inst), to.Ident(), nodeString(clobber))
+		}
+	}
+	from.RemoveInst(inst)
+	to.InsertInst(len(to.Insts), inst)
+	return nil
+}
+
+// Sink moves the given instruction of the function to the start of the given
+// basic block (after its phi instructions and exception handling pad), which
+// must be dominated by the basic block of the instruction. An error is returned
+// if the move is not legal, in which case the function is left unchanged.
+//
+// The instruction must be free of side effects (e.g. a binary instruction or a
+// non-volatile, non-atomic load), and each use of the instruction must be
+// dominated by the target basic block. Loads are only sunk if no instruction
+// along the paths from the load to the target basic block may write to memory.
+func Sink(f *ir.Function, inst ir.Instruction, to *ir.BasicBlock) error {
+	from, err := checkMove(f, inst, to)
+	if err != nil {
+		return errors.Wrapf(err, "unable to sink %v to %v", instIdent(inst), to.Ident())
+	}
+	dom := analysis.Dominators(f)
+	if !dom.Dominates(from, to) {
+		return errors.Errorf("unable to sink %v to %v; %v does not dominate target basic block", instIdent(inst), to.Ident(), from.Ident())
+	}
+	pos := insertionPoint(to)
+	if len(to.Insts) == 0 {
+		if _, ok := to.Term.(*ir.TermCatchSwitch); ok {
+			return errors.Errorf("unable to sink %v to %v; target basic block is a catchswitch", instIdent(inst), to.Ident())
+		}
+	}
+	// Uses must be dominated by the target basic block.
+	v := inst.(value.Value)
+	for _, block := range f.Blocks {
+		for i, node := range blockNodes(block) {
+			if node == inst || !usesValue(node, v) {
+				continue
+			}
+			if phi, ok := node.(*ir.InstPhi); ok {
+				for _, inc := range phi.Incs {
+					if inc.X == v && !dom.Dominates(to, inc.Pred) {
+						return errors.Errorf("unable to sink %v to %v; use in %v not dominated", instIdent(inst), to.Ident(), nodeString(node))
+					}
+				}
+				continue
+			}
+			if !dom.Dominates(to, block) || (block == to && i < pos) {
+				return errors.Errorf("unable to sink %v to %v; use in %v not dominated", instIdent(inst), to.Ident(), nodeString(node))
+			}
+		}
+	}
+	if _, ok := inst.(*ir.InstLoad); ok {
+		// Instructions between the load and the start of the target basic block.
+		var between []interface{}
+		after := false
+		for _, node := range blockNodes(from) {
+			if after {
+				between = append(between, node)
+			}
+			if node == inst {
+				after = true
+			}
+		}
+		if clobber, ok := findClobber(from, to, between); ok {
+			return errors.Errorf("unable to sink %v to %v; memory may be written by %v", instIdent(inst), to.Ident(), nodeString(clobber))
+		}
+	}
+	from.RemoveInst(inst)
+	to.InsertInst(pos, inst)
+	return nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// insertionPoint returns the index of the first instruction of the given basic
+// block which is not a phi instruction or exception handling pad.
+func insertionPoint(block *ir.BasicBlock) int {
+	for i, inst := range block.Insts {
+		switch inst.(type) {
+		case *ir.InstPhi, *ir.InstLandingPad, *ir.InstCatchPad, *ir.InstCleanupPad:
+			// skip.
+		default:
+			return i
+		}
+	}
+	return len(block.Insts)
+}
+
+// checkMove checks that the given instruction of the function may be moved to
+// the given basic block, and returns the basic block of the instruction.
+func checkMove(f *ir.Function, inst ir.Instruction, to *ir.BasicBlock) (*ir.BasicBlock, error) {
+	var from *ir.BasicBlock
+	found := false
+	for _, block := range f.Blocks {
+		if block == to {
+			found = true
+		}
+		for _, x := range block.Insts {
+			if x == inst {
+				from = block
+			}
+		}
+	}
+	if from == nil {
+		return nil, errors.Errorf("instruction not present in function %v", f.Ident())
+	}
+	if !found {
+		return nil, errors.Errorf("target basic block not present in function %v", f.Ident())
+	}
+	if from == to {
+		return nil, errors.Errorf("instruction already present in target basic block")
+	}
+	if load, ok := inst.(*ir.InstLoad); ok {
+		if load.Volatile || load.Atomic {
+			return nil, errors.Errorf("volatile or atomic load")
+		}
+		return from, nil
+	}
+	if !isMovable(inst) {
+		return nil, errors.Errorf("instruction may have side effects or depend on control flow")
+	}
+	return from, nil
+}
+
+// mayTrap reports whether the given instruction may trap when executed
+// speculatively.
+func mayTrap(inst ir.Instruction) bool {
+	switch inst := inst.(type) {
+	case *ir.InstLoad:
+		return true
+	case *ir.InstUDiv:
+		return !isNonZeroConst(inst.Y)
+	case *ir.InstSDiv:
+		// sdiv traps on division by zero and on overflow (e.g. INT_MIN / -1).
+		return !isNonZeroConst(inst.Y) || isAllOnesConst(inst.Y)
+	case *ir.InstURem:
+		return !isNonZeroConst(inst.Y)
+	case *ir.InstSRem:
+		return !isNonZeroConst(inst.Y) || isAllOnesConst(inst.Y)
+	}
+	return false
+}
+
+// isNonZeroConst reports whether the given value is a non-zero integer
+// constant.
+func isNonZeroConst(v value.Value) bool {
+	c, ok := v.(*ir.ConstInt)
+	return ok && c.X.Sign() != 0
+}
+
+// isAllOnesConst reports whether the given value is an integer constant with
+// all bits set.
+func isAllOnesConst(v value.Value) bool {
+	c, ok := v.(*ir.ConstInt)
+	return ok && signed(c.X, c.Typ.BitSize).Cmp(big.NewInt(-1)) == 0
+}
+
+// alwaysReaches reports whether every path from the basic block src reaches
+// the basic block dst; i.e. whether no function exit is reachable from src
+// without passing through dst.
+func alwaysReaches(src, dst *ir.BasicBlock) bool {
+	visited := map[*ir.BasicBlock]bool{dst: true}
+	worklist := []*ir.BasicBlock{src}
+	for len(worklist) > 0 {
+		block := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		if visited[block] {
+			continue
+		}
+		visited[block] = true
+		succs := block.Term.Succs()
+		if len(succs) == 0 {
+			return false
+		}
+		worklist = append(worklist, succs...)
+	}
+	return true
+}
+
+// findClobber returns an instruction or terminator which may write to memory
+// along the paths from the end of the basic block src to the start of the basic
+// block dst, including the given instructions between. The boolean return
+// value indicates success.
+func findClobber(src, dst *ir.BasicBlock, between []interface{}) (interface{}, bool) {
+	for _, node := range between {
+		if mayWriteMemory(node) {
+			return node, true
+		}
+	}
+	// Basic blocks on paths from src to dst, including src and dst if part of
+	// a cycle along such a path.
+	fwd := reachable(src.Term.Succs(), func(block *ir.BasicBlock) []*ir.BasicBlock {
+		return block.Term.Succs()
+	})
+	preds := make(map[*ir.BasicBlock][]*ir.BasicBlock)
+	for block := range fwd {
+		for _, succ := range block.Term.Succs() {
+			preds[succ] = append(preds[succ], block)
+		}
+	}
+	for _, succ := range src.Term.Succs() {
+		preds[succ] = append(preds[succ], src)
+	}
+	bwd := reachable(preds[dst], func(block *ir.BasicBlock) []*ir.BasicBlock {
+		return preds[block]
+	})
+	for block := range fwd {
+		if !bwd[block] {
+			continue
+		}
+		for _, node := range blockNodes(block) {
+			if mayWriteMemory(node) {
+				return node, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// reachable returns the set of basic blocks reachable from the given basic
+// blocks, following the edges given by next.
+func reachable(start []*ir.BasicBlock, next func(block *ir.BasicBlock) []*ir.BasicBlock) map[*ir.BasicBlock]bool {
+	visited := make(map[*ir.BasicBlock]bool)
+	worklist := append([]*ir.BasicBlock(nil), start...)
+	for len(worklist) > 0 {
+		block := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		if visited[block] {
+			continue
+		}
+		visited[block] = true
+		worklist = append(worklist, next(block)...)
+	}
+	return visited
+}
+
+// mayWriteMemory reports whether the given instruction or terminator may write
+// to memory.
+func mayWriteMemory(node interface{}) bool {
+	switch node := node.(type) {
+	case *ir.InstStore, *ir.InstFence, *ir.InstCmpXchg, *ir.InstAtomicRMW, *ir.InstVAArg:
+		return true
+	case *ir.InstLoad:
+		return node.Volatile || node.Atomic
+	case *ir.InstCall:
+		if f, ok := node.Callee.(*ir.Function); ok && onlyReadsMemory(f.FuncAttrs) {
+			return false
+		}
+		return !onlyReadsMemory(node.FuncAttrs)
+	case *ir.TermInvoke:
+		if f, ok := node.Invokee.(*ir.Function); ok && onlyReadsMemory(f.FuncAttrs) {
+			return false
+		}
+		return !onlyReadsMemory(node.FuncAttrs)
+	case *ir.RawInst, ir.CustomInst:
+		// Raw and custom instructions are treated conservatively.
+		return true
+	}
+	return false
+}
+
+// onlyReadsMemory reports whether the given function attributes include
+// readnone or readonly.
+func onlyReadsMemory(attrs []ir.FuncAttribute) bool {
+	for _, attr := range attrs {
+		switch attr {
+		case enum.FuncAttrReadNone, enum.FuncAttrReadOnly:
+			return true
+		}
+	}
+	return false
+}
+
+// instBlocks returns the basic block of each instruction of the given function.
+func instBlocks(f *ir.Function) map[value.Value]*ir.BasicBlock {
+	blockOf := make(map[value.Value]*ir.BasicBlock)
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if v, ok := inst.(value.Value); ok {
+				blockOf[v] = block
+			}
+		}
+		if v, ok := block.Term.(value.Value); ok {
+			blockOf[v] = block
+		}
+	}
+	return blockOf
+}
+
+// instIdent returns the identifier of the given instruction, or its LLVM syntax
+// representation if it does not produce a value.
+func instIdent(inst ir.Instruction) string {
+	if v, ok := inst.(value.Value); ok {
+		return v.Ident()
+	}
+	return nodeString(inst)
+}
+
+// nodeString returns the LLVM syntax representation of the given instruction
+// or terminator.
+func nodeString(node interface{}) string {
+	if n, ok := node.(interface{ Def() string }); ok {
+		return n.Def()
+	}
+	return "terminator"
+}
@@ -0,0 +1,125 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+// newLoopFunc returns a new function with a counting loop, of which the loop
+// body computes the loop invariant value %inv and loads from %p. If store is
+// set, the loop body stores to %p after the load.
+//
+//    define i32 @f(i32 %n, i32* %p) {
+//    entry:
+//       br label %loop
+//    loop:
+//       %i = phi i32 [ 0, %entry ], [ %next, %loop ]
+//       %inv = mul i32 %n, 2
+//       %div = sdiv i32 %n, %i
+//       %x = load i32, i32* %p
+//       store i32 %i, i32* %p ; if store is set
+//       %next = add i32 %i, 1
+//       %cmp = icmp slt i32 %next, %n
+//       br i1 %cmp, label %loop, label %exit
+//    exit:
+//       %sum = add i32 %inv, %x
+//       ret i32 %sum
+//    }
+func newLoopFunc(store bool) (f *ir.Function, inv, div, x, sum ir.Instruction, entry, loop, exit *ir.BasicBlock) {
+	n := ir.NewParam(types.I32, "n")
+	p := ir.NewParam(types.NewPointer(types.I32), "p")
+	f = ir.NewFunc("f", types.I32, n, p)
+	entry = ir.NewBlock("entry")
+	loop = ir.NewBlock("loop")
+	exit = ir.NewBlock("exit")
+	entry.NewBr(loop)
+	i := loop.NewPhi(ir.NewIncoming(ir.NewInt(types.I32, 0), entry))
+	i.SetName("i")
+	mul := loop.NewMul(n, ir.NewInt(types.I32, 2))
+	mul.SetName("inv")
+	sdiv := loop.NewSDiv(n, i)
+	sdiv.SetName("div")
+	load := loop.NewLoad(p)
+	load.SetName("x")
+	if store {
+		loop.NewStore(i, p)
+	}
+	next := loop.NewAdd(i, ir.NewInt(types.I32, 1))
+	next.SetName("next")
+	i.Incs = append(i.Incs, ir.NewIncoming(next, loop))
+	cmp := loop.NewICmp(enum.IPredSLT, next, n)
+	cmp.SetName("cmp")
+	loop.NewCondBr(cmp, loop, exit)
+	add := exit.NewAdd(mul, load)
+	add.SetName("sum")
+	exit.NewRet(add)
+	f.Blocks = []*ir.BasicBlock{entry, loop, exit}
+	return f, mul, sdiv, load, add, entry, loop, exit
+}
+
+func TestHoist(t *testing.T) {
+	// Hoist loop invariant value.
+	f, inv, div, x, _, entry, loop, _ := newLoopFunc(false)
+	if err := Hoist(f, inv, entry); err != nil {
+		t.Errorf("unable to hoist %v; %v", instIdent(inv), err)
+	}
+	if len(entry.Insts) != 1 || entry.Insts[0] != inv {
+		t.Errorf("%v not hoisted to %v", instIdent(inv), entry.Ident())
+	}
+	// Operand %i not available in entry basic block.
+	if err := Hoist(f, div, entry); err == nil {
+		t.Errorf("expected error when hoisting %v", instIdent(div))
+	}
+	// Load without intervening stores.
+	if err := Hoist(f, x, entry); err != nil {
+		t.Errorf("unable to hoist %v; %v", instIdent(x), err)
+	}
+	if len(loop.Insts) != 4 {
+		t.Errorf("instruction count mismatch of %v; expected 4, got %d", loop.Ident(), len(loop.Insts))
+	}
+	// Load with store in loop.
+	f, _, _, x, _, entry, _, _ = newLoopFunc(true)
+	if err := Hoist(f, x, entry); err == nil {
+		t.Errorf("expected error when hoisting %v across store", instIdent(x))
+	}
+	// Target basic block not dominating.
+	f, inv, _, _, _, _, _, exit := newLoopFunc(false)
+	if err := Hoist(f, inv, exit); err == nil {
+		t.Errorf("expected error when hoisting %v to %v", instIdent(inv), exit.Ident())
+	}
+}
+
+func TestSink(t *testing.T) {
+	// Sink value only used after the loop.
+	f, inv, _, x, sum, _, loop, exit := newLoopFunc(false)
+	if err := Sink(f, inv, exit); err != nil {
+		t.Errorf("unable to sink %v; %v", instIdent(inv), err)
+	}
+	if len(exit.Insts) != 2 || exit.Insts[0] != inv {
+		t.Errorf("%v not sunk to %v", instIdent(inv), exit.Ident())
+	}
+	// Load without intervening stores.
+	if err := Sink(f, x, exit); err != nil {
+		t.Errorf("unable to sink %v; %v", instIdent(x), err)
+	}
+	if len(loop.Insts) != 4 {
+		t.Errorf("instruction count mismatch of %v; expected 4, got %d", loop.Ident(), len(loop.Insts))
+	}
+	// Side effects.
+	if err := Sink(f, loop.Insts[0], exit); err == nil {
+		t.Errorf("expected error when sinking phi instruction")
+	}
+	// Load with store after it in the loop.
+	f, _, _, x, _, _, _, exit = newLoopFunc(true)
+	if err := Sink(f, x, exit); err == nil {
+		t.Errorf("expected error when sinking %v across store", instIdent(x))
+	}
+	// Use not dominated by target basic block.
+	f, _, _, _, sum, _, loop, _ = newLoopFunc(false)
+	if err := Sink(f, sum, loop); err == nil {
+		t.Errorf("expected error when sinking %v to %v", instIdent(sum), loop.Ident())
+	}
+}
@@ -0,0 +1,37 @@
+package transform
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/pkg/errors"
+)
+
+// === [ Name stripping ] ======================================================
+
+// DiscardValueNames strips the names of the parameters, basic blocks and local
+// variables of the functions of the given module, which are assigned local IDs
+// instead (e.g. %0); as with the -discard-value-names option of LLVM. Global
+// names are left unchanged.
+func DiscardValueNames(m *ir.Module) error {
+	for _, f := range m.Funcs {
+		if err := DiscardFuncValueNames(f); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// DiscardFuncValueNames strips the names of the parameters, basic blocks and
+// local variables of the given function, which are assigned local IDs instead.
+func DiscardFuncValueNames(f *ir.Function) error {
+	clearLocalNames(f, func(name string) bool {
+		return true
+	})
+	if len(f.Blocks) == 0 {
+		// Parameters of function declarations are unnamed.
+		return nil
+	}
+	if err := f.AssignIDs(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestDiscardValueNames(t *testing.T) {
+	// define i32 @f(i32 %x) {
+	// entry:
+	//    %y = add i32 %x, 1
+	//    br label %exit
+	// exit:
+	//    ret i32 %y
+	// }
+	m := &ir.Module{}
+	x := ir.NewParam(types.I32, "x")
+	m.NewFunc("g", types.Void, ir.NewParam(types.I32, "n"))
+	f := m.NewFunc("f", types.I32, x)
+	entry := f.NewBlock("entry")
+	exit := f.NewBlock("exit")
+	y := entry.NewAdd(x, ir.NewInt(types.I32, 1))
+	y.SetName("y")
+	entry.NewBr(exit)
+	exit.NewRet(y)
+	if err := DiscardValueNames(m); err != nil {
+		t.Fatalf("unable to discard value names; %+v", err)
+	}
+	want := `declare void @g(i32)
+define i32 @f(i32) {
+	%2 = add i32 %0, 1
+	br label %3
+	ret i32 %2
+}
+`
+	if got := m.Def(); want != got {
+		t.Errorf("module mismatch; expected `%v`, got `%v`", want, got)
+	}
+}
@@ -0,0 +1,439 @@
+package transform
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/llir/l/analysis"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Reassociation ] =======================================================
+
+// Reassociate reorders the operands of the associative and commutative
+// expression trees of the given function, and reports whether the function was
+// changed.
+//
+// Expression trees are formed by add, mul, and, or and xor instructions, and by
+// fadd and fmul instructions with the reassoc (or fast) fast-math flag, of
+// which each inner node has a single use by its parent within the same basic
+// block. The operands (leaves) of each expression tree are ranked, so that
+// constants rank lowest, followed by function parameters (in order), followed
+// by values defined in later basic blocks (in reverse postorder), and the tree
+// is rewritten as a chain
+//
+//    (((x0 op x1) op x2) op ... op xn)
+//
+// of operands in decreasing rank, where x0 has the lowest rank. Integer
+// constants of the same expression tree are folded into a single constant, and
+// the no wrap flags of rewritten instructions are dropped.
+//
+// Ranking exposes opportunities for common subexpression elimination, as
+// equivalent expressions are rewritten to the same chain, and for loop
+// invariant code motion, as values defined outside of loops precede values
+// defined within.
+func Reassociate(f *ir.Function) bool {
+	if len(f.Blocks) == 0 {
+		return false
+	}
+	ranks := valueRanks(f)
+	uses := useCounts(f)
+	changed := false
+	for _, block := range f.Blocks {
+		// Visit instructions in reverse order, so that expression trees are
+		// rewritten from their roots.
+		visited := make(map[ir.Instruction]bool)
+		insts := append([]ir.Instruction(nil), block.Insts...)
+		for i := len(insts) - 1; i >= 0; i-- {
+			root := insts[i]
+			if visited[root] || !isAssociative(root) {
+				continue
+			}
+			tree := collectTree(block, root, uses)
+			for _, node := range tree.nodes {
+				visited[node] = true
+			}
+			if rewriteTree(f, block, tree, ranks) {
+				changed = true
+				// Folded expression trees may add uses to their operands.
+				uses = useCounts(f)
+			}
+		}
+	}
+	return changed
+}
+
+// exprTree is an associative and commutative expression tree.
+type exprTree struct {
+	// Root of the expression tree.
+	root ir.Instruction
+	// Nodes of the expression tree, including the root, in order of occurrence
+	// within the basic block.
+	nodes []ir.Instruction
+	// Leaves (operands) of the expression tree, in left-to-right order.
+	leaves []value.Value
+}
+
+// ### [ Helper functions ] ####################################################
+
+// collectTree returns the expression tree of the given root instruction of the
+// basic block.
+func collectTree(block *ir.BasicBlock, root ir.Instruction, uses map[value.Value]int) *exprTree {
+	inTree := map[ir.Instruction]bool{root: true}
+	tree := &exprTree{root: root}
+	inBlock := make(map[ir.Instruction]bool)
+	for _, inst := range block.Insts {
+		inBlock[inst] = true
+	}
+	var collect func(v value.Value)
+	collect = func(v value.Value) {
+		if inst, ok := v.(ir.Instruction); ok && inBlock[inst] && uses[v] == 1 && sameOp(inst, root) {
+			inTree[inst] = true
+			x, y := binaryOperands(inst)
+			collect(x)
+			collect(y)
+			return
+		}
+		tree.leaves = append(tree.leaves, v)
+	}
+	x, y := binaryOperands(root)
+	collect(x)
+	collect(y)
+	for _, inst := range block.Insts {
+		if inTree[inst] {
+			tree.nodes = append(tree.nodes, inst)
+		}
+	}
+	return tree
+}
+
+// rewriteTree rewrites the given expression tree of the basic block as a chain
+// of operands in decreasing rank, and reports whether the expression tree was
+// changed.
+func rewriteTree(f *ir.Function, block *ir.BasicBlock, tree *exprTree, ranks map[value.Value]int) bool {
+	leaves := append([]value.Value(nil), tree.leaves...)
+	sort.SliceStable(leaves, func(i, j int) bool {
+		return ranks[leaves[i]] > ranks[leaves[j]]
+	})
+	leaves, folded := foldLeaves(tree.root, leaves)
+	if len(leaves) == 1 {
+		// Expression tree folded to a single value.
+		ir.ReplaceUses(f, tree.root.(value.Value), leaves[0])
+		removeInsts(block, tree.nodes)
+		return true
+	}
+	// Nodes of the rewritten chain, from the innermost node to the root.
+	chain := make([]ir.Instruction, 0, len(leaves)-1)
+	for _, node := range tree.nodes {
+		if node != tree.root && len(chain) < len(leaves)-2 {
+			chain = append(chain, node)
+		}
+	}
+	chain = append(chain, tree.root)
+	// Operands of each node of the rewritten chain.
+	xs := make([]value.Value, len(chain))
+	ys := make([]value.Value, len(chain))
+	n := len(leaves)
+	xs[0], ys[0] = leaves[n-2], leaves[n-1]
+	for i := 1; i < len(chain); i++ {
+		xs[i], ys[i] = chain[i-1].(value.Value), leaves[n-2-i]
+	}
+	if !folded && len(chain) == len(tree.nodes) {
+		same := true
+		for i, node := range chain {
+			x, y := binaryOperands(node)
+			if x != xs[i] || y != ys[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return false
+		}
+	}
+	for i, node := range chain {
+		setBinaryOperands(node, xs[i], ys[i])
+	}
+	// Place the nodes of the chain in order at the root, and remove unused
+	// nodes.
+	for _, node := range tree.nodes {
+		if node != tree.root {
+			block.RemoveInst(node)
+		}
+	}
+	block.InsertBefore(tree.root, chain[:len(chain)-1]...)
+	return true
+}
+
+// foldLeaves folds the integer constants among the given leaves of an
+// expression tree with the given root, which are ordered by decreasing rank.
+// The boolean return value indicates whether any constants were folded.
+func foldLeaves(root ir.Instruction, leaves []value.Value) ([]value.Value, bool) {
+	var c *ir.ConstInt
+	var others []value.Value
+	nconsts := 0
+	for _, leaf := range leaves {
+		x, ok := leaf.(*ir.ConstInt)
+		if !ok {
+			others = append(others, leaf)
+			continue
+		}
+		nconsts++
+		if c == nil {
+			c = x
+			continue
+		}
+		n := c.Typ.BitSize
+		var z *big.Int
+		switch root.(type) {
+		case *ir.InstAdd:
+			z = new(big.Int).Add(c.X, x.X)
+		case *ir.InstMul:
+			z = new(big.Int).Mul(c.X, x.X)
+		case *ir.InstAnd:
+			z = new(big.Int).And(unsigned(c.X, n), unsigned(x.X, n))
+		case *ir.InstOr:
+			z = new(big.Int).Or(unsigned(c.X, n), unsigned(x.X, n))
+		case *ir.InstXor:
+			z = new(big.Int).Xor(unsigned(c.X, n), unsigned(x.X, n))
+		}
+		c = newInt(c.Typ, z)
+	}
+	if c == nil {
+		return leaves, false
+	}
+	n := c.Typ.BitSize
+	zero := c.X.Sign() == 0
+	ones := unsigned(c.X, n).Cmp(unsigned(big.NewInt(-1), n)) == 0
+	switch root.(type) {
+	case *ir.InstAdd, *ir.InstOr, *ir.InstXor:
+		if zero && len(others) > 0 {
+			// Identity.
+			return others, true
+		}
+		if _, ok := root.(*ir.InstOr); ok && ones {
+			// Absorbing.
+			return []value.Value{c}, true
+		}
+	case *ir.InstMul:
+		if c.X.Cmp(big.NewInt(1)) == 0 && len(others) > 0 {
+			// Identity.
+			return others, true
+		}
+		if zero {
+			// Absorbing.
+			return []value.Value{c}, true
+		}
+	case *ir.InstAnd:
+		if ones && len(others) > 0 {
+			// Identity.
+			return others, true
+		}
+		if zero {
+			// Absorbing.
+			return []value.Value{c}, true
+		}
+	}
+	return append(others, c), nconsts > 1
+}
+
+// isAssociative reports whether the given instruction is an associative and
+// commutative binary instruction.
+func isAssociative(inst ir.Instruction) bool {
+	switch inst := inst.(type) {
+	case *ir.InstAdd, *ir.InstMul, *ir.InstAnd, *ir.InstOr, *ir.InstXor:
+		return true
+	case *ir.InstFAdd:
+		return hasReassoc(inst.FastMathFlags)
+	case *ir.InstFMul:
+		return hasReassoc(inst.FastMathFlags)
+	}
+	return false
+}
+
+// hasReassoc reports whether the given fast-math flags permit reassociation.
+func hasReassoc(flags []enum.FastMathFlag) bool {
+	for _, flag := range flags {
+		switch flag {
+		case enum.FastMathFlagReassoc, enum.FastMathFlagFast:
+			return true
+		}
+	}
+	return false
+}
+
+// sameOp reports whether the given instruction is an associative instruction
+// of the same opcode and type as the root of an expression tree.
+func sameOp(inst, root ir.Instruction) bool {
+	if !isAssociative(inst) {
+		return false
+	}
+	switch inst.(type) {
+	case *ir.InstAdd:
+		_, ok := root.(*ir.InstAdd)
+		return ok
+	case *ir.InstMul:
+		_, ok := root.(*ir.InstMul)
+		return ok
+	case *ir.InstAnd:
+		_, ok := root.(*ir.InstAnd)
+		return ok
+	case *ir.InstOr:
+		_, ok := root.(*ir.InstOr)
+		return ok
+	case *ir.InstXor:
+		_, ok := root.(*ir.InstXor)
+		return ok
+	case *ir.InstFAdd:
+		_, ok := root.(*ir.InstFAdd)
+		return ok
+	case *ir.InstFMul:
+		_, ok := root.(*ir.InstFMul)
+		return ok
+	}
+	return false
+}
+
+// binaryOperands returns the operands of the given associative instruction.
+func binaryOperands(inst ir.Instruction) (x, y value.Value) {
+	switch inst := inst.(type) {
+	case *ir.InstAdd:
+		return inst.X, inst.Y
+	case *ir.InstMul:
+		return inst.X, inst.Y
+	case *ir.InstAnd:
+		return inst.X, inst.Y
+	case *ir.InstOr:
+		return inst.X, inst.Y
+	case *ir.InstXor:
+		return inst.X, inst.Y
+	case *ir.InstFAdd:
+		return inst.X, inst.Y
+	case *ir.InstFMul:
+		return inst.X, inst.Y
+	}
+	panic(errors.Errorf("invalid associative instruction %T", inst))
+}
+
+// setBinaryOperands sets the operands of the given associative instruction,
+// and drops its no wrap flags.
+func setBinaryOperands(inst ir.Instruction, x, y value.Value) {
+	switch inst := inst.(type) {
+	case *ir.InstAdd:
+		inst.X, inst.Y = x, y
+		inst.OverflowFlags = nil
+	case *ir.InstMul:
+		inst.X, inst.Y = x, y
+		inst.OverflowFlags = nil
+	case *ir.InstAnd:
+		inst.X, inst.Y = x, y
+	case *ir.InstOr:
+		inst.X, inst.Y = x, y
+	case *ir.InstXor:
+		inst.X, inst.Y = x, y
+	case *ir.InstFAdd:
+		inst.X, inst.Y = x, y
+	case *ir.InstFMul:
+		inst.X, inst.Y = x, y
+	default:
+		panic(errors.Errorf("invalid associative instruction %T", inst))
+	}
+}
+
+// removeInsts removes the given instructions from the basic block.
+func removeInsts(block *ir.BasicBlock, remove []ir.Instruction) {
+	for _, inst := range remove {
+		block.RemoveInst(inst)
+	}
+}
+
+// valueRanks returns the rank of each parameter and instruction of the given
+// function. Constants have rank 0, parameters have ranks 1 through n, and
+// instructions of each basic block rank above the values of preceding basic
+// blocks in reverse postorder. Instructions which may be moved (e.g. binary
+// instructions) rank above their operands, while other instructions (e.g. phi,
+// load and call instructions) have the base rank of their basic block.
+func valueRanks(f *ir.Function) map[value.Value]int {
+	ranks := make(map[value.Value]int)
+	for i, param := range f.Params {
+		ranks[param] = i + 1
+	}
+	for i, block := range analysis.ReversePostorder(f) {
+		base := (i + 1) << 16
+		for _, inst := range block.Insts {
+			v, ok := inst.(value.Value)
+			if !ok {
+				continue
+			}
+			rank := base
+			if isMovable(inst) {
+				for _, x := range operandsOf(inst) {
+					if ranks[x] >= rank {
+						rank = ranks[x] + 1
+					}
+				}
+			}
+			ranks[v] = rank
+		}
+	}
+	return ranks
+}
+
+// isMovable reports whether the given instruction is free of side effects and
+// depends only on its operands. Integer division may trap, and is only moved
+// if safe (see mayTrap).
+func isMovable(inst ir.Instruction) bool {
+	switch inst.(type) {
+	case *ir.InstFNeg, *ir.InstAdd, *ir.InstFAdd, *ir.InstSub, *ir.InstFSub, *ir.InstMul, *ir.InstFMul,
+		*ir.InstUDiv, *ir.InstSDiv, *ir.InstFDiv, *ir.InstURem, *ir.InstSRem, *ir.InstFRem,
+		*ir.InstShl, *ir.InstLShr, *ir.InstAShr, *ir.InstAnd, *ir.InstOr, *ir.InstXor,
+		*ir.InstTrunc, *ir.InstZExt, *ir.InstSExt, *ir.InstFPTrunc, *ir.InstFPExt,
+		*ir.InstFPToUI, *ir.InstFPToSI, *ir.InstUIToFP, *ir.InstSIToFP,
+		*ir.InstPtrToInt, *ir.InstIntToPtr, *ir.InstBitCast, *ir.InstAddrSpaceCast,
+		*ir.InstICmp, *ir.InstFCmp, *ir.InstSelect, *ir.InstGetElementPtr,
+		*ir.InstExtractElement, *ir.InstInsertElement, *ir.InstShuffleVector,
+		*ir.InstExtractValue, *ir.InstInsertValue:
+		return true
+	}
+	return false
+}
+
+// operandsOf returns the operands of the given instruction.
+func operandsOf(inst ir.Instruction) []value.Value {
+	var operands []value.Value
+	ir.Walk(inst, func(n interface{}) bool {
+		if n == inst {
+			return true
+		}
+		if v, ok := n.(value.Value); ok {
+			operands = append(operands, v)
+		}
+		return true
+	})
+	return operands
+}
+
+// useCounts returns the number of uses of each value used as an operand in the
+// given function.
+func useCounts(f *ir.Function) map[value.Value]int {
+	uses := make(map[value.Value]int)
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			for _, x := range operandsOf(inst) {
+				uses[x]++
+			}
+		}
+		if block.Term != nil {
+			ir.Walk(block.Term, func(n interface{}) bool {
+				if v, ok := n.(value.Value); ok && n != block.Term {
+					uses[v]++
+				}
+				return true
+			})
+		}
+	}
+	return uses
+}
@@ -0,0 +1,121 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+)
+
+func TestReassociate(t *testing.T) {
+	golden := []struct {
+		f    func() *ir.Function
+		want string
+	}{
+		// i=0
+		{
+			f: func() *ir.Function {
+				a := ir.NewParam(types.I32, "a")
+				b := ir.NewParam(types.I32, "b")
+				f := ir.NewFunc("f", types.I32, a, b)
+				entry := ir.NewBlock("entry")
+				t1 := entry.NewAdd(ir.NewInt(types.I32, 4), a)
+				t1.SetName("t1")
+				t2 := entry.NewAdd(t1, b)
+				t2.SetName("t2")
+				t3 := entry.NewAdd(t2, ir.NewInt(types.I32, 5))
+				t3.SetName("t3")
+				t3.OverflowFlags = []enum.OverflowFlag{enum.OverflowFlagNSW}
+				entry.NewRet(t3)
+				f.Blocks = []*ir.BasicBlock{entry}
+				return f
+			},
+			want: `define i32 @f(i32 %a, i32 %b) {
+entry:
+	%t1 = add i32 %a, 9
+	%t3 = add i32 %t1, %b
+	ret i32 %t3
+}`,
+		},
+		// i=1
+		{
+			f: func() *ir.Function {
+				a := ir.NewParam(types.I32, "a")
+				b := ir.NewParam(types.I32, "b")
+				f := ir.NewFunc("f", types.I32, a, b)
+				entry := ir.NewBlock("entry")
+				u := entry.NewMul(a, b)
+				u.SetName("u")
+				v := entry.NewMul(u, ir.NewInt(types.I32, 0))
+				v.SetName("v")
+				entry.NewRet(v)
+				f.Blocks = []*ir.BasicBlock{entry}
+				return f
+			},
+			want: `define i32 @f(i32 %a, i32 %b) {
+entry:
+	ret i32 0
+}`,
+		},
+		// i=2
+		{
+			f: func() *ir.Function {
+				a := ir.NewParam(types.Double, "a")
+				b := ir.NewParam(types.Double, "b")
+				c := ir.NewParam(types.Double, "c")
+				f := ir.NewFunc("f", types.Double, a, b, c)
+				entry := ir.NewBlock("entry")
+				x := entry.NewFAdd(c, b)
+				x.SetName("x")
+				x.FastMathFlags = []enum.FastMathFlag{enum.FastMathFlagReassoc}
+				y := entry.NewFAdd(x, a)
+				y.SetName("y")
+				y.FastMathFlags = []enum.FastMathFlag{enum.FastMathFlagReassoc}
+				entry.NewRet(y)
+				f.Blocks = []*ir.BasicBlock{entry}
+				return f
+			},
+			want: `define double @f(double %a, double %b, double %c) {
+entry:
+	%x = fadd reassoc double %b, %a
+	%y = fadd reassoc double %x, %c
+	ret double %y
+}`,
+		},
+		// i=3; no reassoc fast-math flag.
+		{
+			f: func() *ir.Function {
+				a := ir.NewParam(types.Double, "a")
+				b := ir.NewParam(types.Double, "b")
+				c := ir.NewParam(types.Double, "c")
+				f := ir.NewFunc("f", types.Double, a, b, c)
+				entry := ir.NewBlock("entry")
+				x := entry.NewFAdd(c, b)
+				x.SetName("x")
+				y := entry.NewFAdd(x, a)
+				y.SetName("y")
+				entry.NewRet(y)
+				f.Blocks = []*ir.BasicBlock{entry}
+				return f
+			},
+			want: `define double @f(double %a, double %b, double %c) {
+entry:
+	%x = fadd double %c, %b
+	%y = fadd double %x, %a
+	ret double %y
+}`,
+		},
+	}
+	for i, g := range golden {
+		f := g.f()
+		Reassociate(f)
+		if got := f.Def(); g.want != got {
+			t.Errorf("i=%d: function mismatch; expected `%v`, got `%v`", i, g.want, got)
+		}
+		// Reassociated functions are left as is.
+		if Reassociate(f) {
+			t.Errorf("i=%d: expected reassociated function to be left as is", i)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/llir/l/internal/enc"
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Function specialization ] =============================================
+
+// Specialize adds to the module a specialization of the given function
+// definition, with the parameters at the given indices bound to the given
+// constants, and returns the specialized function.
+//
+// The specialized function is a clone of f with internal linkage and without
+// the bound parameters, the body of which is simplified by FoldConstants. Call
+// instructions of the module which call f with arguments equal to the bound
+// constants are redirected to the specialized function.
+func Specialize(m *ir.Module, f *ir.Function, consts map[int]ir.Constant) (*ir.Function, error) {
+	if len(f.Blocks) == 0 {
+		return nil, errors.Errorf("unable to specialize function declaration %v", f.Ident())
+	}
+	for index, c := range consts {
+		if index < 0 || index >= len(f.Params) {
+			return nil, errors.Errorf("invalid parameter index %d of function %v; expected index in range [0, %d)", index, f.Ident(), len(f.Params))
+		}
+		if want := f.Params[index].Type(); !c.Type().Equal(want) {
+			return nil, errors.Errorf("invalid constant bound to parameter %d of function %v; expected type %v, got %v", index, f.Ident(), want, c.Type())
+		}
+	}
+	spec := ir.CloneFunc(f)
+	var params []*ir.Param
+	var paramTypes []types.Type
+	for index, param := range spec.Params {
+		if c, ok := consts[index]; ok {
+			ir.ReplaceUses(spec, param, c)
+			continue
+		}
+		params = append(params, param)
+		paramTypes = append(paramTypes, param.Type())
+	}
+	spec.Params = params
+	spec.Sig = types.NewFunc(f.Sig.RetType, paramTypes...)
+	spec.Sig.Variadic = f.Sig.Variadic
+	spec.GlobalName = specName(m, f.GlobalName)
+	spec.Linkage = enum.LinkageInternal
+	spec.Comdat = nil
+	FoldConstants(spec)
+	// Local IDs are reassigned, as bound parameters and folded instructions are
+	// removed.
+	clearLocalIDs(spec)
+	if err := spec.AssignIDs(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	m.AddFunc(spec)
+	redirectCalls(m, f, spec, consts)
+	return spec, nil
+}
+
+// ### [ Helper functions ] ####################################################
+
+// specName returns a global name for a specialization of the function with the
+// given name, which is unique within the module.
+func specName(m *ir.Module, name string) string {
+	for i := 1; ; i++ {
+		specName := fmt.Sprintf("%s.spec.%d", name, i)
+		if _, ok := m.Lookup(specName); !ok {
+			return specName
+		}
+	}
+}
+
+// clearLocalIDs clears the names of the parameters, basic blocks and local
+// variables of the given function which are local IDs.
+func clearLocalIDs(f *ir.Function) {
+	clearLocalNames(f, enc.IsID)
+}
+
+// clearLocalNames clears the names of the parameters, basic blocks and local
+// variables of the given function for which match reports true.
+func clearLocalNames(f *ir.Function, match func(name string) bool) {
+	clearName := func(n value.Named) {
+		if match(n.Name()) {
+			n.SetName("")
+		}
+	}
+	for _, param := range f.Params {
+		clearName(param)
+	}
+	for _, block := range f.Blocks {
+		clearName(block)
+		for _, inst := range block.Insts {
+			if n, ok := inst.(value.Named); ok {
+				clearName(n)
+			}
+		}
+		if n, ok := block.Term.(value.Named); ok {
+			clearName(n)
+		}
+	}
+}
+
+// redirectCalls redirects the call instructions of the module which call f with
+// arguments equal to the given constants bound to parameters of f, to the
+// specialized function spec.
+func redirectCalls(m *ir.Module, f, spec *ir.Function, consts map[int]ir.Constant) {
+	for _, g := range m.Funcs {
+		for _, block := range g.Blocks {
+			for _, inst := range block.Insts {
+				call, ok := inst.(*ir.InstCall)
+				if !ok || call.Callee != f || len(call.Args) < len(f.Params) {
+					continue
+				}
+				if !matchArgs(call.Args, consts) {
+					continue
+				}
+				var args []value.Value
+				for index, arg := range call.Args {
+					if _, ok := consts[index]; !ok {
+						args = append(args, arg)
+					}
+				}
+				call.Callee = spec
+				call.Args = args
+			}
+		}
+	}
+}
+
+// matchArgs reports whether the given call arguments are equal to the given
+// constants at their parameter indices.
+func matchArgs(args []value.Value, consts map[int]ir.Constant) bool {
+	for index, c := range consts {
+		if !sameConst(args[index], c) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameConst reports whether the given value is known to be equal to the given
+// constant.
+func sameConst(v value.Value, c ir.Constant) bool {
+	if sameValue(v, c) {
+		return true
+	}
+	if x, ok := v.(*ir.ConstNull); ok {
+		y, ok := c.(*ir.ConstNull)
+		return ok && x.Type().Equal(y.Type())
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+)
+
+func TestSpecialize(t *testing.T) {
+	// define i32 @f(i32 %x, i1 %double) {
+	// entry:
+	//    br i1 %double, label %a, label %b
+	// a:
+	//    %y = mul i32 %x, 2
+	//    br label %exit
+	// b:
+	//    %z = add i32 %x, 1
+	//    br label %exit
+	// exit:
+	//    %r = phi i32 [ %y, %a ], [ %z, %b ]
+	//    ret i32 %r
+	// }
+	m := &ir.Module{}
+	x := ir.NewParam(types.I32, "x")
+	double := ir.NewParam(types.I1, "double")
+	f := m.NewFunc("f", types.I32, x, double)
+	entry := ir.NewBlock("entry")
+	a := ir.NewBlock("a")
+	b := ir.NewBlock("b")
+	exit := ir.NewBlock("exit")
+	entry.NewCondBr(double, a, b)
+	y := a.NewMul(x, ir.NewInt(types.I32, 2))
+	y.SetName("y")
+	a.NewBr(exit)
+	z := b.NewAdd(x, ir.NewInt(types.I32, 1))
+	z.SetName("z")
+	b.NewBr(exit)
+	r := exit.NewPhi(ir.NewIncoming(y, a), ir.NewIncoming(z, b))
+	r.SetName("r")
+	exit.NewRet(r)
+	f.Blocks = []*ir.BasicBlock{entry, a, b, exit}
+	// define i32 @g(i32 %n) {
+	//    %c1 = call i32 @f(i32 %n, i1 true)
+	//    %c2 = call i32 @f(i32 %n, i1 false)
+	//    ret i32 %c2
+	// }
+	n := ir.NewParam(types.I32, "n")
+	g := m.NewFunc("g", types.I32, n)
+	body := ir.NewBlock("")
+	c1 := body.NewCall(f, n, ir.True)
+	c1.SetName("c1")
+	c2 := body.NewCall(f, n, ir.False)
+	c2.SetName("c2")
+	body.NewRet(c2)
+	g.Blocks = []*ir.BasicBlock{body}
+
+	spec, err := Specialize(m, f, map[int]ir.Constant{1: ir.True})
+	if err != nil {
+		t.Fatalf("unable to specialize function; %v", err)
+	}
+	want := `define internal i32 @f.spec.1(i32 %x) {
+entry:
+	br label %a
+a:
+	%y = mul i32 %x, 2
+	br label %exit
+exit:
+	ret i32 %y
+}`
+	if got := spec.Def(); want != got {
+		t.Errorf("specialized function mismatch; expected `%v`, got `%v`", want, got)
+	}
+	if c1.Callee != spec || len(c1.Args) != 1 || c1.Args[0] != n {
+		t.Errorf("call %v not redirected to specialized function", c1.Ident())
+	}
+	if c2.Callee != f || len(c2.Args) != 2 {
+		t.Errorf("call %v with mismatching arguments redirected", c2.Ident())
+	}
+	if v, ok := m.Lookup("f.spec.1"); !ok || v != spec {
+		t.Errorf("specialized function not registered in module")
+	}
+	// Invalid parameter index.
+	if _, err := Specialize(m, f, map[int]ir.Constant{2: ir.True}); err == nil {
+		t.Errorf("expected error for invalid parameter index")
+	}
+	// Mismatching constant type.
+	if _, err := Specialize(m, f, map[int]ir.Constant{0: ir.True}); err == nil {
+		t.Errorf("expected error for mismatching constant type")
+	}
+}
@@ -0,0 +1,96 @@
+// Package verify implements verification of LLVM IR modules.
+package verify
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// Module verifies the given module, returning an error describing the first
+// invalid instruction or constant expression encountered.
+func Module(m *ir.Module) error {
+	for _, g := range m.Globals {
+		if err := walk(g); err != nil {
+			return errors.Wrapf(err, "invalid global variable %s", g.Ident())
+		}
+	}
+	for _, f := range m.Funcs {
+		if err := Func(f); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// Func verifies the given function, returning an error describing the first
+// invalid instruction or constant expression encountered.
+func Func(f *ir.Function) error {
+	if err := walk(f); err != nil {
+		return errors.Wrapf(err, "invalid function %s", f.Ident())
+	}
+	return nil
+}
+
+// Warnings returns the warnings of the given module; call sites which are valid
+// LLVM IR but have undefined behaviour if executed, such as direct calls of
+// functions with a mismatching calling convention.
+func Warnings(m *ir.Module) []error {
+	var warnings []error
+	for _, f := range m.Funcs {
+		ir.Walk(f, func(n interface{}) bool {
+			var (
+				callee value.Value
+				cc     enum.CallingConv
+			)
+			switch n := n.(type) {
+			case *ir.InstCall:
+				callee, cc = n.Callee, n.CallingConv
+			case *ir.TermInvoke:
+				callee, cc = n.Invokee, n.CallingConv
+			default:
+				return true
+			}
+			g, ok := callee.(*ir.Function)
+			if ok && callingConv(cc) != callingConv(g.CallingConv) {
+				warning := errors.Errorf("calling convention mismatch of call to %s; call site %v, callee %v", g.Ident(), callingConv(cc), callingConv(g.CallingConv))
+				warnings = append(warnings, errors.Wrapf(warning, "function %s", f.Ident()))
+			}
+			return true
+		})
+	}
+	return warnings
+}
+
+// walk verifies the instructions, terminators and constant expressions of the
+// given IR node, by the Validate methods of ir.Validator.
+func walk(node interface{}) error {
+	var err error
+	ir.Walk(node, func(n interface{}) bool {
+		if err != nil {
+			return false
+		}
+		switch n.(type) {
+		case *ir.Function, *ir.BasicBlock:
+			// Validate the instructions and terminators of functions and basic
+			// blocks.
+			return true
+		}
+		// Validate the node and the constant expressions it uses.
+		if err = ir.Validate(n); err != nil {
+			err = errors.Wrapf(err, "invalid %v", n)
+		}
+		return false
+	})
+	return err
+}
+
+// callingConv returns the given calling convention, with the default calling
+// convention made explicit.
+func callingConv(cc enum.CallingConv) enum.CallingConv {
+	if cc == enum.CallingConvNone {
+		return enum.CallingConvC
+	}
+	return cc
+}
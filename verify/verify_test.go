@@ -0,0 +1,148 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+)
+
+func TestSelect(t *testing.T) {
+	v2 := types.NewVector(2, types.I32)
+	v4 := types.NewVector(4, types.I32)
+	golden := []struct {
+		cond, x, y types.Type
+		valid      bool
+	}{
+		// i=0
+		{cond: types.I1, x: types.I32, y: types.I32, valid: true},
+		// i=1
+		{cond: types.NewVector(2, types.I1), x: v2, y: v2, valid: true},
+		// i=2
+		{cond: types.I1, x: v2, y: v2, valid: true},
+		// i=3
+		{cond: types.NewVector(4, types.I1), x: v2, y: v2, valid: false},
+		// i=4
+		{cond: types.I8, x: types.I32, y: types.I32, valid: false},
+		// i=5
+		{cond: types.I1, x: v2, y: v4, valid: false},
+		// i=6
+		{cond: types.NewVector(2, types.I1), x: types.I32, y: types.I32, valid: false},
+		// i=7
+		{cond: types.NewScalableVector(4, types.I1), x: types.NewVector(4, types.I32), y: types.NewVector(4, types.I32), valid: false},
+	}
+	for i, g := range golden {
+		m := &ir.Module{}
+		cond := ir.NewParam(g.cond, "cond")
+		x := ir.NewParam(g.x, "x")
+		y := ir.NewParam(g.y, "y")
+		f := m.NewFunc("f", g.x, cond, x, y)
+		entry := ir.NewBlock("entry")
+		entry.NewRet(entry.NewSelect(cond, x, y))
+		f.Blocks = append(f.Blocks, entry)
+		err := Module(m)
+		if g.valid && err != nil {
+			t.Errorf("i=%d: unexpected error; %v", i, err)
+		} else if !g.valid && err == nil {
+			t.Errorf("i=%d: expected error, got nil", i)
+		}
+	}
+}
+
+func TestSelectExpr(t *testing.T) {
+	v4 := types.NewVector(4, types.I32)
+	m := &ir.Module{}
+	cond := ir.NewZeroInitializer(types.NewScalableVector(4, types.I1))
+	m.NewGlobalDef("x", ir.NewSelectExpr(cond, ir.NewZeroInitializer(v4), ir.NewUndef(v4)))
+	if err := Module(m); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestPhi(t *testing.T) {
+	m := &ir.Module{}
+	x := ir.NewParam(types.I32, "x")
+	y := ir.NewParam(types.I64, "y")
+	f := m.NewFunc("f", types.Void, x, y)
+	entry := f.NewBlock("entry")
+	exit := f.NewBlock("exit")
+	entry.NewBr(exit)
+	exit.NewPhi(ir.NewIncoming(x, entry), ir.NewIncoming(y, entry))
+	exit.NewRet(nil)
+	if err := Module(m); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := m.Check(); err == nil {
+		t.Errorf("expected error of Check, got nil")
+	}
+}
+
+func TestCall(t *testing.T) {
+	golden := []struct {
+		variadic bool
+		args     func(x *ir.Param) []value.Value
+		attrs    []ir.ParamAttribute
+		typ      types.Type
+		valid    bool
+	}{
+		// i=0
+		{args: func(x *ir.Param) []value.Value { return []value.Value{x} }, valid: true},
+		// i=1
+		{args: func(x *ir.Param) []value.Value { return nil }, valid: false},
+		// i=2
+		{args: func(x *ir.Param) []value.Value { return []value.Value{x, x} }, valid: false},
+		// i=3
+		{variadic: true, args: func(x *ir.Param) []value.Value { return []value.Value{x, x} }, valid: true},
+		// i=4
+		{args: func(x *ir.Param) []value.Value { return []value.Value{ir.NewInt(types.I64, 1)} }, valid: false},
+		// i=5
+		{args: func(x *ir.Param) []value.Value { return []value.Value{x} }, typ: types.I64, valid: false},
+		// i=6
+		{args: func(x *ir.Param) []value.Value { return []value.Value{ir.NewArg(x, enum.ParamAttrByval)} }, valid: false},
+		// i=7
+		{args: func(x *ir.Param) []value.Value { return []value.Value{ir.NewArg(x, enum.ParamAttrSRet)} }, attrs: []ir.ParamAttribute{enum.ParamAttrSRet}, valid: true},
+		// i=8
+		{args: func(x *ir.Param) []value.Value { return []value.Value{x} }, attrs: []ir.ParamAttribute{enum.ParamAttrSRet}, valid: false},
+	}
+	for i, g := range golden {
+		m := &ir.Module{}
+		p := ir.NewParam(types.I32Ptr, "p")
+		p.Attrs = g.attrs
+		callee := m.NewFunc("g", types.I32, p)
+		callee.Sig.Variadic = g.variadic
+		x := ir.NewParam(types.I32Ptr, "x")
+		f := m.NewFunc("f", types.Void, x)
+		entry := f.NewBlock("entry")
+		call := entry.NewCall(callee, g.args(x)...)
+		call.Typ = g.typ
+		entry.NewRet(nil)
+		err := Module(m)
+		if g.valid && err != nil {
+			t.Errorf("i=%d: unexpected error; %v", i, err)
+		} else if !g.valid && err == nil {
+			t.Errorf("i=%d: expected error, got nil", i)
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	m := &ir.Module{}
+	g := m.NewFunc("g", types.Void)
+	g.CallingConv = enum.CallingConvFast
+	f := m.NewFunc("f", types.Void)
+	entry := f.NewBlock("entry")
+	entry.NewCall(g)
+	call := entry.NewCall(g)
+	call.CallingConv = enum.CallingConvFast
+	entry.NewRet(nil)
+	warnings := Warnings(m)
+	if len(warnings) != 1 {
+		t.Fatalf("number of warnings mismatch; expected 1, got %d (%v)", len(warnings), warnings)
+	}
+	want := "function @f: calling convention mismatch of call to @g; call site ccc, callee fastcc"
+	if got := warnings[0].Error(); got != want {
+		t.Errorf("warning mismatch; expected %q, got %q", want, got)
+	}
+}